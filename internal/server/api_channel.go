@@ -40,12 +40,15 @@ func (ch *ChannelAPI) Route(r *wkhttp.WKHttp) {
 	r.POST("/channel", ch.channelCreateOrUpdate)       // 创建或修改频道
 	r.POST("/channel/info", ch.updateOrAddChannelInfo) // 更新或添加频道基础信息
 	r.POST("/channel/delete", ch.channelDelete)        // 删除频道
+	r.POST("/channel/disband", ch.channelDisband)      // 解散频道（冻结、通知成员、宽限期后清除）
+	r.GET("/channel/disband", ch.channelDisbandGet)    // 获取频道解散进度
 
 	//################### 订阅者 ###################// 删除频道
 	r.POST("/channel/subscriber_add", ch.addSubscriber)       // 添加订阅者
 	r.POST("/channel/subscriber_remove", ch.removeSubscriber) // 移除订阅者
 
 	r.POST("/tmpchannel/subscriber_set", ch.setTmpSubscriber) // 临时频道设置订阅者
+	r.POST("/tmpchannel/create", ch.createTmpChannel)         // 创建临时频道（订阅者持久化，过期后自动清除）
 
 	//################### 黑名单 ###################// 删除频道
 	r.POST("/channel/blacklist_add", ch.blacklistAdd)       // 添加黑名单
@@ -221,6 +224,7 @@ func (ch *ChannelAPI) addSubscriber(c *wkhttp.Context) {
 			c.ResponseError(errors.New("创建频道失败！"))
 			return
 		}
+		ch.s.webhook.channelInfoChanged(EventChannelCreated, channelInfo)
 	}
 
 	err = ch.addSubscriberWithReq(req)
@@ -235,31 +239,52 @@ func (ch *ChannelAPI) addSubscriber(c *wkhttp.Context) {
 func (ch *ChannelAPI) addSubscriberWithReq(req subscriberAddReq) error {
 	var err error
 	existSubscribers := make([]string, 0)
+	members, err := ch.s.store.GetSubscribers(req.ChannelId, req.ChannelType)
+	if err != nil {
+		ch.Error("获取所有订阅者失败！", zap.Error(err), zap.String("channelId", req.ChannelId), zap.Uint8("channelType", req.ChannelType))
+		return err
+	}
+	for _, member := range members {
+		existSubscribers = append(existSubscribers, member.Uid)
+	}
 	if req.Reset == 1 {
 		err = ch.s.store.RemoveAllSubscriber(req.ChannelId, req.ChannelType)
 		if err != nil {
 			ch.Error("移除所有订阅者失败！", zap.Error(err), zap.String("channelId", req.ChannelId), zap.Uint8("channelType", req.ChannelType))
 			return err
 		}
-	} else {
-		members, err := ch.s.store.GetSubscribers(req.ChannelId, req.ChannelType)
-		if err != nil {
-			ch.Error("获取所有订阅者失败！", zap.Error(err), zap.String("channelId", req.ChannelId), zap.Uint8("channelType", req.ChannelType))
-			return err
-		}
-		for _, member := range members {
-			existSubscribers = append(existSubscribers, member.Uid)
+		// 重置时不在新订阅者中的旧订阅者被移除，新订阅者全部重新添加
+		removedSubscribers := make([]string, 0)
+		for _, uid := range existSubscribers {
+			if !wkutil.ArrayContains(req.Subscribers, uid) {
+				removedSubscribers = append(removedSubscribers, uid)
+			}
 		}
+		ch.s.webhook.subscribersChanged(EventChannelSubscribersRemoved, req.ChannelId, req.ChannelType, removedSubscribers, true)
 	}
 	newSubscribers := make([]string, 0, len(req.Subscribers))
+	addedSubscribers := make([]string, 0, len(req.Subscribers)) // 之前不是订阅者的新订阅者（用于通知）
 	for _, subscriber := range req.Subscribers {
 		if strings.TrimSpace(subscriber) == "" {
 			continue
 		}
-		if !wkutil.ArrayContains(existSubscribers, subscriber) {
+		exist := wkutil.ArrayContains(existSubscribers, subscriber)
+		if !exist {
+			addedSubscribers = append(addedSubscribers, subscriber)
+		}
+		if req.Reset == 1 || !exist {
 			newSubscribers = append(newSubscribers, subscriber)
 		}
 	}
+
+	// 超大频道走广播投递，不需要为订阅者创建最近会话和接收者tag
+	channelInfo, err := ch.s.store.GetChannel(req.ChannelId, req.ChannelType)
+	if err != nil && err != wkdb.ErrNotFound {
+		ch.Error("获取频道信息失败！", zap.Error(err), zap.String("channelId", req.ChannelId), zap.Uint8("channelType", req.ChannelType))
+		return err
+	}
+	large := channelInfo.Large
+
 	if len(newSubscribers) > 0 {
 		lastMsgSeq, err := ch.s.store.GetLastMsgSeq(req.ChannelId, req.ChannelType)
 		if err != nil {
@@ -283,6 +308,17 @@ func (ch *ChannelAPI) addSubscriberWithReq(req subscriberAddReq) error {
 			ch.Error("添加订阅者失败！", zap.Error(err), zap.Int("members", len(members)), zap.String("channelId", req.ChannelId), zap.Uint8("channelType", req.ChannelType))
 			return err
 		}
+		ch.s.webhook.subscribersChanged(EventChannelSubscribersAdded, req.ChannelId, req.ChannelType, addedSubscribers, req.Reset == 1)
+		if large {
+			ch.s.notifyBroadcastSubscriberChange(&broadcastSubscriberChange{
+				ChannelId:   req.ChannelId,
+				ChannelType: req.ChannelType,
+				Uids:        newSubscribers,
+				Subscriber:  1,
+				Reset:       req.Reset,
+			})
+			return nil
+		}
 
 		conversations := make([]wkdb.Conversation, 0, len(newSubscribers))
 		for _, subscriber := range newSubscribers {
@@ -307,6 +343,16 @@ func (ch *ChannelAPI) addSubscriberWithReq(req subscriberAddReq) error {
 		}
 
 	}
+	if large {
+		if req.Reset == 1 { // 重置后没有订阅者
+			ch.s.notifyBroadcastSubscriberChange(&broadcastSubscriberChange{
+				ChannelId:   req.ChannelId,
+				ChannelType: req.ChannelType,
+				Reset:       1,
+			})
+		}
+		return nil
+	}
 
 	// 重新制止tag
 	err = ch.makeAndCmdReceiverTag(req.ChannelId, req.ChannelType)
@@ -422,6 +468,14 @@ func (ch *ChannelAPI) removeSubscriber(c *wkhttp.Context) {
 		c.ResponseError(err)
 		return
 	}
+	ch.s.webhook.subscribersChanged(EventChannelSubscribersRemoved, req.ChannelId, req.ChannelType, req.Subscribers, false)
+
+	ch.s.notifyBroadcastSubscriberChange(&broadcastSubscriberChange{
+		ChannelId:   req.ChannelId,
+		ChannelType: req.ChannelType,
+		Uids:        req.Subscribers,
+		Subscriber:  0,
+	})
 
 	err = ch.makeAndCmdReceiverTag(req.ChannelId, req.ChannelType)
 	if err != nil {
@@ -467,6 +521,110 @@ func (ch *ChannelAPI) setTmpSubscriber(c *wkhttp.Context) {
 	c.ResponseOK()
 }
 
+// 创建临时频道
+func (ch *ChannelAPI) createTmpChannel(c *wkhttp.Context) {
+	var req tmpChannelCreateReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		c.ResponseError(errors.Wrap(err, "数据格式有误！"))
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	if !ch.s.opts.IsTmpChannel(req.ChannelId) {
+		c.ResponseError(fmt.Errorf("临时频道ID必须以%s结尾！", ch.s.opts.TmpChannel.Suffix))
+		return
+	}
+	channelIdPrefix := strings.TrimSuffix(req.ChannelId, ch.s.opts.TmpChannel.Suffix)
+	if strings.TrimSpace(channelIdPrefix) == "" || IsSpecialChar(channelIdPrefix) {
+		c.ResponseError(errors.New("频道ID不合法！"))
+		return
+	}
+
+	if ch.s.opts.ClusterOn() {
+		leaderInfo, err := ch.s.cluster.SlotLeaderOfChannel(req.ChannelId, wkproto.ChannelTypeTemp) // 获取频道的槽领导节点
+		if err != nil {
+			ch.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", req.ChannelId), zap.Uint8("channelType", wkproto.ChannelTypeTemp))
+			c.ResponseError(errors.New("获取频道所在节点失败！"))
+			return
+		}
+		leaderIsSelf := leaderInfo.Id == ch.s.opts.Cluster.NodeId
+		if !leaderIsSelf {
+			ch.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+			c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
+			return
+		}
+	}
+
+	ttl := uint64(ch.s.opts.TmpChannel.Ttl.Seconds())
+	if req.Ttl > 0 {
+		ttl = uint64(req.Ttl)
+	} else if req.Ttl == -1 {
+		ttl = 0
+	}
+	idleTtl := uint64(ch.s.opts.TmpChannel.IdleTtl.Seconds())
+	if req.IdleTtl > 0 {
+		idleTtl = uint64(req.IdleTtl)
+	} else if req.IdleTtl == -1 {
+		idleTtl = 0
+	}
+
+	// 重新创建时覆盖原来的订阅者
+	err = ch.s.store.RemoveAllSubscriber(req.ChannelId, wkproto.ChannelTypeTemp)
+	if err != nil {
+		ch.Error("移除临时频道的订阅者失败！", zap.Error(err), zap.String("channelId", req.ChannelId))
+		c.ResponseError(err)
+		return
+	}
+	members := make([]wkdb.Member, 0, len(req.Subscribers))
+	createdAt := time.Now()
+	for _, uid := range req.Subscribers {
+		if strings.TrimSpace(uid) == "" {
+			continue
+		}
+		members = append(members, wkdb.Member{
+			Uid:       uid,
+			CreatedAt: &createdAt,
+			UpdatedAt: &createdAt,
+		})
+	}
+	err = ch.s.store.AddSubscribers(req.ChannelId, wkproto.ChannelTypeTemp, members)
+	if err != nil {
+		ch.Error("添加临时频道的订阅者失败！", zap.Error(err), zap.String("channelId", req.ChannelId))
+		c.ResponseError(err)
+		return
+	}
+
+	err = ch.s.store.AddOrUpdateTmpChannel(wkdb.TmpChannel{
+		ChannelId: req.ChannelId,
+		Ttl:       ttl,
+		IdleTtl:   idleTtl,
+		CreatedAt: &createdAt,
+		UpdatedAt: &createdAt,
+	})
+	if err != nil {
+		ch.Error("保存临时频道失败！", zap.Error(err), zap.String("channelId", req.ChannelId))
+		c.ResponseError(err)
+		return
+	}
+
+	err = ch.makeAndCmdReceiverTag(req.ChannelId, wkproto.ChannelTypeTemp)
+	if err != nil {
+		ch.Error("创建接收者标签失败！", zap.Error(err), zap.String("channelId", req.ChannelId))
+		c.ResponseError(err)
+		return
+	}
+
+	c.ResponseOKWithData(map[string]interface{}{
+		"channel_id":   req.ChannelId,
+		"channel_type": wkproto.ChannelTypeTemp,
+		"ttl":          ttl,
+		"idle_ttl":     idleTtl,
+	})
+}
+
 func setTmpSubscriberWithReq(s *Server, req tmpSubscriberSetReq) {
 	channel := s.channelReactor.loadOrCreateChannel(req.ChannelId, wkproto.ChannelTypeTemp)
 	channel.setTmpSubscribers(req.Uids)
@@ -521,6 +679,7 @@ func (ch *ChannelAPI) blacklistAdd(c *wkhttp.Context) {
 		c.ResponseError(err)
 		return
 	}
+	ch.s.webhook.memberListChanged(EventChannelDenylistChanged, req.ChannelId, req.ChannelType, lifecycleActionAdd, req.UIDs)
 
 	c.ResponseOK()
 }
@@ -579,6 +738,7 @@ func (ch *ChannelAPI) blacklistSet(c *wkhttp.Context) {
 			return
 		}
 	}
+	ch.s.webhook.memberListChanged(EventChannelDenylistChanged, req.ChannelId, req.ChannelType, lifecycleActionSet, req.UIDs)
 
 	c.ResponseOK()
 }
@@ -616,6 +776,7 @@ func (ch *ChannelAPI) blacklistRemove(c *wkhttp.Context) {
 		c.ResponseError(err)
 		return
 	}
+	ch.s.webhook.memberListChanged(EventChannelDenylistChanged, req.ChannelId, req.ChannelType, lifecycleActionRemove, req.UIDs)
 
 	c.ResponseOK()
 }
@@ -675,10 +836,81 @@ func (ch *ChannelAPI) channelDelete(c *wkhttp.Context) {
 		c.ResponseError(err)
 		return
 	}
+	ch.s.webhook.channelInfoChanged(EventChannelDeleted, channelInfo)
 
 	c.ResponseOK()
 }
 
+// 解散频道
+func (ch *ChannelAPI) channelDisband(c *wkhttp.Context) {
+	var req channelDisbandReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		c.ResponseError(errors.Wrap(err, "数据格式有误！"))
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	if ch.s.opts.ClusterOn() {
+		leaderInfo, err := ch.s.cluster.SlotLeaderOfChannel(req.ChannelId, req.ChannelType) // 获取频道的槽领导节点
+		if err != nil {
+			ch.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", req.ChannelId), zap.Uint8("channelType", req.ChannelType))
+			c.ResponseError(errors.New("获取频道所在节点失败！"))
+			return
+		}
+		leaderIsSelf := leaderInfo.Id == ch.s.opts.Cluster.NodeId
+		if !leaderIsSelf {
+			ch.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+			c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
+			return
+		}
+	}
+
+	channelDisband, err := ch.s.channelDisbandManager.disband(req)
+	if err != nil {
+		ch.Error("解散频道失败！", zap.Error(err), zap.String("channelId", req.ChannelId), zap.Uint8("channelType", req.ChannelType))
+		c.ResponseError(err)
+		return
+	}
+	c.JSON(http.StatusOK, newChannelDisbandResp(channelDisband))
+}
+
+// 获取频道解散进度
+func (ch *ChannelAPI) channelDisbandGet(c *wkhttp.Context) {
+	channelId := c.Query("channel_id")
+	channelType := wkutil.ParseUint8(c.Query("channel_type"))
+	if strings.TrimSpace(channelId) == "" {
+		c.ResponseError(errors.New("channel_id不能为空！"))
+		return
+	}
+	if ch.s.opts.ClusterOn() {
+		leaderInfo, err := ch.s.cluster.SlotLeaderOfChannel(channelId, channelType) // 获取频道的槽领导节点
+		if err != nil {
+			ch.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", channelId), zap.Uint8("channelType", channelType))
+			c.ResponseError(errors.New("获取频道所在节点失败！"))
+			return
+		}
+		if leaderInfo.Id != ch.s.opts.Cluster.NodeId {
+			c.Forward(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path))
+			return
+		}
+	}
+
+	channelDisband, err := ch.s.store.GetChannelDisband(channelId, channelType)
+	if err == wkdb.ErrNotFound {
+		c.ResponseError(errors.New("频道没有解散记录！"))
+		return
+	}
+	if err != nil {
+		ch.Error("获取频道解散记录失败！", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+		c.ResponseError(errors.New("获取频道解散记录失败！"))
+		return
+	}
+	c.JSON(http.StatusOK, newChannelDisbandResp(channelDisband))
+}
+
 // ----------- 白名单 -----------
 
 // 添加白名单
@@ -731,6 +963,7 @@ func (ch *ChannelAPI) whitelistAdd(c *wkhttp.Context) {
 		c.ResponseError(err)
 		return
 	}
+	ch.s.webhook.memberListChanged(EventChannelAllowlistChanged, req.ChannelId, req.ChannelType, lifecycleActionAdd, req.UIDs)
 
 	c.ResponseOK()
 }
@@ -786,6 +1019,7 @@ func (ch *ChannelAPI) whitelistSet(c *wkhttp.Context) {
 			return
 		}
 	}
+	ch.s.webhook.memberListChanged(EventChannelAllowlistChanged, req.ChannelId, req.ChannelType, lifecycleActionSet, req.UIDs)
 
 	c.ResponseOK()
 }
@@ -824,6 +1058,7 @@ func (ch *ChannelAPI) whitelistRemove(c *wkhttp.Context) {
 		c.ResponseError(err)
 		return
 	}
+	ch.s.webhook.memberListChanged(EventChannelAllowlistChanged, req.ChannelId, req.ChannelType, lifecycleActionRemove, req.UIDs)
 
 	c.ResponseOK()
 }
@@ -1036,11 +1271,13 @@ func (ch *ChannelAPI) addOrUpdateChannel(channelInfo wkdb.ChannelInfo) error {
 		if err != nil {
 			return err
 		}
+		ch.s.webhook.channelInfoChanged(EventChannelCreated, channelInfo)
 	} else {
 		err = ch.s.store.UpdateChannelInfo(channelInfo)
 		if err != nil {
 			return err
 		}
+		ch.s.webhook.channelInfoChanged(EventChannelUpdated, channelInfo)
 	}
 	return nil
 }
@@ -102,6 +102,7 @@ func (co *ConnzAPI) HandleConnz(c *wkhttp.Context) {
 		Total:       co.s.engine.ConnCount(),
 		Offset:      offset,
 		Limit:       limit,
+		ConnLimit:   co.s.connLimiter.stats(),
 	})
 }
 
@@ -197,11 +198,12 @@ func (s *Server) GetConnInfos(uid string, sortOpt SortOpt, offset, limit int) []
 }
 
 type Connz struct {
-	Connections []*ConnInfo `json:"connections"` // 连接数
-	Now         time.Time   `json:"now"`         // 查询时间
-	Total       int         `json:"total"`       // 总连接数量
-	Offset      int         `json:"offset"`      // 偏移位置
-	Limit       int         `json:"limit"`       // 限制数量
+	Connections []*ConnInfo     `json:"connections"` // 连接数
+	Now         time.Time       `json:"now"`         // 查询时间
+	Total       int             `json:"total"`       // 总连接数量
+	Offset      int             `json:"offset"`      // 偏移位置
+	Limit       int             `json:"limit"`       // 限制数量
+	ConnLimit   *connLimitStats `json:"conn_limit"`  // 连接数限制
 }
 
 type ConnInfo struct {
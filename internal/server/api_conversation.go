@@ -429,6 +429,9 @@ func (s *ConversationAPI) syncUserConversation(c *wkhttp.Context) {
 				continue
 			}
 			resp := newSyncUserConversationResp(conversation)
+			if s.s.opts.IsThreadChannel(conversation.ChannelId) {
+				resp.ParentChannelId, resp.ThreadRoot = s.s.opts.ThreadChannelConvertParent(conversation.ChannelId)
+			}
 
 			for _, channelRecentMessage := range channelRecentMessages {
 				if conversation.ChannelId == channelRecentMessage.ChannelId && conversation.ChannelType == channelRecentMessage.ChannelType {
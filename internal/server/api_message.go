@@ -8,6 +8,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/WuKongIM/WuKongIM/pkg/protocol"
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
 	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
@@ -42,6 +43,7 @@ func NewMessageAPI(s *Server) *MessageAPI {
 func (m *MessageAPI) Route(r *wkhttp.WKHttp) {
 	r.POST("/message/send", m.send)           // 发送消息
 	r.POST("/message/sendbatch", m.sendBatch) // 批量发送消息
+	r.POST("/message/transient", m.transient) // 发送瞬态事件（比如正在输入），不存储，只投递给在线用户
 	r.POST("/message/sync", m.sync)           // 消息同步(写模式)
 	r.POST("/message/syncack", m.syncack)     // 消息同步回执(写模式)
 
@@ -132,6 +134,51 @@ func (m *MessageAPI) send(c *wkhttp.Context) {
 	})
 }
 
+// 发送瞬态事件
+func (m *MessageAPI) transient(c *wkhttp.Context) {
+	var req transientSendReq
+	if err := c.BindJSON(&req); err != nil {
+		m.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	if strings.TrimSpace(req.FromUID) == "" {
+		req.FromUID = m.s.opts.SystemUID
+	}
+
+	clientMsgNo := req.ClientMsgNo
+	if strings.TrimSpace(clientMsgNo) == "" {
+		clientMsgNo = fmt.Sprintf("%s0", wkutil.GenUUID())
+	}
+	messageId := m.s.channelReactor.messageIDGen.Generate().Int64()
+	reasonCode, err := m.s.transientManager.send(&transientEvent{
+		MessageId:   messageId,
+		ClientMsgNo: clientMsgNo,
+		Setting:     protocol.SettingTransient.Uint8(),
+		FromUID:     req.FromUID,
+		ChannelID:   req.ChannelID,
+		ChannelType: req.ChannelType,
+		Payload:     req.Payload,
+	})
+	if err != nil {
+		m.Error("发送瞬态事件失败！", zap.Error(err), zap.String("channelId", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
+		c.ResponseError(errors.New("发送瞬态事件失败！"))
+		return
+	}
+	if reasonCode != wkproto.ReasonSuccess {
+		c.ResponseError(fmt.Errorf("发送瞬态事件失败！reason:%s", reasonCode.String()))
+		return
+	}
+	c.ResponseOKWithData(map[string]interface{}{
+		"message_id":    messageId,
+		"client_msg_no": clientMsgNo,
+	})
+}
+
 // 请求临时频道设置订阅者
 func (m *MessageAPI) requestSetSubscribersForTmpChannel(tmpChannelId string, uids []string) error {
 	timeoutCtx, cancel := context.WithTimeout(m.s.ctx, time.Second*5)
@@ -183,6 +230,11 @@ func sendMessageToChannel(s *Server, req MessageSendReq, channelId string, chann
 		fakeChannelId = GetFakeChannelIDWith(req.FromUID, channelId)
 	}
 
+	if req.ThreadRoot > 0 { // 子区消息，将原频道转换为子区频道
+		channelId = s.opts.ThreadChannelOf(channelId, req.ThreadRoot)
+		fakeChannelId = channelId
+	}
+
 	if req.Header.SyncOnce == 1 { // 命令消息，将原频道转换为cmd频道
 		fakeChannelId = s.opts.OrginalConvertCmdChannel(fakeChannelId)
 	}
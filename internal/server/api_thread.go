@@ -0,0 +1,144 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	cluster "github.com/WuKongIM/WuKongIM/pkg/cluster/clusterserver"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// ThreadAPI 子区相关api
+// 子区是由父频道和根消息派生出来的频道，子区的消息存储在子区频道的副本内，订阅者和权限继承自父频道
+type ThreadAPI struct {
+	s *Server
+	wklog.Log
+}
+
+// NewThreadAPI NewThreadAPI
+func NewThreadAPI(s *Server) *ThreadAPI {
+	return &ThreadAPI{
+		s:   s,
+		Log: wklog.NewWKLog("ThreadAPI"),
+	}
+}
+
+// Route route
+func (t *ThreadAPI) Route(r *wkhttp.WKHttp) {
+	r.GET("/thread/messages", t.messages) // 获取子区消息
+}
+
+// 获取子区消息（包含根消息的回复数量和最后一条回复）
+func (t *ThreadAPI) messages(c *wkhttp.Context) {
+	channelId := c.Query("channel_id")
+	channelType := wkutil.ParseUint8(c.Query("channel_type"))
+	rootMessageId := wkutil.ParseInt64(c.Query("root_message_id"))
+	startMessageSeq := wkutil.ParseUint64(c.Query("start_message_seq"))
+	endMessageSeq := wkutil.ParseUint64(c.Query("end_message_seq"))
+	limit := wkutil.ParseInt(c.Query("limit"))
+	pullMode := PullMode(wkutil.ParseInt(c.Query("pull_mode")))
+
+	if strings.TrimSpace(channelId) == "" {
+		c.ResponseError(errors.New("channel_id不能为空！"))
+		return
+	}
+	if rootMessageId <= 0 {
+		c.ResponseError(errors.New("root_message_id不能为空！"))
+		return
+	}
+	if channelType == wkproto.ChannelTypePerson {
+		c.ResponseError(errors.New("个人频道不支持子区！"))
+		return
+	}
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 10000 {
+		limit = 10000
+	}
+
+	threadChannelId := t.s.opts.ThreadChannelOf(channelId, rootMessageId)
+	resp := &threadMessagesResp{
+		ChannelId:       channelId,
+		ChannelType:     channelType,
+		RootMessageId:   rootMessageId,
+		ThreadChannelId: threadChannelId,
+		Messages:        make([]*MessageResp, 0),
+	}
+
+	leaderInfo, err := t.s.cluster.LeaderOfChannelForRead(threadChannelId, channelType)
+	if errors.Is(err, cluster.ErrChannelClusterConfigNotFound) { // 子区还没有回复
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+	if err != nil {
+		t.Error("获取子区所在节点失败！", zap.Error(err), zap.String("threadChannelId", threadChannelId), zap.Uint8("channelType", channelType))
+		c.ResponseError(errors.New("获取子区所在节点失败！"))
+		return
+	}
+	if leaderInfo.Id != t.s.opts.Cluster.NodeId {
+		c.Forward(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path))
+		return
+	}
+
+	// 根消息的回复数量和最后一条回复（记录在根消息的回复统计里）
+	threadReply, err := t.s.threadManager.getReply(channelId, channelType, rootMessageId)
+	if err != nil {
+		t.Error("获取根消息的回复统计失败！", zap.Error(err), zap.String("channelId", channelId), zap.Int64("rootMessageId", rootMessageId))
+		c.ResponseError(errors.New("获取根消息的回复统计失败！"))
+		return
+	}
+	if threadReply != nil {
+		resp.ReplyCount = threadReply.ReplyCount
+		lastReply, err := t.s.store.LoadMsg(threadChannelId, channelType, threadReply.LastReplyMessageSeq)
+		if err != nil && err != wkdb.ErrNotFound {
+			t.Error("获取子区最后一条回复失败！", zap.Error(err), zap.String("threadChannelId", threadChannelId))
+			c.ResponseError(errors.New("获取子区最后一条回复失败！"))
+			return
+		}
+		if err == nil {
+			resp.LastReply = &MessageResp{}
+			resp.LastReply.from(lastReply, t.s)
+		}
+	}
+
+	var messages []wkdb.Message
+	if startMessageSeq == 0 && endMessageSeq == 0 {
+		messages, err = t.s.store.LoadLastMsgs(threadChannelId, channelType, limit)
+	} else if pullMode == PullModeUp {
+		messages, err = t.s.store.LoadNextRangeMsgs(threadChannelId, channelType, startMessageSeq, endMessageSeq, limit)
+	} else {
+		messages, err = t.s.store.LoadPrevRangeMsgs(threadChannelId, channelType, startMessageSeq, endMessageSeq, limit)
+	}
+	if err != nil && err != wkdb.ErrNotFound {
+		t.Error("获取子区消息失败！", zap.Error(err), zap.String("threadChannelId", threadChannelId))
+		c.ResponseError(errors.New("获取子区消息失败！"))
+		return
+	}
+	for _, message := range messages {
+		messageResp := &MessageResp{}
+		messageResp.from(message, t.s)
+		resp.Messages = append(resp.Messages, messageResp)
+	}
+	resp.More = wkutil.BoolToInt(len(messages) >= limit)
+
+	c.JSON(http.StatusOK, resp)
+}
+
+type threadMessagesResp struct {
+	ChannelId       string         `json:"channel_id"`        // 父频道ID
+	ChannelType     uint8          `json:"channel_type"`      // 父频道类型
+	RootMessageId   int64          `json:"root_message_id"`   // 根消息ID
+	ThreadChannelId string         `json:"thread_channel_id"` // 子区频道ID
+	ReplyCount      uint64         `json:"reply_count"`       // 回复数量
+	LastReply       *MessageResp   `json:"last_reply"`        // 最后一条回复
+	More            int            `json:"more"`              // 是否还有更多 1.是 0.否
+	Messages        []*MessageResp `json:"messages"`          // 子区消息
+}
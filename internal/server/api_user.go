@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,6 +13,7 @@ import (
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
 	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
 	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
 	wkproto "github.com/WuKongIM/WuKongIMGoProto"
 	"github.com/pkg/errors"
@@ -38,7 +40,15 @@ func (u *UserAPI) Route(r *wkhttp.WKHttp) {
 
 	r.POST("/user/token", u.updateToken)                  // 更新用户token
 	r.POST("/user/device_quit", u.deviceQuit)             // 强制设备退出
+	r.GET("/user/devices", u.getDevices)                  // 获取用户的设备列表（登录信息和在线状态）
+	r.GET("/user/sessions", u.getSessions)                // 获取用户的会话审计日志
+	r.POST("/user/device_revoke", u.deviceRevoke)         // 吊销设备（清空设备token并踢掉设备的连接）
 	r.POST("/user/onlinestatus", u.getOnlineStatus)       // 获取用户在线状态
+	r.POST("/user/presence", u.getPresence)               // 批量获取用户在线状态（在线设备、最后在线时间、用户设置的状态）
+	r.POST("/user/status", u.updateStatus)                // 设置用户状态
+	r.POST("/user/ban", u.ban)                            // 封禁用户
+	r.POST("/user/unban", u.unban)                        // 解封用户
+	r.GET("/user/bans", u.getBans)                        // 获取封禁的用户列表
 	r.POST("/user/systemuids_add", u.systemUidsAdd)       // 添加系统uid
 	r.POST("/user/systemuids_remove", u.systemUidsRemove) // 移除系统uid
 	r.GET("/user/systemuids", u.getSystemUids)            // 获取系统uid
@@ -95,10 +105,14 @@ func (u *UserAPI) quitUserDevice(uid string, deviceFlag wkproto.DeviceFlag) erro
 		u.Error("获取设备信息失败！", zap.Error(err), zap.String("uid", uid), zap.Uint8("deviceFlag", deviceFlag.ToUint8()))
 		return err
 	}
-	if wkdb.IsEmptyDevice(device) {
+	if wkdb.IsEmptyDevice(device) && u.s.clientTokenVerifier == nil {
 		u.Error("设备信息不存在！", zap.String("uid", uid), zap.Uint8("deviceFlag", deviceFlag.ToUint8()))
 		return errors.New("设备信息不存在！")
 	}
+	if device, err = u.revokeDevice(uid, deviceFlag, device); err != nil {
+		u.Error("记录设备吊销时间失败！", zap.Error(err), zap.String("uid", uid), zap.Uint8("deviceFlag", deviceFlag.ToUint8()))
+		return err
+	}
 
 	updatedAt := time.Now()
 	err = u.s.store.UpdateDevice(wkdb.Device{
@@ -114,12 +128,17 @@ func (u *UserAPI) quitUserDevice(uid string, deviceFlag wkproto.DeviceFlag) erro
 		u.Error("清空用户token失败！", zap.Error(err), zap.String("uid", uid), zap.Uint8("deviceFlag", deviceFlag.ToUint8()))
 		return err
 	}
+	if u.s.authHook != nil { // 第三方认证服务的认证缓存也需要清除
+		u.s.authHook.removeCache(uid, deviceFlag)
+	}
+	u.s.webhook.userDeviceQuit(uid, deviceFlag, deviceQuitReasonQuit)
 	oldConns := u.s.userReactor.getConnsByDeviceFlag(uid, deviceFlag)
 	if len(oldConns) > 0 {
 		for _, oldConn := range oldConns {
 			_ = u.s.userReactor.writePacket(oldConn, &wkproto.DisconnectPacket{
 				ReasonCode: wkproto.ReasonConnectKick,
 			})
+			u.s.sessionLogManager.recordKick(oldConn, wkproto.ReasonConnectKick, "device quit")
 			u.s.timingWheel.AfterFunc(time.Second*2, func() {
 				oldConn.close()
 			})
@@ -248,6 +267,408 @@ func (u *UserAPI) getOnlineConns(uids []string) []*OnlinestatusResp {
 	return onlineStatusResps
 }
 
+// 批量获取用户在线状态
+func (u *UserAPI) getPresence(c *wkhttp.Context) {
+	var uids []string
+	err := c.BindJSON(&uids)
+	if err != nil {
+		u.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if len(uids) == 0 {
+		c.JSON(http.StatusOK, []*userPresenceResp{})
+		return
+	}
+	if len(uids) > 1000 {
+		c.ResponseError(errors.New("uids数量不能超过1000！"))
+		return
+	}
+
+	uidInPeerMap := make(map[uint64][]string)
+	localUids := make([]string, 0)
+	for _, uid := range uids {
+		leaderInfo, err := u.s.cluster.SlotLeaderOfChannel(uid, wkproto.ChannelTypePerson) // 获取用户的槽领导节点
+		if err != nil {
+			u.Error("获取用户所在节点失败！", zap.Error(err), zap.String("uid", uid))
+			c.ResponseError(errors.New("获取用户所在节点失败！"))
+			return
+		}
+		if leaderInfo.Id == u.s.opts.Cluster.NodeId {
+			localUids = append(localUids, uid)
+			continue
+		}
+		uidInPeerMap[leaderInfo.Id] = append(uidInPeerMap[leaderInfo.Id], uid)
+	}
+
+	presences := make([]*userPresenceResp, 0, len(uids))
+	if len(localUids) > 0 {
+		results, err := u.s.userPresenceManager.getLocalPresences(localUids)
+		if err != nil {
+			u.Error("获取用户在线状态失败！", zap.Error(err))
+			c.ResponseError(errors.New("获取用户在线状态失败！"))
+			return
+		}
+		presences = append(presences, results...)
+	}
+
+	if len(uidInPeerMap) > 0 {
+		var presencesLock sync.Mutex
+		requestGroup, _ := errgroup.WithContext(u.s.ctx)
+		for nodeId, uidList := range uidInPeerMap {
+			nodeId, uidList := nodeId, uidList
+			requestGroup.Go(func() error {
+				results, err := u.requestPresence(nodeId, uidList)
+				if err != nil {
+					return err
+				}
+				presencesLock.Lock()
+				presences = append(presences, results...)
+				presencesLock.Unlock()
+				return nil
+			})
+		}
+		if err := requestGroup.Wait(); err != nil {
+			c.ResponseError(err)
+			return
+		}
+	}
+	c.JSON(http.StatusOK, presences)
+}
+
+func (u *UserAPI) requestPresence(nodeId uint64, uids []string) ([]*userPresenceResp, error) {
+	nodeInfo, err := u.s.cluster.NodeInfoById(nodeId)
+	if err != nil {
+		u.Error("获取节点信息失败！", zap.Error(err), zap.Uint64("nodeId", nodeId))
+		return nil, errors.New("获取节点信息失败！")
+	}
+	reqURL := fmt.Sprintf("%s/user/presence", nodeInfo.ApiServerAddr)
+	resp, err := network.Post(reqURL, []byte(wkutil.ToJSON(uids)), nil)
+	if err != nil {
+		u.Error("获取用户在线状态失败！", zap.Error(err), zap.String("reqURL", reqURL))
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取用户在线状态请求状态错误！[%d]", resp.StatusCode)
+	}
+	var presences []*userPresenceResp
+	err = wkutil.ReadJSONByByte([]byte(resp.Body), &presences)
+	if err != nil {
+		u.Error("解析用户在线状态失败！", zap.Error(err))
+		return nil, err
+	}
+	return presences, nil
+}
+
+// 设置用户状态
+func (u *UserAPI) updateStatus(c *wkhttp.Context) {
+	var req userStatusReq
+	if err := c.BindJSON(&req); err != nil {
+		u.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	err := u.s.store.UpdateUserStatus(req.UID, wkdb.UserStatus(req.Status), req.StatusText)
+	if err != nil {
+		u.Error("设置用户状态失败！", zap.Error(err), zap.String("uid", req.UID))
+		c.ResponseError(errors.New("设置用户状态失败！"))
+		return
+	}
+	c.ResponseOK()
+}
+
+// 获取用户的设备列表
+func (u *UserAPI) getDevices(c *wkhttp.Context) {
+	uid := c.Query("uid")
+	if strings.TrimSpace(uid) == "" {
+		c.ResponseError(errors.New("uid不能为空！"))
+		return
+	}
+	if u.forwardToUserLeader(c, uid, nil) {
+		return
+	}
+	devices, err := u.s.store.GetDevices(uid)
+	if err != nil {
+		u.Error("获取设备列表失败！", zap.Error(err), zap.String("uid", uid))
+		c.ResponseError(errors.New("获取设备列表失败！"))
+		return
+	}
+	resps := make([]*userDeviceResp, 0, len(devices))
+	for _, device := range devices {
+		resp := newUserDeviceResp(device)
+		// 用户的连接都在用户的槽领导节点上（包括代理连接）
+		resp.ConnCount = u.s.userReactor.getConnCountByDeviceFlag(uid, wkproto.DeviceFlag(device.DeviceFlag))
+		if resp.ConnCount > 0 {
+			resp.Online = 1
+		}
+		resps = append(resps, resp)
+	}
+	c.JSON(http.StatusOK, resps)
+}
+
+// 获取用户的会话审计日志（按时间倒序）
+func (u *UserAPI) getSessions(c *wkhttp.Context) {
+	uid := c.Query("uid")
+	start := wkutil.ParseInt64(c.Query("start")) // 开始时间（秒）
+	end := wkutil.ParseInt64(c.Query("end"))     // 结束时间（秒）
+	limit := wkutil.ParseInt(c.Query("limit"))
+	if strings.TrimSpace(uid) == "" {
+		c.ResponseError(errors.New("uid不能为空！"))
+		return
+	}
+	if limit <= 0 {
+		limit = 100
+	}
+	if u.forwardToUserLeader(c, uid, nil) {
+		return
+	}
+	logs, err := u.s.store.GetSessionLogs(uid, start*int64(time.Second), end*int64(time.Second), limit)
+	if err != nil {
+		u.Error("获取会话审计日志失败！", zap.Error(err), zap.String("uid", uid))
+		c.ResponseError(errors.New("获取会话审计日志失败！"))
+		return
+	}
+	resps := make([]*sessionLogResp, 0, len(logs))
+	for _, log := range logs {
+		resps = append(resps, newSessionLogResp(log))
+	}
+	c.JSON(http.StatusOK, resps)
+}
+
+// 吊销设备，清空设备的token（设备需要通过 /user/token 重新获取token才能连接）并踢掉设备的连接
+func (u *UserAPI) deviceRevoke(c *wkhttp.Context) {
+	var req struct {
+		UID        string             `json:"uid"`         // 用户uid
+		DeviceFlag wkproto.DeviceFlag `json:"device_flag"` // 设备flag
+		Reason     string             `json:"reason"`      // 吊销原因（会通过DisconnectPacket告知客户端）
+	}
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		u.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if strings.TrimSpace(req.UID) == "" {
+		c.ResponseError(errors.New("uid不能为空！"))
+		return
+	}
+	if u.forwardToUserLeader(c, req.UID, bodyBytes) {
+		return
+	}
+
+	device, err := u.s.store.GetDevice(req.UID, req.DeviceFlag)
+	if err != nil && err != wkdb.ErrNotFound {
+		u.Error("获取设备信息失败！", zap.Error(err), zap.String("uid", req.UID), zap.Uint8("deviceFlag", req.DeviceFlag.ToUint8()))
+		c.ResponseError(errors.New("获取设备信息失败！"))
+		return
+	}
+	if wkdb.IsEmptyDevice(device) && u.s.clientTokenVerifier == nil {
+		c.ResponseError(errors.New("设备信息不存在！"))
+		return
+	}
+	if device, err = u.revokeDevice(req.UID, req.DeviceFlag, device); err != nil {
+		u.Error("记录设备吊销时间失败！", zap.Error(err), zap.String("uid", req.UID), zap.Uint8("deviceFlag", req.DeviceFlag.ToUint8()))
+		c.ResponseError(errors.New("记录设备吊销时间失败！"))
+		return
+	}
+
+	updatedAt := time.Now()
+	err = u.s.store.UpdateDevice(wkdb.Device{
+		Id:          device.Id,
+		Uid:         req.UID,
+		DeviceFlag:  device.DeviceFlag,
+		DeviceLevel: device.DeviceLevel,
+		Token:       "", // 空token是不让登录的
+		UpdatedAt:   &updatedAt,
+	})
+	if err != nil {
+		u.Error("清空设备token失败！", zap.Error(err), zap.String("uid", req.UID), zap.Uint8("deviceFlag", req.DeviceFlag.ToUint8()))
+		c.ResponseError(errors.New("清空设备token失败！"))
+		return
+	}
+	if u.s.authHook != nil { // 第三方认证服务的认证缓存也需要清除
+		u.s.authHook.removeCache(req.UID, req.DeviceFlag)
+	}
+	u.s.webhook.userDeviceQuit(req.UID, req.DeviceFlag, deviceQuitReasonRevoke)
+
+	reason := req.Reason
+	if strings.TrimSpace(reason) == "" {
+		reason = "device revoked"
+	}
+	conns := u.s.userReactor.getConnsByDeviceFlag(req.UID, req.DeviceFlag)
+	for _, conn := range conns {
+		u.Info("kick revoked device conn", zap.String("uid", req.UID), zap.Int64("connId", conn.connId), zap.String("deviceId", conn.deviceId))
+		_ = u.s.userReactor.writePacket(conn, &wkproto.DisconnectPacket{
+			ReasonCode: wkproto.ReasonConnectKick,
+			Reason:     reason,
+		})
+		u.s.sessionLogManager.recordKick(conn, wkproto.ReasonConnectKick, reason)
+		u.s.userReactor.removeConnById(conn.uid, conn.connId)
+		u.s.timingWheel.AfterFunc(time.Second*2, func(cn *connContext) func() {
+			return func() {
+				cn.close()
+			}
+		}(conn))
+	}
+	c.ResponseOK()
+}
+
+// 记录设备的吊销时间，jwt认证时在此之前签发的token不能再连接
+// jwt认证的设备没有连接过时没有设备信息，先添加设备
+func (u *UserAPI) revokeDevice(uid string, deviceFlag wkproto.DeviceFlag, device wkdb.Device) (wkdb.Device, error) {
+	now := time.Now()
+	if wkdb.IsEmptyDevice(device) {
+		device = wkdb.Device{
+			Id:          u.s.store.NextPrimaryKey(),
+			Uid:         uid,
+			DeviceFlag:  uint64(deviceFlag),
+			DeviceLevel: uint8(wkproto.DeviceLevelSlave),
+			CreatedAt:   &now,
+			UpdatedAt:   &now,
+		}
+		if err := u.s.store.AddDevice(device); err != nil {
+			return device, err
+		}
+	}
+	device.RevokedAt = &now
+	err := u.s.store.UpdateDeviceRevoke(wkdb.Device{
+		Id:        device.Id,
+		Uid:       uid,
+		RevokedAt: &now,
+	})
+	return device, err
+}
+
+// 封禁用户（踢掉用户所有的连接，封禁期间不能连接和发送消息）
+func (u *UserAPI) ban(c *wkhttp.Context) {
+	var req userBanReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		u.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	if u.forwardToUserLeader(c, req.UID, bodyBytes) {
+		return
+	}
+	userBan, err := u.s.userBanManager.ban(req.UID, req.Reason, time.Duration(req.Expire)*time.Second)
+	if err != nil {
+		u.Error("封禁用户失败！", zap.Error(err), zap.String("uid", req.UID))
+		c.ResponseError(errors.New("封禁用户失败！"))
+		return
+	}
+	c.JSON(http.StatusOK, newUserBanResp(userBan))
+}
+
+// 解封用户
+func (u *UserAPI) unban(c *wkhttp.Context) {
+	var req struct {
+		UID string `json:"uid"` // 用户uid
+	}
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		u.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if strings.TrimSpace(req.UID) == "" {
+		c.ResponseError(errors.New("uid不能为空！"))
+		return
+	}
+	if u.forwardToUserLeader(c, req.UID, bodyBytes) {
+		return
+	}
+	err = u.s.userBanManager.unban(req.UID)
+	if err != nil {
+		u.Error("解封用户失败！", zap.Error(err), zap.String("uid", req.UID))
+		c.ResponseError(errors.New("解封用户失败！"))
+		return
+	}
+	c.ResponseOK()
+}
+
+// 如果本节点不是用户的槽领导节点，则将请求转发给用户的槽领导节点，返回是否已转发
+func (u *UserAPI) forwardToUserLeader(c *wkhttp.Context, uid string, bodyBytes []byte) bool {
+	leaderInfo, err := u.s.cluster.SlotLeaderOfChannel(uid, wkproto.ChannelTypePerson) // 获取用户的槽领导节点
+	if err != nil {
+		u.Error("获取用户所在节点失败！", zap.Error(err), zap.String("uid", uid))
+		c.ResponseError(errors.New("获取用户所在节点失败！"))
+		return true
+	}
+	if leaderInfo.Id == u.s.opts.Cluster.NodeId {
+		return false
+	}
+	u.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+	c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
+	return true
+}
+
+// 获取封禁的用户列表（汇总所有节点作为槽领导的封禁记录）
+func (u *UserAPI) getBans(c *wkhttp.Context) {
+	userBans, err := u.s.userBanManager.getLeaderBans()
+	if err != nil {
+		u.Error("获取封禁列表失败！", zap.Error(err))
+		c.ResponseError(errors.New("获取封禁列表失败！"))
+		return
+	}
+	resps := make([]*userBanResp, 0, len(userBans))
+	for _, userBan := range userBans {
+		resps = append(resps, newUserBanResp(userBan))
+	}
+
+	var respsLock sync.Mutex
+	requestGroup, _ := errgroup.WithContext(u.s.ctx)
+	for _, node := range u.s.clusterServer.GetConfig().Nodes {
+		if node.Id == u.s.opts.Cluster.NodeId || !node.Online {
+			continue
+		}
+		nodeId := node.Id
+		requestGroup.Go(func() error {
+			results, err := u.requestBans(nodeId)
+			if err != nil {
+				return err
+			}
+			respsLock.Lock()
+			resps = append(resps, results...)
+			respsLock.Unlock()
+			return nil
+		})
+	}
+	if err := requestGroup.Wait(); err != nil {
+		u.Error("获取节点的封禁列表失败！", zap.Error(err))
+		c.ResponseError(errors.New("获取节点的封禁列表失败！"))
+		return
+	}
+	c.JSON(http.StatusOK, resps)
+}
+
+func (u *UserAPI) requestBans(nodeId uint64) ([]*userBanResp, error) {
+	timeoutCtx, cancel := context.WithTimeout(u.s.ctx, time.Second*5)
+	defer cancel()
+
+	resp, err := u.s.cluster.RequestWithContext(timeoutCtx, nodeId, "/wk/userBans", nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != proto.StatusOK {
+		return nil, fmt.Errorf("requestBans: response status code is %d", resp.Status)
+	}
+	var resps []*userBanResp
+	err = wkutil.ReadJSONByByte(resp.Body, &resps)
+	if err != nil {
+		return nil, err
+	}
+	return resps, nil
+}
+
 // 更新用户的token
 func (u *UserAPI) updateToken(c *wkhttp.Context) {
 	var req UpdateTokenReq
@@ -362,6 +783,8 @@ func (u *UserAPI) updateToken(c *wkhttp.Context) {
 		}
 	}
 
+	u.s.webhook.userTokenUpdated(req.UID, req.DeviceFlag, req.DeviceLevel)
+
 	if req.DeviceLevel == wkproto.DeviceLevelMaster {
 		// 如果存在旧连接，则发起踢出请求
 		oldConns := u.s.userReactor.getConnsByDeviceFlag(req.UID, req.DeviceFlag)
@@ -372,6 +795,7 @@ func (u *UserAPI) updateToken(c *wkhttp.Context) {
 					ReasonCode: wkproto.ReasonConnectKick,
 					Reason:     "账号在其他设备上登录",
 				})
+				u.s.sessionLogManager.recordKick(oldConn, wkproto.ReasonConnectKick, "账号在其他设备上登录")
 
 				u.s.timingWheel.AfterFunc(time.Second*10, func() {
 					oldConn.close()
@@ -640,6 +1064,98 @@ func (u UpdateTokenReq) Check() error {
 	return nil
 }
 
+type userStatusReq struct {
+	UID        string `json:"uid"`         // 用户uid
+	Status     uint8  `json:"status"`      // 状态 0.available 1.away 2.busy
+	StatusText string `json:"status_text"` // 状态文本
+}
+
+func (r userStatusReq) Check() error {
+	if strings.TrimSpace(r.UID) == "" {
+		return errors.New("uid不能为空！")
+	}
+	if r.Status > uint8(wkdb.UserStatusBusy) {
+		return errors.New("status不合法！")
+	}
+	if len(r.StatusText) > 200 {
+		return errors.New("status_text不能超过200个字符！")
+	}
+	return nil
+}
+
+type userDeviceResp struct {
+	DeviceFlag    uint8  `json:"device_flag"`    // 设备标记 0. APP 1.web 2.pc
+	DeviceLevel   uint8  `json:"device_level"`   // 设备等级 0.为从设备 1.为主设备
+	Online        int    `json:"online"`         // 是否在线 1.在线 0.离线
+	ConnCount     int    `json:"conn_count"`     // 在线的连接数量
+	LastLoginAt   int64  `json:"last_login_at"`  // 最后登录时间（秒，客户端信息不变时频繁重连最多每5分钟更新一次）
+	LastLoginIP   string `json:"last_login_ip"`  // 最后登录ip
+	ClientVersion string `json:"client_version"` // 客户端版本
+	UserAgent     string `json:"user_agent"`     // 客户端UserAgent
+	CreatedAt     int64  `json:"created_at"`     // 创建时间（秒）
+	UpdatedAt     int64  `json:"updated_at"`     // 更新时间（秒）
+}
+
+func newUserDeviceResp(device wkdb.Device) *userDeviceResp {
+	resp := &userDeviceResp{
+		DeviceFlag:    uint8(device.DeviceFlag),
+		DeviceLevel:   device.DeviceLevel,
+		LastLoginIP:   device.LastLoginIP,
+		ClientVersion: device.ClientVersion,
+		UserAgent:     device.UserAgent,
+	}
+	if device.LastLoginAt != nil {
+		resp.LastLoginAt = device.LastLoginAt.Unix()
+	}
+	if device.CreatedAt != nil {
+		resp.CreatedAt = device.CreatedAt.Unix()
+	}
+	if device.UpdatedAt != nil {
+		resp.UpdatedAt = device.UpdatedAt.Unix()
+	}
+	return resp
+}
+
+type userBanReq struct {
+	UID    string `json:"uid"`    // 用户uid
+	Reason string `json:"reason"` // 封禁原因（会通过DisconnectPacket告知客户端）
+	Expire int64  `json:"expire"` // 封禁时长（秒），0表示永久封禁
+}
+
+func (r userBanReq) Check() error {
+	if strings.TrimSpace(r.UID) == "" {
+		return errors.New("uid不能为空！")
+	}
+	if r.Expire < 0 {
+		return errors.New("expire不能小于0！")
+	}
+	if len(r.Reason) > 200 {
+		return errors.New("reason不能超过200个字符！")
+	}
+	return nil
+}
+
+type userBanResp struct {
+	UID       string `json:"uid"`        // 用户uid
+	Reason    string `json:"reason"`     // 封禁原因
+	ExpireAt  int64  `json:"expire_at"`  // 封禁到期时间（秒），0表示永久封禁
+	CreatedAt int64  `json:"created_at"` // 封禁时间（秒）
+}
+
+func newUserBanResp(userBan wkdb.UserBan) *userBanResp {
+	resp := &userBanResp{
+		UID:    userBan.Uid,
+		Reason: userBan.Reason,
+	}
+	if userBan.ExpireAt != nil {
+		resp.ExpireAt = userBan.ExpireAt.Unix()
+	}
+	if userBan.CreatedAt != nil {
+		resp.CreatedAt = userBan.CreatedAt.Unix()
+	}
+	return resp
+}
+
 type OnlinestatusResp struct {
 	UID        string `json:"uid"`         // 在线用户uid
 	DeviceFlag uint8  `json:"device_flag"` // 设备标记 0. APP 1.web
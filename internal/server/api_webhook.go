@@ -0,0 +1,395 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// WebhookAPI webhook相关的管理api
+type WebhookAPI struct {
+	s *Server
+	wklog.Log
+}
+
+// NewWebhookAPI NewWebhookAPI
+func NewWebhookAPI(s *Server) *WebhookAPI {
+	return &WebhookAPI{
+		s:   s,
+		Log: wklog.NewWKLog("WebhookAPI"),
+	}
+}
+
+// Route route
+func (w *WebhookAPI) Route(r *wkhttp.WKHttp) {
+	r.GET("/webhook/channels", w.channelStats) // 频道webhook推送统计
+	r.GET("/webhook/sinks", w.sinkStats)       // 事件sink写入统计
+
+	r.GET("/webhook/deadletters", w.deadLetterList)           // 死信列表
+	r.GET("/webhook/deadletters/:id", w.deadLetterGet)        // 死信详情
+	r.POST("/webhook/deadletters/replay", w.deadLetterReplay) // 重放死信
+	r.POST("/webhook/deadletters/purge", w.deadLetterPurge)   // 清除死信
+
+	r.GET("/webhook/endpoints", w.endpointList)           // webhook端点列表
+	r.POST("/webhook/endpoints", w.endpointSave)          // 添加或更新webhook端点
+	r.POST("/webhook/endpoints/remove", w.endpointRemove) // 移除webhook端点
+}
+
+// 频道webhook推送统计（node_id指定查询的节点，默认为当前节点）
+func (w *WebhookAPI) channelStats(c *wkhttp.Context) {
+	if w.forward(c, wkutil.ParseUint64(c.Query("node_id")), nil) {
+		return
+	}
+	stats := w.s.channelWebhook.getStats()
+	c.JSON(http.StatusOK, gin.H{
+		"node_id": w.s.opts.Cluster.NodeId,
+		"total":   len(stats),
+		"data":    stats,
+	})
+}
+
+// 事件sink写入统计（node_id指定查询的节点，默认为当前节点）
+func (w *WebhookAPI) sinkStats(c *wkhttp.Context) {
+	if w.forward(c, wkutil.ParseUint64(c.Query("node_id")), nil) {
+		return
+	}
+	stats := w.s.webhook.sinks.getStats()
+	c.JSON(http.StatusOK, gin.H{
+		"node_id": w.s.opts.Cluster.NodeId,
+		"total":   len(stats),
+		"data":    stats,
+	})
+}
+
+// 死信列表（node_id指定查询的节点，默认为当前节点，按死信id倒序，offset_id为上一页最后一条的id）
+func (w *WebhookAPI) deadLetterList(c *wkhttp.Context) {
+	if w.forward(c, wkutil.ParseUint64(c.Query("node_id")), nil) {
+		return
+	}
+	event := c.Query("event")
+	offsetId := wkutil.ParseUint64(c.Query("offset_id"))
+	limit := wkutil.ParseInt(c.Query("limit"))
+	if limit <= 0 {
+		limit = 100
+	}
+	letters, err := w.s.store.GetWebhookDeadLetters(event, offsetId, limit)
+	if err != nil {
+		w.Error("get webhook dead letters failed", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	count, err := w.s.store.GetWebhookDeadLetterCount()
+	if err != nil {
+		w.Error("get webhook dead letter count failed", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	resps := make([]*webhookDeadLetterResp, 0, len(letters))
+	for _, letter := range letters {
+		resps = append(resps, newWebhookDeadLetterResp(letter, false))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"node_id": w.s.opts.Cluster.NodeId,
+		"total":   count,
+		"data":    resps,
+	})
+}
+
+// 死信详情（包含事件数据）
+func (w *WebhookAPI) deadLetterGet(c *wkhttp.Context) {
+	if w.forward(c, wkutil.ParseUint64(c.Query("node_id")), nil) {
+		return
+	}
+	id := wkutil.ParseUint64(c.Param("id"))
+	letter, err := w.s.store.GetWebhookDeadLetter(id)
+	if err != nil {
+		if err == wkdb.ErrNotFound {
+			c.ResponseStatus(http.StatusNotFound)
+			return
+		}
+		w.Error("get webhook dead letter failed", zap.Error(err), zap.Uint64("id", id))
+		c.ResponseError(err)
+		return
+	}
+	c.JSON(http.StatusOK, newWebhookDeadLetterResp(letter, true))
+}
+
+// 重放死信（ids指定重放的死信，all为true时重放全部死信，event不为空时只重放此类型的事件）
+func (w *WebhookAPI) deadLetterReplay(c *wkhttp.Context) {
+	var req struct {
+		NodeId uint64   `json:"node_id"`
+		Ids    []uint64 `json:"ids"`
+		All    bool     `json:"all"`
+		Event  string   `json:"event"`
+	}
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		w.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if w.forward(c, req.NodeId, bodyBytes) {
+		return
+	}
+	ids := req.Ids
+	if req.All {
+		ids, err = w.s.webhook.deadLetters.allIds(req.Event)
+		if err != nil {
+			w.Error("get webhook dead letter ids failed", zap.Error(err))
+			c.ResponseError(err)
+			return
+		}
+	}
+	if len(ids) == 0 {
+		c.ResponseError(errors.New("ids不能为空！"))
+		return
+	}
+	count, err := w.s.webhook.deadLetters.replay(ids)
+	if err != nil {
+		w.Error("replay webhook dead letters failed", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"node_id": w.s.opts.Cluster.NodeId,
+		"count":   count,
+	})
+}
+
+// 清除死信（ids指定清除的死信，all为true时清除全部死信，before大于0时清除此时间（秒）之前进入死信的事件）
+func (w *WebhookAPI) deadLetterPurge(c *wkhttp.Context) {
+	var req struct {
+		NodeId uint64   `json:"node_id"`
+		Ids    []uint64 `json:"ids"`
+		All    bool     `json:"all"`
+		Before int64    `json:"before"`
+	}
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		w.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if w.forward(c, req.NodeId, bodyBytes) {
+		return
+	}
+	switch {
+	case req.All:
+		err = w.s.store.RemoveWebhookDeadLettersBefore(math.MaxUint64)
+	case req.Before > 0:
+		// 死信id为进入死信的纳秒时间
+		err = w.s.store.RemoveWebhookDeadLettersBefore(uint64(time.Unix(req.Before, 0).UnixNano()))
+	case len(req.Ids) > 0:
+		err = w.s.store.RemoveWebhookDeadLetters(req.Ids)
+	default:
+		c.ResponseError(errors.New("请指定ids、all或before！"))
+		return
+	}
+	if err != nil {
+		w.Error("purge webhook dead letters failed", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	w.s.webhook.deadLetters.check()
+	c.ResponseOK()
+}
+
+// 转发请求到指定的节点，返回是否已转发（nodeId为0或当前节点时不转发）
+func (w *WebhookAPI) forward(c *wkhttp.Context, nodeId uint64, body []byte) bool {
+	if nodeId == 0 || nodeId == w.s.opts.Cluster.NodeId {
+		return false
+	}
+	node, err := w.s.clusterServer.NodeInfoById(nodeId)
+	if err != nil {
+		c.ResponseError(err)
+		return true
+	}
+	if node == nil {
+		w.Error("node not found", zap.Uint64("nodeId", nodeId))
+		c.ResponseError(fmt.Errorf("node not found"))
+		return true
+	}
+	c.ForwardWithBody(fmt.Sprintf("%s%s", node.ApiServerAddr, c.Request.URL.Path), body)
+	return true
+}
+
+// webhook端点列表（不返回密钥）
+func (w *WebhookAPI) endpointList(c *wkhttp.Context) {
+	if w.forwardToSlotLeader(c, nil) {
+		return
+	}
+	endpoints, err := w.s.store.GetWebhookEndpoints()
+	if err != nil {
+		w.Error("get webhook endpoints failed", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	resps := make([]*webhookEndpointResp, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		resps = append(resps, newWebhookEndpointResp(endpoint))
+	}
+	c.JSON(http.StatusOK, resps)
+}
+
+// 添加或更新webhook端点（secret不传时保留原来的密钥）
+func (w *WebhookAPI) endpointSave(c *wkhttp.Context) {
+	var req webhookEndpointReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		w.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if err := req.check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	if w.forwardToSlotLeader(c, bodyBytes) {
+		return
+	}
+	endpoints, err := w.s.store.GetWebhookEndpoints()
+	if err != nil {
+		w.Error("get webhook endpoints failed", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	now := time.Now().Unix()
+	endpoint := wkdb.WebhookEndpoint{
+		Name:      req.Name,
+		HTTPAddr:  req.HTTPAddr,
+		GRPCAddr:  req.GRPCAddr,
+		Events:    req.Events,
+		BatchSize: req.BatchSize,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	for _, channelType := range req.ChannelTypes {
+		endpoint.ChannelTypes = append(endpoint.ChannelTypes, uint8(channelType))
+	}
+	for _, old := range endpoints {
+		if old.Name == req.Name {
+			endpoint.CreatedAt = old.CreatedAt
+			endpoint.Secret = old.Secret
+			break
+		}
+	}
+	if req.Secret != nil {
+		endpoint.Secret = *req.Secret
+	}
+	err = w.s.store.SaveWebhookEndpoint(endpoint)
+	if err != nil {
+		w.Error("save webhook endpoint failed", zap.Error(err), zap.String("name", req.Name))
+		c.ResponseError(errors.New("保存webhook端点失败！"))
+		return
+	}
+	if err = w.syncEndpoints(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	c.ResponseOK()
+}
+
+// 移除webhook端点（发件箱中未投递的此端点的事件会被丢弃）
+func (w *WebhookAPI) endpointRemove(c *wkhttp.Context) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		w.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		c.ResponseError(errors.New("name不能为空！"))
+		return
+	}
+	if w.forwardToSlotLeader(c, bodyBytes) {
+		return
+	}
+	err = w.s.store.RemoveWebhookEndpoint(req.Name)
+	if err != nil {
+		w.Error("remove webhook endpoint failed", zap.Error(err), zap.String("name", req.Name))
+		c.ResponseError(errors.New("移除webhook端点失败！"))
+		return
+	}
+	if err = w.syncEndpoints(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	c.ResponseOK()
+}
+
+// 将槽位0上的端点列表更新到各个节点的缓存内
+func (w *WebhookAPI) syncEndpoints() error {
+	err := w.s.webhook.endpoints.sync()
+	if err != nil {
+		w.Error("同步webhook端点到节点缓存失败！", zap.Error(err))
+		return errors.New("同步webhook端点到节点缓存失败！")
+	}
+	return nil
+}
+
+// 转发请求到槽位0的领导节点（webhook端点存储在槽位0上），返回是否已转发
+func (w *WebhookAPI) forwardToSlotLeader(c *wkhttp.Context, body []byte) bool {
+	nodeInfo, err := w.s.cluster.SlotLeaderNodeInfo(0)
+	if err != nil {
+		w.Error("获取slot所在节点失败！", zap.Error(err), zap.Uint32("slotId", 0))
+		c.ResponseError(errors.New("获取slot所在节点失败！"))
+		return true
+	}
+	if nodeInfo.Id == w.s.opts.Cluster.NodeId {
+		return false
+	}
+	c.ForwardWithBody(fmt.Sprintf("%s%s", nodeInfo.ApiServerAddr, c.Request.URL.Path), body)
+	return true
+}
+
+// 添加或更新webhook端点的请求
+type webhookEndpointReq struct {
+	Name         string   `json:"name"`          // 端点名称（唯一）
+	HTTPAddr     string   `json:"http_addr"`     // http地址（与grpc_addr二选一）
+	GRPCAddr     string   `json:"grpc_addr"`     // grpc地址
+	Events       []string `json:"events"`        // 关注的事件，为空则推送所有事件
+	ChannelTypes []int    `json:"channel_types"` // 关注的频道类型，为空则推送所有频道类型（只过滤频道相关的事件）
+	BatchSize    uint32   `json:"batch_size"`    // msg.notify每次推送的消息数量，为0则使用webhook.msgNotifyEventCountPerPush
+	Secret       *string  `json:"secret"`        // 签名密钥，不传则保留原来的密钥，传空字符串则不签名
+}
+
+func (r webhookEndpointReq) check() error {
+	if len(r.Name) == 0 || len(r.Name) > 64 {
+		return errors.New("name不能为空且长度不能超过64！")
+	}
+	for _, ch := range r.Name {
+		if !(ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9' || ch == '-' || ch == '_' || ch == '.') {
+			return errors.New("name只能包含字母、数字、-、_和.！")
+		}
+	}
+	if r.HTTPAddr == "" && r.GRPCAddr == "" {
+		return errors.New("http_addr和grpc_addr不能都为空！")
+	}
+	if r.HTTPAddr != "" && r.GRPCAddr != "" {
+		return errors.New("http_addr和grpc_addr只能设置一个！")
+	}
+	for _, event := range r.Events {
+		if _, ok := eventWebHook[event]; !ok {
+			return fmt.Errorf("不支持的事件[%s]！", event)
+		}
+	}
+	for _, channelType := range r.ChannelTypes {
+		if channelType <= 0 || channelType > math.MaxUint8 {
+			return fmt.Errorf("频道类型[%d]有误！", channelType)
+		}
+	}
+	return nil
+}
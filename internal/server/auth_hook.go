@@ -0,0 +1,270 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/RussellLuo/timingwheel"
+	"github.com/WuKongIM/WuKongIM/pkg/grpcpool"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhook"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+)
+
+// 委托第三方认证服务认证客户端连接
+// 客户端连接时（用户的槽领导节点上）把uid、token、设备信息和客户端ip发给认证服务（http或grpc），认证服务返回connack的reason code和设备等级。
+// 认证成功的结果按 uid + 设备标记 缓存CacheTTL时间，token不变的重连不再请求认证服务。
+//
+// http方式: POST {HTTPAddr} 请求体为 authHookReq 的json，返回200和 authHookResp 的json
+// grpc方式: 调用 wkhook.WebhookService/Auth
+type authHook struct {
+	s *Server
+	wklog.Log
+	httpClient *http.Client
+	grpcPool   *grpcpool.Pool // 认证服务的grpc客户端
+
+	cacheLock sync.RWMutex
+	cache     map[string]*authHookCache // 认证成功的缓存，key为 uid-deviceFlag
+
+	cleanTimer *timingwheel.Timer
+}
+
+type authHookCache struct {
+	token    string
+	result   *authHookResult
+	expireAt time.Time
+}
+
+// 认证结果
+type authHookResult struct {
+	ReasonCode  wkproto.ReasonCode
+	DeviceLevel wkproto.DeviceLevel
+	Reason      string
+}
+
+func newAuthHook(s *Server) *authHook {
+	a := &authHook{
+		s:     s,
+		Log:   wklog.NewWKLog("authHook"),
+		cache: make(map[string]*authHookCache),
+	}
+	if s.opts.AuthHookGRPCOn() {
+		grpcPool, err := grpcpool.New(func() (*grpc.ClientConn, error) {
+			return grpc.Dial(s.opts.AuthHook.GRPCAddr, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithKeepaliveParams(keepalive.ClientParameters{
+				Time:    5 * time.Minute,
+				Timeout: 2 * time.Second,
+			}))
+		}, 2, 20, time.Minute*5)
+		if err != nil {
+			panic(err)
+		}
+		a.grpcPool = grpcPool
+	} else {
+		a.httpClient = &http.Client{
+			Timeout: s.opts.AuthHook.Timeout,
+			Transport: &http.Transport{
+				DialContext: (&net.Dialer{
+					Timeout:   5 * time.Second,
+					KeepAlive: 5 * time.Second,
+				}).DialContext,
+				ForceAttemptHTTP2:   true,
+				MaxIdleConns:        200,
+				MaxIdleConnsPerHost: 200,
+				IdleConnTimeout:     300 * time.Second,
+				TLSHandshakeTimeout: time.Second * 5,
+			},
+		}
+	}
+	return a
+}
+
+func (a *authHook) start() error {
+	if a.s.opts.AuthHook.CacheTTL > 0 {
+		a.cleanTimer = a.s.Schedule(a.s.opts.AuthHook.CacheTTL, a.cleanCache)
+	}
+	return nil
+}
+
+func (a *authHook) stop() {
+	if a.cleanTimer != nil {
+		a.cleanTimer.Stop()
+	}
+	if a.grpcPool != nil {
+		a.grpcPool.Close()
+	}
+}
+
+// 认证连接
+func (a *authHook) auth(req *authHookReq) (*authHookResult, error) {
+	cacheKey := a.cacheKey(req.UID, req.DeviceFlag)
+	if result := a.getCache(cacheKey, req.Token); result != nil {
+		return result, nil
+	}
+
+	var (
+		resp *authHookResp
+		err  error
+	)
+	if a.grpcPool != nil {
+		resp, err = a.requestGRPC(req)
+	} else {
+		resp, err = a.requestHTTP(req)
+	}
+	if err != nil {
+		return nil, err
+	}
+	result := resp.result()
+	if result.ReasonCode == wkproto.ReasonSuccess && a.s.opts.AuthHook.CacheTTL > 0 {
+		a.cacheLock.Lock()
+		a.cache[cacheKey] = &authHookCache{
+			token:    req.Token,
+			result:   result,
+			expireAt: time.Now().Add(a.s.opts.AuthHook.CacheTTL),
+		}
+		a.cacheLock.Unlock()
+	}
+	return result, nil
+}
+
+func (a *authHook) requestHTTP(req *authHookReq) (*authHookResp, error) {
+	resp, err := a.httpClient.Post(a.s.opts.AuthHook.HTTPAddr, "application/json", bytes.NewBufferString(wkutil.ToJSON(req)))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("auth hook: response status code is %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	authResp := &authHookResp{}
+	err = wkutil.ReadJSONByByte(body, authResp)
+	if err != nil {
+		return nil, err
+	}
+	return authResp, nil
+}
+
+func (a *authHook) requestGRPC(req *authHookReq) (*authHookResp, error) {
+	ctx, cancel := context.WithTimeout(a.s.ctx, a.s.opts.AuthHook.Timeout)
+	defer cancel()
+	clientConn, err := a.grpcPool.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer clientConn.Close()
+
+	cli := wkhook.NewWebhookServiceClient(clientConn)
+	resp, err := cli.Auth(ctx, &wkhook.AuthReq{
+		Uid:           req.UID,
+		Token:         req.Token,
+		DeviceFlag:    int32(req.DeviceFlag),
+		DeviceId:      req.DeviceID,
+		ClientIp:      req.ClientIP,
+		ProtoVersion:  int32(req.ProtoVersion),
+		ClientVersion: req.ClientVersion,
+		UserAgent:     req.UserAgent,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &authHookResp{
+		ReasonCode:  int(resp.ReasonCode),
+		DeviceLevel: int(resp.DeviceLevel),
+		Reason:      resp.Reason,
+	}, nil
+}
+
+// 移除用户设备的认证缓存（设备被撤销或退出登录时调用）
+func (a *authHook) removeCache(uid string, deviceFlag wkproto.DeviceFlag) {
+	a.cacheLock.Lock()
+	delete(a.cache, a.cacheKey(uid, deviceFlag))
+	a.cacheLock.Unlock()
+}
+
+func (a *authHook) getCache(cacheKey string, token string) *authHookResult {
+	a.cacheLock.RLock()
+	defer a.cacheLock.RUnlock()
+	cache := a.cache[cacheKey]
+	if cache == nil || cache.token != token || time.Now().After(cache.expireAt) {
+		return nil
+	}
+	return cache.result
+}
+
+// 清除过期的缓存
+func (a *authHook) cleanCache() {
+	now := time.Now()
+	a.cacheLock.Lock()
+	defer a.cacheLock.Unlock()
+	for key, cache := range a.cache {
+		if now.After(cache.expireAt) {
+			delete(a.cache, key)
+		}
+	}
+}
+
+func (a *authHook) cacheKey(uid string, deviceFlag wkproto.DeviceFlag) string {
+	return fmt.Sprintf("%s-%d", uid, deviceFlag)
+}
+
+// 认证请求
+type authHookReq struct {
+	UID           string             `json:"uid"`            // 用户uid
+	Token         string             `json:"token"`          // 连接token
+	DeviceFlag    wkproto.DeviceFlag `json:"device_flag"`    // 设备标记 0.app 1.web 2.pc
+	DeviceID      string             `json:"device_id"`      // 设备id
+	ClientIP      string             `json:"client_ip"`      // 客户端ip
+	ProtoVersion  uint8              `json:"proto_version"`  // 客户端协议版本
+	ClientVersion string             `json:"client_version"` // 客户端版本
+	UserAgent     string             `json:"user_agent"`     // 客户端user agent
+}
+
+func newAuthHookReq(connectPacket *wkproto.ConnectPacket, meta connMeta) *authHookReq {
+	return &authHookReq{
+		UID:           connectPacket.UID,
+		Token:         connectPacket.Token,
+		DeviceFlag:    connectPacket.DeviceFlag,
+		DeviceID:      connectPacket.DeviceID,
+		ClientIP:      meta.IP,
+		ProtoVersion:  connectPacket.Version,
+		ClientVersion: meta.ClientVersion,
+		UserAgent:     meta.UserAgent,
+	}
+}
+
+// 认证响应
+type authHookResp struct {
+	ReasonCode  int    `json:"reason_code"`  // 认证结果，与connack的reason code一致 1.成功 其他为失败原因
+	DeviceLevel int    `json:"device_level"` // 设备等级 0.从设备 1.主设备
+	Reason      string `json:"reason"`       // 失败原因描述
+}
+
+// 转换为认证结果，无效的reason code视为认证失败，无效的设备等级视为从设备
+func (r *authHookResp) result() *authHookResult {
+	result := &authHookResult{
+		ReasonCode: wkproto.ReasonCode(r.ReasonCode),
+		Reason:     r.Reason,
+	}
+	if r.ReasonCode <= int(wkproto.ReasonUnknown) || r.ReasonCode > int(wkproto.ReasonDisband) {
+		result.ReasonCode = wkproto.ReasonAuthFail
+	}
+	if r.DeviceLevel == int(wkproto.DeviceLevelMaster) {
+		result.DeviceLevel = wkproto.DeviceLevelMaster
+	} else {
+		result.DeviceLevel = wkproto.DeviceLevelSlave
+	}
+	return result
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthHookHTTP(t *testing.T) {
+	var requestCount atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestCount.Add(1)
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		req := &authHookReq{}
+		err = wkutil.ReadJSONByByte(body, req)
+		assert.NoError(t, err)
+
+		resp := authHookResp{ReasonCode: int(wkproto.ReasonSuccess), DeviceLevel: int(wkproto.DeviceLevelMaster)}
+		switch req.Token {
+		case "ban":
+			resp = authHookResp{ReasonCode: int(wkproto.ReasonBan), Reason: "banned"}
+		case "invalid":
+			resp = authHookResp{ReasonCode: 200}
+		}
+		_, _ = w.Write([]byte(wkutil.ToJSON(resp)))
+	}))
+	defer ts.Close()
+
+	s := &Server{opts: NewOptions(WithAuthHookHTTPAddr(ts.URL))}
+	a := newAuthHook(s)
+
+	req := &authHookReq{UID: "u1", Token: "ok", DeviceFlag: wkproto.APP, ClientIP: "127.0.0.1"}
+	result, err := a.auth(req)
+	assert.NoError(t, err)
+	assert.Equal(t, wkproto.ReasonSuccess, result.ReasonCode)
+	assert.Equal(t, wkproto.DeviceLevelMaster, result.DeviceLevel)
+
+	// 认证成功的结果被缓存
+	_, err = a.auth(req)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), requestCount.Load())
+
+	// 移除缓存后重新请求
+	a.removeCache("u1", wkproto.APP)
+	_, err = a.auth(req)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), requestCount.Load())
+
+	// 认证失败的结果映射为对应的reason code，并且不缓存
+	banReq := &authHookReq{UID: "u2", Token: "ban", DeviceFlag: wkproto.APP}
+	result, err = a.auth(banReq)
+	assert.NoError(t, err)
+	assert.Equal(t, wkproto.ReasonBan, result.ReasonCode)
+	assert.Equal(t, "banned", result.Reason)
+	_, _ = a.auth(banReq)
+	assert.Equal(t, int32(4), requestCount.Load())
+
+	// 无效的reason code视为认证失败
+	result, err = a.auth(&authHookReq{UID: "u3", Token: "invalid", DeviceFlag: wkproto.APP})
+	assert.NoError(t, err)
+	assert.Equal(t, wkproto.ReasonAuthFail, result.ReasonCode)
+	assert.Equal(t, wkproto.DeviceLevelSlave, result.DeviceLevel)
+}
@@ -568,7 +568,24 @@ func (c *channel) makeReceiverTag() (*tag, error) {
 			}
 		}
 	} else if c.channelType == wkproto.ChannelTypeTemp { // 临时频道
-		subscribers = c.getTmpSubscribers()
+		tmpChannelId := c.channelId
+		if c.r.s.opts.IsCmdChannel(c.channelId) {
+			tmpChannelId = c.r.opts.CmdChannelConvertOrginalChannel(c.channelId)
+		}
+		explicit := false
+		if c.r.s.opts.IsTmpChannel(tmpChannelId) {
+			if explicit, err = c.r.s.tmpChannelManager.exist(tmpChannelId); err != nil {
+				return nil, err
+			}
+		}
+		if explicit { // 显式创建的临时频道，订阅者已持久化
+			subscribers, err = c.r.s.requestSubscribers(tmpChannelId, c.channelType)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			subscribers = c.getTmpSubscribers()
+		}
 	} else {
 
 		// 处理非个人频道
@@ -576,9 +593,12 @@ func (c *channel) makeReceiverTag() (*tag, error) {
 		if c.r.s.opts.IsCmdChannel(c.channelId) {
 			fakeChannelId = c.r.opts.CmdChannelConvertOrginalChannel(c.channelId) // 将cmd频道id还原成对应的频道id
 		}
+		if c.r.s.opts.IsThreadChannel(fakeChannelId) {
+			fakeChannelId, _ = c.r.opts.ThreadChannelConvertParent(fakeChannelId) // 子区频道的订阅者为父频道的订阅者
+		}
 
 		// 请求频道的订阅者
-		subscribers, err = c.requestSubscribers(fakeChannelId, c.channelType)
+		subscribers, err = c.r.s.requestSubscribers(fakeChannelId, c.channelType)
 		if err != nil {
 			return nil, err
 		}
@@ -630,9 +650,9 @@ func (c *channel) makeReceiverTag() (*tag, error) {
 }
 
 // requestSubscribers 请求订阅者
-func (c *channel) requestSubscribers(channelId string, channelType uint8) ([]string, error) {
+func (s *Server) requestSubscribers(channelId string, channelType uint8) ([]string, error) {
 
-	leaderNode, err := c.r.s.cluster.LeaderOfChannelForRead(channelId, channelType)
+	leaderNode, err := s.cluster.LeaderOfChannelForRead(channelId, channelType)
 	if err != nil {
 		return nil, err
 	}
@@ -640,9 +660,9 @@ func (c *channel) requestSubscribers(channelId string, channelType uint8) ([]str
 		return nil, errors.New("requestSubscribers: channel leader is nil")
 	}
 
-	if leaderNode.Id == c.r.s.opts.Cluster.NodeId {
+	if leaderNode.Id == s.opts.Cluster.NodeId {
 		// 如果是本节点，则直接获取订阅者
-		members, err := c.r.s.store.GetSubscribers(channelId, channelType)
+		members, err := s.store.GetSubscribers(channelId, channelType)
 		if err != nil {
 			return nil, err
 		}
@@ -654,7 +674,7 @@ func (c *channel) requestSubscribers(channelId string, channelType uint8) ([]str
 
 	}
 
-	timeoutCtx, cancel := context.WithTimeout(c.r.s.ctx, time.Second*5)
+	timeoutCtx, cancel := context.WithTimeout(s.ctx, time.Second*5)
 	defer cancel()
 
 	req := &subscriberGetReq{
@@ -663,13 +683,13 @@ func (c *channel) requestSubscribers(channelId string, channelType uint8) ([]str
 	}
 	data := req.Marshal()
 
-	resp, err := c.r.s.cluster.RequestWithContext(timeoutCtx, leaderNode.Id, "/wk/getSubscribers", data)
+	resp, err := s.cluster.RequestWithContext(timeoutCtx, leaderNode.Id, "/wk/getSubscribers", data)
 	if err != nil {
 		return nil, err
 	}
 
 	if resp.Status != proto.StatusOK {
-		c.Error("requestSubscribers: response status code is not ok", zap.Int("status", int(resp.Status)), zap.String("body", string(resp.Body)))
+		s.Error("requestSubscribers: response status code is not ok", zap.Int("status", int(resp.Status)), zap.String("body", string(resp.Body)))
 		return nil, fmt.Errorf("requestSubscribers: response status code is %d", resp.Status)
 	}
 
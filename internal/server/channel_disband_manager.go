@@ -0,0 +1,261 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/RussellLuo/timingwheel"
+	cluster "github.com/WuKongIM/WuKongIM/pkg/cluster/clusterserver"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+// 频道解散
+// 解散的步骤：冻结频道（不能再发送消息） -> 通过cmd消息通知频道成员 -> 宽限期后清除频道消息和成员（可选）
+// 每一步的进度持久化在频道所属的槽上，并通过webhook（channel.disband）通知第三方
+type channelDisbandManager struct {
+	s *Server
+	wklog.Log
+	checkTimer *timingwheel.Timer
+	checking   atomic.Bool
+}
+
+func newChannelDisbandManager(s *Server) *channelDisbandManager {
+	return &channelDisbandManager{
+		s:   s,
+		Log: wklog.NewWKLog("channelDisbandManager"),
+	}
+}
+
+func (c *channelDisbandManager) start() error {
+	c.checkTimer = c.s.Schedule(c.s.opts.Channel.DisbandCheckInterval, func() {
+		if !c.checking.CompareAndSwap(false, true) { // 上一次检查还没结束
+			return
+		}
+		go func() {
+			defer c.checking.Store(false)
+			c.checkPurge()
+		}()
+	})
+	return nil
+}
+
+func (c *channelDisbandManager) stop() {
+	if c.checkTimer != nil {
+		c.checkTimer.Stop()
+	}
+}
+
+// 解散频道（需要在频道的槽领导节点上执行）
+func (c *channelDisbandManager) disband(req channelDisbandReq) (wkdb.ChannelDisband, error) {
+	channelDisband, err := c.s.store.GetChannelDisband(req.ChannelId, req.ChannelType)
+	if err != nil && err != wkdb.ErrNotFound {
+		return wkdb.EmptyChannelDisband, err
+	}
+	if channelDisband.Status != wkdb.ChannelDisbandStatusNone && channelDisband.Status != wkdb.ChannelDisbandStatusPurged {
+		return wkdb.EmptyChannelDisband, errors.New("频道正在解散或已解散！")
+	}
+
+	channelInfo, err := c.s.store.GetChannel(req.ChannelId, req.ChannelType)
+	if err != nil && err != wkdb.ErrNotFound {
+		return wkdb.EmptyChannelDisband, err
+	}
+	if wkdb.IsEmptyChannelInfo(channelInfo) {
+		return wkdb.EmptyChannelDisband, errors.New("频道不存在！")
+	}
+
+	now := time.Now()
+	channelDisband = wkdb.ChannelDisband{
+		ChannelId:   req.ChannelId,
+		ChannelType: req.ChannelType,
+		Reason:      req.Reason,
+		Purge:       req.Purge == 1,
+		CreatedAt:   &now,
+	}
+	if channelDisband.Purge {
+		gracePeriod := c.s.opts.Channel.DisbandGracePeriod
+		if req.GracePeriod > 0 {
+			gracePeriod = time.Duration(req.GracePeriod) * time.Second
+		}
+		purgeAt := now.Add(gracePeriod)
+		channelDisband.PurgeAt = &purgeAt
+	}
+
+	// 冻结频道
+	channelInfo.Disband = true
+	channelInfo.UpdatedAt = &now
+	err = c.s.store.UpdateChannelInfo(channelInfo)
+	if err != nil {
+		return wkdb.EmptyChannelDisband, err
+	}
+	err = c.s.requestUpdateChannelInfo(channelInfo)
+	if err != nil { // 频道领导更新失败，频道领导重新加载频道时依然会读到解散状态
+		c.Warn("requestUpdateChannelInfo failed", zap.Error(err), zap.String("channelId", req.ChannelId), zap.Uint8("channelType", req.ChannelType))
+	}
+	if err = c.step(&channelDisband, channelInfo, wkdb.ChannelDisbandStatusFrozen); err != nil {
+		return wkdb.EmptyChannelDisband, err
+	}
+
+	// 通知频道成员
+	err = c.notifyMembers(channelDisband)
+	if err != nil {
+		return channelDisband, err
+	}
+	if err = c.step(&channelDisband, channelInfo, wkdb.ChannelDisbandStatusNotified); err != nil {
+		return channelDisband, err
+	}
+
+	// 没有宽限期，立即清除
+	if channelDisband.NeedPurge(time.Now()) {
+		err = c.purge(channelDisband, channelInfo)
+		if err != nil {
+			return channelDisband, err
+		}
+		channelDisband.Status = wkdb.ChannelDisbandStatusPurged
+	}
+	return channelDisband, nil
+}
+
+// 检查本节点负责的已解散频道是否需要清除
+func (c *channelDisbandManager) checkPurge() {
+	channelDisbands, err := c.s.store.GetChannelDisbands()
+	if err != nil {
+		c.Error("GetChannelDisbands failed", zap.Error(err))
+		return
+	}
+	now := time.Now()
+	for _, channelDisband := range channelDisbands {
+		if !channelDisband.NeedPurge(now) {
+			continue
+		}
+		// 只有槽领导节点负责清除
+		slotLeaderId, err := c.s.cluster.SlotLeaderIdOfChannel(channelDisband.ChannelId, channelDisband.ChannelType)
+		if err != nil {
+			c.Warn("SlotLeaderIdOfChannel failed", zap.Error(err), zap.String("channelId", channelDisband.ChannelId))
+			continue
+		}
+		if !c.s.opts.IsLocalNode(slotLeaderId) {
+			continue
+		}
+		channelInfo, err := c.s.store.GetChannel(channelDisband.ChannelId, channelDisband.ChannelType)
+		if err != nil && err != wkdb.ErrNotFound {
+			c.Warn("GetChannel failed", zap.Error(err), zap.String("channelId", channelDisband.ChannelId))
+			continue
+		}
+		err = c.purge(channelDisband, channelInfo)
+		if err != nil {
+			c.Error("purge disband channel failed", zap.Error(err), zap.String("channelId", channelDisband.ChannelId), zap.Uint8("channelType", channelDisband.ChannelType))
+		}
+	}
+}
+
+// 清除频道及其cmd频道的消息、成员和分布式配置
+func (c *channelDisbandManager) purge(channelDisband wkdb.ChannelDisband, channelInfo wkdb.ChannelInfo) error {
+	c.Info("purge disband channel", zap.String("channelId", channelDisband.ChannelId), zap.Uint8("channelType", channelDisband.ChannelType))
+
+	channelIds := []string{channelDisband.ChannelId, c.s.opts.OrginalConvertCmdChannel(channelDisband.ChannelId)}
+	for _, channelId := range channelIds {
+		if err := c.s.purgeChannel(channelId, channelDisband.ChannelType); err != nil {
+			return err
+		}
+	}
+	return c.step(&channelDisband, channelInfo, wkdb.ChannelDisbandStatusPurged)
+}
+
+// 通过cmd消息通知频道成员频道已解散
+func (c *channelDisbandManager) notifyMembers(channelDisband wkdb.ChannelDisband) error {
+	param := map[string]interface{}{
+		"channel_id":   channelDisband.ChannelId,
+		"channel_type": channelDisband.ChannelType,
+		"reason":       channelDisband.Reason,
+	}
+	if channelDisband.PurgeAt != nil {
+		param["purge_at"] = channelDisband.PurgeAt.Unix()
+	}
+	payload := []byte(wkutil.ToJSON(map[string]interface{}{
+		"cmd":   "channelDisband",
+		"param": param,
+	}))
+	clientMsgNo := fmt.Sprintf("%s0", wkutil.GenUUID())
+	_, err := sendMessageToChannel(c.s, MessageSendReq{
+		Header: MessageHeader{
+			SyncOnce: 1,
+		},
+		FromUID:     c.s.opts.SystemUID,
+		ChannelID:   channelDisband.ChannelId,
+		ChannelType: channelDisband.ChannelType,
+		Payload:     payload,
+	}, channelDisband.ChannelId, channelDisband.ChannelType, clientMsgNo, wkproto.StreamFlagIng)
+	return err
+}
+
+// 保存解散进度并通知webhook
+func (c *channelDisbandManager) step(channelDisband *wkdb.ChannelDisband, channelInfo wkdb.ChannelInfo, status wkdb.ChannelDisbandStatus) error {
+	now := time.Now()
+	channelDisband.Status = status
+	channelDisband.UpdatedAt = &now
+	err := c.s.store.AddOrUpdateChannelDisband(*channelDisband)
+	if err != nil {
+		c.Error("AddOrUpdateChannelDisband failed", zap.Error(err), zap.String("channelId", channelDisband.ChannelId), zap.String("step", status.String()))
+		return err
+	}
+
+	data := newChannelDisbandResp(*channelDisband)
+	c.s.webhook.TriggerEvent(&Event{
+		Event:       EventChannelDisband,
+		OrderKey:    wkutil.ChannelToKey(channelDisband.ChannelId, channelDisband.ChannelType), // 解散的步骤按顺序投递
+		Data:        data,
+		ChannelType: channelDisband.ChannelType,
+	})
+	c.s.channelWebhook.trigger(channelInfo, EventChannelDisband, data)
+	return nil
+}
+
+// 将频道信息更新到频道领导节点的内存中
+func (s *Server) requestUpdateChannelInfo(channelInfo wkdb.ChannelInfo) error {
+	leaderNode, err := s.cluster.LeaderOfChannelForRead(channelInfo.ChannelId, channelInfo.ChannelType)
+	if errors.Is(err, cluster.ErrChannelClusterConfigNotFound) { // 频道还没有被激活过
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if leaderNode == nil {
+		return errors.New("requestUpdateChannelInfo: channel leader is nil")
+	}
+	if s.opts.IsLocalNode(leaderNode.Id) {
+		s.updateLocalChannelInfo(channelInfo)
+		return nil
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(s.ctx, time.Second*5)
+	defer cancel()
+
+	resp, err := s.cluster.RequestWithContext(timeoutCtx, leaderNode.Id, "/wk/channelInfoUpdate", []byte(wkutil.ToJSON(channelInfo)))
+	if err != nil {
+		return err
+	}
+	if resp.Status != proto.StatusOK {
+		return fmt.Errorf("requestUpdateChannelInfo: response status code is %d", resp.Status)
+	}
+	return nil
+}
+
+// 更新本节点内存中频道（包括cmd频道）的频道信息
+func (s *Server) updateLocalChannelInfo(channelInfo wkdb.ChannelInfo) {
+	channelIds := []string{channelInfo.ChannelId, s.opts.OrginalConvertCmdChannel(channelInfo.ChannelId)}
+	for _, channelId := range channelIds {
+		channelKey := wkutil.ChannelToKey(channelId, channelInfo.ChannelType)
+		ch := s.channelReactor.reactorSub(channelKey).channel(channelKey)
+		if ch != nil {
+			ch.info = channelInfo
+		}
+	}
+}
@@ -85,6 +85,16 @@ func (r *channelReactor) processInit(req *initReq) {
 		return
 	}
 
+	// 领导节点加载频道基础信息（超大频道、封禁、解散等判断需要）
+	if cfg.LeaderId == r.s.opts.Cluster.NodeId && req.ch.channelType != wkproto.ChannelTypePerson {
+		channelInfo, err := r.s.store.GetChannel(r.opts.CmdChannelConvertOrginalChannel(req.ch.channelId), req.ch.channelType)
+		if err != nil && err != wkdb.ErrNotFound {
+			r.Warn("channel init: get channel info failed", zap.Error(err), zap.String("channelId", req.ch.channelId), zap.Uint8("channelType", req.ch.channelType))
+		} else if !wkdb.IsEmptyChannelInfo(channelInfo) {
+			req.ch.info = channelInfo
+		}
+	}
+
 	// if cfg.LeaderId == r.s.opts.Cluster.NodeId { // 只有领导才需要makeReceiverTag
 	// 	_, err = req.ch.makeReceiverTag()
 	// 	if err != nil {
@@ -515,7 +525,7 @@ func (r *channelReactor) processPermission(req *permissionReq) {
 
 		r.MessageTrace("权限验证", msg.SendPacket.ClientMsgNo, "processPermission")
 
-		reasonCode, err := r.hasPermission(req.ch.channelId, req.ch.channelType, msg.FromUid, req.ch)
+		reasonCode, err := r.hasPermission(req.ch.channelId, req.ch.channelType, msg.FromUid, req.ch.info)
 		if err != nil {
 			r.Error("hasPermission error", zap.Error(err))
 			req.messages[i].ReasonCode = wkproto.ReasonSystemError
@@ -541,13 +551,39 @@ func (r *channelReactor) processPermission(req *permissionReq) {
 	})
 }
 
-func (r *channelReactor) hasPermission(channelId string, channelType uint8, fromUid string, ch *channel) (wkproto.ReasonCode, error) {
+func (r *channelReactor) hasPermission(channelId string, channelType uint8, fromUid string, channelInfo wkdb.ChannelInfo) (wkproto.ReasonCode, error) {
 
 	realFakeChannelId := channelId
 	if r.opts.IsCmdChannel(channelId) {
 		realFakeChannelId = r.opts.CmdChannelConvertOrginalChannel(channelId)
 	}
 
+	// 子区频道继承父频道的权限
+	isThread := r.opts.IsThreadChannel(realFakeChannelId)
+	if isThread {
+		var rootMessageId int64
+		realFakeChannelId, rootMessageId = r.opts.ThreadChannelConvertParent(realFakeChannelId)
+		if rootMessageId <= 0 || channelType == wkproto.ChannelTypePerson {
+			return wkproto.ReasonChannelNotExist, nil
+		}
+		// 根消息必须是父频道内的消息
+		exist, err := r.s.threadManager.rootExists(realFakeChannelId, channelType, rootMessageId)
+		if err != nil {
+			r.Error("rootExists error", zap.Error(err), zap.String("parentChannelId", realFakeChannelId), zap.Int64("rootMessageId", rootMessageId))
+			return wkproto.ReasonSystemError, err
+		}
+		if !exist {
+			return wkproto.ReasonChannelNotExist, nil
+		}
+	}
+
+	// 发送者被封禁
+	if !r.s.systemUIDManager.SystemUID(fromUid) {
+		if r.s.userBanManager.isBanned(fromUid) {
+			return wkproto.ReasonBan, nil
+		}
+	}
+
 	// 资讯频道是公开的，直接通过
 	if channelType == wkproto.ChannelTypeInfo {
 		return wkproto.ReasonSuccess, nil
@@ -587,7 +623,14 @@ func (r *channelReactor) hasPermission(channelId string, channelType uint8, from
 		return reasonCode, nil
 	}
 
-	channelInfo := ch.info
+	if isThread {
+		parentInfo, err := r.s.store.GetChannel(realFakeChannelId, channelType)
+		if err != nil && err != wkdb.ErrNotFound {
+			r.Error("GetChannel error", zap.Error(err), zap.String("parentChannelId", realFakeChannelId))
+			return wkproto.ReasonSystemError, err
+		}
+		channelInfo = parentInfo
+	}
 
 	if channelInfo.Ban { // 频道被封禁
 		return wkproto.ReasonBan, nil
@@ -782,6 +825,11 @@ func (r *channelReactor) processStorages(reqs []*storageReq) {
 
 func (r *channelReactor) processStorage(req *storageReq) {
 
+	// 存储前由拦截服务审核消息（同一批存储请求的消息一次请求）
+	if r.s.sendHook != nil && r.opts.SendHookChannelTypeOn(req.ch.channelType) {
+		r.s.sendHook.intercept(req.ch.channelId, req.ch.channelType, req.messages)
+	}
+
 	messages := make([]wkdb.Message, 0, len(req.messages))
 	sotreMessages := make([]wkdb.Message, 0, len(messages))
 	// 将reactorChannelMessage转换为wkdb.Message
@@ -882,7 +930,16 @@ func (r *channelReactor) processStorage(req *storageReq) {
 		}
 	}
 
-	if r.opts.WebhookOn() && reason == ReasonSuccess {
+	// 子区消息，更新根消息的回复统计
+	if reason == ReasonSuccess && len(sotreMessages) > 0 && r.opts.IsThreadChannel(req.ch.channelId) {
+		r.s.threadManager.onStored(req.ch.channelId, req.ch.channelType, req.messages)
+	}
+
+	channelInfo := req.ch.info
+	channelWebhookOn := channelInfo.Webhook != ""
+	endpointsNotifyOn := r.s.webhook.endpoints.notifyOn.Load() // 有运行时添加的端点关注了消息通知
+	sinksNotifyOn := r.s.webhook.sinks.notifyOn                // 有事件sink关注了消息通知
+	if (r.opts.WebhookOn() || channelWebhookOn || endpointsNotifyOn || sinksNotifyOn) && reason == ReasonSuccess {
 		// 赋值messageeq
 		for i, msg := range messages {
 			for _, cmsg := range req.messages {
@@ -894,11 +951,26 @@ func (r *channelReactor) processStorage(req *storageReq) {
 			}
 		}
 
-		// 将消息存储到webhook的推送队列内
-		err := r.s.store.AppendMessageOfNotifyQueue(messages)
-		if err != nil {
-			r.Error("AppendMessageOfNotifyQueue error", zap.Error(err))
-			reason = ReasonError
+		// 频道配置了自己的webhook
+		if channelWebhookOn {
+			r.s.channelWebhook.notifyMessages(channelInfo, messages)
+		}
+
+		if endpointsNotifyOn {
+			r.s.webhook.endpoints.notifyMessages(messages)
+		}
+
+		if sinksNotifyOn {
+			r.s.webhook.sinks.notifyMessages(messages)
+		}
+
+		// 将消息通知写入webhook的发件箱
+		if r.opts.WebhookOn() && !(channelWebhookOn && r.opts.Webhook.ChannelExclusive) {
+			err := r.s.webhook.notifyMessages(messages)
+			if err != nil {
+				r.Error("webhook notifyMessages error", zap.Error(err))
+				reason = ReasonError
+			}
 		}
 	}
 	// 返回存储结果
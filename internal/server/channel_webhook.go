@@ -0,0 +1,291 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RussellLuo/timingwheel"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"go.uber.org/zap"
+)
+
+// 频道级别的webhook
+// 频道信息（ChannelInfo.Webhook）配置了webhook地址的频道，由频道领导节点将频道事件推送到此地址，
+// 请求格式与全局webhook一致：POST {webhook}?event={event}，body为事件数据的json，签名头也与全局webhook一致
+// 事件以频道作为端点写入webhook发件箱，投递失败按发件箱的退避策略重试，超过最大重试次数移入死信，同一频道的同一种事件按顺序投递
+// 推送统计由投递事件的节点（即产生事件的节点）记录，定时写入本节点的wkdb，节点重启后从wkdb加载，统计不会清零
+type channelWebhook struct {
+	s *Server
+	wklog.Log
+	httpClient *http.Client
+
+	statsLock  sync.RWMutex
+	stats      map[string]*channelWebhookStats // 频道webhook推送统计，key为channelKey
+	dirty      map[string]struct{}             // 还没有写入wkdb的统计
+	statsTimer *timingwheel.Timer
+}
+
+// 推送统计写入wkdb的间隔
+const channelWebhookStatsFlushInterval = time.Second * 5
+
+// 频道webhook在发件箱中的端点前缀，端点为 channel:{channelKey}
+const channelWebhookEndpointPrefix = "channel:"
+
+func newChannelWebhook(s *Server) *channelWebhook {
+	return &channelWebhook{
+		s:   s,
+		Log: wklog.NewWKLog("channelWebhook"),
+		httpClient: &http.Client{
+			Transport: &http.Transport{
+				DialContext: (&net.Dialer{
+					Timeout:   5 * time.Second,
+					KeepAlive: 5 * time.Second,
+				}).DialContext,
+				MaxIdleConns:          200,
+				MaxIdleConnsPerHost:   20,
+				IdleConnTimeout:       300 * time.Second,
+				TLSHandshakeTimeout:   time.Second * 5,
+				ResponseHeaderTimeout: 5 * time.Second,
+			},
+		},
+		stats: make(map[string]*channelWebhookStats),
+		dirty: make(map[string]struct{}),
+	}
+}
+
+func (c *channelWebhook) start() error {
+	stats, err := c.s.store.GetChannelWebhookStats()
+	if err != nil {
+		return err
+	}
+	c.statsLock.Lock()
+	for _, st := range stats {
+		resp := newChannelWebhookStats(st)
+		c.stats[wkutil.ChannelToKey(st.ChannelId, st.ChannelType)] = &resp
+	}
+	c.statsLock.Unlock()
+	c.statsTimer = c.s.Schedule(channelWebhookStatsFlushInterval, c.flushStats)
+	return nil
+}
+
+// 需要在存储关闭前停止，写入剩余的推送统计
+func (c *channelWebhook) stop() {
+	if c.statsTimer != nil {
+		c.statsTimer.Stop()
+	}
+	c.flushStats()
+	c.httpClient.CloseIdleConnections()
+}
+
+// 将变化的推送统计写入wkdb
+func (c *channelWebhook) flushStats() {
+	c.statsLock.Lock()
+	if len(c.dirty) == 0 {
+		c.statsLock.Unlock()
+		return
+	}
+	stats := make([]wkdb.ChannelWebhookStats, 0, len(c.dirty))
+	for channelKey := range c.dirty {
+		stats = append(stats, c.stats[channelKey].toDB())
+	}
+	c.dirty = make(map[string]struct{})
+	c.statsLock.Unlock()
+
+	err := c.s.store.SetChannelWebhookStats(stats)
+	if err != nil {
+		c.Error("保存频道webhook推送统计失败！", zap.Error(err), zap.Int("count", len(stats)))
+		c.statsLock.Lock()
+		for _, st := range stats { // 下次重新写入
+			c.dirty[wkutil.ChannelToKey(st.ChannelId, st.ChannelType)] = struct{}{}
+		}
+		c.statsLock.Unlock()
+	}
+}
+
+// 频道是否关注了此事件
+func (c *channelWebhook) isEventFocused(channelInfo wkdb.ChannelInfo, event string) bool {
+	if len(channelInfo.WebhookEvents) == 0 {
+		return true
+	}
+	return wkutil.ArrayContains(channelInfo.WebhookEvents, event)
+}
+
+// notifyMessages 将频道消息推送到频道的webhook
+func (c *channelWebhook) notifyMessages(channelInfo wkdb.ChannelInfo, messages []wkdb.Message) {
+	if len(messages) == 0 {
+		return
+	}
+	messageResps := make([]*MessageResp, 0, len(messages))
+	for _, msg := range messages {
+		resp := &MessageResp{}
+		resp.from(msg, c.s)
+		messageResps = append(messageResps, resp)
+	}
+	c.trigger(channelInfo, EventMsgNotify, messageResps)
+}
+
+// trigger 触发频道事件（写入发件箱）
+func (c *channelWebhook) trigger(channelInfo wkdb.ChannelInfo, event string, data interface{}) {
+	if channelInfo.Webhook == "" || !c.isEventFocused(channelInfo, event) {
+		return
+	}
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		c.Error("频道webhook的event数据不能json化！", zap.Error(err), zap.String("event", event))
+		return
+	}
+	endpoint := channelWebhookEndpointPrefix + wkutil.ChannelToKey(channelInfo.ChannelId, channelInfo.ChannelType)
+	err = c.s.webhook.outbox.add(endpoint, event, event, jsonData)
+	if err != nil {
+		c.Error("添加频道webhook事件到发件箱失败！", zap.Error(err), zap.String("channelId", channelInfo.ChannelId), zap.String("event", event))
+	}
+}
+
+func isChannelWebhookEndpoint(endpoint string) bool {
+	return strings.HasPrefix(endpoint, channelWebhookEndpointPrefix)
+}
+
+// sendTo 投递发件箱中的频道事件，投递时使用频道当前的webhook地址
+func (c *channelWebhook) sendTo(endpoint string, deliveryId string, event string, data []byte) error {
+	channelId, channelType := wkutil.ChannelFromlKey(strings.TrimPrefix(endpoint, channelWebhookEndpointPrefix))
+	channelInfo, err := c.s.store.GetChannel(channelId, channelType)
+	if err != nil && err != wkdb.ErrNotFound {
+		return err
+	}
+	if channelInfo.Webhook == "" || !c.isEventFocused(channelInfo, event) { // 频道已取消webhook，丢弃剩余的事件
+		c.Warn("channel webhook is off, discard event", zap.String("channelId", channelId), zap.Uint8("channelType", channelType), zap.String("event", event), zap.String("deliveryId", deliveryId))
+		return nil
+	}
+
+	err = c.post(channelInfo.Webhook, deliveryId, event, data)
+	if err != nil {
+		c.updateStats(channelInfo, func(st *channelWebhookStats) {
+			st.RetryCount++
+			st.LastError = err.Error()
+		})
+		return err
+	}
+	c.updateStats(channelInfo, func(st *channelWebhookStats) {
+		st.SuccessCount++
+		st.LastSuccessAt = time.Now().Unix()
+	})
+	return nil
+}
+
+// 频道事件超过最大重试次数移入死信
+func (c *channelWebhook) onDeadLetter(endpoint string, lastError string) {
+	channelId, channelType := wkutil.ChannelFromlKey(strings.TrimPrefix(endpoint, channelWebhookEndpointPrefix))
+	c.Warn("频道webhook推送失败超过最大重试次数！", zap.String("channelId", channelId), zap.Uint8("channelType", channelType), zap.String("lastError", lastError))
+	channelInfo, err := c.s.store.GetChannel(channelId, channelType)
+	if err != nil && err != wkdb.ErrNotFound {
+		c.Warn("get channel info failed", zap.Error(err), zap.String("channelId", channelId))
+	}
+	channelInfo.ChannelId = channelId
+	channelInfo.ChannelType = channelType
+	c.updateStats(channelInfo, func(st *channelWebhookStats) {
+		st.FailCount++
+		st.LastError = lastError
+		st.LastFailAt = time.Now().Unix()
+	})
+}
+
+func (c *channelWebhook) post(webhook string, deliveryId string, event string, data []byte) error {
+	eventURL := fmt.Sprintf("%s?event=%s", webhook, event)
+	req, err := http.NewRequest(http.MethodPost, eventURL, bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	newWebhookSign(c.s.opts.WebhookSecrets(), deliveryId, event, data).setHeader(req.Header)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("频道webhook返回状态错误！status:%d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (c *channelWebhook) updateStats(channelInfo wkdb.ChannelInfo, f func(st *channelWebhookStats)) {
+	channelKey := wkutil.ChannelToKey(channelInfo.ChannelId, channelInfo.ChannelType)
+	c.statsLock.Lock()
+	defer c.statsLock.Unlock()
+	st := c.stats[channelKey]
+	if st == nil {
+		st = &channelWebhookStats{
+			ChannelId:   channelInfo.ChannelId,
+			ChannelType: channelInfo.ChannelType,
+		}
+		c.stats[channelKey] = st
+	}
+	if channelInfo.Webhook != "" {
+		st.Webhook = channelInfo.Webhook
+	}
+	f(st)
+	c.dirty[channelKey] = struct{}{}
+}
+
+// 获取本节点的频道webhook推送统计
+func (c *channelWebhook) getStats() []channelWebhookStats {
+	c.statsLock.RLock()
+	defer c.statsLock.RUnlock()
+	stats := make([]channelWebhookStats, 0, len(c.stats))
+	for _, st := range c.stats {
+		stats = append(stats, *st)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].FailCount > stats[j].FailCount
+	})
+	return stats
+}
+
+type channelWebhookStats struct {
+	ChannelId     string `json:"channel_id"`      // 频道ID
+	ChannelType   uint8  `json:"channel_type"`    // 频道类型
+	Webhook       string `json:"webhook"`         // 频道webhook地址
+	SuccessCount  uint64 `json:"success_count"`   // 推送成功次数
+	RetryCount    uint64 `json:"retry_count"`     // 重试次数
+	FailCount     uint64 `json:"fail_count"`      // 超过最大重试次数移入死信的次数
+	LastError     string `json:"last_error"`      // 最后一次错误
+	LastSuccessAt int64  `json:"last_success_at"` // 最后一次成功时间（秒）
+	LastFailAt    int64  `json:"last_fail_at"`    // 最后一次失败时间（秒）
+}
+
+func newChannelWebhookStats(st wkdb.ChannelWebhookStats) channelWebhookStats {
+	return channelWebhookStats{
+		ChannelId:     st.ChannelId,
+		ChannelType:   st.ChannelType,
+		Webhook:       st.Webhook,
+		SuccessCount:  st.SuccessCount,
+		RetryCount:    st.RetryCount,
+		FailCount:     st.FailCount,
+		LastError:     st.LastError,
+		LastSuccessAt: st.LastSuccessAt,
+		LastFailAt:    st.LastFailAt,
+	}
+}
+
+func (c channelWebhookStats) toDB() wkdb.ChannelWebhookStats {
+	return wkdb.ChannelWebhookStats{
+		ChannelId:     c.ChannelId,
+		ChannelType:   c.ChannelType,
+		Webhook:       c.Webhook,
+		SuccessCount:  c.SuccessCount,
+		RetryCount:    c.RetryCount,
+		FailCount:     c.FailCount,
+		LastError:     c.LastError,
+		LastSuccessAt: c.LastSuccessAt,
+		LastFailAt:    c.LastFailAt,
+	}
+}
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/client"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhook"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChannelWebhookSign(t *testing.T) {
+	deliveryIds := make(chan string, 2)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		event := r.URL.Query().Get("event")
+		deliveryId := r.Header.Get(wkhook.HeaderDeliveryId)
+		// 接收方只配置了旧密钥也能验证通过
+		err = wkhook.Verify([]string{"old"}, r.Header.Get(wkhook.HeaderSignature), r.Header.Get(wkhook.HeaderTimestamp), deliveryId, event, body, time.Minute)
+		assert.NoError(t, err)
+		deliveryIds <- deliveryId
+	}))
+	defer ts.Close()
+
+	s := &Server{opts: NewOptions(WithWebhookSecret("new", "old"))}
+	c := newChannelWebhook(s)
+	defer c.stop()
+
+	err := c.post(ts.URL, "d1", EventMsgNotify, []byte(`[{"message_id":1}]`))
+	assert.NoError(t, err)
+	assert.Equal(t, "d1", <-deliveryIds)
+}
+
+func TestChannelWebhookNotify(t *testing.T) {
+	var (
+		lock          sync.Mutex
+		globalMsgs    []string // 全局webhook收到的消息内容
+		channelEvents []string // 频道webhook收到的事件
+		channelMsgs   []string // 频道webhook收到的消息内容
+		channelFailed bool
+	)
+	readPayloads := func(r *http.Request) []string {
+		var messages []*MessageResp
+		err := json.NewDecoder(r.Body).Decode(&messages)
+		assert.NoError(t, err)
+		payloads := make([]string, 0, len(messages))
+		for _, msg := range messages {
+			payloads = append(payloads, string(msg.Payload))
+		}
+		return payloads
+	}
+	globalTs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("event") != EventMsgNotify {
+			return
+		}
+		payloads := readPayloads(r)
+		lock.Lock()
+		defer lock.Unlock()
+		globalMsgs = append(globalMsgs, payloads...)
+	}))
+	defer globalTs.Close()
+	channelTs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		event := r.URL.Query().Get("event")
+		channelEvents = append(channelEvents, event)
+		if !channelFailed { // 第一次推送失败
+			channelFailed = true
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if event == EventMsgNotify {
+			channelMsgs = append(channelMsgs, readPayloads(r)...)
+		}
+	}))
+	defer channelTs.Close()
+
+	s := NewTestServer(t, WithWebhookHTTPAddr(globalTs.URL), WithWebhookChannelExclusive(true), WithWebhookMsgNotifyEventPushInterval(time.Millisecond*100), WithWebhookRetry(time.Millisecond*10, time.Millisecond*50, 5))
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady(time.Second * 10) // 等待服务准备好
+
+	// g1配置了自己的webhook，只关注消息通知
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/channel/info", bytes.NewReader([]byte(wkutil.ToJson(map[string]interface{}{
+		"channel_id":     "g1",
+		"channel_type":   wkproto.ChannelTypeGroup,
+		"webhook":        channelTs.URL,
+		"webhook_events": []string{EventMsgNotify},
+	}))))
+	s.apiServer.r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	TestAddSubscriber(t, s, "g1", wkproto.ChannelTypeGroup, "u1")
+	TestAddSubscriber(t, s, "g2", wkproto.ChannelTypeGroup, "u1")
+
+	cli := TestCreateClient(t, s, "u1")
+	defer cli.Close()
+	err = cli.SendMessage(client.NewChannel("g1", wkproto.ChannelTypeGroup), []byte("m1"))
+	assert.Nil(t, err)
+	err = cli.SendMessage(client.NewChannel("g2", wkproto.ChannelTypeGroup), []byte("m2"))
+	assert.Nil(t, err)
+
+	// 没有关注的频道事件不推送
+	channelInfo, err := s.store.GetChannel("g1", wkproto.ChannelTypeGroup)
+	assert.NoError(t, err)
+	s.channelWebhook.trigger(channelInfo, EventChannelDisband, map[string]string{"channel_id": "g1"})
+
+	// 频道webhook失败后重试成功，开启ChannelExclusive后g1的消息不再推送到全局webhook
+	assert.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(channelMsgs) == 1 && len(globalMsgs) == 1
+	}, time.Second*10, time.Millisecond*50)
+
+	time.Sleep(time.Millisecond * 300)
+	lock.Lock()
+	assert.Equal(t, []string{"m1"}, channelMsgs)
+	assert.Equal(t, []string{"m2"}, globalMsgs)
+	assert.Equal(t, []string{EventMsgNotify, EventMsgNotify}, channelEvents)
+	lock.Unlock()
+
+	stats := s.channelWebhook.getStats()
+	assert.Len(t, stats, 1)
+	assert.Equal(t, uint64(1), stats[0].SuccessCount)
+	assert.Equal(t, uint64(1), stats[0].RetryCount)
+	assert.Equal(t, channelTs.URL, stats[0].Webhook)
+
+	// 推送统计写入wkdb，重启后加载
+	s.channelWebhook.flushStats()
+	reloaded := newChannelWebhook(s)
+	err = reloaded.start()
+	assert.NoError(t, err)
+	reloaded.statsTimer.Stop()
+	stats = reloaded.getStats()
+	assert.Len(t, stats, 1)
+	assert.Equal(t, uint64(1), stats[0].SuccessCount)
+	assert.Equal(t, uint64(1), stats[0].RetryCount)
+	assert.Equal(t, channelTs.URL, stats[0].Webhook)
+}
@@ -0,0 +1,205 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrClientTokenKeyNotFound = errors.New("client token key not found")
+	ErrClientTokenClaims      = errors.New("client token claims invalid")
+)
+
+// 客户端连接token（jwt）的验证
+// 业务服务用配置的密钥签发jwt给客户端，客户端连接时把jwt作为token，服务端本地验签，不需要查询设备token。
+// 验签通过后仍会读取一次设备记录判断token是否已被吊销：认证在用户的槽领导节点上执行，读取的是本地存储（和设备token认证、封禁检查一样），没有跨节点请求。
+// 吊销时间不做缓存，是为了吊销后立即生效，并且槽领导切换后不会读到旧节点缓存的过期数据。
+// jwt的claims：
+//
+//	uid          用户uid（没有则取sub）
+//	device_flag  设备标记 0.app 1.web 2.pc
+//	device_level 设备等级 0.从设备 1.主设备（没有则为从设备）
+//	exp          过期时间（必须）
+//	iat          签发时间（必须，设备被吊销或强制退出后，在此之前签发的token不能再连接）
+//	iss          签发者（配置了Issuer则必须一致）
+//
+// 支持同时配置多个密钥（kid区分），用于密钥轮换：先加入新密钥，业务服务切换到新密钥签发，旧token过期后再移除旧密钥。
+type clientTokenVerifier struct {
+	algorithm string
+	keys      map[string]interface{} // kid -> 验签密钥
+	keyIds    []string               // 按配置顺序的kid，token没有kid时逐个尝试
+	parser    *jwt.Parser
+}
+
+// 客户端token携带的信息
+type clientTokenClaims struct {
+	UID         string
+	DeviceFlag  wkproto.DeviceFlag
+	DeviceLevel wkproto.DeviceLevel
+	IssuedAt    time.Time
+}
+
+func newClientTokenVerifier(opts *Options) (*clientTokenVerifier, error) {
+	algorithm := opts.ClientJwt.Algorithm
+	if jwt.GetSigningMethod(algorithm) == nil {
+		return nil, fmt.Errorf("clientJwt: unsupported algorithm %s", algorithm)
+	}
+	if len(opts.ClientJwt.Keys) == 0 {
+		return nil, errors.New("clientJwt: keys is empty")
+	}
+	v := &clientTokenVerifier{
+		algorithm: algorithm,
+		keys:      make(map[string]interface{}, len(opts.ClientJwt.Keys)),
+	}
+	for _, keyStr := range opts.ClientJwt.Keys {
+		kid, keyValue, ok := strings.Cut(keyStr, ":")
+		kid = strings.TrimSpace(kid)
+		if !ok || kid == "" || strings.TrimSpace(keyValue) == "" {
+			return nil, fmt.Errorf("clientJwt: key format error, must be kid:key")
+		}
+		if _, exist := v.keys[kid]; exist {
+			return nil, fmt.Errorf("clientJwt: duplicate kid %s", kid)
+		}
+		key, err := parseClientTokenKey(algorithm, keyValue)
+		if err != nil {
+			return nil, fmt.Errorf("clientJwt: parse key %s failed: %w", kid, err)
+		}
+		v.keys[kid] = key
+		v.keyIds = append(v.keyIds, kid)
+	}
+
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{algorithm}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(opts.ClientJwt.Leeway),
+	}
+	if strings.TrimSpace(opts.ClientJwt.Issuer) != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(opts.ClientJwt.Issuer))
+	}
+	v.parser = jwt.NewParser(parserOpts...)
+	return v, nil
+}
+
+// HMAC算法的key为密钥，其他算法的key为公钥pem文件路径
+func parseClientTokenKey(algorithm string, keyValue string) (interface{}, error) {
+	if strings.HasPrefix(algorithm, "HS") {
+		return []byte(keyValue), nil
+	}
+	pemData, err := os.ReadFile(keyValue)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case strings.HasPrefix(algorithm, "RS"), strings.HasPrefix(algorithm, "PS"):
+		return jwt.ParseRSAPublicKeyFromPEM(pemData)
+	case strings.HasPrefix(algorithm, "ES"):
+		return jwt.ParseECPublicKeyFromPEM(pemData)
+	case algorithm == "EdDSA":
+		return jwt.ParseEdPublicKeyFromPEM(pemData)
+	}
+	return nil, fmt.Errorf("unsupported algorithm %s", algorithm)
+}
+
+// 验证token，返回token携带的信息
+func (v *clientTokenVerifier) verify(tokenStr string) (*clientTokenClaims, error) {
+	var (
+		token *jwt.Token
+		err   error
+	)
+	claims := jwt.MapClaims{}
+	kid, hasKid := v.tokenKid(tokenStr)
+	if hasKid {
+		key, ok := v.keys[kid]
+		if !ok {
+			return nil, ErrClientTokenKeyNotFound
+		}
+		token, err = v.parser.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (interface{}, error) {
+			return key, nil
+		})
+	} else {
+		for _, keyId := range v.keyIds {
+			key := v.keys[keyId]
+			claims = jwt.MapClaims{}
+			token, err = v.parser.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (interface{}, error) {
+				return key, nil
+			})
+			if !errors.Is(err, jwt.ErrTokenSignatureInvalid) { // 签名不对则尝试下一个密钥
+				break
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, jwt.ErrTokenUnverifiable
+	}
+	return parseClientTokenClaims(claims)
+}
+
+// 获取token头里的kid（不验签）
+func (v *clientTokenVerifier) tokenKid(tokenStr string) (string, bool) {
+	token, _, err := jwt.NewParser().ParseUnverified(tokenStr, jwt.MapClaims{})
+	if err != nil {
+		return "", false
+	}
+	kid, ok := token.Header["kid"].(string)
+	if !ok || kid == "" {
+		return "", false
+	}
+	return kid, true
+}
+
+func parseClientTokenClaims(claims jwt.MapClaims) (*clientTokenClaims, error) {
+	result := &clientTokenClaims{}
+	if uid, ok := claims["uid"].(string); ok {
+		result.UID = uid
+	}
+	if result.UID == "" {
+		sub, err := claims.GetSubject()
+		if err != nil {
+			return nil, err
+		}
+		result.UID = sub
+	}
+	if strings.TrimSpace(result.UID) == "" {
+		return nil, ErrClientTokenClaims
+	}
+
+	// json数字解析为float64
+	deviceFlag, ok := claims["device_flag"].(float64)
+	if !ok || deviceFlag < 0 || deviceFlag > 255 {
+		return nil, ErrClientTokenClaims
+	}
+	result.DeviceFlag = wkproto.DeviceFlag(deviceFlag)
+	if deviceLevel, ok := claims["device_level"]; ok {
+		v, ok := deviceLevel.(float64)
+		if !ok || (v != float64(wkproto.DeviceLevelSlave) && v != float64(wkproto.DeviceLevelMaster)) {
+			return nil, ErrClientTokenClaims
+		}
+		result.DeviceLevel = wkproto.DeviceLevel(v)
+	}
+	issuedAt, err := claims.GetIssuedAt()
+	if err != nil || issuedAt == nil {
+		return nil, ErrClientTokenClaims
+	}
+	result.IssuedAt = issuedAt.Time
+	return result, nil
+}
+
+// token是否在设备吊销（或强制退出）之前签发
+// iat精度为秒，与吊销同一秒签发的token也视为已吊销
+func clientTokenRevoked(claims *clientTokenClaims, device wkdb.Device) bool {
+	if device.RevokedAt == nil {
+		return false
+	}
+	return claims.IssuedAt.Unix() <= device.RevokedAt.Unix()
+}
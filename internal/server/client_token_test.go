@@ -0,0 +1,129 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func signClientToken(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	tokenStr, err := token.SignedString(key)
+	assert.NoError(t, err)
+	return tokenStr
+}
+
+func TestClientTokenVerifierHMAC(t *testing.T) {
+	opts := NewOptions(WithClientJwt("HS256", []string{"old:old-secret", "new:new-secret"}), WithClientJwtIssuer("app"))
+	v, err := newClientTokenVerifier(opts)
+	assert.NoError(t, err)
+
+	claims := jwt.MapClaims{
+		"uid":          "u1",
+		"device_flag":  1,
+		"device_level": 1,
+		"iss":          "app",
+		"exp":          time.Now().Add(time.Hour).Unix(),
+		"iat":          time.Now().Unix(),
+	}
+
+	// 按kid选择密钥
+	result, err := v.verify(signClientToken(t, jwt.SigningMethodHS256, "new", []byte("new-secret"), claims))
+	assert.NoError(t, err)
+	assert.Equal(t, "u1", result.UID)
+	assert.Equal(t, wkproto.DeviceFlag(wkproto.WEB), result.DeviceFlag)
+	assert.Equal(t, wkproto.DeviceLevelMaster, result.DeviceLevel)
+
+	// 没有kid则逐个尝试
+	_, err = v.verify(signClientToken(t, jwt.SigningMethodHS256, "", []byte("new-secret"), claims))
+	assert.NoError(t, err)
+
+	// 未知的kid
+	_, err = v.verify(signClientToken(t, jwt.SigningMethodHS256, "unknown", []byte("new-secret"), claims))
+	assert.Equal(t, ErrClientTokenKeyNotFound, err)
+
+	// 密钥错误
+	_, err = v.verify(signClientToken(t, jwt.SigningMethodHS256, "", []byte("bad-secret"), claims))
+	assert.Error(t, err)
+
+	// 算法不一致
+	_, err = v.verify(signClientToken(t, jwt.SigningMethodHS512, "new", []byte("new-secret"), claims))
+	assert.Error(t, err)
+
+	// 过期
+	expired := jwt.MapClaims{"uid": "u1", "device_flag": 1, "iss": "app", "exp": time.Now().Add(-time.Hour).Unix()}
+	_, err = v.verify(signClientToken(t, jwt.SigningMethodHS256, "new", []byte("new-secret"), expired))
+	assert.ErrorIs(t, err, jwt.ErrTokenExpired)
+
+	// 没有过期时间
+	noExp := jwt.MapClaims{"uid": "u1", "device_flag": 1, "iss": "app"}
+	_, err = v.verify(signClientToken(t, jwt.SigningMethodHS256, "new", []byte("new-secret"), noExp))
+	assert.Error(t, err)
+
+	// 签发者不一致
+	badIss := jwt.MapClaims{"uid": "u1", "device_flag": 1, "iss": "other", "exp": time.Now().Add(time.Hour).Unix()}
+	_, err = v.verify(signClientToken(t, jwt.SigningMethodHS256, "new", []byte("new-secret"), badIss))
+	assert.ErrorIs(t, err, jwt.ErrTokenInvalidIssuer)
+
+	// 没有设备标记
+	noDeviceFlag := jwt.MapClaims{"uid": "u1", "iss": "app", "exp": time.Now().Add(time.Hour).Unix(), "iat": time.Now().Unix()}
+	_, err = v.verify(signClientToken(t, jwt.SigningMethodHS256, "new", []byte("new-secret"), noDeviceFlag))
+	assert.Equal(t, ErrClientTokenClaims, err)
+
+	// 没有签发时间
+	noIat := jwt.MapClaims{"uid": "u1", "device_flag": 1, "iss": "app", "exp": time.Now().Add(time.Hour).Unix()}
+	_, err = v.verify(signClientToken(t, jwt.SigningMethodHS256, "new", []byte("new-secret"), noIat))
+	assert.Equal(t, ErrClientTokenClaims, err)
+}
+
+func TestClientTokenRevoked(t *testing.T) {
+	revokedAt := time.Now()
+	device := wkdb.Device{Id: 1, Uid: "u1", RevokedAt: &revokedAt}
+
+	// 没有吊销过
+	assert.False(t, clientTokenRevoked(&clientTokenClaims{IssuedAt: revokedAt.Add(-time.Hour)}, wkdb.Device{Id: 1, Uid: "u1"}))
+	// 吊销之前签发
+	assert.True(t, clientTokenRevoked(&clientTokenClaims{IssuedAt: revokedAt.Add(-time.Hour)}, device))
+	// 与吊销同一秒签发
+	assert.True(t, clientTokenRevoked(&clientTokenClaims{IssuedAt: time.Unix(revokedAt.Unix(), 0)}, device))
+	// 吊销之后重新签发
+	assert.False(t, clientTokenRevoked(&clientTokenClaims{IssuedAt: revokedAt.Add(time.Second)}, device))
+}
+
+func TestClientTokenVerifierRSA(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	pubBytes, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	assert.NoError(t, err)
+	pubFile := filepath.Join(t.TempDir(), "pub.pem")
+	err = os.WriteFile(pubFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubBytes}), 0644)
+	assert.NoError(t, err)
+
+	v, err := newClientTokenVerifier(NewOptions(WithClientJwt("RS256", []string{"k1:" + pubFile})))
+	assert.NoError(t, err)
+
+	claims := jwt.MapClaims{
+		"sub":         "u1",
+		"device_flag": 0,
+		"exp":         time.Now().Add(time.Hour).Unix(),
+		"iat":         time.Now().Unix(),
+	}
+	result, err := v.verify(signClientToken(t, jwt.SigningMethodRS256, "k1", privateKey, claims))
+	assert.NoError(t, err)
+	assert.Equal(t, "u1", result.UID)
+	assert.Equal(t, wkproto.DeviceFlag(wkproto.APP), result.DeviceFlag)
+	assert.Equal(t, wkproto.DeviceLevelSlave, result.DeviceLevel)
+}
@@ -3,12 +3,15 @@ package server
 import (
 	"errors"
 	"fmt"
+	"net"
 	"strings"
 	"time"
 
+	"github.com/WuKongIM/WuKongIM/pkg/protocol"
 	"github.com/WuKongIM/WuKongIM/pkg/trace"
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
 	"github.com/WuKongIM/WuKongIM/pkg/wknet"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
 	wkproto "github.com/WuKongIM/WuKongIMGoProto"
 	"go.uber.org/atomic"
 	"go.uber.org/zap"
@@ -38,6 +41,7 @@ type connInfo struct {
 	aesKey       []byte
 	aesIV        []byte
 	protoVersion uint8
+	clientIp     string // 客户端ip
 
 	closed atomic.Bool
 
@@ -58,6 +62,9 @@ type connContext struct {
 
 	lastActivity atomic.Int64 // 最后活动时间
 
+	mqtt   *mqttSession // mqtt连接的会话（非mqtt连接为nil）
+	wsJSON bool         // 是否是websocket JSON文本协议的连接
+
 	wklog.Log
 }
 
@@ -76,13 +83,15 @@ func newConnContext(connInfo connInfo, conn wknet.Conn, subReactor *userReactorS
 }
 
 func newConnContextProxy(realNodeId uint64, connInfo connInfo, subReactor *userReactorSub) *connContext {
-	return &connContext{
+	c := &connContext{
 		connInfo:   connInfo,
 		subReactor: subReactor,
 		realNodeId: realNodeId,
 		isRealConn: false,
 		Log:        wklog.NewWKLog(fmt.Sprintf("connContext[%s]", connInfo.uid)),
 	}
+	c.uptime.Store(time.Now())
+	return c
 }
 
 func (c *connContext) addOtherPacket(packet wkproto.Frame) {
@@ -135,6 +144,7 @@ func (c *connContext) addConnectPacket(packet *wkproto.ConnectPacket) {
 		DeviceId:   c.deviceId,
 		InPacket:   packet,
 		FromNodeId: c.subReactor.r.s.opts.Cluster.NodeId,
+		ConnMeta:   c.connMeta(),
 	}
 	err := c.subReactor.stepNoWait(c.uid, UserAction{
 		ActionType: UserActionConnect,
@@ -145,6 +155,27 @@ func (c *connContext) addConnectPacket(packet *wkproto.ConnectPacket) {
 	}
 }
 
+// 连接的客户端信息
+func (c *connContext) connMeta() connMeta {
+	var meta connMeta
+	if c.conn == nil {
+		return meta
+	}
+	if c.conn.RemoteAddr() != nil {
+		meta.IP = c.conn.RemoteAddr().String()
+		if host, _, err := net.SplitHostPort(meta.IP); err == nil {
+			meta.IP = host
+		}
+	}
+	if userAgent, ok := c.conn.Value(wknet.ConnValueUserAgent).(string); ok {
+		meta.UserAgent = userAgent
+	}
+	if clientVersion, ok := c.conn.Value(wknet.ConnValueClientVersion).(string); ok {
+		meta.ClientVersion = clientVersion
+	}
+	return meta
+}
+
 func (c *connContext) addSendPacket(packet *wkproto.SendPacket) {
 
 	// 保持活动
@@ -180,6 +211,12 @@ func (c *connContext) addSendPacket(packet *wkproto.SendPacket) {
 		return
 	}
 
+	// 瞬态事件（比如正在输入）不进入频道的存储流程，直接投递给在线用户
+	if packet.Setting.IsSet(protocol.SettingTransient) {
+		c.subReactor.r.s.transientManager.handleSendPacket(c, messageId, packet)
+		return
+	}
+
 	// 提案发送至频道
 	_ = c.subReactor.proposeSend(c, messageId, packet, false)
 
@@ -244,6 +281,12 @@ func (c *connContext) writeDirectly(data []byte, recvFrameCount uint32) error {
 		return errors.New("writeDirectly failed, conn is nil")
 	}
 	conn := c.conn
+	if c.mqtt != nil { // mqtt连接，将数据转换为mqtt报文后写入
+		return c.subReactor.r.s.mqttGateway.write(c, data)
+	}
+	if c.wsJSON { // websocket JSON文本协议连接，将数据转换为JSON帧后写入
+		return c.subReactor.r.s.wsJSONGateway.write(c, data)
+	}
 	wsConn, wsok := conn.(wknet.IWSConn) // websocket连接
 	if wsok {
 		err := wsConn.WriteServerBinary(data)
@@ -281,6 +324,29 @@ func (c *connContext) close() {
 
 }
 
+// 加密发送包的payload并生成MsgKey（由服务端代替设备加解密的连接使用，例如mqtt和websocket json协议的连接）
+func (c *connContext) encryptSendPacket(packet *wkproto.SendPacket) error {
+	payload, err := wkutil.AesEncryptPkcs7Base64(packet.Payload, c.aesKey, c.aesIV)
+	if err != nil {
+		return err
+	}
+	packet.Payload = payload
+	msgKey, err := wkutil.AesEncryptPkcs7Base64([]byte(packet.VerityString()), c.aesKey, c.aesIV)
+	if err != nil {
+		return err
+	}
+	packet.MsgKey = wkutil.MD5(string(msgKey))
+	return nil
+}
+
+// 解密接收包的payload（由服务端代替设备加解密的连接使用）
+func (c *connContext) decryptRecvPayload(recv *wkproto.RecvPacket) ([]byte, error) {
+	if recv.Setting.IsSet(wkproto.SettingNoEncrypt) {
+		return recv.Payload, nil
+	}
+	return wkutil.AesDecryptPkcs7Base64(recv.Payload, c.aesKey, c.aesIV)
+}
+
 func (c *connContext) isClosed() bool {
 	return c.closed.Load()
 }
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"sort"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/atomic"
+)
+
+// 用户连接数限制
+// 认证在用户的槽领导节点上执行，领导节点上有用户在整个集群的连接（包含代理连接），所以在认证时检查连接数即为集群的连接数。
+// 同主设备互踢和同设备id的从设备重连会替换旧连接，这些旧连接不计入连接数。
+// 超过限制时按策略拒绝新连接（connack为ReasonConnectKick）或踢掉最早的连接。
+type connLimiter struct {
+	s *Server
+	wklog.Log
+
+	rejectedCount atomic.Int64 // 被拒绝的连接数量
+	evictedCount  atomic.Int64 // 被踢掉的连接数量
+}
+
+func newConnLimiter(s *Server) *connLimiter {
+	return &connLimiter{
+		s:   s,
+		Log: wklog.NewWKLog("connLimiter"),
+	}
+}
+
+// 是否开启了连接数限制
+func (c *connLimiter) enabled() bool {
+	opts := c.s.opts.ConnLimit
+	if opts.MaxPerUser > 0 {
+		return true
+	}
+	for _, max := range opts.MaxPerDeviceFlag {
+		if max > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// 检查新连接是否超过连接数限制，ok为false表示拒绝新连接，evicts为需要踢掉的旧连接
+func (c *connLimiter) check(connCtx *connContext, deviceId string, deviceLevel wkproto.DeviceLevel) (evicts []*connContext, ok bool) {
+	if !c.enabled() || c.s.systemUIDManager.SystemUID(connCtx.uid) || connCtx.uid == c.s.opts.ManagerUID {
+		return nil, true
+	}
+	conns := make([]*connContext, 0)
+	for _, conn := range c.s.userReactor.getConns(connCtx.uid) {
+		if conn.connId == connCtx.connId || !conn.isAuth.Load() {
+			continue
+		}
+		if conn.deviceFlag == connCtx.deviceFlag {
+			if deviceLevel == wkproto.DeviceLevelMaster || conn.deviceId == deviceId { // 会被新连接替换
+				continue
+			}
+		}
+		conns = append(conns, conn)
+	}
+	opts := c.s.opts.ConnLimit
+	evicts, over := selectConnLimitEvicts(conns, connCtx.deviceFlag, opts.MaxPerUser, opts.MaxPerDeviceFlag[connCtx.deviceFlag])
+	if !over {
+		return nil, true
+	}
+	if opts.Policy == ConnLimitPolicyEvictOldest {
+		c.evictedCount.Add(int64(len(evicts)))
+		return evicts, true
+	}
+	c.rejectedCount.Add(1)
+	return nil, false
+}
+
+// 选出需要踢掉的最早的连接，先满足设备标记的限制，再满足用户的限制
+// conns为用户已有的连接（不包含新连接），over表示加上新连接后是否超过了限制
+func selectConnLimitEvicts(conns []*connContext, deviceFlag wkproto.DeviceFlag, maxPerUser, maxPerDeviceFlag int) (evicts []*connContext, over bool) {
+	sorted := make([]*connContext, len(conns))
+	copy(sorted, conns)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ti, tj := sorted[i].uptime.Load(), sorted[j].uptime.Load()
+		if ti.Equal(tj) {
+			return sorted[i].connId < sorted[j].connId
+		}
+		return ti.Before(tj)
+	})
+
+	evicted := make(map[int64]struct{})
+	if maxPerDeviceFlag > 0 {
+		flagCount := 0
+		for _, conn := range sorted {
+			if conn.deviceFlag == deviceFlag {
+				flagCount++
+			}
+		}
+		for _, conn := range sorted {
+			if flagCount+1 <= maxPerDeviceFlag {
+				break
+			}
+			if conn.deviceFlag == deviceFlag {
+				evicts = append(evicts, conn)
+				evicted[conn.connId] = struct{}{}
+				flagCount--
+			}
+		}
+	}
+	if maxPerUser > 0 {
+		userCount := len(sorted) - len(evicts)
+		for _, conn := range sorted {
+			if userCount+1 <= maxPerUser {
+				break
+			}
+			if _, ok := evicted[conn.connId]; ok {
+				continue
+			}
+			evicts = append(evicts, conn)
+			userCount--
+		}
+	}
+	return evicts, len(evicts) > 0
+}
+
+// 连接数限制的统计
+type connLimitStats struct {
+	MaxPerUser       int                        `json:"max_per_user"`        // 每个用户最多的连接数
+	MaxPerDeviceFlag map[wkproto.DeviceFlag]int `json:"max_per_device_flag"` // 每个用户每种设备标记最多的连接数
+	Policy           ConnLimitPolicy            `json:"policy"`              // 超过限制时的策略
+	RejectedCount    int64                      `json:"rejected_count"`      // 被拒绝的连接数量
+	EvictedCount     int64                      `json:"evicted_count"`       // 被踢掉的连接数量
+}
+
+func (c *connLimiter) stats() *connLimitStats {
+	opts := c.s.opts.ConnLimit
+	return &connLimitStats{
+		MaxPerUser:       opts.MaxPerUser,
+		MaxPerDeviceFlag: opts.MaxPerDeviceFlag,
+		Policy:           opts.Policy,
+		RejectedCount:    c.rejectedCount.Load(),
+		EvictedCount:     c.evictedCount.Load(),
+	}
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLimitConn(connId int64, deviceFlag wkproto.DeviceFlag, uptime time.Time) *connContext {
+	c := &connContext{
+		connInfo: connInfo{connId: connId, uid: "u1", deviceFlag: deviceFlag},
+	}
+	c.uptime.Store(uptime)
+	return c
+}
+
+func TestSelectConnLimitEvicts(t *testing.T) {
+	now := time.Now()
+	conns := []*connContext{
+		newTestLimitConn(3, wkproto.APP, now.Add(-time.Minute)),
+		newTestLimitConn(1, wkproto.WEB, now.Add(-time.Hour)),
+		newTestLimitConn(2, wkproto.APP, now.Add(-time.Hour*2)),
+	}
+
+	// 没有超过限制
+	evicts, over := selectConnLimitEvicts(conns, wkproto.APP, 4, 3)
+	assert.False(t, over)
+	assert.Len(t, evicts, 0)
+
+	// 超过设备标记的限制，踢掉最早的同设备标记连接
+	evicts, over = selectConnLimitEvicts(conns, wkproto.APP, 0, 2)
+	assert.True(t, over)
+	assert.Len(t, evicts, 1)
+	assert.Equal(t, int64(2), evicts[0].connId)
+
+	// 超过用户的限制，踢掉最早的连接
+	evicts, over = selectConnLimitEvicts(conns, wkproto.PC, 2, 0)
+	assert.True(t, over)
+	assert.Len(t, evicts, 2)
+	assert.Equal(t, int64(2), evicts[0].connId)
+	assert.Equal(t, int64(1), evicts[1].connId)
+
+	// 同时超过两个限制，已经为设备标记踢掉的连接也计入用户的限制
+	evicts, over = selectConnLimitEvicts(conns, wkproto.APP, 3, 1)
+	assert.True(t, over)
+	assert.Len(t, evicts, 2)
+	assert.Equal(t, int64(2), evicts[0].connId)
+	assert.Equal(t, int64(3), evicts[1].connId)
+}
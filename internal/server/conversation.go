@@ -336,6 +336,11 @@ func (c *conversationWorker) handleReq(req *conversationReq) {
 		return
 	}
 
+	// 子区只更新参与者（回复过的用户）的最近会话，不更新整个父频道的订阅者
+	if c.s.opts.IsThreadChannel(req.channelId) {
+		return
+	}
+
 	// 收到命令频道的第一条消息时 应该更新整个频道的最新会话
 	if c.s.opts.IsCmdChannel(req.channelId) && isFirstMsg {
 		update.updateLastTagKey(req.tagKey)
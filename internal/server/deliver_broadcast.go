@@ -0,0 +1,271 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+// 超大频道（ChannelInfo.Large）的广播投递
+// 超大频道的订阅者数量巨大，不生成接收者tag，也不更新订阅者的最近会话。
+// 频道领导节点将消息广播给所有在线节点，每个节点只投递给本节点在线的订阅者，
+// 离线的订阅者上线后通过 /channel/messagesync 主动拉取频道消息。
+// 每个节点缓存本节点在线用户是否是频道的订阅者，广播时只向频道领导节点查询缓存中没有的用户，
+// 订阅者变化时（频道所在槽的领导节点）将变化推送给所有在线节点更新缓存。
+
+// 广播投递的tag key，节点之间通过此key识别广播投递
+const broadcastTagKey = "__broadcast"
+
+// 订阅关系缓存的有效期，过期后重新向频道领导节点查询（防止错过订阅者变化的推送）
+const broadcastSubscriberCacheTTL = time.Minute * 10
+
+// 是否走广播投递
+func (d *deliverr) isBroadcast(req *deliverReq) bool {
+	if req.tagKey == broadcastTagKey {
+		return true
+	}
+	if req.channelType == wkproto.ChannelTypePerson || req.channelType == wkproto.ChannelTypeTemp {
+		return false
+	}
+	return req.ch != nil && req.ch.info.Large
+}
+
+func (d *deliverr) handleBroadcastReq(req *deliverReq) {
+
+	// 频道领导节点，将消息广播给其他在线节点
+	if req.tagKey != broadcastTagKey {
+		nodes := d.dm.s.clusterServer.GetConfig().Nodes
+		for _, node := range nodes {
+			if node.Id == d.dm.s.opts.Cluster.NodeId || !node.Online {
+				continue
+			}
+			d.dm.nodeManager.deliver(node.Id, req)
+		}
+	}
+
+	// 本节点在线用户
+	onlineUids := d.dm.s.userReactor.getOnlineLeaderUids()
+	if len(onlineUids) == 0 {
+		return
+	}
+
+	// 过滤出属于频道订阅者的在线用户，缓存中没有的用户向频道领导节点查询
+	channelId := d.dm.s.opts.CmdChannelConvertOrginalChannel(req.channelId)
+	channelKey := wkutil.ChannelToKey(channelId, req.channelType)
+	cache := d.dm.broadcastSubscribers
+	subscribers, unknownUids := cache.filter(channelKey, onlineUids)
+	if len(unknownUids) > 0 {
+		existUids, err := d.dm.s.requestExistSubscribers(channelId, req.channelType, unknownUids)
+		if err != nil {
+			d.Error("broadcast: requestExistSubscribers failed", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", req.channelType))
+			return
+		}
+		cache.set(channelKey, unknownUids, existUids)
+		subscribers = append(subscribers, existUids...)
+	}
+
+	if d.dm.s.opts.Logger.TraceOn {
+		for _, msg := range req.messages {
+			d.MessageTrace("广播投递", msg.SendPacket.ClientMsgNo, "deliverBroadcast", zap.Int("onlineCount", len(onlineUids)), zap.Int("userCount", len(subscribers)))
+		}
+	}
+
+	d.deliver(req, subscribers)
+}
+
+// 请求频道领导节点，过滤出属于频道订阅者的用户
+func (s *Server) requestExistSubscribers(channelId string, channelType uint8, uids []string) ([]string, error) {
+	leaderNode, err := s.cluster.LeaderOfChannelForRead(channelId, channelType)
+	if err != nil {
+		return nil, err
+	}
+	if leaderNode == nil {
+		return nil, errors.New("requestExistSubscribers: channel leader is nil")
+	}
+	if s.opts.IsLocalNode(leaderNode.Id) {
+		return s.existSubscribers(channelId, channelType, uids)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(s.ctx, time.Second*5)
+	defer cancel()
+
+	req := &subscriberExistReq{
+		ChannelId:   channelId,
+		ChannelType: channelType,
+		Uids:        uids,
+	}
+	resp, err := s.cluster.RequestWithContext(timeoutCtx, leaderNode.Id, "/wk/existSubscribers", req.Marshal())
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != proto.StatusOK {
+		return nil, fmt.Errorf("requestExistSubscribers: response status code is %d", resp.Status)
+	}
+	subResp := subscriberGetResp{}
+	err = subResp.Unmarshal(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return subResp, nil
+}
+
+// 过滤出属于频道订阅者的用户
+func (s *Server) existSubscribers(channelId string, channelType uint8, uids []string) ([]string, error) {
+	subscribers := make([]string, 0, len(uids))
+	for _, uid := range uids {
+		exist, err := s.store.ExistSubscriber(channelId, channelType, uid)
+		if err != nil {
+			return nil, err
+		}
+		if exist {
+			subscribers = append(subscribers, uid)
+		}
+	}
+	return subscribers, nil
+}
+
+// 本节点在线用户的频道订阅关系缓存（超大频道广播投递使用）
+type broadcastSubscriberCache struct {
+	mu       sync.RWMutex
+	channels map[string]*broadcastMembers // key为channelKey
+}
+
+type broadcastMembers struct {
+	members   map[string]bool // 用户是否是订阅者
+	createdAt time.Time
+}
+
+func newBroadcastSubscriberCache() *broadcastSubscriberCache {
+	return &broadcastSubscriberCache{
+		channels: make(map[string]*broadcastMembers),
+	}
+}
+
+// 按缓存过滤出订阅者，返回订阅者和缓存中没有的用户
+func (b *broadcastSubscriberCache) filter(channelKey string, uids []string) ([]string, []string) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	cache := b.channels[channelKey]
+	if cache == nil || time.Since(cache.createdAt) > broadcastSubscriberCacheTTL {
+		return nil, uids
+	}
+	subscribers := make([]string, 0, len(uids))
+	var unknownUids []string
+	for _, uid := range uids {
+		subscriber, ok := cache.members[uid]
+		if !ok {
+			unknownUids = append(unknownUids, uid)
+			continue
+		}
+		if subscriber {
+			subscribers = append(subscribers, uid)
+		}
+	}
+	return subscribers, unknownUids
+}
+
+// 缓存查询的结果，uids为查询的用户，subscribers为其中的订阅者
+func (b *broadcastSubscriberCache) set(channelKey string, uids []string, subscribers []string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	cache := b.channels[channelKey]
+	if cache == nil || time.Since(cache.createdAt) > broadcastSubscriberCacheTTL {
+		cache = &broadcastMembers{
+			members:   make(map[string]bool, len(uids)),
+			createdAt: time.Now(),
+		}
+		b.channels[channelKey] = cache
+	}
+	for _, uid := range uids {
+		cache.members[uid] = false
+	}
+	for _, uid := range subscribers {
+		cache.members[uid] = true
+	}
+}
+
+// 订阅者变化，只更新已缓存的频道
+func (b *broadcastSubscriberCache) update(channelKey string, uids []string, subscriber bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	cache := b.channels[channelKey]
+	if cache == nil {
+		return
+	}
+	for _, uid := range uids {
+		cache.members[uid] = subscriber
+	}
+}
+
+// 移除频道的缓存（订阅者被重置）
+func (b *broadcastSubscriberCache) remove(channelKey string) {
+	b.mu.Lock()
+	delete(b.channels, channelKey)
+	b.mu.Unlock()
+}
+
+// 清除过期的缓存
+func (b *broadcastSubscriberCache) cleanExpired() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for channelKey, cache := range b.channels {
+		if time.Since(cache.createdAt) > broadcastSubscriberCacheTTL {
+			delete(b.channels, channelKey)
+		}
+	}
+}
+
+// 订阅者变化通知
+type broadcastSubscriberChange struct {
+	ChannelId   string   `json:"channel_id"`
+	ChannelType uint8    `json:"channel_type"`
+	Uids        []string `json:"uids,omitempty"` // 变化的订阅者
+	Subscriber  int      `json:"subscriber"`     // 1.添加 0.移除
+	Reset       int      `json:"reset"`          // 1.订阅者被重置（清除频道的缓存）
+}
+
+func (c *broadcastSubscriberChange) apply(cache *broadcastSubscriberCache) {
+	channelKey := wkutil.ChannelToKey(c.ChannelId, c.ChannelType)
+	if c.Reset == 1 {
+		cache.remove(channelKey)
+		return
+	}
+	cache.update(channelKey, c.Uids, c.Subscriber == 1)
+}
+
+// 将超大频道的订阅者变化推送给所有在线节点（包括本节点），更新节点的订阅关系缓存
+func (s *Server) notifyBroadcastSubscriberChange(change *broadcastSubscriberChange) {
+	change.apply(s.deliverManager.broadcastSubscribers)
+
+	data := []byte(wkutil.ToJSON(change))
+	nodes := s.clusterServer.GetConfig().Nodes
+	for _, node := range nodes {
+		if node.Id == s.opts.Cluster.NodeId || !node.Online {
+			continue
+		}
+		if err := s.requestBroadcastSubscriberChange(node.Id, data); err != nil {
+			s.Warn("requestBroadcastSubscriberChange failed", zap.Error(err), zap.Uint64("nodeId", node.Id), zap.String("channelId", change.ChannelId))
+		}
+	}
+}
+
+func (s *Server) requestBroadcastSubscriberChange(nodeId uint64, data []byte) error {
+	timeoutCtx, cancel := context.WithTimeout(s.ctx, time.Second*5)
+	defer cancel()
+
+	resp, err := s.cluster.RequestWithContext(timeoutCtx, nodeId, "/wk/broadcastSubscriberChange", data)
+	if err != nil {
+		return err
+	}
+	if resp.Status != proto.StatusOK {
+		return fmt.Errorf("requestBroadcastSubscriberChange: response status code is %d", resp.Status)
+	}
+	return nil
+}
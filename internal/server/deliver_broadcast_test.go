@@ -0,0 +1,129 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/client"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBroadcastSubscriberCache(t *testing.T) {
+	cache := newBroadcastSubscriberCache()
+
+	// 没有缓存时全部需要查询
+	subscribers, unknownUids := cache.filter("g1", []string{"u1", "u2"})
+	assert.Empty(t, subscribers)
+	assert.Equal(t, []string{"u1", "u2"}, unknownUids)
+
+	cache.set("g1", []string{"u1", "u2"}, []string{"u1"})
+	subscribers, unknownUids = cache.filter("g1", []string{"u1", "u2", "u3"})
+	assert.Equal(t, []string{"u1"}, subscribers)
+	assert.Equal(t, []string{"u3"}, unknownUids)
+
+	// 订阅者变化
+	cache.update("g1", []string{"u2"}, true)
+	cache.update("g1", []string{"u1"}, false)
+	cache.update("g2", []string{"u1"}, true) // 没有缓存的频道不更新
+	subscribers, unknownUids = cache.filter("g1", []string{"u1", "u2"})
+	assert.Equal(t, []string{"u2"}, subscribers)
+	assert.Empty(t, unknownUids)
+	_, unknownUids = cache.filter("g2", []string{"u1"})
+	assert.Equal(t, []string{"u1"}, unknownUids)
+
+	// 重置
+	change := &broadcastSubscriberChange{ChannelId: "g1", ChannelType: wkproto.ChannelTypeGroup, Uids: []string{"u1"}, Subscriber: 1}
+	cache.set(wkutil.ChannelToKey("g1", wkproto.ChannelTypeGroup), []string{"u1"}, nil)
+	change.apply(cache)
+	subscribers, _ = cache.filter(wkutil.ChannelToKey("g1", wkproto.ChannelTypeGroup), []string{"u1"})
+	assert.Equal(t, []string{"u1"}, subscribers)
+	(&broadcastSubscriberChange{ChannelId: "g1", ChannelType: wkproto.ChannelTypeGroup, Reset: 1}).apply(cache)
+	_, unknownUids = cache.filter(wkutil.ChannelToKey("g1", wkproto.ChannelTypeGroup), []string{"u1"})
+	assert.Equal(t, []string{"u1"}, unknownUids)
+
+	// 过期
+	cache.set("g3", []string{"u1"}, []string{"u1"})
+	cache.channels["g3"].createdAt = time.Now().Add(-broadcastSubscriberCacheTTL - time.Second)
+	_, unknownUids = cache.filter("g3", []string{"u1"})
+	assert.Equal(t, []string{"u1"}, unknownUids)
+	cache.cleanExpired()
+	assert.NotContains(t, cache.channels, "g3")
+}
+
+func TestBroadcastDeliver(t *testing.T) {
+	s := NewTestServer(t)
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady(time.Second * 10) // 等待服务准备好
+
+	channelId := "large1"
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/channel/info", bytes.NewReader([]byte(wkutil.ToJson(map[string]interface{}{
+		"channel_id":   channelId,
+		"channel_type": wkproto.ChannelTypeGroup,
+		"large":        1,
+	}))))
+	s.apiServer.r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	TestAddSubscriber(t, s, channelId, wkproto.ChannelTypeGroup, "u1", "u2")
+
+	recvs := make(map[string]chan string)
+	clients := make(map[string]*client.Client)
+	for _, uid := range []string{"u1", "u2", "u3"} {
+		cli := TestCreateClient(t, s, uid)
+		defer cli.Close()
+		recvC := make(chan string, 10)
+		cli.SetOnRecv(func(recv *wkproto.RecvPacket) error {
+			recvC <- string(recv.Payload)
+			return nil
+		})
+		recvs[uid] = recvC
+		clients[uid] = cli
+	}
+	waitRecv := func(uid string, payload string) {
+		select {
+		case p := <-recvs[uid]:
+			assert.Equal(t, payload, p)
+		case <-time.After(time.Second * 10):
+			t.Fatalf("%s wait recv timeout", uid)
+		}
+	}
+	noRecv := func(uid string) {
+		select {
+		case p := <-recvs[uid]:
+			t.Fatalf("%s should not recv %s", uid, p)
+		case <-time.After(time.Millisecond * 500):
+		}
+	}
+
+	// 只有在线的订阅者收到
+	err = clients["u1"].SendMessage(client.NewChannel(channelId, wkproto.ChannelTypeGroup), []byte("hello1"))
+	assert.Nil(t, err)
+	waitRecv("u2", "hello1")
+	noRecv("u3")
+
+	// 订阅者变化后投递跟着变化
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/channel/subscriber_remove", bytes.NewReader([]byte(wkutil.ToJson(map[string]interface{}{
+		"channel_id":   channelId,
+		"channel_type": wkproto.ChannelTypeGroup,
+		"subscribers":  []string{"u2"},
+	}))))
+	s.apiServer.r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	TestAddSubscriber(t, s, channelId, wkproto.ChannelTypeGroup, "u3")
+
+	err = clients["u1"].SendMessage(client.NewChannel(channelId, wkproto.ChannelTypeGroup), []byte("hello2"))
+	assert.Nil(t, err)
+	waitRecv("u3", "hello2")
+	noRecv("u2")
+}
@@ -9,6 +9,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/RussellLuo/timingwheel"
 	"github.com/WuKongIM/WuKongIM/pkg/trace"
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
 	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
@@ -28,6 +29,9 @@ type deliverManager struct {
 	nextDeliverIndex int // 下一个投递者索引
 
 	nodeManager *nodeManager // 节点管理
+
+	broadcastSubscribers *broadcastSubscriberCache // 超大频道的订阅关系缓存
+	cleanTimer           *timingwheel.Timer
 }
 
 func newDeliverManager(s *Server) *deliverManager {
@@ -37,6 +41,8 @@ func newDeliverManager(s *Server) *deliverManager {
 		Log:         wklog.NewWKLog("deliveryManager"),
 		deliverrs:   make([]*deliverr, s.opts.Deliver.DeliverrCount),
 		nodeManager: newNodeManager(s),
+
+		broadcastSubscribers: newBroadcastSubscriberCache(),
 	}
 	return d
 }
@@ -50,10 +56,14 @@ func (d *deliverManager) start() error {
 			return err
 		}
 	}
+	d.cleanTimer = d.s.Schedule(broadcastSubscriberCacheTTL, d.broadcastSubscribers.cleanExpired)
 	return nil
 }
 
 func (d *deliverManager) stop() {
+	if d.cleanTimer != nil {
+		d.cleanTimer.Stop()
+	}
 	for _, deliverr := range d.deliverrs {
 		deliverr.stop()
 	}
@@ -191,6 +201,12 @@ func (d *deliverr) requestNodeChannelTag(nodeId uint64, req *tagReq) (*tagResp,
 }
 func (d *deliverr) handleDeliverReq(req *deliverReq) {
 
+	// ================== 超大频道广播投递 ==================
+	if d.isBroadcast(req) {
+		d.handleBroadcastReq(req)
+		return
+	}
+
 	// ================== 获取tag信息 ==================
 	var tg = d.dm.s.tagManager.getReceiverTag(req.tagKey)
 	if tg == nil {
@@ -619,3 +635,34 @@ func writeAesEncrypt(aesResultBuffer *bytebufferpool.ByteBuffer, signBuffer *byt
 	}
 	return nil
 }
+
+// 直接给连接写入一个接收包（不重试），payload为明文，写入前使用连接的密钥加密并生成MsgKey
+func (s *Server) writeRecvPacketNoRetry(conn *connContext, recvPacket *wkproto.RecvPacket) error {
+	payloadEnc, err := encryptMessagePayload2(recvPacket.Payload, conn)
+	if err != nil {
+		return err
+	}
+	recvPacket.Payload = payloadEnc
+
+	signBuffer := bytebufferpool.Get()
+	defer bytebufferpool.Put(signBuffer)
+	recvPacket.VerityBytes(signBuffer)
+
+	aesResultBuffer := bytebufferpool.Get()
+	defer bytebufferpool.Put(aesResultBuffer)
+	err = writeAesEncrypt(aesResultBuffer, signBuffer, conn)
+	if err != nil {
+		return err
+	}
+	m5 := md5.New()
+	m5.Write(aesResultBuffer.Bytes())
+	recvPacket.MsgKey = hex.EncodeToString(m5.Sum(nil))
+
+	data, err := s.opts.Proto.EncodeFrame(recvPacket, conn.protoVersion)
+	if err != nil {
+		return err
+	}
+	trace.GlobalTrace.Metrics.App().RecvPacketCountAdd(1)
+	trace.GlobalTrace.Metrics.App().RecvPacketBytesAdd(int64(len(data)))
+	return conn.write(data, wkproto.RECV)
+}
@@ -270,8 +270,26 @@ type ReactorUserMessage struct {
 	OutBytes   []byte // 需要输出的字节
 	Index      uint64 // 消息下标
 
+	ConnMeta connMeta // 连接的客户端信息（只有连接包携带）
 }
 
+// 连接的客户端信息
+type connMeta struct {
+	IP            string // 客户端ip
+	ClientVersion string // 客户端版本
+	UserAgent     string // 客户端UserAgent
+}
+
+func (c connMeta) isEmpty() bool {
+	return c.IP == "" && c.ClientVersion == "" && c.UserAgent == ""
+}
+
+// ReactorUserMessage编码的标记（旧版本这个字节只有0和1两个值，表示是否有包数据）
+const (
+	userMessageFlagPacket   uint8 = 1 << 0 // 有包数据
+	userMessageFlagConnMeta uint8 = 1 << 1 // 有连接的客户端信息（在最后）
+)
+
 // 这个大小是不准确的，只是一个大概的值，目的是计算传输的数据量
 func (m *ReactorUserMessage) Size() uint64 {
 	var size uint64 = 0
@@ -283,10 +301,21 @@ func (m *ReactorUserMessage) Size() uint64 {
 		size += (1 + uint64(len(m.OutBytes))) + 2
 	}
 	size += 8 // index
+	if m.hasConnMeta() {
+		size += uint64(len(m.ConnMeta.IP) + len(m.ConnMeta.ClientVersion) + len(m.ConnMeta.UserAgent) + 6)
+	}
 
 	return size
 }
 
+func (m *ReactorUserMessage) isConnect() bool {
+	return m.InPacket != nil && m.InPacket.GetFrameType() == wkproto.CONNECT
+}
+
+func (m *ReactorUserMessage) hasConnMeta() bool {
+	return m.isConnect() && !m.ConnMeta.isEmpty()
+}
+
 func (m *ReactorUserMessage) MarshalWithEncoder(encoder *wkproto.Encoder) error {
 	encoder.WriteUint64(m.FromNodeId)
 	encoder.WriteInt64(m.ConnId)
@@ -300,14 +329,25 @@ func (m *ReactorUserMessage) MarshalWithEncoder(encoder *wkproto.Encoder) error
 			return err
 		}
 	}
+	var flag uint8
+	if len(packetData) > 0 {
+		flag |= userMessageFlagPacket
+	}
+	if m.hasConnMeta() {
+		flag |= userMessageFlagConnMeta
+	}
+	encoder.WriteUint8(flag)
 	if len(packetData) > 0 {
-		encoder.WriteUint8(1) // 有包数据
 		encoder.WriteBinary(packetData)
 	} else {
-		encoder.WriteUint8(0) // 没包数据
 		encoder.WriteUint8(uint8(m.FrameType))
 		encoder.WriteBinary(m.OutBytes)
 	}
+	if flag&userMessageFlagConnMeta != 0 {
+		encoder.WriteString(m.ConnMeta.IP)
+		encoder.WriteString(m.ConnMeta.ClientVersion)
+		encoder.WriteString(m.ConnMeta.UserAgent)
+	}
 	return nil
 }
 
@@ -324,11 +364,11 @@ func (m *ReactorUserMessage) UnmarshalWithDecoder(decoder *wkproto.Decoder) erro
 		return err
 	}
 
-	hasPacket, err := decoder.Uint8()
+	flag, err := decoder.Uint8()
 	if err != nil {
 		return err
 	}
-	if hasPacket == 1 {
+	if flag&userMessageFlagPacket != 0 {
 		packetData, err := decoder.Binary()
 		if err != nil {
 			return err
@@ -349,6 +389,17 @@ func (m *ReactorUserMessage) UnmarshalWithDecoder(decoder *wkproto.Decoder) erro
 			return err
 		}
 	}
+	if flag&userMessageFlagConnMeta != 0 {
+		if m.ConnMeta.IP, err = decoder.String(); err != nil {
+			return err
+		}
+		if m.ConnMeta.ClientVersion, err = decoder.String(); err != nil {
+			return err
+		}
+		if m.ConnMeta.UserAgent, err = decoder.String(); err != nil {
+			return err
+		}
+	}
 	return nil
 
 }
@@ -689,16 +740,18 @@ func (c *channelReq) Unmarshal(data []byte) error {
 }
 
 type syncUserConversationResp struct {
-	ChannelId       string         `json:"channel_id"`         // 频道ID
-	ChannelType     uint8          `json:"channel_type"`       // 频道类型
-	Unread          int            `json:"unread"`             // 未读消息
-	Timestamp       int64          `json:"timestamp"`          // 最后一次会话时间
-	LastMsgSeq      uint32         `json:"last_msg_seq"`       // 最后一条消息seq
-	LastClientMsgNo string         `json:"last_client_msg_no"` // 最后一次消息客户端编号
-	OffsetMsgSeq    int64          `json:"offset_msg_seq"`     // 偏移位的消息seq
-	ReadedToMsgSeq  uint32         `json:"readed_to_msg_seq"`  // 已读至的消息seq
-	Version         int64          `json:"version"`            // 数据版本
-	Recents         []*MessageResp `json:"recents"`            // 最近N条消息
+	ChannelId       string         `json:"channel_id"`                  // 频道ID
+	ChannelType     uint8          `json:"channel_type"`                // 频道类型
+	Unread          int            `json:"unread"`                      // 未读消息
+	Timestamp       int64          `json:"timestamp"`                   // 最后一次会话时间
+	LastMsgSeq      uint32         `json:"last_msg_seq"`                // 最后一条消息seq
+	LastClientMsgNo string         `json:"last_client_msg_no"`          // 最后一次消息客户端编号
+	OffsetMsgSeq    int64          `json:"offset_msg_seq"`              // 偏移位的消息seq
+	ReadedToMsgSeq  uint32         `json:"readed_to_msg_seq"`           // 已读至的消息seq
+	Version         int64          `json:"version"`                     // 数据版本
+	ParentChannelId string         `json:"parent_channel_id,omitempty"` // 子区的父频道ID（只有子区会话才有值）
+	ThreadRoot      int64          `json:"thread_root,omitempty"`       // 子区的根消息ID（只有子区会话才有值）
+	Recents         []*MessageResp `json:"recents"`                     // 最近N条消息
 }
 
 func newSyncUserConversationResp(conversation wkdb.Conversation) *syncUserConversationResp {
@@ -808,6 +861,49 @@ func (s *subscriberGetReq) Unmarshal(data []byte) error {
 	return nil
 }
 
+// 判断订阅者是否存在的请求（返回存在的订阅者）
+type subscriberExistReq struct {
+	ChannelId   string   `json:"channel_id"`
+	ChannelType uint8    `json:"channel_type"`
+	Uids        []string `json:"uids"`
+}
+
+func (s *subscriberExistReq) Marshal() []byte {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+
+	enc.WriteString(s.ChannelId)
+	enc.WriteUint8(s.ChannelType)
+	enc.WriteUint32(uint32(len(s.Uids)))
+	for _, uid := range s.Uids {
+		enc.WriteString(uid)
+	}
+	return enc.Bytes()
+}
+
+func (s *subscriberExistReq) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if s.ChannelId, err = dec.String(); err != nil {
+		return err
+	}
+	if s.ChannelType, err = dec.Uint8(); err != nil {
+		return err
+	}
+	count, err := dec.Uint32()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < int(count); i++ {
+		uid, err := dec.String()
+		if err != nil {
+			return err
+		}
+		s.Uids = append(s.Uids, uid)
+	}
+	return nil
+}
+
 type subscriberGetResp []string
 
 func (s subscriberGetResp) Marshal() []byte {
@@ -970,24 +1066,28 @@ func (s syncackReq) Check() error {
 
 // ChannelInfoReq ChannelInfoReq
 type ChannelInfoReq struct {
-	ChannelID   string `json:"channel_id"`   // 频道ID
-	ChannelType uint8  `json:"channel_type"` // 频道类型
-	Large       int    `json:"large"`        // 是否是超大群
-	Ban         int    `json:"ban"`          // 是否封禁频道（封禁后此频道所有人都将不能发消息，除了系统账号）
-	Disband     int    `json:"disband"`      // 是否解散频道
+	ChannelID     string   `json:"channel_id"`     // 频道ID
+	ChannelType   uint8    `json:"channel_type"`   // 频道类型
+	Large         int      `json:"large"`          // 是否是超大群
+	Ban           int      `json:"ban"`            // 是否封禁频道（封禁后此频道所有人都将不能发消息，除了系统账号）
+	Disband       int      `json:"disband"`        // 是否解散频道
+	Webhook       string   `json:"webhook"`        // 频道自己的webhook地址，频道的消息会推送到此地址
+	WebhookEvents []string `json:"webhook_events"` // 频道webhook关注的事件，为空表示关注所有频道事件
 }
 
 func (c ChannelInfoReq) ToChannelInfo() wkdb.ChannelInfo {
 	createdAt := time.Now()
 	updatedAt := time.Now()
 	return wkdb.ChannelInfo{
-		ChannelId:   c.ChannelID,
-		ChannelType: c.ChannelType,
-		Large:       c.Large == 1,
-		Ban:         c.Ban == 1,
-		Disband:     c.Disband == 1,
-		CreatedAt:   &createdAt,
-		UpdatedAt:   &updatedAt,
+		ChannelId:     c.ChannelID,
+		ChannelType:   c.ChannelType,
+		Large:         c.Large == 1,
+		Ban:           c.Ban == 1,
+		Disband:       c.Disband == 1,
+		Webhook:       c.Webhook,
+		WebhookEvents: c.WebhookEvents,
+		CreatedAt:     &createdAt,
+		UpdatedAt:     &updatedAt,
 	}
 }
 
@@ -1001,6 +1101,7 @@ type MessageSendReq struct {
 	ChannelType uint8         `json:"channel_type"`  // 频道类型
 	Expire      uint32        `json:"expire"`        // 消息过期时间
 	Subscribers []string      `json:"subscribers"`   // 订阅者 如果此字段有值，表示消息只发给指定的订阅者
+	ThreadRoot  int64         `json:"thread_root"`   // 子区根消息ID，如果此字段有值，表示消息发送到此根消息的子区内
 	Payload     []byte        `json:"payload"`       // 消息内容
 }
 
@@ -1009,6 +1110,34 @@ func (m MessageSendReq) Check() error {
 	if m.Payload == nil || len(m.Payload) <= 0 {
 		return errors.New("payload不能为空！")
 	}
+	if m.ThreadRoot > 0 && m.ChannelType == wkproto.ChannelTypePerson {
+		return errors.New("个人频道不支持子区！")
+	}
+	if m.ThreadRoot > 0 && len(m.Subscribers) > 0 {
+		return errors.New("子区消息不能指定subscribers！")
+	}
+	return nil
+}
+
+// 瞬态事件发送请求
+type transientSendReq struct {
+	ClientMsgNo string `json:"client_msg_no"` // 客户端消息编号
+	FromUID     string `json:"from_uid"`      // 发送者UID，为空则为系统账号
+	ChannelID   string `json:"channel_id"`    // 频道ID（个人频道为接收者uid）
+	ChannelType uint8  `json:"channel_type"`  // 频道类型
+	Payload     []byte `json:"payload"`       // 事件内容
+}
+
+func (t transientSendReq) Check() error {
+	if strings.TrimSpace(t.ChannelID) == "" {
+		return errors.New("channel_id不能为空！")
+	}
+	if t.ChannelType == 0 {
+		return errors.New("channel_type不能为0！")
+	}
+	if len(t.Payload) == 0 {
+		return errors.New("payload不能为空！")
+	}
 	return nil
 }
 
@@ -1067,6 +1196,81 @@ func (r tmpSubscriberSetReq) Check() error {
 	return nil
 }
 
+type tmpChannelCreateReq struct {
+	ChannelId   string   `json:"channel_id"`  // 频道ID（需以临时频道后缀结尾）
+	Subscribers []string `json:"subscribers"` // 订阅者
+	Ttl         int64    `json:"ttl"`         // 创建后的存活时间（秒），0表示使用默认配置，-1表示不限制
+	IdleTtl     int64    `json:"idle_ttl"`    // 闲置（没有新消息）的存活时间（秒），0表示使用默认配置，-1表示不限制
+}
+
+func (r tmpChannelCreateReq) Check() error {
+	if r.ChannelId == "" {
+		return errors.New("channel_id不能为空！")
+	}
+	if len(r.Subscribers) <= 0 {
+		return errors.New("subscribers不能为空！")
+	}
+	if r.Ttl < -1 || r.IdleTtl < -1 {
+		return errors.New("ttl或idle_ttl不合法！")
+	}
+	return nil
+}
+
+type channelDisbandReq struct {
+	ChannelId   string `json:"channel_id"`   // 频道ID
+	ChannelType uint8  `json:"channel_type"` // 频道类型
+	Reason      string `json:"reason"`       // 解散原因（会通知给频道成员）
+	Purge       int    `json:"purge"`        // 是否在宽限期后清除频道消息和成员 1.是 0.否
+	GracePeriod int64  `json:"grace_period"` // 清除前的宽限期（秒），0表示使用默认配置
+}
+
+func (r channelDisbandReq) Check() error {
+	if strings.TrimSpace(r.ChannelId) == "" {
+		return errors.New("channel_id不能为空！")
+	}
+	if r.ChannelType == 0 {
+		return errors.New("channel_type不能为0！")
+	}
+	if r.ChannelType == wkproto.ChannelTypePerson {
+		return errors.New("个人频道不支持解散！")
+	}
+	if r.GracePeriod < 0 {
+		return errors.New("grace_period不合法！")
+	}
+	return nil
+}
+
+type channelDisbandResp struct {
+	ChannelId   string `json:"channel_id"`   // 频道ID
+	ChannelType uint8  `json:"channel_type"` // 频道类型
+	Step        string `json:"step"`         // 当前进度 frozen:已冻结 notified:已通知成员 purged:已清除
+	Reason      string `json:"reason"`       // 解散原因
+	Purge       int    `json:"purge"`        // 是否清除频道消息和成员
+	PurgeAt     int64  `json:"purge_at"`     // 清除的时间（秒）
+	CreatedAt   int64  `json:"created_at"`   // 开始解散的时间（秒）
+	UpdatedAt   int64  `json:"updated_at"`   // 进度更新时间（秒）
+}
+
+func newChannelDisbandResp(c wkdb.ChannelDisband) *channelDisbandResp {
+	resp := &channelDisbandResp{
+		ChannelId:   c.ChannelId,
+		ChannelType: c.ChannelType,
+		Step:        c.Status.String(),
+		Reason:      c.Reason,
+		Purge:       wkutil.BoolToInt(c.Purge),
+	}
+	if c.PurgeAt != nil {
+		resp.PurgeAt = c.PurgeAt.Unix()
+	}
+	if c.CreatedAt != nil {
+		resp.CreatedAt = c.CreatedAt.Unix()
+	}
+	if c.UpdatedAt != nil {
+		resp.UpdatedAt = c.UpdatedAt.Unix()
+	}
+	return resp
+}
+
 type readyState struct {
 	processing bool // 处理中
 	willRetry  bool // 将要重试
@@ -3,6 +3,7 @@ package server
 import (
 	"testing"
 
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -18,3 +19,59 @@ func TestSubscriberGetRespMarshal(t *testing.T) {
 	assert.Equal(t, len(resp), len(resp1))
 
 }
+
+func TestSubscriberExistReqMarshal(t *testing.T) {
+	req := &subscriberExistReq{
+		ChannelId:   "g1",
+		ChannelType: 2,
+		Uids:        []string{"test1", "test2"},
+	}
+	data := req.Marshal()
+
+	req1 := &subscriberExistReq{}
+	err := req1.Unmarshal(data)
+	assert.NoError(t, err)
+
+	assert.Equal(t, req.ChannelId, req1.ChannelId)
+	assert.Equal(t, req.ChannelType, req1.ChannelType)
+	assert.Equal(t, req.Uids, req1.Uids)
+}
+
+func TestReactorUserMessageMarshal(t *testing.T) {
+	msg := ReactorUserMessage{
+		FromNodeId: 1,
+		ConnId:     2,
+		DeviceId:   "d1",
+		InPacket:   &wkproto.ConnectPacket{UID: "u1", Token: "t1"},
+		ConnMeta:   connMeta{IP: "127.0.0.1", ClientVersion: "1.0.0", UserAgent: "test"},
+	}
+	enc := wkproto.NewEncoder()
+	err := msg.MarshalWithEncoder(enc)
+	assert.NoError(t, err)
+	data := enc.Bytes()
+	enc.End()
+
+	msg1 := ReactorUserMessage{}
+	err = msg1.UnmarshalWithDecoder(wkproto.NewDecoder(data))
+	assert.NoError(t, err)
+	assert.Equal(t, msg.ConnMeta, msg1.ConnMeta)
+	assert.Equal(t, "u1", msg1.InPacket.(*wkproto.ConnectPacket).UID)
+
+	// 旧版本编码的连接包（没有连接的客户端信息）
+	packetData, err := defaultWkproto.EncodeFrame(msg.InPacket, defaultProtoVersion)
+	assert.NoError(t, err)
+	enc = wkproto.NewEncoder()
+	enc.WriteUint64(1)
+	enc.WriteInt64(2)
+	enc.WriteString("d1")
+	enc.WriteUint8(1)
+	enc.WriteBinary(packetData)
+	data = enc.Bytes()
+	enc.End()
+
+	msg2 := ReactorUserMessage{}
+	err = msg2.UnmarshalWithDecoder(wkproto.NewDecoder(data))
+	assert.NoError(t, err)
+	assert.True(t, msg2.ConnMeta.isEmpty())
+	assert.Equal(t, "u1", msg2.InPacket.(*wkproto.ConnectPacket).UID)
+}
@@ -0,0 +1,764 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/mqtt"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wknet"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+// mqtt网关
+// 每个mqtt连接在服务端等同于一个普通客户端连接：CONNECT 转换为连接包走正常的认证（token为设备token），
+// PUBLISH 转换为发送包进入频道流程，频道投递的消息按订阅推送给设备，QoS1的消息在设备PUBACK后才recvack。
+// 主题格式为 {channelType}/{channelId}，例如 2/group1 表示群频道group1，1/u2 表示与u2的个人频道。
+type mqttGateway struct {
+	s *Server
+	wklog.Log
+}
+
+func newMQTTGateway(s *Server) *mqttGateway {
+	return &mqttGateway{
+		s:   s,
+		Log: wklog.NewWKLog("mqttGateway"),
+	}
+}
+
+var errMQTTPacketTooLarge = errors.New("mqtt packet too large")
+
+// 处理mqtt连接收到的数据
+func (g *mqttGateway) onData(conn wknet.Conn, buff []byte) error {
+	var connCtx *connContext
+	if connCtxObj := conn.Context(); connCtxObj != nil {
+		connCtx = connCtxObj.(*connContext)
+	}
+	offset := 0
+	for len(buff) > offset {
+		version := mqtt.Version311
+		if connCtx != nil {
+			version = connCtx.mqtt.version
+		}
+		packet, size, err := mqtt.Decode(buff[offset:], version)
+		if err != nil {
+			g.Warn("Failed to decode the mqtt packet,conn will be closed", zap.Error(err), zap.Int64("connId", conn.ID()))
+			if connCtx == nil && errors.Is(err, mqtt.ErrUnsupportedVersion) {
+				g.writeRaw(conn, &mqtt.ConnackPacket{ReasonCode: mqtt.ConnRefusedUnacceptableProtocol}, mqtt.Version311)
+			}
+			_ = conn.Close()
+			return nil
+		}
+		if packet == nil {
+			break
+		}
+		offset += size
+
+		if connCtx == nil {
+			connectPacket, ok := packet.(*mqtt.ConnectPacket)
+			if !ok {
+				g.Warn("请先进行连接！", zap.String("packetType", packet.Type().String()))
+				_ = conn.Close()
+				return nil
+			}
+			connCtx = g.handleConnect(conn, connectPacket)
+			if connCtx == nil {
+				return nil
+			}
+			continue
+		}
+		if !g.handlePacket(connCtx, packet) {
+			return nil
+		}
+	}
+	_, _ = conn.Discard(offset)
+	return nil
+}
+
+func (g *mqttGateway) handleConnect(conn wknet.Conn, connectPacket *mqtt.ConnectPacket) *connContext {
+	version := connectPacket.ProtocolVersion
+	reject := func(code mqtt.ReasonCode) *connContext {
+		connack := &mqtt.ConnackPacket{ReasonCode: byte(code)}
+		if version != mqtt.Version5 {
+			connack.ReasonCode = mqtt.ConnackCodeV3(code)
+		}
+		g.writeRaw(conn, connack, version)
+		g.s.timingWheel.AfterFunc(time.Second, func() {
+			_ = conn.Close()
+		})
+		return nil
+	}
+
+	// 用户名为uid，未填写用户名时使用客户端id作为uid
+	uid := strings.TrimSpace(connectPacket.Username)
+	if uid == "" {
+		uid = strings.TrimSpace(connectPacket.ClientID)
+	}
+	if uid == "" || IsSpecialChar(uid) {
+		g.Warn("mqtt uid is illegal,conn will be closed", zap.String("uid", uid), zap.String("clientId", connectPacket.ClientID))
+		return reject(mqtt.ClientIdentifierNotValid)
+	}
+	if connectPacket.Properties != nil && connectPacket.Properties.AuthMethod != "" { // 不支持扩展认证
+		return reject(mqtt.BadAuthMethod)
+	}
+
+	sess := newMQTTSession(connectPacket)
+	if sess.clientId == "" {
+		if version != mqtt.Version5 && !connectPacket.CleanStart { // 3.1.1 客户端id为空时必须清除会话
+			return reject(mqtt.ClientIdentifierNotValid)
+		}
+		sess.clientId = wkutil.GenUUID()
+		sess.assignedClientId = true
+	}
+
+	if version == mqtt.Version5 {
+		conn.SetValue(wknet.ConnValueUserAgent, "mqtt/5.0")
+	} else {
+		conn.SetValue(wknet.ConnValueUserAgent, "mqtt/3.1.1")
+	}
+
+	// 网关代替设备完成DH密钥交换，消息在网关内加解密
+	_, clientPubKey := wkutil.GetCurve25519KeypPair()
+	packet := &wkproto.ConnectPacket{
+		Version:         wkproto.LatestVersion,
+		ClientKey:       base64.StdEncoding.EncodeToString(clientPubKey[:]),
+		DeviceID:        sess.clientId,
+		DeviceFlag:      g.s.opts.MQTT.DeviceFlag,
+		ClientTimestamp: time.Now().UnixNano() / 1000 / 1000,
+		UID:             uid,
+		Token:           string(connectPacket.Password),
+	}
+
+	sub := g.s.userReactor.reactorSub(uid)
+	connInfo := connInfo{
+		connId:       conn.ID(),
+		uid:          uid,
+		deviceId:     packet.DeviceID,
+		deviceFlag:   packet.DeviceFlag,
+		protoVersion: packet.Version,
+	}
+	connCtx := newConnContext(connInfo, conn, sub)
+	connCtx.mqtt = sess
+	conn.SetContext(connCtx)
+
+	// 添加用户的连接，如果用户不存在则创建
+	g.s.userReactor.addConnAndCreateUserHandlerIfNotExist(connCtx)
+
+	connCtx.addConnectPacket(packet)
+	return connCtx
+}
+
+// 处理认证后收到的报文，返回false表示连接已关闭
+func (g *mqttGateway) handlePacket(connCtx *connContext, packet mqtt.ControlPacket) bool {
+	sess := connCtx.mqtt
+	sess.inMu.Lock()
+	defer sess.inMu.Unlock()
+
+	if !sess.ready.Load() { // 认证完成前收到的报文先缓存，认证成功后再处理
+		if len(sess.pending) >= mqttMaxPendingPackets {
+			g.Warn("too many mqtt packets before connack,conn will be closed", zap.String("uid", connCtx.uid))
+			connCtx.close()
+			return false
+		}
+		sess.pending = append(sess.pending, packet)
+		return true
+	}
+	if !g.dispatchPending(connCtx) {
+		return false
+	}
+	return g.dispatch(connCtx, packet)
+}
+
+// 处理认证前缓存的报文，调用者需持有inMu
+func (g *mqttGateway) dispatchPending(connCtx *connContext) bool {
+	sess := connCtx.mqtt
+	for len(sess.pending) > 0 {
+		packet := sess.pending[0]
+		sess.pending = sess.pending[1:]
+		if !g.dispatch(connCtx, packet) {
+			return false
+		}
+	}
+	sess.pending = nil
+	return true
+}
+
+func (g *mqttGateway) dispatch(connCtx *connContext, packet mqtt.ControlPacket) bool {
+	switch p := packet.(type) {
+	case *mqtt.PublishPacket:
+		return g.handlePublish(connCtx, p)
+	case *mqtt.AckPacket:
+		if p.PacketType != mqtt.PUBACK { // 只支持QoS0和QoS1
+			return g.protocolError(connCtx, mqtt.QoSNotSupported, "qos 2 not supported")
+		}
+		g.handlePuback(connCtx, p)
+	case *mqtt.SubscribePacket:
+		g.handleSubscribe(connCtx, p)
+	case *mqtt.UnsubscribePacket:
+		g.handleUnsubscribe(connCtx, p)
+	case *mqtt.PingreqPacket:
+		connCtx.addOtherPacket(&wkproto.PingPacket{})
+	case *mqtt.DisconnectPacket:
+		connCtx.mqtt.disconnected.Store(true)
+		connCtx.mqtt.disconnectWill.Store(p.ReasonCode == 0x04) // 0x04: Disconnect with Will Message
+		connCtx.close()
+		return false
+	case *mqtt.ConnectPacket:
+		return g.protocolError(connCtx, mqtt.ProtocolError, "duplicate connect")
+	default:
+		return g.protocolError(connCtx, mqtt.ProtocolError, "unsupported packet "+packet.Type().String())
+	}
+	return true
+}
+
+// 协议错误，5.0 发送DISCONNECT后关闭连接，3.1.1 直接关闭连接
+func (g *mqttGateway) protocolError(connCtx *connContext, code mqtt.ReasonCode, reason string) bool {
+	g.Warn("mqtt protocol error,conn will be closed", zap.String("uid", connCtx.uid), zap.String("reason", reason))
+	if connCtx.mqtt.version == mqtt.Version5 {
+		_ = g.writePacket(connCtx, &mqtt.DisconnectPacket{ReasonCode: code, Properties: &mqtt.Properties{ReasonString: reason}})
+	}
+	connCtx.close()
+	return false
+}
+
+func (g *mqttGateway) handlePublish(connCtx *connContext, p *mqtt.PublishPacket) bool {
+	sess := connCtx.mqtt
+	if p.QoS > 1 {
+		return g.protocolError(connCtx, mqtt.QoSNotSupported, "qos 2 not supported")
+	}
+	channelId, channelType, ok := parseMQTTTopic(p.Topic)
+	if !ok {
+		if sess.version != mqtt.Version5 {
+			return g.protocolError(connCtx, mqtt.TopicNameInvalid, "topic name invalid")
+		}
+		if p.QoS > 0 {
+			_ = g.writePacket(connCtx, mqtt.NewPuback(p.PacketID, mqtt.TopicNameInvalid))
+		}
+		return true
+	}
+	// 保留标记的消息和普通消息一样进入频道，存储成功（sendack）后设置为主题的保留消息，payload为空则清除保留消息
+	publish := mqttPublish{
+		qos:      p.QoS,
+		packetId: p.PacketID,
+		retain:   p.Retain && g.s.opts.MQTT.RetainOn,
+		topic:    p.Topic,
+	}
+	if publish.retain {
+		publish.payload = p.Payload
+	}
+	sendPacket := &wkproto.SendPacket{
+		Framer: wkproto.Framer{
+			RedDot: true,
+		},
+		ClientSeq:   sess.addPublish(publish),
+		ClientMsgNo: wkutil.GenUUID(),
+		ChannelID:   channelId,
+		ChannelType: channelType,
+		Payload:     p.Payload,
+	}
+	if err := connCtx.encryptSendPacket(sendPacket); err != nil {
+		g.Error("加密消息失败！", zap.Error(err), zap.String("uid", connCtx.uid))
+		connCtx.mqtt.removePublish(sendPacket.ClientSeq)
+		if p.QoS > 0 && sess.version == mqtt.Version5 {
+			_ = g.writePacket(connCtx, mqtt.NewPuback(p.PacketID, mqtt.UnspecifiedError))
+		}
+		return true
+	}
+	connCtx.addSendPacket(sendPacket)
+	return true
+}
+
+func (g *mqttGateway) handlePuback(connCtx *connContext, p *mqtt.AckPacket) {
+	connCtx.keepActivity()
+	inflight, ok := connCtx.mqtt.removeInflight(p.PacketID)
+	if !ok || inflight.messageId == 0 {
+		return
+	}
+	connCtx.addOtherPacket(&wkproto.RecvackPacket{
+		MessageID:  inflight.messageId,
+		MessageSeq: inflight.messageSeq,
+	})
+}
+
+func (g *mqttGateway) handleSubscribe(connCtx *connContext, p *mqtt.SubscribePacket) {
+	connCtx.keepActivity()
+	sess := connCtx.mqtt
+	v5 := sess.version == mqtt.Version5
+	hasSubId := p.Properties != nil && len(p.Properties.SubscriptionIdentifier) > 0
+
+	reasonCodes := make([]byte, 0, len(p.Subscriptions))
+	retains := make([]mqtt.Subscription, 0, len(p.Subscriptions))
+	for _, sub := range p.Subscriptions {
+		var code mqtt.ReasonCode
+		switch {
+		case hasSubId:
+			code = mqtt.SubscriptionIdsNotSupported
+		case strings.HasPrefix(sub.Topic, mqtt.SharedSubscriptionPrefix):
+			code = mqtt.SharedSubscriptionsNotSupported
+		case !mqtt.ValidTopicFilter(sub.Topic):
+			code = mqtt.TopicFilterInvalid
+		}
+		if code != mqtt.Success {
+			if v5 {
+				reasonCodes = append(reasonCodes, byte(code))
+			} else {
+				reasonCodes = append(reasonCodes, mqtt.SubackFailure)
+			}
+			continue
+		}
+		if sub.QoS > 1 {
+			sub.QoS = 1
+		}
+		isNew := sess.subscribe(sub)
+		reasonCodes = append(reasonCodes, sub.QoS)
+
+		if !g.s.opts.MQTT.RetainOn || mqtt.HasWildcard(sub.Topic) {
+			continue
+		}
+		if v5 && (sub.RetainHandling == 2 || (sub.RetainHandling == 1 && !isNew)) {
+			continue
+		}
+		retains = append(retains, sub)
+	}
+	_ = g.writePacket(connCtx, &mqtt.SubackPacket{PacketID: p.PacketID, ReasonCodes: reasonCodes})
+
+	if len(retains) > 0 {
+		go g.sendRetained(connCtx, retains)
+	}
+}
+
+func (g *mqttGateway) handleUnsubscribe(connCtx *connContext, p *mqtt.UnsubscribePacket) {
+	connCtx.keepActivity()
+	reasonCodes := make([]byte, 0, len(p.Topics))
+	for _, topic := range p.Topics {
+		if connCtx.mqtt.unsubscribe(topic) {
+			reasonCodes = append(reasonCodes, byte(mqtt.Success))
+		} else {
+			reasonCodes = append(reasonCodes, byte(mqtt.NoSubscriptionExisted))
+		}
+	}
+	_ = g.writePacket(connCtx, &mqtt.UnsubackPacket{PacketID: p.PacketID, ReasonCodes: reasonCodes})
+}
+
+// 推送订阅主题的保留消息（需要有频道的读取权限）
+func (g *mqttGateway) sendRetained(connCtx *connContext, subs []mqtt.Subscription) {
+	for _, sub := range subs {
+		channelId, channelType, ok := parseMQTTTopic(sub.Topic)
+		if !ok {
+			continue
+		}
+		retainChannelId := channelId
+		if channelType == wkproto.ChannelTypePerson { // 订阅1/u2收到的是u2发给自己的消息
+			retainChannelId = mqttRetainPersonChannelId(channelId, connCtx.uid)
+		} else {
+			uids, err := g.s.requestExistSubscribers(channelId, channelType, []string{connCtx.uid})
+			if err != nil {
+				g.Warn("sendRetained: requestExistSubscribers failed", zap.Error(err), zap.String("uid", connCtx.uid), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+				continue
+			}
+			if len(uids) == 0 { // 不是频道的订阅者
+				continue
+			}
+		}
+		retain, err := g.getRetained(retainChannelId, channelType)
+		if err != nil {
+			g.Warn("sendRetained: getRetained failed", zap.Error(err), zap.String("uid", connCtx.uid), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+			continue
+		}
+		if retain == nil {
+			continue
+		}
+		if connCtx.isClosed() {
+			return
+		}
+		publish := &mqtt.PublishPacket{
+			QoS:     sub.QoS,
+			Retain:  true,
+			Topic:   sub.Topic,
+			Payload: retain.Payload,
+		}
+		g.setMessageProperties(connCtx, publish, retain.FromUid, retain.MessageId, uint32(retain.MessageSeq), retain.Timestamp)
+		if publish.QoS > 0 {
+			packetId, _, ok := connCtx.mqtt.addInflight(mqttInflight{}, g.s.opts.MQTT.MaxInflight)
+			if ok {
+				publish.PacketID = packetId
+			} else {
+				publish.QoS = 0
+			}
+		}
+		if err := g.writePacket(connCtx, publish); err != nil {
+			g.Debug("sendRetained: write failed", zap.Error(err), zap.String("uid", connCtx.uid))
+			if publish.QoS > 0 {
+				connCtx.mqtt.removeInflight(publish.PacketID)
+			}
+		}
+	}
+}
+
+// 5.0 将消息的元数据作为用户属性推送
+func (g *mqttGateway) setMessageProperties(connCtx *connContext, publish *mqtt.PublishPacket, fromUid string, messageId int64, messageSeq uint32, timestamp int32) {
+	if connCtx.mqtt.version != mqtt.Version5 {
+		return
+	}
+	props := &mqtt.Properties{}
+	props.AddUser("from_uid", fromUid)
+	props.AddUser("message_id", strconv.FormatInt(messageId, 10))
+	props.AddUser("message_seq", strconv.FormatUint(uint64(messageSeq), 10))
+	props.AddUser("timestamp", strconv.FormatInt(int64(timestamp), 10))
+	publish.Properties = props
+}
+
+// 将要写给连接的悟空IM协议数据转换为mqtt报文后写入
+func (g *mqttGateway) write(connCtx *connContext, data []byte) error {
+	offset := 0
+	for len(data) > offset {
+		frame, size, err := g.s.opts.Proto.DecodeFrame(data[offset:], connCtx.protoVersion)
+		if err != nil {
+			g.Warn("write: decode frame failed", zap.Error(err), zap.String("uid", connCtx.uid))
+			return err
+		}
+		if frame == nil {
+			break
+		}
+		offset += size
+		switch frame.GetFrameType() {
+		case wkproto.CONNACK:
+			g.handleConnack(connCtx, frame.(*wkproto.ConnackPacket))
+		case wkproto.RECV:
+			g.handleRecv(connCtx, frame.(*wkproto.RecvPacket))
+		case wkproto.SENDACK:
+			g.handleSendack(connCtx, frame.(*wkproto.SendackPacket))
+		case wkproto.PONG:
+			_ = g.writePacket(connCtx, &mqtt.PingrespPacket{})
+		case wkproto.DISCONNECT:
+			g.handleDisconnect(connCtx, frame.(*wkproto.DisconnectPacket))
+		default: // 其他的包mqtt设备不需要
+			g.Debug("write: ignore frame", zap.String("frameType", frame.GetFrameType().String()), zap.String("uid", connCtx.uid))
+		}
+	}
+	return nil
+}
+
+func (g *mqttGateway) handleConnack(connCtx *connContext, connack *wkproto.ConnackPacket) {
+	sess := connCtx.mqtt
+	code := mqttReasonCodeOfConnack(connack.ReasonCode)
+	packet := &mqtt.ConnackPacket{}
+	if sess.version == mqtt.Version5 {
+		packet.ReasonCode = byte(code)
+		if code == mqtt.Success {
+			retainAvailable := byte(0)
+			if g.s.opts.MQTT.RetainOn {
+				retainAvailable = 1
+			}
+			packet.Properties = &mqtt.Properties{
+				MaximumQoS:           mqtt.Byte(1),
+				RetainAvailable:      mqtt.Byte(retainAvailable),
+				WildcardSubAvailable: mqtt.Byte(1),
+				SubIDAvailable:       mqtt.Byte(0),
+				SharedSubAvailable:   mqtt.Byte(0),
+				// 会话不做持久化
+				SessionExpiryInterval: mqtt.Uint32(0),
+			}
+			if sess.assignedClientId {
+				packet.Properties.AssignedClientID = sess.clientId
+			}
+		}
+	} else {
+		packet.ReasonCode = mqtt.ConnackCodeV3(code)
+	}
+	_ = g.writePacket(connCtx, packet)
+
+	if code != mqtt.Success { // 认证失败，发送CONNACK后关闭连接
+		g.s.timingWheel.AfterFunc(time.Second, connCtx.close)
+		return
+	}
+	if sess.keepAlive > 0 { // 按心跳间隔的1.5倍检测空闲
+		connCtx.conn.SetMaxIdle(time.Duration(sess.keepAlive) * time.Second * 3 / 2)
+	}
+	sess.ready.Store(true)
+
+	// 处理认证前收到的报文
+	go func() {
+		sess.inMu.Lock()
+		defer sess.inMu.Unlock()
+		g.dispatchPending(connCtx)
+	}()
+}
+
+func (g *mqttGateway) handleRecv(connCtx *connContext, recv *wkproto.RecvPacket) {
+	recvack := func() {
+		connCtx.addOtherPacket(&wkproto.RecvackPacket{
+			MessageID:  recv.MessageID,
+			MessageSeq: recv.MessageSeq,
+		})
+	}
+	topic := formatMQTTTopic(recv.ChannelType, recv.ChannelID)
+	qos, ok := connCtx.mqtt.match(topic)
+	if !ok { // 设备没有订阅，直接确认
+		recvack()
+		return
+	}
+	payload, err := connCtx.decryptRecvPayload(recv)
+	if err != nil {
+		g.Error("解密消息payload失败！", zap.Error(err), zap.String("uid", connCtx.uid), zap.Int64("messageId", recv.MessageID))
+		recvack()
+		return
+	}
+	publish := &mqtt.PublishPacket{
+		QoS:     qos,
+		Topic:   topic,
+		Payload: payload,
+	}
+	g.setMessageProperties(connCtx, publish, recv.FromUID, recv.MessageID, recv.MessageSeq, recv.Timestamp)
+	if qos > 0 {
+		packetId, dup, ok := connCtx.mqtt.addInflight(mqttInflight{messageId: recv.MessageID, messageSeq: recv.MessageSeq}, g.s.opts.MQTT.MaxInflight)
+		if ok {
+			publish.PacketID = packetId
+			publish.Dup = dup
+		} else { // 推送中的消息太多，降级为QoS0
+			publish.QoS = 0
+		}
+	}
+	err = g.writePacket(connCtx, publish)
+	if err != nil && publish.QoS > 0 {
+		connCtx.mqtt.removeInflight(publish.PacketID)
+	}
+	if publish.QoS == 0 || errors.Is(err, errMQTTPacketTooLarge) {
+		recvack()
+	}
+}
+
+func (g *mqttGateway) handleSendack(connCtx *connContext, sendack *wkproto.SendackPacket) {
+	publish, ok := connCtx.mqtt.removePublish(sendack.ClientSeq)
+	if !ok { // QoS0的消息不需要回复
+		return
+	}
+	if publish.retain && sendack.ReasonCode == wkproto.ReasonSuccess {
+		go g.setRetained(connCtx.uid, publish, sendack)
+	}
+	if publish.qos > 0 {
+		_ = g.writePacket(connCtx, mqtt.NewPuback(publish.packetId, mqttReasonCodeOfSendack(sendack.ReasonCode)))
+	}
+}
+
+// 设置主题的保留消息（按消息序号只保留最新的，payload为空表示清除）
+func (g *mqttGateway) setRetained(uid string, publish mqttPublish, sendack *wkproto.SendackPacket) {
+	channelId, channelType, ok := parseMQTTTopic(publish.topic)
+	if !ok {
+		return
+	}
+	if channelType == wkproto.ChannelTypePerson {
+		channelId = mqttRetainPersonChannelId(uid, channelId)
+	}
+	err := g.s.store.SetMQTTRetain(wkdb.MQTTRetain{
+		ChannelId:   channelId,
+		ChannelType: channelType,
+		MessageId:   sendack.MessageID,
+		MessageSeq:  uint64(sendack.MessageSeq),
+		FromUid:     uid,
+		Timestamp:   int32(time.Now().Unix()),
+		Payload:     publish.payload,
+	})
+	if err != nil {
+		g.Warn("set mqtt retained message failed", zap.Error(err), zap.String("uid", uid), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+	}
+}
+
+// 获取频道的保留消息（没有返回nil）
+func (g *mqttGateway) getRetained(channelId string, channelType uint8) (*wkdb.MQTTRetain, error) {
+	leaderId, err := g.s.cluster.SlotLeaderIdOfChannel(channelId, channelType)
+	if err != nil {
+		return nil, err
+	}
+	if g.s.opts.IsLocalNode(leaderId) {
+		return g.getLocalRetained(channelId, channelType)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(g.s.ctx, time.Second*5)
+	defer cancel()
+	req := &channelReq{
+		ChannelId:   channelId,
+		ChannelType: channelType,
+	}
+	resp, err := g.s.cluster.RequestWithContext(timeoutCtx, leaderId, "/wk/mqttRetainGet", req.Marshal())
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != proto.StatusOK {
+		return nil, fmt.Errorf("getRetained: response status code is %d", resp.Status)
+	}
+	if len(resp.Body) == 0 {
+		return nil, nil
+	}
+	retain := &wkdb.MQTTRetain{}
+	if err = retain.Unmarshal(resp.Body); err != nil {
+		return nil, err
+	}
+	return retain, nil
+}
+
+// 获取本节点存储的频道保留消息（频道所在槽的领导节点）
+func (g *mqttGateway) getLocalRetained(channelId string, channelType uint8) (*wkdb.MQTTRetain, error) {
+	retain, err := g.s.store.GetMQTTRetain(channelId, channelType)
+	if err == wkdb.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &retain, nil
+}
+
+func (g *mqttGateway) handleDisconnect(connCtx *connContext, disconnect *wkproto.DisconnectPacket) {
+	if connCtx.mqtt.version != mqtt.Version5 { // 3.1.1 没有服务端的DISCONNECT，由服务端随后关闭连接
+		return
+	}
+	code := mqtt.UnspecifiedError
+	switch disconnect.ReasonCode {
+	case wkproto.ReasonConnectKick:
+		code = mqtt.SessionTakenOver
+	case wkproto.ReasonBan:
+		code = mqtt.NotAuthorized
+	}
+	packet := &mqtt.DisconnectPacket{ReasonCode: code}
+	if disconnect.Reason != "" {
+		packet.Properties = &mqtt.Properties{ReasonString: disconnect.Reason}
+	}
+	_ = g.writePacket(connCtx, packet)
+}
+
+// 连接关闭，非正常断开时发布遗嘱消息
+func (g *mqttGateway) onClose(connCtx *connContext) {
+	sess := connCtx.mqtt
+	if !sess.willFlag || !sess.ready.Load() {
+		return
+	}
+	if sess.disconnected.Load() && !sess.disconnectWill.Load() {
+		return
+	}
+	channelId, channelType, ok := parseMQTTTopic(sess.willTopic)
+	if !ok {
+		g.Warn("will topic is invalid", zap.String("uid", connCtx.uid), zap.String("topic", sess.willTopic))
+		return
+	}
+	publish := func() {
+		// 遗嘱消息以设备用户的身份发送，和普通消息一样需要频道的发送权限（不设置保留消息，WillRetain忽略）
+		messageId := g.s.channelReactor.messageIDGen.Generate().Int64()
+		err := g.s.channelReactor.proposeSend(messageId, connCtx.uid, g.s.opts.SystemDeviceId, SystemConnId, g.s.opts.Cluster.NodeId, false, &wkproto.SendPacket{
+			Framer: wkproto.Framer{
+				RedDot: true,
+			},
+			ClientMsgNo: wkutil.GenUUID(),
+			ChannelID:   channelId,
+			ChannelType: channelType,
+			Payload:     sess.willPayload,
+		}, false)
+		if err != nil {
+			g.Warn("publish will message failed", zap.Error(err), zap.String("uid", connCtx.uid), zap.String("topic", sess.willTopic))
+		}
+	}
+	if sess.willDelay > 0 {
+		g.s.timingWheel.AfterFunc(time.Duration(sess.willDelay)*time.Second, publish)
+		return
+	}
+	publish()
+}
+
+// 编码mqtt报文并写入连接
+func (g *mqttGateway) writePacket(connCtx *connContext, packet mqtt.ControlPacket) error {
+	data, err := mqtt.Encode(packet, connCtx.mqtt.version)
+	if err != nil {
+		g.Warn("encode mqtt packet failed", zap.Error(err), zap.String("packetType", packet.Type().String()))
+		return err
+	}
+	if connCtx.mqtt.maxPacketSize > 0 && len(data) > connCtx.mqtt.maxPacketSize {
+		return errMQTTPacketTooLarge
+	}
+	conn := connCtx.conn
+	if _, err = conn.WriteToOutboundBuffer(data); err != nil {
+		g.Warn("Failed to write the mqtt packet", zap.Error(err), zap.String("uid", connCtx.uid))
+		return err
+	}
+	return conn.WakeWrite()
+}
+
+// 连接还没有上下文时写入（拒绝连接）
+func (g *mqttGateway) writeRaw(conn wknet.Conn, packet mqtt.ControlPacket, version byte) {
+	data, err := mqtt.Encode(packet, version)
+	if err != nil {
+		return
+	}
+	if _, err = conn.WriteToOutboundBuffer(data); err == nil {
+		_ = conn.WakeWrite()
+	}
+}
+
+// 主题格式为 {channelType}/{channelId}
+func parseMQTTTopic(topic string) (string, uint8, bool) {
+	if !mqtt.ValidTopicName(topic) {
+		return "", 0, false
+	}
+	idx := strings.IndexByte(topic, '/')
+	if idx <= 0 {
+		return "", 0, false
+	}
+	channelType, err := strconv.ParseUint(topic[:idx], 10, 8)
+	if err != nil {
+		return "", 0, false
+	}
+	channelId := topic[idx+1:]
+	if strings.TrimSpace(channelId) == "" || IsSpecialChar(channelId) {
+		return "", 0, false
+	}
+	return channelId, uint8(channelType), true
+}
+
+// 个人频道的保留消息按发送方向区分（双方各自发布的保留消息互不覆盖）
+func mqttRetainPersonChannelId(fromUid string, toUid string) string {
+	return fromUid + "@" + toUid
+}
+
+func formatMQTTTopic(channelType uint8, channelId string) string {
+	return strconv.FormatUint(uint64(channelType), 10) + "/" + channelId
+}
+
+func mqttReasonCodeOfConnack(reasonCode wkproto.ReasonCode) mqtt.ReasonCode {
+	switch reasonCode {
+	case wkproto.ReasonSuccess:
+		return mqtt.Success
+	case wkproto.ReasonAuthFail:
+		return mqtt.BadUserNameOrPassword
+	case wkproto.ReasonBan:
+		return mqtt.Banned
+	case wkproto.ReasonConnectKick: // 连接数超过限制
+		return mqtt.QuotaExceeded
+	case wkproto.ReasonSystemError:
+		return mqtt.ServerUnavailable
+	}
+	return mqtt.UnspecifiedError
+}
+
+func mqttReasonCodeOfSendack(reasonCode wkproto.ReasonCode) mqtt.ReasonCode {
+	switch reasonCode {
+	case wkproto.ReasonSuccess:
+		return mqtt.Success
+	case wkproto.ReasonSubscriberNotExist, wkproto.ReasonInBlacklist, wkproto.ReasonNotInWhitelist, wkproto.ReasonNotAllowSend, wkproto.ReasonBan, wkproto.ReasonDisband:
+		return mqtt.NotAuthorized
+	case wkproto.ReasonRateLimit:
+		return mqtt.QuotaExceeded
+	case wkproto.ReasonChannelIDError, wkproto.ReasonNotSupportChannelType, wkproto.ReasonChannelNotExist:
+		return mqtt.TopicNameInvalid
+	case wkproto.ReasonMsgKeyError, wkproto.ReasonPayloadDecodeError:
+		return mqtt.PayloadFormatInvalid
+	}
+	return mqtt.UnspecifiedError
+}
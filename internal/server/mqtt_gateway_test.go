@@ -0,0 +1,165 @@
+package server
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/mqtt"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func mqttTestConnect(t *testing.T, addr string, uid string, version byte) net.Conn {
+	conn, err := net.DialTimeout("tcp", addr, time.Second*5)
+	assert.NoError(t, err)
+
+	mqttTestWrite(t, conn, &mqtt.ConnectPacket{
+		ProtocolVersion: version,
+		CleanStart:      true,
+		KeepAlive:       60,
+		ClientID:        uid + "-device",
+		UsernameFlag:    true,
+		Username:        uid,
+	}, version)
+
+	connack, ok := mqttTestRead(t, conn, version).(*mqtt.ConnackPacket)
+	assert.True(t, ok)
+	assert.Equal(t, byte(mqtt.Success), connack.ReasonCode)
+	return conn
+}
+
+func mqttTestWrite(t *testing.T, conn net.Conn, packet mqtt.ControlPacket, version byte) {
+	data, err := mqtt.Encode(packet, version)
+	assert.NoError(t, err)
+	_, err = conn.Write(data)
+	assert.NoError(t, err)
+}
+
+func mqttTestRead(t *testing.T, conn net.Conn, version byte) mqtt.ControlPacket {
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second * 10))
+	packet, err := mqtt.ReadFrom(conn, version)
+	assert.NoError(t, err)
+	return packet
+}
+
+func TestMQTTPublishAndSubscribe(t *testing.T) {
+	s := NewTestServer(t, WithMQTTAddr("mqtt://127.0.0.1:0"))
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady(time.Second * 10) // 等待服务准备好
+
+	addr := s.engine.MQTTRealListenAddr().String()
+
+	for _, version := range []byte{mqtt.Version311, mqtt.Version5} {
+		sender := mqttTestConnect(t, addr, "mqttsender", version)
+		receiver := mqttTestConnect(t, addr, "mqttreceiver", version)
+
+		// 订阅与发送者的个人频道
+		mqttTestWrite(t, receiver, &mqtt.SubscribePacket{
+			PacketID:      1,
+			Subscriptions: []mqtt.Subscription{{Topic: "1/mqttsender", QoS: 1}},
+		}, version)
+		suback, ok := mqttTestRead(t, receiver, version).(*mqtt.SubackPacket)
+		assert.True(t, ok)
+		assert.Equal(t, []byte{1}, suback.ReasonCodes)
+
+		mqttTestWrite(t, sender, &mqtt.PublishPacket{
+			QoS:      1,
+			Topic:    "1/mqttreceiver",
+			PacketID: 10,
+			Payload:  []byte("hello"),
+		}, version)
+		puback, ok := mqttTestRead(t, sender, version).(*mqtt.AckPacket)
+		assert.True(t, ok)
+		assert.Equal(t, uint16(10), puback.PacketID)
+		assert.Equal(t, byte(mqtt.Success), byte(puback.ReasonCode))
+
+		publish, ok := mqttTestRead(t, receiver, version).(*mqtt.PublishPacket)
+		assert.True(t, ok)
+		assert.Equal(t, "1/mqttsender", publish.Topic)
+		assert.Equal(t, byte(1), publish.QoS)
+		assert.Equal(t, "hello", string(publish.Payload))
+		mqttTestWrite(t, receiver, mqtt.NewPuback(publish.PacketID, mqtt.Success), version)
+
+		mqttTestWrite(t, sender, &mqtt.DisconnectPacket{}, version)
+		mqttTestWrite(t, receiver, &mqtt.DisconnectPacket{}, version)
+		_ = sender.Close()
+		_ = receiver.Close()
+	}
+}
+
+func TestMQTTRetain(t *testing.T) {
+	s := NewTestServer(t, WithMQTTAddr("mqtt://127.0.0.1:0"))
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady(time.Second * 10) // 等待服务准备好
+
+	addr := s.engine.MQTTRealListenAddr().String()
+	version := mqtt.Version311
+	retainChannelId := mqttRetainPersonChannelId("retainsender", "retainreceiver")
+
+	sender := mqttTestConnect(t, addr, "retainsender", version)
+	defer sender.Close()
+	publish := func(packetId uint16, retain bool, payload string) {
+		mqttTestWrite(t, sender, &mqtt.PublishPacket{
+			QoS:      1,
+			Retain:   retain,
+			Topic:    "1/retainreceiver",
+			PacketID: packetId,
+			Payload:  []byte(payload),
+		}, version)
+		puback, ok := mqttTestRead(t, sender, version).(*mqtt.AckPacket)
+		assert.True(t, ok)
+		assert.Equal(t, packetId, puback.PacketID)
+	}
+
+	// 只有retain=1的发布成为保留消息
+	publish(1, false, "a")
+	publish(2, true, "b")
+	publish(3, false, "c")
+	assert.Eventually(t, func() bool {
+		retain, err := s.store.GetMQTTRetain(retainChannelId, wkproto.ChannelTypePerson)
+		return err == nil && string(retain.Payload) == "b"
+	}, time.Second*5, time.Millisecond*10)
+
+	receiver := mqttTestConnect(t, addr, "retainreceiver", version)
+	mqttTestWrite(t, receiver, &mqtt.SubscribePacket{
+		PacketID:      1,
+		Subscriptions: []mqtt.Subscription{{Topic: "1/retainsender", QoS: 0}},
+	}, version)
+	_, ok := mqttTestRead(t, receiver, version).(*mqtt.SubackPacket)
+	assert.True(t, ok)
+	retained, ok := mqttTestRead(t, receiver, version).(*mqtt.PublishPacket)
+	assert.True(t, ok)
+	assert.True(t, retained.Retain)
+	assert.Equal(t, "b", string(retained.Payload))
+	mqttTestWrite(t, receiver, &mqtt.DisconnectPacket{}, version)
+	_ = receiver.Close()
+
+	// payload为空的保留消息清除保留消息
+	publish(4, true, "")
+	assert.Eventually(t, func() bool {
+		_, err := s.store.GetMQTTRetain(retainChannelId, wkproto.ChannelTypePerson)
+		return err == wkdb.ErrNotFound
+	}, time.Second*5, time.Millisecond*10)
+
+	receiver = mqttTestConnect(t, addr, "retainreceiver", version)
+	defer receiver.Close()
+	mqttTestWrite(t, receiver, &mqtt.SubscribePacket{
+		PacketID:      2,
+		Subscriptions: []mqtt.Subscription{{Topic: "1/retainsender", QoS: 0}},
+	}, version)
+	_, ok = mqttTestRead(t, receiver, version).(*mqtt.SubackPacket)
+	assert.True(t, ok)
+	_ = receiver.SetReadDeadline(time.Now().Add(time.Millisecond * 300))
+	_, err = mqtt.ReadFrom(receiver, version)
+	assert.Error(t, err) // 没有保留消息推送
+}
@@ -0,0 +1,194 @@
+package server
+
+import (
+	"sync"
+
+	"github.com/WuKongIM/WuKongIM/pkg/mqtt"
+	"go.uber.org/atomic"
+)
+
+// mqtt连接最多缓存的认证前收到的报文数量
+const mqttMaxPendingPackets = 100
+
+// mqtt推送中（等待PUBACK）的消息
+type mqttInflight struct {
+	messageId  int64  // 消息id，保留消息为0（不需要recvack）
+	messageSeq uint32 // 消息序号
+}
+
+// 等待sendack的发布
+type mqttPublish struct {
+	qos      byte
+	packetId uint16 // QoS1的报文id
+	retain   bool   // 是否设置为主题的保留消息
+	topic    string
+	payload  []byte // 保留消息的内容
+}
+
+// mqtt连接的会话，会话不做持久化，连接断开后即清除
+type mqttSession struct {
+	version          byte   // mqtt协议版本
+	clientId         string // 客户端id（对应设备id）
+	assignedClientId bool   // 客户端id是否由服务端分配
+	keepAlive        uint16 // 心跳间隔（秒）
+	receiveMaximum   int    // 客户端能同时处理的QoS1消息数量（5.0）
+	maxPacketSize    int    // 客户端能接收的最大报文大小，0表示不限制（5.0）
+
+	// 遗嘱消息
+	willFlag    bool
+	willTopic   string
+	willPayload []byte
+	willDelay   uint32 // 遗嘱延迟发布的时间（秒，5.0）
+
+	ready          atomic.Bool // 是否已认证成功
+	disconnected   atomic.Bool // 是否收到了客户端的DISCONNECT（正常断开不发布遗嘱）
+	disconnectWill atomic.Bool // 客户端的DISCONNECT是否要求发布遗嘱（5.0）
+
+	// 串行处理客户端的报文
+	inMu    sync.Mutex
+	pending []mqtt.ControlPacket // 认证完成前收到的报文
+
+	mu            sync.Mutex
+	subscriptions map[string]mqtt.Subscription // 订阅，key为主题过滤器
+	nextPacketId  uint16
+	inflight      map[uint16]mqttInflight // 推送中的QoS1消息，key为报文id
+	inflightMsgs  map[int64]uint16        // 消息id对应的报文id（重试推送时复用报文id）
+	clientSeq     uint64                  // 客户端发送消息的序号
+	publishes     map[uint64]mqttPublish  // 等待sendack的QoS1消息和保留消息，key为clientSeq
+}
+
+func newMQTTSession(connect *mqtt.ConnectPacket) *mqttSession {
+	sess := &mqttSession{
+		version:       connect.ProtocolVersion,
+		clientId:      connect.ClientID,
+		keepAlive:     connect.KeepAlive,
+		willFlag:      connect.WillFlag,
+		willTopic:     connect.WillTopic,
+		willPayload:   connect.WillPayload,
+		subscriptions: make(map[string]mqtt.Subscription),
+		inflight:      make(map[uint16]mqttInflight),
+		inflightMsgs:  make(map[int64]uint16),
+		publishes:     make(map[uint64]mqttPublish),
+	}
+	if connect.Properties != nil {
+		if connect.Properties.ReceiveMaximum != nil {
+			sess.receiveMaximum = int(*connect.Properties.ReceiveMaximum)
+		}
+		if connect.Properties.MaximumPacketSize != nil {
+			sess.maxPacketSize = int(*connect.Properties.MaximumPacketSize)
+		}
+	}
+	if connect.WillProperties != nil && connect.WillProperties.WillDelayInterval != nil {
+		sess.willDelay = *connect.WillProperties.WillDelayInterval
+	}
+	return sess
+}
+
+// 添加订阅，返回是否是新的订阅
+func (m *mqttSession) subscribe(sub mqtt.Subscription) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, exist := m.subscriptions[sub.Topic]
+	m.subscriptions[sub.Topic] = sub
+	return !exist
+}
+
+// 取消订阅，返回订阅是否存在
+func (m *mqttSession) unsubscribe(filter string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, exist := m.subscriptions[filter]
+	delete(m.subscriptions, filter)
+	return exist
+}
+
+// 匹配主题，返回匹配的订阅中最大的QoS
+func (m *mqttSession) match(topic string) (byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var (
+		qos     byte
+		matched bool
+	)
+	for filter, sub := range m.subscriptions {
+		if mqtt.MatchTopic(filter, topic) {
+			matched = true
+			if sub.QoS > qos {
+				qos = sub.QoS
+			}
+		}
+	}
+	return qos, matched
+}
+
+// 为QoS1消息分配报文id，重复推送的消息返回原来的报文id和dup=true
+// 推送中的消息达到上限时返回ok=false
+func (m *mqttSession) addInflight(inflight mqttInflight, maxInflight int) (packetId uint16, dup bool, ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if inflight.messageId != 0 {
+		if packetId, exist := m.inflightMsgs[inflight.messageId]; exist {
+			return packetId, true, true
+		}
+	}
+	if m.receiveMaximum > 0 && (maxInflight <= 0 || m.receiveMaximum < maxInflight) {
+		maxInflight = m.receiveMaximum
+	}
+	if maxInflight > 0 && len(m.inflight) >= maxInflight {
+		return 0, false, false
+	}
+	packetId = m.allocPacketId()
+	m.inflight[packetId] = inflight
+	if inflight.messageId != 0 {
+		m.inflightMsgs[inflight.messageId] = packetId
+	}
+	return packetId, false, true
+}
+
+// 移除推送中的消息（收到PUBACK）
+func (m *mqttSession) removeInflight(packetId uint16) (mqttInflight, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	inflight, ok := m.inflight[packetId]
+	if !ok {
+		return inflight, false
+	}
+	delete(m.inflight, packetId)
+	if inflight.messageId != 0 {
+		delete(m.inflightMsgs, inflight.messageId)
+	}
+	return inflight, true
+}
+
+func (m *mqttSession) allocPacketId() uint16 {
+	for {
+		m.nextPacketId++
+		if m.nextPacketId == 0 {
+			continue
+		}
+		if _, ok := m.inflight[m.nextPacketId]; !ok {
+			return m.nextPacketId
+		}
+	}
+}
+
+// 生成客户端发送消息的序号，QoS1的消息和保留消息记录下来，等待sendack后回复PUBACK和设置保留消息
+func (m *mqttSession) addPublish(publish mqttPublish) uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clientSeq++
+	if publish.qos > 0 || publish.retain {
+		m.publishes[m.clientSeq] = publish
+	}
+	return m.clientSeq
+}
+
+func (m *mqttSession) removePublish(clientSeq uint64) (mqttPublish, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	publish, ok := m.publishes[clientSeq]
+	if ok {
+		delete(m.publishes, clientSeq)
+	}
+	return publish, ok
+}
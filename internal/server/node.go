@@ -207,6 +207,15 @@ func (n *node) requestDeliver(msgs []ReactorChannelMessage) error {
 		}
 		if !exist {
 			ch := n.s.channelReactor.loadOrCreateChannel(fakeChannelId, msg.SendPacket.ChannelType)
+			if ch.info.Large && msg.SendPacket.ChannelType != wkproto.ChannelTypePerson { // 超大频道广播投递，不需要tag
+				channelMessages = append(channelMessages, &ChannelMessages{
+					ChannelId:   fakeChannelId,
+					ChannelType: msg.SendPacket.ChannelType,
+					TagKey:      broadcastTagKey,
+					Messages:    ReactorChannelMessageSet{msg},
+				})
+				continue
+			}
 			var tg *tag
 			if ch.receiverTagKey.Load() != "" {
 				tg = n.s.tagManager.getReceiverTag(ch.receiverTagKey.Load())
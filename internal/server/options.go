@@ -13,6 +13,7 @@ import (
 
 	"github.com/WuKongIM/WuKongIM/pkg/auth"
 	"github.com/WuKongIM/WuKongIM/pkg/auth/resource"
+	"github.com/WuKongIM/WuKongIM/pkg/eventsink"
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
 	"github.com/WuKongIM/crypto/tls"
 	"github.com/google/uuid"
@@ -100,23 +101,87 @@ type Options struct {
 		TCPAddr string // 内网连接的tcp长连接地址
 	}
 	Channel struct { // 频道配置
-		CacheCount                int    // 频道缓存数量
-		CreateIfNoExist           bool   // 如果频道不存在是否创建
-		SubscriberCompressOfCount int    // 订订阅者数组多大开始压缩（离线推送的时候订阅者数组太大 可以设置此参数进行压缩 默认为0 表示不压缩 ）
-		CmdSuffix                 string // cmd频道后缀
+		CacheCount                int           // 频道缓存数量
+		CreateIfNoExist           bool          // 如果频道不存在是否创建
+		SubscriberCompressOfCount int           // 订订阅者数组多大开始压缩（离线推送的时候订阅者数组太大 可以设置此参数进行压缩 默认为0 表示不压缩 ）
+		CmdSuffix                 string        // cmd频道后缀
+		ThreadFlag                string        // 子区频道标识，子区频道ID格式为: 父频道ID + ThreadFlag + 根消息ID
+		DisbandGracePeriod        time.Duration // 解散频道后清除频道消息和成员的默认宽限期
+		DisbandCheckInterval      time.Duration // 检查解散的频道是否需要清除的间隔
 	}
 	TmpChannel struct { // 临时频道配置
-		Suffix     string // 临时频道的后缀
-		CacheCount int    // 临时频道缓存数量
+		Suffix        string        // 临时频道的后缀
+		CacheCount    int           // 临时频道缓存数量
+		Ttl           time.Duration // 显式创建的临时频道默认存活时间（从创建开始计算），0表示不限制
+		IdleTtl       time.Duration // 显式创建的临时频道默认闲置存活时间（没有新消息开始计算），0表示不限制
+		CheckInterval time.Duration // 检查临时频道是否过期的间隔
+	}
+	Presence struct { // 在线状态订阅配置
+		On                      bool          // 是否开启在线状态订阅
+		MaxSubscriptionsPerConn int           // 每个连接最多订阅的用户数量，0表示不限制，超过上限时suback返回ReasonPresenceSubLimit
+		FlushInterval           time.Duration // 在线状态事件的推送间隔（事件在间隔内合并后推送和广播）
+		HookHTTPAddr            string        // 订阅校验地址（例如校验好友关系），为空不校验。POST {"uid":"订阅者","uids":["要订阅的用户"]}，返回 {"uids":["允许订阅的用户"]}
+		HookTimeout             time.Duration // 订阅校验的超时时间
+	}
+	Transient struct { // 瞬态事件（比如正在输入）配置
+		Interval time.Duration // 同一发送者在同一频道内发送瞬态事件的最小间隔，间隔内的事件将被丢弃，0表示不限制
+	}
+	UserBan struct { // 用户封禁配置
+		CheckInterval time.Duration // 检查封禁是否到期的间隔
+		CacheTTL      time.Duration // 封禁缓存的有效期（非用户槽领导节点判断用户是否被封禁时使用）
+	}
+	RateLimit struct { // 用户发送消息限速（令牌桶，按用户在连接所在节点上限速，连接分布在多个节点时每个节点各自限速，系统账号不限速）
+		MsgRate   float64                      // 每个用户在每个节点上每秒允许发送的消息数量（不是集群整体的限制），0表示不限制
+		MsgBurst  int                          // 消息数量的突发上限，0表示与MsgRate相同
+		ByteRate  int                          // 每个用户在每个节点上每秒允许发送的消息字节数（payload大小，不是集群整体的限制），0表示不限制
+		ByteBurst int                          // 消息字节数的突发上限，0表示与ByteRate相同
+		Overrides map[string]RateLimitOverride // 指定用户的限速，key为uid
+	}
+	SessionLog struct { // 用户会话审计日志（连接、认证失败、被踢、断开），存储在用户所在的槽上
+		On            bool          // 是否开启
+		Retention     time.Duration // 日志保留时间（按天分区清理）
+		FlushInterval time.Duration // 日志批量写入的间隔
+		MaxBuffer     int           // 待写入日志的最大数量（包含写入失败等待重试的日志），超过后丢弃
+		WebhookOn     bool          // 是否推送 user.session webhook事件
+	}
+	MQTT struct { // MQTT网关，设备以普通客户端的身份接入，主题 {channelType}/{channelId} 映射到频道
+		Addr        string             // mqtt 监听地址 例如：mqtt://0.0.0.0:1883，为空则不开启
+		DeviceFlag  wkproto.DeviceFlag // mqtt设备使用的设备标记
+		RetainOn    bool               // 是否支持保留消息（retain=1的发布成为主题的保留消息，payload为空则清除，订阅时推送）
+		MaxInflight int                // 每个连接未确认的QoS1消息的最大数量，超过后以QoS0推送
+	}
+	WSJSONOn         bool     // 是否支持websocket JSON文本协议（客户端握手时通过Sec-WebSocket-Protocol: wukongim.json协商，只在wss上支持）
+	WSJSONInsecureOn bool     // 是否允许在明文ws上使用JSON文本协议（token和消息明文传输，仅用于内网或测试）
+	WSCompression    struct { // websocket permessage-deflate 压缩（客户端请求时才协商）
+		On                      bool // 是否开启
+		Level                   int  // 压缩级别 -2~9，1为最快，9为最小
+		Threshold               int  // 小于此大小（字节）的消息不压缩
+		ServerNoContextTakeover bool // 服务端压缩不保留上下文（压缩器在连接间复用）。保留上下文压缩率更高，但每个连接独占一个flate.Writer直到连接关闭（级别1约470K，级别越高越大，最高约1M），连接多时内存占用很大（1万个连接约5~10G）
+		ClientNoContextTakeover bool // 要求客户端压缩不保留上下文，保留上下文时每个连接需要缓存32K的解压数据
+	}
+	ConnLimit struct { // 用户连接数限制（在用户的槽领导节点上认证时检查，包含代理连接，即整个集群的连接数）
+		MaxPerUser       int                        // 每个用户最多的连接数，0表示不限制
+		MaxPerDeviceFlag map[wkproto.DeviceFlag]int // 每个用户每种设备标记最多的连接数，0表示不限制
+		Policy           ConnLimitPolicy            // 超过限制时的策略
 	}
 	Webhook struct { // 两者配其一即可
 		HTTPAddr                    string        // webhook的http地址 通过此地址通知数据给第三方 格式为 http://xxxxx
 		GRPCAddr                    string        //  webhook的grpc地址 如果此地址有值 则不会再调用HttpAddr配置的地址,格式为 ip:port
-		MsgNotifyEventPushInterval  time.Duration // 消息通知事件推送间隔，默认500毫秒发起一次推送
-		MsgNotifyEventCountPerPush  int           // 每次webhook消息通知事件推送消息数量限制 默认一次请求最多推送100条
-		MsgNotifyEventRetryMaxCount int           // 消息通知事件消息推送失败最大重试次数 默认为5次，超过将丢弃
+		MsgNotifyEventPushInterval  time.Duration // 发件箱扫描投递的间隔（新写入的事件会立即投递），默认500毫秒
+		MsgNotifyEventCountPerPush  int           // 每个消息通知事件最多包含的消息数量 默认一次请求最多推送100条
+		MsgNotifyEventRetryMaxCount int           // 消息通知事件推送失败最大重试次数 默认为5次，超过将移入死信
 		FocusEvents                 []string      // 关注的通知事件,如果为空表示关注所有事件
+		ChannelExclusive            bool          // 频道配置了自己的webhook后，频道消息是否不再推送到全局webhook（默认两者都推送）
+		Secret                      string        // webhook签名密钥，配置后请求会携带签名头（X-WK-Signature、X-WK-Timestamp），为空不签名
+		SecondarySecret             string        // 密钥轮换期间的另一个有效密钥，配置后请求会同时携带两个密钥的签名
+		RetryInitialInterval        time.Duration // 事件投递失败后的首次重试间隔，之后每次翻倍（指数退避）
+		RetryMaxInterval            time.Duration // 事件投递失败后的最大重试间隔
+		RetryMaxCount               int           // 发件箱事件投递失败的最大重试次数，超过将移入死信
+		DeadLetterAlertThreshold    int           // 本节点死信数量超过此值时告警（错误日志），0表示不告警
 	}
+	// 事件sink，webhook事件额外写入本地文件或消息中间件（每个节点写入自己产生的事件）
+	EventSinks []eventsink.Config
+
 	Datasource struct { // 数据源配置，不填写则使用自身数据存储逻辑，如果填写则使用第三方数据源，数据格式请查看文档
 		Addr          string // 数据源地址
 		ChannelInfoOn bool   // 是否开启频道信息获取
@@ -249,6 +314,26 @@ type Options struct {
 		Expire time.Duration // jwt expire
 		Issuer string        // jwt 发行者名字
 	}
+	ClientJwt struct { // 客户端连接token使用签名的jwt（需要开启TokenAuthOn），本地验签，不再需要通过 /user/token 设置设备token（为了支持吊销，每次连接仍会在本地读取一次设备的吊销时间）
+		On        bool          // 是否开启
+		Algorithm string        // 签名算法 HS256 HS384 HS512 RS256 RS384 RS512 PS256 PS384 PS512 ES256 ES384 ES512 EdDSA
+		Keys      []string      // 验签密钥 格式 kid:key，HMAC算法key为密钥，其他算法key为公钥pem文件路径。轮换密钥时新旧密钥同时配置，按token头的kid选择密钥，token没有kid则逐个尝试
+		Issuer    string        // 不为空则校验token的iss
+		Leeway    time.Duration // 校验过期时间允许的时钟偏差
+	}
+	AuthHook struct { // 客户端连接的认证委托给第三方认证服务（需要开启TokenAuthOn），两者配其一即可
+		HTTPAddr string        // 认证服务的http地址 格式为 http://xxxxx ，以POST json的方式请求
+		GRPCAddr string        // 认证服务的grpc地址 如果此地址有值 则不会再调用HTTPAddr配置的地址,格式为 ip:port
+		Timeout  time.Duration // 请求认证服务的超时时间
+		CacheTTL time.Duration // 认证成功的结果缓存时间，0表示不缓存
+	}
+	SendHook struct { // 消息发送前的拦截钩子（例如内容审核），频道领导节点在存储消息前调用，两者配其一即可
+		HTTPAddr     string        // 拦截服务的http地址 格式为 http://xxxxx ，以POST json的方式请求
+		GRPCAddr     string        // 拦截服务的grpc地址 如果此地址有值 则不会再调用HTTPAddr配置的地址,格式为 ip:port
+		Timeout      time.Duration // 请求拦截服务的超时时间
+		FailOpen     bool          // 请求拦截服务失败或超时时是否放行消息 true: 放行 false: 拒绝（sendack返回ReasonSystemError）
+		ChannelTypes []uint8       // 需要拦截的频道类型，为空表示所有频道类型
+	}
 	PprofOn          bool        // 是否开启pprof
 	OldV1Api         string      //旧v1版本的api地址，如果不为空则开启数据迁移任务，将v1的数据迁移到v2
 	MigrateStartStep MigrateStep // 从那步开始迁移，默认顺序是 message,user,channel
@@ -262,6 +347,20 @@ const (
 	MigrateStepChannel MigrateStep = "channel"
 )
 
+// ConnLimitPolicy 超过连接数限制时的策略
+type ConnLimitPolicy string
+
+const (
+	ConnLimitPolicyRejectNewest ConnLimitPolicy = "rejectNewest" // 拒绝新连接
+	ConnLimitPolicyEvictOldest  ConnLimitPolicy = "evictOldest"  // 踢掉最早的连接
+)
+
+// RateLimitOverride 指定用户的限速，突发上限与速率相同
+type RateLimitOverride struct {
+	MsgRate  float64 // 每秒允许发送的消息数量，0表示不限制
+	ByteRate int     // 每秒允许发送的消息字节数，0表示不限制
+}
+
 func NewOptions(op ...Option) *Options {
 
 	// http.ServeTLS(l net.Listener, handler Handler, certFile string, keyFile string)
@@ -315,22 +414,107 @@ func NewOptions(op ...Option) *Options {
 		ConnIdleTime:        time.Minute * 3,
 		UserMsgQueueMaxSize: 0,
 		TmpChannel: struct {
-			Suffix     string
-			CacheCount int
+			Suffix        string
+			CacheCount    int
+			Ttl           time.Duration
+			IdleTtl       time.Duration
+			CheckInterval time.Duration
+		}{
+			Suffix:        "@tmp",
+			CacheCount:    500,
+			Ttl:           time.Hour * 24,
+			IdleTtl:       time.Hour,
+			CheckInterval: time.Minute,
+		},
+		Presence: struct {
+			On                      bool
+			MaxSubscriptionsPerConn int
+			FlushInterval           time.Duration
+			HookHTTPAddr            string
+			HookTimeout             time.Duration
+		}{
+			On:                      true,
+			MaxSubscriptionsPerConn: 1000,
+			FlushInterval:           time.Millisecond * 200,
+			HookTimeout:             time.Second * 2,
+		},
+		Transient: struct {
+			Interval time.Duration
+		}{
+			Interval: time.Millisecond * 500,
+		},
+		UserBan: struct {
+			CheckInterval time.Duration
+			CacheTTL      time.Duration
+		}{
+			CheckInterval: time.Minute,
+			CacheTTL:      time.Minute,
+		},
+		RateLimit: struct {
+			MsgRate   float64
+			MsgBurst  int
+			ByteRate  int
+			ByteBurst int
+			Overrides map[string]RateLimitOverride
 		}{
-			Suffix:     "@tmp",
-			CacheCount: 500,
+			Overrides: map[string]RateLimitOverride{},
+		},
+		SessionLog: struct {
+			On            bool
+			Retention     time.Duration
+			FlushInterval time.Duration
+			MaxBuffer     int
+			WebhookOn     bool
+		}{
+			Retention:     time.Hour * 24 * 30,
+			FlushInterval: time.Second,
+			MaxBuffer:     100000,
+		},
+		MQTT: struct {
+			Addr        string
+			DeviceFlag  wkproto.DeviceFlag
+			RetainOn    bool
+			MaxInflight int
+		}{
+			DeviceFlag:  wkproto.APP,
+			RetainOn:    true,
+			MaxInflight: 1000,
+		},
+		WSCompression: struct {
+			On                      bool
+			Level                   int
+			Threshold               int
+			ServerNoContextTakeover bool
+			ClientNoContextTakeover bool
+		}{
+			Level:                   1,
+			Threshold:               512,
+			ServerNoContextTakeover: true,
+		},
+		ConnLimit: struct {
+			MaxPerUser       int
+			MaxPerDeviceFlag map[wkproto.DeviceFlag]int
+			Policy           ConnLimitPolicy
+		}{
+			MaxPerDeviceFlag: map[wkproto.DeviceFlag]int{},
+			Policy:           ConnLimitPolicyRejectNewest,
 		},
 		Channel: struct {
 			CacheCount                int
 			CreateIfNoExist           bool
 			SubscriberCompressOfCount int
 			CmdSuffix                 string
+			ThreadFlag                string
+			DisbandGracePeriod        time.Duration
+			DisbandCheckInterval      time.Duration
 		}{
 			CacheCount:                1000,
 			CreateIfNoExist:           true,
 			SubscriberCompressOfCount: 0,
 			CmdSuffix:                 "____cmd",
+			ThreadFlag:                "____thread_",
+			DisbandGracePeriod:        time.Hour * 24,
+			DisbandCheckInterval:      time.Minute,
 		},
 		Datasource: struct {
 			Addr          string
@@ -381,10 +565,21 @@ func NewOptions(op ...Option) *Options {
 			MsgNotifyEventCountPerPush  int
 			MsgNotifyEventRetryMaxCount int
 			FocusEvents                 []string
+			ChannelExclusive            bool
+			Secret                      string
+			SecondarySecret             string
+			RetryInitialInterval        time.Duration
+			RetryMaxInterval            time.Duration
+			RetryMaxCount               int
+			DeadLetterAlertThreshold    int
 		}{
 			MsgNotifyEventPushInterval:  time.Millisecond * 500,
 			MsgNotifyEventCountPerPush:  100,
 			MsgNotifyEventRetryMaxCount: 5,
+			RetryInitialInterval:        time.Second,
+			RetryMaxInterval:            time.Minute * 5,
+			RetryMaxCount:               20,
+			DeadLetterAlertThreshold:    1000,
 		},
 		Manager: struct {
 			On   bool
@@ -543,6 +738,34 @@ func NewOptions(op ...Option) *Options {
 			Secret: "secret_wukongim",
 			Issuer: "wukongim",
 		},
+		ClientJwt: struct {
+			On        bool
+			Algorithm string
+			Keys      []string
+			Issuer    string
+			Leeway    time.Duration
+		}{
+			Algorithm: "HS256",
+			Leeway:    time.Second * 30,
+		},
+		AuthHook: struct {
+			HTTPAddr string
+			GRPCAddr string
+			Timeout  time.Duration
+			CacheTTL time.Duration
+		}{
+			Timeout:  time.Second * 5,
+			CacheTTL: time.Minute * 5,
+		},
+		SendHook: struct {
+			HTTPAddr     string
+			GRPCAddr     string
+			Timeout      time.Duration
+			FailOpen     bool
+			ChannelTypes []uint8
+		}{
+			Timeout: time.Second * 2,
+		},
 		MigrateStartStep: MigrateStepMessage,
 	}
 
@@ -616,6 +839,8 @@ func (o *Options) ConfigureWithViper(vp *viper.Viper) {
 	o.Channel.CacheCount = o.getInt("channel.cacheCount", o.Channel.CacheCount)
 	o.Channel.CreateIfNoExist = o.getBool("channel.createIfNoExist", o.Channel.CreateIfNoExist)
 	o.Channel.SubscriberCompressOfCount = o.getInt("channel.subscriberCompressOfCount", o.Channel.SubscriberCompressOfCount)
+	o.Channel.DisbandGracePeriod = o.getDuration("channel.disbandGracePeriod", o.Channel.DisbandGracePeriod)
+	o.Channel.DisbandCheckInterval = o.getDuration("channel.disbandCheckInterval", o.Channel.DisbandCheckInterval)
 
 	o.ConnIdleTime = o.getDuration("connIdleTime", o.ConnIdleTime)
 
@@ -638,6 +863,13 @@ func (o *Options) ConfigureWithViper(vp *viper.Viper) {
 	o.Webhook.MsgNotifyEventCountPerPush = o.getInt("webhook.msgNotifyEventCountPerPush", o.Webhook.MsgNotifyEventCountPerPush)
 	o.Webhook.MsgNotifyEventPushInterval = o.getDuration("webhook.msgNotifyEventPushInterval", o.Webhook.MsgNotifyEventPushInterval)
 	o.Webhook.FocusEvents = o.getStringSlice("webhook.focusEvents")
+	o.Webhook.ChannelExclusive = o.getBool("webhook.channelExclusive", o.Webhook.ChannelExclusive)
+	o.Webhook.Secret = o.getString("webhook.secret", o.Webhook.Secret)
+	o.Webhook.SecondarySecret = o.getString("webhook.secondarySecret", o.Webhook.SecondarySecret)
+	o.Webhook.RetryInitialInterval = o.getDuration("webhook.retryInitialInterval", o.Webhook.RetryInitialInterval)
+	o.Webhook.RetryMaxInterval = o.getDuration("webhook.retryMaxInterval", o.Webhook.RetryMaxInterval)
+	o.Webhook.RetryMaxCount = o.getInt("webhook.retryMaxCount", o.Webhook.RetryMaxCount)
+	o.Webhook.DeadLetterAlertThreshold = o.getInt("webhook.deadLetterAlertThreshold", o.Webhook.DeadLetterAlertThreshold)
 
 	o.EventPoolSize = o.getInt("eventPoolSize", o.EventPoolSize)
 	o.DeliveryMsgPoolSize = o.getInt("deliveryMsgPoolSize", o.DeliveryMsgPoolSize)
@@ -645,6 +877,48 @@ func (o *Options) ConfigureWithViper(vp *viper.Viper) {
 
 	o.TmpChannel.CacheCount = o.getInt("tmpChannel.cacheCount", o.TmpChannel.CacheCount)
 	o.TmpChannel.Suffix = o.getString("tmpChannel.suffix", o.TmpChannel.Suffix)
+	o.TmpChannel.Ttl = o.getDuration("tmpChannel.ttl", o.TmpChannel.Ttl)
+	o.TmpChannel.IdleTtl = o.getDuration("tmpChannel.idleTtl", o.TmpChannel.IdleTtl)
+	o.TmpChannel.CheckInterval = o.getDuration("tmpChannel.checkInterval", o.TmpChannel.CheckInterval)
+
+	o.Presence.On = o.getBool("presence.on", o.Presence.On)
+	o.Presence.MaxSubscriptionsPerConn = o.getInt("presence.maxSubscriptionsPerConn", o.Presence.MaxSubscriptionsPerConn)
+	o.Presence.FlushInterval = o.getDuration("presence.flushInterval", o.Presence.FlushInterval)
+	o.Presence.HookHTTPAddr = o.getString("presence.hookHttpAddr", o.Presence.HookHTTPAddr)
+	o.Presence.HookTimeout = o.getDuration("presence.hookTimeout", o.Presence.HookTimeout)
+
+	o.Transient.Interval = o.getDuration("transient.interval", o.Transient.Interval)
+
+	o.UserBan.CheckInterval = o.getDuration("userBan.checkInterval", o.UserBan.CheckInterval)
+	o.UserBan.CacheTTL = o.getDuration("userBan.cacheTTL", o.UserBan.CacheTTL)
+
+	o.configureRateLimit()
+
+	// =================== conn limit ===================
+	o.configureConnLimit()
+
+	// =================== session log ===================
+	o.SessionLog.On = o.getBool("sessionLog.on", o.SessionLog.On)
+	o.SessionLog.Retention = o.getDuration("sessionLog.retention", o.SessionLog.Retention)
+	o.SessionLog.FlushInterval = o.getDuration("sessionLog.flushInterval", o.SessionLog.FlushInterval)
+	o.SessionLog.MaxBuffer = o.getInt("sessionLog.maxBuffer", o.SessionLog.MaxBuffer)
+	o.SessionLog.WebhookOn = o.getBool("sessionLog.webhookOn", o.SessionLog.WebhookOn)
+
+	// =================== mqtt ===================
+	o.MQTT.Addr = o.getString("mqtt.addr", o.MQTT.Addr)
+	o.MQTT.DeviceFlag = wkproto.DeviceFlag(o.getInt("mqtt.deviceFlag", int(o.MQTT.DeviceFlag)))
+	o.MQTT.RetainOn = o.getBool("mqtt.retainOn", o.MQTT.RetainOn)
+	o.MQTT.MaxInflight = o.getInt("mqtt.maxInflight", o.MQTT.MaxInflight)
+
+	o.WSJSONOn = o.getBool("wsJSONOn", o.WSJSONOn)
+	o.WSJSONInsecureOn = o.getBool("wsJSONInsecureOn", o.WSJSONInsecureOn)
+
+	// =================== ws compression ===================
+	o.WSCompression.On = o.getBool("wsCompression.on", o.WSCompression.On)
+	o.WSCompression.Level = o.getInt("wsCompression.level", o.WSCompression.Level)
+	o.WSCompression.Threshold = o.getInt("wsCompression.threshold", o.WSCompression.Threshold)
+	o.WSCompression.ServerNoContextTakeover = o.getBool("wsCompression.serverNoContextTakeover", o.WSCompression.ServerNoContextTakeover)
+	o.WSCompression.ClientNoContextTakeover = o.getBool("wsCompression.clientNoContextTakeover", o.WSCompression.ClientNoContextTakeover)
 
 	o.Datasource.Addr = o.getString("datasource.addr", o.Datasource.Addr)
 	o.Datasource.ChannelInfoOn = o.getBool("datasource.channelInfoOn", o.Datasource.ChannelInfoOn)
@@ -861,6 +1135,53 @@ func (o *Options) configureAuth() {
 		}
 	}
 
+	// =================== client jwt ===================
+	o.ClientJwt.On = o.getBool("clientJwt.on", o.ClientJwt.On)
+	o.ClientJwt.Algorithm = o.getString("clientJwt.algorithm", o.ClientJwt.Algorithm)
+	clientJwtKeys := o.getStringSlice("clientJwt.keys")
+	if len(clientJwtKeys) > 0 {
+		o.ClientJwt.Keys = clientJwtKeys
+	}
+	o.ClientJwt.Issuer = o.getString("clientJwt.issuer", o.ClientJwt.Issuer)
+	o.ClientJwt.Leeway = o.getDuration("clientJwt.leeway", o.ClientJwt.Leeway)
+
+	// =================== auth hook ===================
+	o.AuthHook.HTTPAddr = o.getString("authHook.httpAddr", o.AuthHook.HTTPAddr)
+	o.AuthHook.GRPCAddr = o.getString("authHook.grpcAddr", o.AuthHook.GRPCAddr)
+	o.AuthHook.Timeout = o.getDuration("authHook.timeout", o.AuthHook.Timeout)
+	o.AuthHook.CacheTTL = o.getDuration("authHook.cacheTTL", o.AuthHook.CacheTTL)
+
+	// =================== send hook ===================
+	o.SendHook.HTTPAddr = o.getString("sendHook.httpAddr", o.SendHook.HTTPAddr)
+	o.SendHook.GRPCAddr = o.getString("sendHook.grpcAddr", o.SendHook.GRPCAddr)
+	o.SendHook.Timeout = o.getDuration("sendHook.timeout", o.SendHook.Timeout)
+	o.SendHook.FailOpen = o.getBool("sendHook.failOpen", o.SendHook.FailOpen)
+	sendHookChannelTypes := o.getStringSlice("sendHook.channelTypes")
+	if len(sendHookChannelTypes) > 0 {
+		o.SendHook.ChannelTypes = make([]uint8, 0, len(sendHookChannelTypes))
+		for _, channelTypeStr := range sendHookChannelTypes {
+			channelType, err := strconv.ParseUint(strings.TrimSpace(channelTypeStr), 10, 8)
+			if err != nil {
+				wklog.Panic("sendHook.channelTypes format error", zap.String("channelType", channelTypeStr), zap.Error(err))
+			}
+			o.SendHook.ChannelTypes = append(o.SendHook.ChannelTypes, uint8(channelType))
+		}
+	}
+
+	// =================== event sinks ===================
+	sinkNames := make(map[string]struct{})
+	for _, sinkURL := range o.getStringSlice("eventSinks") {
+		sinkCfg, err := eventsink.ParseURL(strings.TrimSpace(sinkURL))
+		if err != nil {
+			wklog.Panic("eventSinks format error", zap.String("sink", sinkURL), zap.Error(err))
+		}
+		if _, ok := sinkNames[sinkCfg.Name]; ok {
+			wklog.Panic("eventSinks name duplicate", zap.String("name", sinkCfg.Name))
+		}
+		sinkNames[sinkCfg.Name] = struct{}{}
+		o.EventSinks = append(o.EventSinks, sinkCfg)
+	}
+
 	// =================== auth ===================
 	o.Auth.On = o.getBool("auth.on", o.Auth.On)
 	o.Auth.SuperToken = o.getString("auth.superToken", o.Auth.SuperToken)
@@ -974,6 +1295,52 @@ func (o *Options) configureAuth() {
 	o.Auth.Users = usersCfgs
 }
 
+func (o *Options) configureRateLimit() {
+	o.RateLimit.MsgRate = o.getFloat64("rateLimit.msgRate", o.RateLimit.MsgRate)
+	o.RateLimit.MsgBurst = o.getInt("rateLimit.msgBurst", o.RateLimit.MsgBurst)
+	o.RateLimit.ByteRate = o.getInt("rateLimit.byteRate", o.RateLimit.ByteRate)
+	o.RateLimit.ByteBurst = o.getInt("rateLimit.byteBurst", o.RateLimit.ByteBurst)
+
+	// 格式 uid:msgRate:byteRate  例如 "u1:100:102400"
+	overrides := o.getStringSlice("rateLimit.overrides")
+	for _, overrideStr := range overrides {
+		parts := strings.Split(overrideStr, ":")
+		if len(parts) != 3 || strings.TrimSpace(parts[0]) == "" {
+			wklog.Panic("rateLimit.overrides format error", zap.String("override", overrideStr))
+		}
+		msgRate, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			wklog.Panic("rateLimit.overrides msgRate format error", zap.String("override", overrideStr), zap.Error(err))
+		}
+		byteRate, err := strconv.Atoi(parts[2])
+		if err != nil {
+			wklog.Panic("rateLimit.overrides byteRate format error", zap.String("override", overrideStr), zap.Error(err))
+		}
+		o.RateLimit.Overrides[strings.TrimSpace(parts[0])] = RateLimitOverride{
+			MsgRate:  msgRate,
+			ByteRate: byteRate,
+		}
+	}
+}
+
+func (o *Options) configureConnLimit() {
+	o.ConnLimit.MaxPerUser = o.getInt("connLimit.maxPerUser", o.ConnLimit.MaxPerUser)
+	for deviceFlag, key := range map[wkproto.DeviceFlag]string{
+		wkproto.APP: "connLimit.maxPerApp",
+		wkproto.WEB: "connLimit.maxPerWeb",
+		wkproto.PC:  "connLimit.maxPerPC",
+	} {
+		max := o.getInt(key, o.ConnLimit.MaxPerDeviceFlag[deviceFlag])
+		if max > 0 {
+			o.ConnLimit.MaxPerDeviceFlag[deviceFlag] = max
+		}
+	}
+	o.ConnLimit.Policy = ConnLimitPolicy(o.getString("connLimit.policy", string(o.ConnLimit.Policy)))
+	if o.ConnLimit.Policy != ConnLimitPolicyRejectNewest && o.ConnLimit.Policy != ConnLimitPolicyEvictOldest {
+		wklog.Panic("connLimit.policy must be rejectNewest or evictOldest", zap.String("policy", string(o.ConnLimit.Policy)))
+	}
+}
+
 func (o *Options) ConfigureDataDir() {
 
 	// 数据目录
@@ -1126,6 +1493,54 @@ func (o *Options) WebhookOn() bool {
 	return strings.TrimSpace(o.Webhook.HTTPAddr) != "" || o.WebhookGRPCOn()
 }
 
+// WebhookSecrets webhook签名使用的密钥（未配置返回空）
+func (o *Options) WebhookSecrets() []string {
+	secrets := make([]string, 0, 2)
+	if strings.TrimSpace(o.Webhook.Secret) != "" {
+		secrets = append(secrets, o.Webhook.Secret)
+	}
+	if strings.TrimSpace(o.Webhook.SecondarySecret) != "" {
+		secrets = append(secrets, o.Webhook.SecondarySecret)
+	}
+	return secrets
+}
+
+// AuthHookOn 是否配置了第三方认证服务
+func (o *Options) AuthHookOn() bool {
+	return strings.TrimSpace(o.AuthHook.HTTPAddr) != "" || o.AuthHookGRPCOn()
+}
+
+// AuthHookGRPCOn 是否配置了第三方认证服务的grpc地址
+func (o *Options) AuthHookGRPCOn() bool {
+	return strings.TrimSpace(o.AuthHook.GRPCAddr) != ""
+}
+
+// SendHookOn 是否配置了消息发送前的拦截服务
+func (o *Options) SendHookOn() bool {
+	return strings.TrimSpace(o.SendHook.HTTPAddr) != "" || o.SendHookGRPCOn()
+}
+
+// SendHookGRPCOn 是否配置了拦截服务的grpc地址
+func (o *Options) SendHookGRPCOn() bool {
+	return strings.TrimSpace(o.SendHook.GRPCAddr) != ""
+}
+
+// SendHookChannelTypeOn 频道类型是否需要拦截
+func (o *Options) SendHookChannelTypeOn(channelType uint8) bool {
+	if !o.SendHookOn() {
+		return false
+	}
+	if len(o.SendHook.ChannelTypes) == 0 {
+		return true
+	}
+	for _, ct := range o.SendHook.ChannelTypes {
+		if ct == channelType {
+			return true
+		}
+	}
+	return false
+}
+
 // WebhookGRPCOn 是否配置了webhook grpc地址
 func (o *Options) WebhookGRPCOn() bool {
 	return strings.TrimSpace(o.Webhook.GRPCAddr) != ""
@@ -1172,6 +1587,31 @@ func (o *Options) CmdChannelConvertOrginalChannel(fakeChannelId string) string {
 
 }
 
+// IsThreadChannel 是否是子区频道（频道ID以 ThreadFlag + 根消息ID 结尾）
+func (o *Options) IsThreadChannel(channelId string) bool {
+	_, rootMessageId := o.ThreadChannelConvertParent(channelId)
+	return rootMessageId > 0
+}
+
+// ThreadChannelOf 根据父频道和根消息ID获取子区频道ID
+func (o *Options) ThreadChannelOf(parentChannelId string, rootMessageId int64) string {
+	return fmt.Sprintf("%s%s%d", parentChannelId, o.Channel.ThreadFlag, rootMessageId)
+}
+
+// ThreadChannelConvertParent 将子区频道转换为父频道和根消息ID
+func (o *Options) ThreadChannelConvertParent(threadChannelId string) (string, int64) {
+	threadChannelId = o.CmdChannelConvertOrginalChannel(threadChannelId)
+	idx := strings.LastIndex(threadChannelId, o.Channel.ThreadFlag)
+	if idx <= 0 {
+		return threadChannelId, 0
+	}
+	rootMessageId, err := strconv.ParseInt(threadChannelId[idx+len(o.Channel.ThreadFlag):], 10, 64)
+	if err != nil || rootMessageId <= 0 {
+		return threadChannelId, 0
+	}
+	return threadChannelId[:idx], rootMessageId
+}
+
 // 获取内网地址
 func getIntranetIP() string {
 	intranetIPs, err := wkutil.GetIntranetIP()
@@ -1378,6 +1818,24 @@ func WithChannelCmdSuffix(cmdSuffix string) Option {
 	}
 }
 
+func WithChannelThreadFlag(threadFlag string) Option {
+	return func(opts *Options) {
+		opts.Channel.ThreadFlag = threadFlag
+	}
+}
+
+func WithChannelDisbandGracePeriod(gracePeriod time.Duration) Option {
+	return func(opts *Options) {
+		opts.Channel.DisbandGracePeriod = gracePeriod
+	}
+}
+
+func WithChannelDisbandCheckInterval(interval time.Duration) Option {
+	return func(opts *Options) {
+		opts.Channel.DisbandCheckInterval = interval
+	}
+}
+
 func WithConnIdleTime(connIdleTime time.Duration) Option {
 	return func(opts *Options) {
 		opts.ConnIdleTime = connIdleTime
@@ -1414,6 +1872,147 @@ func WithTmpChannelCacheCount(cacheCount int) Option {
 	}
 }
 
+func WithTmpChannelTtl(ttl time.Duration) Option {
+	return func(opts *Options) {
+		opts.TmpChannel.Ttl = ttl
+	}
+}
+
+func WithTmpChannelIdleTtl(idleTtl time.Duration) Option {
+	return func(opts *Options) {
+		opts.TmpChannel.IdleTtl = idleTtl
+	}
+}
+
+func WithTmpChannelCheckInterval(interval time.Duration) Option {
+	return func(opts *Options) {
+		opts.TmpChannel.CheckInterval = interval
+	}
+}
+
+func WithPresenceOn(on bool) Option {
+	return func(opts *Options) {
+		opts.Presence.On = on
+	}
+}
+
+func WithPresenceMaxSubscriptionsPerConn(max int) Option {
+	return func(opts *Options) {
+		opts.Presence.MaxSubscriptionsPerConn = max
+	}
+}
+
+func WithPresenceFlushInterval(interval time.Duration) Option {
+	return func(opts *Options) {
+		opts.Presence.FlushInterval = interval
+	}
+}
+
+func WithPresenceHookHTTPAddr(addr string) Option {
+	return func(opts *Options) {
+		opts.Presence.HookHTTPAddr = addr
+	}
+}
+
+func WithTransientInterval(interval time.Duration) Option {
+	return func(opts *Options) {
+		opts.Transient.Interval = interval
+	}
+}
+
+func WithUserBanCheckInterval(interval time.Duration) Option {
+	return func(opts *Options) {
+		opts.UserBan.CheckInterval = interval
+	}
+}
+
+// WithRateLimitMsgRate 每个用户每秒允许发送的消息数量，限速在连接所在的节点上各自生效（用户整体上限为有连接的节点数倍）
+func WithRateLimitMsgRate(msgRate float64, msgBurst int) Option {
+	return func(opts *Options) {
+		opts.RateLimit.MsgRate = msgRate
+		opts.RateLimit.MsgBurst = msgBurst
+	}
+}
+
+// WithRateLimitByteRate 每个用户每秒允许发送的消息字节数，限速在连接所在的节点上各自生效（用户整体上限为有连接的节点数倍）
+func WithRateLimitByteRate(byteRate int, byteBurst int) Option {
+	return func(opts *Options) {
+		opts.RateLimit.ByteRate = byteRate
+		opts.RateLimit.ByteBurst = byteBurst
+	}
+}
+
+// WithRateLimitOverride 指定用户的限速，与全局限速一样按节点各自生效
+func WithRateLimitOverride(uid string, override RateLimitOverride) Option {
+	return func(opts *Options) {
+		opts.RateLimit.Overrides[uid] = override
+	}
+}
+
+func WithSessionLog(on bool, retention time.Duration) Option {
+	return func(opts *Options) {
+		opts.SessionLog.On = on
+		opts.SessionLog.Retention = retention
+	}
+}
+
+func WithSessionLogWebhookOn(webhookOn bool) Option {
+	return func(opts *Options) {
+		opts.SessionLog.WebhookOn = webhookOn
+	}
+}
+
+func WithMQTTAddr(addr string) Option {
+	return func(opts *Options) {
+		opts.MQTT.Addr = addr
+	}
+}
+
+func WithMQTTDeviceFlag(deviceFlag wkproto.DeviceFlag) Option {
+	return func(opts *Options) {
+		opts.MQTT.DeviceFlag = deviceFlag
+	}
+}
+
+func WithWSJSONOn(on bool) Option {
+	return func(opts *Options) {
+		opts.WSJSONOn = on
+	}
+}
+
+func WithWSJSONInsecureOn(on bool) Option {
+	return func(opts *Options) {
+		opts.WSJSONInsecureOn = on
+	}
+}
+
+func WithWSCompression(on bool, level int, threshold int) Option {
+	return func(opts *Options) {
+		opts.WSCompression.On = on
+		opts.WSCompression.Level = level
+		opts.WSCompression.Threshold = threshold
+	}
+}
+
+func WithConnLimit(maxPerUser int, policy ConnLimitPolicy) Option {
+	return func(opts *Options) {
+		opts.ConnLimit.MaxPerUser = maxPerUser
+		opts.ConnLimit.Policy = policy
+	}
+}
+
+func WithConnLimitOfDeviceFlag(deviceFlag wkproto.DeviceFlag, max int) Option {
+	return func(opts *Options) {
+		opts.ConnLimit.MaxPerDeviceFlag[deviceFlag] = max
+	}
+}
+
+func WithUserBanCacheTTL(ttl time.Duration) Option {
+	return func(opts *Options) {
+		opts.UserBan.CacheTTL = ttl
+	}
+}
+
 func WithDatasourceAddr(addr string) Option {
 	return func(opts *Options) {
 		opts.Datasource.Addr = addr
@@ -1432,6 +2031,74 @@ func WithWhitelistOffOfPerson(whitelistOffOfPerson bool) Option {
 	}
 }
 
+func WithClientJwt(algorithm string, keys []string) Option {
+	return func(opts *Options) {
+		opts.ClientJwt.On = true
+		opts.ClientJwt.Algorithm = algorithm
+		opts.ClientJwt.Keys = keys
+	}
+}
+
+func WithClientJwtIssuer(issuer string) Option {
+	return func(opts *Options) {
+		opts.ClientJwt.Issuer = issuer
+	}
+}
+
+func WithAuthHookHTTPAddr(httpAddr string) Option {
+	return func(opts *Options) {
+		opts.AuthHook.HTTPAddr = httpAddr
+	}
+}
+
+func WithAuthHookGRPCAddr(grpcAddr string) Option {
+	return func(opts *Options) {
+		opts.AuthHook.GRPCAddr = grpcAddr
+	}
+}
+
+func WithAuthHookCacheTTL(cacheTTL time.Duration) Option {
+	return func(opts *Options) {
+		opts.AuthHook.CacheTTL = cacheTTL
+	}
+}
+
+func WithSendHookHTTPAddr(httpAddr string) Option {
+	return func(opts *Options) {
+		opts.SendHook.HTTPAddr = httpAddr
+	}
+}
+
+func WithSendHookGRPCAddr(grpcAddr string) Option {
+	return func(opts *Options) {
+		opts.SendHook.GRPCAddr = grpcAddr
+	}
+}
+
+func WithSendHookTimeout(timeout time.Duration) Option {
+	return func(opts *Options) {
+		opts.SendHook.Timeout = timeout
+	}
+}
+
+func WithSendHookFailOpen(failOpen bool) Option {
+	return func(opts *Options) {
+		opts.SendHook.FailOpen = failOpen
+	}
+}
+
+func WithSendHookChannelTypes(channelTypes ...uint8) Option {
+	return func(opts *Options) {
+		opts.SendHook.ChannelTypes = channelTypes
+	}
+}
+
+func WithEventSinks(sinks ...eventsink.Config) Option {
+	return func(opts *Options) {
+		opts.EventSinks = sinks
+	}
+}
+
 func WithTokenAuthOn(tokenAuthOn bool) Option {
 	return func(opts *Options) {
 		opts.TokenAuthOn = tokenAuthOn
@@ -1552,6 +2219,29 @@ func WithWebhookMsgNotifyEventRetryMaxCount(retryMaxCount int) Option {
 	}
 }
 
+func WithWebhookChannelExclusive(exclusive bool) Option {
+	return func(opts *Options) {
+		opts.Webhook.ChannelExclusive = exclusive
+	}
+}
+
+// WithWebhookSecret 设置webhook签名密钥，secondarySecret为密钥轮换期间的另一个有效密钥
+func WithWebhookSecret(secret string, secondarySecret string) Option {
+	return func(opts *Options) {
+		opts.Webhook.Secret = secret
+		opts.Webhook.SecondarySecret = secondarySecret
+	}
+}
+
+// WithWebhookRetry 设置webhook事件投递失败的重试（指数退避）
+func WithWebhookRetry(initialInterval time.Duration, maxInterval time.Duration, maxCount int) Option {
+	return func(opts *Options) {
+		opts.Webhook.RetryInitialInterval = initialInterval
+		opts.Webhook.RetryMaxInterval = maxInterval
+		opts.Webhook.RetryMaxCount = maxCount
+	}
+}
+
 func WithClusterNodeId(nodeId uint64) Option {
 	return func(opts *Options) {
 		opts.Cluster.NodeId = nodeId
@@ -0,0 +1,573 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RussellLuo/timingwheel"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/panjf2000/ants/v2"
+	"go.uber.org/zap"
+)
+
+// 在线状态订阅的SUB包参数
+const presenceSubParam = "presence"
+
+// ReasonPresenceSubLimit 连接订阅的用户数量超过上限（Presence.MaxSubscriptionsPerConn）时suback返回的原因码。
+// wkproto中没有对应的原因码，这里在wkproto的原因码之后预留一个值，避免与发送限速的ReasonRateLimit混淆
+const ReasonPresenceSubLimit wkproto.ReasonCode = 100
+
+// 在线状态订阅
+// 客户端通过SUB包（Param为presence）订阅一批用户（ChannelType为个人频道，ChannelID为逗号分隔的uid）或者某个频道的成员（订阅者需要是频道成员），
+// 配置了校验地址（Presence.HookHTTPAddr）时，订阅前由第三方服务校验可以订阅哪些用户（例如只能订阅好友）。
+// 被订阅的用户第一个连接上线（userReactor认证成功）或最后一个连接下线（连接关闭）时，服务端通过不存储的cmd接收包推送精简的在线状态事件。
+// 订阅关系保存在订阅连接所在的节点，节点把本节点订阅了哪些用户登记到用户所属槽的领导节点（定时重新登记，登记过期后失效），
+// 事件由产生的节点批量发给用户所属槽的领导节点，再由领导节点转发给登记了订阅的节点，每个节点只推送给本节点的订阅连接。
+type presenceSubManager struct {
+	s *Server
+	wklog.Log
+	pool       *ants.Pool
+	httpClient *http.Client // 订阅校验的http客户端
+
+	mu       sync.RWMutex
+	subs     map[string]map[int64]*connContext // 被订阅的uid -> 订阅的连接
+	connSubs map[int64]map[string]struct{}     // 订阅的连接id -> 订阅的uid集合
+
+	eventsLock      sync.Mutex
+	events          []*presenceEvent // 等待推送的事件
+	pendingInterest map[string]bool  // 等待登记的订阅变化，uid -> true.订阅 false.取消订阅
+
+	interestLock sync.RWMutex
+	interests    map[string]map[uint64]int64 // 本节点为槽领导的被订阅uid -> 订阅的节点 -> 登记的过期时间（纳秒）
+
+	flushTimer   *timingwheel.Timer
+	refreshTimer *timingwheel.Timer
+}
+
+const (
+	presenceInterestRefreshInterval = time.Minute                         // 重新登记本节点所有订阅的间隔
+	presenceInterestTTL             = presenceInterestRefreshInterval * 3 // 登记的有效期
+)
+
+func newPresenceSubManager(s *Server) *presenceSubManager {
+	pool, err := ants.NewPool(s.opts.EventPoolSize, ants.WithPanicHandler(func(err interface{}) {
+		s.Error("presence sub panic", zap.Any("err", err), zap.Stack("stack"))
+	}))
+	if err != nil {
+		panic(err)
+	}
+	return &presenceSubManager{
+		s:    s,
+		Log:  wklog.NewWKLog("presenceSubManager"),
+		pool: pool,
+		httpClient: &http.Client{
+			Timeout: s.opts.Presence.HookTimeout,
+		},
+		subs:            make(map[string]map[int64]*connContext),
+		connSubs:        make(map[int64]map[string]struct{}),
+		pendingInterest: make(map[string]bool),
+		interests:       make(map[string]map[uint64]int64),
+	}
+}
+
+func (p *presenceSubManager) start() error {
+	if !p.s.opts.Presence.On {
+		return nil
+	}
+	p.flushTimer = p.s.Schedule(p.s.opts.Presence.FlushInterval, p.flush)
+	p.refreshTimer = p.s.Schedule(presenceInterestRefreshInterval, p.refreshInterest)
+	return nil
+}
+
+func (p *presenceSubManager) stop() {
+	if p.flushTimer != nil {
+		p.flushTimer.Stop()
+	}
+	if p.refreshTimer != nil {
+		p.refreshTimer.Stop()
+	}
+	p.pool.Release()
+}
+
+// 处理客户端的订阅包
+func (p *presenceSubManager) handleSub(conn *connContext, subPacket *wkproto.SubPacket) {
+	err := p.pool.Submit(func() {
+		reasonCode := p.handleSubPacket(conn, subPacket)
+		err := conn.writePacket(&wkproto.SubackPacket{
+			SubNo:       subPacket.SubNo,
+			ChannelID:   subPacket.ChannelID,
+			ChannelType: subPacket.ChannelType,
+			Action:      subPacket.Action,
+			ReasonCode:  reasonCode,
+		})
+		if err != nil {
+			p.Warn("write suback failed", zap.Error(err), zap.String("uid", conn.uid), zap.Int64("connId", conn.connId))
+		}
+	})
+	if err != nil {
+		p.Error("submit handleSub failed", zap.Error(err), zap.String("uid", conn.uid))
+	}
+}
+
+func (p *presenceSubManager) handleSubPacket(conn *connContext, subPacket *wkproto.SubPacket) wkproto.ReasonCode {
+	if !p.s.opts.Presence.On || subPacket.Param != presenceSubParam {
+		p.Warn("not support sub", zap.String("uid", conn.uid), zap.String("param", subPacket.Param))
+		return wkproto.ReasonSystemError
+	}
+
+	var uids []string
+	if subPacket.ChannelType == wkproto.ChannelTypePerson {
+		for _, uid := range strings.Split(subPacket.ChannelID, ",") {
+			uid = strings.TrimSpace(uid)
+			if uid != "" && uid != conn.uid {
+				uids = append(uids, uid)
+			}
+		}
+	} else {
+		channelId := subPacket.ChannelID
+		// 只有频道成员才能订阅频道成员的在线状态
+		exists, err := p.s.requestExistSubscribers(channelId, subPacket.ChannelType, []string{conn.uid})
+		if err != nil {
+			p.Error("requestExistSubscribers failed", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", subPacket.ChannelType))
+			return wkproto.ReasonSystemError
+		}
+		if len(exists) == 0 {
+			return wkproto.ReasonSubscriberNotExist
+		}
+		members, err := p.s.requestSubscribers(channelId, subPacket.ChannelType)
+		if err != nil {
+			p.Error("requestSubscribers failed", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", subPacket.ChannelType))
+			return wkproto.ReasonSystemError
+		}
+		for _, member := range members {
+			if member != conn.uid {
+				uids = append(uids, member)
+			}
+		}
+	}
+
+	if subPacket.Action == wkproto.UnSubscribe {
+		p.unsubscribe(conn, uids)
+		return wkproto.ReasonSuccess
+	}
+	if p.s.opts.Presence.HookHTTPAddr != "" && len(uids) > 0 {
+		allowed, err := p.checkByHook(conn.uid, uids)
+		if err != nil {
+			p.Error("presence hook failed", zap.Error(err), zap.String("uid", conn.uid))
+			return wkproto.ReasonSystemError
+		}
+		uids = allowed
+	}
+	if !p.subscribe(conn, uids) {
+		p.Warn("presence subscriptions exceed the limit", zap.String("uid", conn.uid), zap.Int64("connId", conn.connId), zap.Int("max", p.s.opts.Presence.MaxSubscriptionsPerConn))
+		return ReasonPresenceSubLimit
+	}
+	return wkproto.ReasonSuccess
+}
+
+// 订阅用户的在线状态，超过连接的订阅上限返回false
+func (p *presenceSubManager) subscribe(conn *connContext, uids []string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	connSub := p.connSubs[conn.connId]
+	newCount := 0
+	for _, uid := range uids {
+		if _, ok := connSub[uid]; !ok {
+			newCount++
+		}
+	}
+	maxCount := p.s.opts.Presence.MaxSubscriptionsPerConn
+	if maxCount > 0 && len(connSub)+newCount > maxCount {
+		return false
+	}
+
+	if connSub == nil {
+		connSub = make(map[string]struct{}, len(uids))
+		p.connSubs[conn.connId] = connSub
+	}
+	for _, uid := range uids {
+		connSub[uid] = struct{}{}
+		conns := p.subs[uid]
+		if conns == nil {
+			conns = make(map[int64]*connContext)
+			p.subs[uid] = conns
+			p.changeInterest(uid, true) // 本节点第一个订阅此用户的连接
+		}
+		conns[conn.connId] = conn
+	}
+	return true
+}
+
+// 取消订阅用户的在线状态
+func (p *presenceSubManager) unsubscribe(conn *connContext, uids []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	connSub := p.connSubs[conn.connId]
+	if connSub == nil {
+		return
+	}
+	for _, uid := range uids {
+		delete(connSub, uid)
+		p.removeSubLocked(uid, conn.connId)
+	}
+	if len(connSub) == 0 {
+		delete(p.connSubs, conn.connId)
+	}
+}
+
+// 移除连接的所有订阅（连接关闭时调用）
+func (p *presenceSubManager) removeConn(connId int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	connSub := p.connSubs[connId]
+	if connSub == nil {
+		return
+	}
+	for uid := range connSub {
+		p.removeSubLocked(uid, connId)
+	}
+	delete(p.connSubs, connId)
+}
+
+func (p *presenceSubManager) removeSubLocked(uid string, connId int64) {
+	conns := p.subs[uid]
+	if conns == nil {
+		return
+	}
+	delete(conns, connId)
+	if len(conns) == 0 {
+		delete(p.subs, uid)
+		p.changeInterest(uid, false) // 本节点没有连接订阅此用户了
+	}
+}
+
+// 记录等待登记的订阅变化（持有mu时调用）
+func (p *presenceSubManager) changeInterest(uid string, subscribe bool) {
+	p.eventsLock.Lock()
+	p.pendingInterest[uid] = subscribe
+	p.eventsLock.Unlock()
+}
+
+// 通过第三方服务校验可以订阅哪些用户
+func (p *presenceSubManager) checkByHook(uid string, uids []string) ([]string, error) {
+	req := &presenceHookReq{
+		UID:  uid,
+		UIDs: uids,
+	}
+	resp, err := p.httpClient.Post(p.s.opts.Presence.HookHTTPAddr, "application/json", bytes.NewReader([]byte(wkutil.ToJSON(req))))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("presence hook: response status code is %d", resp.StatusCode)
+	}
+	hookResp := &presenceHookResp{}
+	if err = json.NewDecoder(resp.Body).Decode(hookResp); err != nil {
+		return nil, err
+	}
+	// 只保留请求中的用户
+	allowed := make([]string, 0, len(hookResp.UIDs))
+	for _, u := range hookResp.UIDs {
+		if wkutil.ArrayContains(uids, u) {
+			allowed = append(allowed, u)
+		}
+	}
+	return allowed, nil
+}
+
+// 用户上线（第一个连接）或下线（最后一个连接），事件在下一次flush时推送
+func (p *presenceSubManager) onPresenceChange(uid string, deviceFlag wkproto.DeviceFlag, online bool) {
+	if !p.s.opts.Presence.On || p.s.systemUIDManager.SystemUID(uid) {
+		return
+	}
+	event := &presenceEvent{
+		UID:        uid,
+		DeviceFlag: uint8(deviceFlag),
+		Timestamp:  time.Now().Unix(),
+	}
+	if online {
+		event.Online = 1
+	}
+	p.eventsLock.Lock()
+	p.events = append(p.events, event)
+	p.eventsLock.Unlock()
+}
+
+// 登记订阅变化，推送等待的事件给本节点的订阅连接，并发给用户所属槽的领导节点转发
+func (p *presenceSubManager) flush() {
+	p.eventsLock.Lock()
+	events := p.events
+	p.events = nil
+	var pendingInterest map[string]bool
+	if len(p.pendingInterest) > 0 {
+		pendingInterest = p.pendingInterest
+		p.pendingInterest = make(map[string]bool)
+	}
+	p.eventsLock.Unlock()
+
+	if len(pendingInterest) > 0 {
+		interests := make([]presenceInterest, 0, len(pendingInterest))
+		for uid, subscribe := range pendingInterest {
+			interests = append(interests, presenceInterest{UID: uid, Subscribe: subscribe})
+		}
+		p.registerInterest(interests)
+	}
+
+	if len(events) == 0 {
+		return
+	}
+
+	err := p.pool.Submit(func() {
+		p.notifyLocal(events)
+	})
+	if err != nil {
+		p.Error("submit notifyLocal failed", zap.Error(err))
+	}
+
+	leaderEvents := make(map[uint64][]*presenceEvent)
+	for _, event := range events {
+		leaderId, err := p.s.cluster.SlotLeaderIdOfChannel(event.UID, wkproto.ChannelTypePerson)
+		if err != nil {
+			p.Warn("get leader of user failed", zap.Error(err), zap.String("uid", event.UID))
+			continue
+		}
+		leaderEvents[leaderId] = append(leaderEvents[leaderId], event)
+	}
+	for leaderId, evts := range leaderEvents {
+		if p.s.opts.IsLocalNode(leaderId) {
+			p.route(p.s.opts.Cluster.NodeId, evts)
+			continue
+		}
+		leaderId, data := leaderId, []byte(wkutil.ToJSON(&presenceRouteReq{FromNodeId: p.s.opts.Cluster.NodeId, Events: evts}))
+		err = p.pool.Submit(func() {
+			if err := p.request(leaderId, "/wk/presenceRoute", data); err != nil {
+				p.Warn("request presenceRoute failed", zap.Error(err), zap.Uint64("nodeId", leaderId))
+			}
+		})
+		if err != nil {
+			p.Error("submit presenceRoute failed", zap.Error(err), zap.Uint64("nodeId", leaderId))
+		}
+	}
+}
+
+// 将事件转发给登记了订阅的节点（用户所属槽的领导节点），不转发给事件的来源节点
+func (p *presenceSubManager) route(fromNodeId uint64, events []*presenceEvent) {
+	nodeEvents := make(map[uint64][]*presenceEvent)
+	for _, event := range events {
+		for _, nodeId := range p.interestedNodes(event.UID) {
+			if nodeId == fromNodeId || p.s.opts.IsLocalNode(nodeId) {
+				continue
+			}
+			nodeEvents[nodeId] = append(nodeEvents[nodeId], event)
+		}
+	}
+	for nodeId, evts := range nodeEvents {
+		nodeId, data := nodeId, []byte(wkutil.ToJSON(evts))
+		err := p.pool.Submit(func() {
+			if err := p.request(nodeId, "/wk/presenceNotify", data); err != nil {
+				p.Warn("request presenceNotify failed", zap.Error(err), zap.Uint64("nodeId", nodeId))
+			}
+		})
+		if err != nil {
+			p.Error("submit presenceNotify failed", zap.Error(err), zap.Uint64("nodeId", nodeId))
+		}
+	}
+}
+
+// 定时重新登记本节点所有的订阅，并清除过期的登记
+func (p *presenceSubManager) refreshInterest() {
+	p.mu.RLock()
+	interests := make([]presenceInterest, 0, len(p.subs))
+	for uid := range p.subs {
+		interests = append(interests, presenceInterest{UID: uid, Subscribe: true})
+	}
+	p.mu.RUnlock()
+	p.registerInterest(interests)
+
+	now := time.Now().UnixNano()
+	p.interestLock.Lock()
+	for uid, nodes := range p.interests {
+		for nodeId, expireAt := range nodes {
+			if expireAt < now {
+				delete(nodes, nodeId)
+			}
+		}
+		if len(nodes) == 0 {
+			delete(p.interests, uid)
+		}
+	}
+	p.interestLock.Unlock()
+}
+
+// 将订阅变化登记到用户所属槽的领导节点
+func (p *presenceSubManager) registerInterest(interests []presenceInterest) {
+	leaderInterests := make(map[uint64][]presenceInterest)
+	for _, interest := range interests {
+		leaderId, err := p.s.cluster.SlotLeaderIdOfChannel(interest.UID, wkproto.ChannelTypePerson)
+		if err != nil {
+			p.Warn("get leader of user failed", zap.Error(err), zap.String("uid", interest.UID))
+			continue
+		}
+		leaderInterests[leaderId] = append(leaderInterests[leaderId], interest)
+	}
+	for leaderId, items := range leaderInterests {
+		if p.s.opts.IsLocalNode(leaderId) {
+			p.applyInterest(p.s.opts.Cluster.NodeId, items)
+			continue
+		}
+		leaderId, data := leaderId, []byte(wkutil.ToJSON(&presenceInterestReq{NodeId: p.s.opts.Cluster.NodeId, Interests: items}))
+		err := p.pool.Submit(func() {
+			if err := p.request(leaderId, "/wk/presenceInterest", data); err != nil {
+				p.Warn("request presenceInterest failed", zap.Error(err), zap.Uint64("nodeId", leaderId))
+			}
+		})
+		if err != nil {
+			p.Error("submit presenceInterest failed", zap.Error(err), zap.Uint64("nodeId", leaderId))
+		}
+	}
+}
+
+// 登记节点的订阅变化（用户所属槽的领导节点）
+func (p *presenceSubManager) applyInterest(nodeId uint64, interests []presenceInterest) {
+	expireAt := time.Now().Add(presenceInterestTTL).UnixNano()
+	p.interestLock.Lock()
+	defer p.interestLock.Unlock()
+	for _, interest := range interests {
+		nodes := p.interests[interest.UID]
+		if !interest.Subscribe {
+			if nodes != nil {
+				delete(nodes, nodeId)
+				if len(nodes) == 0 {
+					delete(p.interests, interest.UID)
+				}
+			}
+			continue
+		}
+		if nodes == nil {
+			nodes = make(map[uint64]int64)
+			p.interests[interest.UID] = nodes
+		}
+		nodes[nodeId] = expireAt
+	}
+}
+
+// 登记了订阅用户的节点
+func (p *presenceSubManager) interestedNodes(uid string) []uint64 {
+	now := time.Now().UnixNano()
+	p.interestLock.RLock()
+	defer p.interestLock.RUnlock()
+	nodes := p.interests[uid]
+	nodeIds := make([]uint64, 0, len(nodes))
+	for nodeId, expireAt := range nodes {
+		if expireAt >= now {
+			nodeIds = append(nodeIds, nodeId)
+		}
+	}
+	return nodeIds
+}
+
+func (p *presenceSubManager) request(nodeId uint64, path string, data []byte) error {
+	timeoutCtx, cancel := context.WithTimeout(p.s.ctx, time.Second*5)
+	defer cancel()
+
+	resp, err := p.s.cluster.RequestWithContext(timeoutCtx, nodeId, path, data)
+	if err != nil {
+		return err
+	}
+	if resp.Status != proto.StatusOK {
+		return fmt.Errorf("request %s: response status code is %d", path, resp.Status)
+	}
+	return nil
+}
+
+// 将事件推送给本节点订阅了的连接
+func (p *presenceSubManager) notifyLocal(events []*presenceEvent) {
+	connEvents := make(map[int64][]*presenceEvent)
+	conns := make(map[int64]*connContext)
+	p.mu.RLock()
+	for _, event := range events {
+		for connId, conn := range p.subs[event.UID] {
+			connEvents[connId] = append(connEvents[connId], event)
+			conns[connId] = conn
+		}
+	}
+	p.mu.RUnlock()
+
+	for connId, evts := range connEvents {
+		conn := conns[connId]
+		if conn.isClosed() {
+			continue
+		}
+		payload := []byte(wkutil.ToJSON(map[string]interface{}{
+			"cmd":   "presence",
+			"param": evts,
+		}))
+		recvPacket := &wkproto.RecvPacket{
+			Framer: wkproto.Framer{
+				SyncOnce:  true,
+				NoPersist: true,
+			},
+			MessageID:   p.s.channelReactor.messageIDGen.Generate().Int64(),
+			ClientMsgNo: wkutil.GenUUID(),
+			StreamFlag:  wkproto.StreamFlagIng,
+			ChannelID:   p.s.opts.SystemUID,
+			ChannelType: wkproto.ChannelTypePerson,
+			Timestamp:   int32(time.Now().Unix()),
+			Payload:     payload,
+		}
+		err := p.s.writeRecvPacketNoRetry(conn, recvPacket)
+		if err != nil {
+			p.Warn("write presence event failed", zap.Error(err), zap.String("uid", conn.uid), zap.Int64("connId", connId))
+		}
+	}
+}
+
+// 在线状态事件
+type presenceEvent struct {
+	UID        string `json:"uid"`         // 用户uid
+	Online     int    `json:"online"`      // 1.上线 0.下线
+	DeviceFlag uint8  `json:"device_flag"` // 设备标记 0. APP 1.web 2.pc
+	Timestamp  int64  `json:"timestamp"`   // 事件时间（秒）
+}
+
+// 订阅变化
+type presenceInterest struct {
+	UID       string `json:"uid"`       // 被订阅的用户
+	Subscribe bool   `json:"subscribe"` // true.订阅 false.取消订阅
+}
+
+// 节点登记订阅变化的请求
+type presenceInterestReq struct {
+	NodeId    uint64             `json:"node_id"` // 订阅的节点
+	Interests []presenceInterest `json:"interests"`
+}
+
+// 发给用户所属槽的领导节点转发的事件
+type presenceRouteReq struct {
+	FromNodeId uint64           `json:"from_node_id"` // 事件的来源节点（已推送给本节点的订阅连接）
+	Events     []*presenceEvent `json:"events"`
+}
+
+// 订阅校验的请求
+type presenceHookReq struct {
+	UID  string   `json:"uid"`  // 订阅者
+	UIDs []string `json:"uids"` // 要订阅的用户
+}
+
+// 订阅校验的返回
+type presenceHookResp struct {
+	UIDs []string `json:"uids"` // 允许订阅的用户
+}
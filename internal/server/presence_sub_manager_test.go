@@ -0,0 +1,132 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPresenceInterest(t *testing.T) {
+	p := newPresenceSubManager(&Server{opts: NewOptions()})
+	defer p.pool.Release()
+
+	p.applyInterest(2, []presenceInterest{{UID: "u1", Subscribe: true}, {UID: "u2", Subscribe: true}})
+	p.applyInterest(3, []presenceInterest{{UID: "u1", Subscribe: true}})
+	assert.ElementsMatch(t, []uint64{2, 3}, p.interestedNodes("u1"))
+	assert.ElementsMatch(t, []uint64{2}, p.interestedNodes("u2"))
+	assert.Empty(t, p.interestedNodes("u3"))
+
+	// 取消订阅
+	p.applyInterest(2, []presenceInterest{{UID: "u1", Subscribe: false}, {UID: "u2", Subscribe: false}})
+	assert.ElementsMatch(t, []uint64{3}, p.interestedNodes("u1"))
+	assert.NotContains(t, p.interests, "u2")
+
+	// 登记过期后不再转发
+	p.interests["u1"][3] = time.Now().Add(-time.Second).UnixNano()
+	assert.Empty(t, p.interestedNodes("u1"))
+}
+
+func TestPresenceSub(t *testing.T) {
+	// 只允许订阅u2
+	hookTs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := &presenceHookReq{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(req))
+		assert.Equal(t, "u1", req.UID)
+		_ = json.NewEncoder(w).Encode(&presenceHookResp{UIDs: []string{"u2", "u4"}})
+	}))
+	defer hookTs.Close()
+
+	s := NewTestServer(t, WithPresenceHookHTTPAddr(hookTs.URL), WithPresenceFlushInterval(time.Millisecond*50))
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady(time.Second * 10) // 等待服务准备好
+
+	cli := TestCreateClient(t, s, "u1")
+	defer cli.Close()
+	eventC := make(chan *presenceEvent, 10)
+	cli.SetOnRecv(func(recv *wkproto.RecvPacket) error {
+		var cmd struct {
+			Cmd   string           `json:"cmd"`
+			Param []*presenceEvent `json:"param"`
+		}
+		assert.NoError(t, json.Unmarshal(recv.Payload, &cmd))
+		assert.Equal(t, "presence", cmd.Cmd)
+		for _, event := range cmd.Param {
+			eventC <- event
+		}
+		return nil
+	})
+
+	conns := s.userReactor.getConns("u1")
+	assert.Len(t, conns, 1)
+	reasonCode := s.presenceSubManager.handleSubPacket(conns[0], &wkproto.SubPacket{
+		ChannelID:   "u2,u3",
+		ChannelType: wkproto.ChannelTypePerson,
+		Param:       presenceSubParam,
+	})
+	assert.Equal(t, wkproto.ReasonSuccess, reasonCode)
+	s.presenceSubManager.mu.RLock()
+	assert.Contains(t, s.presenceSubManager.subs, "u2")
+	assert.NotContains(t, s.presenceSubManager.subs, "u3") // 校验没有通过
+	s.presenceSubManager.mu.RUnlock()
+
+	// 超过连接的订阅上限
+	s.opts.Presence.MaxSubscriptionsPerConn = 1
+	reasonCode = s.presenceSubManager.handleSubPacket(conns[0], &wkproto.SubPacket{
+		ChannelID:   "u4",
+		ChannelType: wkproto.ChannelTypePerson,
+		Param:       presenceSubParam,
+	})
+	assert.Equal(t, ReasonPresenceSubLimit, reasonCode)
+	s.presenceSubManager.mu.RLock()
+	assert.NotContains(t, s.presenceSubManager.subs, "u4")
+	s.presenceSubManager.mu.RUnlock()
+
+	// 订阅登记到用户所属槽的领导节点
+	assert.Eventually(t, func() bool {
+		return len(s.presenceSubManager.interestedNodes("u2")) == 1
+	}, time.Second*5, time.Millisecond*10)
+
+	waitEvent := func() *presenceEvent {
+		select {
+		case event := <-eventC:
+			return event
+		case <-time.After(time.Second * 5):
+			t.Fatal("wait presence event timeout")
+		}
+		return nil
+	}
+	noEvent := func() {
+		select {
+		case event := <-eventC:
+			t.Fatalf("unexpected presence event: %+v", event)
+		case <-time.After(time.Millisecond * 300):
+		}
+	}
+
+	// 只有第一个连接上线和最后一个连接下线时推送
+	u2Cli1 := TestCreateClient(t, s, "u2")
+	event := waitEvent()
+	assert.Equal(t, "u2", event.UID)
+	assert.Equal(t, 1, event.Online)
+
+	u2Cli2 := TestCreateClient(t, s, "u2")
+	noEvent()
+	TestCreateClient(t, s, "u3").Close()
+	noEvent()
+
+	u2Cli1.Close()
+	noEvent()
+	u2Cli2.Close()
+	event = waitEvent()
+	assert.Equal(t, "u2", event.UID)
+	assert.Equal(t, 0, event.Online)
+}
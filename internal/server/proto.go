@@ -35,6 +35,13 @@ func (s *Server) onData(conn wknet.Conn) error {
 		}
 	}
 
+	if _, ok := conn.(*wknet.MQTTConn); ok { // mqtt连接由mqtt网关处理
+		return s.mqttGateway.onData(conn, buff)
+	}
+	if isWSJSONConn(conn) { // websocket JSON文本协议的连接由JSON网关处理
+		return s.wsJSONGateway.onData(conn, buff)
+	}
+
 	data, _ := gnetUnpacket(buff)
 	if len(data) == 0 {
 		return nil
@@ -122,6 +129,9 @@ func (s *Server) onData(conn wknet.Conn) error {
 					sendPacket.Payload = newPayload
 				}
 				connCtx.addSendPacket(sendPacket)
+			} else if frame.GetFrameType() == wkproto.SUB { // 订阅只在连接所在节点处理
+				connCtx.keepActivity()
+				s.presenceSubManager.handleSub(connCtx, frame.(*wkproto.SubPacket))
 			} else {
 				connCtx.addOtherPacket(frame)
 			}
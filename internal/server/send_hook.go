@@ -0,0 +1,247 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/grpcpool"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhook"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+)
+
+// 消息发送前的拦截（例如内容审核）
+// 频道领导节点存储消息前，把同一批存储请求内的消息（已解密）一次性发给拦截服务（http或grpc），拦截服务逐条返回放行、拒绝或替换后的消息内容。
+// 被拒绝的消息不存储也不投递，拦截服务返回的reason code通过sendack返回给发送者。
+// 请求失败或超时时按FailOpen决定放行还是拒绝（ReasonSystemError）。
+//
+// http方式: POST {HTTPAddr} 请求体为 sendHookReq 的json，返回200和 sendHookResp 的json，没有返回结果的消息视为放行
+// grpc方式: 调用 wkhook.WebhookService/BeforeSend
+type sendHook struct {
+	s *Server
+	wklog.Log
+	httpClient *http.Client
+	grpcPool   *grpcpool.Pool // 拦截服务的grpc客户端
+}
+
+func newSendHook(s *Server) *sendHook {
+	h := &sendHook{
+		s:   s,
+		Log: wklog.NewWKLog("sendHook"),
+	}
+	if s.opts.SendHookGRPCOn() {
+		grpcPool, err := grpcpool.New(func() (*grpc.ClientConn, error) {
+			return grpc.Dial(s.opts.SendHook.GRPCAddr, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithKeepaliveParams(keepalive.ClientParameters{
+				Time:    5 * time.Minute,
+				Timeout: 2 * time.Second,
+			}))
+		}, 2, 20, time.Minute*5)
+		if err != nil {
+			panic(err)
+		}
+		h.grpcPool = grpcPool
+	} else {
+		h.httpClient = &http.Client{
+			Timeout: s.opts.SendHook.Timeout,
+			Transport: &http.Transport{
+				DialContext: (&net.Dialer{
+					Timeout:   5 * time.Second,
+					KeepAlive: 5 * time.Second,
+				}).DialContext,
+				ForceAttemptHTTP2:   true,
+				MaxIdleConns:        200,
+				MaxIdleConnsPerHost: 200,
+				IdleConnTimeout:     300 * time.Second,
+				TLSHandshakeTimeout: time.Second * 5,
+			},
+		}
+	}
+	return h
+}
+
+func (h *sendHook) stop() {
+	if h.grpcPool != nil {
+		h.grpcPool.Close()
+	}
+}
+
+// intercept 拦截频道的一批消息，拒绝的消息修改ReasonCode，替换的消息直接修改SendPacket的Payload
+func (h *sendHook) intercept(channelId string, channelType uint8, messages []ReactorChannelMessage) {
+	req := &sendHookReq{}
+	indexs := make([]int, 0, len(messages)) // 需要拦截的消息在messages中的下标
+	for i, msg := range messages {
+		if msg.ReasonCode != wkproto.ReasonSuccess || msg.IsEncrypt || msg.IsSystem || msg.SendPacket == nil {
+			continue
+		}
+		indexs = append(indexs, i)
+		req.Messages = append(req.Messages, &sendHookMessage{
+			MessageId:    msg.MessageId,
+			ClientMsgNo:  msg.SendPacket.ClientMsgNo,
+			FromUid:      msg.FromUid,
+			FromDeviceId: msg.FromDeviceId,
+			ChannelId:    channelId,
+			ChannelType:  channelType,
+			Topic:        msg.SendPacket.Topic,
+			Payload:      msg.SendPacket.Payload,
+		})
+	}
+	if len(indexs) == 0 {
+		return
+	}
+
+	var (
+		resp *sendHookResp
+		err  error
+	)
+	if h.grpcPool != nil {
+		resp, err = h.requestGRPC(req)
+	} else {
+		resp, err = h.requestHTTP(req)
+	}
+	if err != nil {
+		h.Warn("request send hook failed", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType), zap.Int("msgCount", len(indexs)), zap.Bool("failOpen", h.s.opts.SendHook.FailOpen))
+		if !h.s.opts.SendHook.FailOpen {
+			for _, idx := range indexs {
+				messages[idx].ReasonCode = wkproto.ReasonSystemError
+			}
+		}
+		return
+	}
+
+	results := make(map[int64]*sendHookResult, len(resp.Results))
+	for _, result := range resp.Results {
+		results[result.MessageId] = result
+	}
+	for _, idx := range indexs {
+		msg := messages[idx]
+		result := results[msg.MessageId]
+		if result == nil { // 没有返回结果视为放行
+			continue
+		}
+		reasonCode := result.reasonCode()
+		if reasonCode != wkproto.ReasonSuccess {
+			h.Debug("message rejected by send hook", zap.Int64("messageId", msg.MessageId), zap.String("fromUid", msg.FromUid), zap.String("channelId", channelId), zap.Uint8("channelType", channelType), zap.String("reasonCode", reasonCode.String()), zap.String("reason", result.Reason))
+			messages[idx].ReasonCode = reasonCode
+			continue
+		}
+		if len(result.Payload) > 0 {
+			msg.SendPacket.Payload = result.Payload
+		}
+	}
+}
+
+func (h *sendHook) requestHTTP(req *sendHookReq) (*sendHookResp, error) {
+	resp, err := h.httpClient.Post(h.s.opts.SendHook.HTTPAddr, "application/json", bytes.NewBufferString(wkutil.ToJSON(req)))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("send hook: response status code is %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	hookResp := &sendHookResp{}
+	if len(body) == 0 { // 没有返回内容视为全部放行
+		return hookResp, nil
+	}
+	err = wkutil.ReadJSONByByte(body, hookResp)
+	if err != nil {
+		return nil, err
+	}
+	return hookResp, nil
+}
+
+func (h *sendHook) requestGRPC(req *sendHookReq) (*sendHookResp, error) {
+	ctx, cancel := context.WithTimeout(h.s.ctx, h.s.opts.SendHook.Timeout)
+	defer cancel()
+	clientConn, err := h.grpcPool.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer clientConn.Close()
+
+	grpcMessages := make([]*wkhook.BeforeSendMessage, 0, len(req.Messages))
+	for _, msg := range req.Messages {
+		grpcMessages = append(grpcMessages, &wkhook.BeforeSendMessage{
+			MessageId:    msg.MessageId,
+			ClientMsgNo:  msg.ClientMsgNo,
+			FromUid:      msg.FromUid,
+			FromDeviceId: msg.FromDeviceId,
+			ChannelId:    msg.ChannelId,
+			ChannelType:  int32(msg.ChannelType),
+			Topic:        msg.Topic,
+			Payload:      msg.Payload,
+		})
+	}
+	cli := wkhook.NewWebhookServiceClient(clientConn)
+	resp, err := cli.BeforeSend(ctx, &wkhook.BeforeSendReq{
+		Messages: grpcMessages,
+	})
+	if err != nil {
+		return nil, err
+	}
+	hookResp := &sendHookResp{
+		Results: make([]*sendHookResult, 0, len(resp.Results)),
+	}
+	for _, result := range resp.Results {
+		hookResp.Results = append(hookResp.Results, &sendHookResult{
+			MessageId:  result.MessageId,
+			ReasonCode: int(result.ReasonCode),
+			Reason:     result.Reason,
+			Payload:    result.Payload,
+		})
+	}
+	return hookResp, nil
+}
+
+// 拦截请求
+type sendHookReq struct {
+	Messages []*sendHookMessage `json:"messages"` // 同一个频道的一批消息
+}
+
+type sendHookMessage struct {
+	MessageId    int64  `json:"message_id"`     // 消息id
+	ClientMsgNo  string `json:"client_msg_no"`  // 客户端消息编号
+	FromUid      string `json:"from_uid"`       // 发送者uid
+	FromDeviceId string `json:"from_device_id"` // 发送者设备id
+	ChannelId    string `json:"channel_id"`     // 频道id
+	ChannelType  uint8  `json:"channel_type"`   // 频道类型
+	Topic        string `json:"topic"`          // 消息topic
+	Payload      []byte `json:"payload"`        // 消息内容（已解密，json中为base64编码）
+}
+
+// 拦截响应
+type sendHookResp struct {
+	Results []*sendHookResult `json:"results"` // 拦截结果，没有返回结果的消息视为放行
+}
+
+type sendHookResult struct {
+	MessageId  int64  `json:"message_id"`  // 消息id
+	ReasonCode int    `json:"reason_code"` // 拦截结果，0或1为放行，其他为拒绝（与sendack的reason code一致，例如 11.不允许发送）
+	Reason     string `json:"reason"`      // 拒绝原因描述
+	Payload    []byte `json:"payload"`     // 放行时不为空则替换消息内容（json中为base64编码）
+}
+
+// 转换为sendack的reason code，无效的reason code视为不允许发送
+func (r *sendHookResult) reasonCode() wkproto.ReasonCode {
+	if r.ReasonCode == int(wkproto.ReasonUnknown) || r.ReasonCode == int(wkproto.ReasonSuccess) {
+		return wkproto.ReasonSuccess
+	}
+	if r.ReasonCode < 0 || r.ReasonCode > int(wkproto.ReasonDisband) {
+		return wkproto.ReasonNotAllowSend
+	}
+	return wkproto.ReasonCode(r.ReasonCode)
+}
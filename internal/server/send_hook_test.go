@@ -0,0 +1,95 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendHookHTTP(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		req := &sendHookReq{}
+		err = wkutil.ReadJSONByByte(body, req)
+		assert.NoError(t, err)
+		// 一批消息一次请求，不需要拦截的消息不发送
+		assert.Len(t, req.Messages, 3)
+
+		resp := sendHookResp{}
+		for _, msg := range req.Messages {
+			switch string(msg.Payload) {
+			case "bad":
+				resp.Results = append(resp.Results, &sendHookResult{MessageId: msg.MessageId, ReasonCode: int(wkproto.ReasonNotAllowSend), Reason: "sensitive"})
+			case "rewrite":
+				resp.Results = append(resp.Results, &sendHookResult{MessageId: msg.MessageId, ReasonCode: int(wkproto.ReasonSuccess), Payload: []byte("***")})
+			}
+		}
+		_, _ = w.Write([]byte(wkutil.ToJSON(resp)))
+	}))
+	defer ts.Close()
+
+	s := &Server{opts: NewOptions(WithSendHookHTTPAddr(ts.URL))}
+	h := newSendHook(s)
+	defer h.stop()
+
+	messages := []ReactorChannelMessage{
+		{MessageId: 1, FromUid: "u1", ReasonCode: wkproto.ReasonSuccess, SendPacket: &wkproto.SendPacket{Payload: []byte("ok")}},
+		{MessageId: 2, FromUid: "u1", ReasonCode: wkproto.ReasonSuccess, SendPacket: &wkproto.SendPacket{Payload: []byte("bad")}},
+		{MessageId: 3, FromUid: "u1", ReasonCode: wkproto.ReasonSuccess, SendPacket: &wkproto.SendPacket{Payload: []byte("rewrite")}},
+		{MessageId: 4, FromUid: "u1", ReasonCode: wkproto.ReasonInBlacklist, SendPacket: &wkproto.SendPacket{Payload: []byte("bad")}},
+	}
+	h.intercept("g1", wkproto.ChannelTypeGroup, messages)
+
+	assert.Equal(t, wkproto.ReasonSuccess, messages[0].ReasonCode)
+	assert.Equal(t, "ok", string(messages[0].SendPacket.Payload))
+	assert.Equal(t, wkproto.ReasonNotAllowSend, messages[1].ReasonCode)
+	assert.Equal(t, wkproto.ReasonSuccess, messages[2].ReasonCode)
+	assert.Equal(t, "***", string(messages[2].SendPacket.Payload))
+	assert.Equal(t, wkproto.ReasonInBlacklist, messages[3].ReasonCode)
+}
+
+func TestSendHookTimeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Millisecond * 200)
+	}))
+	defer ts.Close()
+
+	newMessages := func() []ReactorChannelMessage {
+		return []ReactorChannelMessage{
+			{MessageId: 1, FromUid: "u1", ReasonCode: wkproto.ReasonSuccess, SendPacket: &wkproto.SendPacket{Payload: []byte("ok")}},
+		}
+	}
+
+	// 超时拒绝
+	s := &Server{opts: NewOptions(WithSendHookHTTPAddr(ts.URL), WithSendHookTimeout(time.Millisecond*50))}
+	h := newSendHook(s)
+	messages := newMessages()
+	h.intercept("g1", wkproto.ChannelTypeGroup, messages)
+	assert.Equal(t, wkproto.ReasonSystemError, messages[0].ReasonCode)
+
+	// 超时放行
+	s = &Server{opts: NewOptions(WithSendHookHTTPAddr(ts.URL), WithSendHookTimeout(time.Millisecond*50), WithSendHookFailOpen(true))}
+	h = newSendHook(s)
+	messages = newMessages()
+	h.intercept("g1", wkproto.ChannelTypeGroup, messages)
+	assert.Equal(t, wkproto.ReasonSuccess, messages[0].ReasonCode)
+}
+
+func TestSendHookChannelTypeOn(t *testing.T) {
+	opts := NewOptions(WithSendHookHTTPAddr("http://127.0.0.1"), WithSendHookChannelTypes(wkproto.ChannelTypeGroup))
+	assert.True(t, opts.SendHookChannelTypeOn(wkproto.ChannelTypeGroup))
+	assert.False(t, opts.SendHookChannelTypeOn(wkproto.ChannelTypePerson))
+
+	opts = NewOptions(WithSendHookHTTPAddr("http://127.0.0.1"))
+	assert.True(t, opts.SendHookChannelTypeOn(wkproto.ChannelTypePerson))
+
+	opts = NewOptions()
+	assert.False(t, opts.SendHookChannelTypeOn(wkproto.ChannelTypeGroup))
+}
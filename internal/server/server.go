@@ -49,6 +49,7 @@ type Server struct {
 	userReactor    *userReactor    // 用户的reactor，用于处理用户的行为逻辑
 	channelReactor *channelReactor // 频道的reactor，用户处理频道的行为逻辑
 	webhook        *webhook        // webhook
+	channelWebhook *channelWebhook // 频道自己的webhook
 	trace          *trace.Trace    // 监控
 
 	demoServer    *DemoServer    // demo server
@@ -61,6 +62,35 @@ type Server struct {
 	deliverManager *deliverManager // 消息投递管理
 	retryManager   *retryManager   // 消息重试管理
 
+	tmpChannelManager *tmpChannelManager // 临时频道管理
+
+	channelDisbandManager *channelDisbandManager // 频道解散管理
+
+	userPresenceManager *userPresenceManager // 用户在线状态管理
+
+	presenceSubManager *presenceSubManager // 在线状态订阅管理
+
+	transientManager *transientManager // 瞬态事件管理
+
+	userBanManager *userBanManager // 用户封禁管理
+
+	userRateLimiter *userRateLimiter // 用户发送消息限速
+
+	connLimiter *connLimiter // 用户连接数限制
+
+	clientTokenVerifier *clientTokenVerifier // 客户端连接token（jwt）的验证
+
+	authHook *authHook // 第三方认证服务
+
+	sendHook *sendHook // 消息发送前的拦截服务
+
+	sessionLogManager *sessionLogManager // 用户会话审计日志
+
+	threadManager *threadManager // 子区管理
+
+	mqttGateway   *mqttGateway   // mqtt网关
+	wsJSONGateway *wsJSONGateway // websocket JSON文本协议网关
+
 	conversationManager *ConversationManager // 会话管理
 
 	migrateTask *MigrateTask // 迁移任务
@@ -110,34 +140,84 @@ func New(opts *Options) *Server {
 	storeOpts.Db.MemTableSize = s.opts.Db.MemTableSize
 	s.store = clusterstore.NewStore(storeOpts)
 
+	// 客户端连接token（jwt）的验证
+	if s.opts.ClientJwt.On {
+		s.clientTokenVerifier, err = newClientTokenVerifier(s.opts)
+		if err != nil {
+			s.Panic("new client token verifier error", zap.Error(err))
+		}
+	}
+	// 第三方认证服务
+	if s.opts.AuthHookOn() {
+		s.authHook = newAuthHook(s)
+	}
+	// 消息发送前的拦截服务
+	if s.opts.SendHookOn() {
+		s.sendHook = newSendHook(s)
+	}
+
 	// 数据源
 	s.datasource = NewDatasource(s)
 	// 初始化tag管理
 	s.tagManager = newTagManager(s)
 
 	// 初始化长连接引擎
-	s.engine = wknet.NewEngine(
+	engineOpts := []wknet.Option{
 		wknet.WithAddr(s.opts.Addr),
 		wknet.WithWSAddr(s.opts.WSAddr),
 		wknet.WithWSSAddr(s.opts.WSSAddr),
 		wknet.WithWSTLSConfig(s.opts.WSTLSConfig),
+		wknet.WithMQTTAddr(s.opts.MQTT.Addr),
 		wknet.WithOnReadBytes(func(n int) {
 			trace.GlobalTrace.Metrics.System().ExtranetIncomingAdd(int64(n))
 		}),
 		wknet.WithOnWirteBytes(func(n int) {
 			trace.GlobalTrace.Metrics.System().ExtranetOutgoingAdd(int64(n))
 		}),
-	)
-	s.webhook = newWebhook(s)                         // webhook
-	s.channelReactor = newChannelReactor(s, opts)     // 频道的reactor
-	s.userReactor = newUserReactor(s)                 // 用户的reactor
-	s.demoServer = NewDemoServer(s)                   // demo server
-	s.systemUIDManager = NewSystemUIDManager(s)       // 系统账号管理
-	s.apiServer = NewAPIServer(s)                     // api服务
-	s.managerServer = NewManagerServer(s)             // 管理者的api服务
-	s.retryManager = newRetryManager(s)               // 消息重试管理
-	s.conversationManager = NewConversationManager(s) // 会话管理
-	s.migrateTask = NewMigrateTask(s)                 // 迁移任务
+		wknet.WithOnWSCompress(func(rawN, compressedN int) {
+			trace.GlobalTrace.Metrics.App().WSCompressBytesAdd(int64(rawN), int64(compressedN))
+		}),
+		wknet.WithOnWSDecompress(func(compressedN, rawN int) {
+			trace.GlobalTrace.Metrics.App().WSDecompressBytesAdd(int64(compressedN), int64(rawN))
+		}),
+	}
+	if s.opts.WSJSONOn {
+		if s.opts.WSJSONInsecureOn {
+			engineOpts = append(engineOpts, wknet.WithWSSubprotocols(wsJSONSubprotocol))
+		} else { // 默认只在wss上支持
+			engineOpts = append(engineOpts, wknet.WithWSSSubprotocols(wsJSONSubprotocol))
+		}
+	}
+	if s.opts.WSCompression.On {
+		engineOpts = append(engineOpts,
+			wknet.WithWSCompression(s.opts.WSCompression.Level, s.opts.WSCompression.Threshold),
+			wknet.WithWSCompressionNoContextTakeover(s.opts.WSCompression.ServerNoContextTakeover, s.opts.WSCompression.ClientNoContextTakeover),
+		)
+	}
+	s.engine = wknet.NewEngine(engineOpts...)
+	s.webhook = newWebhook(s)                             // webhook
+	s.channelWebhook = newChannelWebhook(s)               // 频道自己的webhook
+	s.channelReactor = newChannelReactor(s, opts)         // 频道的reactor
+	s.userReactor = newUserReactor(s)                     // 用户的reactor
+	s.demoServer = NewDemoServer(s)                       // demo server
+	s.systemUIDManager = NewSystemUIDManager(s)           // 系统账号管理
+	s.apiServer = NewAPIServer(s)                         // api服务
+	s.managerServer = NewManagerServer(s)                 // 管理者的api服务
+	s.retryManager = newRetryManager(s)                   // 消息重试管理
+	s.tmpChannelManager = newTmpChannelManager(s)         // 临时频道管理
+	s.channelDisbandManager = newChannelDisbandManager(s) // 频道解散管理
+	s.userPresenceManager = newUserPresenceManager(s)     // 用户在线状态管理
+	s.presenceSubManager = newPresenceSubManager(s)       // 在线状态订阅管理
+	s.transientManager = newTransientManager(s)           // 瞬态事件管理
+	s.userBanManager = newUserBanManager(s)               // 用户封禁管理
+	s.userRateLimiter = newUserRateLimiter(s)             // 用户发送消息限速
+	s.connLimiter = newConnLimiter(s)                     // 用户连接数限制
+	s.sessionLogManager = newSessionLogManager(s)         // 用户会话审计日志
+	s.threadManager = newThreadManager(s)                 // 子区管理
+	s.mqttGateway = newMQTTGateway(s)                     // mqtt网关
+	s.wsJSONGateway = newWSJSONGateway(s)                 // websocket JSON文本协议网关
+	s.conversationManager = NewConversationManager(s)     // 会话管理
+	s.migrateTask = NewMigrateTask(s)                     // 迁移任务
 
 	// 初始化分布式服务
 	initNodes := make(map[uint64]string)
@@ -249,6 +329,9 @@ func (s *Server) Start() error {
 	if s.opts.WSSAddr != "" {
 		s.Info(fmt.Sprintf("Listening  for WSS client on %s", s.opts.WSSAddr))
 	}
+	if s.opts.MQTT.Addr != "" {
+		s.Info(fmt.Sprintf("Listening  for MQTT client on %s", s.opts.MQTT.Addr))
+	}
 	s.Info(fmt.Sprintf("Listening  for Manager http api on %s", fmt.Sprintf("http://%s", s.opts.HTTPAddr)))
 
 	if s.opts.Manager.On {
@@ -318,6 +401,58 @@ func (s *Server) Start() error {
 		return err
 	}
 
+	err = s.tmpChannelManager.start()
+	if err != nil {
+		return err
+	}
+
+	err = s.channelDisbandManager.start()
+	if err != nil {
+		return err
+	}
+
+	err = s.presenceSubManager.start()
+	if err != nil {
+		return err
+	}
+
+	err = s.transientManager.start()
+	if err != nil {
+		return err
+	}
+
+	err = s.userBanManager.start()
+	if err != nil {
+		return err
+	}
+
+	err = s.userRateLimiter.start()
+	if err != nil {
+		return err
+	}
+
+	if s.authHook != nil {
+		err = s.authHook.start()
+		if err != nil {
+			return err
+		}
+	}
+
+	err = s.sessionLogManager.start()
+	if err != nil {
+		return err
+	}
+
+	err = s.threadManager.start()
+	if err != nil {
+		return err
+	}
+
+	err = s.channelWebhook.start()
+	if err != nil {
+		return err
+	}
+
 	if s.opts.Conversation.On {
 		err = s.conversationManager.Start()
 		if err != nil {
@@ -357,6 +492,32 @@ func (s *Server) Stop() error {
 
 	s.retryManager.stop()
 
+	s.tmpChannelManager.stop()
+
+	s.channelDisbandManager.stop()
+
+	s.userPresenceManager.stop()
+
+	s.presenceSubManager.stop()
+
+	s.transientManager.stop()
+
+	s.userBanManager.stop()
+
+	s.userRateLimiter.stop()
+
+	if s.authHook != nil {
+		s.authHook.stop()
+	}
+
+	if s.sendHook != nil {
+		s.sendHook.stop()
+	}
+
+	s.sessionLogManager.stop()
+
+	s.threadManager.stop()
+
 	if s.opts.Conversation.On {
 		s.conversationManager.Stop()
 	}
@@ -378,14 +539,16 @@ func (s *Server) Stop() error {
 	}
 	s.trace.Stop()
 
+	s.webhook.Stop() // 需要在存储关闭前停止，等待发件箱的投递协程退出
+
+	s.channelWebhook.stop() // 需要在webhook停止后、存储关闭前停止，写入剩余的推送统计
+
 	s.store.Close()
 
 	s.timingWheel.Stop()
 
 	s.tagManager.stop()
 
-	s.webhook.Stop()
-
 	if s.opts.LokiOn() {
 		s.promtailServer.Stop()
 	}
@@ -471,11 +634,23 @@ func (s *Server) onClose(conn wknet.Conn) {
 	if connCtxObj != nil {
 		connCtx := connCtxObj.(*connContext)
 		s.userReactor.removeConnById(connCtx.uid, connCtx.connId)
+		s.presenceSubManager.removeConn(connCtx.connId)
 
 		if connCtx.isAuth.Load() {
 			deviceOnlineCount := s.userReactor.getConnCountByDeviceFlag(connCtx.uid, connCtx.deviceFlag)
 			totalOnlineCount := s.userReactor.getConnCount(connCtx.uid)
 			s.webhook.Offline(connCtx.uid, wkproto.DeviceFlag(connCtx.deviceFlag), connCtx.connId, deviceOnlineCount, totalOnlineCount) // 触发离线webhook
+			// 记录最后在线时间
+			s.userPresenceManager.updateLastSeen(connCtx.uid)
+			// 最后一个连接下线时通知在线状态的订阅者
+			if s.userReactor.getAuthConnCount(connCtx.uid) == 0 {
+				s.presenceSubManager.onPresenceChange(connCtx.uid, connCtx.deviceFlag, false)
+			}
+			// 会话审计日志
+			s.sessionLogManager.recordDisconnect(connCtx)
+		}
+		if connCtx.mqtt != nil {
+			s.mqttGateway.onClose(connCtx)
 		}
 
 	}
@@ -2,9 +2,11 @@ package server
 
 import (
 	"context"
+	"encoding/binary"
 	"errors"
 	"time"
 
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
 	"github.com/WuKongIM/WuKongIM/pkg/wkserver"
 	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
 	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
@@ -57,10 +59,55 @@ func (s *Server) setClusterRoutes() {
 	s.cluster.Route("/wk/allowSend", s.handleAllowSend)
 	// 获取订阅者
 	s.cluster.Route("/wk/getSubscribers", s.handleGetSubscribers)
+	// 过滤出属于频道订阅者的用户
+	s.cluster.Route("/wk/existSubscribers", s.handleExistSubscribers)
+	// 超大频道的订阅者变化（更新本节点的订阅关系缓存）
+	s.cluster.Route("/wk/broadcastSubscriberChange", s.handleBroadcastSubscriberChange)
 
 	// 频道重新创建ReceiverTag
 	s.cluster.Route("/wk/makeReceiverTag", s.handleMakeReceiverTag)
 
+	// 获取频道最后一条消息的时间
+	s.cluster.Route("/wk/channelLastMsgTime", s.handleChannelLastMsgTime)
+
+	// 清除本节点上的频道消息
+	s.cluster.Route("/wk/channelClear", s.handleChannelClear)
+	s.cluster.Route("/wk/tmpChannelExist", s.handleTmpChannelExist)
+
+	// 更新本节点内存中的频道信息
+	s.cluster.Route("/wk/channelInfoUpdate", s.handleChannelInfoUpdate)
+
+	// 推送在线状态事件给本节点的订阅连接
+	s.cluster.Route("/wk/presenceNotify", s.handlePresenceNotify)
+	// 转发在线状态事件给订阅了的节点（用户所属槽的领导节点）
+	s.cluster.Route("/wk/presenceRoute", s.handlePresenceRoute)
+	// 登记节点的在线状态订阅（用户所属槽的领导节点）
+	s.cluster.Route("/wk/presenceInterest", s.handlePresenceInterest)
+
+	// 处理瞬态事件（频道所属槽的领导节点）
+	s.cluster.Route("/wk/transientSend", s.handleTransientSend)
+	// 投递瞬态事件给本节点的在线用户
+	s.cluster.Route("/wk/transientDeliver", s.handleTransientDeliver)
+
+	// 用户封禁变化通知
+	s.cluster.Route("/wk/userBanNotify", s.handleUserBanNotify)
+	// 获取用户的封禁记录（用户所属槽的领导节点）
+	s.cluster.Route("/wk/userBanGet", s.handleUserBanGet)
+	// 获取本节点作为槽领导的用户封禁列表
+	s.cluster.Route("/wk/userBans", s.handleUserBans)
+
+	// 子区的根消息是否存在（父频道的领导节点）
+	s.cluster.Route("/wk/threadRootExist", s.handleThreadRootExist)
+	// 获取子区根消息的回复统计（父频道所在槽的领导节点）
+	s.cluster.Route("/wk/threadReply", s.handleThreadReply)
+
+	// 获取webhook端点列表（槽位0的领导节点，包含密钥）
+	s.cluster.Route("/wk/webhookEndpoints", s.handleWebhookEndpoints)
+	// 更新本节点缓存的webhook端点
+	s.cluster.Route("/wk/webhookEndpointsSetCache", s.handleWebhookEndpointsSetCache)
+
+	// 获取频道的mqtt保留消息（频道所在槽的领导节点）
+	s.cluster.Route("/wk/mqttRetainGet", s.handleMQTTRetainGet)
 }
 
 func (s *Server) handleChannelForward(c *wkserver.Context) {
@@ -524,6 +571,24 @@ func (s *Server) handleGetSubscribers(c *wkserver.Context) {
 	c.Write(resps.Marshal())
 }
 
+func (s *Server) handleExistSubscribers(c *wkserver.Context) {
+	req := &subscriberExistReq{}
+	err := req.Unmarshal(c.Body())
+	if err != nil {
+		s.Error("handleExistSubscribers Unmarshal err", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+
+	subscribers, err := s.existSubscribers(req.ChannelId, req.ChannelType, req.Uids)
+	if err != nil {
+		s.Error("handleExistSubscribers: existSubscribers failed", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	c.Write(subscriberGetResp(subscribers).Marshal())
+}
+
 func (s *Server) handleMakeReceiverTag(c *wkserver.Context) {
 	req := &channelReq{}
 	err := req.Unmarshal(c.Body())
@@ -546,3 +611,293 @@ func (s *Server) handleMakeReceiverTag(c *wkserver.Context) {
 	}
 	c.WriteOk()
 }
+
+func (s *Server) handleBroadcastSubscriberChange(c *wkserver.Context) {
+	change := &broadcastSubscriberChange{}
+	err := wkutil.ReadJSONByByte(c.Body(), change)
+	if err != nil {
+		s.Error("handleBroadcastSubscriberChange Unmarshal err", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	change.apply(s.deliverManager.broadcastSubscribers)
+	c.WriteOk()
+}
+
+func (s *Server) handleChannelLastMsgTime(c *wkserver.Context) {
+	req := &channelReq{}
+	err := req.Unmarshal(c.Body())
+	if err != nil {
+		s.Error("handleChannelLastMsgTime Unmarshal err", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+
+	_, lastMsgTime, err := s.store.DB().GetChannelLastMessageSeq(req.ChannelId, req.ChannelType)
+	if err != nil {
+		s.Error("handleChannelLastMsgTime: GetChannelLastMessageSeq failed", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, lastMsgTime)
+	c.Write(data)
+}
+
+func (s *Server) handleTmpChannelExist(c *wkserver.Context) {
+	req := &channelReq{}
+	err := req.Unmarshal(c.Body())
+	if err != nil {
+		s.Error("handleTmpChannelExist Unmarshal err", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	exist, err := s.tmpChannelManager.localExist(req.ChannelId)
+	if err != nil {
+		s.Error("handleTmpChannelExist: localExist failed", zap.Error(err), zap.String("channelId", req.ChannelId))
+		c.WriteErr(err)
+		return
+	}
+	if exist {
+		c.Write([]byte{1})
+		return
+	}
+	c.Write([]byte{0})
+}
+
+func (s *Server) handleChannelClear(c *wkserver.Context) {
+	req := &channelReq{}
+	err := req.Unmarshal(c.Body())
+	if err != nil {
+		s.Error("handleChannelClear Unmarshal err", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+
+	err = s.clearLocalChannel(req.ChannelId, req.ChannelType)
+	if err != nil {
+		s.Error("handleChannelClear: clearLocalChannel failed", zap.Error(err), zap.String("channelId", req.ChannelId), zap.Uint8("channelType", req.ChannelType))
+		c.WriteErr(err)
+		return
+	}
+	c.WriteOk()
+}
+
+func (s *Server) handleChannelInfoUpdate(c *wkserver.Context) {
+	var channelInfo wkdb.ChannelInfo
+	err := wkutil.ReadJSONByByte(c.Body(), &channelInfo)
+	if err != nil {
+		s.Error("handleChannelInfoUpdate Unmarshal err", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	s.updateLocalChannelInfo(channelInfo)
+	c.WriteOk()
+}
+
+// 处理其他节点广播过来的在线状态事件
+func (s *Server) handlePresenceNotify(c *wkserver.Context) {
+	var events []*presenceEvent
+	err := wkutil.ReadJSONByByte(c.Body(), &events)
+	if err != nil {
+		s.Error("handlePresenceNotify Unmarshal err", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	s.presenceSubManager.notifyLocal(events)
+	c.WriteOk()
+}
+
+func (s *Server) handlePresenceRoute(c *wkserver.Context) {
+	req := &presenceRouteReq{}
+	err := wkutil.ReadJSONByByte(c.Body(), req)
+	if err != nil {
+		s.Error("handlePresenceRoute Unmarshal err", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	s.presenceSubManager.notifyLocal(req.Events)
+	s.presenceSubManager.route(req.FromNodeId, req.Events)
+	c.WriteOk()
+}
+
+func (s *Server) handlePresenceInterest(c *wkserver.Context) {
+	req := &presenceInterestReq{}
+	err := wkutil.ReadJSONByByte(c.Body(), req)
+	if err != nil {
+		s.Error("handlePresenceInterest Unmarshal err", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	s.presenceSubManager.applyInterest(req.NodeId, req.Interests)
+	c.WriteOk()
+}
+
+func (s *Server) handleTransientSend(c *wkserver.Context) {
+	event := &transientEvent{}
+	err := wkutil.ReadJSONByByte(c.Body(), event)
+	if err != nil {
+		s.Error("handleTransientSend Unmarshal err", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	reasonCode, err := s.transientManager.handle(event)
+	if err != nil {
+		s.Error("handleTransientSend: handle failed", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	if reasonCode == wkproto.ReasonSuccess {
+		c.WriteOk()
+		return
+	}
+	c.WriteErrorAndStatus(errors.New("transient event not allow send"), proto.Status(reasonCode))
+}
+
+func (s *Server) handleTransientDeliver(c *wkserver.Context) {
+	req := &transientDeliverReq{}
+	err := wkutil.ReadJSONByByte(c.Body(), req)
+	if err != nil {
+		s.Error("handleTransientDeliver Unmarshal err", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	if req.Event == nil {
+		c.WriteErr(errors.New("event is nil"))
+		return
+	}
+	s.transientManager.deliverLocal(req.Event, req.Uids)
+	c.WriteOk()
+}
+
+func (s *Server) handleUserBanNotify(c *wkserver.Context) {
+	notify := &userBanNotify{}
+	err := wkutil.ReadJSONByByte(c.Body(), notify)
+	if err != nil {
+		s.Error("handleUserBanNotify Unmarshal err", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	s.userBanManager.handleNotify(notify)
+	c.WriteOk()
+}
+
+func (s *Server) handleUserBanGet(c *wkserver.Context) {
+	uid := string(c.Body())
+	userBan, err := s.userBanManager.getLocalBan(uid)
+	if err != nil {
+		s.Error("handleUserBanGet: getLocalBan failed", zap.Error(err), zap.String("uid", uid))
+		c.WriteErr(err)
+		return
+	}
+	c.Write([]byte(wkutil.ToJSON(newUserBanNotify(uid, userBan))))
+}
+
+func (s *Server) handleUserBans(c *wkserver.Context) {
+	userBans, err := s.userBanManager.getLeaderBans()
+	if err != nil {
+		s.Error("handleUserBans: getLeaderBans failed", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	resps := make([]*userBanResp, 0, len(userBans))
+	for _, userBan := range userBans {
+		resps = append(resps, newUserBanResp(userBan))
+	}
+	c.Write([]byte(wkutil.ToJSON(resps)))
+}
+
+func (s *Server) handleThreadRootExist(c *wkserver.Context) {
+	req := &threadRootReq{}
+	err := wkutil.ReadJSONByByte(c.Body(), req)
+	if err != nil {
+		s.Error("handleThreadRootExist Unmarshal err", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	exist, err := s.threadManager.localRootExists(req.ChannelId, req.ChannelType, req.RootMessageId)
+	if err != nil {
+		s.Error("handleThreadRootExist: localRootExists failed", zap.Error(err), zap.Int64("rootMessageId", req.RootMessageId))
+		c.WriteErr(err)
+		return
+	}
+	if exist {
+		c.Write([]byte{1})
+		return
+	}
+	c.Write([]byte{0})
+}
+
+func (s *Server) handleThreadReply(c *wkserver.Context) {
+	req := &threadRootReq{}
+	err := wkutil.ReadJSONByByte(c.Body(), req)
+	if err != nil {
+		s.Error("handleThreadReply Unmarshal err", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	threadReply, err := s.threadManager.getLocalReply(req.RootMessageId)
+	if err != nil {
+		s.Error("handleThreadReply: getLocalReply failed", zap.Error(err), zap.Int64("rootMessageId", req.RootMessageId))
+		c.WriteErr(err)
+		return
+	}
+	if threadReply == nil {
+		c.Write(nil)
+		return
+	}
+	data, err := threadReply.Marshal()
+	if err != nil {
+		c.WriteErr(err)
+		return
+	}
+	c.Write(data)
+}
+
+func (s *Server) handleWebhookEndpoints(c *wkserver.Context) {
+	endpoints, err := s.store.GetWebhookEndpoints()
+	if err != nil {
+		s.Error("handleWebhookEndpoints: GetWebhookEndpoints failed", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	c.Write([]byte(wkutil.ToJSON(endpoints)))
+}
+
+func (s *Server) handleWebhookEndpointsSetCache(c *wkserver.Context) {
+	var endpoints []wkdb.WebhookEndpoint
+	err := wkutil.ReadJSONByByte(c.Body(), &endpoints)
+	if err != nil {
+		s.Error("handleWebhookEndpointsSetCache Unmarshal err", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	s.webhook.endpoints.setCache(endpoints)
+	c.WriteOk()
+}
+
+func (s *Server) handleMQTTRetainGet(c *wkserver.Context) {
+	req := &channelReq{}
+	err := req.Unmarshal(c.Body())
+	if err != nil {
+		s.Error("handleMQTTRetainGet Unmarshal err", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	retain, err := s.mqttGateway.getLocalRetained(req.ChannelId, req.ChannelType)
+	if err != nil {
+		s.Error("handleMQTTRetainGet: getLocalRetained failed", zap.Error(err), zap.String("channelId", req.ChannelId), zap.Uint8("channelType", req.ChannelType))
+		c.WriteErr(err)
+		return
+	}
+	if retain == nil {
+		c.Write(nil)
+		return
+	}
+	data, err := retain.Marshal()
+	if err != nil {
+		c.WriteErr(err)
+		return
+	}
+	c.Write(data)
+}
@@ -91,6 +91,10 @@ func (s *APIServer) setRoutes() {
 	varz := NewVarzAPI(s.s)
 	varz.Route(s.r)
 
+	// webhook
+	webhook := NewWebhookAPI(s.s)
+	webhook.Route(s.r)
+
 	// 用户相关API
 	u := NewUserAPI(s.s)
 	u.Route(s.r)
@@ -107,6 +111,10 @@ func (s *APIServer) setRoutes() {
 	message := NewMessageAPI(s.s)
 	message.Route(s.r)
 
+	// 子区api
+	thread := NewThreadAPI(s.s)
+	thread.Route(s.r)
+
 	// 路由api
 	routeapi := NewRouteAPI(s.s)
 	routeapi.Route(s.r)
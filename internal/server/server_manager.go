@@ -86,6 +86,10 @@ func (m *ManagerServer) setRoutes() {
 	varz := NewVarzAPI(m.s)
 	varz.Route(m.r)
 
+	// webhook
+	webhook := NewWebhookAPI(m.s)
+	webhook.Route(m.r)
+
 	// 管理者api
 	manager := NewManagerAPI(m.s)
 	manager.Route(m.r)
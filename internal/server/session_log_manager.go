@@ -0,0 +1,249 @@
+package server
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/RussellLuo/timingwheel"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+// 用户会话审计日志
+// 记录连接认证成功、认证失败（用户的槽领导节点）、连接被踢和连接断开（连接所在节点）的事件，
+// 事件先缓存在内存中，按FlushInterval批量提案到用户所在的槽（按槽分组），写入失败的日志在MaxBuffer内放回缓存下次重试，停止时写入剩余的日志。
+// 日志id为记录节点的纳秒时间（只在本节点内唯一），日志的key包含记录日志的节点id，不同节点写入同一个槽的日志不会冲突。
+// 日志表按天分区，每个节点各自清理过期的分区。
+type sessionLogManager struct {
+	s *Server
+	wklog.Log
+
+	mu     sync.Mutex
+	logs   []wkdb.SessionLog // 待写入的日志
+	lastId uint64            // 最后分配的日志id
+
+	flushTimer *timingwheel.Timer
+	purgeTimer *timingwheel.Timer
+	flushing   atomic.Bool
+}
+
+// 过期日志的清理间隔
+const sessionLogPurgeInterval = time.Hour
+
+func newSessionLogManager(s *Server) *sessionLogManager {
+	return &sessionLogManager{
+		s:   s,
+		Log: wklog.NewWKLog("sessionLogManager"),
+	}
+}
+
+func (m *sessionLogManager) start() error {
+	if !m.s.opts.SessionLog.On {
+		return nil
+	}
+	m.flushTimer = m.s.Schedule(m.s.opts.SessionLog.FlushInterval, func() {
+		if !m.flushing.CompareAndSwap(false, true) { // 上一次写入还没结束
+			return
+		}
+		go func() {
+			defer m.flushing.Store(false)
+			m.flush()
+		}()
+	})
+	m.purgeTimer = m.s.Schedule(sessionLogPurgeInterval, m.purge)
+	return nil
+}
+
+func (m *sessionLogManager) stop() {
+	if m.flushTimer != nil {
+		m.flushTimer.Stop()
+	}
+	if m.purgeTimer != nil {
+		m.purgeTimer.Stop()
+	}
+	if !m.s.opts.SessionLog.On {
+		return
+	}
+	// 等待正在进行的写入结束后写入剩余的日志
+	for !m.flushing.CompareAndSwap(false, true) {
+		time.Sleep(time.Millisecond * 10)
+	}
+	m.flush()
+	m.flushing.Store(false)
+}
+
+// 记录连接认证的结果（在用户的槽领导节点上）
+func (m *sessionLogManager) recordAuth(uid string, msg ReactorUserMessage, reasonCode wkproto.ReasonCode, err error) {
+	if !m.s.opts.SessionLog.On {
+		return
+	}
+	connectPacket, ok := msg.InPacket.(*wkproto.ConnectPacket)
+	if !ok {
+		return
+	}
+	log := wkdb.SessionLog{
+		Uid:        uid,
+		Event:      wkdb.SessionEventConnect,
+		NodeId:     msg.FromNodeId,
+		DeviceFlag: uint64(connectPacket.DeviceFlag),
+		DeviceId:   connectPacket.DeviceID,
+		Ip:         msg.ConnMeta.IP,
+		ReasonCode: uint8(reasonCode),
+	}
+	if reasonCode != wkproto.ReasonSuccess {
+		log.Event = wkdb.SessionEventAuthFail
+		if err != nil {
+			log.Reason = err.Error()
+		}
+	}
+	m.record(log)
+}
+
+// 记录连接被踢
+func (m *sessionLogManager) recordKick(conn *connContext, reasonCode wkproto.ReasonCode, reason string) {
+	m.recordConn(wkdb.SessionEventKick, conn, reasonCode, reason)
+}
+
+// 记录连接断开（连接所在节点）
+func (m *sessionLogManager) recordDisconnect(conn *connContext) {
+	m.recordConn(wkdb.SessionEventDisconnect, conn, 0, "")
+}
+
+func (m *sessionLogManager) recordConn(event string, conn *connContext, reasonCode wkproto.ReasonCode, reason string) {
+	if !m.s.opts.SessionLog.On {
+		return
+	}
+	nodeId := m.s.opts.Cluster.NodeId
+	if !conn.isRealConn {
+		nodeId = conn.realNodeId
+	}
+	ip := conn.clientIp
+	if ip == "" && conn.isRealConn {
+		ip = conn.connMeta().IP
+	}
+	m.record(wkdb.SessionLog{
+		Uid:        conn.uid,
+		Event:      event,
+		NodeId:     nodeId,
+		DeviceFlag: uint64(conn.deviceFlag),
+		DeviceId:   conn.deviceId,
+		Ip:         ip,
+		ReasonCode: uint8(reasonCode),
+		Reason:     reason,
+	})
+}
+
+func (m *sessionLogManager) record(log wkdb.SessionLog) {
+	if m.s.systemUIDManager.SystemUID(log.Uid) || log.Uid == m.s.opts.ManagerUID {
+		return
+	}
+	now := time.Now()
+	log.CreatedAt = &now
+
+	m.mu.Lock()
+	if len(m.logs) >= m.s.opts.SessionLog.MaxBuffer {
+		m.mu.Unlock()
+		m.Warn("session log buffer is full, drop", zap.String("uid", log.Uid), zap.String("event", log.Event))
+		return
+	}
+	// 日志id为纳秒时间，保证本节点分配的id递增不重复
+	log.Id = uint64(now.UnixNano())
+	if log.Id <= m.lastId {
+		log.Id = m.lastId + 1
+	}
+	m.lastId = log.Id
+	log.RecordNodeId = m.s.opts.Cluster.NodeId
+	m.logs = append(m.logs, log)
+	m.mu.Unlock()
+
+	if m.s.opts.SessionLog.WebhookOn {
+		m.s.webhook.TriggerEvent(&Event{
+			Event:    EventUserSession,
+			OrderKey: log.Uid, // 同一个用户的会话事件按顺序投递
+			Data:     newSessionLogResp(log),
+		})
+	}
+}
+
+// 批量写入缓存的日志
+func (m *sessionLogManager) flush() {
+	m.mu.Lock()
+	if len(m.logs) == 0 {
+		m.mu.Unlock()
+		return
+	}
+	logs := m.logs
+	m.logs = nil
+	m.mu.Unlock()
+
+	err := m.s.store.AddSessionLogs(logs)
+	if err != nil {
+		m.Error("add session logs failed", zap.Error(err), zap.Int("count", len(logs)))
+		m.requeue(logs)
+	}
+}
+
+// 写入失败的日志放回缓存的前面（保持顺序），超过MaxBuffer时丢弃最早的日志
+func (m *sessionLogManager) requeue(logs []wkdb.SessionLog) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	room := m.s.opts.SessionLog.MaxBuffer - len(m.logs)
+	if room < len(logs) {
+		dropCount := len(logs)
+		if room > 0 {
+			dropCount = len(logs) - room
+		}
+		m.Warn("session log buffer is full, drop failed logs", zap.Int("count", dropCount))
+		logs = logs[dropCount:]
+	}
+	if len(logs) == 0 {
+		return
+	}
+	m.logs = append(logs, m.logs...)
+}
+
+// 清理本节点过期的日志
+func (m *sessionLogManager) purge() {
+	if m.s.opts.SessionLog.Retention <= 0 {
+		return
+	}
+	err := m.s.store.RemoveSessionLogsBefore(time.Now().Add(-m.s.opts.SessionLog.Retention))
+	if err != nil {
+		m.Error("remove expired session logs failed", zap.Error(err))
+	}
+}
+
+// 会话审计日志（api和webhook的数据）
+type sessionLogResp struct {
+	Id         uint64 `json:"id"`
+	Uid        string `json:"uid"`         // 用户uid
+	Event      string `json:"event"`       // 事件类型 connect: 连接认证成功 auth_fail: 认证失败 kick: 被踢 disconnect: 断开
+	NodeId     uint64 `json:"node_id"`     // 连接所在的节点
+	DeviceFlag uint64 `json:"device_flag"` // 设备标记
+	DeviceId   string `json:"device_id"`   // 设备id
+	Ip         string `json:"ip"`          // 客户端ip
+	ReasonCode uint8  `json:"reason_code"` // 原因码
+	Reason     string `json:"reason"`      // 原因
+	CreatedAt  int64  `json:"created_at"`  // 事件时间（毫秒）
+}
+
+func newSessionLogResp(log wkdb.SessionLog) *sessionLogResp {
+	resp := &sessionLogResp{
+		Id:         log.Id,
+		Uid:        log.Uid,
+		Event:      log.Event,
+		NodeId:     log.NodeId,
+		DeviceFlag: log.DeviceFlag,
+		DeviceId:   log.DeviceId,
+		Ip:         log.Ip,
+		ReasonCode: log.ReasonCode,
+		Reason:     log.Reason,
+	}
+	if log.CreatedAt != nil {
+		resp.CreatedAt = log.CreatedAt.UnixMilli()
+	}
+	return resp
+}
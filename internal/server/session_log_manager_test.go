@@ -0,0 +1,28 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionLogRequeue(t *testing.T) {
+	opts := NewOptions()
+	opts.SessionLog.MaxBuffer = 3
+	m := newSessionLogManager(&Server{opts: opts})
+
+	// 写入失败的日志放回缓存的前面
+	m.logs = []wkdb.SessionLog{{Id: 3}}
+	m.requeue([]wkdb.SessionLog{{Id: 1}, {Id: 2}})
+	assert.Equal(t, []wkdb.SessionLog{{Id: 1}, {Id: 2}, {Id: 3}}, m.logs)
+
+	// 超过MaxBuffer时丢弃最早的日志
+	m.logs = []wkdb.SessionLog{{Id: 4}, {Id: 5}}
+	m.requeue([]wkdb.SessionLog{{Id: 1}, {Id: 2}, {Id: 3}})
+	assert.Equal(t, []wkdb.SessionLog{{Id: 3}, {Id: 4}, {Id: 5}}, m.logs)
+
+	// 缓存已满时全部丢弃
+	m.requeue([]wkdb.SessionLog{{Id: 6}})
+	assert.Equal(t, []wkdb.SessionLog{{Id: 3}, {Id: 4}, {Id: 5}}, m.logs)
+}
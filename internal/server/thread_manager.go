@@ -0,0 +1,253 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/RussellLuo/timingwheel"
+	cluster "github.com/WuKongIM/WuKongIM/pkg/cluster/clusterserver"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+// 子区管理
+// 子区的根消息必须是父频道内的消息，在父频道的领导节点上校验，校验通过的根消息缓存在本节点。
+// 子区消息存储成功后（子区频道的领导节点）更新根消息的回复统计，统计先在内存中合并，定时提案到父频道所在的槽。
+type threadManager struct {
+	s *Server
+	wklog.Log
+
+	rootLock sync.RWMutex
+	roots    map[threadRootKey]struct{} // 已校验存在的根消息
+
+	mu      sync.Mutex
+	replies map[int64]wkdb.ThreadReply // 待更新的回复统计，key为根消息ID
+
+	flushTimer *timingwheel.Timer
+	flushing   atomic.Bool
+}
+
+const (
+	threadReplyFlushInterval = time.Second // 回复统计的提案间隔
+	threadRootCacheMaxSize   = 100000      // 根消息缓存的最大数量，超过后清空重新缓存
+)
+
+func newThreadManager(s *Server) *threadManager {
+	return &threadManager{
+		s:       s,
+		Log:     wklog.NewWKLog("threadManager"),
+		roots:   make(map[threadRootKey]struct{}),
+		replies: make(map[int64]wkdb.ThreadReply),
+	}
+}
+
+func (t *threadManager) start() error {
+	t.flushTimer = t.s.Schedule(threadReplyFlushInterval, func() {
+		if !t.flushing.CompareAndSwap(false, true) { // 上一次提案还没结束
+			return
+		}
+		go func() {
+			defer t.flushing.Store(false)
+			t.flush()
+		}()
+	})
+	return nil
+}
+
+func (t *threadManager) stop() {
+	if t.flushTimer != nil {
+		t.flushTimer.Stop()
+	}
+	// 等待正在进行的提案结束，再提案剩余的回复统计
+	for !t.flushing.CompareAndSwap(false, true) {
+		time.Sleep(time.Millisecond * 10)
+	}
+	t.flush()
+	t.flushing.Store(false)
+}
+
+// 根消息是否存在于父频道内
+func (t *threadManager) rootExists(channelId string, channelType uint8, rootMessageId int64) (bool, error) {
+	rootKey := threadRootKey{channelId: channelId, channelType: channelType, rootMessageId: rootMessageId}
+	t.rootLock.RLock()
+	_, ok := t.roots[rootKey]
+	t.rootLock.RUnlock()
+	if ok {
+		return true, nil
+	}
+
+	leaderInfo, err := t.s.cluster.LeaderOfChannelForRead(channelId, channelType)
+	if errors.Is(err, cluster.ErrChannelClusterConfigNotFound) { // 父频道还没有消息
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	var exist bool
+	if t.s.opts.IsLocalNode(leaderInfo.Id) {
+		exist, err = t.localRootExists(channelId, channelType, rootMessageId)
+	} else {
+		exist, err = t.requestRootExists(leaderInfo.Id, channelId, channelType, rootMessageId)
+	}
+	if err != nil {
+		return false, err
+	}
+	if exist {
+		t.rootLock.Lock()
+		if len(t.roots) >= threadRootCacheMaxSize {
+			t.roots = make(map[threadRootKey]struct{})
+		}
+		t.roots[rootKey] = struct{}{}
+		t.rootLock.Unlock()
+	}
+	return exist, nil
+}
+
+// 本节点存储的父频道消息中是否有根消息（父频道的领导节点）
+func (t *threadManager) localRootExists(channelId string, channelType uint8, rootMessageId int64) (bool, error) {
+	msg, err := t.s.store.DB().GetMessage(uint64(rootMessageId))
+	if err == wkdb.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return msg.ChannelID == channelId && msg.ChannelType == channelType, nil
+}
+
+func (t *threadManager) requestRootExists(nodeId uint64, channelId string, channelType uint8, rootMessageId int64) (bool, error) {
+	timeoutCtx, cancel := context.WithTimeout(t.s.ctx, time.Second*5)
+	defer cancel()
+
+	req := &threadRootReq{
+		ChannelId:     channelId,
+		ChannelType:   channelType,
+		RootMessageId: rootMessageId,
+	}
+	resp, err := t.s.cluster.RequestWithContext(timeoutCtx, nodeId, "/wk/threadRootExist", []byte(wkutil.ToJSON(req)))
+	if err != nil {
+		return false, err
+	}
+	if resp.Status != proto.StatusOK {
+		return false, fmt.Errorf("requestRootExists: response status code is %d", resp.Status)
+	}
+	return len(resp.Body) > 0 && resp.Body[0] == 1, nil
+}
+
+// 子区消息存储成功（子区频道的领导节点），记录根消息最新的回复统计
+func (t *threadManager) onStored(threadChannelId string, channelType uint8, messages []ReactorChannelMessage) {
+	var lastMsg *ReactorChannelMessage
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].ReasonCode == wkproto.ReasonSuccess && messages[i].MessageSeq > 0 {
+			lastMsg = &messages[i]
+			break
+		}
+	}
+	if lastMsg == nil {
+		return
+	}
+	channelId, rootMessageId := t.s.opts.ThreadChannelConvertParent(threadChannelId)
+	if rootMessageId <= 0 {
+		return
+	}
+	threadReply := wkdb.ThreadReply{
+		RootMessageId:       rootMessageId,
+		ChannelId:           channelId,
+		ChannelType:         channelType,
+		ReplyCount:          uint64(lastMsg.MessageSeq),
+		LastReplyMessageId:  lastMsg.MessageId,
+		LastReplyMessageSeq: uint64(lastMsg.MessageSeq),
+		LastReplyFromUid:    lastMsg.FromUid,
+		LastReplyAt:         time.Now().Unix(),
+	}
+	t.mu.Lock()
+	if old, ok := t.replies[rootMessageId]; !ok || old.ReplyCount < threadReply.ReplyCount {
+		t.replies[rootMessageId] = threadReply
+	}
+	t.mu.Unlock()
+}
+
+// 将回复统计提案到父频道所在的槽
+func (t *threadManager) flush() {
+	t.mu.Lock()
+	if len(t.replies) == 0 {
+		t.mu.Unlock()
+		return
+	}
+	replies := t.replies
+	t.replies = make(map[int64]wkdb.ThreadReply)
+	t.mu.Unlock()
+
+	for _, threadReply := range replies {
+		if err := t.s.store.UpdateThreadReply(threadReply); err != nil {
+			t.Error("UpdateThreadReply failed", zap.Error(err), zap.Int64("rootMessageId", threadReply.RootMessageId), zap.String("channelId", threadReply.ChannelId))
+		}
+	}
+}
+
+// 获取根消息的回复统计（没有回复返回nil）
+func (t *threadManager) getReply(channelId string, channelType uint8, rootMessageId int64) (*wkdb.ThreadReply, error) {
+	leaderId, err := t.s.cluster.SlotLeaderIdOfChannel(channelId, channelType)
+	if err != nil {
+		return nil, err
+	}
+	if t.s.opts.IsLocalNode(leaderId) {
+		return t.getLocalReply(rootMessageId)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(t.s.ctx, time.Second*5)
+	defer cancel()
+	req := &threadRootReq{
+		ChannelId:     channelId,
+		ChannelType:   channelType,
+		RootMessageId: rootMessageId,
+	}
+	resp, err := t.s.cluster.RequestWithContext(timeoutCtx, leaderId, "/wk/threadReply", []byte(wkutil.ToJSON(req)))
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != proto.StatusOK {
+		return nil, fmt.Errorf("getReply: response status code is %d", resp.Status)
+	}
+	if len(resp.Body) == 0 {
+		return nil, nil
+	}
+	threadReply := &wkdb.ThreadReply{}
+	if err = threadReply.Unmarshal(resp.Body); err != nil {
+		return nil, err
+	}
+	return threadReply, nil
+}
+
+// 获取本节点存储的根消息回复统计（父频道所在槽的领导节点）
+func (t *threadManager) getLocalReply(rootMessageId int64) (*wkdb.ThreadReply, error) {
+	threadReply, err := t.s.store.GetThreadReply(rootMessageId)
+	if err == wkdb.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &threadReply, nil
+}
+
+// 根消息缓存的key，根消息只在所属的父频道内有效
+type threadRootKey struct {
+	channelId     string
+	channelType   uint8
+	rootMessageId int64
+}
+
+type threadRootReq struct {
+	ChannelId     string `json:"channel_id"`      // 父频道ID
+	ChannelType   uint8  `json:"channel_type"`    // 父频道类型
+	RootMessageId int64  `json:"root_message_id"` // 根消息ID
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/client"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsThreadChannel(t *testing.T) {
+	opts := NewOptions()
+
+	threadChannelId := opts.ThreadChannelOf("g1", 100)
+	assert.True(t, opts.IsThreadChannel(threadChannelId))
+	channelId, rootMessageId := opts.ThreadChannelConvertParent(threadChannelId)
+	assert.Equal(t, "g1", channelId)
+	assert.Equal(t, int64(100), rootMessageId)
+
+	// 标识在中间或后缀不是根消息ID的都不是子区频道
+	assert.False(t, opts.IsThreadChannel("a"+opts.Channel.ThreadFlag+"b"))
+	assert.False(t, opts.IsThreadChannel("a"+opts.Channel.ThreadFlag+"100x"))
+	assert.False(t, opts.IsThreadChannel("a"+opts.Channel.ThreadFlag))
+	assert.False(t, opts.IsThreadChannel(opts.Channel.ThreadFlag+"100"))
+	assert.False(t, opts.IsThreadChannel("a"+opts.Channel.ThreadFlag+"0"))
+}
+
+func TestThreadReply(t *testing.T) {
+	s := NewTestServer(t)
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady(time.Second * 10) // 等待服务准备好
+
+	TestAddSubscriber(t, s, "g1", wkproto.ChannelTypeGroup, "u1", "u2")
+
+	cli := TestCreateClient(t, s, "u1")
+	defer cli.Close()
+	sendacks := make(chan *wkproto.SendackPacket, 10)
+	cli.SetOnSendack(func(sendack *wkproto.SendackPacket) {
+		sendacks <- sendack
+	})
+	waitSendack := func() *wkproto.SendackPacket {
+		select {
+		case sendack := <-sendacks:
+			return sendack
+		case <-time.After(time.Second * 10):
+			t.Fatal("wait sendack timeout")
+		}
+		return nil
+	}
+
+	// 根消息
+	err = cli.SendMessage(client.NewChannel("g1", wkproto.ChannelTypeGroup), []byte("root"))
+	assert.Nil(t, err)
+	rootAck := waitSendack()
+	assert.Equal(t, wkproto.ReasonSuccess, rootAck.ReasonCode)
+
+	// 根消息不在父频道内，不允许回复
+	err = cli.SendMessage(client.NewChannel(s.opts.ThreadChannelOf("g1", rootAck.MessageID+1), wkproto.ChannelTypeGroup), []byte("reply"))
+	assert.Nil(t, err)
+	assert.Equal(t, wkproto.ReasonChannelNotExist, waitSendack().ReasonCode)
+
+	threadChannel := client.NewChannel(s.opts.ThreadChannelOf("g1", rootAck.MessageID), wkproto.ChannelTypeGroup)
+	for i := 0; i < 2; i++ {
+		err = cli.SendMessage(threadChannel, []byte("reply"))
+		assert.Nil(t, err)
+		assert.Equal(t, wkproto.ReasonSuccess, waitSendack().ReasonCode)
+	}
+
+	// 回复统计记录在根消息上
+	assert.Eventually(t, func() bool {
+		threadReply, err := s.threadManager.getReply("g1", wkproto.ChannelTypeGroup, rootAck.MessageID)
+		return err == nil && threadReply != nil && threadReply.ReplyCount == 2
+	}, time.Second*5, time.Millisecond*100)
+
+	threadReply, err := s.threadManager.getReply("g1", wkproto.ChannelTypeGroup, rootAck.MessageID)
+	assert.NoError(t, err)
+	assert.Equal(t, "u1", threadReply.LastReplyFromUid)
+	assert.Equal(t, uint64(2), threadReply.LastReplyMessageSeq)
+
+	// 根消息只在所属的父频道内有效，缓存的根消息不能用于其他频道
+	exist, err := s.threadManager.rootExists("g2", wkproto.ChannelTypeGroup, rootAck.MessageID)
+	assert.NoError(t, err)
+	assert.False(t, exist)
+
+	// 停止时提案还没提案的回复统计
+	s.threadManager.onStored(threadChannel.ChannelID, wkproto.ChannelTypeGroup, []ReactorChannelMessage{
+		{MessageId: 100, MessageSeq: 3, FromUid: "u2", ReasonCode: wkproto.ReasonSuccess},
+	})
+	s.threadManager.stop()
+	threadReply, err = s.threadManager.getReply("g1", wkproto.ChannelTypeGroup, rootAck.MessageID)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), threadReply.ReplyCount)
+	assert.Equal(t, "u2", threadReply.LastReplyFromUid)
+}
@@ -0,0 +1,258 @@
+package server
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/RussellLuo/timingwheel"
+	cluster "github.com/WuKongIM/WuKongIM/pkg/cluster/clusterserver"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+// 显式创建的临时频道（频道ID以临时频道后缀结尾）管理
+// 临时频道的订阅者和过期配置持久化在频道所属的槽上，由槽领导节点定时检查是否过期，
+// 过期后通知webhook，并清除频道的消息、订阅者和分布式配置
+type tmpChannelManager struct {
+	s *Server
+	wklog.Log
+	checkTimer *timingwheel.Timer
+	checking   atomic.Bool
+}
+
+func newTmpChannelManager(s *Server) *tmpChannelManager {
+	return &tmpChannelManager{
+		s:   s,
+		Log: wklog.NewWKLog("tmpChannelManager"),
+	}
+}
+
+func (t *tmpChannelManager) start() error {
+	t.checkTimer = t.s.Schedule(t.s.opts.TmpChannel.CheckInterval, func() {
+		if !t.checking.CompareAndSwap(false, true) { // 上一次检查还没结束
+			return
+		}
+		go func() {
+			defer t.checking.Store(false)
+			t.checkExpired()
+		}()
+	})
+	return nil
+}
+
+func (t *tmpChannelManager) stop() {
+	if t.checkTimer != nil {
+		t.checkTimer.Stop()
+	}
+}
+
+// 检查本节点负责的临时频道是否过期
+func (t *tmpChannelManager) checkExpired() {
+	tmpChannels, err := t.s.store.GetTmpChannels()
+	if err != nil {
+		t.Error("GetTmpChannels failed", zap.Error(err))
+		return
+	}
+	now := time.Now()
+	for _, tmpChannel := range tmpChannels {
+		// 只有槽领导节点负责检查
+		slotLeaderId, err := t.s.cluster.SlotLeaderIdOfChannel(tmpChannel.ChannelId, wkproto.ChannelTypeTemp)
+		if err != nil {
+			t.Warn("SlotLeaderIdOfChannel failed", zap.Error(err), zap.String("channelId", tmpChannel.ChannelId))
+			continue
+		}
+		if !t.s.opts.IsLocalNode(slotLeaderId) {
+			continue
+		}
+
+		var lastMsgTime uint64
+		if tmpChannel.IdleTtl > 0 {
+			lastMsgTime, err = t.requestLastMsgTime(tmpChannel.ChannelId, wkproto.ChannelTypeTemp)
+			if err != nil {
+				t.Warn("requestLastMsgTime failed", zap.Error(err), zap.String("channelId", tmpChannel.ChannelId))
+				continue
+			}
+		}
+		if !tmpChannel.Expired(now, lastMsgTime) {
+			continue
+		}
+		err = t.expire(tmpChannel, lastMsgTime)
+		if err != nil {
+			t.Error("expire tmp channel failed", zap.Error(err), zap.String("channelId", tmpChannel.ChannelId))
+		}
+	}
+}
+
+// 临时频道过期
+func (t *tmpChannelManager) expire(tmpChannel wkdb.TmpChannel, lastMsgTime uint64) error {
+
+	t.Info("tmp channel expired", zap.String("channelId", tmpChannel.ChannelId))
+
+	// 临时频道和其对应的cmd频道
+	channelIds := []string{tmpChannel.ChannelId, t.s.opts.OrginalConvertCmdChannel(tmpChannel.ChannelId)}
+	for _, channelId := range channelIds {
+		if err := t.s.purgeChannel(channelId, wkproto.ChannelTypeTemp); err != nil {
+			return err
+		}
+	}
+	if err := t.s.store.RemoveTmpChannel(tmpChannel.ChannelId); err != nil {
+		return err
+	}
+
+	// 清除成功后通知第三方临时频道过期（清除失败下次检查时重试，不会重复通知）
+	t.s.webhook.TriggerEvent(&Event{
+		Event:       EventChannelTmpExpired,
+		ChannelType: wkproto.ChannelTypeTemp,
+		Data: map[string]interface{}{
+			"channel_id":    tmpChannel.ChannelId,
+			"channel_type":  wkproto.ChannelTypeTemp,
+			"ttl":           tmpChannel.Ttl,
+			"idle_ttl":      tmpChannel.IdleTtl,
+			"created_at":    tmpChannel.CreatedAt.Unix(),
+			"last_msg_time": lastMsgTime / 1e9,
+		},
+	})
+	return nil
+}
+
+// 是否是显式创建的临时频道（有临时频道记录），向频道所属槽的领导节点查询
+// 通过 /tmpchannel/subscriber_set 设置订阅者的临时频道没有记录，订阅者只在内存中
+func (t *tmpChannelManager) exist(channelId string) (bool, error) {
+	slotLeaderId, err := t.s.cluster.SlotLeaderIdOfChannel(channelId, wkproto.ChannelTypeTemp)
+	if err != nil {
+		return false, err
+	}
+	if t.s.opts.IsLocalNode(slotLeaderId) {
+		return t.localExist(channelId)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(t.s.ctx, time.Second*5)
+	defer cancel()
+
+	req := &channelReq{
+		ChannelId:   channelId,
+		ChannelType: wkproto.ChannelTypeTemp,
+	}
+	resp, err := t.s.cluster.RequestWithContext(timeoutCtx, slotLeaderId, "/wk/tmpChannelExist", req.Marshal())
+	if err != nil {
+		return false, err
+	}
+	if resp.Status != proto.StatusOK {
+		return false, fmt.Errorf("tmpChannelExist: response status code is %d", resp.Status)
+	}
+	return len(resp.Body) > 0 && resp.Body[0] == 1, nil
+}
+
+// 本节点（槽领导节点）是否有临时频道记录
+func (t *tmpChannelManager) localExist(channelId string) (bool, error) {
+	_, err := t.s.store.GetTmpChannel(channelId)
+	if err == wkdb.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// 清除频道在各个副本上的消息，然后删除频道的元数据和分布式配置
+func (s *Server) purgeChannel(channelId string, channelType uint8) error {
+	cfg, err := s.cluster.LoadOnlyChannelClusterConfig(channelId, channelType)
+	if err != nil && !errors.Is(err, cluster.ErrChannelClusterConfigNotFound) {
+		return err
+	}
+	if err == nil {
+		replicas := make([]uint64, 0, len(cfg.Replicas)+len(cfg.Learners))
+		replicas = append(replicas, cfg.Replicas...)
+		replicas = append(replicas, cfg.Learners...)
+		for _, nodeId := range replicas {
+			if err = s.requestClearChannel(nodeId, channelId, channelType); err != nil {
+				s.Warn("requestClearChannel failed", zap.Error(err), zap.Uint64("nodeId", nodeId), zap.String("channelId", channelId))
+			}
+		}
+	}
+	return s.store.DeleteChannelAndClearMessages(channelId, channelType)
+}
+
+// 获取频道最后一条消息的时间（纳秒）
+func (t *tmpChannelManager) requestLastMsgTime(channelId string, channelType uint8) (uint64, error) {
+	leaderNode, err := t.s.cluster.LeaderOfChannelForRead(channelId, channelType)
+	if errors.Is(err, cluster.ErrChannelClusterConfigNotFound) { // 频道还没有消息
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if t.s.opts.IsLocalNode(leaderNode.Id) {
+		_, lastMsgTime, err := t.s.store.DB().GetChannelLastMessageSeq(channelId, channelType)
+		return lastMsgTime, err
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(t.s.ctx, time.Second*5)
+	defer cancel()
+
+	req := &channelReq{
+		ChannelId:   channelId,
+		ChannelType: channelType,
+	}
+	resp, err := t.s.cluster.RequestWithContext(timeoutCtx, leaderNode.Id, "/wk/channelLastMsgTime", req.Marshal())
+	if err != nil {
+		return 0, err
+	}
+	if resp.Status != proto.StatusOK {
+		return 0, fmt.Errorf("requestLastMsgTime: response status code is %d", resp.Status)
+	}
+	if len(resp.Body) < 8 {
+		return 0, errors.New("requestLastMsgTime: invalid response body")
+	}
+	return binary.BigEndian.Uint64(resp.Body), nil
+}
+
+// 请求节点清除本地的频道消息
+func (s *Server) requestClearChannel(nodeId uint64, channelId string, channelType uint8) error {
+	if s.opts.IsLocalNode(nodeId) {
+		return s.clearLocalChannel(channelId, channelType)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(s.ctx, time.Second*5)
+	defer cancel()
+
+	req := &channelReq{
+		ChannelId:   channelId,
+		ChannelType: channelType,
+	}
+	resp, err := s.cluster.RequestWithContext(timeoutCtx, nodeId, "/wk/channelClear", req.Marshal())
+	if err != nil {
+		return err
+	}
+	if resp.Status != proto.StatusOK {
+		return fmt.Errorf("requestClearChannel: response status code is %d", resp.Status)
+	}
+	return nil
+}
+
+// 关闭本节点内存中的频道并清空本节点上的频道消息
+func (s *Server) clearLocalChannel(channelId string, channelType uint8) error {
+	channelKey := wkutil.ChannelToKey(channelId, channelType)
+	ch := s.channelReactor.reactorSub(channelKey).channel(channelKey)
+	if ch != nil {
+		s.channelReactor.addCloseReq(&closeReq{ch: ch})
+	}
+
+	lastMsgSeq, _, err := s.store.DB().GetChannelLastMessageSeq(channelId, channelType)
+	if err != nil {
+		return err
+	}
+	if lastMsgSeq == 0 {
+		return nil
+	}
+	return s.store.DB().TruncateLogTo(channelId, channelType, 1)
+}
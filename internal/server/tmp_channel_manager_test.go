@@ -0,0 +1,117 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTmpChannelSubscriberSet(t *testing.T) {
+	s := NewTestServer(t, WithTmpChannelSuffix("_tmp"))
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady(time.Second * 10) // 等待服务准备好
+
+	// 通过subscriber_set设置订阅者的临时频道没有临时频道记录，即使以临时频道后缀结尾也使用内存中的订阅者
+	channelId := "chat" + s.opts.TmpChannel.Suffix
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/tmpchannel/subscriber_set", bytes.NewReader([]byte(wkutil.ToJson(map[string]interface{}{
+		"channel_id": channelId,
+		"uids":       []string{"u1", "u2"},
+	}))))
+	s.apiServer.r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	u2 := TestCreateClient(t, s, "u2")
+	defer u2.Close()
+	recvC := make(chan string, 10)
+	u2.SetOnRecv(func(recv *wkproto.RecvPacket) error {
+		recvC <- string(recv.Payload)
+		return nil
+	})
+
+	_, err = sendMessageToChannel(s, MessageSendReq{FromUID: s.opts.SystemUID, Payload: []byte("hello")}, channelId, wkproto.ChannelTypeTemp, wkutil.GenUUID(), wkproto.StreamFlagIng)
+	assert.Nil(t, err)
+	select {
+	case payload := <-recvC:
+		assert.Equal(t, "hello", payload)
+	case <-time.After(time.Second * 10):
+		t.Fatal("wait recv timeout")
+	}
+}
+
+func TestTmpChannelExpire(t *testing.T) {
+	var (
+		lock    sync.Mutex
+		expired []string // 过期通知的数据
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("event") != EventChannelTmpExpired {
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		lock.Lock()
+		defer lock.Unlock()
+		expired = append(expired, string(body))
+	}))
+	defer ts.Close()
+
+	s := NewTestServer(t, WithWebhookHTTPAddr(ts.URL), WithTmpChannelCheckInterval(time.Millisecond*100))
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady(time.Second * 10) // 等待服务准备好
+
+	ttlChannelId := "ttl" + s.opts.TmpChannel.Suffix
+	idleChannelId := "idle" + s.opts.TmpChannel.Suffix
+	for _, data := range []map[string]interface{}{
+		{"channel_id": ttlChannelId, "subscribers": []string{"u1"}, "ttl": 1, "idle_ttl": -1},  // 创建后1秒过期
+		{"channel_id": idleChannelId, "subscribers": []string{"u1"}, "ttl": -1, "idle_ttl": 1}, // 闲置1秒过期
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/tmpchannel/create", bytes.NewReader([]byte(wkutil.ToJson(data))))
+		s.apiServer.r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	for _, channelId := range []string{ttlChannelId, idleChannelId} {
+		exist, err := s.tmpChannelManager.exist(channelId)
+		assert.NoError(t, err)
+		assert.True(t, exist)
+	}
+
+	// 过期后清除临时频道并通知
+	assert.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(expired) == 2
+	}, time.Second*10, time.Millisecond*50)
+	for _, channelId := range []string{ttlChannelId, idleChannelId} {
+		_, err = s.store.GetTmpChannel(channelId)
+		assert.Equal(t, wkdb.ErrNotFound, err)
+		subscribers, err := s.store.GetSubscribers(channelId, wkproto.ChannelTypeTemp)
+		assert.NoError(t, err)
+		assert.Len(t, subscribers, 0)
+	}
+
+	// 只通知一次
+	time.Sleep(time.Millisecond * 500)
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Len(t, expired, 2)
+	assert.Contains(t, expired[0]+expired[1], ttlChannelId)
+	assert.Contains(t, expired[0]+expired[1], idleChannelId)
+}
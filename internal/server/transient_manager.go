@@ -0,0 +1,310 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/RussellLuo/timingwheel"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/panjf2000/ants/v2"
+	"go.uber.org/zap"
+)
+
+// 瞬态事件（比如正在输入）
+// SendPacket的Setting带有protocol.SettingTransient标记或通过 /message/transient 发送的消息为瞬态事件，
+// 瞬态事件只做权限验证，然后直接投递给在线的接收者：不存储、不更新最近会话、不重试、也不触发离线webhook。
+// 事件由频道所属槽的领导节点（个人频道为接收者所属槽的领导节点）处理：按发送者和频道限流、权限验证、获取接收者，
+// 然后按接收者的用户领导节点分组投递。
+type transientManager struct {
+	s *Server
+	wklog.Log
+	pool *ants.Pool
+
+	throttleLock sync.Mutex
+	lastSendAt   map[string]time.Time // 发送者在频道内最后发送瞬态事件的时间，key为 uid-channelKey
+
+	cleanTimer *timingwheel.Timer
+}
+
+func newTransientManager(s *Server) *transientManager {
+	pool, err := ants.NewPool(s.opts.EventPoolSize, ants.WithPanicHandler(func(err interface{}) {
+		s.Error("transient panic", zap.Any("err", err), zap.Stack("stack"))
+	}))
+	if err != nil {
+		panic(err)
+	}
+	return &transientManager{
+		s:          s,
+		Log:        wklog.NewWKLog("transientManager"),
+		pool:       pool,
+		lastSendAt: make(map[string]time.Time),
+	}
+}
+
+func (t *transientManager) start() error {
+	t.cleanTimer = t.s.Schedule(time.Minute, t.cleanThrottle)
+	return nil
+}
+
+func (t *transientManager) stop() {
+	if t.cleanTimer != nil {
+		t.cleanTimer.Stop()
+	}
+	t.pool.Release()
+}
+
+// 处理连接发送的瞬态事件，并回应sendack
+func (t *transientManager) handleSendPacket(conn *connContext, messageId int64, sendPacket *wkproto.SendPacket) {
+	err := t.pool.Submit(func() {
+		reasonCode := wkproto.ReasonSuccess
+		payload, err := t.s.checkAndDecodePayload(sendPacket, conn)
+		if err != nil {
+			t.Warn("decrypt transient payload error", zap.Error(err), zap.String("uid", conn.uid), zap.Int64("connId", conn.connId))
+			reasonCode = wkproto.ReasonPayloadDecodeError
+		} else {
+			reasonCode, err = t.send(&transientEvent{
+				MessageId:   messageId,
+				ClientMsgNo: sendPacket.ClientMsgNo,
+				Setting:     sendPacket.Setting.Uint8(),
+				FromUID:     conn.uid,
+				ChannelID:   sendPacket.ChannelID,
+				ChannelType: sendPacket.ChannelType,
+				Topic:       sendPacket.Topic,
+				Payload:     payload,
+			})
+			if err != nil {
+				t.Error("send transient event failed", zap.Error(err), zap.String("uid", conn.uid), zap.String("channelId", sendPacket.ChannelID), zap.Uint8("channelType", sendPacket.ChannelType))
+			}
+		}
+		err = conn.writePacket(&wkproto.SendackPacket{
+			Framer:      sendPacket.Framer,
+			MessageID:   messageId,
+			ClientSeq:   sendPacket.ClientSeq,
+			ClientMsgNo: sendPacket.ClientMsgNo,
+			ReasonCode:  reasonCode,
+		})
+		if err != nil {
+			t.Warn("write transient sendack failed", zap.Error(err), zap.String("uid", conn.uid))
+		}
+	})
+	if err != nil {
+		t.Error("submit transient send packet failed", zap.Error(err), zap.String("uid", conn.uid))
+	}
+}
+
+// 发送瞬态事件，交给频道所属槽的领导节点处理
+func (t *transientManager) send(event *transientEvent) (wkproto.ReasonCode, error) {
+	if event.ChannelType == wkproto.ChannelTypeTemp {
+		return wkproto.ReasonNotSupportChannelType, nil
+	}
+	// 个人频道的ChannelID为接收者uid，由接收者所属槽的领导节点处理
+	leaderId, err := t.s.cluster.SlotLeaderIdOfChannel(event.ChannelID, event.ChannelType)
+	if err != nil {
+		return wkproto.ReasonSystemError, err
+	}
+	if t.s.opts.IsLocalNode(leaderId) {
+		return t.handle(event)
+	}
+	return t.requestSend(leaderId, event)
+}
+
+func (t *transientManager) requestSend(nodeId uint64, event *transientEvent) (wkproto.ReasonCode, error) {
+	timeoutCtx, cancel := context.WithTimeout(t.s.ctx, time.Second*5)
+	defer cancel()
+
+	resp, err := t.s.cluster.RequestWithContext(timeoutCtx, nodeId, "/wk/transientSend", []byte(wkutil.ToJSON(event)))
+	if err != nil {
+		return wkproto.ReasonSystemError, err
+	}
+	if resp.Status == proto.StatusOK {
+		return wkproto.ReasonSuccess, nil
+	}
+	if resp.Status == proto.StatusError {
+		return wkproto.ReasonSystemError, errors.New(string(resp.Body))
+	}
+	return wkproto.ReasonCode(resp.Status), nil
+}
+
+// 处理瞬态事件（在频道所属槽的领导节点上执行）
+func (t *transientManager) handle(event *transientEvent) (wkproto.ReasonCode, error) {
+	if !t.allow(event.FromUID, event.ChannelID, event.ChannelType) {
+		return wkproto.ReasonRateLimit, nil
+	}
+
+	// 权限验证
+	var (
+		channelId   = event.ChannelID
+		channelInfo wkdb.ChannelInfo
+		err         error
+	)
+	if event.ChannelType == wkproto.ChannelTypePerson {
+		channelId = GetFakeChannelIDWith(event.ChannelID, event.FromUID)
+	} else {
+		channelInfo, err = t.s.store.GetChannel(event.ChannelID, event.ChannelType)
+		if err != nil && err != wkdb.ErrNotFound {
+			return wkproto.ReasonSystemError, err
+		}
+	}
+	reasonCode, err := t.s.channelReactor.hasPermission(channelId, event.ChannelType, event.FromUID, channelInfo)
+	if err != nil {
+		return wkproto.ReasonSystemError, err
+	}
+	if reasonCode != wkproto.ReasonSuccess {
+		return reasonCode, nil
+	}
+
+	// 接收者
+	var uids []string
+	if event.ChannelType == wkproto.ChannelTypePerson {
+		uids = []string{event.ChannelID}
+	} else {
+		members, err := t.s.store.GetSubscribers(event.ChannelID, event.ChannelType)
+		if err != nil {
+			return wkproto.ReasonSystemError, err
+		}
+		uids = make([]string, 0, len(members))
+		for _, member := range members {
+			if member.Uid != event.FromUID {
+				uids = append(uids, member.Uid)
+			}
+		}
+	}
+	t.deliver(event, uids)
+	return wkproto.ReasonSuccess, nil
+}
+
+// 同一发送者在同一频道内发送瞬态事件的间隔不能小于配置的间隔
+func (t *transientManager) allow(fromUid string, channelId string, channelType uint8) bool {
+	interval := t.s.opts.Transient.Interval
+	if interval <= 0 || t.s.systemUIDManager.SystemUID(fromUid) {
+		return true
+	}
+	key := fmt.Sprintf("%s-%s", fromUid, wkutil.ChannelToKey(channelId, channelType))
+	now := time.Now()
+
+	t.throttleLock.Lock()
+	defer t.throttleLock.Unlock()
+	if lastSendAt, ok := t.lastSendAt[key]; ok && now.Sub(lastSendAt) < interval {
+		return false
+	}
+	t.lastSendAt[key] = now
+	return true
+}
+
+// 清除已经过了限流间隔的记录
+func (t *transientManager) cleanThrottle() {
+	now := time.Now()
+	t.throttleLock.Lock()
+	defer t.throttleLock.Unlock()
+	for key, lastSendAt := range t.lastSendAt {
+		if now.Sub(lastSendAt) >= t.s.opts.Transient.Interval {
+			delete(t.lastSendAt, key)
+		}
+	}
+}
+
+// 按接收者的用户领导节点分组投递
+func (t *transientManager) deliver(event *transientEvent, uids []string) {
+	nodeUids := make(map[uint64][]string)
+	for _, uid := range uids {
+		leaderId, err := t.s.cluster.SlotLeaderIdOfChannel(uid, wkproto.ChannelTypePerson)
+		if err != nil {
+			t.Warn("SlotLeaderIdOfChannel failed", zap.Error(err), zap.String("uid", uid))
+			continue
+		}
+		nodeUids[leaderId] = append(nodeUids[leaderId], uid)
+	}
+
+	for nodeId, uids := range nodeUids {
+		if t.s.opts.IsLocalNode(nodeId) {
+			t.deliverLocal(event, uids)
+			continue
+		}
+		nodeId, req := nodeId, &transientDeliverReq{Event: event, Uids: uids}
+		err := t.pool.Submit(func() {
+			if err := t.requestDeliver(nodeId, req); err != nil {
+				t.Warn("requestDeliver failed", zap.Error(err), zap.Uint64("nodeId", nodeId))
+			}
+		})
+		if err != nil {
+			t.Error("submit requestDeliver failed", zap.Error(err), zap.Uint64("nodeId", nodeId))
+		}
+	}
+}
+
+func (t *transientManager) requestDeliver(nodeId uint64, req *transientDeliverReq) error {
+	timeoutCtx, cancel := context.WithTimeout(t.s.ctx, time.Second*5)
+	defer cancel()
+
+	resp, err := t.s.cluster.RequestWithContext(timeoutCtx, nodeId, "/wk/transientDeliver", []byte(wkutil.ToJSON(req)))
+	if err != nil {
+		return err
+	}
+	if resp.Status != proto.StatusOK {
+		return fmt.Errorf("requestDeliver: response status code is %d", resp.Status)
+	}
+	return nil
+}
+
+// 投递给本节点（用户领导节点）上在线的接收者，离线的接收者直接忽略
+func (t *transientManager) deliverLocal(event *transientEvent, uids []string) {
+	fromUid := event.FromUID
+	if fromUid == t.s.opts.SystemUID { // 如果发送者是系统账号，则不显示发送者
+		fromUid = ""
+	}
+	for _, uid := range uids {
+		conns := t.s.userReactor.getConns(uid)
+		for _, conn := range conns {
+			if !conn.isAuth.Load() {
+				continue
+			}
+			channelId := event.ChannelID
+			if event.ChannelType == wkproto.ChannelTypePerson {
+				channelId = fromUid
+			}
+			recvPacket := &wkproto.RecvPacket{
+				Framer: wkproto.Framer{
+					NoPersist: true,
+				},
+				Setting:     wkproto.Setting(event.Setting),
+				MessageID:   event.MessageId,
+				ClientMsgNo: event.ClientMsgNo,
+				StreamFlag:  wkproto.StreamFlagIng,
+				FromUID:     fromUid,
+				ChannelID:   channelId,
+				ChannelType: event.ChannelType,
+				Topic:       event.Topic,
+				Timestamp:   int32(time.Now().Unix()),
+				Payload:     event.Payload,
+			}
+			err := t.s.writeRecvPacketNoRetry(conn, recvPacket)
+			if err != nil {
+				t.Warn("write transient event failed", zap.Error(err), zap.String("uid", uid), zap.Int64("connId", conn.connId))
+			}
+		}
+	}
+}
+
+// 瞬态事件
+type transientEvent struct {
+	MessageId   int64  `json:"message_id"`    // 消息id
+	ClientMsgNo string `json:"client_msg_no"` // 客户端消息编号
+	Setting     uint8  `json:"setting"`       // 消息设置
+	FromUID     string `json:"from_uid"`      // 发送者
+	ChannelID   string `json:"channel_id"`    // 频道ID（个人频道为接收者uid）
+	ChannelType uint8  `json:"channel_type"`  // 频道类型
+	Topic       string `json:"topic"`         // 话题
+	Payload     []byte `json:"payload"`       // 事件内容（未加密）
+}
+
+type transientDeliverReq struct {
+	Event *transientEvent `json:"event"`
+	Uids  []string        `json:"uids"` // 本节点需要投递的接收者
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/client"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransientAllow(t *testing.T) {
+	s := &Server{opts: NewOptions(WithTransientInterval(time.Millisecond * 100))}
+	s.systemUIDManager = NewSystemUIDManager(s)
+	s.systemUIDManager.loaded.Store(true)
+	tm := newTransientManager(s)
+	defer tm.pool.Release()
+
+	assert.True(t, tm.allow("u1", "g1", wkproto.ChannelTypeGroup))
+	assert.False(t, tm.allow("u1", "g1", wkproto.ChannelTypeGroup)) // 间隔内的事件丢弃
+	assert.True(t, tm.allow("u2", "g1", wkproto.ChannelTypeGroup))  // 按发送者限制
+	assert.True(t, tm.allow("u1", "g2", wkproto.ChannelTypeGroup))  // 按频道限制
+
+	time.Sleep(time.Millisecond * 150)
+	assert.True(t, tm.allow("u1", "g1", wkproto.ChannelTypeGroup))
+
+	time.Sleep(time.Millisecond * 150)
+	tm.cleanThrottle()
+	assert.Empty(t, tm.lastSendAt)
+}
+
+func TestTransientSend(t *testing.T) {
+	s := NewTestServer(t, WithTransientInterval(time.Second*10))
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady(time.Second * 10) // 等待服务准备好
+
+	TestAddSubscriber(t, s, "g1", wkproto.ChannelTypeGroup, "u1", "u2")
+
+	sender := TestCreateClient(t, s, "u1")
+	defer sender.Close()
+	sendacks := make(chan *wkproto.SendackPacket, 10)
+	sender.SetOnSendack(func(sendack *wkproto.SendackPacket) {
+		sendacks <- sendack
+	})
+	receiver := TestCreateClient(t, s, "u2")
+	defer receiver.Close()
+	recvs := make(chan *wkproto.RecvPacket, 10)
+	receiver.SetOnRecv(func(recv *wkproto.RecvPacket) error {
+		recvs <- recv
+		return nil
+	})
+	waitSendack := func() *wkproto.SendackPacket {
+		select {
+		case sendack := <-sendacks:
+			return sendack
+		case <-time.After(time.Second * 10):
+			t.Fatal("wait sendack timeout")
+		}
+		return nil
+	}
+
+	channel := client.NewChannel("g1", wkproto.ChannelTypeGroup)
+	err = sender.SendMessage(channel, []byte("typing"), client.SendOptionWithTransient(true))
+	assert.Nil(t, err)
+	assert.Equal(t, wkproto.ReasonSuccess, waitSendack().ReasonCode)
+
+	select {
+	case recv := <-recvs:
+		assert.Equal(t, "typing", string(recv.Payload))
+		assert.Equal(t, "u1", recv.FromUID)
+		assert.True(t, recv.NoPersist)
+		assert.True(t, recv.Setting.IsSet(client.SettingTransient))
+	case <-time.After(time.Second * 10):
+		t.Fatal("wait transient event timeout")
+	}
+
+	// 同一发送者在同一频道内间隔内的事件被限制
+	err = sender.SendMessage(channel, []byte("typing"), client.SendOptionWithTransient(true))
+	assert.Nil(t, err)
+	assert.Equal(t, wkproto.ReasonRateLimit, waitSendack().ReasonCode)
+	select {
+	case recv := <-recvs:
+		t.Fatalf("unexpected recv: %s", string(recv.Payload))
+	case <-time.After(time.Millisecond * 300):
+	}
+
+	// 瞬态事件不存储
+	lastMsgSeq, err := s.store.GetLastMsgSeq("g1", wkproto.ChannelTypeGroup)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), lastMsgSeq)
+}
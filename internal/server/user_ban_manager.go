@@ -0,0 +1,334 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/RussellLuo/timingwheel"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+// 用户封禁
+// 封禁记录持久化在用户所在的槽上，封禁和解封都在用户的槽领导节点上执行，然后广播给所有在线节点：
+// 各节点踢掉本节点上该用户的连接（DisconnectPacket携带封禁原因），并更新本节点的封禁缓存。
+// 连接认证时（用户的槽领导节点）直接读取封禁记录，发送消息时（频道权限验证）通过封禁缓存判断发送者是否被封禁，
+// 缓存中没有的用户向用户的槽领导节点请求封禁记录。到期的封禁由用户的槽领导节点自动解封。
+type userBanManager struct {
+	s *Server
+	wklog.Log
+
+	cacheLock sync.RWMutex
+	cache     map[string]*userBanCache // 封禁缓存，key为uid
+
+	checkTimer *timingwheel.Timer
+	checking   atomic.Bool
+}
+
+type userBanCache struct {
+	ban      *wkdb.UserBan // 封禁记录，nil表示没有被封禁
+	cachedAt time.Time     // 缓存时间
+}
+
+func newUserBanManager(s *Server) *userBanManager {
+	return &userBanManager{
+		s:     s,
+		Log:   wklog.NewWKLog("userBanManager"),
+		cache: make(map[string]*userBanCache),
+	}
+}
+
+func (u *userBanManager) start() error {
+	u.checkTimer = u.s.Schedule(u.s.opts.UserBan.CheckInterval, func() {
+		if !u.checking.CompareAndSwap(false, true) { // 上一次检查还没结束
+			return
+		}
+		go func() {
+			defer u.checking.Store(false)
+			u.cleanCache()
+			u.checkExpired()
+		}()
+	})
+	return nil
+}
+
+func (u *userBanManager) stop() {
+	if u.checkTimer != nil {
+		u.checkTimer.Stop()
+	}
+}
+
+// 封禁用户（需要在用户的槽领导节点上执行）
+func (u *userBanManager) ban(uid string, reason string, expire time.Duration) (wkdb.UserBan, error) {
+	now := time.Now()
+	userBan := wkdb.UserBan{
+		Uid:       uid,
+		Reason:    reason,
+		CreatedAt: &now,
+	}
+	if expire > 0 {
+		expireAt := now.Add(expire)
+		userBan.ExpireAt = &expireAt
+	}
+	err := u.s.store.AddOrUpdateUserBan(userBan)
+	if err != nil {
+		return wkdb.EmptyUserBan, err
+	}
+	u.broadcast(newUserBanNotify(uid, &userBan))
+	u.s.webhook.userBanChanged(uid, lifecycleActionBan, &userBan)
+	return userBan, nil
+}
+
+// 解封用户（需要在用户的槽领导节点上执行，到期自动解封也通过此方法）
+func (u *userBanManager) unban(uid string) error {
+	err := u.s.store.RemoveUserBan(uid)
+	if err != nil {
+		return err
+	}
+	u.broadcast(newUserBanNotify(uid, nil))
+	u.s.webhook.userBanChanged(uid, lifecycleActionUnban, nil)
+	return nil
+}
+
+// 将封禁变化通知给所有在线节点（包括本节点）
+func (u *userBanManager) broadcast(notify *userBanNotify) {
+	u.handleNotify(notify)
+
+	data := []byte(wkutil.ToJSON(notify))
+	nodes := u.s.clusterServer.GetConfig().Nodes
+	for _, node := range nodes {
+		if node.Id == u.s.opts.Cluster.NodeId || !node.Online {
+			continue
+		}
+		if err := u.requestNotify(node.Id, data); err != nil {
+			u.Warn("requestNotify failed", zap.Error(err), zap.Uint64("nodeId", node.Id), zap.String("uid", notify.Uid))
+		}
+	}
+}
+
+func (u *userBanManager) requestNotify(nodeId uint64, data []byte) error {
+	timeoutCtx, cancel := context.WithTimeout(u.s.ctx, time.Second*5)
+	defer cancel()
+
+	resp, err := u.s.cluster.RequestWithContext(timeoutCtx, nodeId, "/wk/userBanNotify", data)
+	if err != nil {
+		return err
+	}
+	if resp.Status != proto.StatusOK {
+		return fmt.Errorf("requestNotify: response status code is %d", resp.Status)
+	}
+	return nil
+}
+
+// 处理封禁变化：更新本节点的封禁缓存，如果是封禁则踢掉本节点上该用户的连接
+func (u *userBanManager) handleNotify(notify *userBanNotify) {
+	u.setCache(notify.Uid, notify.userBan())
+	if notify.Ban == 1 {
+		u.kickLocalConns(notify.Uid, notify.Reason)
+	}
+}
+
+// 踢掉本节点上用户的真实连接
+func (u *userBanManager) kickLocalConns(uid string, reason string) {
+	conns := u.s.userReactor.getConns(uid)
+	for _, conn := range conns {
+		if !conn.isRealConn {
+			continue
+		}
+		u.Info("kick banned user conn", zap.String("uid", uid), zap.Int64("connId", conn.connId), zap.String("deviceId", conn.deviceId))
+		_ = conn.writeDirectlyPacket(&wkproto.DisconnectPacket{
+			ReasonCode: wkproto.ReasonBan,
+			Reason:     reason,
+		})
+		u.s.sessionLogManager.recordKick(conn, wkproto.ReasonBan, reason)
+		u.s.userReactor.removeConnById(conn.uid, conn.connId)
+		u.s.timingWheel.AfterFunc(time.Second*2, func(cn *connContext) func() {
+			return func() {
+				cn.close()
+			}
+		}(conn))
+	}
+}
+
+// 用户是否被封禁
+// 获取封禁记录失败时使用最后缓存的记录（即使已过期），没有缓存时放行，避免领导节点不可用时所有用户都不能发消息
+func (u *userBanManager) isBanned(uid string) bool {
+	userBan, err := u.getBan(uid)
+	if err != nil {
+		u.cacheLock.RLock()
+		cache := u.cache[uid]
+		u.cacheLock.RUnlock()
+		if cache == nil {
+			u.Warn("get user ban failed, allow the user", zap.Error(err), zap.String("uid", uid))
+			return false
+		}
+		u.Warn("get user ban failed, use the last cached ban", zap.Error(err), zap.String("uid", uid))
+		userBan = cache.ban
+	}
+	return userBan != nil && !userBan.Expired(time.Now())
+}
+
+// 获取用户的封禁记录，优先从缓存获取
+func (u *userBanManager) getBan(uid string) (*wkdb.UserBan, error) {
+	u.cacheLock.RLock()
+	cache := u.cache[uid]
+	u.cacheLock.RUnlock()
+	if cache != nil && time.Since(cache.cachedAt) < u.s.opts.UserBan.CacheTTL {
+		return cache.ban, nil
+	}
+
+	leaderId, err := u.s.cluster.SlotLeaderIdOfChannel(uid, wkproto.ChannelTypePerson)
+	if err != nil {
+		return nil, err
+	}
+	var userBan *wkdb.UserBan
+	if u.s.opts.IsLocalNode(leaderId) {
+		userBan, err = u.getLocalBan(uid)
+	} else {
+		userBan, err = u.requestGetBan(leaderId, uid)
+	}
+	if err != nil {
+		return nil, err
+	}
+	u.setCache(uid, userBan)
+	return userBan, nil
+}
+
+// 获取本节点存储的用户封禁记录
+func (u *userBanManager) getLocalBan(uid string) (*wkdb.UserBan, error) {
+	userBan, err := u.s.store.GetUserBan(uid)
+	if err == wkdb.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &userBan, nil
+}
+
+func (u *userBanManager) requestGetBan(nodeId uint64, uid string) (*wkdb.UserBan, error) {
+	timeoutCtx, cancel := context.WithTimeout(u.s.ctx, time.Second*5)
+	defer cancel()
+
+	resp, err := u.s.cluster.RequestWithContext(timeoutCtx, nodeId, "/wk/userBanGet", []byte(uid))
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != proto.StatusOK {
+		return nil, fmt.Errorf("requestGetBan: response status code is %d", resp.Status)
+	}
+	notify := &userBanNotify{}
+	err = wkutil.ReadJSONByByte(resp.Body, notify)
+	if err != nil {
+		return nil, err
+	}
+	return notify.userBan(), nil
+}
+
+func (u *userBanManager) setCache(uid string, userBan *wkdb.UserBan) {
+	u.cacheLock.Lock()
+	u.cache[uid] = &userBanCache{
+		ban:      userBan,
+		cachedAt: time.Now(),
+	}
+	u.cacheLock.Unlock()
+}
+
+// 清除过期的缓存（封禁中的记录保留，获取封禁记录失败时使用）
+func (u *userBanManager) cleanCache() {
+	now := time.Now()
+	u.cacheLock.Lock()
+	defer u.cacheLock.Unlock()
+	for uid, cache := range u.cache {
+		if now.Sub(cache.cachedAt) < u.s.opts.UserBan.CacheTTL {
+			continue
+		}
+		if cache.ban != nil && !cache.ban.Expired(now) {
+			continue
+		}
+		delete(u.cache, uid)
+	}
+}
+
+// 解封本节点负责的到期封禁
+func (u *userBanManager) checkExpired() {
+	userBans, err := u.getLeaderBans()
+	if err != nil {
+		u.Error("getLeaderBans failed", zap.Error(err))
+		return
+	}
+	now := time.Now()
+	for _, userBan := range userBans {
+		if !userBan.Expired(now) {
+			continue
+		}
+		u.Info("user ban expired, unban", zap.String("uid", userBan.Uid))
+		if err := u.unban(userBan.Uid); err != nil {
+			u.Error("unban failed", zap.Error(err), zap.String("uid", userBan.Uid))
+		}
+	}
+}
+
+// 获取本节点作为槽领导的用户封禁记录
+func (u *userBanManager) getLeaderBans() ([]wkdb.UserBan, error) {
+	userBans, err := u.s.store.GetUserBans()
+	if err != nil {
+		return nil, err
+	}
+	leaderBans := make([]wkdb.UserBan, 0, len(userBans))
+	for _, userBan := range userBans {
+		leaderId, err := u.s.cluster.SlotLeaderIdOfChannel(userBan.Uid, wkproto.ChannelTypePerson)
+		if err != nil {
+			u.Warn("SlotLeaderIdOfChannel failed", zap.Error(err), zap.String("uid", userBan.Uid))
+			continue
+		}
+		if u.s.opts.IsLocalNode(leaderId) {
+			leaderBans = append(leaderBans, userBan)
+		}
+	}
+	return leaderBans, nil
+}
+
+// 封禁变化通知
+type userBanNotify struct {
+	Uid      string `json:"uid"`       // 用户uid
+	Ban      int    `json:"ban"`       // 是否封禁 1.封禁 0.解封
+	Reason   string `json:"reason"`    // 封禁原因
+	ExpireAt int64  `json:"expire_at"` // 封禁到期时间（秒），0表示永久
+}
+
+func newUserBanNotify(uid string, userBan *wkdb.UserBan) *userBanNotify {
+	notify := &userBanNotify{
+		Uid: uid,
+	}
+	if userBan != nil {
+		notify.Ban = 1
+		notify.Reason = userBan.Reason
+		if userBan.ExpireAt != nil {
+			notify.ExpireAt = userBan.ExpireAt.Unix()
+		}
+	}
+	return notify
+}
+
+func (n *userBanNotify) userBan() *wkdb.UserBan {
+	if n.Ban != 1 {
+		return nil
+	}
+	userBan := &wkdb.UserBan{
+		Uid:    n.Uid,
+		Reason: n.Reason,
+	}
+	if n.ExpireAt > 0 {
+		expireAt := time.Unix(n.ExpireAt, 0)
+		userBan.ExpireAt = &expireAt
+	}
+	return userBan
+}
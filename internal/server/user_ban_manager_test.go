@@ -0,0 +1,42 @@
+package server
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/icluster"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+// 用户所属槽的领导节点不可用
+type userBanTestCluster struct {
+	icluster.Cluster
+}
+
+func (c *userBanTestCluster) SlotLeaderIdOfChannel(channelId string, channelType uint8) (uint64, error) {
+	return 0, errors.New("slot leader not found")
+}
+
+func TestUserBanFallback(t *testing.T) {
+	s := &Server{opts: NewOptions(), cluster: &userBanTestCluster{}}
+	u := newUserBanManager(s)
+
+	// 没有缓存时放行
+	assert.False(t, u.isBanned("u1"))
+
+	// 使用最后缓存的记录（即使已过期）
+	u.setCache("u2", &wkdb.UserBan{Uid: "u2"})
+	u.setCache("u3", nil)
+	for _, cache := range u.cache {
+		cache.cachedAt = time.Now().Add(-s.opts.UserBan.CacheTTL * 2)
+	}
+	assert.True(t, u.isBanned("u2"))
+	assert.False(t, u.isBanned("u3"))
+
+	// 清除过期缓存时保留封禁中的记录
+	u.cleanCache()
+	assert.Contains(t, u.cache, "u2")
+	assert.NotContains(t, u.cache, "u3")
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/panjf2000/ants/v2"
+	"go.uber.org/zap"
+)
+
+// 用户在线状态
+// 用户的最后在线时间（连接断开时记录）和用户设置的状态持久化在用户所在的槽上，
+// 用户的在线设备由用户的槽领导节点提供
+type userPresenceManager struct {
+	s *Server
+	wklog.Log
+	pool *ants.Pool
+}
+
+func newUserPresenceManager(s *Server) *userPresenceManager {
+	pool, err := ants.NewPool(s.opts.EventPoolSize, ants.WithNonblocking(true), ants.WithPanicHandler(func(err interface{}) {
+		s.Error("user presence panic", zap.Any("err", err), zap.Stack("stack"))
+	}))
+	if err != nil {
+		panic(err)
+	}
+	return &userPresenceManager{
+		s:    s,
+		Log:  wklog.NewWKLog("userPresenceManager"),
+		pool: pool,
+	}
+}
+
+func (u *userPresenceManager) stop() {
+	u.pool.Release()
+}
+
+// 记录用户的最后在线时间
+func (u *userPresenceManager) updateLastSeen(uid string) {
+	if u.s.systemUIDManager.SystemUID(uid) {
+		return
+	}
+	lastSeen := time.Now()
+	err := u.pool.Submit(func() {
+		if err := u.s.store.UpdateUserLastSeen(uid, lastSeen); err != nil {
+			u.Warn("UpdateUserLastSeen failed", zap.Error(err), zap.String("uid", uid))
+		}
+	})
+	if err != nil {
+		u.Warn("submit updateLastSeen failed", zap.Error(err), zap.String("uid", uid))
+	}
+}
+
+// 获取本节点（用户的槽领导节点）上的用户在线状态
+func (u *userPresenceManager) getLocalPresences(uids []string) ([]*userPresenceResp, error) {
+	resps := make([]*userPresenceResp, 0, len(uids))
+	for _, uid := range uids {
+		resp := &userPresenceResp{
+			UID:     uid,
+			Devices: make([]*userPresenceDevice, 0),
+		}
+		conns := u.s.userReactor.getConns(uid)
+		for _, conn := range conns {
+			if !conn.isAuth.Load() {
+				continue
+			}
+			resp.Devices = append(resp.Devices, &userPresenceDevice{
+				DeviceId:    conn.deviceId,
+				DeviceFlag:  uint8(conn.deviceFlag),
+				DeviceLevel: uint8(conn.deviceLevel),
+			})
+		}
+		if len(resp.Devices) > 0 {
+			resp.Online = 1
+		}
+
+		presence, err := u.s.store.GetUserPresence(uid)
+		if err != nil && err != wkdb.ErrNotFound {
+			return nil, err
+		}
+		if presence.LastSeen != nil {
+			resp.LastSeen = presence.LastSeen.Unix()
+		}
+		resp.Status = uint8(presence.Status)
+		resp.StatusText = presence.StatusText
+		resps = append(resps, resp)
+	}
+	return resps, nil
+}
+
+type userPresenceResp struct {
+	UID        string                `json:"uid"`         // 用户uid
+	Online     int                   `json:"online"`      // 是否在线 1.在线 0.离线
+	Devices    []*userPresenceDevice `json:"devices"`     // 在线设备
+	LastSeen   int64                 `json:"last_seen"`   // 最后在线时间（秒），0表示没有记录
+	Status     uint8                 `json:"status"`      // 用户设置的状态 0.available 1.away 2.busy
+	StatusText string                `json:"status_text"` // 用户设置的状态文本
+}
+
+type userPresenceDevice struct {
+	DeviceId    string `json:"device_id"`    // 设备id
+	DeviceFlag  uint8  `json:"device_flag"`  // 设备标记 0. APP 1.web 2.pc
+	DeviceLevel uint8  `json:"device_level"` // 设备等级 0.从设备 1.主设备
+}
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/RussellLuo/timingwheel"
+	"github.com/WuKongIM/WuKongIM/pkg/trace"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+// 用户发送消息限速
+// 每个用户一个消息数量的令牌桶和一个消息字节数的令牌桶，用户连接发送的消息在进入频道流程前（userReactor）需要同时拿到两个桶的令牌，
+// 否则直接回应ReasonRateLimit的sendack。系统账号不限速。
+// 限速按节点生效：连接发送的消息在连接所在的节点上进入频道流程，令牌桶也只在这个节点上，不做跨节点的同步。
+// 用户的连接分布在多个节点上时，每个节点各自按配置限速，用户整体的速率上限为（有连接的节点数 x 配置的速率）。
+type userRateLimiter struct {
+	s *Server
+	wklog.Log
+
+	mu       sync.Mutex
+	limiters map[string]*userLimiter // 用户的令牌桶，key为uid
+
+	cleanTimer *timingwheel.Timer
+}
+
+type userLimiter struct {
+	msg       *rate.Limiter // 消息数量令牌桶，nil表示不限制
+	bytes     *rate.Limiter // 消息字节数令牌桶，nil表示不限制
+	activeAt  time.Time     // 最后发送消息的时间
+	limitedAt time.Time     // 最后被限速的时间
+}
+
+// 限速状态的统计间隔
+const userRateLimitStatInterval = time.Second * 10
+
+func newUserRateLimiter(s *Server) *userRateLimiter {
+	return &userRateLimiter{
+		s:        s,
+		Log:      wklog.NewWKLog("userRateLimiter"),
+		limiters: make(map[string]*userLimiter),
+	}
+}
+
+func (u *userRateLimiter) start() error {
+	u.cleanTimer = u.s.Schedule(userRateLimitStatInterval, u.clean)
+	return nil
+}
+
+func (u *userRateLimiter) stop() {
+	if u.cleanTimer != nil {
+		u.cleanTimer.Stop()
+	}
+}
+
+// 是否开启了限速
+func (u *userRateLimiter) enabled() bool {
+	opts := u.s.opts.RateLimit
+	return opts.MsgRate > 0 || opts.ByteRate > 0 || len(opts.Overrides) > 0
+}
+
+// 用户是否允许发送消息，size为消息的字节数
+func (u *userRateLimiter) allow(uid string, size int) bool {
+	if !u.enabled() || u.s.systemUIDManager.SystemUID(uid) {
+		return true
+	}
+	now := time.Now()
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	limiter := u.limiters[uid]
+	if limiter == nil {
+		limiter = u.newLimiter(uid)
+		u.limiters[uid] = limiter
+	}
+	limiter.activeAt = now
+
+	if limiter.allow(now, size) {
+		return true
+	}
+	limiter.limitedAt = now
+	trace.GlobalTrace.Metrics.App().RateLimitedMsgCountAdd(1)
+	return false
+}
+
+func (u *userRateLimiter) newLimiter(uid string) *userLimiter {
+	opts := u.s.opts.RateLimit
+	msgRate, msgBurst := opts.MsgRate, opts.MsgBurst
+	byteRate, byteBurst := opts.ByteRate, opts.ByteBurst
+	if override, ok := opts.Overrides[uid]; ok {
+		msgRate, msgBurst = override.MsgRate, 0
+		byteRate, byteBurst = override.ByteRate, 0
+	}
+
+	limiter := &userLimiter{}
+	if msgRate > 0 {
+		if msgBurst <= 0 {
+			msgBurst = int(math.Ceil(msgRate))
+		}
+		limiter.msg = rate.NewLimiter(rate.Limit(msgRate), msgBurst)
+	}
+	if byteRate > 0 {
+		if byteBurst <= 0 {
+			byteBurst = byteRate
+		}
+		limiter.bytes = rate.NewLimiter(rate.Limit(byteRate), byteBurst)
+	}
+	return limiter
+}
+
+// 同时从两个令牌桶获取令牌，任意一个桶的令牌不够都不允许发送（已获取的令牌会归还）
+func (l *userLimiter) allow(now time.Time, size int) bool {
+	var msgReservation *rate.Reservation
+	if l.msg != nil {
+		msgReservation = l.msg.ReserveN(now, 1)
+		if !msgReservation.OK() || msgReservation.DelayFrom(now) > 0 {
+			msgReservation.CancelAt(now)
+			return false
+		}
+	}
+	if l.bytes != nil && size > 0 {
+		bytesReservation := l.bytes.ReserveN(now, size)
+		if !bytesReservation.OK() || bytesReservation.DelayFrom(now) > 0 { // 超过突发上限的消息永远不允许发送
+			bytesReservation.CancelAt(now)
+			if msgReservation != nil {
+				msgReservation.CancelAt(now)
+			}
+			return false
+		}
+	}
+	return true
+}
+
+// 统计被限速的用户数，并清除长时间没有发送消息的用户令牌桶（令牌桶已经满了，清除后重建的效果一样）
+func (u *userRateLimiter) clean() {
+	now := time.Now()
+	var limitedCount int64
+
+	u.mu.Lock()
+	for uid, limiter := range u.limiters {
+		if now.Sub(limiter.limitedAt) < userRateLimitStatInterval {
+			limitedCount++
+		}
+		if now.Sub(limiter.activeAt) >= time.Minute {
+			delete(u.limiters, uid)
+		}
+	}
+	u.mu.Unlock()
+
+	trace.GlobalTrace.Metrics.App().RateLimitedUserCountSet(limitedCount)
+	if limitedCount > 0 {
+		u.Info("users are rate limited", zap.Int64("count", limitedCount))
+	}
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/trace"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestUserRateLimiter(opt ...Option) *userRateLimiter {
+	if trace.GlobalTrace == nil { // 被限速时统计
+		trace.SetGlobalTrace(trace.New(context.Background(), trace.NewOptions()))
+	}
+	s := &Server{opts: NewOptions(opt...)}
+	s.systemUIDManager = NewSystemUIDManager(s)
+	s.systemUIDManager.loaded.Store(true)
+	return newUserRateLimiter(s)
+}
+
+func TestUserRateLimiterMsg(t *testing.T) {
+	u := newTestUserRateLimiter(WithRateLimitMsgRate(10, 2))
+	limiter := u.newLimiter("u1")
+	now := time.Now()
+
+	// 突发上限内允许发送
+	assert.True(t, limiter.allow(now, 10))
+	assert.True(t, limiter.allow(now, 10))
+	assert.False(t, limiter.allow(now, 10))
+
+	// 每100毫秒补充一个令牌
+	assert.False(t, limiter.allow(now.Add(time.Millisecond*50), 10))
+	assert.True(t, limiter.allow(now.Add(time.Millisecond*100), 10))
+	assert.False(t, limiter.allow(now.Add(time.Millisecond*100), 10))
+
+	// 补充的令牌不超过突发上限
+	later := now.Add(time.Second * 10)
+	assert.True(t, limiter.allow(later, 10))
+	assert.True(t, limiter.allow(later, 10))
+	assert.False(t, limiter.allow(later, 10))
+}
+
+func TestUserRateLimiterBytes(t *testing.T) {
+	u := newTestUserRateLimiter(WithRateLimitMsgRate(10, 1), WithRateLimitByteRate(100, 100))
+	limiter := u.newLimiter("u1")
+	now := time.Now()
+
+	// 超过突发上限的消息永远不允许发送，并且归还已获取的消息令牌
+	assert.False(t, limiter.allow(now, 101))
+	assert.True(t, limiter.allow(now, 60))
+
+	// 字节数令牌不够
+	later := now.Add(time.Millisecond * 100)
+	assert.False(t, limiter.allow(later, 60))
+	assert.True(t, limiter.allow(later, 40))
+}
+
+func TestUserRateLimiterAllow(t *testing.T) {
+	u := newTestUserRateLimiter(WithRateLimitMsgRate(1, 1), WithRateLimitOverride("vip", RateLimitOverride{MsgRate: 100}))
+
+	assert.True(t, u.allow("u1", 10))
+	assert.False(t, u.allow("u1", 10))
+	assert.True(t, u.allow("u2", 10)) // 按用户限速
+
+	// 指定用户的限速
+	for i := 0; i < 100; i++ {
+		assert.True(t, u.allow("vip", 10))
+	}
+
+	// 系统账号不限速
+	for i := 0; i < 10; i++ {
+		assert.True(t, u.allow(u.s.opts.SystemUID, 10))
+	}
+
+	// 限速只在本节点生效，其他节点的令牌桶各自独立
+	other := newTestUserRateLimiter(WithRateLimitMsgRate(1, 1))
+	assert.True(t, other.allow("u1", 10))
+
+	// 长时间没有发送消息的令牌桶被清除
+	u.limiters["u1"].activeAt = time.Now().Add(-time.Minute)
+	u.clean()
+	assert.NotContains(t, u.limiters, "u1")
+	assert.Contains(t, u.limiters, "u2")
+}
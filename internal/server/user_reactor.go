@@ -160,6 +160,17 @@ func (u *userReactor) getConnCount(uid string) int {
 	return len(u.getConns(uid))
 }
 
+// 获取用户已认证的连接数量
+func (u *userReactor) getAuthConnCount(uid string) int {
+	count := 0
+	for _, conn := range u.getConns(uid) {
+		if conn.isAuth.Load() {
+			count++
+		}
+	}
+	return count
+}
+
 // 获取用户的所有连接
 func (u *userReactor) getConns(uid string) []*connContext {
 	userHandler := u.reactorSub(uid).getUserHandler(uid)
@@ -216,6 +227,15 @@ func (u *userReactor) getAllConnCount() int {
 	return count
 }
 
+// 获取在本节点为领导的在线用户
+func (u *userReactor) getOnlineLeaderUids() []string {
+	uids := make([]string, 0)
+	for _, sub := range u.subs {
+		uids = append(uids, sub.getOnlineLeaderUids()...)
+	}
+	return uids
+}
+
 // func (u *userReactor) step(uid string, a UserAction) {
 // 	u.reactorSub(uid).step(uid, a)
 // }
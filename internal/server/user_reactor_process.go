@@ -131,7 +131,8 @@ func (r *userReactor) processAuth(req *userAuthReq) {
 
 	for _, msg := range req.messages {
 		r.Debug("processAuth", zap.String("uid", req.uid), zap.Int64("connId", msg.ConnId))
-		_, _ = r.handleAuth(req.uid, msg)
+		reasonCode, err := r.handleAuth(req.uid, msg)
+		r.s.sessionLogManager.recordAuth(req.uid, msg, reasonCode, err)
 	}
 	lastIndex := req.messages[len(req.messages)-1].Index
 	req.sub.step(req.uid, UserAction{
@@ -184,19 +185,58 @@ func (r *userReactor) handleAuth(uid string, msg ReactorUserMessage) (wkproto.Re
 			r.authResponseConnackAuthFail(connCtx)
 			return wkproto.ReasonAuthFail, errors.New("token is empty")
 		}
-		device, err := r.s.store.GetDevice(uid, connectPacket.DeviceFlag)
-		if err != nil {
-			r.Error("get device token err", zap.Error(err))
-			r.authResponseConnackAuthFail(connCtx)
-			return wkproto.ReasonAuthFail, err
+		if r.s.authHook != nil { // 委托第三方认证服务认证
+			result, err := r.s.authHook.auth(newAuthHookReq(connectPacket, msg.ConnMeta))
+			if err != nil {
+				r.Error("auth hook request fail", zap.Error(err), zap.String("uid", uid))
+				r.authResponseConnackAuthFail(connCtx)
+				return wkproto.ReasonAuthFail, err
+			}
+			if result.ReasonCode != wkproto.ReasonSuccess {
+				r.Warn("auth hook verify fail", zap.String("uid", uid), zap.String("reasonCode", result.ReasonCode.String()), zap.String("reason", result.Reason), zap.Any("conn", connCtx))
+				r.authResponseConnack(connCtx, result.ReasonCode)
+				return result.ReasonCode, errors.New("auth hook verify fail")
+			}
+			devceLevel = result.DeviceLevel
+		} else if r.s.clientTokenVerifier != nil { // token为签名的jwt，本地验签
+			claims, err := r.s.clientTokenVerifier.verify(connectPacket.Token)
+			if err != nil {
+				r.Warn("client jwt verify fail", zap.Error(err), zap.String("uid", uid), zap.Any("conn", connCtx))
+				r.authResponseConnackAuthFail(connCtx)
+				return wkproto.ReasonAuthFail, err
+			}
+			if claims.UID != uid || claims.DeviceFlag != connectPacket.DeviceFlag {
+				r.Warn("client jwt claims not match", zap.String("uid", uid), zap.String("tokenUid", claims.UID), zap.Uint8("deviceFlag", connectPacket.DeviceFlag.ToUint8()), zap.Uint8("tokenDeviceFlag", claims.DeviceFlag.ToUint8()))
+				r.authResponseConnackAuthFail(connCtx)
+				return wkproto.ReasonAuthFail, errors.New("client jwt claims not match")
+			}
+			device, err := r.s.store.GetDevice(uid, connectPacket.DeviceFlag)
+			if err != nil && err != wkdb.ErrNotFound {
+				r.Error("get device err", zap.Error(err), zap.String("uid", uid))
+				r.authResponseConnackAuthFail(connCtx)
+				return wkproto.ReasonAuthFail, err
+			}
+			if clientTokenRevoked(claims, device) { // 设备被吊销或强制退出后，之前签发的token不能再连接（设备记录为本地读取，不做缓存）
+				r.Warn("client jwt revoked", zap.String("uid", uid), zap.Uint8("deviceFlag", connectPacket.DeviceFlag.ToUint8()), zap.Time("issuedAt", claims.IssuedAt), zap.Timep("revokedAt", device.RevokedAt))
+				r.authResponseConnackAuthFail(connCtx)
+				return wkproto.ReasonAuthFail, errors.New("client jwt revoked")
+			}
+			devceLevel = claims.DeviceLevel
+		} else {
+			device, err := r.s.store.GetDevice(uid, connectPacket.DeviceFlag)
+			if err != nil {
+				r.Error("get device token err", zap.Error(err))
+				r.authResponseConnackAuthFail(connCtx)
+				return wkproto.ReasonAuthFail, err
 
+			}
+			if device.Token != connectPacket.Token {
+				r.Error("token verify fail", zap.String("expectToken", device.Token), zap.String("actToken", connectPacket.Token), zap.Any("conn", connCtx))
+				r.authResponseConnackAuthFail(connCtx)
+				return wkproto.ReasonAuthFail, errors.New("token verify fail")
+			}
+			devceLevel = wkproto.DeviceLevel(device.DeviceLevel)
 		}
-		if device.Token != connectPacket.Token {
-			r.Error("token verify fail", zap.String("expectToken", device.Token), zap.String("actToken", connectPacket.Token), zap.Any("conn", connCtx))
-			r.authResponseConnackAuthFail(connCtx)
-			return wkproto.ReasonAuthFail, errors.New("token verify fail")
-		}
-		devceLevel = wkproto.DeviceLevel(device.DeviceLevel)
 	} else {
 		devceLevel = wkproto.DeviceLevelSlave // 默认都是slave设备
 	}
@@ -217,6 +257,39 @@ func (r *userReactor) handleAuth(uid string, msg ReactorUserMessage) (wkproto.Re
 		r.authResponseConnack(connCtx, wkproto.ReasonBan)
 		return wkproto.ReasonBan, errors.New("device is ban")
 	}
+	userBan, err := r.s.userBanManager.getLocalBan(uid) // 认证在用户的槽领导节点上执行，直接读取封禁记录
+	if err != nil {
+		r.Error("get user ban err", zap.Error(err), zap.String("uid", uid))
+		r.authResponseConnackAuthFail(connCtx)
+		return wkproto.ReasonAuthFail, err
+	}
+	if userBan != nil && !userBan.Expired(time.Now()) {
+		r.Info("user is ban", zap.String("uid", uid), zap.String("reason", userBan.Reason))
+		r.authResponseConnack(connCtx, wkproto.ReasonBan)
+		return wkproto.ReasonBan, errors.New("user is ban")
+	}
+
+	// -------------------- conn limit --------------------
+	evicts, ok := r.s.connLimiter.check(connCtx, connectPacket.DeviceID, devceLevel)
+	if !ok {
+		r.Warn("too many connections, reject", zap.String("uid", uid), zap.String("deviceFlag", connectPacket.DeviceFlag.String()), zap.String("deviceID", connectPacket.DeviceID))
+		r.authResponseConnack(connCtx, wkproto.ReasonConnectKick)
+		return wkproto.ReasonConnectKick, errors.New("too many connections")
+	}
+	for _, evict := range evicts {
+		r.Info("too many connections, evict oldest conn", zap.String("uid", uid), zap.Int64("connId", evict.connId), zap.String("deviceId", evict.deviceId))
+		_ = r.s.userReactor.writePacket(evict, &wkproto.DisconnectPacket{
+			ReasonCode: wkproto.ReasonConnectKick,
+			Reason:     "too many connections",
+		})
+		r.s.sessionLogManager.recordKick(evict, wkproto.ReasonConnectKick, "too many connections")
+		r.s.userReactor.removeConnById(evict.uid, evict.connId)
+		r.s.timingWheel.AfterFunc(time.Second*2, func(cn *connContext) func() {
+			return func() {
+				cn.close()
+			}
+		}(evict))
+	}
 
 	// -------------------- get message encrypt key --------------------
 	dhServerPrivKey, dhServerPublicKey := wkutil.GetCurve25519KeypPair() // 生成服务器的DH密钥对
@@ -244,6 +317,7 @@ func (r *userReactor) handleAuth(uid string, msg ReactorUserMessage) (wkproto.Re
 						ReasonCode: wkproto.ReasonConnectKick,
 						Reason:     "login in other device",
 					})
+					r.s.sessionLogManager.recordKick(oldConn, wkproto.ReasonConnectKick, "login in other device")
 					r.s.timingWheel.AfterFunc(time.Second*5, func(cn *connContext) func() {
 						return func() {
 							cn.close()
@@ -288,6 +362,7 @@ func (r *userReactor) handleAuth(uid string, msg ReactorUserMessage) (wkproto.Re
 	connCtx.aesKey = aesKey
 	connCtx.deviceLevel = devceLevel
 	connCtx.protoVersion = lastVersion
+	connCtx.clientIp = msg.ConnMeta.IP
 	connCtx.isAuth.Store(true)
 
 	if connCtx.isRealConn {
@@ -316,10 +391,66 @@ func (r *userReactor) handleAuth(uid string, msg ReactorUserMessage) (wkproto.Re
 	deviceOnlineCount := r.s.userReactor.getConnCountByDeviceFlag(uid, connectPacket.DeviceFlag)
 	totalOnlineCount := r.s.userReactor.getConnCount(uid)
 	r.s.webhook.Online(uid, connectPacket.DeviceFlag, connCtx.connId, deviceOnlineCount, totalOnlineCount)
+	// 第一个连接上线时通知在线状态的订阅者
+	if r.s.userReactor.getAuthConnCount(uid) == 1 {
+		r.s.presenceSubManager.onPresenceChange(uid, wkproto.DeviceFlag(connectPacket.DeviceFlag), true)
+	}
+	// 记录设备的登录信息
+	r.updateDeviceLogin(uid, connectPacket.DeviceFlag, devceLevel, msg.ConnMeta)
 
 	return wkproto.ReasonSuccess, nil
 }
 
+// 同一设备的客户端信息没有变化时，登录信息最多每隔这个时间写入一次（每次写入都是一次raft提案）
+const deviceLoginUpdateInterval = time.Minute * 5
+
+// 异步记录设备的登录信息，设备不存在则添加设备（未开启token验证时设备不会通过 /user/token 添加）
+func (r *userReactor) updateDeviceLogin(uid string, deviceFlag wkproto.DeviceFlag, deviceLevel wkproto.DeviceLevel, meta connMeta) {
+	if uid == r.s.opts.ManagerUID || r.s.systemUIDManager.SystemUID(uid) {
+		return
+	}
+	err := r.processGoPool.Submit(func() {
+		device, err := r.s.store.GetDevice(uid, deviceFlag)
+		if err != nil && err != wkdb.ErrNotFound {
+			r.Error("updateDeviceLogin: get device failed", zap.Error(err), zap.String("uid", uid), zap.Uint8("deviceFlag", deviceFlag.ToUint8()))
+			return
+		}
+		now := time.Now()
+		if !wkdb.IsEmptyDevice(device) && device.LastLoginAt != nil && now.Sub(*device.LastLoginAt) < deviceLoginUpdateInterval &&
+			device.LastLoginIP == meta.IP && device.ClientVersion == meta.ClientVersion && device.UserAgent == meta.UserAgent { // 频繁重连时不重复写入
+			return
+		}
+		if wkdb.IsEmptyDevice(device) {
+			device = wkdb.Device{
+				Id:          r.s.store.NextPrimaryKey(),
+				Uid:         uid,
+				DeviceFlag:  uint64(deviceFlag),
+				DeviceLevel: uint8(deviceLevel),
+				CreatedAt:   &now,
+				UpdatedAt:   &now,
+			}
+			if err = r.s.store.AddDevice(device); err != nil {
+				r.Error("updateDeviceLogin: add device failed", zap.Error(err), zap.String("uid", uid), zap.Uint8("deviceFlag", deviceFlag.ToUint8()))
+				return
+			}
+		}
+		err = r.s.store.UpdateDeviceLogin(wkdb.Device{
+			Id:            device.Id,
+			Uid:           uid,
+			LastLoginAt:   &now,
+			LastLoginIP:   meta.IP,
+			ClientVersion: meta.ClientVersion,
+			UserAgent:     meta.UserAgent,
+		})
+		if err != nil {
+			r.Error("updateDeviceLogin: update device login failed", zap.Error(err), zap.String("uid", uid), zap.Uint8("deviceFlag", deviceFlag.ToUint8()))
+		}
+	})
+	if err != nil {
+		r.Error("submit updateDeviceLogin failed", zap.Error(err), zap.String("uid", uid))
+	}
+}
+
 // 获取客户端的aesKey和aesIV
 // dhServerPrivKey  服务端私钥
 func (r *userReactor) getClientAesKeyAndIV(clientKey string, dhServerPrivKey [32]byte) ([]byte, []byte, error) {
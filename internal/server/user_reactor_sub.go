@@ -105,6 +105,18 @@ func (u *userReactorSub) stepNoWait(uid string, action UserAction) error {
 
 func (u *userReactorSub) proposeSend(conn *connContext, messageId int64, sendPacket *wkproto.SendPacket, wait bool) error {
 
+	// 用户发送消息限速，被限速的消息不进入频道流程
+	if !u.r.s.userRateLimiter.allow(conn.uid, len(sendPacket.Payload)) {
+		u.Debug("user is rate limited", zap.String("uid", conn.uid), zap.Int64("connId", conn.connId), zap.String("channelId", sendPacket.ChannelID))
+		return conn.writeDirectlyPacket(&wkproto.SendackPacket{
+			Framer:      sendPacket.Framer,
+			MessageID:   messageId,
+			ClientSeq:   sendPacket.ClientSeq,
+			ClientMsgNo: sendPacket.ClientMsgNo,
+			ReasonCode:  wkproto.ReasonRateLimit,
+		})
+	}
+
 	return u.r.s.channelReactor.proposeSend(messageId, conn.uid, conn.deviceId, conn.connId, u.r.s.opts.Cluster.NodeId, true, sendPacket, wait)
 }
 
@@ -353,6 +365,18 @@ func (u *userReactorSub) getAllConnCount() int {
 	return count
 }
 
+// 获取在本节点为领导的在线用户
+func (u *userReactorSub) getOnlineLeaderUids() []string {
+	uids := make([]string, 0, u.userHandlers.len())
+	u.userHandlers.iter(func(uh *userHandler) bool {
+		if uh.role == userRoleLeader && uh.getConnCount() > 0 {
+			uids = append(uids, uh.uid)
+		}
+		return true
+	})
+	return uids
+}
+
 // func (u *userReactorSub) existUser(uid string) bool {
 // 	return u.users.exist(uid)
 // }
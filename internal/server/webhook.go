@@ -9,10 +9,12 @@ import (
 	"net"
 	"net/http"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/grpcpool"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
 	"github.com/WuKongIM/WuKongIM/pkg/wkhook"
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
 	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
@@ -23,6 +25,7 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
 )
 
 type webhook struct {
@@ -35,6 +38,10 @@ type webhook struct {
 	onlinestatusLock sync.RWMutex
 	onlinestatusList []string
 	focusEvents      map[string]struct{} // 用户关注的事件类型,如果为空则推送所有类型
+	outbox           *webhookOutbox      // 事件发件箱
+	deadLetters      *webhookDeadLetters // 死信
+	endpoints        *webhookEndpoints   // 运行时添加的webhook端点
+	sinks            *webhookSinks       // 事件sink
 }
 
 func newWebhook(s *Server) *webhook {
@@ -73,7 +80,7 @@ func newWebhook(s *Server) *webhook {
 		}
 	}
 
-	return &webhook{
+	w := &webhook{
 		s:                s,
 		Log:              wklog.NewWKLog("Webhook"),
 		eventPool:        eventPool,
@@ -97,15 +104,28 @@ func newWebhook(s *Server) *webhook {
 		},
 		focusEvents: focusEvents,
 	}
+	w.outbox = newWebhookOutbox(w)
+	w.deadLetters = newWebhookDeadLetters(w)
+	w.endpoints = newWebhookEndpoints(w)
+	w.sinks = newWebhookSinks(w)
+	return w
 }
 
 func (w *webhook) Start() {
-	go w.notifyQueueLoop()
+	// 运行时可以添加webhook端点，所以发件箱总是开启
+	w.outbox.start()
+	w.deadLetters.start()
+	w.endpoints.start()
+	go w.moveNotifyQueueToOutbox()
 	go w.loopOnlineStatus()
 }
 
 func (w *webhook) Stop() {
+	w.deadLetters.stop()
+	w.endpoints.stop()
 	close(w.stoped)
+	w.outbox.waitStopped()
+	w.sinks.stop()
 }
 
 // Online 用户设备上线通知
@@ -128,34 +148,52 @@ func (w *webhook) Offline(uid string, deviceFlag wkproto.DeviceFlag, connId int6
 	w.Debug("User offline", zap.String("uid", uid), zap.String("deviceFlag", deviceFlag.String()))
 }
 
-// TriggerEvent 触发事件
+// TriggerEvent 触发事件（配置的webhook和关注了此事件的端点、sink各自写入发件箱后投递）
 func (w *webhook) TriggerEvent(event *Event) {
-	if !w.s.opts.WebhookOn() { // 没设置webhook直接忽略
+	globalOn := w.s.opts.WebhookOn() && w.isEventFocused(event.Event)
+	endpoints := w.endpoints.focused(event.Event, event.ChannelType)
+	sinks := w.sinks.focused(event.Event)
+	if !globalOn && len(endpoints) == 0 && len(sinks) == 0 {
 		return
 	}
-	err := w.eventPool.Submit(func() {
-		jsonData, err := json.Marshal(event.Data)
-		if err != nil {
-			w.Error("webhook的event数据不能json化！", zap.Error(err))
-			return
-		}
-
-		if w.s.opts.WebhookGRPCOn() {
-			err = w.sendWebhookForGRPC(event.Event, jsonData)
-		} else {
-			err = w.sendWebhookForHttp(event.Event, jsonData)
-		}
-		if err != nil {
-			w.Error("请求webhook失败！", zap.Error(err), zap.String("event", event.Event))
-			return
-		}
-
-	})
+	jsonData, err := json.Marshal(event.Data)
 	if err != nil {
-		w.Error("提交事件失败", zap.Error(err))
+		w.Error("webhook的event数据不能json化！", zap.Error(err))
+		return
+	}
+	events := make([]webhookOutboxEvent, 0, 1+len(endpoints)+len(sinks))
+	if globalOn {
+		events = append(events, webhookOutboxEvent{event: event.Event, orderKey: event.OrderKey, data: jsonData})
+	}
+	for _, endpoint := range endpoints {
+		events = append(events, webhookOutboxEvent{endpoint: endpoint.endpoint.Name, event: event.Event, orderKey: event.OrderKey, data: jsonData})
+	}
+	for _, sink := range sinks {
+		events = append(events, webhookOutboxEvent{endpoint: sink.endpoint(), event: event.Event, orderKey: event.OrderKey, data: jsonData})
+	}
+	if err = w.outbox.addEvents(events); err != nil {
+		w.Error("添加事件到发件箱失败！", zap.Error(err), zap.String("event", event.Event))
 	}
 }
 
+// 投递事件到指定的端点（端点为空表示配置文件中的webhook）
+func (w *webhook) send(endpoint string, deliveryId string, event string, data []byte) error {
+	if isChannelWebhookEndpoint(endpoint) {
+		return w.s.channelWebhook.sendTo(endpoint, deliveryId, event, data)
+	}
+	if endpoint != "" {
+		return w.endpoints.sendTo(endpoint, deliveryId, event, data)
+	}
+	if !w.s.opts.WebhookOn() { // 配置文件中的webhook已关闭，丢弃剩余的事件
+		w.Warn("webhook is off, discard event", zap.String("event", event), zap.String("deliveryId", deliveryId))
+		return nil
+	}
+	if w.s.opts.WebhookGRPCOn() {
+		return w.sendWebhookForGRPC(deliveryId, event, data)
+	}
+	return w.sendWebhookForHttp(deliveryId, event, data)
+}
+
 func (w *webhook) notifyOfflineMsg(msg ReactorChannelMessage, subscribers []string) {
 	compress := ""
 	toUIDs := subscribers
@@ -175,7 +213,9 @@ func (w *webhook) notifyOfflineMsg(msg ReactorChannelMessage, subscribers []stri
 	}
 	// 推送离线到上层应用
 	w.TriggerEvent(&Event{
-		Event: EventMsgOffline,
+		Event:       EventMsgOffline,
+		OrderKey:    wkutil.ChannelToKey(msg.SendPacket.ChannelID, msg.SendPacket.ChannelType), // 同一个频道的离线消息按顺序投递
+		ChannelType: msg.SendPacket.ChannelType,
 		Data: MessageOfflineNotify{
 			MessageResp: MessageResp{
 				Header: MessageHeader{
@@ -204,151 +244,98 @@ func (w *webhook) notifyOfflineMsg(msg ReactorChannelMessage, subscribers []stri
 	})
 }
 
-// 通知上层应用 TODO: 此初报错可以做一个邮件报警处理类的东西，
-func (w *webhook) notifyQueueLoop() {
-	errorSleepTime := time.Second * 1 // 发生错误后sleep时间
-	ticker := time.NewTicker(w.s.opts.Webhook.MsgNotifyEventPushInterval)
-	defer ticker.Stop()
-	errMessageIDMap := make(map[int64]int) // 记录错误的消息ID value为错误次数
-	if w.s.opts.WebhookOn() {
-		for {
-			messages, err := w.s.store.GetMessagesOfNotifyQueue(w.s.opts.Webhook.MsgNotifyEventCountPerPush)
-			if err != nil {
-				w.Error("获取通知队列内的消息失败！", zap.Error(err))
-				// 如果系统出现错误，就移除第一个
-				err = w.s.store.DB().RemoveMessagesOfNotifyQueueCount(1)
-				if err != nil {
-					w.Error("RemoveMessagesOfNotifyQueueCount: 移除通知对列消息失败！", zap.Error(err))
-				}
-				time.Sleep(errorSleepTime) // 如果报错就休息下
-				continue
-			}
-			if len(messages) > 0 {
-				messageResps := make([]*MessageResp, 0, len(messages))
-				for _, msg := range messages {
-					resp := &MessageResp{}
-					resp.from(msg, w.s)
-					messageResps = append(messageResps, resp)
-				}
-				messageData, err := json.Marshal(messageResps)
-				if err != nil {
-					w.Error("第三方消息通知的event数据不能json化！", zap.Error(err))
-					time.Sleep(errorSleepTime) // 如果报错就休息下
-					continue
-				}
-
-				if w.s.opts.WebhookGRPCOn() {
-					err = w.sendWebhookForGRPC(EventMsgNotify, messageData)
-				} else {
-					err = w.sendWebhookForHttp(EventMsgNotify, messageData)
-				}
-				if err != nil {
-					w.Error("请求所有消息通知webhook失败！", zap.Error(err))
-					errMessageIDs := make([]int64, 0, len(messages))
-					for _, message := range messages {
-						errCount := errMessageIDMap[message.MessageID]
-						errCount++
-						errMessageIDMap[message.MessageID] = errCount
-						if errCount >= w.s.opts.Webhook.MsgNotifyEventRetryMaxCount {
-							errMessageIDs = append(errMessageIDs, message.MessageID)
-						}
-					}
-					if len(errMessageIDs) > 0 {
-						w.Error("消息通知失败超过最大次数！", zap.Int64s("messageIDs", errMessageIDs))
-						err = w.s.store.RemoveMessagesOfNotifyQueue(errMessageIDs)
-						if err != nil {
-							w.Warn("从通知队列里移除消息失败！", zap.Error(err), zap.Int64s("messageIDs", errMessageIDs))
-						}
-						for _, errMessageID := range errMessageIDs {
-							delete(errMessageIDMap, errMessageID)
-						}
-					}
-					time.Sleep(errorSleepTime) // 如果报错就休息下
-					continue
-				}
-
-				messageIDs := make([]int64, 0, len(messages))
-				for _, message := range messages {
-					messageID := message.MessageID
-					messageIDs = append(messageIDs, messageID)
-
-					delete(errMessageIDMap, messageID)
-				}
-				err = w.s.store.RemoveMessagesOfNotifyQueue(messageIDs)
-				if err != nil {
-					w.Warn("从通知队列里移除消息失败！", zap.Error(err), zap.Int64s("messageIDs", messageIDs), zap.String("Webhook", w.s.opts.Webhook.HTTPAddr))
-					time.Sleep(errorSleepTime) // 如果报错就休息下
-					continue
-				}
-			}
-
-			select {
-			case <-ticker.C:
-			case <-w.stoped:
-				return
-			}
+// notifyMessages 将存储成功的消息按MsgNotifyEventCountPerPush分批作为msg.notify事件写入发件箱（配置文件中的webhook）
+func (w *webhook) notifyMessages(messages []wkdb.Message) error {
+	countPerPush := w.s.opts.Webhook.MsgNotifyEventCountPerPush
+	if countPerPush <= 0 {
+		countPerPush = len(messages)
+	}
+	events := make([]webhookOutboxEvent, 0, len(messages)/countPerPush+1)
+	for len(messages) > 0 {
+		count := len(messages)
+		if count > countPerPush {
+			count = countPerPush
+		}
+		batch := messages[:count]
+		messages = messages[count:]
+
+		messageResps := make([]*MessageResp, 0, len(batch))
+		for _, msg := range batch {
+			resp := &MessageResp{}
+			resp.from(msg, w.s)
+			messageResps = append(messageResps, resp)
+		}
+		data, err := json.Marshal(messageResps)
+		if err != nil {
+			return err
 		}
+		events = append(events, webhookOutboxEvent{
+			event:      EventMsgNotify,
+			orderKey:   EventMsgNotify, // 消息通知按顺序投递
+			deliveryId: notifyDeliveryId(batch),
+			data:       data,
+		})
 	}
+	return w.outbox.addEvents(events)
 }
 
-func (w *webhook) loopOnlineStatus() {
-	if !w.s.opts.WebhookOn() {
-		return
-	}
-	opLen := 0    // 最后一次操作在线状态数组的长度
-	errCount := 0 // webhook请求失败重试次数
+// 将旧版本消息通知队列中还没有推送的消息移入发件箱（升级后只需要执行一次）
+func (w *webhook) moveNotifyQueueToOutbox() {
 	for {
-		if opLen == 0 {
-			w.onlinestatusLock.Lock()
-			opLen = len(w.onlinestatusList)
-			w.onlinestatusLock.Unlock()
+		messages, err := w.s.store.GetMessagesOfNotifyQueue(w.s.opts.Webhook.MsgNotifyEventCountPerPush)
+		if err != nil {
+			w.Error("获取通知队列内的消息失败！", zap.Error(err))
+			return
 		}
-		if opLen == 0 {
-			time.Sleep(time.Second * 2) // 没有数据就休息2秒
-			continue
+		if len(messages) == 0 {
+			return
 		}
-		w.onlinestatusLock.Lock()
-		data := w.onlinestatusList[:opLen]
-		w.onlinestatusLock.Unlock()
-		jsonData, err := json.Marshal(data)
-		if err != nil {
-			w.Error("webhook的event数据不能json化！", zap.Error(err))
-			time.Sleep(time.Second * 1)
-			continue
+		if err = w.notifyMessages(messages); err != nil {
+			w.Error("通知队列内的消息移入发件箱失败！", zap.Error(err))
+			return
 		}
-
-		if w.s.opts.WebhookGRPCOn() {
-			err = w.sendWebhookForGRPC(EventOnlineStatus, jsonData)
-		} else {
-			err = w.sendWebhookForHttp(EventOnlineStatus, jsonData)
+		messageIDs := make([]int64, 0, len(messages))
+		for _, message := range messages {
+			messageIDs = append(messageIDs, message.MessageID)
 		}
-		if err != nil {
-			errCount++
-			w.Error("请求在线状态webhook失败！", zap.Error(err))
-			if errCount >= w.s.opts.Webhook.MsgNotifyEventRetryMaxCount {
-				w.Error("请求在线状态webhook失败通知超过最大次数！", zap.Int("MsgNotifyEventRetryMaxCount", w.s.opts.Webhook.MsgNotifyEventRetryMaxCount))
-
-				w.onlinestatusLock.Lock()
-				w.onlinestatusList = w.onlinestatusList[opLen:]
-				opLen = 0
-				w.onlinestatusLock.Unlock()
-
-				errCount = 0
-			}
-
-			time.Sleep(time.Second * 1) // 如果报错就休息下
-			continue
+		if err = w.s.store.RemoveMessagesOfNotifyQueue(messageIDs); err != nil {
+			w.Error("从通知队列里移除消息失败！", zap.Error(err), zap.Int64s("messageIDs", messageIDs))
+			return
 		}
+		select {
+		case <-w.stoped:
+			return
+		default:
+		}
+	}
+}
 
+func (w *webhook) loopOnlineStatus() {
+	// 定时将这段时间内的在线状态合并成一个事件写入发件箱，在线状态事件之间按顺序投递
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-w.stoped:
+			return
+		}
 		w.onlinestatusLock.Lock()
-		w.onlinestatusList = w.onlinestatusList[opLen:]
-		opLen = 0
+		data := w.onlinestatusList
+		w.onlinestatusList = make([]string, 0)
 		w.onlinestatusLock.Unlock()
-
+		if len(data) == 0 {
+			continue
+		}
+		w.TriggerEvent(&Event{
+			Event:    EventOnlineStatus,
+			OrderKey: EventOnlineStatus,
+			Data:     data,
+		})
 	}
 }
 
-func (w *webhook) sendWebhookForHttp(event string, data []byte) error {
+func (w *webhook) sendWebhookForHttp(deliveryId string, event string, data []byte) error {
 	eventURL := fmt.Sprintf("%s?event=%s", w.s.opts.Webhook.HTTPAddr, event)
 	startTime := time.Now().UnixNano() / 1000 / 1000
 	w.Debug("webhook开始请求", zap.String("eventURL", eventURL))
@@ -356,7 +343,13 @@ func (w *webhook) sendWebhookForHttp(event string, data []byte) error {
 		w.Debug("webhook http非关注事件, 不推送", zap.String("event", event))
 		return nil
 	}
-	resp, err := w.httpClient.Post(eventURL, "application/json", bytes.NewBuffer(data))
+	req, err := http.NewRequest(http.MethodPost, eventURL, bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	newWebhookSign(w.s.opts.WebhookSecrets(), deliveryId, event, data).setHeader(req.Header)
+	resp, err := w.httpClient.Do(req)
 	w.Debug("webhook请求结束 耗时", zap.Int64("mill", time.Now().UnixNano()/1000/1000-startTime))
 	if err != nil {
 		w.Warn("调用第三方消息通知失败！", zap.String("Webhook", w.s.opts.Webhook.HTTPAddr), zap.Error(err))
@@ -371,7 +364,7 @@ func (w *webhook) sendWebhookForHttp(event string, data []byte) error {
 	return nil
 }
 
-func (w *webhook) sendWebhookForGRPC(event string, data []byte) error {
+func (w *webhook) sendWebhookForGRPC(deliveryId string, event string, data []byte) error {
 
 	startNow := time.Now()
 	startTime := startNow.UnixNano() / 1000 / 1000
@@ -396,6 +389,7 @@ func (w *webhook) sendWebhookForGRPC(event string, data []byte) error {
 
 	sendCtx, sendCancel := context.WithTimeout(context.Background(), time.Second*10)
 	defer sendCancel()
+	sendCtx = newWebhookSign(w.s.opts.WebhookSecrets(), deliveryId, event, data).appendToContext(sendCtx)
 	resp, err := cli.SendWebhook(sendCtx, &wkhook.EventReq{
 		Event: event,
 		Data:  data,
@@ -411,6 +405,54 @@ func (w *webhook) sendWebhookForGRPC(event string, data []byte) error {
 	return nil
 }
 
+// webhook投递的签名信息
+type webhookSign struct {
+	deliveryId string
+	timestamp  int64
+	signature  string // 未配置密钥时为空
+}
+
+func newWebhookSign(secrets []string, deliveryId string, event string, data []byte) webhookSign {
+	sign := webhookSign{
+		deliveryId: deliveryId,
+		timestamp:  time.Now().Unix(),
+	}
+	if len(secrets) > 0 {
+		sign.signature = wkhook.SignatureHeader(secrets, sign.timestamp, deliveryId, event, data)
+	}
+	return sign
+}
+
+func (s webhookSign) setHeader(header http.Header) {
+	header.Set(wkhook.HeaderDeliveryId, s.deliveryId)
+	header.Set(wkhook.HeaderTimestamp, strconv.FormatInt(s.timestamp, 10))
+	if s.signature != "" {
+		header.Set(wkhook.HeaderSignature, s.signature)
+	}
+}
+
+func (s webhookSign) appendToContext(ctx context.Context) context.Context {
+	kv := []string{
+		wkhook.MetadataDeliveryId, s.deliveryId,
+		wkhook.MetadataTimestamp, strconv.FormatInt(s.timestamp, 10),
+	}
+	if s.signature != "" {
+		kv = append(kv, wkhook.MetadataSignature, s.signature)
+	}
+	return metadata.AppendToOutgoingContext(ctx, kv...)
+}
+
+// 消息通知的投递id（由消息id生成，同一批消息的重试和重启后的重新投递id不变）
+func notifyDeliveryId(messages []wkdb.Message) string {
+	var b strings.Builder
+	b.WriteString(EventMsgNotify)
+	for _, message := range messages {
+		b.WriteString("-")
+		b.WriteString(strconv.FormatInt(message.MessageID, 10))
+	}
+	return wkutil.MD5(b.String())
+}
+
 func (w *webhook) isEventFocused(event string) bool {
 	if len(w.focusEvents) == 0 {
 		return true
@@ -426,21 +468,64 @@ const (
 	EventMsgNotify = "msg.notify"
 	// EventOnlineStatus 用户在线状态
 	EventOnlineStatus = "user.onlinestatus"
+	// EventChannelTmpExpired 临时频道过期
+	EventChannelTmpExpired = "channel.tmp.expired"
+	// EventChannelDisband 频道解散（解散的每一步都会通知，step为当前步骤）
+	EventChannelDisband = "channel.disband"
+	// EventUserSession 用户会话审计事件（连接、认证失败、被踢、断开）
+	EventUserSession = "user.session"
+	// EventChannelCreated 频道创建
+	EventChannelCreated = "channel.created"
+	// EventChannelUpdated 频道信息更新
+	EventChannelUpdated = "channel.updated"
+	// EventChannelDeleted 频道删除
+	EventChannelDeleted = "channel.deleted"
+	// EventChannelSubscribersAdded 频道添加订阅者
+	EventChannelSubscribersAdded = "channel.subscribers.added"
+	// EventChannelSubscribersRemoved 频道移除订阅者
+	EventChannelSubscribersRemoved = "channel.subscribers.removed"
+	// EventChannelDenylistChanged 频道黑名单变更
+	EventChannelDenylistChanged = "channel.denylist.changed"
+	// EventChannelAllowlistChanged 频道白名单变更
+	EventChannelAllowlistChanged = "channel.allowlist.changed"
+	// EventUserTokenUpdated 用户设备token更新（不包含token）
+	EventUserTokenUpdated = "user.token.updated"
+	// EventUserDeviceQuit 用户设备被强制退出或吊销
+	EventUserDeviceQuit = "user.device.quit"
+	// EventUserBanChanged 用户封禁或解封（包括到期自动解封）
+	EventUserBanChanged = "user.ban.changed"
 )
 
 var (
 	// eventWebHook 用于快速校验用用户配置的关注事件
 	eventWebHook = map[string]map[string]struct{}{
-		EventMsgOffline:   {},
-		EventMsgNotify:    {},
-		EventOnlineStatus: {},
+		EventMsgOffline:        {},
+		EventMsgNotify:         {},
+		EventOnlineStatus:      {},
+		EventChannelTmpExpired: {},
+		EventChannelDisband:    {},
+		EventUserSession:       {},
+
+		EventChannelCreated:            {},
+		EventChannelUpdated:            {},
+		EventChannelDeleted:            {},
+		EventChannelSubscribersAdded:   {},
+		EventChannelSubscribersRemoved: {},
+		EventChannelDenylistChanged:    {},
+		EventChannelAllowlistChanged:   {},
+		EventUserTokenUpdated:          {},
+		EventUserDeviceQuit:            {},
+		EventUserBanChanged:            {},
 	}
 )
 
 // Event Event
 type Event struct {
-	Event string      `json:"event"` // 事件标示
-	Data  interface{} `json:"data"`  // 事件数据
+	Event    string      `json:"event"` // 事件标示
+	Data     interface{} `json:"data"`  // 事件数据
+	OrderKey string      `json:"-"`     // 顺序key，同一个key的事件按触发顺序投递，为空不保证顺序
+	// 频道类型（频道相关的事件才有，用于端点的频道类型过滤）
+	ChannelType uint8 `json:"-"`
 }
 
 func (e *Event) String() string {
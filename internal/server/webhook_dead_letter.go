@@ -0,0 +1,166 @@
+package server
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/RussellLuo/timingwheel"
+	"github.com/WuKongIM/WuKongIM/pkg/trace"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"go.uber.org/zap"
+)
+
+// webhook死信
+// 发件箱事件投递失败超过最大重试次数后移入本节点的死信表，
+// 可以通过api查看、重放（重新写入发件箱，投递id不变）和清除。
+type webhookDeadLetters struct {
+	w *webhook
+	wklog.Log
+
+	mu     sync.Mutex
+	lastId uint64 // 最后分配的死信id
+
+	checkTimer *timingwheel.Timer
+}
+
+// 死信数量的检查间隔
+const webhookDeadLetterCheckInterval = time.Minute
+
+func newWebhookDeadLetters(w *webhook) *webhookDeadLetters {
+	return &webhookDeadLetters{
+		w:   w,
+		Log: wklog.NewWKLog("webhookDeadLetters"),
+	}
+}
+
+func (d *webhookDeadLetters) start() {
+	d.check()
+	d.checkTimer = d.w.s.Schedule(webhookDeadLetterCheckInterval, d.check)
+}
+
+func (d *webhookDeadLetters) stop() {
+	if d.checkTimer != nil {
+		d.checkTimer.Stop()
+	}
+}
+
+// add 添加死信（分配死信id）
+func (d *webhookDeadLetters) add(letters []wkdb.WebhookDeadLetter) error {
+	d.mu.Lock()
+	for i := range letters {
+		// 死信id为纳秒时间，保证本节点分配的id递增不重复
+		id := uint64(time.Now().UnixNano())
+		if id <= d.lastId {
+			id = d.lastId + 1
+		}
+		d.lastId = id
+		letters[i].Id = id
+	}
+	d.mu.Unlock()
+
+	err := d.w.s.store.AddWebhookDeadLetters(letters)
+	if err != nil {
+		return err
+	}
+	trace.GlobalTrace.Metrics.App().WebhookDeadLetterAddCountAdd(int64(len(letters)))
+	return nil
+}
+
+// replay 重放死信（按死信的先后顺序重新写入发件箱），返回重放的数量
+func (d *webhookDeadLetters) replay(ids []uint64) (int, error) {
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+	replayIds := make([]uint64, 0, len(ids))
+	for _, id := range ids {
+		letter, err := d.w.s.store.GetWebhookDeadLetter(id)
+		if err != nil {
+			if err == wkdb.ErrNotFound {
+				continue
+			}
+			return 0, err
+		}
+		err = d.w.outbox.addWithDeliveryId(letter.Endpoint, letter.Event, letter.OrderKey, letter.DeliveryId, letter.Data)
+		if err != nil {
+			return 0, err
+		}
+		replayIds = append(replayIds, id)
+	}
+	if len(replayIds) == 0 {
+		return 0, nil
+	}
+	err := d.w.s.store.RemoveWebhookDeadLetters(replayIds)
+	if err != nil {
+		return 0, err
+	}
+	d.Info("replay webhook dead letters", zap.Int("count", len(replayIds)))
+	return len(replayIds), nil
+}
+
+// 获取全部的死信id（event为空表示所有事件）
+func (d *webhookDeadLetters) allIds(event string) ([]uint64, error) {
+	letters, err := d.w.s.store.GetWebhookDeadLetters(event, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint64, 0, len(letters))
+	for _, letter := range letters {
+		ids = append(ids, letter.Id)
+	}
+	return ids, nil
+}
+
+// 检查死信数量（更新监控，超过阈值告警）
+func (d *webhookDeadLetters) check() {
+	count, err := d.w.s.store.GetWebhookDeadLetterCount()
+	if err != nil {
+		d.Error("get webhook dead letter count failed", zap.Error(err))
+		return
+	}
+	trace.GlobalTrace.Metrics.App().WebhookDeadLetterCountSet(int64(count))
+	threshold := d.w.s.opts.Webhook.DeadLetterAlertThreshold
+	if threshold > 0 && count >= threshold {
+		d.Error("too many webhook dead letters, please check the webhook server and replay or purge them", zap.Int("count", count), zap.Int("threshold", threshold))
+	}
+}
+
+// 死信（api返回的数据）
+type webhookDeadLetterResp struct {
+	Id         uint64          `json:"id"`             // 死信id
+	Endpoint   string          `json:"endpoint"`       // 投递的端点（为空表示配置文件中的webhook）
+	Event      string          `json:"event"`          // 事件类型
+	DeliveryId string          `json:"delivery_id"`    // 投递id
+	OrderKey   string          `json:"order_key"`      // 顺序key
+	Attempts   uint32          `json:"attempts"`       // 已投递的次数
+	LastError  string          `json:"last_error"`     // 最后一次投递的错误
+	DataSize   int             `json:"data_size"`      // 事件数据大小
+	Data       json.RawMessage `json:"data,omitempty"` // 事件数据（详情才返回）
+	CreatedAt  int64           `json:"created_at"`     // 事件创建时间（毫秒）
+	DeadAt     int64           `json:"dead_at"`        // 进入死信的时间（毫秒）
+}
+
+func newWebhookDeadLetterResp(letter wkdb.WebhookDeadLetter, withData bool) *webhookDeadLetterResp {
+	resp := &webhookDeadLetterResp{
+		Id:         letter.Id,
+		Endpoint:   letter.Endpoint,
+		Event:      letter.Event,
+		DeliveryId: letter.DeliveryId,
+		OrderKey:   letter.OrderKey,
+		Attempts:   letter.Attempts,
+		LastError:  letter.LastError,
+		DataSize:   len(letter.Data),
+		CreatedAt:  letter.CreatedAt / int64(time.Millisecond),
+		DeadAt:     int64(letter.Id) / int64(time.Millisecond),
+	}
+	if withData {
+		if json.Valid(letter.Data) {
+			resp.Data = letter.Data
+		} else {
+			resp.Data, _ = json.Marshal(string(letter.Data))
+		}
+	}
+	return resp
+}
@@ -0,0 +1,488 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/RussellLuo/timingwheel"
+	"github.com/WuKongIM/WuKongIM/pkg/grpcpool"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhook"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"github.com/pkg/errors"
+	"go.uber.org/atomic"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+)
+
+// 运行时添加的webhook端点
+// 端点存储在槽位0上（集群共享），各节点缓存端点列表，通过api修改后广播到各节点，并定时从槽位0的领导节点重新加载。
+// 端点的事件和配置文件中的webhook一样先写入本节点的发件箱再投递（msg.notify按端点的批量大小合并后写入）。
+type webhookEndpoints struct {
+	w *webhook
+	wklog.Log
+
+	mu       sync.RWMutex
+	clients  map[string]*webhookEndpointClient // key为端点名称
+	loaded   atomic.Bool
+	notifyOn atomic.Bool // 是否有端点关注了msg.notify
+
+	stopC       chan struct{}
+	notifyTimer *timingwheel.Timer
+}
+
+// 端点列表的重新加载间隔
+const webhookEndpointReloadInterval = time.Second * 30
+
+func newWebhookEndpoints(w *webhook) *webhookEndpoints {
+	return &webhookEndpoints{
+		w:       w,
+		Log:     wklog.NewWKLog("webhookEndpoints"),
+		clients: make(map[string]*webhookEndpointClient),
+		stopC:   make(chan struct{}),
+	}
+}
+
+func (e *webhookEndpoints) start() {
+	go e.loopReload()
+	e.notifyTimer = e.w.s.Schedule(e.w.s.opts.Webhook.MsgNotifyEventPushInterval, e.flushNotify)
+}
+
+// stop 停止并将缓存的消息通知写入发件箱（需要在发件箱停止前调用）
+func (e *webhookEndpoints) stop() {
+	close(e.stopC)
+	if e.notifyTimer != nil {
+		e.notifyTimer.Stop()
+	}
+	e.flushNotify()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, client := range e.clients {
+		client.close()
+	}
+}
+
+func (e *webhookEndpoints) loopReload() {
+	for {
+		interval := webhookEndpointReloadInterval
+		err := e.reload()
+		if err != nil {
+			if e.loaded.Load() {
+				e.Warn("reload webhook endpoints failed", zap.Error(err))
+			} else {
+				// 集群还没准备好时尽快重试
+				e.Debug("load webhook endpoints failed", zap.Error(err))
+				interval = time.Second
+			}
+		}
+		select {
+		case <-time.After(interval):
+		case <-e.stopC:
+			return
+		}
+	}
+}
+
+// reload 从槽位0的领导节点加载端点列表
+func (e *webhookEndpoints) reload() error {
+	nodeInfo, err := e.w.s.cluster.SlotLeaderNodeInfo(0)
+	if err != nil {
+		return err
+	}
+	var endpoints []wkdb.WebhookEndpoint
+	if e.w.s.opts.IsLocalNode(nodeInfo.Id) {
+		endpoints, err = e.w.s.store.GetWebhookEndpoints()
+	} else {
+		endpoints, err = e.requestEndpoints(nodeInfo.Id)
+	}
+	if err != nil {
+		return err
+	}
+	e.setCache(endpoints)
+	return nil
+}
+
+// 通过节点间的rpc获取端点列表（包含密钥，不对外暴露）
+func (e *webhookEndpoints) requestEndpoints(nodeId uint64) ([]wkdb.WebhookEndpoint, error) {
+	timeoutCtx, cancel := context.WithTimeout(e.w.s.ctx, e.w.s.opts.Cluster.ReqTimeout)
+	defer cancel()
+
+	resp, err := e.w.s.cluster.RequestWithContext(timeoutCtx, nodeId, "/wk/webhookEndpoints", nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != proto.StatusOK {
+		return nil, fmt.Errorf("requestEndpoints: response status code is %d", resp.Status)
+	}
+	var endpoints []wkdb.WebhookEndpoint
+	err = wkutil.ReadJSONByByte(resp.Body, &endpoints)
+	if err != nil {
+		return nil, err
+	}
+	return endpoints, nil
+}
+
+// sync 将槽位0上的端点列表更新到各个节点的缓存内（需要在槽位0的领导节点调用）
+func (e *webhookEndpoints) sync() error {
+	endpoints, err := e.w.s.store.GetWebhookEndpoints()
+	if err != nil {
+		return err
+	}
+	e.setCache(endpoints)
+
+	data := []byte(wkutil.ToJSON(endpoints))
+	requestGroup, _ := errgroup.WithContext(e.w.s.ctx)
+	for _, node := range e.w.s.clusterServer.GetConfig().Nodes {
+		if e.w.s.opts.IsLocalNode(node.Id) || !node.Online {
+			continue
+		}
+		nodeId := node.Id
+		requestGroup.Go(func() error {
+			return e.requestSetCache(nodeId, data)
+		})
+	}
+	// 未更新成功的节点会在定时重新加载时更新
+	return requestGroup.Wait()
+}
+
+func (e *webhookEndpoints) requestSetCache(nodeId uint64, data []byte) error {
+	timeoutCtx, cancel := context.WithTimeout(e.w.s.ctx, e.w.s.opts.Cluster.ReqTimeout)
+	defer cancel()
+
+	resp, err := e.w.s.cluster.RequestWithContext(timeoutCtx, nodeId, "/wk/webhookEndpointsSetCache", data)
+	if err != nil {
+		return err
+	}
+	if resp.Status != proto.StatusOK {
+		return fmt.Errorf("requestSetCache: response status code is %d", resp.Status)
+	}
+	return nil
+}
+
+// setCache 替换缓存的端点列表（配置变化的端点重新创建客户端）
+func (e *webhookEndpoints) setCache(endpoints []wkdb.WebhookEndpoint) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	clients := make(map[string]*webhookEndpointClient, len(endpoints))
+	notifyOn := false
+	for _, endpoint := range endpoints {
+		client := e.clients[endpoint.Name]
+		if client == nil || !client.sameConfig(endpoint) {
+			newClient, err := newWebhookEndpointClient(e.w, endpoint)
+			if err != nil {
+				e.Error("create webhook endpoint client failed", zap.Error(err), zap.String("name", endpoint.Name))
+				continue
+			}
+			if client != nil {
+				newClient.takeNotifyMessages(client)
+			}
+			client = newClient
+		}
+		clients[endpoint.Name] = client
+		if client.isEventFocused(EventMsgNotify) {
+			notifyOn = true
+		}
+	}
+	for name, client := range e.clients {
+		if clients[name] != client {
+			client.close()
+		}
+	}
+	e.clients = clients
+	e.notifyOn.Store(notifyOn)
+	e.loaded.Store(true)
+}
+
+func (e *webhookEndpoints) get(name string) *webhookEndpointClient {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.clients[name]
+}
+
+// 获取关注了此事件的端点
+func (e *webhookEndpoints) focused(event string, channelType uint8) []*webhookEndpointClient {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	var clients []*webhookEndpointClient
+	for _, client := range e.clients {
+		if client.isEventFocused(event) && client.isChannelTypeFocused(channelType) {
+			clients = append(clients, client)
+		}
+	}
+	return clients
+}
+
+// sendTo 投递事件到指定的端点（端点已被移除时丢弃事件）
+func (e *webhookEndpoints) sendTo(name string, deliveryId string, event string, data []byte) error {
+	if !e.loaded.Load() {
+		return errors.New("webhook endpoints not loaded")
+	}
+	client := e.get(name)
+	if client == nil {
+		e.Warn("webhook endpoint not found, discard event", zap.String("name", name), zap.String("event", event), zap.String("deliveryId", deliveryId))
+		return nil
+	}
+	return client.send(deliveryId, event, data)
+}
+
+// notifyMessages 将消息缓存到关注了msg.notify的端点，达到端点的批量大小后写入发件箱
+func (e *webhookEndpoints) notifyMessages(messages []wkdb.Message) {
+	for _, client := range e.focused(EventMsgNotify, 0) {
+		client.addNotifyMessages(messages)
+	}
+}
+
+// 将所有端点缓存的消息通知写入发件箱
+func (e *webhookEndpoints) flushNotify() {
+	e.mu.RLock()
+	clients := make([]*webhookEndpointClient, 0, len(e.clients))
+	for _, client := range e.clients {
+		clients = append(clients, client)
+	}
+	e.mu.RUnlock()
+	for _, client := range clients {
+		client.flushNotify()
+	}
+}
+
+type webhookEndpointClient struct {
+	w        *webhook
+	endpoint wkdb.WebhookEndpoint
+	wklog.Log
+
+	events       map[string]struct{} // 为空则推送所有类型
+	channelTypes map[uint8]struct{}  // 为空则推送所有频道类型
+	grpcPool     *grpcpool.Pool
+
+	notifyMu       sync.Mutex
+	notifyMessages []wkdb.Message // 待写入发件箱的消息通知
+}
+
+func newWebhookEndpointClient(w *webhook, endpoint wkdb.WebhookEndpoint) (*webhookEndpointClient, error) {
+	c := &webhookEndpointClient{
+		w:            w,
+		endpoint:     endpoint,
+		Log:          wklog.NewWKLog(fmt.Sprintf("webhookEndpoint[%s]", endpoint.Name)),
+		events:       make(map[string]struct{}),
+		channelTypes: make(map[uint8]struct{}),
+	}
+	for _, event := range endpoint.Events {
+		c.events[event] = struct{}{}
+	}
+	for _, channelType := range endpoint.ChannelTypes {
+		c.channelTypes[channelType] = struct{}{}
+	}
+	if endpoint.GRPCAddr != "" {
+		var err error
+		c.grpcPool, err = grpcpool.New(func() (*grpc.ClientConn, error) {
+			return grpc.Dial(endpoint.GRPCAddr, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithKeepaliveParams(keepalive.ClientParameters{
+				Time:    5 * time.Minute,
+				Timeout: 2 * time.Second,
+			}))
+		}, 1, 10, time.Minute*5)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (c *webhookEndpointClient) close() {
+	if c.grpcPool != nil {
+		c.grpcPool.Close()
+	}
+}
+
+func (c *webhookEndpointClient) sameConfig(endpoint wkdb.WebhookEndpoint) bool {
+	return wkutil.ToJSON(c.endpoint) == wkutil.ToJSON(endpoint)
+}
+
+func (c *webhookEndpointClient) isEventFocused(event string) bool {
+	if len(c.events) == 0 {
+		return true
+	}
+	_, ok := c.events[event]
+	return ok
+}
+
+// 频道类型为0表示事件与频道无关
+func (c *webhookEndpointClient) isChannelTypeFocused(channelType uint8) bool {
+	if len(c.channelTypes) == 0 || channelType == 0 {
+		return true
+	}
+	_, ok := c.channelTypes[channelType]
+	return ok
+}
+
+func (c *webhookEndpointClient) batchSize() int {
+	if c.endpoint.BatchSize > 0 {
+		return int(c.endpoint.BatchSize)
+	}
+	return c.w.s.opts.Webhook.MsgNotifyEventCountPerPush
+}
+
+func (c *webhookEndpointClient) secrets() []string {
+	if c.endpoint.Secret == "" {
+		return nil
+	}
+	return []string{c.endpoint.Secret}
+}
+
+func (c *webhookEndpointClient) addNotifyMessages(messages []wkdb.Message) {
+	c.notifyMu.Lock()
+	for _, message := range messages {
+		if c.isChannelTypeFocused(message.ChannelType) {
+			c.notifyMessages = append(c.notifyMessages, message)
+		}
+	}
+	full := len(c.notifyMessages) >= c.batchSize()
+	c.notifyMu.Unlock()
+	if full {
+		c.flushNotify()
+	}
+}
+
+// 端点配置更新后接管旧客户端未写入发件箱的消息
+func (c *webhookEndpointClient) takeNotifyMessages(old *webhookEndpointClient) {
+	old.notifyMu.Lock()
+	messages := old.notifyMessages
+	old.notifyMessages = nil
+	old.notifyMu.Unlock()
+	if len(messages) > 0 {
+		c.addNotifyMessages(messages)
+	}
+}
+
+// 按批量大小将缓存的消息通知写入发件箱
+func (c *webhookEndpointClient) flushNotify() {
+	c.notifyMu.Lock()
+	messages := c.notifyMessages
+	c.notifyMessages = nil
+	c.notifyMu.Unlock()
+
+	batchSize := c.batchSize()
+	for len(messages) > 0 {
+		count := len(messages)
+		if count > batchSize {
+			count = batchSize
+		}
+		batch := messages[:count]
+		messages = messages[count:]
+
+		messageResps := make([]*MessageResp, 0, len(batch))
+		for _, msg := range batch {
+			resp := &MessageResp{}
+			resp.from(msg, c.w.s)
+			messageResps = append(messageResps, resp)
+		}
+		data, err := json.Marshal(messageResps)
+		if err != nil {
+			c.Error("消息通知的event数据不能json化！", zap.Error(err))
+			continue
+		}
+		err = c.w.outbox.addWithDeliveryId(c.endpoint.Name, EventMsgNotify, EventMsgNotify, notifyDeliveryId(batch), data)
+		if err != nil {
+			c.Error("添加消息通知到发件箱失败！", zap.Error(err))
+		}
+	}
+}
+
+// 按端点配置的方式（grpc或http）投递事件
+func (c *webhookEndpointClient) send(deliveryId string, event string, data []byte) error {
+	if c.grpcPool != nil {
+		return c.sendForGRPC(deliveryId, event, data)
+	}
+	return c.sendForHttp(deliveryId, event, data)
+}
+
+func (c *webhookEndpointClient) sendForHttp(deliveryId string, event string, data []byte) error {
+	eventURL := fmt.Sprintf("%s?event=%s", c.endpoint.HTTPAddr, event)
+	req, err := http.NewRequest(http.MethodPost, eventURL, bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	newWebhookSign(c.secrets(), deliveryId, event, data).setHeader(req.Header)
+	resp, err := c.w.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("webhook端点返回状态错误！status:%d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (c *webhookEndpointClient) sendForGRPC(deliveryId string, event string, data []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+	defer cancel()
+	clientConn, err := c.grpcPool.Get(ctx)
+	if err != nil {
+		return err
+	}
+	defer clientConn.Close()
+	cli := wkhook.NewWebhookServiceClient(clientConn)
+
+	sendCtx, sendCancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer sendCancel()
+	sendCtx = newWebhookSign(c.secrets(), deliveryId, event, data).appendToContext(sendCtx)
+	resp, err := cli.SendWebhook(sendCtx, &wkhook.EventReq{
+		Event: event,
+		Data:  data,
+	})
+	if err != nil {
+		return err
+	}
+	if resp.Status != wkhook.EventStatus_Success {
+		return errors.New("grpc返回状态错误！")
+	}
+	return nil
+}
+
+// webhook端点（api返回的数据，不返回密钥）
+type webhookEndpointResp struct {
+	Name         string   `json:"name"`          // 端点名称
+	HTTPAddr     string   `json:"http_addr"`     // http地址
+	GRPCAddr     string   `json:"grpc_addr"`     // grpc地址
+	Events       []string `json:"events"`        // 关注的事件，为空则推送所有事件
+	ChannelTypes []int    `json:"channel_types"` // 关注的频道类型，为空则推送所有频道类型
+	BatchSize    uint32   `json:"batch_size"`    // msg.notify每次推送的消息数量
+	SecretSet    bool     `json:"secret_set"`    // 是否设置了签名密钥
+	CreatedAt    int64    `json:"created_at"`    // 创建时间（秒）
+	UpdatedAt    int64    `json:"updated_at"`    // 更新时间（秒）
+}
+
+func newWebhookEndpointResp(endpoint wkdb.WebhookEndpoint) *webhookEndpointResp {
+	channelTypes := make([]int, 0, len(endpoint.ChannelTypes))
+	for _, channelType := range endpoint.ChannelTypes {
+		channelTypes = append(channelTypes, int(channelType))
+	}
+	events := endpoint.Events
+	if events == nil {
+		events = make([]string, 0)
+	}
+	return &webhookEndpointResp{
+		Name:         endpoint.Name,
+		HTTPAddr:     endpoint.HTTPAddr,
+		GRPCAddr:     endpoint.GRPCAddr,
+		Events:       events,
+		ChannelTypes: channelTypes,
+		BatchSize:    endpoint.BatchSize,
+		SecretSet:    endpoint.Secret != "",
+		CreatedAt:    endpoint.CreatedAt,
+		UpdatedAt:    endpoint.UpdatedAt,
+	}
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhook"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookEndpoints(t *testing.T) {
+	type request struct {
+		endpoint string
+		event    string
+		body     string
+		sign     string
+	}
+	var (
+		lock     sync.Mutex
+		requests []request
+	)
+	newEndpointServer := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			lock.Lock()
+			defer lock.Unlock()
+			requests = append(requests, request{
+				endpoint: name,
+				event:    r.URL.Query().Get("event"),
+				body:     string(body),
+				sign:     r.Header.Get(wkhook.HeaderSignature),
+			})
+		}))
+	}
+	tsA := newEndpointServer("a")
+	defer tsA.Close()
+	tsB := newEndpointServer("b")
+	defer tsB.Close()
+
+	s := NewTestServer(t)
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+	s.MustWaitAllSlotsReady(time.Second * 10)
+
+	// 端点存储在槽位0上，加载后生效
+	err = s.store.SaveWebhookEndpoint(wkdb.WebhookEndpoint{
+		Name:         "a",
+		HTTPAddr:     tsA.URL,
+		Events:       []string{EventChannelTmpExpired},
+		ChannelTypes: []uint8{wkproto.ChannelTypeTemp},
+		Secret:       "secret",
+	})
+	assert.NoError(t, err)
+	err = s.store.SaveWebhookEndpoint(wkdb.WebhookEndpoint{
+		Name:     "b",
+		HTTPAddr: tsB.URL,
+		Events:   []string{EventUserSession},
+	})
+	assert.NoError(t, err)
+	err = s.webhook.endpoints.reload()
+	assert.NoError(t, err)
+
+	// 包含密钥的端点列表只通过节点间的rpc获取，不对外暴露
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/webhook/endpoints/internal", nil)
+	s.apiServer.r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	s.webhook.TriggerEvent(&Event{Event: EventChannelTmpExpired, ChannelType: wkproto.ChannelTypeGroup, Data: "group"}) // 频道类型不匹配
+	s.webhook.TriggerEvent(&Event{Event: EventChannelTmpExpired, ChannelType: wkproto.ChannelTypeTemp, Data: "temp"})
+	s.webhook.TriggerEvent(&Event{Event: EventUserSession, Data: "session"})
+
+	assert.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(requests) == 2
+	}, time.Second*5, time.Millisecond*10)
+
+	// 等待一会确认没有多余的投递
+	time.Sleep(time.Millisecond * 200)
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Len(t, requests, 2)
+	for _, req := range requests {
+		switch req.endpoint {
+		case "a":
+			assert.Equal(t, EventChannelTmpExpired, req.event)
+			assert.Equal(t, `"temp"`, req.body)
+			assert.NotEmpty(t, req.sign)
+		case "b":
+			assert.Equal(t, EventUserSession, req.event)
+			assert.Equal(t, `"session"`, req.body)
+			assert.Empty(t, req.sign)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package server
+
+import (
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+)
+
+// 频道和成员的生命周期事件
+// 事件只在数据提交成功的节点（频道或用户的槽领导节点）触发，转发请求的节点不触发。
+// 同一个频道的事件按频道顺序投递，同一个用户的事件按用户顺序投递。
+
+// 名单变更的操作
+const (
+	lifecycleActionAdd    = "add"    // 添加
+	lifecycleActionSet    = "set"    // 覆盖（先清空再添加）
+	lifecycleActionRemove = "remove" // 移除
+)
+
+// 用户封禁变更的操作
+const (
+	lifecycleActionBan   = "ban"   // 封禁
+	lifecycleActionUnban = "unban" // 解封
+)
+
+// 设备退出的原因
+const (
+	deviceQuitReasonQuit   = "quit"   // 强制退出（/user/device_quit）
+	deviceQuitReasonRevoke = "revoke" // 吊销（/user/device_revoke）
+)
+
+// channel.created、channel.updated、channel.deleted 的事件数据
+type channelLifecycleEvent struct {
+	ChannelId   string `json:"channel_id"`   // 频道id
+	ChannelType uint8  `json:"channel_type"` // 频道类型
+	Ban         int    `json:"ban"`          // 是否封禁 1.是 0.否
+	Large       int    `json:"large"`        // 是否超大频道 1.是 0.否
+	Disband     int    `json:"disband"`      // 是否已解散 1.是 0.否
+	Timestamp   int64  `json:"timestamp"`    // 事件时间（毫秒）
+}
+
+// channel.subscribers.added、channel.subscribers.removed 的事件数据
+type channelSubscribersEvent struct {
+	ChannelId   string   `json:"channel_id"`   // 频道id
+	ChannelType uint8    `json:"channel_type"` // 频道类型
+	Uids        []string `json:"uids"`         // 添加或移除的订阅者
+	Reset       int      `json:"reset"`        // 是否由重置订阅者引起 1.是 0.否
+	Timestamp   int64    `json:"timestamp"`    // 事件时间（毫秒）
+}
+
+// channel.denylist.changed、channel.allowlist.changed 的事件数据
+type channelMemberListEvent struct {
+	ChannelId   string   `json:"channel_id"`   // 频道id
+	ChannelType uint8    `json:"channel_type"` // 频道类型
+	Action      string   `json:"action"`       // 操作 add: 添加 set: 覆盖（uids为覆盖后的完整名单） remove: 移除
+	Uids        []string `json:"uids"`         // 操作的uid
+	Timestamp   int64    `json:"timestamp"`    // 事件时间（毫秒）
+}
+
+// user.token.updated 的事件数据（不包含token）
+type userTokenEvent struct {
+	Uid         string `json:"uid"`          // 用户uid
+	DeviceFlag  uint8  `json:"device_flag"`  // 设备标记
+	DeviceLevel uint8  `json:"device_level"` // 设备等级 0.从设备 1.主设备
+	Timestamp   int64  `json:"timestamp"`    // 事件时间（毫秒）
+}
+
+// user.device.quit 的事件数据
+type userDeviceQuitEvent struct {
+	Uid        string `json:"uid"`         // 用户uid
+	DeviceFlag uint8  `json:"device_flag"` // 设备标记
+	Reason     string `json:"reason"`      // 原因 quit: 强制退出 revoke: 吊销
+	Timestamp  int64  `json:"timestamp"`   // 事件时间（毫秒）
+}
+
+// user.ban.changed 的事件数据
+type userBanEvent struct {
+	Uid       string `json:"uid"`       // 用户uid
+	Action    string `json:"action"`    // 操作 ban: 封禁 unban: 解封
+	Reason    string `json:"reason"`    // 封禁原因（解封为空）
+	ExpireAt  int64  `json:"expire_at"` // 封禁到期时间（秒），0表示永久封禁（解封为0）
+	Timestamp int64  `json:"timestamp"` // 事件时间（毫秒）
+}
+
+func lifecycleTimestamp() int64 {
+	return time.Now().UnixNano() / int64(time.Millisecond)
+}
+
+// 触发频道相关的生命周期事件
+func (w *webhook) triggerChannelEvent(event string, channelId string, channelType uint8, data interface{}) {
+	w.TriggerEvent(&Event{
+		Event:       event,
+		Data:        data,
+		OrderKey:    wkutil.ChannelToKey(channelId, channelType),
+		ChannelType: channelType,
+	})
+}
+
+// 触发用户相关的生命周期事件
+func (w *webhook) triggerUserEvent(event string, uid string, data interface{}) {
+	w.TriggerEvent(&Event{
+		Event:    event,
+		Data:     data,
+		OrderKey: uid,
+	})
+}
+
+func (w *webhook) channelInfoChanged(event string, channelInfo wkdb.ChannelInfo) {
+	w.triggerChannelEvent(event, channelInfo.ChannelId, channelInfo.ChannelType, &channelLifecycleEvent{
+		ChannelId:   channelInfo.ChannelId,
+		ChannelType: channelInfo.ChannelType,
+		Ban:         wkutil.BoolToInt(channelInfo.Ban),
+		Large:       wkutil.BoolToInt(channelInfo.Large),
+		Disband:     wkutil.BoolToInt(channelInfo.Disband),
+		Timestamp:   lifecycleTimestamp(),
+	})
+}
+
+func (w *webhook) subscribersChanged(event string, channelId string, channelType uint8, uids []string, reset bool) {
+	if len(uids) == 0 {
+		return
+	}
+	w.triggerChannelEvent(event, channelId, channelType, &channelSubscribersEvent{
+		ChannelId:   channelId,
+		ChannelType: channelType,
+		Uids:        uids,
+		Reset:       wkutil.BoolToInt(reset),
+		Timestamp:   lifecycleTimestamp(),
+	})
+}
+
+func (w *webhook) memberListChanged(event string, channelId string, channelType uint8, action string, uids []string) {
+	if uids == nil {
+		uids = make([]string, 0)
+	}
+	w.triggerChannelEvent(event, channelId, channelType, &channelMemberListEvent{
+		ChannelId:   channelId,
+		ChannelType: channelType,
+		Action:      action,
+		Uids:        uids,
+		Timestamp:   lifecycleTimestamp(),
+	})
+}
+
+func (w *webhook) userTokenUpdated(uid string, deviceFlag wkproto.DeviceFlag, deviceLevel wkproto.DeviceLevel) {
+	w.triggerUserEvent(EventUserTokenUpdated, uid, &userTokenEvent{
+		Uid:         uid,
+		DeviceFlag:  deviceFlag.ToUint8(),
+		DeviceLevel: uint8(deviceLevel),
+		Timestamp:   lifecycleTimestamp(),
+	})
+}
+
+func (w *webhook) userDeviceQuit(uid string, deviceFlag wkproto.DeviceFlag, reason string) {
+	w.triggerUserEvent(EventUserDeviceQuit, uid, &userDeviceQuitEvent{
+		Uid:        uid,
+		DeviceFlag: deviceFlag.ToUint8(),
+		Reason:     reason,
+		Timestamp:  lifecycleTimestamp(),
+	})
+}
+
+func (w *webhook) userBanChanged(uid string, action string, userBan *wkdb.UserBan) {
+	data := &userBanEvent{
+		Uid:       uid,
+		Action:    action,
+		Timestamp: lifecycleTimestamp(),
+	}
+	if userBan != nil {
+		data.Reason = userBan.Reason
+		if userBan.ExpireAt != nil {
+			data.ExpireAt = userBan.ExpireAt.Unix()
+		}
+	}
+	w.triggerUserEvent(EventUserBanChanged, uid, data)
+}
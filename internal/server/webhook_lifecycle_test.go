@@ -0,0 +1,119 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookLifecycleEvents(t *testing.T) {
+	var (
+		lock   sync.Mutex
+		events = make(map[string][]json.RawMessage)
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event := r.URL.Query().Get("event")
+		if !strings.HasPrefix(event, "channel.") && !strings.HasPrefix(event, "user.ban") {
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		lock.Lock()
+		defer lock.Unlock()
+		events[event] = append(events[event], body)
+	}))
+	defer ts.Close()
+
+	s := NewTestServer(t, WithWebhookHTTPAddr(ts.URL))
+	err := s.Start()
+	assert.NoError(t, err)
+	defer s.StopNoErr()
+	s.MustWaitAllSlotsReady(time.Second * 10)
+
+	post := func(path string, data map[string]interface{}) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", path, bytes.NewReader([]byte(wkutil.ToJson(data))))
+		s.apiServer.r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	}
+	post("/channel", map[string]interface{}{
+		"channel_id":   "g1",
+		"channel_type": wkproto.ChannelTypeGroup,
+		"subscribers":  []string{"u1", "u2"},
+	})
+	post("/channel/subscriber_add", map[string]interface{}{
+		"channel_id":   "g1",
+		"channel_type": wkproto.ChannelTypeGroup,
+		"subscribers":  []string{"u2", "u3"}, // u2已经是订阅者
+	})
+	post("/channel/subscriber_remove", map[string]interface{}{
+		"channel_id":   "g1",
+		"channel_type": wkproto.ChannelTypeGroup,
+		"subscribers":  []string{"u1"},
+	})
+	post("/channel/blacklist_add", map[string]interface{}{
+		"channel_id":   "g1",
+		"channel_type": wkproto.ChannelTypeGroup,
+		"uids":         []string{"u4"},
+	})
+	post("/user/ban", map[string]interface{}{
+		"uid":    "u5",
+		"reason": "spam",
+	})
+	// 到期自动解封也会通知
+	_, err = s.userBanManager.ban("u6", "flood", time.Millisecond)
+	assert.NoError(t, err)
+	time.Sleep(time.Millisecond * 5)
+	s.userBanManager.checkExpired()
+
+	assert.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(events[EventChannelCreated]) == 1 && len(events[EventChannelSubscribersAdded]) == 2 &&
+			len(events[EventChannelSubscribersRemoved]) == 1 && len(events[EventChannelDenylistChanged]) == 1 &&
+			len(events[EventUserBanChanged]) == 3
+	}, time.Second*10, time.Millisecond*20)
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	var created channelLifecycleEvent
+	assert.NoError(t, json.Unmarshal(events[EventChannelCreated][0], &created))
+	assert.Equal(t, "g1", created.ChannelId)
+	assert.Equal(t, wkproto.ChannelTypeGroup, created.ChannelType)
+
+	var added channelSubscribersEvent
+	assert.NoError(t, json.Unmarshal(events[EventChannelSubscribersAdded][1], &added))
+	assert.Equal(t, []string{"u3"}, added.Uids) // 只通知新增的订阅者
+
+	var removed channelSubscribersEvent
+	assert.NoError(t, json.Unmarshal(events[EventChannelSubscribersRemoved][0], &removed))
+	assert.Equal(t, []string{"u1"}, removed.Uids)
+
+	var denylist channelMemberListEvent
+	assert.NoError(t, json.Unmarshal(events[EventChannelDenylistChanged][0], &denylist))
+	assert.Equal(t, lifecycleActionAdd, denylist.Action)
+	assert.Equal(t, []string{"u4"}, denylist.Uids)
+
+	bans := make(map[string][]userBanEvent)
+	for _, data := range events[EventUserBanChanged] {
+		var ban userBanEvent
+		assert.NoError(t, json.Unmarshal(data, &ban))
+		bans[ban.Uid] = append(bans[ban.Uid], ban)
+	}
+	assert.Len(t, bans["u5"], 1)
+	assert.Equal(t, lifecycleActionBan, bans["u5"][0].Action)
+	assert.Equal(t, "spam", bans["u5"][0].Reason)
+	assert.Len(t, bans["u6"], 2)
+	assert.Equal(t, lifecycleActionBan, bans["u6"][0].Action)
+	assert.Equal(t, lifecycleActionUnban, bans["u6"][1].Action)
+}
@@ -0,0 +1,349 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"go.uber.org/zap"
+)
+
+// webhook事件发件箱
+// 所有webhook事件（包括msg.notify）都先同步写入本节点wkdb的发件箱（写入成功才算触发成功）再投递，投递失败按指数退避重试，节点重启后继续投递。
+// 发件箱属于产生事件的节点，与频道和槽的领导无关，领导切换后旧领导已产生的事件仍由其自身投递。
+// 注意：发件箱只存储在本节点，不做副本，节点的数据丢失（磁盘损坏、节点下线不再恢复）时发件箱中还没有投递的事件也会丢失。
+// 顺序：投递到同一个端点且OrderKey相同的事件按id顺序串行投递，前面的事件没有投递成功时后面的事件不会投递；OrderKey为空的事件并发投递；写入同一个sink的事件都按id顺序批量写入。
+// 扫描：按id分页扫描发件箱，跳过未到期和被阻塞的事件，一次投递没有扫描完时下次从上次的位置继续，某个端点积压的失败事件不会阻塞其他端点的投递。
+type webhookOutbox struct {
+	w *webhook
+	wklog.Log
+
+	mu       sync.Mutex // 分配id和写入在同一个锁内，保证发件箱中的事件按id顺序写入
+	lastId   uint64     // 最后分配的事件id
+	idLoaded bool       // 是否已从发件箱加载了最大id
+
+	scanId  uint64              // 下次扫描的起始事件id（扫描到末尾后从头开始）
+	blocked map[string]struct{} // 本轮扫描中前面有未投递成功的事件的顺序key（从头扫描时清空）
+
+	deliverC chan struct{} // 有新写入的事件
+	doneC    chan struct{} // 投递协程已退出
+	started  bool
+}
+
+const (
+	webhookOutboxScanCount    = 1000 // 每页扫描发件箱的事件数量，也是每次投递的最大事件数量
+	webhookOutboxScanMaxPages = 100  // 每次投递最多扫描的页数
+)
+
+// 待写入发件箱的事件
+type webhookOutboxEvent struct {
+	endpoint   string // 投递的端点（为空表示配置文件中的webhook）
+	event      string
+	orderKey   string
+	deliveryId string // 为空则生成新的投递id
+	data       []byte
+}
+
+func newWebhookOutbox(w *webhook) *webhookOutbox {
+	return &webhookOutbox{
+		w:        w,
+		Log:      wklog.NewWKLog("webhookOutbox"),
+		deliverC: make(chan struct{}, 1),
+		doneC:    make(chan struct{}),
+	}
+}
+
+func (o *webhookOutbox) start() {
+	o.started = true
+	go o.loopDeliver()
+}
+
+// 等待投递协程退出（webhook停止后调用）
+func (o *webhookOutbox) waitStopped() {
+	if o.started {
+		<-o.doneC
+	}
+}
+
+// add 添加投递到指定端点的事件到发件箱（端点为空表示配置文件中的webhook）
+func (o *webhookOutbox) add(endpoint string, event string, orderKey string, data []byte) error {
+	return o.addEvents([]webhookOutboxEvent{{endpoint: endpoint, event: event, orderKey: orderKey, data: data}})
+}
+
+// addWithDeliveryId 使用指定的投递id添加事件到发件箱（死信重放时投递id不变）
+func (o *webhookOutbox) addWithDeliveryId(endpoint string, event string, orderKey string, deliveryId string, data []byte) error {
+	return o.addEvents([]webhookOutboxEvent{{endpoint: endpoint, event: event, orderKey: orderKey, deliveryId: deliveryId, data: data}})
+}
+
+// addEvents 按顺序分配id并同步写入发件箱（一批事件一次写入）
+func (o *webhookOutbox) addEvents(events []webhookOutboxEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if !o.idLoaded {
+		maxId, err := o.w.s.store.GetWebhookOutboxMaxId()
+		if err != nil {
+			return err
+		}
+		if maxId > o.lastId {
+			o.lastId = maxId
+		}
+		o.idLoaded = true
+	}
+	now := time.Now().UnixNano()
+	outboxEvents := make([]wkdb.WebhookEvent, 0, len(events))
+	for i, event := range events {
+		deliveryId := event.deliveryId
+		if deliveryId == "" {
+			deliveryId = wkutil.GenUUID()
+		}
+		outboxEvents = append(outboxEvents, wkdb.WebhookEvent{
+			Id:         o.lastId + uint64(i) + 1,
+			Endpoint:   event.endpoint,
+			Event:      event.event,
+			Data:       event.data,
+			DeliveryId: deliveryId,
+			OrderKey:   event.orderKey,
+			CreatedAt:  now,
+		})
+	}
+	if err := o.allocSinkOffsets(outboxEvents); err != nil {
+		return err
+	}
+	if err := o.w.s.store.AppendWebhookEvents(outboxEvents); err != nil {
+		return err
+	}
+	o.lastId += uint64(len(outboxEvents))
+	signal(o.deliverC)
+	return nil
+}
+
+// 为写入sink的事件分配偏移量（在发件箱的锁内分配，保证偏移量和事件id的顺序一致）
+func (o *webhookOutbox) allocSinkOffsets(events []wkdb.WebhookEvent) error {
+	var (
+		sinks   []*webhookSink
+		indexes = make(map[*webhookSink][]int)
+	)
+	for i, event := range events {
+		if !isWebhookSinkEndpoint(event.Endpoint) {
+			continue
+		}
+		sink := o.w.sinks.get(event.Endpoint)
+		if sink == nil {
+			continue
+		}
+		if _, ok := indexes[sink]; !ok {
+			sinks = append(sinks, sink)
+		}
+		indexes[sink] = append(indexes[sink], i)
+	}
+	for _, sink := range sinks {
+		first, err := sink.allocOffsets(o.w.s, len(indexes[sink]))
+		if err != nil {
+			return err
+		}
+		for j, i := range indexes[sink] {
+			events[i].SinkOffset = first + uint64(j)
+		}
+	}
+	return nil
+}
+
+func (o *webhookOutbox) loopDeliver() {
+	defer close(o.doneC)
+	ticker := time.NewTicker(o.w.s.opts.Webhook.MsgNotifyEventPushInterval)
+	defer ticker.Stop()
+	for {
+		o.deliver()
+		select {
+		case <-ticker.C:
+		case <-o.deliverC:
+		case <-o.w.stoped:
+			return
+		}
+	}
+}
+
+// 投递发件箱中到期的事件
+func (o *webhookOutbox) deliver() {
+	if o.scanId == 0 || o.blocked == nil { // 从头扫描
+		o.blocked = make(map[string]struct{})
+	}
+
+	now := time.Now().UnixNano()
+	groups := make([][]wkdb.WebhookEvent, 0)
+	groupIndexs := make(map[string]int)
+	count := 0 // 待投递的事件数量
+	for page := 0; page < webhookOutboxScanMaxPages && count < webhookOutboxScanCount; page++ {
+		events, err := o.w.s.store.GetWebhookEvents(o.scanId, webhookOutboxScanCount)
+		if err != nil {
+			o.Error("get webhook events from outbox failed", zap.Error(err), zap.Uint64("scanId", o.scanId))
+			break
+		}
+		for _, event := range events {
+			orderKey := webhookOutboxOrderKey(event)
+			if orderKey != "" {
+				if _, ok := o.blocked[orderKey]; ok {
+					continue
+				}
+			}
+			if event.NextRetryAt > now {
+				if orderKey != "" {
+					o.blocked[orderKey] = struct{}{}
+				}
+				continue
+			}
+			count++
+			if orderKey == "" {
+				groups = append(groups, []wkdb.WebhookEvent{event})
+				continue
+			}
+			idx, ok := groupIndexs[orderKey]
+			if !ok {
+				idx = len(groups)
+				groupIndexs[orderKey] = idx
+				groups = append(groups, nil)
+			}
+			groups[idx] = append(groups[idx], event)
+		}
+		if len(events) < webhookOutboxScanCount { // 已扫描到末尾，下次从头开始
+			o.scanId = 0
+			break
+		}
+		o.scanId = events[len(events)-1].Id + 1
+	}
+	if len(groups) == 0 {
+		return
+	}
+
+	var (
+		resultLock  sync.Mutex
+		removeIds   []uint64            // 投递成功的事件
+		updates     []wkdb.WebhookEvent // 投递失败需要重试的事件
+		deadIds     []uint64            // 超过最大重试次数的事件
+		deadLetters []wkdb.WebhookDeadLetter
+		wg          sync.WaitGroup
+	)
+	deliverGroup := func(group []wkdb.WebhookEvent) {
+		defer wg.Done()
+		for i := 0; i < len(group); {
+			event := group[i]
+			count := 1
+			var err error
+			if isWebhookSinkEndpoint(event.Endpoint) { // sink的事件批量写入
+				count = min(len(group)-i, webhookSinkWriteMaxCount)
+				err = o.w.sinks.writeTo(event.Endpoint, group[i:i+count])
+			} else {
+				err = o.w.send(event.Endpoint, event.DeliveryId, event.Event, event.Data)
+			}
+			resultLock.Lock()
+			if err == nil {
+				for _, e := range group[i : i+count] {
+					removeIds = append(removeIds, e.Id)
+				}
+				resultLock.Unlock()
+				i += count
+				continue
+			}
+			event.RetryCount++
+			event.LastError = err.Error()
+			if retryMaxCount := o.retryMaxCount(event); retryMaxCount > 0 && int(event.RetryCount) > retryMaxCount {
+				o.Warn("webhook event retry count exceeded, move to dead letter", zap.Uint64("id", event.Id), zap.String("endpoint", event.Endpoint), zap.String("event", event.Event), zap.String("deliveryId", event.DeliveryId), zap.Error(err))
+				deadIds = append(deadIds, event.Id)
+				if isChannelWebhookEndpoint(event.Endpoint) {
+					o.w.s.channelWebhook.onDeadLetter(event.Endpoint, event.LastError)
+				}
+				deadLetters = append(deadLetters, wkdb.WebhookDeadLetter{
+					Endpoint:   event.Endpoint,
+					Event:      event.Event,
+					Data:       event.Data,
+					DeliveryId: event.DeliveryId,
+					OrderKey:   event.OrderKey,
+					Attempts:   event.RetryCount,
+					LastError:  event.LastError,
+					CreatedAt:  event.CreatedAt,
+				})
+			} else {
+				event.NextRetryAt = time.Now().Add(webhookRetryBackoff(o.w.s.opts, int(event.RetryCount))).UnixNano()
+				updates = append(updates, event)
+			}
+			if orderKey := webhookOutboxOrderKey(event); orderKey != "" { // 保证顺序，后面的事件等这个事件投递成功后再投递（批量写入sink失败时记在第一个事件上）
+				o.blocked[orderKey] = struct{}{}
+				resultLock.Unlock()
+				return
+			}
+			resultLock.Unlock()
+			i++
+		}
+	}
+	for _, group := range groups {
+		wg.Add(1)
+		group := group
+		if err := o.w.eventPool.Submit(func() { deliverGroup(group) }); err != nil {
+			deliverGroup(group)
+		}
+	}
+	wg.Wait()
+
+	if len(updates) > 0 {
+		if err := o.w.s.store.AppendWebhookEvents(updates); err != nil {
+			o.Error("update webhook events of outbox failed", zap.Error(err))
+		}
+	}
+	if len(deadLetters) > 0 {
+		if err := o.w.deadLetters.add(deadLetters); err != nil { // 死信写入失败时事件保留在发件箱，下次继续投递
+			o.Error("add webhook dead letters failed", zap.Error(err))
+		} else {
+			removeIds = append(removeIds, deadIds...)
+		}
+	}
+	if len(removeIds) > 0 {
+		if err := o.w.s.store.RemoveWebhookEvents(removeIds); err != nil {
+			o.Error("remove webhook events from outbox failed", zap.Error(err))
+		}
+	}
+}
+
+// 事件的顺序key（不同端点之间的顺序互不影响，写入sink的事件不论OrderKey都按偏移量顺序串行写入）
+func webhookOutboxOrderKey(event wkdb.WebhookEvent) string {
+	if isWebhookSinkEndpoint(event.Endpoint) {
+		return event.Endpoint
+	}
+	if event.OrderKey == "" {
+		return ""
+	}
+	return event.Endpoint + "|" + event.OrderKey
+}
+
+// 事件的最大重试次数（配置文件中webhook的msg.notify使用MsgNotifyEventRetryMaxCount）
+func (o *webhookOutbox) retryMaxCount(event wkdb.WebhookEvent) int {
+	if event.Endpoint == "" && event.Event == EventMsgNotify {
+		return o.w.s.opts.Webhook.MsgNotifyEventRetryMaxCount
+	}
+	return o.w.s.opts.Webhook.RetryMaxCount
+}
+
+// 第retry次重试前的等待时间（指数退避）
+func webhookRetryBackoff(opts *Options, retry int) time.Duration {
+	interval := opts.Webhook.RetryInitialInterval
+	for i := 1; i < retry && interval < opts.Webhook.RetryMaxInterval; i++ {
+		interval *= 2
+	}
+	if interval > opts.Webhook.RetryMaxInterval {
+		interval = opts.Webhook.RetryMaxInterval
+	}
+	return interval
+}
+
+// 非阻塞的通知
+func signal(c chan struct{}) {
+	select {
+	case c <- struct{}{}:
+	default:
+	}
+}
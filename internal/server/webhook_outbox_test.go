@@ -0,0 +1,236 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhook"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookOutbox(t *testing.T) {
+	var (
+		lock        sync.Mutex
+		requests    int
+		received    []string // 投递成功的事件数据
+		deliveryIds []string // 每次请求的投递id
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		lock.Lock()
+		defer lock.Unlock()
+		requests++
+		deliveryIds = append(deliveryIds, r.Header.Get(wkhook.HeaderDeliveryId))
+		if requests == 1 { // 第一次请求失败
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		received = append(received, string(body))
+	}))
+	defer ts.Close()
+
+	s := NewTestServer(t, WithWebhookHTTPAddr(ts.URL), WithWebhookRetry(time.Millisecond*10, time.Millisecond*50, 5))
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	// 同一个顺序key的事件，第一个失败后重试成功，后面的事件依然按顺序投递
+	for _, data := range []string{"1", "2", "3"} {
+		s.webhook.TriggerEvent(&Event{Event: EventChannelTmpExpired, OrderKey: "k", Data: data})
+	}
+
+	assert.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(received) == 3
+	}, time.Second*5, time.Millisecond*10)
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, []string{`"1"`, `"2"`, `"3"`}, received)
+	assert.Equal(t, deliveryIds[0], deliveryIds[1]) // 重试的投递id不变
+	assert.NotEqual(t, deliveryIds[1], deliveryIds[2])
+
+	events, err := s.store.GetWebhookEvents(0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, events, 0)
+}
+
+func TestWebhookDeadLetter(t *testing.T) {
+	var (
+		lock        sync.Mutex
+		fail        = true
+		deliveryIds []string // 投递成功的投递id
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		if fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		deliveryIds = append(deliveryIds, r.Header.Get(wkhook.HeaderDeliveryId))
+	}))
+	defer ts.Close()
+
+	s := NewTestServer(t, WithWebhookHTTPAddr(ts.URL), WithWebhookRetry(time.Millisecond*10, time.Millisecond*20, 1))
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.webhook.TriggerEvent(&Event{Event: EventChannelTmpExpired, Data: "1"})
+
+	// 超过最大重试次数后移入死信
+	var letters []wkdb.WebhookDeadLetter
+	assert.Eventually(t, func() bool {
+		letters, err = s.store.GetWebhookDeadLetters("", 0, 0)
+		assert.NoError(t, err)
+		return len(letters) == 1
+	}, time.Second*5, time.Millisecond*10)
+	assert.Equal(t, EventChannelTmpExpired, letters[0].Event)
+	assert.Equal(t, uint32(2), letters[0].Attempts)
+
+	events, err := s.store.GetWebhookEvents(0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, events, 0)
+
+	// 重放后使用原来的投递id投递
+	lock.Lock()
+	fail = false
+	lock.Unlock()
+	count, err := s.webhook.deadLetters.replay([]uint64{letters[0].Id})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	assert.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(deliveryIds) == 1
+	}, time.Second*5, time.Millisecond*10)
+	assert.Equal(t, letters[0].DeliveryId, deliveryIds[0])
+
+	count, err = s.store.GetWebhookDeadLetterCount()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestWebhookOutboxNotBlockedByFailingEndpoint(t *testing.T) {
+	var (
+		lock     sync.Mutex
+		received []string // 正常端点投递成功的事件数据
+	)
+	badTs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer badTs.Close()
+	goodTs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		lock.Lock()
+		defer lock.Unlock()
+		received = append(received, string(body))
+	}))
+	defer goodTs.Close()
+
+	s := NewTestServer(t, WithWebhookRetry(time.Minute, time.Minute, 5))
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+	s.MustWaitAllSlotsReady(time.Second * 10)
+
+	for _, endpoint := range []wkdb.WebhookEndpoint{
+		{Name: "bad", HTTPAddr: badTs.URL, Events: []string{EventChannelTmpExpired}},
+		{Name: "good", HTTPAddr: goodTs.URL, Events: []string{EventChannelTmpExpired}},
+	} {
+		err = s.store.SaveWebhookEndpoint(endpoint)
+		assert.NoError(t, err)
+	}
+	err = s.webhook.endpoints.reload()
+	assert.NoError(t, err)
+
+	// 失败端点积压的事件超过一页
+	for i := 0; i < webhookOutboxScanCount+500; i++ {
+		err = s.webhook.outbox.add("bad", EventChannelTmpExpired, "k", []byte(`"bad"`))
+		assert.NoError(t, err)
+	}
+	err = s.webhook.outbox.add("good", EventChannelTmpExpired, "k", []byte(`"good"`))
+	assert.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(received) == 1
+	}, time.Second*5, time.Millisecond*10)
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, []string{`"good"`}, received)
+}
+
+func TestWebhookMsgNotifyOutbox(t *testing.T) {
+	var (
+		lock     sync.Mutex
+		requests int
+		notified []string // 收到的msg.notify事件数据
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("event") != EventMsgNotify {
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		lock.Lock()
+		defer lock.Unlock()
+		requests++
+		if requests == 1 { // 第一次请求失败，由发件箱重试
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		notified = append(notified, string(body))
+	}))
+	defer ts.Close()
+
+	s := NewTestServer(t, WithTmpChannelSuffix("_tmp"), WithWebhookHTTPAddr(ts.URL), WithWebhookRetry(time.Millisecond*10, time.Millisecond*50, 5))
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady(time.Second * 10) // 等待服务准备好
+
+	channelId := "chat" + s.opts.TmpChannel.Suffix
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/tmpchannel/subscriber_set", bytes.NewReader([]byte(wkutil.ToJson(map[string]interface{}{
+		"channel_id": channelId,
+		"uids":       []string{"u1"},
+	}))))
+	s.apiServer.r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	_, err = sendMessageToChannel(s, MessageSendReq{FromUID: s.opts.SystemUID, Payload: []byte("hello")}, channelId, wkproto.ChannelTypeTemp, wkutil.GenUUID(), wkproto.StreamFlagIng)
+	assert.Nil(t, err)
+
+	// 消息通知经发件箱投递，失败后重试成功
+	assert.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(notified) == 1
+	}, time.Second*5, time.Millisecond*10)
+
+	lock.Lock()
+	assert.Contains(t, notified[0], channelId)
+	lock.Unlock()
+
+	events, err := s.store.GetWebhookEvents(0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, events, 0)
+}
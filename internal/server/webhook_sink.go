@@ -0,0 +1,239 @@
+package server
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/eventsink"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"go.uber.org/zap"
+)
+
+// 事件sink在发件箱中的端点名称前缀（webhook端点的名称不能包含冒号，不会冲突）
+const webhookSinkEndpointPrefix = "sink:"
+
+// 每次批量写入sink的最大事件数量
+const webhookSinkWriteMaxCount = 100
+
+func isWebhookSinkEndpoint(endpoint string) bool {
+	return strings.HasPrefix(endpoint, webhookSinkEndpointPrefix)
+}
+
+// 事件sink
+// 配置的sink和webhook端点一样，事件先写入本节点的发件箱再按偏移量顺序批量写入sink，失败重试，超过最大重试次数移入死信（至少一次）。
+// 偏移量在事件写入发件箱时分配并保存在本节点，随事件存储在发件箱中，重试时不变；分配后写入发件箱失败的偏移量不会再使用（偏移量递增但可能不连续）。
+type webhookSinks struct {
+	w *webhook
+	wklog.Log
+
+	sinks    map[string]*webhookSink // key为sink名称
+	notifyOn bool                    // 是否有sink关注了msg.notify
+}
+
+func newWebhookSinks(w *webhook) *webhookSinks {
+	s := &webhookSinks{
+		w:     w,
+		Log:   wklog.NewWKLog("webhookSinks"),
+		sinks: make(map[string]*webhookSink),
+	}
+	for _, cfg := range w.s.opts.EventSinks {
+		if cfg.Type == eventsink.TypeFile && !filepath.IsAbs(cfg.Path) { // 相对路径相对于数据目录
+			cfg.Path = filepath.Join(w.s.opts.DataDir, cfg.Path)
+		}
+		sink, err := eventsink.New(cfg)
+		if err != nil {
+			panic(err)
+		}
+		ws := &webhookSink{
+			cfg:    cfg,
+			sink:   sink,
+			events: make(map[string]struct{}),
+		}
+		for _, event := range cfg.Events {
+			ws.events[event] = struct{}{}
+		}
+		if ws.isEventFocused(EventMsgNotify) {
+			s.notifyOn = true
+		}
+		s.sinks[cfg.Name] = ws
+	}
+	return s
+}
+
+func (s *webhookSinks) stop() {
+	for _, sink := range s.sinks {
+		if err := sink.sink.Close(); err != nil {
+			s.Warn("close event sink failed", zap.Error(err), zap.String("name", sink.cfg.Name))
+		}
+	}
+}
+
+// 获取关注了此事件的sink
+func (s *webhookSinks) focused(event string) []*webhookSink {
+	var sinks []*webhookSink
+	for _, sink := range s.sinks {
+		if sink.isEventFocused(event) {
+			sinks = append(sinks, sink)
+		}
+	}
+	return sinks
+}
+
+// notifyMessages 将同一批存储的消息作为一个msg.notify事件写入关注了此事件的sink
+func (s *webhookSinks) notifyMessages(messages []wkdb.Message) {
+	messageResps := make([]*MessageResp, 0, len(messages))
+	for _, msg := range messages {
+		resp := &MessageResp{}
+		resp.from(msg, s.w.s)
+		messageResps = append(messageResps, resp)
+	}
+	data, err := json.Marshal(messageResps)
+	if err != nil {
+		s.Error("消息通知的event数据不能json化！", zap.Error(err))
+		return
+	}
+	deliveryId := notifyDeliveryId(messages)
+	for _, sink := range s.focused(EventMsgNotify) {
+		err = s.w.outbox.addWithDeliveryId(sink.endpoint(), EventMsgNotify, EventMsgNotify, deliveryId, data)
+		if err != nil {
+			s.Error("添加消息通知到发件箱失败！", zap.Error(err), zap.String("sink", sink.cfg.Name))
+		}
+	}
+}
+
+// get 获取发件箱端点对应的sink（sink已从配置中移除时返回nil）
+func (s *webhookSinks) get(endpoint string) *webhookSink {
+	return s.sinks[strings.TrimPrefix(endpoint, webhookSinkEndpointPrefix)]
+}
+
+// writeTo 批量写入发件箱中的事件到指定的sink（sink已从配置中移除时丢弃事件）
+func (s *webhookSinks) writeTo(endpoint string, events []wkdb.WebhookEvent) error {
+	sink := s.get(endpoint)
+	if sink == nil {
+		s.Warn("event sink not found, discard events", zap.String("endpoint", endpoint), zap.Int("count", len(events)))
+		return nil
+	}
+	return sink.write(s.w.s.opts.Cluster.NodeId, events)
+}
+
+func (s *webhookSinks) getStats() []webhookSinkStats {
+	stats := make([]webhookSinkStats, 0, len(s.sinks))
+	for _, sink := range s.sinks {
+		stats = append(stats, sink.getStats())
+	}
+	return stats
+}
+
+type webhookSink struct {
+	cfg    eventsink.Config
+	sink   eventsink.Sink
+	events map[string]struct{} // 为空则写入所有事件
+
+	allocMu         sync.Mutex
+	allocatedOffset uint64 // 已分配的偏移量（保存在本节点）
+	allocLoaded     bool
+
+	mu          sync.Mutex
+	offset      uint64 // 已写入的偏移量
+	writeCount  uint64 // 写入成功的事件数量
+	errorCount  uint64 // 写入失败的次数
+	lastError   string
+	lastWriteAt int64
+}
+
+func (k *webhookSink) endpoint() string {
+	return webhookSinkEndpointPrefix + k.cfg.Name
+}
+
+func (k *webhookSink) isEventFocused(event string) bool {
+	if len(k.events) == 0 {
+		return true
+	}
+	_, ok := k.events[event]
+	return ok
+}
+
+// allocOffsets 分配count个连续的偏移量并保存，返回第一个偏移量
+func (k *webhookSink) allocOffsets(s *Server, count int) (uint64, error) {
+	k.allocMu.Lock()
+	defer k.allocMu.Unlock()
+
+	if !k.allocLoaded {
+		offset, err := s.store.GetEventSinkOffset(k.cfg.Name)
+		if err != nil {
+			return 0, err
+		}
+		k.allocatedOffset = offset
+		k.allocLoaded = true
+	}
+	allocated := k.allocatedOffset + uint64(count)
+	if err := s.store.SetEventSinkOffset(k.cfg.Name, allocated); err != nil {
+		return 0, err
+	}
+	first := k.allocatedOffset + 1
+	k.allocatedOffset = allocated
+	return first, nil
+}
+
+// write 使用事件在发件箱中分配的偏移量批量写入sink
+func (k *webhookSink) write(nodeId uint64, events []wkdb.WebhookEvent) error {
+	sinkEvents := make([]eventsink.Event, 0, len(events))
+	for _, event := range events {
+		sinkEvents = append(sinkEvents, eventsink.Event{
+			NodeId:     nodeId,
+			Offset:     event.SinkOffset,
+			DeliveryId: event.DeliveryId,
+			Event:      event.Event,
+			Data:       event.Data,
+			Timestamp:  time.Now().UnixNano() / int64(time.Millisecond),
+		})
+	}
+	err := k.sink.Write(sinkEvents)
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if err != nil {
+		k.errorCount++
+		k.lastError = err.Error()
+		return err
+	}
+	k.offset = events[len(events)-1].SinkOffset
+	k.writeCount += uint64(len(events))
+	k.lastWriteAt = time.Now().Unix()
+	return nil
+}
+
+func (k *webhookSink) getStats() webhookSinkStats {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	events := k.cfg.Events
+	if events == nil {
+		events = make([]string, 0)
+	}
+	return webhookSinkStats{
+		Name:        k.cfg.Name,
+		Type:        k.cfg.Type,
+		Events:      events,
+		Offset:      k.offset,
+		WriteCount:  k.writeCount,
+		ErrorCount:  k.errorCount,
+		LastError:   k.lastError,
+		LastWriteAt: k.lastWriteAt,
+	}
+}
+
+// 事件sink的写入统计（本节点）
+type webhookSinkStats struct {
+	Name        string   `json:"name"`          // sink名称
+	Type        string   `json:"type"`          // sink类型
+	Events      []string `json:"events"`        // 关注的事件，为空则写入所有事件
+	Offset      uint64   `json:"offset"`        // 最后写入的偏移量（节点重启后第一次写入前为0）
+	WriteCount  uint64   `json:"write_count"`   // 本次启动后写入成功的事件数量
+	ErrorCount  uint64   `json:"error_count"`   // 本次启动后写入失败的次数
+	LastError   string   `json:"last_error"`    // 最后一次写入的错误
+	LastWriteAt int64    `json:"last_write_at"` // 最后一次写入成功的时间（秒）
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/eventsink"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	s := NewTestServer(t, WithEventSinks(eventsink.Config{
+		Name:    "analytics",
+		Type:    eventsink.TypeFile,
+		Events:  []string{EventChannelTmpExpired},
+		Path:    path,
+		MaxSize: 1,
+	}))
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	for _, data := range []string{"1", "2"} {
+		s.webhook.TriggerEvent(&Event{Event: EventChannelTmpExpired, OrderKey: "k", Data: data})
+	}
+	s.webhook.TriggerEvent(&Event{Event: EventUserSession, Data: "session"}) // 不关注的事件
+
+	var events []eventsink.Event
+	assert.Eventually(t, func() bool {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return false
+		}
+		events = events[:0]
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			var event eventsink.Event
+			if err := json.Unmarshal([]byte(line), &event); err != nil {
+				return false
+			}
+			events = append(events, event)
+		}
+		return len(events) == 2
+	}, time.Second*5, time.Millisecond*10)
+
+	// 偏移量按写入顺序递增
+	assert.Equal(t, uint64(1), events[0].Offset)
+	assert.Equal(t, `"1"`, string(events[0].Data))
+	assert.Equal(t, uint64(2), events[1].Offset)
+	assert.Equal(t, `"2"`, string(events[1].Data))
+	assert.Equal(t, s.opts.Cluster.NodeId, events[1].NodeId)
+
+	offset, err := s.store.GetEventSinkOffset("analytics")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), offset)
+}
+
+// 记录每次写入的偏移量，第一次写入失败
+type testFailOnceSink struct {
+	lock    sync.Mutex
+	writes  int
+	batches [][]uint64 // 写入成功的每批事件的偏移量
+	failed  [][]uint64 // 写入失败的每批事件的偏移量
+}
+
+func (s *testFailOnceSink) Write(events []eventsink.Event) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	offsets := make([]uint64, 0, len(events))
+	for _, event := range events {
+		offsets = append(offsets, event.Offset)
+	}
+	s.writes++
+	if s.writes == 1 {
+		s.failed = append(s.failed, offsets)
+		return errors.New("write failed")
+	}
+	s.batches = append(s.batches, offsets)
+	return nil
+}
+
+func (s *testFailOnceSink) Close() error {
+	return nil
+}
+
+func TestWebhookSinkRetry(t *testing.T) {
+	s := NewTestServer(t, WithEventSinks(eventsink.Config{
+		Name: "analytics",
+		Type: eventsink.TypeFile,
+		Path: filepath.Join(t.TempDir(), "events.jsonl"),
+	}), WithWebhookRetry(time.Millisecond*50, time.Millisecond*100, 5))
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	sink := &testFailOnceSink{}
+	s.webhook.sinks.sinks["analytics"].sink = sink
+
+	for _, data := range []string{"1", "2", "3"} {
+		s.webhook.TriggerEvent(&Event{Event: EventChannelTmpExpired, Data: data})
+	}
+
+	assert.Eventually(t, func() bool {
+		sink.lock.Lock()
+		defer sink.lock.Unlock()
+		return len(sink.batches) == 1
+	}, time.Second*5, time.Millisecond*10)
+
+	sink.lock.Lock()
+	defer sink.lock.Unlock()
+	// 重试时偏移量不变，积压的事件一次批量写入
+	assert.Equal(t, uint64(1), sink.failed[0][0])
+	assert.Equal(t, []uint64{1, 2, 3}, sink.batches[0])
+
+	offset, err := s.store.GetEventSinkOffset("analytics")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), offset)
+}
@@ -0,0 +1,421 @@
+package server
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wknet"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+// websocket JSON文本协议
+// 客户端在握手时通过 Sec-WebSocket-Protocol: wukongim.json 协商，之后每个文本消息是一个JSON帧，type为帧类型，
+// 帧与悟空IM协议包一一对应：connect/connack、send/sendack、recv/recvack、ping/pong、disconnect。
+// 只在wss上支持（传输加密由wss负责，WSJSONInsecureOn开启后明文ws也支持），payload为原始消息内容的base64，服务端代替客户端完成DH密钥交换和消息的加解密。
+const wsJSONSubprotocol = "wukongim.json"
+
+const (
+	wsJSONTypeConnect    = "connect"
+	wsJSONTypeConnack    = "connack"
+	wsJSONTypeSend       = "send"
+	wsJSONTypeSendack    = "sendack"
+	wsJSONTypeRecv       = "recv"
+	wsJSONTypeRecvack    = "recvack"
+	wsJSONTypePing       = "ping"
+	wsJSONTypePong       = "pong"
+	wsJSONTypeDisconnect = "disconnect"
+)
+
+type wsJSONFrame struct {
+	Type string `json:"type"` // 帧类型
+}
+
+// 对应 ConnectPacket
+type wsJSONConnect struct {
+	Type            string `json:"type"`
+	UID             string `json:"uid"`              // 用户uid
+	Token           string `json:"token"`            // 用户token
+	DeviceID        string `json:"device_id"`        // 设备id
+	DeviceFlag      uint8  `json:"device_flag"`      // 设备标记 0.app 1.web 2.pc
+	ClientTimestamp int64  `json:"client_timestamp"` // 客户端当前时间戳（13位，到毫秒）
+}
+
+// 对应 ConnackPacket
+type wsJSONConnack struct {
+	Type          string `json:"type"`
+	ServerVersion uint8  `json:"server_version"` // 服务端协议版本
+	TimeDiff      int64  `json:"time_diff"`      // 客户端时间与服务器的差值，单位毫秒
+	ReasonCode    uint8  `json:"reason_code"`    // 原因码 1.成功
+	NodeId        uint64 `json:"node_id"`        // 节点id
+}
+
+// 对应 SendPacket
+type wsJSONSend struct {
+	Type        string        `json:"type"`
+	Header      MessageHeader `json:"header"`          // 消息头
+	Setting     uint8         `json:"setting"`         // 设置
+	Expire      uint32        `json:"expire"`          // 消息过期时间 0 表示永不过期
+	ClientSeq   uint64        `json:"client_seq"`      // 客户端序列号，sendack原样返回
+	ClientMsgNo string        `json:"client_msg_no"`   // 客户端消息唯一编号
+	ChannelID   string        `json:"channel_id"`      // 频道id
+	ChannelType uint8         `json:"channel_type"`    // 频道类型
+	Topic       string        `json:"topic,omitempty"` // 话题
+	Payload     []byte        `json:"payload"`         // 消息内容（base64）
+}
+
+// 对应 SendackPacket
+type wsJSONSendack struct {
+	Type         string `json:"type"`
+	ClientSeq    uint64 `json:"client_seq"`
+	ClientMsgNo  string `json:"client_msg_no"`
+	MessageID    int64  `json:"message_id"`
+	MessageIdStr string `json:"message_idstr"`
+	MessageSeq   uint32 `json:"message_seq"`
+	ReasonCode   uint8  `json:"reason_code"`
+}
+
+// 对应 RecvPacket
+type wsJSONRecv struct {
+	Type         string             `json:"type"`
+	Header       MessageHeader      `json:"header"`
+	Setting      uint8              `json:"setting"`
+	MessageID    int64              `json:"message_id"`
+	MessageIdStr string             `json:"message_idstr"`
+	MessageSeq   uint32             `json:"message_seq"`
+	ClientMsgNo  string             `json:"client_msg_no"`
+	StreamNo     string             `json:"stream_no,omitempty"`
+	StreamSeq    uint32             `json:"stream_seq,omitempty"`
+	StreamFlag   wkproto.StreamFlag `json:"stream_flag,omitempty"`
+	FromUID      string             `json:"from_uid"`
+	ChannelID    string             `json:"channel_id"`
+	ChannelType  uint8              `json:"channel_type"`
+	Topic        string             `json:"topic,omitempty"`
+	Expire       uint32             `json:"expire"`
+	Timestamp    int32              `json:"timestamp"`
+	Payload      []byte             `json:"payload"`
+}
+
+// 对应 RecvackPacket，message_id超过js的安全整数时可以使用message_idstr
+type wsJSONRecvack struct {
+	Type         string `json:"type"`
+	MessageID    int64  `json:"message_id"`
+	MessageIdStr string `json:"message_idstr"`
+	MessageSeq   uint32 `json:"message_seq"`
+}
+
+// 对应 DisconnectPacket
+type wsJSONDisconnect struct {
+	Type       string `json:"type"`
+	ReasonCode uint8  `json:"reason_code"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+// websocket JSON文本协议网关
+type wsJSONGateway struct {
+	s *Server
+	wklog.Log
+}
+
+func newWSJSONGateway(s *Server) *wsJSONGateway {
+	return &wsJSONGateway{
+		s:   s,
+		Log: wklog.NewWKLog("wsJSONGateway"),
+	}
+}
+
+// 是否是协商了JSON文本协议的websocket连接
+func isWSJSONConn(conn wknet.Conn) bool {
+	protocol, _ := conn.Value(wknet.ConnValueWSSubprotocol).(string)
+	return protocol == wsJSONSubprotocol
+}
+
+// 处理收到的数据，收件缓存里是完整的websocket消息，每个消息是一个JSON帧
+func (g *wsJSONGateway) onData(conn wknet.Conn, buff []byte) error {
+	var connCtx *connContext
+	if connCtxObj := conn.Context(); connCtxObj != nil {
+		connCtx = connCtxObj.(*connContext)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(buff))
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			if err == io.EOF {
+				break
+			}
+			g.Warn("Failed to decode the json frame,conn will be closed", zap.Error(err), zap.Int64("connId", conn.ID()))
+			_ = conn.Close()
+			return nil
+		}
+		var frame wsJSONFrame
+		if err := json.Unmarshal(raw, &frame); err != nil {
+			g.Warn("json frame is illegal,conn will be closed", zap.Error(err), zap.Int64("connId", conn.ID()))
+			_ = conn.Close()
+			return nil
+		}
+
+		if connCtx == nil || !connCtx.isAuth.Load() {
+			if connCtx != nil || frame.Type != wsJSONTypeConnect {
+				g.Warn("请先进行连接！", zap.String("type", frame.Type))
+				_ = conn.Close()
+				return nil
+			}
+			connCtx = g.handleConnect(conn, raw)
+			if connCtx == nil {
+				return nil
+			}
+			continue
+		}
+		if !g.handleFrame(connCtx, frame.Type, raw) {
+			return nil
+		}
+	}
+	_, _ = conn.Discard(len(buff))
+	return nil
+}
+
+func (g *wsJSONGateway) handleConnect(conn wknet.Conn, raw json.RawMessage) *connContext {
+	var connect wsJSONConnect
+	if err := json.Unmarshal(raw, &connect); err != nil {
+		g.Warn("Failed to decode the connect frame,conn will be closed", zap.Error(err))
+		_ = conn.Close()
+		return nil
+	}
+	if strings.TrimSpace(connect.UID) == "" {
+		g.Warn("UID is empty,conn will be closed")
+		_ = conn.Close()
+		return nil
+	}
+	if IsSpecialChar(connect.UID) {
+		g.Warn("UID is illegal,conn will be closed", zap.String("uid", connect.UID))
+		_ = conn.Close()
+		return nil
+	}
+	clientTimestamp := connect.ClientTimestamp
+	if clientTimestamp == 0 {
+		clientTimestamp = time.Now().UnixNano() / 1000 / 1000
+	}
+
+	// 网关代替客户端完成DH密钥交换，消息在网关内加解密
+	_, clientPubKey := wkutil.GetCurve25519KeypPair()
+	packet := &wkproto.ConnectPacket{
+		Version:         wkproto.LatestVersion,
+		ClientKey:       base64.StdEncoding.EncodeToString(clientPubKey[:]),
+		DeviceID:        connect.DeviceID,
+		DeviceFlag:      wkproto.DeviceFlag(connect.DeviceFlag),
+		ClientTimestamp: clientTimestamp,
+		UID:             connect.UID,
+		Token:           connect.Token,
+	}
+
+	sub := g.s.userReactor.reactorSub(packet.UID)
+	connInfo := connInfo{
+		connId:       conn.ID(),
+		uid:          packet.UID,
+		deviceId:     packet.DeviceID,
+		deviceFlag:   packet.DeviceFlag,
+		protoVersion: packet.Version,
+	}
+	connCtx := newConnContext(connInfo, conn, sub)
+	connCtx.wsJSON = true
+	conn.SetContext(connCtx)
+
+	// 添加用户的连接，如果用户不存在则创建
+	g.s.userReactor.addConnAndCreateUserHandlerIfNotExist(connCtx)
+
+	connCtx.addConnectPacket(packet)
+	return connCtx
+}
+
+// 处理认证后收到的帧，返回false表示连接已关闭
+func (g *wsJSONGateway) handleFrame(connCtx *connContext, frameType string, raw json.RawMessage) bool {
+	switch frameType {
+	case wsJSONTypeSend:
+		var send wsJSONSend
+		if err := json.Unmarshal(raw, &send); err != nil {
+			return g.frameError(connCtx, frameType, err)
+		}
+		g.handleSend(connCtx, &send)
+	case wsJSONTypeRecvack:
+		var recvack wsJSONRecvack
+		if err := json.Unmarshal(raw, &recvack); err != nil {
+			return g.frameError(connCtx, frameType, err)
+		}
+		messageId := recvack.MessageID
+		if recvack.MessageIdStr != "" {
+			var err error
+			if messageId, err = strconv.ParseInt(recvack.MessageIdStr, 10, 64); err != nil {
+				return g.frameError(connCtx, frameType, err)
+			}
+		}
+		connCtx.addOtherPacket(&wkproto.RecvackPacket{
+			MessageID:  messageId,
+			MessageSeq: recvack.MessageSeq,
+		})
+	case wsJSONTypePing:
+		connCtx.addOtherPacket(&wkproto.PingPacket{})
+	case wsJSONTypeDisconnect:
+		connCtx.close()
+		return false
+	default:
+		g.Warn("unsupported json frame,conn will be closed", zap.String("type", frameType), zap.String("uid", connCtx.uid))
+		connCtx.close()
+		return false
+	}
+	return true
+}
+
+func (g *wsJSONGateway) frameError(connCtx *connContext, frameType string, err error) bool {
+	g.Warn("json frame is illegal,conn will be closed", zap.Error(err), zap.String("type", frameType), zap.String("uid", connCtx.uid))
+	connCtx.close()
+	return false
+}
+
+func (g *wsJSONGateway) handleSend(connCtx *connContext, send *wsJSONSend) {
+	setting := wkproto.Setting(send.Setting)
+	if send.Topic != "" {
+		setting = setting.Set(wkproto.SettingTopic)
+	}
+	sendPacket := &wkproto.SendPacket{
+		Framer: wkproto.Framer{
+			NoPersist: send.Header.NoPersist == 1,
+			RedDot:    send.Header.RedDot == 1,
+			SyncOnce:  send.Header.SyncOnce == 1,
+		},
+		Setting:     setting,
+		Expire:      send.Expire,
+		ClientSeq:   send.ClientSeq,
+		ClientMsgNo: send.ClientMsgNo,
+		ChannelID:   send.ChannelID,
+		ChannelType: send.ChannelType,
+		Topic:       send.Topic,
+		Payload:     send.Payload,
+	}
+	if strings.TrimSpace(sendPacket.ClientMsgNo) == "" {
+		sendPacket.ClientMsgNo = wkutil.GenUUID()
+	}
+	if err := connCtx.encryptSendPacket(sendPacket); err != nil {
+		g.Error("加密消息失败！", zap.Error(err), zap.String("uid", connCtx.uid))
+		_ = g.writeFrame(connCtx, &wsJSONSendack{
+			Type:        wsJSONTypeSendack,
+			ClientSeq:   send.ClientSeq,
+			ClientMsgNo: sendPacket.ClientMsgNo,
+			ReasonCode:  uint8(wkproto.ReasonSystemError),
+		})
+		_ = connCtx.conn.WakeWrite()
+		return
+	}
+	connCtx.addSendPacket(sendPacket)
+}
+
+// 将要写给连接的悟空IM协议数据转换为JSON帧后写入
+func (g *wsJSONGateway) write(connCtx *connContext, data []byte) error {
+	offset := 0
+	for len(data) > offset {
+		frame, size, err := g.s.opts.Proto.DecodeFrame(data[offset:], connCtx.protoVersion)
+		if err != nil {
+			g.Warn("write: decode frame failed", zap.Error(err), zap.String("uid", connCtx.uid))
+			return err
+		}
+		if frame == nil {
+			break
+		}
+		offset += size
+
+		var jsonFrame interface{}
+		switch frame.GetFrameType() {
+		case wkproto.CONNACK:
+			connack := frame.(*wkproto.ConnackPacket)
+			jsonFrame = &wsJSONConnack{
+				Type:          wsJSONTypeConnack,
+				ServerVersion: connack.ServerVersion,
+				TimeDiff:      connack.TimeDiff,
+				ReasonCode:    uint8(connack.ReasonCode),
+				NodeId:        connack.NodeId,
+			}
+		case wkproto.SENDACK:
+			sendack := frame.(*wkproto.SendackPacket)
+			jsonFrame = &wsJSONSendack{
+				Type:         wsJSONTypeSendack,
+				ClientSeq:    sendack.ClientSeq,
+				ClientMsgNo:  sendack.ClientMsgNo,
+				MessageID:    sendack.MessageID,
+				MessageIdStr: strconv.FormatInt(sendack.MessageID, 10),
+				MessageSeq:   sendack.MessageSeq,
+				ReasonCode:   uint8(sendack.ReasonCode),
+			}
+		case wkproto.RECV:
+			recv := frame.(*wkproto.RecvPacket)
+			payload, err := connCtx.decryptRecvPayload(recv)
+			if err != nil {
+				g.Error("解密消息payload失败！", zap.Error(err), zap.String("uid", connCtx.uid), zap.Int64("messageId", recv.MessageID))
+				connCtx.addOtherPacket(&wkproto.RecvackPacket{
+					MessageID:  recv.MessageID,
+					MessageSeq: recv.MessageSeq,
+				})
+				continue
+			}
+			setting := recv.Setting
+			setting.Clear(wkproto.SettingNoEncrypt) // payload已解密
+			jsonFrame = &wsJSONRecv{
+				Type:         wsJSONTypeRecv,
+				Header:       MessageHeader{NoPersist: wkutil.BoolToInt(recv.NoPersist), RedDot: wkutil.BoolToInt(recv.RedDot), SyncOnce: wkutil.BoolToInt(recv.SyncOnce)},
+				Setting:      setting.Uint8(),
+				MessageID:    recv.MessageID,
+				MessageIdStr: strconv.FormatInt(recv.MessageID, 10),
+				MessageSeq:   recv.MessageSeq,
+				ClientMsgNo:  recv.ClientMsgNo,
+				StreamNo:     recv.StreamNo,
+				StreamSeq:    recv.StreamSeq,
+				StreamFlag:   recv.StreamFlag,
+				FromUID:      recv.FromUID,
+				ChannelID:    recv.ChannelID,
+				ChannelType:  recv.ChannelType,
+				Topic:        recv.Topic,
+				Expire:       recv.Expire,
+				Timestamp:    recv.Timestamp,
+				Payload:      payload,
+			}
+		case wkproto.PONG:
+			jsonFrame = &wsJSONFrame{Type: wsJSONTypePong}
+		case wkproto.DISCONNECT:
+			disconnect := frame.(*wkproto.DisconnectPacket)
+			jsonFrame = &wsJSONDisconnect{
+				Type:       wsJSONTypeDisconnect,
+				ReasonCode: uint8(disconnect.ReasonCode),
+				Reason:     disconnect.Reason,
+			}
+		default: // 其他的包JSON协议不支持
+			g.Debug("write: ignore frame", zap.String("frameType", frame.GetFrameType().String()), zap.String("uid", connCtx.uid))
+			continue
+		}
+		if err = g.writeFrame(connCtx, jsonFrame); err != nil {
+			return err
+		}
+	}
+	return connCtx.conn.WakeWrite()
+}
+
+func (g *wsJSONGateway) writeFrame(connCtx *connContext, frame interface{}) error {
+	data, err := json.Marshal(frame)
+	if err != nil {
+		g.Warn("encode json frame failed", zap.Error(err), zap.String("uid", connCtx.uid))
+		return err
+	}
+	wsConn, ok := connCtx.conn.(wknet.IWSConn)
+	if !ok {
+		return nil
+	}
+	if err = wsConn.WriteServerText(data); err != nil {
+		g.Warn("Failed to write the json frame", zap.Error(err), zap.String("uid", connCtx.uid))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+	"time"
+
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+func wsJSONTestConnect(t *testing.T, addr string, uid string) *websocket.Conn {
+	u := url.URL{Scheme: "ws", Host: addr, Path: "/"}
+	dialer := websocket.Dialer{Subprotocols: []string{wsJSONSubprotocol}}
+	conn, _, err := dialer.Dial(u.String(), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, wsJSONSubprotocol, conn.Subprotocol())
+
+	err = conn.WriteJSON(&wsJSONConnect{Type: wsJSONTypeConnect, UID: uid, DeviceID: uid + "-web", DeviceFlag: uint8(wkproto.WEB)})
+	assert.NoError(t, err)
+
+	var connack wsJSONConnack
+	wsJSONTestRead(t, conn, &connack)
+	assert.Equal(t, wsJSONTypeConnack, connack.Type)
+	assert.Equal(t, uint8(wkproto.ReasonSuccess), connack.ReasonCode)
+	return conn
+}
+
+func wsJSONTestRead(t *testing.T, conn *websocket.Conn, v interface{}) {
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second * 10))
+	msgType, data, err := conn.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, websocket.TextMessage, msgType)
+	assert.NoError(t, json.Unmarshal(data, v))
+}
+
+func TestWSJSONSendAndRecv(t *testing.T) {
+	s := NewTestServer(t, WithWSAddr("ws://127.0.0.1:0"), WithWSJSONOn(true), WithWSJSONInsecureOn(true)) // 测试使用明文ws
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady(time.Second * 10) // 等待服务准备好
+
+	addr := s.engine.WSRealListenAddr().String()
+	sender := wsJSONTestConnect(t, addr, "jsonsender")
+	defer sender.Close()
+	receiver := wsJSONTestConnect(t, addr, "jsonreceiver")
+	defer receiver.Close()
+
+	err = sender.WriteJSON(&wsJSONSend{
+		Type:        wsJSONTypeSend,
+		Header:      MessageHeader{RedDot: 1},
+		ClientSeq:   1,
+		ClientMsgNo: "msgno1",
+		ChannelID:   "jsonreceiver",
+		ChannelType: wkproto.ChannelTypePerson,
+		Payload:     []byte(`{"type":1,"content":"hello"}`),
+	})
+	assert.NoError(t, err)
+
+	var sendack wsJSONSendack
+	wsJSONTestRead(t, sender, &sendack)
+	assert.Equal(t, wsJSONTypeSendack, sendack.Type)
+	assert.Equal(t, uint64(1), sendack.ClientSeq)
+	assert.Equal(t, uint8(wkproto.ReasonSuccess), sendack.ReasonCode)
+	assert.NotZero(t, sendack.MessageID)
+
+	var recv wsJSONRecv
+	wsJSONTestRead(t, receiver, &recv)
+	assert.Equal(t, wsJSONTypeRecv, recv.Type)
+	assert.Equal(t, "jsonsender", recv.FromUID)
+	assert.Equal(t, "jsonsender", recv.ChannelID)
+	assert.Equal(t, "msgno1", recv.ClientMsgNo)
+	assert.Equal(t, sendack.MessageIdStr, recv.MessageIdStr)
+	assert.Equal(t, `{"type":1,"content":"hello"}`, string(recv.Payload))
+
+	err = receiver.WriteJSON(&wsJSONRecvack{Type: wsJSONTypeRecvack, MessageIdStr: recv.MessageIdStr, MessageSeq: recv.MessageSeq})
+	assert.NoError(t, err)
+
+	err = receiver.WriteJSON(&wsJSONFrame{Type: wsJSONTypePing})
+	assert.NoError(t, err)
+	var pong wsJSONFrame
+	wsJSONTestRead(t, receiver, &pong)
+	assert.Equal(t, wsJSONTypePong, pong.Type)
+}
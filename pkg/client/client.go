@@ -601,6 +601,9 @@ func (c *Client) SendMessage(channel *Channel, payload []byte, opt ...SendOption
 	} else {
 		setting.Set(wkproto.SettingNoEncrypt)
 	}
+	if opts.Transient {
+		setting.Set(SettingTransient)
+	}
 
 	clientMsgNo := opts.ClientMsgNo
 	if clientMsgNo == "" {
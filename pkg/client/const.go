@@ -3,9 +3,13 @@ package client
 import (
 	"errors"
 
+	"github.com/WuKongIM/WuKongIM/pkg/protocol"
 	"go.uber.org/atomic"
 )
 
+// SettingTransient 瞬态事件（比如正在输入）的消息设置，见protocol.SettingTransient
+const SettingTransient = protocol.SettingTransient
+
 // Status represents the state of the connection.
 type Status int
 
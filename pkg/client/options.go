@@ -96,6 +96,7 @@ type SendOptions struct {
 	Flush       bool // 是否io flush 默认true
 	RedDot      bool // 是否显示红点 默认true
 	NoEncrypt   bool // 是否不需要加密
+	Transient   bool // 是否是瞬态事件（比如正在输入），不存储只投递给在线用户
 	ClientMsgNo string
 }
 
@@ -159,3 +160,11 @@ func SendOptionWithNoEncrypt(noEncrypt bool) SendOption {
 		return nil
 	}
 }
+
+// SendOptionWithTransient 是否是瞬态事件（比如正在输入）
+func SendOptionWithTransient(transient bool) SendOption {
+	return func(opts *SendOptions) error {
+		opts.Transient = transient
+		return nil
+	}
+}
@@ -8,11 +8,13 @@ import (
 	"net/url"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/network"
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
 	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
 	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
 )
@@ -342,3 +344,38 @@ func (s *Server) requestDeviceSearch(path string, nodeId uint64, queryMap map[st
 	}
 	return deviceRespTotal, nil
 }
+
+// 获取用户的会话审计日志（按时间倒序）
+func (s *Server) userSessionsGet(c *wkhttp.Context) {
+	uid := c.Param("uid")
+	limit := wkutil.ParseInt(c.Query("limit"))
+	start := wkutil.ParseInt64(c.Query("start")) // 开始时间（秒）
+	end := wkutil.ParseInt64(c.Query("end"))     // 结束时间（秒）
+
+	if limit <= 0 {
+		limit = s.opts.PageSize
+	}
+
+	leaderNode, err := s.SlotLeaderOfChannel(uid, wkproto.ChannelTypePerson)
+	if err != nil {
+		s.Error("SlotLeaderOfChannel error", zap.Error(err), zap.String("uid", uid))
+		c.ResponseError(err)
+		return
+	}
+	if leaderNode.Id != s.opts.NodeId {
+		c.Forward(fmt.Sprintf("%s%s", leaderNode.ApiServerAddr, c.Request.URL.Path))
+		return
+	}
+
+	logs, err := s.opts.DB.GetSessionLogs(uid, start*int64(time.Second), end*int64(time.Second), limit)
+	if err != nil {
+		s.Error("GetSessionLogs error", zap.Error(err), zap.String("uid", uid))
+		c.ResponseError(err)
+		return
+	}
+	resps := make([]*sessionLogResp, 0, len(logs))
+	for _, l := range logs {
+		resps = append(resps, newSessionLogResp(l))
+	}
+	c.JSON(http.StatusOK, resps)
+}
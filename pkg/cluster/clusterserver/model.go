@@ -1324,6 +1324,39 @@ func newDeviceResp(d wkdb.Device) *deviceResp {
 	}
 }
 
+type sessionLogResp struct {
+	Id              uint64 `json:"id"`
+	Uid             string `json:"uid"`               // 用户uid
+	Event           string `json:"event"`             // 事件类型 connect: 连接认证成功 auth_fail: 认证失败 kick: 被踢 disconnect: 断开
+	NodeId          uint64 `json:"node_id"`           // 连接所在的节点
+	DeviceFlag      uint64 `json:"device_flag"`       // 设备标记
+	DeviceId        string `json:"device_id"`         // 设备id
+	Ip              string `json:"ip"`                // 客户端ip
+	ReasonCode      uint8  `json:"reason_code"`       // 原因码
+	Reason          string `json:"reason"`            // 原因
+	CreatedAt       int64  `json:"created_at"`        // 事件时间
+	CreatedAtFormat string `json:"created_at_format"` // 事件时间格式化
+}
+
+func newSessionLogResp(l wkdb.SessionLog) *sessionLogResp {
+	resp := &sessionLogResp{
+		Id:         l.Id,
+		Uid:        l.Uid,
+		Event:      l.Event,
+		NodeId:     l.NodeId,
+		DeviceFlag: l.DeviceFlag,
+		DeviceId:   l.DeviceId,
+		Ip:         l.Ip,
+		ReasonCode: l.ReasonCode,
+		Reason:     l.Reason,
+	}
+	if l.CreatedAt != nil {
+		resp.CreatedAt = l.CreatedAt.UnixNano()
+		resp.CreatedAtFormat = wkutil.ToyyyyMMddHHmmss(*l.CreatedAt)
+	}
+	return resp
+}
+
 type deviceRespTotal struct {
 	Total int           `json:"total"` // 总数
 	More  int           `json:"more"`  // 是否还有更多
@@ -31,8 +31,9 @@ func (s *Server) ServerAPI(route *wkhttp.WKHttp, prefix string) {
 	route.GET(s.formatPath("/channels/:channel_id/:channel_type/allowlist"), s.allowlistGet)     // 获取白名单列表
 
 	// ================== user ==================
-	route.GET(s.formatPath("/users"), s.userSearch)     // 用户搜索
-	route.GET(s.formatPath("/devices"), s.deviceSearch) // 设备搜索
+	route.GET(s.formatPath("/users"), s.userSearch)                    // 用户搜索
+	route.GET(s.formatPath("/devices"), s.deviceSearch)                // 设备搜索
+	route.GET(s.formatPath("/users/:uid/sessions"), s.userSessionsGet) // 用户的会话审计日志
 
 	// ================== conversation ==================
 	route.GET(s.formatPath("/conversations"), s.conversationSearch) // 搜索最近会话消息
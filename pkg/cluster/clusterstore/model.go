@@ -2,6 +2,7 @@ package clusterstore
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/cluster/replica"
@@ -89,6 +90,34 @@ const (
 	CMDAddOrUpdateTester
 	// 移除测试机
 	CMDRemoveTester
+	// 添加或更新临时频道
+	CMDAddOrUpdateTmpChannel
+	// 移除临时频道
+	CMDRemoveTmpChannel
+	// 添加或更新频道解散记录
+	CMDAddOrUpdateChannelDisband
+	// 更新用户最后在线时间
+	CMDUpdateUserLastSeen
+	// 更新用户设置的状态
+	CMDUpdateUserStatus
+	// 添加或更新用户封禁
+	CMDAddOrUpdateUserBan
+	// 移除用户封禁
+	CMDRemoveUserBan
+	// 更新设备登录信息
+	CMDUpdateDeviceLogin
+	// 添加用户会话审计日志
+	CMDAddSessionLogs
+	// 添加或更新webhook端点
+	CMDSaveWebhookEndpoint
+	// 移除webhook端点
+	CMDRemoveWebhookEndpoint
+	// 更新子区根消息的回复统计
+	CMDUpdateThreadReply
+	// 更新设备的吊销时间
+	CMDUpdateDeviceRevoke
+	// 设置mqtt保留消息
+	CMDSetMQTTRetain
 )
 
 func (c CMDType) Uint16() uint16 {
@@ -171,6 +200,34 @@ func (c CMDType) String() string {
 		return "CMDAddOrUpdateTester"
 	case CMDRemoveTester:
 		return "CMDRemoveTester"
+	case CMDAddOrUpdateTmpChannel:
+		return "CMDAddOrUpdateTmpChannel"
+	case CMDRemoveTmpChannel:
+		return "CMDRemoveTmpChannel"
+	case CMDAddOrUpdateChannelDisband:
+		return "CMDAddOrUpdateChannelDisband"
+	case CMDUpdateUserLastSeen:
+		return "CMDUpdateUserLastSeen"
+	case CMDUpdateUserStatus:
+		return "CMDUpdateUserStatus"
+	case CMDAddOrUpdateUserBan:
+		return "CMDAddOrUpdateUserBan"
+	case CMDRemoveUserBan:
+		return "CMDRemoveUserBan"
+	case CMDUpdateDeviceLogin:
+		return "CMDUpdateDeviceLogin"
+	case CMDAddSessionLogs:
+		return "CMDAddSessionLogs"
+	case CMDSaveWebhookEndpoint:
+		return "CMDSaveWebhookEndpoint"
+	case CMDRemoveWebhookEndpoint:
+		return "CMDRemoveWebhookEndpoint"
+	case CMDUpdateThreadReply:
+		return "CMDUpdateThreadReply"
+	case CMDUpdateDeviceRevoke:
+		return "CMDUpdateDeviceRevoke"
+	case CMDSetMQTTRetain:
+		return "CMDSetMQTTRetain"
 	default:
 		return fmt.Sprintf("CMDUnknown[%d]", c)
 	}
@@ -199,7 +256,9 @@ func NewCMDWithVersion(cmdType CMDType, data []byte, version CmdVersion) *CMD {
 }
 
 func (c *CMD) Marshal() ([]byte, error) {
-	c.version = 1
+	if c.version == 0 {
+		c.version = 1
+	}
 	enc := wkproto.NewEncoder()
 	defer enc.End()
 	enc.WriteUint16(c.version.Uint16())
@@ -242,6 +301,49 @@ func (c *CMD) CMDContent() (string, error) {
 			return "", err
 		}
 		return wkutil.ToJSON(device), nil
+	case CMDUpdateDeviceLogin:
+		device, err := c.DecodeCMDDeviceLogin()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(device), nil
+	case CMDUpdateDeviceRevoke:
+		device, err := c.DecodeCMDDeviceRevoke()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(device), nil
+	case CMDAddSessionLogs:
+		logs, err := c.DecodeCMDAddSessionLogs()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(logs), nil
+	case CMDSaveWebhookEndpoint:
+		endpoint, err := c.DecodeCMDSaveWebhookEndpoint()
+		if err != nil {
+			return "", err
+		}
+		endpoint.Secret = ""
+		return wkutil.ToJSON(endpoint), nil
+	case CMDRemoveWebhookEndpoint:
+		name, err := c.DecodeCMDRemoveWebhookEndpoint()
+		if err != nil {
+			return "", err
+		}
+		return name, nil
+	case CMDUpdateThreadReply:
+		threadReply, err := c.DecodeCMDUpdateThreadReply()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(threadReply), nil
+	case CMDSetMQTTRetain:
+		retain, err := c.DecodeCMDSetMQTTRetain()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(retain), nil
 	case CMDAddUser:
 		user, err := c.DecodeCMDUser()
 		if err != nil {
@@ -796,6 +898,9 @@ func EncodeChannelInfo(c wkdb.ChannelInfo, version CmdVersion) ([]byte, error) {
 	if version > 0 {
 		enc.WriteString(c.Webhook)
 	}
+	if version > 2 {
+		enc.WriteString(strings.Join(c.WebhookEvents, ","))
+	}
 	return enc.Bytes(), nil
 }
 
@@ -850,6 +955,15 @@ func (c *CMD) DecodeChannelInfo() (wkdb.ChannelInfo, error) {
 			return channelInfo, err
 		}
 	}
+	if c.version > 2 {
+		var webhookEvents string
+		if webhookEvents, err = dec.String(); err != nil {
+			return channelInfo, err
+		}
+		if webhookEvents != "" {
+			channelInfo.WebhookEvents = strings.Split(webhookEvents, ",")
+		}
+	}
 
 	return channelInfo, err
 }
@@ -1289,9 +1403,424 @@ func (c *CMD) DecodeCMDRemoveTester() (no string, err error) {
 	return
 }
 
+func EncodeCMDAddOrUpdateTmpChannel(tmpChannel wkdb.TmpChannel) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(tmpChannel.ChannelId)
+	encoder.WriteUint64(tmpChannel.Ttl)
+	encoder.WriteUint64(tmpChannel.IdleTtl)
+	encoder.WriteUint64(uint64(tmpChannel.CreatedAt.UnixNano()))
+	encoder.WriteUint64(uint64(tmpChannel.UpdatedAt.UnixNano()))
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDAddOrUpdateTmpChannel() (tmpChannel wkdb.TmpChannel, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if tmpChannel.ChannelId, err = decoder.String(); err != nil {
+		return
+	}
+	if tmpChannel.Ttl, err = decoder.Uint64(); err != nil {
+		return
+	}
+	if tmpChannel.IdleTtl, err = decoder.Uint64(); err != nil {
+		return
+	}
+	var createdAtUnixNano uint64
+	if createdAtUnixNano, err = decoder.Uint64(); err != nil {
+		return
+	}
+	ct := time.Unix(int64(createdAtUnixNano/1e9), int64(createdAtUnixNano%1e9))
+	tmpChannel.CreatedAt = &ct
+	var updatedAtUnixNano uint64
+	if updatedAtUnixNano, err = decoder.Uint64(); err != nil {
+		return
+	}
+	ut := time.Unix(int64(updatedAtUnixNano/1e9), int64(updatedAtUnixNano%1e9))
+	tmpChannel.UpdatedAt = &ut
+	return
+}
+
+func EncodeCMDRemoveTmpChannel(channelId string) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(channelId)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDRemoveTmpChannel() (channelId string, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if channelId, err = decoder.String(); err != nil {
+		return
+	}
+	return
+}
+
+func EncodeCMDAddOrUpdateChannelDisband(channelDisband wkdb.ChannelDisband) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(channelDisband.ChannelId)
+	encoder.WriteUint8(channelDisband.ChannelType)
+	encoder.WriteUint8(uint8(channelDisband.Status))
+	encoder.WriteString(channelDisband.Reason)
+	encoder.WriteUint8(wkutil.BoolToUint8(channelDisband.Purge))
+	var purgeAt uint64
+	if channelDisband.PurgeAt != nil {
+		purgeAt = uint64(channelDisband.PurgeAt.UnixNano())
+	}
+	encoder.WriteUint64(purgeAt)
+	encoder.WriteUint64(uint64(channelDisband.CreatedAt.UnixNano()))
+	encoder.WriteUint64(uint64(channelDisband.UpdatedAt.UnixNano()))
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDAddOrUpdateChannelDisband() (channelDisband wkdb.ChannelDisband, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if channelDisband.ChannelId, err = decoder.String(); err != nil {
+		return
+	}
+	if channelDisband.ChannelType, err = decoder.Uint8(); err != nil {
+		return
+	}
+	var status uint8
+	if status, err = decoder.Uint8(); err != nil {
+		return
+	}
+	channelDisband.Status = wkdb.ChannelDisbandStatus(status)
+	if channelDisband.Reason, err = decoder.String(); err != nil {
+		return
+	}
+	var purge uint8
+	if purge, err = decoder.Uint8(); err != nil {
+		return
+	}
+	channelDisband.Purge = wkutil.Uint8ToBool(purge)
+	var purgeAtUnixNano uint64
+	if purgeAtUnixNano, err = decoder.Uint64(); err != nil {
+		return
+	}
+	if purgeAtUnixNano > 0 {
+		pt := time.Unix(int64(purgeAtUnixNano/1e9), int64(purgeAtUnixNano%1e9))
+		channelDisband.PurgeAt = &pt
+	}
+	var createdAtUnixNano uint64
+	if createdAtUnixNano, err = decoder.Uint64(); err != nil {
+		return
+	}
+	ct := time.Unix(int64(createdAtUnixNano/1e9), int64(createdAtUnixNano%1e9))
+	channelDisband.CreatedAt = &ct
+	var updatedAtUnixNano uint64
+	if updatedAtUnixNano, err = decoder.Uint64(); err != nil {
+		return
+	}
+	ut := time.Unix(int64(updatedAtUnixNano/1e9), int64(updatedAtUnixNano%1e9))
+	channelDisband.UpdatedAt = &ut
+	return
+}
+
+func EncodeCMDUpdateUserLastSeen(uid string, lastSeen time.Time) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(uid)
+	encoder.WriteUint64(uint64(lastSeen.UnixNano()))
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDUpdateUserLastSeen() (uid string, lastSeen time.Time, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if uid, err = decoder.String(); err != nil {
+		return
+	}
+	var lastSeenUnixNano uint64
+	if lastSeenUnixNano, err = decoder.Uint64(); err != nil {
+		return
+	}
+	lastSeen = time.Unix(int64(lastSeenUnixNano/1e9), int64(lastSeenUnixNano%1e9))
+	return
+}
+
+func EncodeCMDUpdateUserStatus(uid string, status wkdb.UserStatus, statusText string, updatedAt time.Time) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(uid)
+	encoder.WriteUint8(uint8(status))
+	encoder.WriteString(statusText)
+	encoder.WriteUint64(uint64(updatedAt.UnixNano()))
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDUpdateUserStatus() (uid string, status wkdb.UserStatus, statusText string, updatedAt time.Time, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if uid, err = decoder.String(); err != nil {
+		return
+	}
+	var statusUint8 uint8
+	if statusUint8, err = decoder.Uint8(); err != nil {
+		return
+	}
+	status = wkdb.UserStatus(statusUint8)
+	if statusText, err = decoder.String(); err != nil {
+		return
+	}
+	var updatedAtUnixNano uint64
+	if updatedAtUnixNano, err = decoder.Uint64(); err != nil {
+		return
+	}
+	updatedAt = time.Unix(int64(updatedAtUnixNano/1e9), int64(updatedAtUnixNano%1e9))
+	return
+}
+
+func EncodeCMDAddOrUpdateUserBan(userBan wkdb.UserBan) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(userBan.Uid)
+	encoder.WriteString(userBan.Reason)
+	var expireAt uint64
+	if userBan.ExpireAt != nil {
+		expireAt = uint64(userBan.ExpireAt.UnixNano())
+	}
+	encoder.WriteUint64(expireAt)
+	encoder.WriteUint64(uint64(userBan.CreatedAt.UnixNano()))
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDAddOrUpdateUserBan() (userBan wkdb.UserBan, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if userBan.Uid, err = decoder.String(); err != nil {
+		return
+	}
+	if userBan.Reason, err = decoder.String(); err != nil {
+		return
+	}
+	var expireAtUnixNano uint64
+	if expireAtUnixNano, err = decoder.Uint64(); err != nil {
+		return
+	}
+	if expireAtUnixNano > 0 {
+		et := time.Unix(int64(expireAtUnixNano/1e9), int64(expireAtUnixNano%1e9))
+		userBan.ExpireAt = &et
+	}
+	var createdAtUnixNano uint64
+	if createdAtUnixNano, err = decoder.Uint64(); err != nil {
+		return
+	}
+	ct := time.Unix(int64(createdAtUnixNano/1e9), int64(createdAtUnixNano%1e9))
+	userBan.CreatedAt = &ct
+	return
+}
+
+func EncodeCMDRemoveUserBan(uid string) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(uid)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDRemoveUserBan() (uid string, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if uid, err = decoder.String(); err != nil {
+		return
+	}
+	return
+}
+
+// EncodeCMDDeviceLogin 设备的登录信息
+func EncodeCMDDeviceLogin(d wkdb.Device) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteUint64(d.Id)
+	encoder.WriteString(d.Uid)
+	if d.LastLoginAt != nil {
+		encoder.WriteUint64(uint64(d.LastLoginAt.UnixNano()))
+	} else {
+		encoder.WriteUint64(0)
+	}
+	encoder.WriteString(d.LastLoginIP)
+	encoder.WriteString(d.ClientVersion)
+	encoder.WriteString(d.UserAgent)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDDeviceLogin() (d wkdb.Device, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if d.Id, err = decoder.Uint64(); err != nil {
+		return
+	}
+	if d.Uid, err = decoder.String(); err != nil {
+		return
+	}
+	var lastLoginAt uint64
+	if lastLoginAt, err = decoder.Uint64(); err != nil {
+		return
+	}
+	if lastLoginAt > 0 {
+		t := time.Unix(int64(lastLoginAt/1e9), int64(lastLoginAt%1e9))
+		d.LastLoginAt = &t
+	}
+	if d.LastLoginIP, err = decoder.String(); err != nil {
+		return
+	}
+	if d.ClientVersion, err = decoder.String(); err != nil {
+		return
+	}
+	if d.UserAgent, err = decoder.String(); err != nil {
+		return
+	}
+	return
+}
+
+// EncodeCMDDeviceRevoke 设备的吊销时间
+func EncodeCMDDeviceRevoke(d wkdb.Device) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteUint64(d.Id)
+	encoder.WriteString(d.Uid)
+	if d.RevokedAt != nil {
+		encoder.WriteUint64(uint64(d.RevokedAt.UnixNano()))
+	} else {
+		encoder.WriteUint64(0)
+	}
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDDeviceRevoke() (d wkdb.Device, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if d.Id, err = decoder.Uint64(); err != nil {
+		return
+	}
+	if d.Uid, err = decoder.String(); err != nil {
+		return
+	}
+	var revokedAt uint64
+	if revokedAt, err = decoder.Uint64(); err != nil {
+		return
+	}
+	if revokedAt > 0 {
+		t := time.Unix(int64(revokedAt/1e9), int64(revokedAt%1e9))
+		d.RevokedAt = &t
+	}
+	return
+}
+
+func EncodeCMDAddSessionLogs(logs []wkdb.SessionLog) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteUint32(uint32(len(logs)))
+	for _, log := range logs {
+		encoder.WriteUint64(log.Id)
+		encoder.WriteString(log.Uid)
+		encoder.WriteString(log.Event)
+		encoder.WriteUint64(log.NodeId)
+		encoder.WriteUint64(log.DeviceFlag)
+		encoder.WriteString(log.DeviceId)
+		encoder.WriteString(log.Ip)
+		encoder.WriteUint8(log.ReasonCode)
+		encoder.WriteString(log.Reason)
+		var createdAt uint64
+		if log.CreatedAt != nil {
+			createdAt = uint64(log.CreatedAt.UnixNano())
+		}
+		encoder.WriteUint64(createdAt)
+		encoder.WriteUint64(log.RecordNodeId)
+	}
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDAddSessionLogs() (logs []wkdb.SessionLog, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	var count uint32
+	if count, err = decoder.Uint32(); err != nil {
+		return
+	}
+	for i := uint32(0); i < count; i++ {
+		var log wkdb.SessionLog
+		if log.Id, err = decoder.Uint64(); err != nil {
+			return
+		}
+		if log.Uid, err = decoder.String(); err != nil {
+			return
+		}
+		if log.Event, err = decoder.String(); err != nil {
+			return
+		}
+		if log.NodeId, err = decoder.Uint64(); err != nil {
+			return
+		}
+		if log.DeviceFlag, err = decoder.Uint64(); err != nil {
+			return
+		}
+		if log.DeviceId, err = decoder.String(); err != nil {
+			return
+		}
+		if log.Ip, err = decoder.String(); err != nil {
+			return
+		}
+		if log.ReasonCode, err = decoder.Uint8(); err != nil {
+			return
+		}
+		if log.Reason, err = decoder.String(); err != nil {
+			return
+		}
+		var createdAt uint64
+		if createdAt, err = decoder.Uint64(); err != nil {
+			return
+		}
+		if createdAt > 0 {
+			t := time.Unix(int64(createdAt/1e9), int64(createdAt%1e9))
+			log.CreatedAt = &t
+		}
+		if log.RecordNodeId, err = decoder.Uint64(); err != nil {
+			return
+		}
+		logs = append(logs, log)
+	}
+	return
+}
+
 var ErrStoreStopped = fmt.Errorf("store stopped")
 
 type applyReq struct {
 	logs  []replica.Log
 	waitC chan error
 }
+
+func EncodeCMDSaveWebhookEndpoint(endpoint wkdb.WebhookEndpoint) ([]byte, error) {
+	return endpoint.Marshal()
+}
+
+func (c *CMD) DecodeCMDSaveWebhookEndpoint() (endpoint wkdb.WebhookEndpoint, err error) {
+	err = endpoint.Unmarshal(c.Data)
+	return
+}
+
+func EncodeCMDRemoveWebhookEndpoint(name string) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(name)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDRemoveWebhookEndpoint() (name string, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if name, err = decoder.String(); err != nil {
+		return
+	}
+	return
+}
+
+func EncodeCMDUpdateThreadReply(threadReply wkdb.ThreadReply) ([]byte, error) {
+	return threadReply.Marshal()
+}
+
+func (c *CMD) DecodeCMDUpdateThreadReply() (threadReply wkdb.ThreadReply, err error) {
+	err = threadReply.Unmarshal(c.Data)
+	return
+}
+
+func EncodeCMDSetMQTTRetain(retain wkdb.MQTTRetain) ([]byte, error) {
+	return retain.Marshal()
+}
+
+func (c *CMD) DecodeCMDSetMQTTRetain() (retain wkdb.MQTTRetain, err error) {
+	err = retain.Unmarshal(c.Data)
+	return
+}
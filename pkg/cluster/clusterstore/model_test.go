@@ -0,0 +1,219 @@
+package clusterstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChannelInfoCMD(t *testing.T) {
+	createdAt := time.Now()
+	channelInfo := wkdb.ChannelInfo{
+		ChannelId:     "test",
+		ChannelType:   2,
+		Large:         true,
+		Webhook:       "http://127.0.0.1:8080/webhook",
+		WebhookEvents: []string{"msg.notify"},
+		CreatedAt:     &createdAt,
+		UpdatedAt:     &createdAt,
+	}
+	data, err := EncodeChannelInfo(channelInfo, CmdVersionChannelInfo)
+	assert.NoError(t, err)
+
+	cmdData, err := NewCMDWithVersion(CMDAddChannelInfo, data, CmdVersionChannelInfo).Marshal()
+	assert.NoError(t, err)
+
+	cmd := &CMD{}
+	err = cmd.Unmarshal(cmdData)
+	assert.NoError(t, err)
+
+	resultChannelInfo, err := cmd.DecodeChannelInfo()
+	assert.NoError(t, err)
+	assert.Equal(t, channelInfo.ChannelId, resultChannelInfo.ChannelId)
+	assert.Equal(t, channelInfo.Large, resultChannelInfo.Large)
+	assert.Equal(t, channelInfo.Webhook, resultChannelInfo.Webhook)
+	assert.Equal(t, channelInfo.WebhookEvents, resultChannelInfo.WebhookEvents)
+}
+
+func TestChannelDisbandCMD(t *testing.T) {
+	nw := time.Now()
+	purgeAt := nw.Add(time.Hour)
+	channelDisband := wkdb.ChannelDisband{
+		ChannelId:   "test",
+		ChannelType: 2,
+		Status:      wkdb.ChannelDisbandStatusFrozen,
+		Reason:      "test reason",
+		Purge:       true,
+		PurgeAt:     &purgeAt,
+		CreatedAt:   &nw,
+		UpdatedAt:   &nw,
+	}
+	cmd := NewCMD(CMDAddOrUpdateChannelDisband, EncodeCMDAddOrUpdateChannelDisband(channelDisband))
+
+	resultChannelDisband, err := cmd.DecodeCMDAddOrUpdateChannelDisband()
+	assert.NoError(t, err)
+	assert.Equal(t, channelDisband.ChannelId, resultChannelDisband.ChannelId)
+	assert.Equal(t, channelDisband.ChannelType, resultChannelDisband.ChannelType)
+	assert.Equal(t, channelDisband.Status, resultChannelDisband.Status)
+	assert.Equal(t, channelDisband.Reason, resultChannelDisband.Reason)
+	assert.True(t, resultChannelDisband.Purge)
+	assert.Equal(t, purgeAt.UnixNano(), resultChannelDisband.PurgeAt.UnixNano())
+}
+
+func TestUserStatusCMD(t *testing.T) {
+	updatedAt := time.Now()
+	cmd := NewCMD(CMDUpdateUserStatus, EncodeCMDUpdateUserStatus("u1", wkdb.UserStatusAway, "马上回来", updatedAt))
+
+	uid, status, statusText, resultUpdatedAt, err := cmd.DecodeCMDUpdateUserStatus()
+	assert.NoError(t, err)
+	assert.Equal(t, "u1", uid)
+	assert.Equal(t, wkdb.UserStatusAway, status)
+	assert.Equal(t, "马上回来", statusText)
+	assert.Equal(t, updatedAt.UnixNano(), resultUpdatedAt.UnixNano())
+}
+
+func TestUserBanCMD(t *testing.T) {
+	nw := time.Now()
+	expireAt := nw.Add(time.Hour)
+	userBan := wkdb.UserBan{
+		Uid:       "u1",
+		Reason:    "spam",
+		ExpireAt:  &expireAt,
+		CreatedAt: &nw,
+	}
+	cmd := NewCMD(CMDAddOrUpdateUserBan, EncodeCMDAddOrUpdateUserBan(userBan))
+
+	resultUserBan, err := cmd.DecodeCMDAddOrUpdateUserBan()
+	assert.NoError(t, err)
+	assert.Equal(t, userBan.Uid, resultUserBan.Uid)
+	assert.Equal(t, userBan.Reason, resultUserBan.Reason)
+	assert.Equal(t, expireAt.UnixNano(), resultUserBan.ExpireAt.UnixNano())
+	assert.Equal(t, nw.UnixNano(), resultUserBan.CreatedAt.UnixNano())
+}
+
+func TestDeviceLoginCMD(t *testing.T) {
+	lastLoginAt := time.Now()
+	device := wkdb.Device{
+		Id:            1,
+		Uid:           "u1",
+		LastLoginAt:   &lastLoginAt,
+		LastLoginIP:   "127.0.0.1",
+		ClientVersion: "1.0.0",
+		UserAgent:     "test-agent",
+	}
+	cmd := NewCMD(CMDUpdateDeviceLogin, EncodeCMDDeviceLogin(device))
+
+	resultDevice, err := cmd.DecodeCMDDeviceLogin()
+	assert.NoError(t, err)
+	assert.Equal(t, device.Id, resultDevice.Id)
+	assert.Equal(t, device.Uid, resultDevice.Uid)
+	assert.Equal(t, lastLoginAt.UnixNano(), resultDevice.LastLoginAt.UnixNano())
+	assert.Equal(t, device.LastLoginIP, resultDevice.LastLoginIP)
+	assert.Equal(t, device.ClientVersion, resultDevice.ClientVersion)
+	assert.Equal(t, device.UserAgent, resultDevice.UserAgent)
+}
+
+func TestDeviceRevokeCMD(t *testing.T) {
+	revokedAt := time.Now()
+	device := wkdb.Device{
+		Id:        1,
+		Uid:       "u1",
+		RevokedAt: &revokedAt,
+	}
+	cmd := NewCMD(CMDUpdateDeviceRevoke, EncodeCMDDeviceRevoke(device))
+
+	resultDevice, err := cmd.DecodeCMDDeviceRevoke()
+	assert.NoError(t, err)
+	assert.Equal(t, device.Id, resultDevice.Id)
+	assert.Equal(t, device.Uid, resultDevice.Uid)
+	assert.Equal(t, revokedAt.UnixNano(), resultDevice.RevokedAt.UnixNano())
+}
+
+func TestSessionLogsCMD(t *testing.T) {
+	createdAt := time.Now()
+	logs := []wkdb.SessionLog{
+		{Id: 1, RecordNodeId: 1, Uid: "u1", Event: wkdb.SessionEventConnect, NodeId: 1, DeviceFlag: 1, DeviceId: "d1", Ip: "127.0.0.1", ReasonCode: 1, CreatedAt: &createdAt},
+		{Id: 2, RecordNodeId: 2, Uid: "u2", Event: wkdb.SessionEventKick, NodeId: 2, Reason: "banned", CreatedAt: &createdAt},
+	}
+	cmd := NewCMD(CMDAddSessionLogs, EncodeCMDAddSessionLogs(logs))
+
+	resultLogs, err := cmd.DecodeCMDAddSessionLogs()
+	assert.NoError(t, err)
+	assert.Len(t, resultLogs, 2)
+	for i, log := range logs {
+		assert.Equal(t, log.Id, resultLogs[i].Id)
+		assert.Equal(t, log.RecordNodeId, resultLogs[i].RecordNodeId)
+		assert.Equal(t, log.Uid, resultLogs[i].Uid)
+		assert.Equal(t, log.Event, resultLogs[i].Event)
+		assert.Equal(t, log.NodeId, resultLogs[i].NodeId)
+		assert.Equal(t, log.DeviceFlag, resultLogs[i].DeviceFlag)
+		assert.Equal(t, log.DeviceId, resultLogs[i].DeviceId)
+		assert.Equal(t, log.Ip, resultLogs[i].Ip)
+		assert.Equal(t, log.ReasonCode, resultLogs[i].ReasonCode)
+		assert.Equal(t, log.Reason, resultLogs[i].Reason)
+		assert.Equal(t, createdAt.UnixNano(), resultLogs[i].CreatedAt.UnixNano())
+	}
+}
+
+func TestWebhookEndpointCMD(t *testing.T) {
+	endpoint := wkdb.WebhookEndpoint{
+		Name:         "audit",
+		HTTPAddr:     "http://127.0.0.1:8080/webhook",
+		Events:       []string{"msg.notify", "user.onlinestatus"},
+		ChannelTypes: []uint8{1, 2},
+		BatchSize:    50,
+		Secret:       "secret",
+		CreatedAt:    time.Now().Unix(),
+		UpdatedAt:    time.Now().Unix(),
+	}
+	data, err := EncodeCMDSaveWebhookEndpoint(endpoint)
+	assert.NoError(t, err)
+	cmd := NewCMD(CMDSaveWebhookEndpoint, data)
+	resultEndpoint, err := cmd.DecodeCMDSaveWebhookEndpoint()
+	assert.NoError(t, err)
+	assert.Equal(t, endpoint, resultEndpoint)
+
+	cmd = NewCMD(CMDRemoveWebhookEndpoint, EncodeCMDRemoveWebhookEndpoint("audit"))
+	name, err := cmd.DecodeCMDRemoveWebhookEndpoint()
+	assert.NoError(t, err)
+	assert.Equal(t, "audit", name)
+}
+
+func TestThreadReplyCMD(t *testing.T) {
+	threadReply := wkdb.ThreadReply{
+		RootMessageId:       100,
+		ChannelId:           "g1",
+		ChannelType:         2,
+		ReplyCount:          3,
+		LastReplyMessageId:  1003,
+		LastReplyMessageSeq: 3,
+		LastReplyFromUid:    "u1",
+		LastReplyAt:         time.Now().Unix(),
+	}
+	data, err := EncodeCMDUpdateThreadReply(threadReply)
+	assert.NoError(t, err)
+	cmd := NewCMD(CMDUpdateThreadReply, data)
+	resultThreadReply, err := cmd.DecodeCMDUpdateThreadReply()
+	assert.NoError(t, err)
+	assert.Equal(t, threadReply, resultThreadReply)
+}
+
+func TestMQTTRetainCMD(t *testing.T) {
+	retain := wkdb.MQTTRetain{
+		ChannelId:   "g1",
+		ChannelType: 2,
+		MessageId:   1003,
+		MessageSeq:  3,
+		FromUid:     "u1",
+		Timestamp:   int32(time.Now().Unix()),
+		Payload:     []byte("on"),
+	}
+	data, err := EncodeCMDSetMQTTRetain(retain)
+	assert.NoError(t, err)
+	cmd := NewCMD(CMDSetMQTTRetain, data)
+	resultRetain, err := cmd.DecodeCMDSetMQTTRetain()
+	assert.NoError(t, err)
+	assert.Equal(t, retain, resultRetain)
+}
@@ -174,6 +174,36 @@ func (s *Store) execCMD(cmd *CMD) error {
 		return s.handleAddOrUpdateTester(cmd)
 	case CMDRemoveTester: // 移除测试机
 		return s.handleRemoveTester(cmd)
+	case CMDAddOrUpdateTmpChannel: // 添加或更新临时频道
+		return s.handleAddOrUpdateTmpChannel(cmd)
+	case CMDRemoveTmpChannel: // 移除临时频道
+		return s.handleRemoveTmpChannel(cmd)
+	case CMDAddOrUpdateChannelDisband: // 添加或更新频道解散记录
+		return s.handleAddOrUpdateChannelDisband(cmd)
+	case CMDUpdateUserLastSeen: // 更新用户最后在线时间
+		return s.handleUpdateUserLastSeen(cmd)
+	case CMDUpdateUserStatus: // 更新用户设置的状态
+		return s.handleUpdateUserStatus(cmd)
+	case CMDAddOrUpdateUserBan: // 添加或更新用户封禁
+		return s.handleAddOrUpdateUserBan(cmd)
+	case CMDRemoveUserBan: // 移除用户封禁
+		return s.handleRemoveUserBan(cmd)
+	case CMDUpdateDeviceLogin: // 更新设备登录信息
+		return s.handleUpdateDeviceLogin(cmd)
+	case CMDAddSessionLogs: // 添加用户会话审计日志
+		return s.handleAddSessionLogs(cmd)
+	case CMDSaveWebhookEndpoint: // 添加或更新webhook端点
+		return s.handleSaveWebhookEndpoint(cmd)
+	case CMDRemoveWebhookEndpoint: // 移除webhook端点
+		return s.handleRemoveWebhookEndpoint(cmd)
+	case CMDUpdateThreadReply: // 更新子区根消息的回复统计
+		return s.handleUpdateThreadReply(cmd)
+	case CMDUpdateDeviceRevoke: // 更新设备的吊销时间
+		return s.handleUpdateDeviceRevoke(cmd)
+	case CMDSetMQTTRetain: // 设置mqtt保留消息
+		return s.handleSetMQTTRetain(cmd)
+	case CMDDeleteChannelAndClearMessages: // 删除频道并清空消息
+		return s.handleDeleteChannelAndClearMessages(cmd)
 
 	}
 	return nil
@@ -220,6 +250,22 @@ func (s *Store) handleAddDevice(cmd *CMD) error {
 	return s.wdb.AddDevice(u)
 }
 
+func (s *Store) handleUpdateDeviceLogin(cmd *CMD) error {
+	d, err := cmd.DecodeCMDDeviceLogin()
+	if err != nil {
+		return err
+	}
+	return s.wdb.UpdateDeviceLogin(d)
+}
+
+func (s *Store) handleUpdateDeviceRevoke(cmd *CMD) error {
+	d, err := cmd.DecodeCMDDeviceRevoke()
+	if err != nil {
+		return err
+	}
+	return s.wdb.UpdateDeviceRevoke(d)
+}
+
 func (s *Store) handleUpdateDevice(cmd *CMD) error {
 	u, err := cmd.DecodeCMDDevice()
 	if err != nil {
@@ -450,3 +496,132 @@ func (s *Store) handleRemoveTester(cmd *CMD) error {
 	}
 	return s.wdb.RemoveTester(no)
 }
+
+func (s *Store) handleAddOrUpdateTmpChannel(cmd *CMD) error {
+	tmpChannel, err := cmd.DecodeCMDAddOrUpdateTmpChannel()
+	if err != nil {
+		return err
+	}
+	return s.wdb.AddOrUpdateTmpChannel(tmpChannel)
+}
+
+func (s *Store) handleRemoveTmpChannel(cmd *CMD) error {
+	channelId, err := cmd.DecodeCMDRemoveTmpChannel()
+	if err != nil {
+		return err
+	}
+	return s.wdb.RemoveTmpChannel(channelId)
+}
+
+func (s *Store) handleDeleteChannelAndClearMessages(cmd *CMD) error {
+	channelId, channelType, err := cmd.DecodeChannel()
+	if err != nil {
+		return err
+	}
+	// 删除频道的元数据
+	if err = s.wdb.RemoveAllSubscriber(channelId, channelType); err != nil {
+		return err
+	}
+	if err = s.wdb.RemoveAllDenylist(channelId, channelType); err != nil {
+		return err
+	}
+	if err = s.wdb.RemoveAllAllowlist(channelId, channelType); err != nil {
+		return err
+	}
+	if err = s.wdb.DeleteChannel(channelId, channelType); err != nil {
+		return err
+	}
+	// 删除频道的分布式配置
+	if err = s.wdb.DeleteChannelClusterConfig(channelId, channelType); err != nil {
+		return err
+	}
+	// 清空本节点上的频道消息
+	lastMsgSeq, _, err := s.wdb.GetChannelLastMessageSeq(channelId, channelType)
+	if err != nil {
+		return err
+	}
+	if lastMsgSeq > 0 {
+		return s.wdb.TruncateLogTo(channelId, channelType, 1)
+	}
+	return nil
+}
+
+func (s *Store) handleAddOrUpdateChannelDisband(cmd *CMD) error {
+	channelDisband, err := cmd.DecodeCMDAddOrUpdateChannelDisband()
+	if err != nil {
+		return err
+	}
+	return s.wdb.AddOrUpdateChannelDisband(channelDisband)
+}
+
+func (s *Store) handleUpdateUserLastSeen(cmd *CMD) error {
+	uid, lastSeen, err := cmd.DecodeCMDUpdateUserLastSeen()
+	if err != nil {
+		return err
+	}
+	return s.wdb.UpdateUserLastSeen(uid, lastSeen)
+}
+
+func (s *Store) handleUpdateUserStatus(cmd *CMD) error {
+	uid, status, statusText, updatedAt, err := cmd.DecodeCMDUpdateUserStatus()
+	if err != nil {
+		return err
+	}
+	return s.wdb.UpdateUserStatus(uid, status, statusText, updatedAt)
+}
+
+func (s *Store) handleAddOrUpdateUserBan(cmd *CMD) error {
+	userBan, err := cmd.DecodeCMDAddOrUpdateUserBan()
+	if err != nil {
+		return err
+	}
+	return s.wdb.AddOrUpdateUserBan(userBan)
+}
+
+func (s *Store) handleAddSessionLogs(cmd *CMD) error {
+	logs, err := cmd.DecodeCMDAddSessionLogs()
+	if err != nil {
+		return err
+	}
+	return s.wdb.AddSessionLogs(logs)
+}
+
+func (s *Store) handleRemoveUserBan(cmd *CMD) error {
+	uid, err := cmd.DecodeCMDRemoveUserBan()
+	if err != nil {
+		return err
+	}
+	return s.wdb.RemoveUserBan(uid)
+}
+
+func (s *Store) handleSaveWebhookEndpoint(cmd *CMD) error {
+	endpoint, err := cmd.DecodeCMDSaveWebhookEndpoint()
+	if err != nil {
+		return err
+	}
+	return s.wdb.SaveWebhookEndpoint(endpoint)
+}
+
+func (s *Store) handleRemoveWebhookEndpoint(cmd *CMD) error {
+	name, err := cmd.DecodeCMDRemoveWebhookEndpoint()
+	if err != nil {
+		return err
+	}
+	return s.wdb.RemoveWebhookEndpoint(name)
+}
+
+func (s *Store) handleUpdateThreadReply(cmd *CMD) error {
+	threadReply, err := cmd.DecodeCMDUpdateThreadReply()
+	if err != nil {
+		return err
+	}
+	return s.wdb.UpdateThreadReply(threadReply)
+}
+
+func (s *Store) handleSetMQTTRetain(cmd *CMD) error {
+	retain, err := cmd.DecodeCMDSetMQTTRetain()
+	if err != nil {
+		return err
+	}
+	return s.wdb.SetMQTTRetain(retain)
+}
@@ -0,0 +1,29 @@
+package clusterstore
+
+import (
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"go.uber.org/zap"
+)
+
+// AddOrUpdateChannelDisband 添加或更新频道解散记录（数据存储在频道所在的槽位上）
+func (s *Store) AddOrUpdateChannelDisband(channelDisband wkdb.ChannelDisband) error {
+	data := EncodeCMDAddOrUpdateChannelDisband(channelDisband)
+	cmd := NewCMD(CMDAddOrUpdateChannelDisband, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		s.Error("AddOrUpdateChannelDisband: marshal cmd failed", zap.Error(err))
+		return err
+	}
+	slotId := s.opts.GetSlotId(channelDisband.ChannelId)
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
+
+func (s *Store) GetChannelDisband(channelId string, channelType uint8) (wkdb.ChannelDisband, error) {
+	return s.wdb.GetChannelDisband(channelId, channelType)
+}
+
+// GetChannelDisbands 获取本节点上的频道解散记录
+func (s *Store) GetChannelDisbands() ([]wkdb.ChannelDisband, error) {
+	return s.wdb.GetChannelDisbands()
+}
@@ -0,0 +1,11 @@
+package clusterstore
+
+// 事件sink的偏移量只存储在本节点，不需要提案
+
+func (s *Store) SetEventSinkOffset(name string, offset uint64) error {
+	return s.wdb.SetEventSinkOffset(name, offset)
+}
+
+func (s *Store) GetEventSinkOffset(name string) (uint64, error) {
+	return s.wdb.GetEventSinkOffset(name)
+}
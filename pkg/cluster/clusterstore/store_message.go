@@ -145,8 +145,17 @@ func (s *Store) SyncMessageOfUser(uid string, messageSeq uint64, limit uint32) (
 	return s.wdb.LoadNextRangeMsgs(uid, wkproto.ChannelTypePerson, messageSeq, 0, int(limit))
 }
 
+// DeleteChannelAndClearMessages 删除频道（包括订阅者、黑白名单、分布式配置）并清空频道消息
 func (s *Store) DeleteChannelAndClearMessages(channelID string, channelType uint8) error {
-	return nil
+	data := EncodeChannel(channelID, channelType)
+	cmd := NewCMD(CMDDeleteChannelAndClearMessages, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		return err
+	}
+	slotId := s.opts.GetSlotId(channelID)
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
 }
 
 // 搜索消息
@@ -0,0 +1,28 @@
+package clusterstore
+
+import (
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"go.uber.org/zap"
+)
+
+// SetMQTTRetain 设置频道的mqtt保留消息（数据存储在频道所在的槽位上）
+func (s *Store) SetMQTTRetain(retain wkdb.MQTTRetain) error {
+	data, err := EncodeCMDSetMQTTRetain(retain)
+	if err != nil {
+		return err
+	}
+	cmd := NewCMD(CMDSetMQTTRetain, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		s.Error("SetMQTTRetain: marshal cmd failed", zap.Error(err))
+		return err
+	}
+	slotId := s.opts.GetSlotId(retain.ChannelId)
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
+
+// GetMQTTRetain 获取本节点存储的频道mqtt保留消息
+func (s *Store) GetMQTTRetain(channelId string, channelType uint8) (wkdb.MQTTRetain, error) {
+	return s.wdb.GetMQTTRetain(channelId, channelType)
+}
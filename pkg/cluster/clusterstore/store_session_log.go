@@ -0,0 +1,40 @@
+package clusterstore
+
+import (
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"go.uber.org/zap"
+)
+
+// AddSessionLogs 添加用户会话审计日志（数据存储在用户所在的槽位上，按槽位分组提案）
+func (s *Store) AddSessionLogs(logs []wkdb.SessionLog) error {
+	slotLogs := make(map[uint32][]wkdb.SessionLog)
+	for _, log := range logs {
+		slotId := s.opts.GetSlotId(log.Uid)
+		slotLogs[slotId] = append(slotLogs[slotId], log)
+	}
+	for slotId, logs := range slotLogs {
+		data := EncodeCMDAddSessionLogs(logs)
+		cmd := NewCMD(CMDAddSessionLogs, data)
+		cmdData, err := cmd.Marshal()
+		if err != nil {
+			s.Error("AddSessionLogs: marshal cmd failed", zap.Error(err))
+			return err
+		}
+		_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) GetSessionLogs(uid string, startTime, endTime int64, limit int) ([]wkdb.SessionLog, error) {
+	return s.wdb.GetSessionLogs(uid, startTime, endTime, limit)
+}
+
+// RemoveSessionLogsBefore 移除本节点指定时间之前的会话审计日志（过期清理，各节点各自执行）
+func (s *Store) RemoveSessionLogsBefore(t time.Time) error {
+	return s.wdb.RemoveSessionLogsBefore(t)
+}
@@ -0,0 +1,28 @@
+package clusterstore
+
+import (
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"go.uber.org/zap"
+)
+
+// UpdateThreadReply 更新子区根消息的回复统计（数据存储在父频道所在的槽位上）
+func (s *Store) UpdateThreadReply(threadReply wkdb.ThreadReply) error {
+	data, err := EncodeCMDUpdateThreadReply(threadReply)
+	if err != nil {
+		return err
+	}
+	cmd := NewCMD(CMDUpdateThreadReply, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		s.Error("UpdateThreadReply: marshal cmd failed", zap.Error(err))
+		return err
+	}
+	slotId := s.opts.GetSlotId(threadReply.ChannelId)
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
+
+// GetThreadReply 获取本节点存储的子区根消息回复统计
+func (s *Store) GetThreadReply(rootMessageId int64) (wkdb.ThreadReply, error) {
+	return s.wdb.GetThreadReply(rootMessageId)
+}
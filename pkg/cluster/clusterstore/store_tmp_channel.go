@@ -0,0 +1,42 @@
+package clusterstore
+
+import (
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"go.uber.org/zap"
+)
+
+// AddOrUpdateTmpChannel 添加或更新临时频道（数据存储在临时频道所在的槽位上）
+func (s *Store) AddOrUpdateTmpChannel(tmpChannel wkdb.TmpChannel) error {
+	data := EncodeCMDAddOrUpdateTmpChannel(tmpChannel)
+	cmd := NewCMD(CMDAddOrUpdateTmpChannel, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		s.Error("AddOrUpdateTmpChannel: marshal cmd failed", zap.Error(err))
+		return err
+	}
+	slotId := s.opts.GetSlotId(tmpChannel.ChannelId)
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
+
+func (s *Store) GetTmpChannel(channelId string) (wkdb.TmpChannel, error) {
+	return s.wdb.GetTmpChannel(channelId)
+}
+
+// GetTmpChannels 获取本节点上的临时频道
+func (s *Store) GetTmpChannels() ([]wkdb.TmpChannel, error) {
+	return s.wdb.GetTmpChannels()
+}
+
+func (s *Store) RemoveTmpChannel(channelId string) error {
+	data := EncodeCMDRemoveTmpChannel(channelId)
+	cmd := NewCMD(CMDRemoveTmpChannel, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		s.Error("RemoveTmpChannel: marshal cmd failed", zap.Error(err))
+		return err
+	}
+	slotId := s.opts.GetSlotId(channelId)
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
@@ -50,6 +50,39 @@ func (s *Store) UpdateDevice(d wkdb.Device) error {
 	return err
 }
 
+// UpdateDeviceLogin 更新设备的登录信息
+func (s *Store) UpdateDeviceLogin(d wkdb.Device) error {
+	data := EncodeCMDDeviceLogin(d)
+	cmd := NewCMD(CMDUpdateDeviceLogin, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		s.Error("marshal cmd failed", zap.Error(err))
+		return err
+	}
+	slotId := s.opts.GetSlotId(d.Uid)
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
+
+// UpdateDeviceRevoke 更新设备的吊销时间
+func (s *Store) UpdateDeviceRevoke(d wkdb.Device) error {
+	data := EncodeCMDDeviceRevoke(d)
+	cmd := NewCMD(CMDUpdateDeviceRevoke, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		s.Error("marshal cmd failed", zap.Error(err))
+		return err
+	}
+	slotId := s.opts.GetSlotId(d.Uid)
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
+
+// GetDevices 获取用户的所有设备
+func (s *Store) GetDevices(uid string) ([]wkdb.Device, error) {
+	return s.wdb.GetDevices(uid)
+}
+
 func (s *Store) AddDevice(d wkdb.Device) error {
 	data := EncodeCMDDevice(d)
 	cmd := NewCMD(CMDAddDevice, data)
@@ -0,0 +1,43 @@
+package clusterstore
+
+import (
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"go.uber.org/zap"
+)
+
+// AddOrUpdateUserBan 添加或更新用户封禁（数据存储在用户所在的槽位上）
+func (s *Store) AddOrUpdateUserBan(userBan wkdb.UserBan) error {
+	data := EncodeCMDAddOrUpdateUserBan(userBan)
+	cmd := NewCMD(CMDAddOrUpdateUserBan, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		s.Error("AddOrUpdateUserBan: marshal cmd failed", zap.Error(err))
+		return err
+	}
+	slotId := s.opts.GetSlotId(userBan.Uid)
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
+
+// RemoveUserBan 移除用户封禁
+func (s *Store) RemoveUserBan(uid string) error {
+	data := EncodeCMDRemoveUserBan(uid)
+	cmd := NewCMD(CMDRemoveUserBan, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		s.Error("RemoveUserBan: marshal cmd failed", zap.Error(err))
+		return err
+	}
+	slotId := s.opts.GetSlotId(uid)
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
+
+func (s *Store) GetUserBan(uid string) (wkdb.UserBan, error) {
+	return s.wdb.GetUserBan(uid)
+}
+
+// GetUserBans 获取本节点的用户封禁
+func (s *Store) GetUserBans() ([]wkdb.UserBan, error) {
+	return s.wdb.GetUserBans()
+}
@@ -0,0 +1,40 @@
+package clusterstore
+
+import (
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"go.uber.org/zap"
+)
+
+// UpdateUserLastSeen 更新用户最后在线时间（数据存储在用户所在的槽位上）
+func (s *Store) UpdateUserLastSeen(uid string, lastSeen time.Time) error {
+	data := EncodeCMDUpdateUserLastSeen(uid, lastSeen)
+	cmd := NewCMD(CMDUpdateUserLastSeen, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		s.Error("UpdateUserLastSeen: marshal cmd failed", zap.Error(err))
+		return err
+	}
+	slotId := s.opts.GetSlotId(uid)
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
+
+// UpdateUserStatus 更新用户设置的状态
+func (s *Store) UpdateUserStatus(uid string, status wkdb.UserStatus, statusText string) error {
+	data := EncodeCMDUpdateUserStatus(uid, status, statusText, time.Now())
+	cmd := NewCMD(CMDUpdateUserStatus, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		s.Error("UpdateUserStatus: marshal cmd failed", zap.Error(err))
+		return err
+	}
+	slotId := s.opts.GetSlotId(uid)
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
+
+func (s *Store) GetUserPresence(uid string) (wkdb.UserPresence, error) {
+	return s.wdb.GetUserPresence(uid)
+}
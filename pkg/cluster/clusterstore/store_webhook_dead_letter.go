@@ -0,0 +1,29 @@
+package clusterstore
+
+import "github.com/WuKongIM/WuKongIM/pkg/wkdb"
+
+// webhook死信只存储在本节点，不需要提案
+
+func (s *Store) AddWebhookDeadLetters(letters []wkdb.WebhookDeadLetter) error {
+	return s.wdb.AddWebhookDeadLetters(letters)
+}
+
+func (s *Store) GetWebhookDeadLetters(event string, offsetId uint64, limit int) ([]wkdb.WebhookDeadLetter, error) {
+	return s.wdb.GetWebhookDeadLetters(event, offsetId, limit)
+}
+
+func (s *Store) GetWebhookDeadLetter(id uint64) (wkdb.WebhookDeadLetter, error) {
+	return s.wdb.GetWebhookDeadLetter(id)
+}
+
+func (s *Store) RemoveWebhookDeadLetters(ids []uint64) error {
+	return s.wdb.RemoveWebhookDeadLetters(ids)
+}
+
+func (s *Store) RemoveWebhookDeadLettersBefore(id uint64) error {
+	return s.wdb.RemoveWebhookDeadLettersBefore(id)
+}
+
+func (s *Store) GetWebhookDeadLetterCount() (int, error) {
+	return s.wdb.GetWebhookDeadLetterCount()
+}
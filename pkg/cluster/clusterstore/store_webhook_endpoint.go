@@ -0,0 +1,40 @@
+package clusterstore
+
+import (
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"go.uber.org/zap"
+)
+
+// SaveWebhookEndpoint 添加或更新webhook端点（数据存储在槽位0上）
+func (s *Store) SaveWebhookEndpoint(endpoint wkdb.WebhookEndpoint) error {
+	data, err := EncodeCMDSaveWebhookEndpoint(endpoint)
+	if err != nil {
+		return err
+	}
+	cmd := NewCMD(CMDSaveWebhookEndpoint, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		s.Error("SaveWebhookEndpoint: marshal cmd failed", zap.Error(err))
+		return err
+	}
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, 0, cmdData)
+	return err
+}
+
+// RemoveWebhookEndpoint 移除webhook端点
+func (s *Store) RemoveWebhookEndpoint(name string) error {
+	data := EncodeCMDRemoveWebhookEndpoint(name)
+	cmd := NewCMD(CMDRemoveWebhookEndpoint, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		s.Error("RemoveWebhookEndpoint: marshal cmd failed", zap.Error(err))
+		return err
+	}
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, 0, cmdData)
+	return err
+}
+
+// GetWebhookEndpoints 获取本节点存储的webhook端点（槽位0的副本节点才有数据）
+func (s *Store) GetWebhookEndpoints() ([]wkdb.WebhookEndpoint, error) {
+	return s.wdb.GetWebhookEndpoints()
+}
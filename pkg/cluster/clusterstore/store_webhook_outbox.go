@@ -0,0 +1,31 @@
+package clusterstore
+
+import "github.com/WuKongIM/WuKongIM/pkg/wkdb"
+
+// webhook事件发件箱只存储在本节点，不需要提案
+
+func (s *Store) AppendWebhookEvents(events []wkdb.WebhookEvent) error {
+	return s.wdb.AppendWebhookEvents(events)
+}
+
+func (s *Store) GetWebhookEvents(startId uint64, limit int) ([]wkdb.WebhookEvent, error) {
+	return s.wdb.GetWebhookEvents(startId, limit)
+}
+
+func (s *Store) RemoveWebhookEvents(ids []uint64) error {
+	return s.wdb.RemoveWebhookEvents(ids)
+}
+
+func (s *Store) GetWebhookOutboxMaxId() (uint64, error) {
+	return s.wdb.GetWebhookOutboxMaxId()
+}
+
+// 频道webhook的推送统计只存储在本节点，不需要提案
+
+func (s *Store) SetChannelWebhookStats(stats []wkdb.ChannelWebhookStats) error {
+	return s.wdb.SetChannelWebhookStats(stats)
+}
+
+func (s *Store) GetChannelWebhookStats() ([]wkdb.ChannelWebhookStats, error) {
+	return s.wdb.GetChannelWebhookStats()
+}
@@ -4,7 +4,7 @@ type CmdVersion uint16
 
 const (
 	// CmdVersionChannelInfo is the version of the command that contains channel info
-	CmdVersionChannelInfo CmdVersion = 2
+	CmdVersionChannelInfo CmdVersion = 3
 )
 
 func (c CmdVersion) Uint16() uint16 {
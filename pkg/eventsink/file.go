@@ -0,0 +1,52 @@
+package eventsink
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// FileSink 将事件按行（jsonl）追加到本地文件，文件超过大小后滚动
+// 滚动后的文件名为 {name}-{时间}.jsonl，消费方可以按文件名顺序读取。
+type FileSink struct {
+	mu     sync.Mutex
+	writer *lumberjack.Logger
+	buff   bytes.Buffer
+}
+
+func NewFileSink(cfg Config) *FileSink {
+	return &FileSink{
+		writer: &lumberjack.Logger{
+			Filename:   cfg.Path,
+			MaxSize:    cfg.MaxSize,
+			MaxBackups: cfg.MaxBackups,
+			MaxAge:     cfg.MaxAge,
+			Compress:   cfg.Compress,
+			LocalTime:  true,
+		},
+	}
+}
+
+// Write 一批事件一次写入（写入操作系统缓存，不保证落盘）
+func (f *FileSink) Write(events []Event) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.buff.Reset()
+	encoder := json.NewEncoder(&f.buff)
+	for _, event := range events {
+		if err := encoder.Encode(event); err != nil { // Encode会在末尾追加换行
+			return err
+		}
+	}
+	_, err := f.writer.Write(f.buff.Bytes())
+	return err
+}
+
+func (f *FileSink) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.writer.Close()
+}
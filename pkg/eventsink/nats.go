@@ -0,0 +1,138 @@
+package eventsink
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NatsSink 将事件发布到nats，subject为 {topic}.{event}（例如 wukongim.events.msg.notify），消息内容为事件的json
+// 一批事件发布后发送PING，收到PONG表示服务端已处理完这批事件。
+type NatsSink struct {
+	cfg Config
+
+	mu   sync.Mutex
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+func NewNatsSink(cfg Config) *NatsSink {
+	return &NatsSink{cfg: cfg}
+}
+
+func (n *NatsSink) Write(events []Event) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	err := n.write(events)
+	if err != nil {
+		n.closeConn()
+	}
+	return err
+}
+
+func (n *NatsSink) write(events []Event) error {
+	if err := n.connectIfNeed(); err != nil {
+		return err
+	}
+	w := bufio.NewWriter(n.conn)
+	for _, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(w, "PUB %s.%s %d\r\n", n.cfg.Topic, event.Event, len(payload))
+		_, _ = w.Write(payload)
+		_, _ = w.WriteString("\r\n")
+	}
+	_, _ = w.WriteString("PING\r\n")
+	_ = n.conn.SetDeadline(time.Now().Add(n.cfg.Timeout))
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return n.waitPong()
+}
+
+// 等待PONG（期间服务端的PING需要回复PONG，-ERR表示发布失败）
+func (n *NatsSink) waitPong() error {
+	for {
+		line, err := readLine(n.rd)
+		if err != nil {
+			return err
+		}
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err = n.conn.Write([]byte("PONG\r\n")); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("nats: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+		// +OK、INFO等忽略
+	}
+}
+
+func (n *NatsSink) connectIfNeed() error {
+	if n.conn != nil {
+		return nil
+	}
+	conn, err := net.DialTimeout("tcp", n.cfg.Addr, n.cfg.Timeout)
+	if err != nil {
+		return err
+	}
+	n.conn = conn
+	n.rd = bufio.NewReader(conn)
+	_ = conn.SetDeadline(time.Now().Add(n.cfg.Timeout))
+
+	// 连接后服务端先发送INFO
+	line, err := readLine(n.rd)
+	if err != nil {
+		n.closeConn()
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO") {
+		n.closeConn()
+		return fmt.Errorf("nats: unexpected info %q", line)
+	}
+	connectOpts := map[string]interface{}{
+		"verbose":  false,
+		"pedantic": false,
+		"name":     "wukongim-eventsink",
+		"lang":     "go",
+	}
+	if n.cfg.Username != "" {
+		connectOpts["user"] = n.cfg.Username
+		connectOpts["pass"] = n.cfg.Password
+	}
+	connectData, _ := json.Marshal(connectOpts)
+	if _, err = fmt.Fprintf(conn, "CONNECT %s\r\nPING\r\n", connectData); err != nil {
+		n.closeConn()
+		return err
+	}
+	if err = n.waitPong(); err != nil { // 认证失败时服务端返回-ERR
+		n.closeConn()
+		return err
+	}
+	return nil
+}
+
+func (n *NatsSink) closeConn() {
+	if n.conn != nil {
+		_ = n.conn.Close()
+		n.conn = nil
+		n.rd = nil
+	}
+}
+
+func (n *NatsSink) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.closeConn()
+	return nil
+}
@@ -0,0 +1,173 @@
+package eventsink
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RedisSink 将事件通过XADD写入redis stream（一批事件使用pipeline写入）
+// stream的字段为 node_id、offset、delivery_id、event、data、timestamp，消费方可以使用XREAD或消费组读取。
+type RedisSink struct {
+	cfg Config
+
+	mu   sync.Mutex
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+func NewRedisSink(cfg Config) *RedisSink {
+	return &RedisSink{cfg: cfg}
+}
+
+func (r *RedisSink) Write(events []Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.write(events)
+	if err != nil {
+		// 出错后连接的状态不确定，下次重新连接
+		r.closeConn()
+	}
+	return err
+}
+
+func (r *RedisSink) write(events []Event) error {
+	if err := r.connectIfNeed(); err != nil {
+		return err
+	}
+	w := bufio.NewWriter(r.conn)
+	for _, event := range events {
+		args := []string{"XADD", r.cfg.Topic}
+		if r.cfg.MaxLen > 0 {
+			args = append(args, "MAXLEN", "~", strconv.FormatInt(r.cfg.MaxLen, 10))
+		}
+		args = append(args, "*",
+			"node_id", strconv.FormatUint(event.NodeId, 10),
+			"offset", strconv.FormatUint(event.Offset, 10),
+			"delivery_id", event.DeliveryId,
+			"event", event.Event,
+			"data", string(event.Data),
+			"timestamp", strconv.FormatInt(event.Timestamp, 10),
+		)
+		writeRedisCommand(w, args)
+	}
+	_ = r.conn.SetDeadline(time.Now().Add(r.cfg.Timeout))
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	// 读取每个命令的回复，任意一个失败整批重试
+	var firstErr error
+	for range events {
+		if err := readRedisReply(r.rd); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (r *RedisSink) connectIfNeed() error {
+	if r.conn != nil {
+		return nil
+	}
+	conn, err := net.DialTimeout("tcp", r.cfg.Addr, r.cfg.Timeout)
+	if err != nil {
+		return err
+	}
+	r.conn = conn
+	r.rd = bufio.NewReader(conn)
+	if r.cfg.Password != "" {
+		args := []string{"AUTH", r.cfg.Password}
+		if r.cfg.Username != "" {
+			args = []string{"AUTH", r.cfg.Username, r.cfg.Password}
+		}
+		w := bufio.NewWriter(conn)
+		writeRedisCommand(w, args)
+		_ = conn.SetDeadline(time.Now().Add(r.cfg.Timeout))
+		if err = w.Flush(); err == nil {
+			err = readRedisReply(r.rd)
+		}
+		if err != nil {
+			r.closeConn()
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *RedisSink) closeConn() {
+	if r.conn != nil {
+		_ = r.conn.Close()
+		r.conn = nil
+		r.rd = nil
+	}
+}
+
+func (r *RedisSink) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closeConn()
+	return nil
+}
+
+// 按RESP协议写入命令（数组 + 多行字符串）
+func writeRedisCommand(w *bufio.Writer, args []string) {
+	_, _ = fmt.Fprintf(w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		_, _ = fmt.Fprintf(w, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+}
+
+// 读取一个RESP回复，错误回复转为error
+func readRedisReply(rd *bufio.Reader) error {
+	line, err := readLine(rd)
+	if err != nil {
+		return err
+	}
+	if len(line) == 0 {
+		return fmt.Errorf("redis: empty reply")
+	}
+	switch line[0] {
+	case '+', ':':
+		return nil
+	case '-':
+		return fmt.Errorf("redis: %s", line[1:])
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return err
+		}
+		if n < 0 {
+			return nil
+		}
+		_, err = rd.Discard(n + 2)
+		return err
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return err
+		}
+		for i := 0; i < n; i++ {
+			if err := readRedisReply(rd); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("redis: unexpected reply %q", line)
+}
+
+// 读取一行（去掉末尾的\r\n）
+func readLine(rd *bufio.Reader) (string, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if len(line) >= 2 && line[len(line)-2] == '\r' {
+		return line[:len(line)-2], nil
+	}
+	return line[:len(line)-1], nil
+}
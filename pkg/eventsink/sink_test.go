@@ -0,0 +1,281 @@
+package eventsink
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testEvents() []Event {
+	return []Event{
+		{NodeId: 1, Offset: 1, DeliveryId: "d1", Event: "msg.notify", Data: json.RawMessage(`[{"message_id":1}]`), Timestamp: 1},
+		{NodeId: 1, Offset: 2, DeliveryId: "d2", Event: "user.onlinestatus", Data: json.RawMessage(`["u1-1-1-1-1-1"]`), Timestamp: 2},
+	}
+}
+
+func TestParseURL(t *testing.T) {
+	cfg, err := ParseURL("file:events/a.jsonl?name=analytics&events=msg.notify,user.onlinestatus&maxSize=10&maxBackups=3&compress=true")
+	assert.NoError(t, err)
+	assert.Equal(t, "analytics", cfg.Name)
+	assert.Equal(t, TypeFile, cfg.Type)
+	assert.Equal(t, "events/a.jsonl", cfg.Path)
+	assert.Equal(t, []string{"msg.notify", "user.onlinestatus"}, cfg.Events)
+	assert.Equal(t, 10, cfg.MaxSize)
+	assert.Equal(t, 3, cfg.MaxBackups)
+	assert.True(t, cfg.Compress)
+
+	cfg, err = ParseURL("file:///var/events/a.jsonl")
+	assert.NoError(t, err)
+	assert.Equal(t, "/var/events/a.jsonl", cfg.Path)
+	assert.Equal(t, TypeFile, cfg.Name)
+
+	cfg, err = ParseURL("redis://:pwd@127.0.0.1:6379/wk:events?maxLen=1000&timeout=1s")
+	assert.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:6379", cfg.Addr)
+	assert.Equal(t, "pwd", cfg.Password)
+	assert.Equal(t, "wk:events", cfg.Topic)
+	assert.Equal(t, int64(1000), cfg.MaxLen)
+	assert.Equal(t, time.Second, cfg.Timeout)
+
+	_, err = ParseURL("kafka://127.0.0.1:9092")
+	assert.Error(t, err)
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	sink := NewFileSink(Config{Path: path, MaxSize: 1})
+	err := sink.Write(testEvents())
+	assert.NoError(t, err)
+	err = sink.Close()
+	assert.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Len(t, lines, 2)
+	var event Event
+	err = json.Unmarshal([]byte(lines[1]), &event)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), event.Offset)
+	assert.Equal(t, "user.onlinestatus", event.Event)
+	assert.JSONEq(t, `["u1-1-1-1-1-1"]`, string(event.Data))
+}
+
+// 本地的redis替身，只支持AUTH和XADD
+type fakeRedis struct {
+	ln       net.Listener
+	password string
+
+	mu      sync.Mutex
+	entries [][]string // XADD的参数
+	failNum int        // 前failNum个XADD返回错误
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	r := &fakeRedis{ln: ln, password: password}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go r.serve(conn)
+		}
+	}()
+	return r
+}
+
+func (r *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	rd := bufio.NewReader(conn)
+	authed := r.password == ""
+	for {
+		args, err := readFakeRedisCommand(rd)
+		if err != nil {
+			return
+		}
+		switch strings.ToUpper(args[0]) {
+		case "AUTH":
+			authed = args[len(args)-1] == r.password
+			if !authed {
+				_, _ = conn.Write([]byte("-WRONGPASS invalid password\r\n"))
+				continue
+			}
+			_, _ = conn.Write([]byte("+OK\r\n"))
+		case "XADD":
+			if !authed {
+				_, _ = conn.Write([]byte("-NOAUTH Authentication required.\r\n"))
+				continue
+			}
+			r.mu.Lock()
+			if r.failNum > 0 {
+				r.failNum--
+				r.mu.Unlock()
+				_, _ = conn.Write([]byte("-ERR fake error\r\n"))
+				continue
+			}
+			r.entries = append(r.entries, args[1:])
+			id := fmt.Sprintf("%d-0", len(r.entries))
+			r.mu.Unlock()
+			_, _ = fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(id), id)
+		default:
+			_, _ = conn.Write([]byte("-ERR unknown command\r\n"))
+		}
+	}
+}
+
+func readFakeRedisCommand(rd *bufio.Reader) ([]string, error) {
+	line, err := readLine(rd)
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(line, "*"))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		line, err = readLine(rd)
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimPrefix(line, "$"))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err = io.ReadFull(rd, buf); err != nil {
+			return nil, err
+		}
+		args = append(args, string(buf[:size]))
+	}
+	return args, nil
+}
+
+func TestRedisSink(t *testing.T) {
+	r := newFakeRedis(t, "pwd")
+	defer r.ln.Close()
+
+	sink := NewRedisSink(Config{Addr: r.ln.Addr().String(), Password: "pwd", Topic: "wk:events", MaxLen: 100, Timeout: time.Second})
+	defer sink.Close()
+
+	// 第一次写入失败，重试后成功
+	r.mu.Lock()
+	r.failNum = 1
+	r.mu.Unlock()
+	err := sink.Write(testEvents())
+	assert.Error(t, err)
+	err = sink.Write(testEvents())
+	assert.NoError(t, err)
+
+	// 密码错误
+	badSink := NewRedisSink(Config{Addr: r.ln.Addr().String(), Password: "bad", Topic: "wk:events", Timeout: time.Second})
+	defer badSink.Close()
+	err = badSink.Write(testEvents())
+	assert.Error(t, err)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	assert.Len(t, r.entries, 3)
+	last := r.entries[2]
+	assert.Equal(t, []string{"wk:events", "MAXLEN", "~", "100", "*"}, last[:5])
+	fields := map[string]string{}
+	for i := 5; i+1 < len(last); i += 2 {
+		fields[last[i]] = last[i+1]
+	}
+	assert.Equal(t, "2", fields["offset"])
+	assert.Equal(t, "user.onlinestatus", fields["event"])
+	assert.Equal(t, `["u1-1-1-1-1-1"]`, fields["data"])
+}
+
+// 本地的nats替身，只支持CONNECT、PUB和PING
+type fakeNats struct {
+	ln net.Listener
+
+	mu       sync.Mutex
+	subjects []string
+	payloads []string
+}
+
+func newFakeNats(t *testing.T) *fakeNats {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	n := &fakeNats{ln: ln}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go n.serve(conn)
+		}
+	}()
+	return n
+}
+
+func (n *fakeNats) serve(conn net.Conn) {
+	defer conn.Close()
+	_, _ = conn.Write([]byte(`INFO {"server_id":"fake","max_payload":1048576}` + "\r\n"))
+	rd := bufio.NewReader(conn)
+	for {
+		line, err := readLine(rd)
+		if err != nil {
+			return
+		}
+		parts := strings.Fields(line)
+		if len(parts) == 0 {
+			continue
+		}
+		switch parts[0] {
+		case "CONNECT":
+		case "PING":
+			_, _ = conn.Write([]byte("PONG\r\n"))
+		case "PUB":
+			size, _ := strconv.Atoi(parts[len(parts)-1])
+			buf := make([]byte, size+2)
+			if _, err = io.ReadFull(rd, buf); err != nil {
+				return
+			}
+			n.mu.Lock()
+			n.subjects = append(n.subjects, parts[1])
+			n.payloads = append(n.payloads, string(buf[:size]))
+			n.mu.Unlock()
+		default:
+			_, _ = conn.Write([]byte("-ERR 'Unknown Protocol Operation'\r\n"))
+		}
+	}
+}
+
+func TestNatsSink(t *testing.T) {
+	n := newFakeNats(t)
+	defer n.ln.Close()
+
+	sink := NewNatsSink(Config{Addr: n.ln.Addr().String(), Topic: "wukongim.events", Timeout: time.Second})
+	defer sink.Close()
+
+	err := sink.Write(testEvents())
+	assert.NoError(t, err)
+
+	// 收到PONG时服务端已处理完这批事件
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	assert.Equal(t, []string{"wukongim.events.msg.notify", "wukongim.events.user.onlinestatus"}, n.subjects)
+	var event Event
+	err = json.Unmarshal([]byte(n.payloads[0]), &event)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), event.Offset)
+	assert.Equal(t, "d1", event.DeliveryId)
+}
@@ -0,0 +1,277 @@
+package mqtt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"unicode/utf8"
+)
+
+var (
+	ErrMalformedPacket    = errors.New("mqtt: malformed packet")
+	ErrMalformedLength    = errors.New("mqtt: malformed remaining length")
+	ErrUnknownPacketType  = errors.New("mqtt: unknown packet type")
+	ErrInvalidFlags       = errors.New("mqtt: invalid fixed header flags")
+	ErrInvalidUTF8        = errors.New("mqtt: invalid utf-8 string")
+	ErrInvalidQoS         = errors.New("mqtt: invalid qos")
+	ErrInvalidProperty    = errors.New("mqtt: invalid property")
+	ErrUnsupportedVersion = errors.New("mqtt: unsupported protocol version")
+)
+
+// FixedHeader 固定头
+type FixedHeader struct {
+	Type            PacketType
+	Dup             bool // 仅 PUBLISH
+	QoS             byte // 仅 PUBLISH
+	Retain          bool // 仅 PUBLISH
+	RemainingLength int
+}
+
+func (f FixedHeader) flags() byte {
+	switch f.Type {
+	case PUBLISH:
+		var b byte
+		if f.Dup {
+			b |= 0x08
+		}
+		b |= (f.QoS & 0x03) << 1
+		if f.Retain {
+			b |= 0x01
+		}
+		return b
+	case PUBREL, SUBSCRIBE, UNSUBSCRIBE:
+		return 0x02
+	}
+	return 0
+}
+
+func (f FixedHeader) encode(w io.Writer) error {
+	buf := make([]byte, 0, 5)
+	buf = append(buf, byte(f.Type)<<4|f.flags())
+	buf = appendVarint(buf, f.RemainingLength)
+	_, err := w.Write(buf)
+	return err
+}
+
+func parseFixedHeader(b byte) (FixedHeader, error) {
+	fh := FixedHeader{Type: PacketType(b >> 4)}
+	flags := b & 0x0F
+	switch fh.Type {
+	case PUBLISH:
+		fh.Dup = flags&0x08 != 0
+		fh.QoS = (flags >> 1) & 0x03
+		fh.Retain = flags&0x01 != 0
+		if fh.QoS > 2 {
+			return fh, ErrInvalidQoS
+		}
+	case PUBREL, SUBSCRIBE, UNSUBSCRIBE:
+		if flags != 0x02 {
+			return fh, ErrInvalidFlags
+		}
+	case 0:
+		return fh, ErrUnknownPacketType
+	default:
+		if flags != 0 {
+			return fh, ErrInvalidFlags
+		}
+	}
+	return fh, nil
+}
+
+func appendVarint(buf []byte, v int) []byte {
+	for {
+		b := byte(v % 128)
+		v /= 128
+		if v > 0 {
+			b |= 0x80
+		}
+		buf = append(buf, b)
+		if v == 0 {
+			return buf
+		}
+	}
+}
+
+// decodeVarint 解码变长整数，数据不完整时返回 n=0
+func decodeVarint(data []byte) (value int, n int, err error) {
+	multiplier := 1
+	for i := 0; i < 4; i++ {
+		if i >= len(data) {
+			return 0, 0, nil
+		}
+		b := data[i]
+		value += int(b&0x7F) * multiplier
+		if b&0x80 == 0 {
+			return value, i + 1, nil
+		}
+		multiplier *= 128
+	}
+	return 0, 0, ErrMalformedLength
+}
+
+func readVarint(r io.Reader) (int, error) {
+	var (
+		value      int
+		multiplier = 1
+		b          [1]byte
+	)
+	for i := 0; i < 4; i++ {
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return 0, err
+		}
+		value += int(b[0]&0x7F) * multiplier
+		if b[0]&0x80 == 0 {
+			return value, nil
+		}
+		multiplier *= 128
+	}
+	return 0, ErrMalformedLength
+}
+
+// encoder 可变头和载荷的编码器
+type encoder struct {
+	bytes.Buffer
+}
+
+func (e *encoder) writeByte(b byte) {
+	_ = e.WriteByte(b)
+}
+
+func (e *encoder) writeUint16(v uint16) {
+	_ = e.WriteByte(byte(v >> 8))
+	_ = e.WriteByte(byte(v))
+}
+
+func (e *encoder) writeUint32(v uint32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	_, _ = e.Write(b[:])
+}
+
+func (e *encoder) writeVarint(v int) {
+	_, _ = e.Write(appendVarint(nil, v))
+}
+
+func (e *encoder) writeString(s string) {
+	e.writeUint16(uint16(len(s)))
+	_, _ = e.WriteString(s)
+}
+
+func (e *encoder) writeBinary(b []byte) {
+	e.writeUint16(uint16(len(b)))
+	_, _ = e.Write(b)
+}
+
+// flush 写出固定头和已编码的数据
+func (e *encoder) flush(w io.Writer, fh FixedHeader) error {
+	fh.RemainingLength = e.Len()
+	if fh.RemainingLength > MaxRemainingLength {
+		return ErrMalformedLength
+	}
+	if err := fh.encode(w); err != nil {
+		return err
+	}
+	_, err := w.Write(e.Bytes())
+	return err
+}
+
+// decoder 可变头和载荷的解码器
+type decoder struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func newDecoder(r io.Reader, remainingLength int) (*decoder, error) {
+	data := make([]byte, remainingLength)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return &decoder{data: data}, nil
+}
+
+func (d *decoder) remaining() int {
+	return len(d.data) - d.pos
+}
+
+func (d *decoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || d.remaining() < n {
+		d.err = ErrMalformedPacket
+		return nil
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b
+}
+
+func (d *decoder) readByte() byte {
+	b := d.next(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (d *decoder) readUint16() uint16 {
+	b := d.next(2)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint16(b)
+}
+
+func (d *decoder) readUint32() uint32 {
+	b := d.next(4)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+func (d *decoder) readVarint() int {
+	if d.err != nil {
+		return 0
+	}
+	v, n, err := decodeVarint(d.data[d.pos:])
+	if err != nil {
+		d.err = err
+		return 0
+	}
+	if n == 0 {
+		d.err = ErrMalformedPacket
+		return 0
+	}
+	d.pos += n
+	return v
+}
+
+func (d *decoder) readBinary() []byte {
+	n := int(d.readUint16())
+	b := d.next(n)
+	if b == nil {
+		return nil
+	}
+	return append([]byte(nil), b...)
+}
+
+func (d *decoder) readString() string {
+	n := int(d.readUint16())
+	b := d.next(n)
+	if b == nil {
+		return ""
+	}
+	if !utf8.Valid(b) {
+		d.err = ErrInvalidUTF8
+		return ""
+	}
+	return string(b)
+}
+
+// rest 读取剩余的所有数据
+func (d *decoder) rest() []byte {
+	return append([]byte(nil), d.next(d.remaining())...)
+}
@@ -1,4 +1,169 @@
 package mqtt
 
+import "io"
+
+// ConnectPacket 连接请求
 type ConnectPacket struct {
+	ProtocolName    string
+	ProtocolVersion byte
+	CleanStart      bool // 3.1.1 中为 CleanSession
+	KeepAlive       uint16
+	Properties      *Properties // 5.0
+	ClientID        string
+
+	WillFlag       bool
+	WillQoS        byte
+	WillRetain     bool
+	WillProperties *Properties // 5.0
+	WillTopic      string
+	WillPayload    []byte
+
+	UsernameFlag bool
+	Username     string
+	PasswordFlag bool
+	Password     []byte
+}
+
+func (p *ConnectPacket) Type() PacketType { return CONNECT }
+
+// Encode CONNECT 的协议版本以 ProtocolVersion 为准
+func (p *ConnectPacket) Encode(w io.Writer, version byte) error {
+	if p.ProtocolVersion == 0 {
+		p.ProtocolVersion = version
+	}
+	name := p.ProtocolName
+	if name == "" {
+		name = ProtocolName
+	}
+	e := &encoder{}
+	e.writeString(name)
+	e.writeByte(p.ProtocolVersion)
+	var flags byte
+	if p.CleanStart {
+		flags |= 0x02
+	}
+	if p.WillFlag {
+		flags |= 0x04
+		flags |= (p.WillQoS & 0x03) << 3
+		if p.WillRetain {
+			flags |= 0x20
+		}
+	}
+	if p.PasswordFlag {
+		flags |= 0x40
+	}
+	if p.UsernameFlag {
+		flags |= 0x80
+	}
+	e.writeByte(flags)
+	e.writeUint16(p.KeepAlive)
+	if p.ProtocolVersion == Version5 {
+		p.Properties.encode(e)
+	}
+	e.writeString(p.ClientID)
+	if p.WillFlag {
+		if p.ProtocolVersion == Version5 {
+			p.WillProperties.encode(e)
+		}
+		e.writeString(p.WillTopic)
+		e.writeBinary(p.WillPayload)
+	}
+	if p.UsernameFlag {
+		e.writeString(p.Username)
+	}
+	if p.PasswordFlag {
+		e.writeBinary(p.Password)
+	}
+	return e.flush(w, FixedHeader{Type: CONNECT})
+}
+
+// Decode CONNECT 的协议版本从报文中读取，version 参数被忽略
+func (p *ConnectPacket) Decode(r io.Reader, fh FixedHeader, _ byte) error {
+	d, err := newDecoder(r, fh.RemainingLength)
+	if err != nil {
+		return err
+	}
+	p.ProtocolName = d.readString()
+	p.ProtocolVersion = d.readByte()
+	if d.err != nil {
+		return d.err
+	}
+	if p.ProtocolName != ProtocolName || (p.ProtocolVersion != Version311 && p.ProtocolVersion != Version5) {
+		return ErrUnsupportedVersion
+	}
+	flags := d.readByte()
+	if flags&0x01 != 0 {
+		return ErrMalformedPacket
+	}
+	p.CleanStart = flags&0x02 != 0
+	p.WillFlag = flags&0x04 != 0
+	p.WillQoS = (flags >> 3) & 0x03
+	p.WillRetain = flags&0x20 != 0
+	p.PasswordFlag = flags&0x40 != 0
+	p.UsernameFlag = flags&0x80 != 0
+	if p.WillQoS > 2 || (!p.WillFlag && (p.WillQoS != 0 || p.WillRetain)) {
+		return ErrMalformedPacket
+	}
+	p.KeepAlive = d.readUint16()
+	if p.ProtocolVersion == Version5 {
+		p.Properties = &Properties{}
+		p.Properties.decode(d)
+	}
+	p.ClientID = d.readString()
+	if p.WillFlag {
+		if p.ProtocolVersion == Version5 {
+			p.WillProperties = &Properties{}
+			p.WillProperties.decode(d)
+		}
+		p.WillTopic = d.readString()
+		p.WillPayload = d.readBinary()
+	}
+	if p.UsernameFlag {
+		p.Username = d.readString()
+	}
+	if p.PasswordFlag {
+		p.Password = d.readBinary()
+	}
+	if d.err == nil && d.remaining() != 0 {
+		return ErrMalformedPacket
+	}
+	return d.err
+}
+
+// ConnackPacket 连接确认
+type ConnackPacket struct {
+	SessionPresent bool
+	// ReasonCode 5.0 为原因码，3.1.1 为返回码（见 ConnackCodeV3）
+	ReasonCode byte
+	Properties *Properties // 5.0
+}
+
+func (p *ConnackPacket) Type() PacketType { return CONNACK }
+
+func (p *ConnackPacket) Encode(w io.Writer, version byte) error {
+	e := &encoder{}
+	if p.SessionPresent {
+		e.writeByte(0x01)
+	} else {
+		e.writeByte(0x00)
+	}
+	e.writeByte(p.ReasonCode)
+	if version == Version5 {
+		p.Properties.encode(e)
+	}
+	return e.flush(w, FixedHeader{Type: CONNACK})
+}
+
+func (p *ConnackPacket) Decode(r io.Reader, fh FixedHeader, version byte) error {
+	d, err := newDecoder(r, fh.RemainingLength)
+	if err != nil {
+		return err
+	}
+	p.SessionPresent = d.readByte()&0x01 != 0
+	p.ReasonCode = d.readByte()
+	if version == Version5 && d.remaining() > 0 {
+		p.Properties = &Properties{}
+		p.Properties.decode(d)
+	}
+	return d.err
 }
@@ -1,17 +1,99 @@
 package mqtt
 
+// 协议版本
+const (
+	Version311 byte = 0x04 // MQTT 3.1.1
+	Version5   byte = 0x05 // MQTT 5.0
+)
+
+// 协议名
+const ProtocolName = "MQTT"
+
+// 剩余长度的最大值
+const MaxRemainingLength = 268435455
+
+// PacketType 控制报文类型
+type PacketType byte
+
+const (
+	CONNECT     PacketType = 1
+	CONNACK     PacketType = 2
+	PUBLISH     PacketType = 3
+	PUBACK      PacketType = 4
+	PUBREC      PacketType = 5
+	PUBREL      PacketType = 6
+	PUBCOMP     PacketType = 7
+	SUBSCRIBE   PacketType = 8
+	SUBACK      PacketType = 9
+	UNSUBSCRIBE PacketType = 10
+	UNSUBACK    PacketType = 11
+	PINGREQ     PacketType = 12
+	PINGRESP    PacketType = 13
+	DISCONNECT  PacketType = 14
+	AUTH        PacketType = 15
+)
+
+func (p PacketType) String() string {
+	switch p {
+	case CONNECT:
+		return "CONNECT"
+	case CONNACK:
+		return "CONNACK"
+	case PUBLISH:
+		return "PUBLISH"
+	case PUBACK:
+		return "PUBACK"
+	case PUBREC:
+		return "PUBREC"
+	case PUBREL:
+		return "PUBREL"
+	case PUBCOMP:
+		return "PUBCOMP"
+	case SUBSCRIBE:
+		return "SUBSCRIBE"
+	case SUBACK:
+		return "SUBACK"
+	case UNSUBSCRIBE:
+		return "UNSUBSCRIBE"
+	case UNSUBACK:
+		return "UNSUBACK"
+	case PINGREQ:
+		return "PINGREQ"
+	case PINGRESP:
+		return "PINGRESP"
+	case DISCONNECT:
+		return "DISCONNECT"
+	case AUTH:
+		return "AUTH"
+	}
+	return "UNKNOWN"
+}
+
 type ReasonCode byte
 
 const (
 	Success                           ReasonCode = 0x00 // CONNACK, PUBACK, PUBREC, PUBREL, PUBCOMP, UNSUBACK, AUTH
+	NormalDisconnection               ReasonCode = 0x00 // DISCONNECT
+	GrantedQoS0                       ReasonCode = 0x00 // SUBACK
+	GrantedQoS1                       ReasonCode = 0x01 // SUBACK
+	GrantedQoS2                       ReasonCode = 0x02 // SUBACK
 	NoMatchingSubscribers             ReasonCode = 0x10 // PUBACK, PUBREC
+	NoSubscriptionExisted             ReasonCode = 0x11 // UNSUBACK
 	UnspecifiedError                  ReasonCode = 0x80 // CONNACK, PUBACK, PUBREC, SUBACK, UNSUBACK, DISCONNECT
 	MalformedPacket                   ReasonCode = 0x81 // CONNACK, DISCONNECT
 	ProtocolError                     ReasonCode = 0x82 // CONNACK, DISCONNECT
 	ImplSpecificError                 ReasonCode = 0x83 // CONNACK, PUBACK, PUBREC, SUBACK, UNSUBACK, DISCONNECT
+	UnsupportedProtocolVersion        ReasonCode = 0x84 // CONNACK
+	ClientIdentifierNotValid          ReasonCode = 0x85 // CONNACK
+	BadUserNameOrPassword             ReasonCode = 0x86 // CONNACK
 	NotAuthorized                     ReasonCode = 0x87 // CONNACK, PUBACK, PUBREC, SUBACK, UNSUBACK, DISCONNECT
+	ServerUnavailable                 ReasonCode = 0x88 // CONNACK
 	ServerBusy                        ReasonCode = 0x89 // CONNACK, DISCONNECT
+	Banned                            ReasonCode = 0x8A // CONNACK
+	ServerShuttingDown                ReasonCode = 0x8B // DISCONNECT
 	BadAuthMethod                     ReasonCode = 0x8C // CONNACK, DISCONNECT
+	KeepAliveTimeout                  ReasonCode = 0x8D // DISCONNECT
+	SessionTakenOver                  ReasonCode = 0x8E // DISCONNECT
 	TopicFilterInvalid                ReasonCode = 0x8F // SUBACK, UNSUBACK, DISCONNECT
 	TopicNameInvalid                  ReasonCode = 0x90 // CONNACK, PUBACK, PUBREC, DISCONNECT
 	PacketIdentifierInUse             ReasonCode = 0x91 // PUBACK, SUBACK, UNSUBACK
@@ -28,3 +110,33 @@ const (
 	SubscriptionIdsNotSupported       ReasonCode = 0xA1 // SUBACK, DISCONNECT
 	WildcardSubscriptionsNotSupported ReasonCode = 0xA2 // SUBACK, DISCONNECT
 )
+
+// MQTT 3.1.1 CONNACK 返回码
+const (
+	ConnAccepted                    byte = 0x00 // 连接已接受
+	ConnRefusedUnacceptableProtocol byte = 0x01 // 不支持的协议版本
+	ConnRefusedIdentifierRejected   byte = 0x02 // 不合格的客户端标识符
+	ConnRefusedServerUnavailable    byte = 0x03 // 服务端不可用
+	ConnRefusedBadUsernamePassword  byte = 0x04 // 无效的用户名或密码
+	ConnRefusedNotAuthorized        byte = 0x05 // 未授权
+)
+
+// MQTT 3.1.1 SUBACK 订阅失败返回码
+const SubackFailure byte = 0x80
+
+// ConnackCodeV3 将 MQTT 5.0 的原因码转换为 MQTT 3.1.1 的 CONNACK 返回码
+func ConnackCodeV3(code ReasonCode) byte {
+	switch code {
+	case Success:
+		return ConnAccepted
+	case UnsupportedProtocolVersion:
+		return ConnRefusedUnacceptableProtocol
+	case ClientIdentifierNotValid:
+		return ConnRefusedIdentifierRejected
+	case BadUserNameOrPassword:
+		return ConnRefusedBadUsernamePassword
+	case NotAuthorized, Banned:
+		return ConnRefusedNotAuthorized
+	}
+	return ConnRefusedServerUnavailable
+}
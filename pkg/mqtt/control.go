@@ -0,0 +1,113 @@
+package mqtt
+
+import "io"
+
+// PingreqPacket 心跳请求
+type PingreqPacket struct{}
+
+func (p *PingreqPacket) Type() PacketType { return PINGREQ }
+
+func (p *PingreqPacket) Encode(w io.Writer, _ byte) error {
+	return (&encoder{}).flush(w, FixedHeader{Type: PINGREQ})
+}
+
+func (p *PingreqPacket) Decode(r io.Reader, fh FixedHeader, _ byte) error {
+	if fh.RemainingLength != 0 {
+		return ErrMalformedPacket
+	}
+	return nil
+}
+
+// PingrespPacket 心跳响应
+type PingrespPacket struct{}
+
+func (p *PingrespPacket) Type() PacketType { return PINGRESP }
+
+func (p *PingrespPacket) Encode(w io.Writer, _ byte) error {
+	return (&encoder{}).flush(w, FixedHeader{Type: PINGRESP})
+}
+
+func (p *PingrespPacket) Decode(r io.Reader, fh FixedHeader, _ byte) error {
+	if fh.RemainingLength != 0 {
+		return ErrMalformedPacket
+	}
+	return nil
+}
+
+// DisconnectPacket 断开连接，3.1.1 中没有可变头
+type DisconnectPacket struct {
+	ReasonCode ReasonCode  // 5.0
+	Properties *Properties // 5.0
+}
+
+func (p *DisconnectPacket) Type() PacketType { return DISCONNECT }
+
+func (p *DisconnectPacket) Encode(w io.Writer, version byte) error {
+	e := &encoder{}
+	if version == Version5 {
+		props := p.Properties.encodeBody()
+		if p.ReasonCode != NormalDisconnection || len(props) > 0 {
+			e.writeByte(byte(p.ReasonCode))
+			if len(props) > 0 {
+				e.writeVarint(len(props))
+				_, _ = e.Write(props)
+			}
+		}
+	}
+	return e.flush(w, FixedHeader{Type: DISCONNECT})
+}
+
+func (p *DisconnectPacket) Decode(r io.Reader, fh FixedHeader, version byte) error {
+	d, err := newDecoder(r, fh.RemainingLength)
+	if err != nil {
+		return err
+	}
+	if version == Version5 {
+		if d.remaining() > 0 {
+			p.ReasonCode = ReasonCode(d.readByte())
+		}
+		if d.remaining() > 0 {
+			p.Properties = &Properties{}
+			p.Properties.decode(d)
+		}
+	}
+	return d.err
+}
+
+// AuthPacket 认证交换（仅 5.0）
+type AuthPacket struct {
+	ReasonCode ReasonCode
+	Properties *Properties
+}
+
+func (p *AuthPacket) Type() PacketType { return AUTH }
+
+func (p *AuthPacket) Encode(w io.Writer, version byte) error {
+	if version != Version5 {
+		return ErrUnsupportedVersion
+	}
+	e := &encoder{}
+	props := p.Properties.encodeBody()
+	if p.ReasonCode != Success || len(props) > 0 {
+		e.writeByte(byte(p.ReasonCode))
+		e.writeVarint(len(props))
+		_, _ = e.Write(props)
+	}
+	return e.flush(w, FixedHeader{Type: AUTH})
+}
+
+func (p *AuthPacket) Decode(r io.Reader, fh FixedHeader, version byte) error {
+	if version != Version5 {
+		return ErrUnsupportedVersion
+	}
+	d, err := newDecoder(r, fh.RemainingLength)
+	if err != nil {
+		return err
+	}
+	if d.remaining() > 0 {
+		p.ReasonCode = ReasonCode(d.readByte())
+		p.Properties = &Properties{}
+		p.Properties.decode(d)
+	}
+	return d.err
+}
@@ -0,0 +1,44 @@
+package mqtt
+
+import "io"
+
+// ControlPacket MQTT 控制报文
+type ControlPacket interface {
+	// Type 报文类型
+	Type() PacketType
+	// Encode 按协议版本编码报文（包含固定头）
+	Encode(w io.Writer, version byte) error
+	// Decode 按协议版本解码报文的可变头和载荷，r 只包含剩余长度的数据
+	Decode(r io.Reader, fh FixedHeader, version byte) error
+}
+
+// NewControlPacket 根据报文类型创建报文
+func NewControlPacket(t PacketType) (ControlPacket, error) {
+	switch t {
+	case CONNECT:
+		return &ConnectPacket{}, nil
+	case CONNACK:
+		return &ConnackPacket{}, nil
+	case PUBLISH:
+		return &PublishPacket{}, nil
+	case PUBACK, PUBREC, PUBREL, PUBCOMP:
+		return &AckPacket{PacketType: t}, nil
+	case SUBSCRIBE:
+		return &SubscribePacket{}, nil
+	case SUBACK:
+		return &SubackPacket{}, nil
+	case UNSUBSCRIBE:
+		return &UnsubscribePacket{}, nil
+	case UNSUBACK:
+		return &UnsubackPacket{}, nil
+	case PINGREQ:
+		return &PingreqPacket{}, nil
+	case PINGRESP:
+		return &PingrespPacket{}, nil
+	case DISCONNECT:
+		return &DisconnectPacket{}, nil
+	case AUTH:
+		return &AuthPacket{}, nil
+	}
+	return nil, ErrUnknownPacketType
+}
@@ -0,0 +1,264 @@
+package mqtt
+
+// 属性标识符（MQTT 5.0）
+const (
+	PropPayloadFormat          byte = 0x01
+	PropMessageExpiry          byte = 0x02
+	PropContentType            byte = 0x03
+	PropResponseTopic          byte = 0x08
+	PropCorrelationData        byte = 0x09
+	PropSubscriptionIdentifier byte = 0x0B
+	PropSessionExpiryInterval  byte = 0x11
+	PropAssignedClientID       byte = 0x12
+	PropServerKeepAlive        byte = 0x13
+	PropAuthMethod             byte = 0x15
+	PropAuthData               byte = 0x16
+	PropRequestProblemInfo     byte = 0x17
+	PropWillDelayInterval      byte = 0x18
+	PropRequestResponseInfo    byte = 0x19
+	PropResponseInfo           byte = 0x1A
+	PropServerReference        byte = 0x1C
+	PropReasonString           byte = 0x1F
+	PropReceiveMaximum         byte = 0x21
+	PropTopicAliasMaximum      byte = 0x22
+	PropTopicAlias             byte = 0x23
+	PropMaximumQoS             byte = 0x24
+	PropRetainAvailable        byte = 0x25
+	PropUserProperty           byte = 0x26
+	PropMaximumPacketSize      byte = 0x27
+	PropWildcardSubAvailable   byte = 0x28
+	PropSubIDAvailable         byte = 0x29
+	PropSharedSubAvailable     byte = 0x2A
+)
+
+// UserProperty 用户属性
+type UserProperty struct {
+	Key   string
+	Value string
+}
+
+// Properties MQTT 5.0 属性，指针类型的字段为 nil 表示未设置
+type Properties struct {
+	PayloadFormat          *byte
+	MessageExpiry          *uint32
+	ContentType            string
+	ResponseTopic          string
+	CorrelationData        []byte
+	SubscriptionIdentifier []int
+	SessionExpiryInterval  *uint32
+	AssignedClientID       string
+	ServerKeepAlive        *uint16
+	AuthMethod             string
+	AuthData               []byte
+	RequestProblemInfo     *byte
+	WillDelayInterval      *uint32
+	RequestResponseInfo    *byte
+	ResponseInfo           string
+	ServerReference        string
+	ReasonString           string
+	ReceiveMaximum         *uint16
+	TopicAliasMaximum      *uint16
+	TopicAlias             *uint16
+	MaximumQoS             *byte
+	RetainAvailable        *byte
+	User                   []UserProperty
+	MaximumPacketSize      *uint32
+	WildcardSubAvailable   *byte
+	SubIDAvailable         *byte
+	SharedSubAvailable     *byte
+}
+
+// AddUser 添加用户属性
+func (p *Properties) AddUser(key, value string) {
+	p.User = append(p.User, UserProperty{Key: key, Value: value})
+}
+
+// GetUser 获取第一个匹配的用户属性
+func (p *Properties) GetUser(key string) (string, bool) {
+	if p == nil {
+		return "", false
+	}
+	for _, u := range p.User {
+		if u.Key == key {
+			return u.Value, true
+		}
+	}
+	return "", false
+}
+
+func (p *Properties) encodeBody() []byte {
+	e := &encoder{}
+	if p == nil {
+		return nil
+	}
+	putByte := func(id byte, v *byte) {
+		if v != nil {
+			e.writeByte(id)
+			e.writeByte(*v)
+		}
+	}
+	putUint16 := func(id byte, v *uint16) {
+		if v != nil {
+			e.writeByte(id)
+			e.writeUint16(*v)
+		}
+	}
+	putUint32 := func(id byte, v *uint32) {
+		if v != nil {
+			e.writeByte(id)
+			e.writeUint32(*v)
+		}
+	}
+	putString := func(id byte, v string) {
+		if v != "" {
+			e.writeByte(id)
+			e.writeString(v)
+		}
+	}
+	putBinary := func(id byte, v []byte) {
+		if len(v) > 0 {
+			e.writeByte(id)
+			e.writeBinary(v)
+		}
+	}
+	putByte(PropPayloadFormat, p.PayloadFormat)
+	putUint32(PropMessageExpiry, p.MessageExpiry)
+	putString(PropContentType, p.ContentType)
+	putString(PropResponseTopic, p.ResponseTopic)
+	putBinary(PropCorrelationData, p.CorrelationData)
+	for _, id := range p.SubscriptionIdentifier {
+		e.writeByte(PropSubscriptionIdentifier)
+		e.writeVarint(id)
+	}
+	putUint32(PropSessionExpiryInterval, p.SessionExpiryInterval)
+	putString(PropAssignedClientID, p.AssignedClientID)
+	putUint16(PropServerKeepAlive, p.ServerKeepAlive)
+	putString(PropAuthMethod, p.AuthMethod)
+	putBinary(PropAuthData, p.AuthData)
+	putByte(PropRequestProblemInfo, p.RequestProblemInfo)
+	putUint32(PropWillDelayInterval, p.WillDelayInterval)
+	putByte(PropRequestResponseInfo, p.RequestResponseInfo)
+	putString(PropResponseInfo, p.ResponseInfo)
+	putString(PropServerReference, p.ServerReference)
+	putString(PropReasonString, p.ReasonString)
+	putUint16(PropReceiveMaximum, p.ReceiveMaximum)
+	putUint16(PropTopicAliasMaximum, p.TopicAliasMaximum)
+	putUint16(PropTopicAlias, p.TopicAlias)
+	putByte(PropMaximumQoS, p.MaximumQoS)
+	putByte(PropRetainAvailable, p.RetainAvailable)
+	for _, u := range p.User {
+		e.writeByte(PropUserProperty)
+		e.writeString(u.Key)
+		e.writeString(u.Value)
+	}
+	putUint32(PropMaximumPacketSize, p.MaximumPacketSize)
+	putByte(PropWildcardSubAvailable, p.WildcardSubAvailable)
+	putByte(PropSubIDAvailable, p.SubIDAvailable)
+	putByte(PropSharedSubAvailable, p.SharedSubAvailable)
+	return e.Bytes()
+}
+
+// encode 编码属性（包含属性长度）
+func (p *Properties) encode(e *encoder) {
+	body := p.encodeBody()
+	e.writeVarint(len(body))
+	_, _ = e.Write(body)
+}
+
+// decode 解码属性（包含属性长度）
+func (p *Properties) decode(d *decoder) {
+	length := d.readVarint()
+	if d.err != nil {
+		return
+	}
+	if length > d.remaining() {
+		d.err = ErrMalformedPacket
+		return
+	}
+	end := d.pos + length
+	getByte := func() *byte {
+		v := d.readByte()
+		return &v
+	}
+	getUint16 := func() *uint16 {
+		v := d.readUint16()
+		return &v
+	}
+	getUint32 := func() *uint32 {
+		v := d.readUint32()
+		return &v
+	}
+	for d.err == nil && d.pos < end {
+		id := d.readByte()
+		switch id {
+		case PropPayloadFormat:
+			p.PayloadFormat = getByte()
+		case PropMessageExpiry:
+			p.MessageExpiry = getUint32()
+		case PropContentType:
+			p.ContentType = d.readString()
+		case PropResponseTopic:
+			p.ResponseTopic = d.readString()
+		case PropCorrelationData:
+			p.CorrelationData = d.readBinary()
+		case PropSubscriptionIdentifier:
+			p.SubscriptionIdentifier = append(p.SubscriptionIdentifier, d.readVarint())
+		case PropSessionExpiryInterval:
+			p.SessionExpiryInterval = getUint32()
+		case PropAssignedClientID:
+			p.AssignedClientID = d.readString()
+		case PropServerKeepAlive:
+			p.ServerKeepAlive = getUint16()
+		case PropAuthMethod:
+			p.AuthMethod = d.readString()
+		case PropAuthData:
+			p.AuthData = d.readBinary()
+		case PropRequestProblemInfo:
+			p.RequestProblemInfo = getByte()
+		case PropWillDelayInterval:
+			p.WillDelayInterval = getUint32()
+		case PropRequestResponseInfo:
+			p.RequestResponseInfo = getByte()
+		case PropResponseInfo:
+			p.ResponseInfo = d.readString()
+		case PropServerReference:
+			p.ServerReference = d.readString()
+		case PropReasonString:
+			p.ReasonString = d.readString()
+		case PropReceiveMaximum:
+			p.ReceiveMaximum = getUint16()
+		case PropTopicAliasMaximum:
+			p.TopicAliasMaximum = getUint16()
+		case PropTopicAlias:
+			p.TopicAlias = getUint16()
+		case PropMaximumQoS:
+			p.MaximumQoS = getByte()
+		case PropRetainAvailable:
+			p.RetainAvailable = getByte()
+		case PropUserProperty:
+			p.User = append(p.User, UserProperty{Key: d.readString(), Value: d.readString()})
+		case PropMaximumPacketSize:
+			p.MaximumPacketSize = getUint32()
+		case PropWildcardSubAvailable:
+			p.WildcardSubAvailable = getByte()
+		case PropSubIDAvailable:
+			p.SubIDAvailable = getByte()
+		case PropSharedSubAvailable:
+			p.SharedSubAvailable = getByte()
+		default:
+			d.err = ErrInvalidProperty
+		}
+	}
+	if d.err == nil && d.pos != end {
+		d.err = ErrMalformedPacket
+	}
+}
+
+// Byte 返回字节指针，方便设置属性
+func Byte(v byte) *byte { return &v }
+
+// Uint16 返回uint16指针，方便设置属性
+func Uint16(v uint16) *uint16 { return &v }
+
+// Uint32 返回uint32指针，方便设置属性
+func Uint32(v uint32) *uint32 { return &v }
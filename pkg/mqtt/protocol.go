@@ -1,8 +1,77 @@
 package mqtt
 
-import "io"
+import (
+	"bytes"
+	"io"
+)
 
-func ReadFrom(r io.Reader) (ControlPacket, error) {
+// ReadFrom 从 r 中读取一个完整的控制报文
+func ReadFrom(r io.Reader, version byte) (ControlPacket, error) {
+	var b [1]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return nil, err
+	}
+	fh, err := parseFixedHeader(b[0])
+	if err != nil {
+		return nil, err
+	}
+	fh.RemainingLength, err = readVarint(r)
+	if err != nil {
+		return nil, err
+	}
+	packet, err := NewControlPacket(fh.Type)
+	if err != nil {
+		return nil, err
+	}
+	if err = packet.Decode(r, fh, version); err != nil {
+		return nil, err
+	}
+	return packet, nil
+}
+
+// Decode 从 data 中解码一个控制报文，返回报文和消耗的字节数
+// 数据不完整时返回 nil, 0, nil
+func Decode(data []byte, version byte) (ControlPacket, int, error) {
+	fh, headerLen, err := PeekFixedHeader(data)
+	if err != nil || headerLen == 0 {
+		return nil, 0, err
+	}
+	total := headerLen + fh.RemainingLength
+	if len(data) < total {
+		return nil, 0, nil
+	}
+	packet, err := NewControlPacket(fh.Type)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err = packet.Decode(bytes.NewReader(data[headerLen:total]), fh, version); err != nil {
+		return nil, 0, err
+	}
+	return packet, total, nil
+}
+
+// PeekFixedHeader 解析固定头，返回固定头和固定头的长度，数据不完整时长度为0
+func PeekFixedHeader(data []byte) (FixedHeader, int, error) {
+	if len(data) < 2 {
+		return FixedHeader{}, 0, nil
+	}
+	fh, err := parseFixedHeader(data[0])
+	if err != nil {
+		return fh, 0, err
+	}
+	length, n, err := decodeVarint(data[1:])
+	if err != nil || n == 0 {
+		return fh, 0, err
+	}
+	fh.RemainingLength = length
+	return fh, 1 + n, nil
+}
 
-	return nil, nil
+// Encode 编码控制报文
+func Encode(packet ControlPacket, version byte) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if err := packet.Encode(buf, version); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
 }
@@ -0,0 +1,132 @@
+package mqtt
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func encodeDecode(t *testing.T, packet ControlPacket, version byte) ControlPacket {
+	data, err := Encode(packet, version)
+	assert.NoError(t, err)
+
+	// 不完整的数据
+	p, n, err := Decode(data[:len(data)-1], version)
+	assert.NoError(t, err)
+	assert.Nil(t, p)
+	assert.Equal(t, 0, n)
+
+	p, n, err = Decode(data, version)
+	assert.NoError(t, err)
+	assert.Equal(t, len(data), n)
+
+	p2, err := ReadFrom(bytes.NewReader(data), version)
+	assert.NoError(t, err)
+	assert.Equal(t, p, p2)
+	return p
+}
+
+func TestConnectCodec(t *testing.T) {
+	for _, version := range []byte{Version311, Version5} {
+		connect := &ConnectPacket{
+			ProtocolName:    ProtocolName,
+			ProtocolVersion: version,
+			CleanStart:      true,
+			KeepAlive:       60,
+			ClientID:        "device1",
+			WillFlag:        true,
+			WillQoS:         1,
+			WillTopic:       "2/g1",
+			WillPayload:     []byte("bye"),
+			UsernameFlag:    true,
+			Username:        "u1",
+			PasswordFlag:    true,
+			Password:        []byte("token"),
+		}
+		if version == Version5 {
+			connect.Properties = &Properties{SessionExpiryInterval: Uint32(30)}
+			connect.Properties.AddUser("k", "v")
+			connect.WillProperties = &Properties{}
+		}
+		p := encodeDecode(t, connect, version).(*ConnectPacket)
+		assert.Equal(t, connect, p)
+	}
+}
+
+func TestPublishCodec(t *testing.T) {
+	publish := &PublishPacket{
+		Dup:        true,
+		QoS:        1,
+		Retain:     true,
+		Topic:      "1/u2",
+		PacketID:   10,
+		Properties: &Properties{ContentType: "text/plain"},
+		Payload:    []byte("hello"),
+	}
+	p := encodeDecode(t, publish, Version5).(*PublishPacket)
+	assert.Equal(t, publish, p)
+
+	publish = &PublishPacket{Topic: "1/u2", Payload: []byte("hello")}
+	p = encodeDecode(t, publish, Version311).(*PublishPacket)
+	assert.Equal(t, publish, p)
+}
+
+func TestAckCodec(t *testing.T) {
+	ack := encodeDecode(t, NewPuback(3, QuotaExceeded), Version5).(*AckPacket)
+	assert.Equal(t, uint16(3), ack.PacketID)
+	assert.Equal(t, QuotaExceeded, ack.ReasonCode)
+
+	data, err := Encode(NewPuback(3, Success), Version5)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{byte(PUBACK) << 4, 2, 0, 3}, data)
+
+	rel := encodeDecode(t, &AckPacket{PacketType: PUBREL, PacketID: 5}, Version311).(*AckPacket)
+	assert.Equal(t, PUBREL, rel.Type())
+}
+
+func TestSubscribeCodec(t *testing.T) {
+	sub := &SubscribePacket{
+		PacketID:   1,
+		Properties: &Properties{},
+		Subscriptions: []Subscription{
+			{Topic: "2/g1", QoS: 1, NoLocal: true, RetainHandling: 2},
+			{Topic: "1/+", QoS: 0},
+		},
+	}
+	p := encodeDecode(t, sub, Version5).(*SubscribePacket)
+	assert.Equal(t, sub, p)
+
+	suback := &SubackPacket{PacketID: 1, ReasonCodes: []byte{1, SubackFailure}}
+	p2 := encodeDecode(t, suback, Version311).(*SubackPacket)
+	assert.Equal(t, suback, p2)
+
+	unsub := &UnsubscribePacket{PacketID: 2, Topics: []string{"2/g1"}}
+	p3 := encodeDecode(t, unsub, Version311).(*UnsubscribePacket)
+	assert.Equal(t, unsub, p3)
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	// SUBSCRIBE 的固定头标志必须为 0x02
+	_, _, err := Decode([]byte{byte(SUBSCRIBE) << 4, 0}, Version311)
+	assert.Equal(t, ErrInvalidFlags, err)
+
+	// 剩余长度超过4个字节
+	_, _, err = Decode([]byte{byte(PINGREQ) << 4, 0xFF, 0xFF, 0xFF, 0xFF, 0x01}, Version311)
+	assert.Equal(t, ErrMalformedLength, err)
+}
+
+func TestMatchTopic(t *testing.T) {
+	assert.True(t, MatchTopic("2/g1", "2/g1"))
+	assert.True(t, MatchTopic("2/+", "2/g1"))
+	assert.True(t, MatchTopic("#", "2/g1"))
+	assert.True(t, MatchTopic("2/#", "2/g1"))
+	assert.False(t, MatchTopic("1/+", "2/g1"))
+	assert.False(t, MatchTopic("+/+/+", "2/g1"))
+	assert.False(t, MatchTopic("#", "$SYS/x"))
+
+	assert.True(t, ValidTopicFilter("a/+/#"))
+	assert.False(t, ValidTopicFilter("a/#/b"))
+	assert.False(t, ValidTopicFilter("a/b+"))
+	assert.False(t, ValidTopicName("a/+"))
+}
@@ -0,0 +1,106 @@
+package mqtt
+
+import "io"
+
+// PublishPacket 发布消息
+type PublishPacket struct {
+	Dup        bool
+	QoS        byte
+	Retain     bool
+	Topic      string
+	PacketID   uint16      // QoS > 0 时有效
+	Properties *Properties // 5.0
+	Payload    []byte
+}
+
+func (p *PublishPacket) Type() PacketType { return PUBLISH }
+
+func (p *PublishPacket) Encode(w io.Writer, version byte) error {
+	if p.QoS > 2 {
+		return ErrInvalidQoS
+	}
+	e := &encoder{}
+	e.writeString(p.Topic)
+	if p.QoS > 0 {
+		e.writeUint16(p.PacketID)
+	}
+	if version == Version5 {
+		p.Properties.encode(e)
+	}
+	_, _ = e.Write(p.Payload)
+	return e.flush(w, FixedHeader{Type: PUBLISH, Dup: p.Dup, QoS: p.QoS, Retain: p.Retain})
+}
+
+func (p *PublishPacket) Decode(r io.Reader, fh FixedHeader, version byte) error {
+	d, err := newDecoder(r, fh.RemainingLength)
+	if err != nil {
+		return err
+	}
+	p.Dup = fh.Dup
+	p.QoS = fh.QoS
+	p.Retain = fh.Retain
+	p.Topic = d.readString()
+	if p.QoS > 0 {
+		p.PacketID = d.readUint16()
+		if d.err == nil && p.PacketID == 0 {
+			return ErrMalformedPacket
+		}
+	}
+	if version == Version5 {
+		p.Properties = &Properties{}
+		p.Properties.decode(d)
+	}
+	p.Payload = d.rest()
+	return d.err
+}
+
+// AckPacket PUBACK、PUBREC、PUBREL、PUBCOMP 共用的结构
+type AckPacket struct {
+	PacketType PacketType
+	PacketID   uint16
+	ReasonCode ReasonCode  // 5.0
+	Properties *Properties // 5.0
+}
+
+func (p *AckPacket) Type() PacketType { return p.PacketType }
+
+func (p *AckPacket) Encode(w io.Writer, version byte) error {
+	e := &encoder{}
+	e.writeUint16(p.PacketID)
+	if version == Version5 {
+		props := p.Properties.encodeBody()
+		// 成功且没有属性时可以省略原因码和属性
+		if p.ReasonCode != Success || len(props) > 0 {
+			e.writeByte(byte(p.ReasonCode))
+			if len(props) > 0 {
+				e.writeVarint(len(props))
+				_, _ = e.Write(props)
+			}
+		}
+	}
+	return e.flush(w, FixedHeader{Type: p.PacketType})
+}
+
+func (p *AckPacket) Decode(r io.Reader, fh FixedHeader, version byte) error {
+	d, err := newDecoder(r, fh.RemainingLength)
+	if err != nil {
+		return err
+	}
+	p.PacketType = fh.Type
+	p.PacketID = d.readUint16()
+	if version == Version5 {
+		if d.remaining() > 0 {
+			p.ReasonCode = ReasonCode(d.readByte())
+		}
+		if d.remaining() > 0 {
+			p.Properties = &Properties{}
+			p.Properties.decode(d)
+		}
+	}
+	return d.err
+}
+
+// NewPuback 创建 PUBACK
+func NewPuback(packetID uint16, reasonCode ReasonCode) *AckPacket {
+	return &AckPacket{PacketType: PUBACK, PacketID: packetID, ReasonCode: reasonCode}
+}
@@ -0,0 +1,185 @@
+package mqtt
+
+import "io"
+
+// Subscription 订阅项
+type Subscription struct {
+	Topic             string
+	QoS               byte
+	NoLocal           bool // 5.0
+	RetainAsPublished bool // 5.0
+	RetainHandling    byte // 5.0 0.订阅时发送保留消息 1.新订阅时发送 2.不发送
+}
+
+// SubscribePacket 订阅请求
+type SubscribePacket struct {
+	PacketID      uint16
+	Properties    *Properties // 5.0
+	Subscriptions []Subscription
+}
+
+func (p *SubscribePacket) Type() PacketType { return SUBSCRIBE }
+
+func (p *SubscribePacket) Encode(w io.Writer, version byte) error {
+	e := &encoder{}
+	e.writeUint16(p.PacketID)
+	if version == Version5 {
+		p.Properties.encode(e)
+	}
+	for _, sub := range p.Subscriptions {
+		e.writeString(sub.Topic)
+		opts := sub.QoS & 0x03
+		if version == Version5 {
+			if sub.NoLocal {
+				opts |= 0x04
+			}
+			if sub.RetainAsPublished {
+				opts |= 0x08
+			}
+			opts |= (sub.RetainHandling & 0x03) << 4
+		}
+		e.writeByte(opts)
+	}
+	return e.flush(w, FixedHeader{Type: SUBSCRIBE})
+}
+
+func (p *SubscribePacket) Decode(r io.Reader, fh FixedHeader, version byte) error {
+	d, err := newDecoder(r, fh.RemainingLength)
+	if err != nil {
+		return err
+	}
+	p.PacketID = d.readUint16()
+	if version == Version5 {
+		p.Properties = &Properties{}
+		p.Properties.decode(d)
+	}
+	for d.err == nil && d.remaining() > 0 {
+		sub := Subscription{Topic: d.readString()}
+		opts := d.readByte()
+		sub.QoS = opts & 0x03
+		if version == Version5 {
+			sub.NoLocal = opts&0x04 != 0
+			sub.RetainAsPublished = opts&0x08 != 0
+			sub.RetainHandling = (opts >> 4) & 0x03
+			if opts&0xC0 != 0 || sub.RetainHandling > 2 {
+				return ErrMalformedPacket
+			}
+		} else if opts&0xFC != 0 {
+			return ErrMalformedPacket
+		}
+		if sub.QoS > 2 {
+			return ErrInvalidQoS
+		}
+		p.Subscriptions = append(p.Subscriptions, sub)
+	}
+	if d.err == nil && len(p.Subscriptions) == 0 {
+		return ErrMalformedPacket
+	}
+	return d.err
+}
+
+// SubackPacket 订阅确认
+type SubackPacket struct {
+	PacketID    uint16
+	Properties  *Properties // 5.0
+	ReasonCodes []byte      // 每个订阅项对应一个，3.1.1 中为授予的QoS或0x80
+}
+
+func (p *SubackPacket) Type() PacketType { return SUBACK }
+
+func (p *SubackPacket) Encode(w io.Writer, version byte) error {
+	e := &encoder{}
+	e.writeUint16(p.PacketID)
+	if version == Version5 {
+		p.Properties.encode(e)
+	}
+	_, _ = e.Write(p.ReasonCodes)
+	return e.flush(w, FixedHeader{Type: SUBACK})
+}
+
+func (p *SubackPacket) Decode(r io.Reader, fh FixedHeader, version byte) error {
+	d, err := newDecoder(r, fh.RemainingLength)
+	if err != nil {
+		return err
+	}
+	p.PacketID = d.readUint16()
+	if version == Version5 {
+		p.Properties = &Properties{}
+		p.Properties.decode(d)
+	}
+	p.ReasonCodes = d.rest()
+	return d.err
+}
+
+// UnsubscribePacket 取消订阅
+type UnsubscribePacket struct {
+	PacketID   uint16
+	Properties *Properties // 5.0
+	Topics     []string
+}
+
+func (p *UnsubscribePacket) Type() PacketType { return UNSUBSCRIBE }
+
+func (p *UnsubscribePacket) Encode(w io.Writer, version byte) error {
+	e := &encoder{}
+	e.writeUint16(p.PacketID)
+	if version == Version5 {
+		p.Properties.encode(e)
+	}
+	for _, topic := range p.Topics {
+		e.writeString(topic)
+	}
+	return e.flush(w, FixedHeader{Type: UNSUBSCRIBE})
+}
+
+func (p *UnsubscribePacket) Decode(r io.Reader, fh FixedHeader, version byte) error {
+	d, err := newDecoder(r, fh.RemainingLength)
+	if err != nil {
+		return err
+	}
+	p.PacketID = d.readUint16()
+	if version == Version5 {
+		p.Properties = &Properties{}
+		p.Properties.decode(d)
+	}
+	for d.err == nil && d.remaining() > 0 {
+		p.Topics = append(p.Topics, d.readString())
+	}
+	if d.err == nil && len(p.Topics) == 0 {
+		return ErrMalformedPacket
+	}
+	return d.err
+}
+
+// UnsubackPacket 取消订阅确认
+type UnsubackPacket struct {
+	PacketID    uint16
+	Properties  *Properties // 5.0
+	ReasonCodes []byte      // 5.0
+}
+
+func (p *UnsubackPacket) Type() PacketType { return UNSUBACK }
+
+func (p *UnsubackPacket) Encode(w io.Writer, version byte) error {
+	e := &encoder{}
+	e.writeUint16(p.PacketID)
+	if version == Version5 {
+		p.Properties.encode(e)
+		_, _ = e.Write(p.ReasonCodes)
+	}
+	return e.flush(w, FixedHeader{Type: UNSUBACK})
+}
+
+func (p *UnsubackPacket) Decode(r io.Reader, fh FixedHeader, version byte) error {
+	d, err := newDecoder(r, fh.RemainingLength)
+	if err != nil {
+		return err
+	}
+	p.PacketID = d.readUint16()
+	if version == Version5 {
+		p.Properties = &Properties{}
+		p.Properties.decode(d)
+		p.ReasonCodes = d.rest()
+	}
+	return d.err
+}
@@ -0,0 +1,58 @@
+package mqtt
+
+import "strings"
+
+// SharedSubscriptionPrefix 共享订阅的前缀
+const SharedSubscriptionPrefix = "$share/"
+
+// ValidTopicName 校验发布的主题名（不能包含通配符）
+func ValidTopicName(topic string) bool {
+	if topic == "" || len(topic) > 65535 {
+		return false
+	}
+	return !strings.ContainsAny(topic, "+#\x00")
+}
+
+// ValidTopicFilter 校验订阅的主题过滤器
+func ValidTopicFilter(filter string) bool {
+	if filter == "" || len(filter) > 65535 || strings.ContainsRune(filter, 0) {
+		return false
+	}
+	levels := strings.Split(filter, "/")
+	for i, level := range levels {
+		if strings.Contains(level, "#") && (level != "#" || i != len(levels)-1) {
+			return false
+		}
+		if strings.Contains(level, "+") && level != "+" {
+			return false
+		}
+	}
+	return true
+}
+
+// HasWildcard 主题过滤器是否包含通配符
+func HasWildcard(filter string) bool {
+	return strings.ContainsAny(filter, "+#")
+}
+
+// MatchTopic 主题名是否匹配主题过滤器
+func MatchTopic(filter, topic string) bool {
+	// 以$开头的主题不匹配以通配符开头的过滤器
+	if strings.HasPrefix(topic, "$") && (strings.HasPrefix(filter, "+") || strings.HasPrefix(filter, "#")) {
+		return false
+	}
+	fLevels := strings.Split(filter, "/")
+	tLevels := strings.Split(topic, "/")
+	for i, f := range fLevels {
+		if f == "#" {
+			return true
+		}
+		if i >= len(tLevels) {
+			return false
+		}
+		if f != "+" && f != tLevels[i] {
+			return false
+		}
+	}
+	return len(fLevels) == len(tLevels)
+}
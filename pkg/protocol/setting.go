@@ -0,0 +1,8 @@
+// Package protocol 服务端和客户端共用的、wkproto之外的协议扩展
+package protocol
+
+import wkproto "github.com/WuKongIM/WuKongIMGoProto"
+
+// SettingTransient 瞬态事件（比如正在输入）的消息设置（协议中未使用的第6位）
+// SendPacket的Setting设置了此标记时，服务端只做权限验证后投递给在线的接收者，不存储、不更新最近会话、不重试
+const SettingTransient wkproto.Setting = 1 << 6
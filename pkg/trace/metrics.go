@@ -336,6 +336,25 @@ type IAppMetrics interface {
 	// ConnackPacketCountAdd 连接应答包数量
 	ConnackPacketCountAdd(v int64)
 	ConnackPacketCount() int64
+
+	// RateLimitedMsgCountAdd 被限速的消息数量
+	RateLimitedMsgCountAdd(v int64)
+	RateLimitedMsgCount() int64
+	// RateLimitedUserCountSet 当前被限速的用户数
+	RateLimitedUserCountSet(v int64)
+	RateLimitedUserCount() int64
+
+	// WebhookDeadLetterAddCountAdd 进入死信的webhook事件数量
+	WebhookDeadLetterAddCountAdd(v int64)
+	WebhookDeadLetterAddCount() int64
+	// WebhookDeadLetterCountSet 本节点当前的webhook死信数量
+	WebhookDeadLetterCountSet(v int64)
+	WebhookDeadLetterCount() int64
+
+	// WSCompressBytesAdd websocket消息压缩前后的字节数（压缩率 = 压缩后/压缩前）
+	WSCompressBytesAdd(rawN, compressedN int64)
+	// WSDecompressBytesAdd websocket消息解压前后的字节数
+	WSDecompressBytesAdd(compressedN, rawN int64)
 }
 
 // IClusterMetrics 分布式监控
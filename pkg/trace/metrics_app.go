@@ -33,6 +33,17 @@ type appMetrics struct {
 	connPacketCount    atomic.Int64
 	connackPacketBytes atomic.Int64
 	connackPacketCount atomic.Int64
+
+	rateLimitedMsgCount  atomic.Int64
+	rateLimitedUserCount atomic.Int64
+
+	webhookDeadLetterAddCount atomic.Int64
+	webhookDeadLetterCount    atomic.Int64
+
+	wsCompressRawBytes        atomic.Int64
+	wsCompressedBytes         atomic.Int64
+	wsDecompressRawBytes      atomic.Int64
+	wsDecompressCompressBytes atomic.Int64
 }
 
 func newAppMetrics(opts *Options) *appMetrics {
@@ -61,6 +72,16 @@ func newAppMetrics(opts *Options) *appMetrics {
 	connPacketCount := NewInt64ObservableCounter("app_conn_packet_count")
 	connackPacketBytes := NewInt64ObservableCounter("app_connack_packet_bytes")
 	connackPacketCount := NewInt64ObservableCounter("app_connack_packet_count")
+	rateLimitedMsgCount := NewInt64ObservableCounter("app_rate_limited_msg_count")
+	rateLimitedUserCount := NewInt64ObservableGauge("app_rate_limited_user_count")
+	webhookDeadLetterAddCount := NewInt64ObservableCounter("app_webhook_dead_letter_add_count")
+	webhookDeadLetterCount := NewInt64ObservableGauge("app_webhook_dead_letter_count")
+	wsCompressRawBytes := NewInt64ObservableCounter("app_ws_compress_raw_bytes")
+	wsCompressedBytes := NewInt64ObservableCounter("app_ws_compress_compressed_bytes")
+	wsDecompressRawBytes := NewInt64ObservableCounter("app_ws_decompress_raw_bytes")
+	wsDecompressCompressBytes := NewInt64ObservableCounter("app_ws_decompress_compressed_bytes")
+	wsCompressRatio := NewInt64ObservableGauge("app_ws_compress_ratio")     // 发送消息的压缩率（百分比）
+	wsDecompressRatio := NewInt64ObservableGauge("app_ws_decompress_ratio") // 接收消息的压缩率（百分比）
 
 	RegisterCallback(func(ctx context.Context, obs metric.Observer) error {
 		obs.ObserveInt64(connCount, a.connCount.Load())
@@ -82,8 +103,18 @@ func newAppMetrics(opts *Options) *appMetrics {
 		obs.ObserveInt64(connPacketCount, a.connPacketCount.Load())
 		obs.ObserveInt64(connackPacketBytes, a.connackPacketBytes.Load())
 		obs.ObserveInt64(connackPacketCount, a.connackPacketCount.Load())
+		obs.ObserveInt64(rateLimitedMsgCount, a.rateLimitedMsgCount.Load())
+		obs.ObserveInt64(rateLimitedUserCount, a.rateLimitedUserCount.Load())
+		obs.ObserveInt64(webhookDeadLetterAddCount, a.webhookDeadLetterAddCount.Load())
+		obs.ObserveInt64(webhookDeadLetterCount, a.webhookDeadLetterCount.Load())
+		obs.ObserveInt64(wsCompressRawBytes, a.wsCompressRawBytes.Load())
+		obs.ObserveInt64(wsCompressedBytes, a.wsCompressedBytes.Load())
+		obs.ObserveInt64(wsDecompressRawBytes, a.wsDecompressRawBytes.Load())
+		obs.ObserveInt64(wsDecompressCompressBytes, a.wsDecompressCompressBytes.Load())
+		obs.ObserveInt64(wsCompressRatio, ratioPercent(a.wsCompressedBytes.Load(), a.wsCompressRawBytes.Load()))
+		obs.ObserveInt64(wsDecompressRatio, ratioPercent(a.wsDecompressCompressBytes.Load(), a.wsDecompressRawBytes.Load()))
 		return nil
-	}, connCount, onlineUserCount, onlineDeviceCount, pingBytes, pingCount, pongBytes, pongCount, sendPacketBytes, sendPacketCount, sendackPacketBytes, sendackPacketCount, recvPacketBytes, recvPacketCount, recvackPacketBytes, recvackPacketCount, connPacketBytes, connPacketCount, connackPacketBytes, connackPacketCount)
+	}, connCount, onlineUserCount, onlineDeviceCount, pingBytes, pingCount, pongBytes, pongCount, sendPacketBytes, sendPacketCount, sendackPacketBytes, sendackPacketCount, recvPacketBytes, recvPacketCount, recvackPacketBytes, recvackPacketCount, connPacketBytes, connPacketCount, connackPacketBytes, connackPacketCount, rateLimitedMsgCount, rateLimitedUserCount, webhookDeadLetterAddCount, webhookDeadLetterCount, wsCompressRawBytes, wsCompressedBytes, wsDecompressRawBytes, wsDecompressCompressBytes, wsCompressRatio, wsDecompressRatio)
 	var err error
 	a.messageLatency, err = meter.Int64Histogram("app_message_latency", metric.WithDescription("The latency of message processing in the app layer"), metric.WithUnit("ms"))
 	if err != nil {
@@ -242,3 +273,53 @@ func (a *appMetrics) ConnackPacketCountAdd(v int64) {
 func (a *appMetrics) ConnackPacketCount() int64 {
 	return a.connackPacketCount.Load()
 }
+
+func (a *appMetrics) RateLimitedMsgCountAdd(v int64) {
+	a.rateLimitedMsgCount.Add(v)
+}
+
+func (a *appMetrics) RateLimitedMsgCount() int64 {
+	return a.rateLimitedMsgCount.Load()
+}
+
+func (a *appMetrics) RateLimitedUserCountSet(v int64) {
+	a.rateLimitedUserCount.Store(v)
+}
+
+func (a *appMetrics) RateLimitedUserCount() int64 {
+	return a.rateLimitedUserCount.Load()
+}
+
+func (a *appMetrics) WebhookDeadLetterAddCountAdd(v int64) {
+	a.webhookDeadLetterAddCount.Add(v)
+}
+
+func (a *appMetrics) WebhookDeadLetterAddCount() int64 {
+	return a.webhookDeadLetterAddCount.Load()
+}
+
+func (a *appMetrics) WebhookDeadLetterCountSet(v int64) {
+	a.webhookDeadLetterCount.Store(v)
+}
+
+func (a *appMetrics) WebhookDeadLetterCount() int64 {
+	return a.webhookDeadLetterCount.Load()
+}
+
+func (a *appMetrics) WSCompressBytesAdd(rawN, compressedN int64) {
+	a.wsCompressRawBytes.Add(rawN)
+	a.wsCompressedBytes.Add(compressedN)
+}
+
+func (a *appMetrics) WSDecompressBytesAdd(compressedN, rawN int64) {
+	a.wsDecompressCompressBytes.Add(compressedN)
+	a.wsDecompressRawBytes.Add(rawN)
+}
+
+// 压缩后占压缩前的百分比，没有数据时为100
+func ratioPercent(compressed, raw int64) int64 {
+	if raw <= 0 {
+		return 100
+	}
+	return compressed * 100 / raw
+}
@@ -3,6 +3,7 @@ package wkdb
 import (
 	"math"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
@@ -569,6 +570,16 @@ func (wk *wukongDB) writeChannelInfo(primaryKey uint64, channelInfo ChannelInfo,
 
 	}
 
+	// webhook
+	if err = w.Set(key.NewChannelInfoColumnKey(primaryKey, key.TableChannelInfo.Column.Webhook), []byte(channelInfo.Webhook), wk.noSync); err != nil {
+		return err
+	}
+
+	// webhookEvents
+	if err = w.Set(key.NewChannelInfoColumnKey(primaryKey, key.TableChannelInfo.Column.WebhookEvents), []byte(strings.Join(channelInfo.WebhookEvents, ",")), wk.noSync); err != nil {
+		return err
+	}
+
 	// write index
 	if err = wk.writeChannelInfoBaseIndex(channelInfo, w); err != nil {
 		return err
@@ -720,6 +731,12 @@ func (wk *wukongDB) iterChannelInfo(iter *pebble.Iterator, iterFnc func(channelI
 				t := time.Unix(tm/1e9, tm%1e9)
 				preChannelInfo.UpdatedAt = &t
 			}
+		case key.TableChannelInfo.Column.Webhook:
+			preChannelInfo.Webhook = string(iter.Value())
+		case key.TableChannelInfo.Column.WebhookEvents:
+			if len(iter.Value()) > 0 {
+				preChannelInfo.WebhookEvents = strings.Split(string(iter.Value()), ",")
+			}
 		}
 		hasData = true
 	}
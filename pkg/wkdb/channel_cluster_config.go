@@ -165,22 +165,27 @@ func (wk *wukongDB) GetChannelClusterConfigVersion(channelId string, channelType
 	return binary.BigEndian.Uint64(result), nil
 }
 
-// func (wk *wukongDB) DeleteChannelClusterConfig(channelId string, channelType uint8) error {
+func (wk *wukongDB) DeleteChannelClusterConfig(channelId string, channelType uint8) error {
 
-// 	primaryKey := key.channelToNum(channelId, channelType)
+	wk.dblock.channelClusterConfig.lockByChannel(channelId, channelType)
+	defer wk.dblock.channelClusterConfig.unlockByChannel(channelId, channelType)
 
-// 	cfg, err := wk.getChannelClusterConfigById(primaryKey)
-// 	if err != nil {
-// 		return err
-// 	}
-// 	batch := wk.defaultShardDB().NewIndexedBatch()
-// 	defer batch.Close()
-// 	err = wk.deleteChannelClusterConfig(cfg, batch)
-// 	if err != nil {
-// 		return err
-// 	}
-// 	return batch.Commit(wk.sync)
-// }
+	primaryKey := key.ChannelToNum(channelId, channelType)
+
+	cfg, err := wk.getChannelClusterConfigById(primaryKey)
+	if err != nil {
+		if err == ErrNotFound {
+			return nil
+		}
+		return err
+	}
+	batch := wk.defaultShardBatchDB().NewBatch()
+	err = wk.deleteChannelClusterConfig(cfg, batch)
+	if err != nil {
+		return err
+	}
+	return batch.CommitWait()
+}
 
 func (wk *wukongDB) GetChannelClusterConfigs(offsetId uint64, limit int) ([]ChannelClusterConfig, error) {
 
@@ -364,19 +369,19 @@ func (wk *wukongDB) GetChannelClusterConfigWithSlotId(slotId uint32) ([]ChannelC
 	return results, nil
 }
 
-// func (wk *wukongDB) deleteChannelClusterConfig(channelClusterConfig ChannelClusterConfig, w *Batch) error {
+func (wk *wukongDB) deleteChannelClusterConfig(channelClusterConfig ChannelClusterConfig, w *Batch) error {
 
-// 	// delete channel cluster config
-// 	w.DeleteRange(key.NewChannelClusterConfigColumnKey(channelClusterConfig.Id, key.MinColumnKey), key.NewChannelClusterConfigColumnKey(channelClusterConfig.Id, key.MaxColumnKey))
+	// delete channel cluster config
+	w.DeleteRange(key.NewChannelClusterConfigColumnKey(channelClusterConfig.Id, key.MinColumnKey), key.NewChannelClusterConfigColumnKey(channelClusterConfig.Id, key.MaxColumnKey))
 
-// 	// delete index
-// 	err := wk.deleteChannelClusterConfigIndex(channelClusterConfig.Id, channelClusterConfig, w)
-// 	if err != nil {
-// 		return err
-// 	}
+	// delete index
+	err := wk.deleteChannelClusterConfigIndex(channelClusterConfig.Id, channelClusterConfig, w)
+	if err != nil {
+		return err
+	}
 
-// 	return nil
-// }
+	return nil
+}
 
 // func (wk *wukongDB) deleteChannelClusterConfigLeaderIndex(id uint64, w *pebble.Batch) error {
 
@@ -169,3 +169,39 @@ func TestGetChannelClusterConfigs(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, 1, len(configs))
 }
+
+func TestDeleteChannelClusterConfig(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel1"
+	channelType := uint8(1)
+
+	createdAt := time.Now()
+	updatedAt := time.Now()
+	config := wkdb.ChannelClusterConfig{
+		ChannelId:       channelId,
+		ChannelType:     channelType,
+		ReplicaMaxCount: 3,
+		Replicas:        []uint64{1, 2, 3},
+		LeaderId:        1001,
+		Term:            1,
+		CreatedAt:       &createdAt,
+		UpdatedAt:       &updatedAt,
+	}
+
+	err = d.SaveChannelClusterConfig(config)
+	assert.NoError(t, err)
+
+	err = d.DeleteChannelClusterConfig(channelId, channelType)
+	assert.NoError(t, err)
+
+	_, err = d.GetChannelClusterConfig(channelId, channelType)
+	assert.Equal(t, wkdb.ErrNotFound, err)
+}
@@ -0,0 +1,180 @@
+package wkdb
+
+import (
+	"math"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) AddOrUpdateChannelDisband(channelDisband ChannelDisband) error {
+
+	batch := wk.defaultShardBatchDB().NewBatch()
+
+	channelDisband.Id = key.ChannelToNum(channelDisband.ChannelId, channelDisband.ChannelType)
+
+	if err := wk.writeChannelDisband(batch, channelDisband); err != nil {
+		return err
+	}
+
+	return batch.CommitWait()
+}
+
+func (wk *wukongDB) GetChannelDisband(channelId string, channelType uint8) (ChannelDisband, error) {
+
+	db := wk.defaultShardDB()
+
+	id := key.ChannelToNum(channelId, channelType)
+
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewChannelDisbandColumnKey(id, key.MinColumnKey),
+		UpperBound: key.NewChannelDisbandColumnKey(id, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	var channelDisband ChannelDisband
+	err := wk.iteratorChannelDisband(iter, func(c ChannelDisband) bool {
+		channelDisband = c
+		return false
+	})
+	if err != nil {
+		return EmptyChannelDisband, err
+	}
+	if channelDisband.ChannelId == "" {
+		return EmptyChannelDisband, ErrNotFound
+	}
+	return channelDisband, nil
+}
+
+func (wk *wukongDB) GetChannelDisbands() ([]ChannelDisband, error) {
+
+	db := wk.defaultShardDB()
+
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewChannelDisbandColumnKey(0, key.MinColumnKey),
+		UpperBound: key.NewChannelDisbandColumnKey(math.MaxUint64, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	var channelDisbands []ChannelDisband
+	err := wk.iteratorChannelDisband(iter, func(c ChannelDisband) bool {
+		channelDisbands = append(channelDisbands, c)
+		return true
+	})
+	return channelDisbands, err
+}
+
+func (wk *wukongDB) RemoveChannelDisband(channelId string, channelType uint8) error {
+
+	batch := wk.defaultShardBatchDB().NewBatch()
+
+	id := key.ChannelToNum(channelId, channelType)
+
+	batch.DeleteRange(key.NewChannelDisbandColumnKey(id, key.MinColumnKey), key.NewChannelDisbandColumnKey(id, key.MaxColumnKey))
+
+	return batch.CommitWait()
+}
+
+func (wk *wukongDB) writeChannelDisband(w *Batch, channelDisband ChannelDisband) error {
+
+	w.Set(key.NewChannelDisbandColumnKey(channelDisband.Id, key.TableChannelDisband.Column.ChannelId), []byte(channelDisband.ChannelId))
+
+	w.Set(key.NewChannelDisbandColumnKey(channelDisband.Id, key.TableChannelDisband.Column.ChannelType), []byte{channelDisband.ChannelType})
+
+	w.Set(key.NewChannelDisbandColumnKey(channelDisband.Id, key.TableChannelDisband.Column.Status), []byte{uint8(channelDisband.Status)})
+
+	w.Set(key.NewChannelDisbandColumnKey(channelDisband.Id, key.TableChannelDisband.Column.Reason), []byte(channelDisband.Reason))
+
+	purge := uint8(0)
+	if channelDisband.Purge {
+		purge = 1
+	}
+	w.Set(key.NewChannelDisbandColumnKey(channelDisband.Id, key.TableChannelDisband.Column.Purge), []byte{purge})
+
+	if channelDisband.PurgeAt != nil {
+		var purgeAtBytes = make([]byte, 8)
+		wk.endian.PutUint64(purgeAtBytes, uint64(channelDisband.PurgeAt.UnixNano()))
+		w.Set(key.NewChannelDisbandColumnKey(channelDisband.Id, key.TableChannelDisband.Column.PurgeAt), purgeAtBytes)
+	}
+
+	if channelDisband.CreatedAt != nil {
+		var createdAtBytes = make([]byte, 8)
+		wk.endian.PutUint64(createdAtBytes, uint64(channelDisband.CreatedAt.UnixNano()))
+		w.Set(key.NewChannelDisbandColumnKey(channelDisband.Id, key.TableChannelDisband.Column.CreatedAt), createdAtBytes)
+	}
+
+	if channelDisband.UpdatedAt != nil {
+		var updatedAtBytes = make([]byte, 8)
+		wk.endian.PutUint64(updatedAtBytes, uint64(channelDisband.UpdatedAt.UnixNano()))
+		w.Set(key.NewChannelDisbandColumnKey(channelDisband.Id, key.TableChannelDisband.Column.UpdatedAt), updatedAtBytes)
+	}
+
+	return nil
+}
+
+func (wk *wukongDB) iteratorChannelDisband(iter *pebble.Iterator, iterFnc func(channelDisband ChannelDisband) bool) error {
+
+	var (
+		preId             uint64
+		preChannelDisband ChannelDisband
+		lastNeedAppend    bool = true
+		hasData           bool = false
+	)
+
+	for iter.First(); iter.Valid(); iter.Next() {
+		primaryKey, columnName, err := key.ParseChannelDisbandColumnKey(iter.Key())
+		if err != nil {
+			return err
+		}
+
+		if preId != primaryKey {
+			if preId != 0 {
+				if !iterFnc(preChannelDisband) {
+					lastNeedAppend = false
+					break
+				}
+			}
+			preId = primaryKey
+			preChannelDisband = ChannelDisband{Id: primaryKey}
+		}
+
+		switch columnName {
+		case key.TableChannelDisband.Column.ChannelId:
+			preChannelDisband.ChannelId = string(iter.Value())
+		case key.TableChannelDisband.Column.ChannelType:
+			preChannelDisband.ChannelType = iter.Value()[0]
+		case key.TableChannelDisband.Column.Status:
+			preChannelDisband.Status = ChannelDisbandStatus(iter.Value()[0])
+		case key.TableChannelDisband.Column.Reason:
+			preChannelDisband.Reason = string(iter.Value())
+		case key.TableChannelDisband.Column.Purge:
+			preChannelDisband.Purge = iter.Value()[0] == 1
+		case key.TableChannelDisband.Column.PurgeAt:
+			tm := int64(wk.endian.Uint64(iter.Value()))
+			if tm > 0 {
+				t := time.Unix(tm/1e9, tm%1e9)
+				preChannelDisband.PurgeAt = &t
+			}
+		case key.TableChannelDisband.Column.CreatedAt:
+			tm := int64(wk.endian.Uint64(iter.Value()))
+			if tm > 0 {
+				t := time.Unix(tm/1e9, tm%1e9)
+				preChannelDisband.CreatedAt = &t
+			}
+		case key.TableChannelDisband.Column.UpdatedAt:
+			tm := int64(wk.endian.Uint64(iter.Value()))
+			if tm > 0 {
+				t := time.Unix(tm/1e9, tm%1e9)
+				preChannelDisband.UpdatedAt = &t
+			}
+		}
+		lastNeedAppend = true
+		hasData = true
+	}
+
+	if lastNeedAppend && hasData {
+		_ = iterFnc(preChannelDisband)
+	}
+	return nil
+}
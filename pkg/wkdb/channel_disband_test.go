@@ -0,0 +1,68 @@
+package wkdb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChannelDisband(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	nw := time.Now()
+	purgeAt := nw.Add(time.Minute)
+	channelDisband := wkdb.ChannelDisband{
+		ChannelId:   "test",
+		ChannelType: 2,
+		Status:      wkdb.ChannelDisbandStatusNotified,
+		Reason:      "违规",
+		Purge:       true,
+		PurgeAt:     &purgeAt,
+		CreatedAt:   &nw,
+		UpdatedAt:   &nw,
+	}
+
+	t.Run("AddOrUpdateChannelDisband", func(t *testing.T) {
+		err := d.AddOrUpdateChannelDisband(channelDisband)
+		assert.NoError(t, err)
+	})
+
+	t.Run("GetChannelDisbands", func(t *testing.T) {
+		cc, err := d.GetChannelDisbands()
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(cc))
+	})
+
+	t.Run("GetChannelDisband", func(t *testing.T) {
+		c, err := d.GetChannelDisband(channelDisband.ChannelId, channelDisband.ChannelType)
+		assert.NoError(t, err)
+		assert.Equal(t, channelDisband.ChannelId, c.ChannelId)
+		assert.Equal(t, channelDisband.ChannelType, c.ChannelType)
+		assert.Equal(t, channelDisband.Status, c.Status)
+		assert.Equal(t, channelDisband.Reason, c.Reason)
+		assert.True(t, c.Purge)
+		assert.Equal(t, purgeAt.Unix(), c.PurgeAt.Unix())
+	})
+
+	t.Run("NeedPurge", func(t *testing.T) {
+		assert.False(t, channelDisband.NeedPurge(nw))
+		assert.True(t, channelDisband.NeedPurge(purgeAt))
+	})
+
+	t.Run("RemoveChannelDisband", func(t *testing.T) {
+		err := d.RemoveChannelDisband(channelDisband.ChannelId, channelDisband.ChannelType)
+		assert.NoError(t, err)
+
+		_, err = d.GetChannelDisband(channelDisband.ChannelId, channelDisband.ChannelType)
+		assert.Equal(t, wkdb.ErrNotFound, err)
+	})
+}
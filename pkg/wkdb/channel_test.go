@@ -19,13 +19,15 @@ func TestAddChannel(t *testing.T) {
 	}()
 	nw := time.Now()
 	channelInfo := wkdb.ChannelInfo{
-		ChannelId:   "channel1",
-		ChannelType: 1,
-		Ban:         true,
-		Large:       true,
-		Disband:     true,
-		CreatedAt:   &nw,
-		UpdatedAt:   &nw,
+		ChannelId:     "channel1",
+		ChannelType:   1,
+		Ban:           true,
+		Large:         true,
+		Disband:       true,
+		Webhook:       "http://127.0.0.1:8080/webhook",
+		WebhookEvents: []string{"msg.notify"},
+		CreatedAt:     &nw,
+		UpdatedAt:     &nw,
 	}
 	_, err = d.AddChannel(channelInfo)
 	assert.NoError(t, err)
@@ -38,6 +40,8 @@ func TestAddChannel(t *testing.T) {
 	assert.Equal(t, channelInfo.Ban, channelInfo2.Ban)
 	assert.Equal(t, channelInfo.Large, channelInfo2.Large)
 	assert.Equal(t, channelInfo.Disband, channelInfo2.Disband)
+	assert.Equal(t, channelInfo.Webhook, channelInfo2.Webhook)
+	assert.Equal(t, channelInfo.WebhookEvents, channelInfo2.WebhookEvents)
 	assert.Equal(t, channelInfo.CreatedAt.Unix(), channelInfo2.CreatedAt.Unix())
 	assert.Equal(t, channelInfo.UpdatedAt.Unix(), channelInfo2.UpdatedAt.Unix())
 }
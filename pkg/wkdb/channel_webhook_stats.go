@@ -0,0 +1,41 @@
+package wkdb
+
+import (
+	"math"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) SetChannelWebhookStats(stats []ChannelWebhookStats) error {
+	batch := wk.defaultShardDB().NewBatch()
+	defer batch.Close()
+	for _, st := range stats {
+		data, err := st.Marshal()
+		if err != nil {
+			return err
+		}
+		if err = batch.Set(key.NewChannelWebhookStatsKey(st.ChannelId, st.ChannelType), data, wk.noSync); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) GetChannelWebhookStats() ([]ChannelWebhookStats, error) {
+	iter := wk.defaultShardDB().NewIter(&pebble.IterOptions{
+		LowerBound: key.NewChannelWebhookStatsHashKey(0),
+		UpperBound: key.NewChannelWebhookStatsHashKey(math.MaxUint64),
+	})
+	defer iter.Close()
+
+	stats := make([]ChannelWebhookStats, 0)
+	for iter.First(); iter.Valid(); iter.Next() {
+		var st ChannelWebhookStats
+		if err := st.Unmarshal(iter.Value()); err != nil {
+			return nil, err
+		}
+		stats = append(stats, st)
+	}
+	return stats, nil
+}
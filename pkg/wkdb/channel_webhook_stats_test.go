@@ -0,0 +1,49 @@
+package wkdb_test
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChannelWebhookStats(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	stats, err := d.GetChannelWebhookStats()
+	assert.NoError(t, err)
+	assert.Len(t, stats, 0)
+
+	err = d.SetChannelWebhookStats([]wkdb.ChannelWebhookStats{
+		{ChannelId: "g1", ChannelType: 2, Webhook: "http://127.0.0.1/hook", SuccessCount: 1},
+		{ChannelId: "g2", ChannelType: 2, FailCount: 2, LastError: "timeout", LastFailAt: 100},
+	})
+	assert.NoError(t, err)
+
+	// 覆盖已存在的统计
+	err = d.SetChannelWebhookStats([]wkdb.ChannelWebhookStats{
+		{ChannelId: "g1", ChannelType: 2, Webhook: "http://127.0.0.1/hook", SuccessCount: 3, RetryCount: 1},
+	})
+	assert.NoError(t, err)
+
+	stats, err = d.GetChannelWebhookStats()
+	assert.NoError(t, err)
+	assert.Len(t, stats, 2)
+	statMap := make(map[string]wkdb.ChannelWebhookStats)
+	for _, st := range stats {
+		statMap[st.ChannelId] = st
+	}
+	assert.Equal(t, uint64(3), statMap["g1"].SuccessCount)
+	assert.Equal(t, uint64(1), statMap["g1"].RetryCount)
+	assert.Equal(t, "http://127.0.0.1/hook", statMap["g1"].Webhook)
+	assert.Equal(t, uint64(2), statMap["g2"].FailCount)
+	assert.Equal(t, "timeout", statMap["g2"].LastError)
+	assert.Equal(t, int64(100), statMap["g2"].LastFailAt)
+}
@@ -1,6 +1,10 @@
 package wkdb
 
-import "github.com/WuKongIM/WuKongIM/pkg/cluster/reactor"
+import (
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/reactor"
+)
 
 type DB interface {
 	Open() error
@@ -31,6 +35,23 @@ type DB interface {
 	StreamDB
 	// 测试机
 	TesterDB
+	// 临时频道
+	TmpChannelDB
+	// 频道解散记录
+	ChannelDisbandDB
+	// 用户在线状态
+	UserPresenceDB
+	UserBanDB
+	SessionLogDB
+	WebhookOutboxDB
+	WebhookDeadLetterDB
+	WebhookEndpointDB
+	EventSinkOffsetDB
+	ChannelWebhookStatsDB
+	// 子区回复统计
+	ThreadReplyDB
+	// mqtt保留消息
+	MQTTRetainDB
 }
 
 type MessageDB interface {
@@ -101,6 +122,12 @@ type DeviceDB interface {
 
 	// UpdateDevice 更新设备
 	UpdateDevice(device Device) error
+
+	// UpdateDeviceLogin 更新设备的登录信息（只更新LastLoginAt、LastLoginIP、ClientVersion、UserAgent）
+	UpdateDeviceLogin(device Device) error
+
+	// UpdateDeviceRevoke 更新设备的吊销时间（只更新RevokedAt）
+	UpdateDeviceRevoke(device Device) error
 }
 
 type UserDB interface {
@@ -248,7 +275,7 @@ type ChannelClusterConfigDB interface {
 	GetChannelClusterConfig(channelId string, channelType uint8) (ChannelClusterConfig, error)
 
 	// DeleteChannelClusterConfig 删除频道的分布式配置
-	// DeleteChannelClusterConfig(channelId string, channelType uint8) error
+	DeleteChannelClusterConfig(channelId string, channelType uint8) error
 
 	// GetChannelClusterConfigs 获取频道的分布式配置
 	GetChannelClusterConfigs(offsetId uint64, limit int) ([]ChannelClusterConfig, error)
@@ -393,6 +420,113 @@ type TesterDB interface {
 	RemoveTester(no string) error
 }
 
+type TmpChannelDB interface {
+
+	// AddOrUpdateTmpChannel 添加或更新临时频道
+	AddOrUpdateTmpChannel(tmpChannel TmpChannel) error
+
+	// GetTmpChannel 获取临时频道
+	GetTmpChannel(channelId string) (TmpChannel, error)
+
+	// GetTmpChannels 获取本节点的临时频道列表
+	GetTmpChannels() ([]TmpChannel, error)
+
+	// RemoveTmpChannel 移除临时频道
+	RemoveTmpChannel(channelId string) error
+}
+
+type ChannelDisbandDB interface {
+
+	// AddOrUpdateChannelDisband 添加或更新频道解散记录
+	AddOrUpdateChannelDisband(channelDisband ChannelDisband) error
+
+	// GetChannelDisband 获取频道解散记录
+	GetChannelDisband(channelId string, channelType uint8) (ChannelDisband, error)
+
+	// GetChannelDisbands 获取本节点的频道解散记录
+	GetChannelDisbands() ([]ChannelDisband, error)
+
+	// RemoveChannelDisband 移除频道解散记录
+	RemoveChannelDisband(channelId string, channelType uint8) error
+}
+
+type UserPresenceDB interface {
+
+	// UpdateUserLastSeen 更新用户最后在线时间
+	UpdateUserLastSeen(uid string, lastSeen time.Time) error
+
+	// UpdateUserStatus 更新用户设置的状态
+	UpdateUserStatus(uid string, status UserStatus, statusText string, updatedAt time.Time) error
+
+	// GetUserPresence 获取用户在线状态
+	GetUserPresence(uid string) (UserPresence, error)
+}
+
+type UserBanDB interface {
+
+	// AddOrUpdateUserBan 添加或更新用户封禁
+	AddOrUpdateUserBan(userBan UserBan) error
+
+	// GetUserBan 获取用户封禁
+	GetUserBan(uid string) (UserBan, error)
+
+	// GetUserBans 获取本节点的用户封禁
+	GetUserBans() ([]UserBan, error)
+
+	// RemoveUserBan 移除用户封禁
+	RemoveUserBan(uid string) error
+}
+
+type SessionLogDB interface {
+
+	// AddSessionLogs 添加用户会话审计日志
+	AddSessionLogs(logs []SessionLog) error
+
+	// GetSessionLogs 获取用户的会话审计日志（按时间倒序），startTime和endTime为unix纳秒，0表示不限制
+	GetSessionLogs(uid string, startTime, endTime int64, limit int) ([]SessionLog, error)
+
+	// RemoveSessionLogsBefore 移除指定时间之前（按天）的会话审计日志
+	RemoveSessionLogsBefore(t time.Time) error
+}
+
+// WebhookOutboxDB webhook事件发件箱（本节点）
+type WebhookOutboxDB interface {
+
+	// AppendWebhookEvents 添加事件到发件箱（已存在的相同id会被覆盖，用于更新重试信息）
+	AppendWebhookEvents(events []WebhookEvent) error
+
+	// GetWebhookEvents 按id顺序获取发件箱中id大于等于startId的事件
+	GetWebhookEvents(startId uint64, limit int) ([]WebhookEvent, error)
+
+	// RemoveWebhookEvents 从发件箱移除事件
+	RemoveWebhookEvents(ids []uint64) error
+
+	// GetWebhookOutboxMaxId 获取发件箱最大的事件id
+	GetWebhookOutboxMaxId() (uint64, error)
+}
+
+// WebhookDeadLetterDB webhook死信（本节点）
+type WebhookDeadLetterDB interface {
+
+	// AddWebhookDeadLetters 添加死信
+	AddWebhookDeadLetters(letters []WebhookDeadLetter) error
+
+	// GetWebhookDeadLetters 获取死信（按id倒序），event为空不过滤，offsetId大于0时只获取id小于offsetId的死信
+	GetWebhookDeadLetters(event string, offsetId uint64, limit int) ([]WebhookDeadLetter, error)
+
+	// GetWebhookDeadLetter 获取死信，不存在返回ErrNotFound
+	GetWebhookDeadLetter(id uint64) (WebhookDeadLetter, error)
+
+	// RemoveWebhookDeadLetters 移除死信
+	RemoveWebhookDeadLetters(ids []uint64) error
+
+	// RemoveWebhookDeadLettersBefore 移除id小于指定id（即指定时间之前）的死信
+	RemoveWebhookDeadLettersBefore(id uint64) error
+
+	// GetWebhookDeadLetterCount 获取死信数量
+	GetWebhookDeadLetterCount() (int, error)
+}
+
 type MessageSearchReq struct {
 	MessageId        int64
 	FromUid          string // 发送者uid
@@ -460,3 +594,56 @@ type ChannelClusterConfigSearchReq struct {
 	Pre             bool   // 是否向前搜索
 
 }
+
+// WebhookEndpointDB 运行时添加的webhook端点
+type WebhookEndpointDB interface {
+
+	// SaveWebhookEndpoint 添加或更新webhook端点
+	SaveWebhookEndpoint(endpoint WebhookEndpoint) error
+
+	// RemoveWebhookEndpoint 移除webhook端点
+	RemoveWebhookEndpoint(name string) error
+
+	// GetWebhookEndpoints 获取所有webhook端点（按名称排序）
+	GetWebhookEndpoints() ([]WebhookEndpoint, error)
+}
+
+// EventSinkOffsetDB 事件sink的偏移量（本节点）
+type EventSinkOffsetDB interface {
+
+	// SetEventSinkOffset 设置sink已分配的偏移量
+	SetEventSinkOffset(name string, offset uint64) error
+
+	// GetEventSinkOffset 获取sink已分配的偏移量（没有分配过返回0）
+	GetEventSinkOffset(name string) (uint64, error)
+}
+
+// ChannelWebhookStatsDB 频道webhook的推送统计（本节点）
+type ChannelWebhookStatsDB interface {
+
+	// SetChannelWebhookStats 保存频道webhook的推送统计（覆盖已存在的统计）
+	SetChannelWebhookStats(stats []ChannelWebhookStats) error
+
+	// GetChannelWebhookStats 获取本节点所有频道webhook的推送统计
+	GetChannelWebhookStats() ([]ChannelWebhookStats, error)
+}
+
+// ThreadReplyDB 子区根消息的回复统计（存储在父频道所在的槽上）
+type ThreadReplyDB interface {
+
+	// UpdateThreadReply 更新根消息的回复统计，回复数量没有增加时不更新
+	UpdateThreadReply(threadReply ThreadReply) error
+
+	// GetThreadReply 获取根消息的回复统计
+	GetThreadReply(rootMessageId int64) (ThreadReply, error)
+}
+
+// MQTTRetainDB mqtt主题（频道）的保留消息（存储在频道所在的槽上）
+type MQTTRetainDB interface {
+
+	// SetMQTTRetain 设置频道的保留消息，消息序号不大于已保存的序号时不更新（payload为空表示清除保留消息）
+	SetMQTTRetain(retain MQTTRetain) error
+
+	// GetMQTTRetain 获取频道的保留消息（没有或已清除返回ErrNotFound）
+	GetMQTTRetain(channelId string, channelType uint8) (MQTTRetain, error)
+}
@@ -162,6 +162,40 @@ func (wk *wukongDB) AddDevice(d Device) error {
 	return nil
 }
 
+func (wk *wukongDB) UpdateDeviceLogin(d Device) error {
+
+	wk.metrics.UpdateDeviceAdd(1)
+
+	if d.Id == 0 {
+		return ErrInvalidDeviceId
+	}
+	db := wk.shardDB(d.Uid)
+	batch := db.NewBatch()
+	defer batch.Close()
+	err := wk.writeDeviceLogin(d, batch)
+	if err != nil {
+		return err
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) UpdateDeviceRevoke(d Device) error {
+
+	wk.metrics.UpdateDeviceAdd(1)
+
+	if d.Id == 0 {
+		return ErrInvalidDeviceId
+	}
+	db := wk.shardDB(d.Uid)
+	batch := db.NewBatch()
+	defer batch.Close()
+	err := wk.writeDeviceRevoke(d, batch)
+	if err != nil {
+		return err
+	}
+	return batch.Commit(wk.sync)
+}
+
 func (wk *wukongDB) UpdateDevice(d Device) error {
 
 	wk.metrics.UpdateDeviceAdd(1)
@@ -397,6 +431,20 @@ func (wk *wukongDB) writeDevice(d Device, w pebble.Writer) error {
 		}
 	}
 
+	// 登录信息
+	if d.LastLoginAt != nil {
+		if err = wk.writeDeviceLogin(d, w); err != nil {
+			return err
+		}
+	}
+
+	// 吊销时间
+	if d.RevokedAt != nil {
+		if err = wk.writeDeviceRevoke(d, w); err != nil {
+			return err
+		}
+	}
+
 	// uid index
 	if err = w.Set(key.NewDeviceSecondIndexKey(key.TableDevice.SecondIndex.Uid, key.HashWithString(d.Uid), d.Id), nil, wk.noSync); err != nil {
 		return err
@@ -410,6 +458,44 @@ func (wk *wukongDB) writeDevice(d Device, w pebble.Writer) error {
 	return nil
 }
 
+func (wk *wukongDB) writeDeviceLogin(d Device, w pebble.Writer) error {
+	var (
+		err error
+	)
+	// lastLoginAt
+	lastLoginAt := make([]byte, 8)
+	if d.LastLoginAt != nil {
+		wk.endian.PutUint64(lastLoginAt, uint64(d.LastLoginAt.UnixNano()))
+	}
+	if err = w.Set(key.NewDeviceColumnKey(d.Id, key.TableDevice.Column.LastLoginAt), lastLoginAt, wk.noSync); err != nil {
+		return err
+	}
+
+	// lastLoginIP
+	if err = w.Set(key.NewDeviceColumnKey(d.Id, key.TableDevice.Column.LastLoginIP), []byte(d.LastLoginIP), wk.noSync); err != nil {
+		return err
+	}
+
+	// clientVersion
+	if err = w.Set(key.NewDeviceColumnKey(d.Id, key.TableDevice.Column.ClientVersion), []byte(d.ClientVersion), wk.noSync); err != nil {
+		return err
+	}
+
+	// userAgent
+	if err = w.Set(key.NewDeviceColumnKey(d.Id, key.TableDevice.Column.UserAgent), []byte(d.UserAgent), wk.noSync); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (wk *wukongDB) writeDeviceRevoke(d Device, w pebble.Writer) error {
+	revokedAt := make([]byte, 8)
+	if d.RevokedAt != nil {
+		wk.endian.PutUint64(revokedAt, uint64(d.RevokedAt.UnixNano()))
+	}
+	return w.Set(key.NewDeviceColumnKey(d.Id, key.TableDevice.Column.RevokedAt), revokedAt, wk.noSync)
+}
+
 func (wk *wukongDB) deleteDeviceIndex(old Device, w pebble.Writer) error {
 	// uid index
 	if err := w.Delete(key.NewDeviceSecondIndexKey(key.TableDevice.SecondIndex.Uid, key.HashWithString(old.Uid), old.Id), wk.noSync); err != nil {
@@ -487,6 +573,24 @@ func (wk *wukongDB) iterDevice(iter *pebble.Iterator, iterFnc func(d Device) boo
 				t := time.Unix(tm/1e9, tm%1e9)
 				preDevice.UpdatedAt = &t
 			}
+		case key.TableDevice.Column.LastLoginAt:
+			tm := int64(wk.endian.Uint64(iter.Value()))
+			if tm > 0 {
+				t := time.Unix(tm/1e9, tm%1e9)
+				preDevice.LastLoginAt = &t
+			}
+		case key.TableDevice.Column.LastLoginIP:
+			preDevice.LastLoginIP = string(iter.Value())
+		case key.TableDevice.Column.ClientVersion:
+			preDevice.ClientVersion = string(iter.Value())
+		case key.TableDevice.Column.UserAgent:
+			preDevice.UserAgent = string(iter.Value())
+		case key.TableDevice.Column.RevokedAt:
+			tm := int64(wk.endian.Uint64(iter.Value()))
+			if tm > 0 {
+				t := time.Unix(tm/1e9, tm%1e9)
+				preDevice.RevokedAt = &t
+			}
 
 		}
 		lastNeedAppend = true
@@ -62,6 +62,90 @@ func TestGetDevice(t *testing.T) {
 	assert.Equal(t, u.DeviceLevel, u2.DeviceLevel)
 }
 
+func TestUpdateDeviceLogin(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	u := wkdb.Device{
+		Id:          1,
+		Uid:         "test",
+		Token:       "token",
+		DeviceFlag:  2,
+		DeviceLevel: 1,
+	}
+	err = d.AddDevice(u)
+	assert.NoError(t, err)
+
+	lastLoginAt := time.Now()
+	err = d.UpdateDeviceLogin(wkdb.Device{
+		Id:            1,
+		Uid:           "test",
+		LastLoginAt:   &lastLoginAt,
+		LastLoginIP:   "127.0.0.1",
+		ClientVersion: "1.0.0",
+		UserAgent:     "test-agent",
+	})
+	assert.NoError(t, err)
+
+	// 更新token不影响登录信息
+	u.Token = "token2"
+	err = d.UpdateDevice(u)
+	assert.NoError(t, err)
+
+	u2, err := d.GetDevice("test", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, "token2", u2.Token)
+	assert.Equal(t, lastLoginAt.UnixNano(), u2.LastLoginAt.UnixNano())
+	assert.Equal(t, "127.0.0.1", u2.LastLoginIP)
+	assert.Equal(t, "1.0.0", u2.ClientVersion)
+	assert.Equal(t, "test-agent", u2.UserAgent)
+}
+
+func TestUpdateDeviceRevoke(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	u := wkdb.Device{
+		Id:          1,
+		Uid:         "test",
+		Token:       "token",
+		DeviceFlag:  2,
+		DeviceLevel: 1,
+	}
+	err = d.AddDevice(u)
+	assert.NoError(t, err)
+
+	revokedAt := time.Now()
+	err = d.UpdateDeviceRevoke(wkdb.Device{
+		Id:        1,
+		Uid:       "test",
+		RevokedAt: &revokedAt,
+	})
+	assert.NoError(t, err)
+
+	// 更新token不影响吊销时间
+	u.Token = "token2"
+	err = d.UpdateDevice(u)
+	assert.NoError(t, err)
+
+	u2, err := d.GetDevice("test", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, "token2", u2.Token)
+	assert.Equal(t, revokedAt.UnixNano(), u2.RevokedAt.UnixNano())
+}
+
 func TestGetDevices(t *testing.T) {
 	d := wkdb.NewWukongDB(wkdb.NewOptions(wkdb.WithDir(t.TempDir())))
 	err := d.Open()
@@ -0,0 +1,36 @@
+package wkdb
+
+import (
+	"encoding/binary"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) SetEventSinkOffset(name string, offset uint64) error {
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint64(value, offset)
+	batch := wk.defaultShardDB().NewBatch()
+	defer batch.Close()
+	if err := batch.Set(key.NewEventSinkOffsetKey(name), value, wk.noSync); err != nil {
+		return err
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) GetEventSinkOffset(name string) (uint64, error) {
+	value, closer, err := wk.defaultShardDB().Get(key.NewEventSinkOffsetKey(name))
+	if closer != nil {
+		defer closer.Close()
+	}
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return 0, nil
+		}
+		return 0, err
+	}
+	if len(value) < 8 {
+		return 0, nil
+	}
+	return binary.BigEndian.Uint64(value), nil
+}
@@ -0,0 +1,35 @@
+package wkdb_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventSinkOffset(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	offset, err := d.GetEventSinkOffset("file")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), offset)
+
+	err = d.SetEventSinkOffset("file", 10)
+	assert.NoError(t, err)
+	err = d.SetEventSinkOffset("redis", 3)
+	assert.NoError(t, err)
+
+	offset, err = d.GetEventSinkOffset("file")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), offset)
+
+	offset, err = d.GetEventSinkOffset("redis")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), offset)
+}
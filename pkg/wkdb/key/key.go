@@ -1002,3 +1002,232 @@ func ParseTesterColumnKey(key []byte) (id uint64, columnName [2]byte, err error)
 	columnName[1] = key[13]
 	return
 }
+
+// ---------------------- TmpChannel ----------------------
+
+func NewTmpChannelColumnKey(id uint64, columnName [2]byte) []byte {
+	key := make([]byte, TableTmpChannel.Size)
+	key[0] = TableTmpChannel.Id[0]
+	key[1] = TableTmpChannel.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], id)
+	key[12] = columnName[0]
+	key[13] = columnName[1]
+	return key
+}
+
+func ParseTmpChannelColumnKey(key []byte) (id uint64, columnName [2]byte, err error) {
+	if len(key) != TableTmpChannel.Size {
+		err = fmt.Errorf("tmpChannel: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	id = binary.BigEndian.Uint64(key[4:])
+	columnName[0] = key[12]
+	columnName[1] = key[13]
+	return
+}
+
+// ---------------------- ChannelDisband ----------------------
+
+func NewChannelDisbandColumnKey(id uint64, columnName [2]byte) []byte {
+	key := make([]byte, TableChannelDisband.Size)
+	key[0] = TableChannelDisband.Id[0]
+	key[1] = TableChannelDisband.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], id)
+	key[12] = columnName[0]
+	key[13] = columnName[1]
+	return key
+}
+
+func ParseChannelDisbandColumnKey(key []byte) (id uint64, columnName [2]byte, err error) {
+	if len(key) != TableChannelDisband.Size {
+		err = fmt.Errorf("channelDisband: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	id = binary.BigEndian.Uint64(key[4:])
+	columnName[0] = key[12]
+	columnName[1] = key[13]
+	return
+}
+
+// ---------------------- UserPresence ----------------------
+
+func NewUserPresenceColumnKey(id uint64, columnName [2]byte) []byte {
+	key := make([]byte, TableUserPresence.Size)
+	key[0] = TableUserPresence.Id[0]
+	key[1] = TableUserPresence.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], id)
+	key[12] = columnName[0]
+	key[13] = columnName[1]
+	return key
+}
+
+func ParseUserPresenceColumnKey(key []byte) (id uint64, columnName [2]byte, err error) {
+	if len(key) != TableUserPresence.Size {
+		err = fmt.Errorf("userPresence: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	id = binary.BigEndian.Uint64(key[4:])
+	columnName[0] = key[12]
+	columnName[1] = key[13]
+	return
+}
+
+// ---------------------- UserBan ----------------------
+
+func NewUserBanColumnKey(id uint64, columnName [2]byte) []byte {
+	key := make([]byte, TableUserBan.Size)
+	key[0] = TableUserBan.Id[0]
+	key[1] = TableUserBan.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], id)
+	key[12] = columnName[0]
+	key[13] = columnName[1]
+	return key
+}
+
+func ParseUserBanColumnKey(key []byte) (id uint64, columnName [2]byte, err error) {
+	if len(key) != TableUserBan.Size {
+		err = fmt.Errorf("userBan: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	id = binary.BigEndian.Uint64(key[4:])
+	columnName[0] = key[12]
+	columnName[1] = key[13]
+	return
+}
+
+// ---------------------- SessionLog ----------------------
+
+func NewSessionLogColumnKey(day uint32, uidHash uint64, id uint64, recordNodeId uint64, columnName [2]byte) []byte {
+	key := make([]byte, TableSessionLog.Size)
+	key[0] = TableSessionLog.Id[0]
+	key[1] = TableSessionLog.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint32(key[4:], day)
+	binary.BigEndian.PutUint64(key[8:], uidHash)
+	binary.BigEndian.PutUint64(key[16:], id)
+	binary.BigEndian.PutUint64(key[24:], recordNodeId)
+	key[32] = columnName[0]
+	key[33] = columnName[1]
+	return key
+}
+
+func ParseSessionLogColumnKey(key []byte) (day uint32, uidHash uint64, id uint64, recordNodeId uint64, columnName [2]byte, err error) {
+	if len(key) != TableSessionLog.Size {
+		err = fmt.Errorf("sessionLog: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	day = binary.BigEndian.Uint32(key[4:])
+	uidHash = binary.BigEndian.Uint64(key[8:])
+	id = binary.BigEndian.Uint64(key[16:])
+	recordNodeId = binary.BigEndian.Uint64(key[24:])
+	columnName[0] = key[32]
+	columnName[1] = key[33]
+	return
+}
+
+// ---------------------- WebhookOutbox ----------------------
+
+func NewWebhookOutboxKey(id uint64) []byte {
+	key := make([]byte, TableWebhookOutbox.Size)
+	key[0] = TableWebhookOutbox.Id[0]
+	key[1] = TableWebhookOutbox.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], id)
+	return key
+}
+
+func ParseWebhookOutboxKey(key []byte) (id uint64, err error) {
+	if len(key) != TableWebhookOutbox.Size {
+		err = fmt.Errorf("webhookOutbox: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	id = binary.BigEndian.Uint64(key[4:])
+	return
+}
+
+// ---------------------- WebhookDeadLetter ----------------------
+
+func NewWebhookDeadLetterKey(id uint64) []byte {
+	key := make([]byte, TableWebhookDeadLetter.Size)
+	key[0] = TableWebhookDeadLetter.Id[0]
+	key[1] = TableWebhookDeadLetter.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], id)
+	return key
+}
+
+// NewWebhookEndpointKey webhook端点的key
+func NewWebhookEndpointKey(name string) []byte {
+	return NewWebhookEndpointHashKey(HashWithString(name))
+}
+
+// NewWebhookEndpointHashKey 按名称hash生成webhook端点的key（用于范围查询）
+func NewWebhookEndpointHashKey(nameHash uint64) []byte {
+	key := make([]byte, TableWebhookEndpoint.Size)
+	key[0] = TableWebhookEndpoint.Id[0]
+	key[1] = TableWebhookEndpoint.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], nameHash)
+	return key
+}
+
+// NewEventSinkOffsetKey 事件sink偏移量的key
+func NewEventSinkOffsetKey(name string) []byte {
+	key := make([]byte, TableEventSinkOffset.Size)
+	key[0] = TableEventSinkOffset.Id[0]
+	key[1] = TableEventSinkOffset.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], HashWithString(name))
+	return key
+}
+
+// NewChannelWebhookStatsKey 频道webhook推送统计的key
+func NewChannelWebhookStatsKey(channelId string, channelType uint8) []byte {
+	return NewChannelWebhookStatsHashKey(ChannelToNum(channelId, channelType))
+}
+
+// NewChannelWebhookStatsHashKey 按频道hash生成频道webhook推送统计的key（用于范围查询）
+func NewChannelWebhookStatsHashKey(channelHash uint64) []byte {
+	key := make([]byte, TableChannelWebhookStats.Size)
+	key[0] = TableChannelWebhookStats.Id[0]
+	key[1] = TableChannelWebhookStats.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelHash)
+	return key
+}
+
+// NewThreadReplyKey 子区根消息回复统计的key
+func NewThreadReplyKey(rootMessageId int64) []byte {
+	key := make([]byte, TableThreadReply.Size)
+	key[0] = TableThreadReply.Id[0]
+	key[1] = TableThreadReply.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], uint64(rootMessageId))
+	return key
+}
+
+// NewMQTTRetainKey mqtt保留消息的key
+func NewMQTTRetainKey(channelId string, channelType uint8) []byte {
+	key := make([]byte, TableMQTTRetain.Size)
+	key[0] = TableMQTTRetain.Id[0]
+	key[1] = TableMQTTRetain.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], ChannelToNum(channelId, channelType))
+	return key
+}
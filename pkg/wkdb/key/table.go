@@ -183,6 +183,13 @@ var TableDevice = struct {
 		DeviceLevel [2]byte // 设备等级
 		CreatedAt   [2]byte // 创建时间
 		UpdatedAt   [2]byte // 更新时间
+
+		LastLoginAt   [2]byte // 最后登录时间
+		LastLoginIP   [2]byte // 最后登录ip
+		ClientVersion [2]byte // 客户端版本
+		UserAgent     [2]byte // 客户端UserAgent
+
+		RevokedAt [2]byte // 吊销时间
 	}
 	SecondIndex struct {
 		Uid         [2]byte
@@ -203,6 +210,13 @@ var TableDevice = struct {
 		DeviceLevel [2]byte
 		CreatedAt   [2]byte
 		UpdatedAt   [2]byte
+
+		LastLoginAt   [2]byte
+		LastLoginIP   [2]byte
+		ClientVersion [2]byte
+		UserAgent     [2]byte
+
+		RevokedAt [2]byte
 	}{
 		Uid:         [2]byte{0x03, 0x01},
 		Token:       [2]byte{0x03, 0x02},
@@ -210,6 +224,13 @@ var TableDevice = struct {
 		DeviceLevel: [2]byte{0x03, 0x04},
 		CreatedAt:   [2]byte{0x03, 0x05},
 		UpdatedAt:   [2]byte{0x03, 0x06},
+
+		LastLoginAt:   [2]byte{0x03, 0x07},
+		LastLoginIP:   [2]byte{0x03, 0x08},
+		ClientVersion: [2]byte{0x03, 0x09},
+		UserAgent:     [2]byte{0x03, 0x0A},
+
+		RevokedAt: [2]byte{0x03, 0x0B},
 	},
 	SecondIndex: struct {
 		Uid         [2]byte
@@ -313,6 +334,8 @@ var TableChannelInfo = struct {
 		DenylistCount   [2]byte // 黑名单数量
 		CreatedAt       [2]byte
 		UpdatedAt       [2]byte
+		Webhook         [2]byte // 频道webhook地址
+		WebhookEvents   [2]byte // 频道webhook关注的事件
 	}
 	Index struct {
 		Channel [2]byte
@@ -343,6 +366,8 @@ var TableChannelInfo = struct {
 		DenylistCount   [2]byte
 		CreatedAt       [2]byte
 		UpdatedAt       [2]byte
+		Webhook         [2]byte
+		WebhookEvents   [2]byte
 	}{
 		Id:              [2]byte{0x06, 0x01},
 		ChannelId:       [2]byte{0x06, 0x02},
@@ -355,6 +380,8 @@ var TableChannelInfo = struct {
 		DenylistCount:   [2]byte{0x06, 0x09},
 		CreatedAt:       [2]byte{0x06, 0x0A},
 		UpdatedAt:       [2]byte{0x06, 0x0B},
+		Webhook:         [2]byte{0x06, 0x0C},
+		WebhookEvents:   [2]byte{0x06, 0x0D},
 	},
 	Index: struct {
 		Channel [2]byte
@@ -795,3 +822,286 @@ var TableTester = struct {
 		UpdatedAt: [2]byte{0x14, 0x04},
 	},
 }
+
+// ======================== TableTmpChannel ========================
+
+// 临时频道表
+var TableTmpChannel = struct {
+	Id     [2]byte
+	Size   int
+	Column struct {
+		ChannelId [2]byte // 临时频道ID
+		Ttl       [2]byte // 创建后的存活时间（秒）
+		IdleTtl   [2]byte // 闲置存活时间（秒）
+		CreatedAt [2]byte // 创建时间
+		UpdatedAt [2]byte // 更新时间
+	}
+}{
+	Id:   [2]byte{0x15, 0x01},
+	Size: 2 + 2 + 8 + 2, // tableId + dataType  + primaryKey + columnKey
+	Column: struct {
+		ChannelId [2]byte
+		Ttl       [2]byte
+		IdleTtl   [2]byte
+		CreatedAt [2]byte
+		UpdatedAt [2]byte
+	}{
+		ChannelId: [2]byte{0x15, 0x01},
+		Ttl:       [2]byte{0x15, 0x02},
+		IdleTtl:   [2]byte{0x15, 0x03},
+		CreatedAt: [2]byte{0x15, 0x04},
+		UpdatedAt: [2]byte{0x15, 0x05},
+	},
+}
+
+// ======================== TableChannelDisband ========================
+
+// 频道解散记录表
+var TableChannelDisband = struct {
+	Id     [2]byte
+	Size   int
+	Column struct {
+		ChannelId   [2]byte // 频道ID
+		ChannelType [2]byte // 频道类型
+		Status      [2]byte // 解散进度
+		Reason      [2]byte // 解散原因
+		Purge       [2]byte // 是否清除频道消息
+		PurgeAt     [2]byte // 清除频道消息的时间
+		CreatedAt   [2]byte // 创建时间
+		UpdatedAt   [2]byte // 更新时间
+	}
+}{
+	Id:   [2]byte{0x16, 0x01},
+	Size: 2 + 2 + 8 + 2, // tableId + dataType  + primaryKey + columnKey
+	Column: struct {
+		ChannelId   [2]byte
+		ChannelType [2]byte
+		Status      [2]byte
+		Reason      [2]byte
+		Purge       [2]byte
+		PurgeAt     [2]byte
+		CreatedAt   [2]byte
+		UpdatedAt   [2]byte
+	}{
+		ChannelId:   [2]byte{0x16, 0x01},
+		ChannelType: [2]byte{0x16, 0x02},
+		Status:      [2]byte{0x16, 0x03},
+		Reason:      [2]byte{0x16, 0x04},
+		Purge:       [2]byte{0x16, 0x05},
+		PurgeAt:     [2]byte{0x16, 0x06},
+		CreatedAt:   [2]byte{0x16, 0x07},
+		UpdatedAt:   [2]byte{0x16, 0x08},
+	},
+}
+
+// ======================== TableUserPresence ========================
+
+// 用户在线状态表（最后在线时间和用户自定义状态）
+var TableUserPresence = struct {
+	Id     [2]byte
+	Size   int
+	Column struct {
+		Uid        [2]byte // 用户uid
+		LastSeen   [2]byte // 最后在线时间（最后一次断开连接的时间）
+		Status     [2]byte // 用户设置的状态
+		StatusText [2]byte // 用户设置的状态文本
+		UpdatedAt  [2]byte // 状态更新时间
+	}
+}{
+	Id:   [2]byte{0x17, 0x01},
+	Size: 2 + 2 + 8 + 2, // tableId + dataType  + primaryKey + columnKey
+	Column: struct {
+		Uid        [2]byte
+		LastSeen   [2]byte
+		Status     [2]byte
+		StatusText [2]byte
+		UpdatedAt  [2]byte
+	}{
+		Uid:        [2]byte{0x17, 0x01},
+		LastSeen:   [2]byte{0x17, 0x02},
+		Status:     [2]byte{0x17, 0x03},
+		StatusText: [2]byte{0x17, 0x04},
+		UpdatedAt:  [2]byte{0x17, 0x05},
+	},
+}
+
+// ======================== TableUserBan ========================
+
+// 用户封禁表
+var TableUserBan = struct {
+	Id     [2]byte
+	Size   int
+	Column struct {
+		Uid       [2]byte // 用户uid
+		Reason    [2]byte // 封禁原因
+		ExpireAt  [2]byte // 封禁到期时间（为空表示永久封禁）
+		CreatedAt [2]byte // 封禁时间
+	}
+}{
+	Id:   [2]byte{0x18, 0x01},
+	Size: 2 + 2 + 8 + 2, // tableId + dataType  + primaryKey + columnKey
+	Column: struct {
+		Uid       [2]byte
+		Reason    [2]byte
+		ExpireAt  [2]byte
+		CreatedAt [2]byte
+	}{
+		Uid:       [2]byte{0x18, 0x01},
+		Reason:    [2]byte{0x18, 0x02},
+		ExpireAt:  [2]byte{0x18, 0x03},
+		CreatedAt: [2]byte{0x18, 0x04},
+	},
+}
+
+// ======================== TableSessionLog ========================
+// ---------------------
+// | tableID  | dataType | day    | uid hash | id     | record node id | columnKey |
+// | 2 byte   | 2 byte   | 4 字节  | 8 字节   | 8 字节  | 8 字节          | 2 字节     |
+// ---------------------
+// 按天分区，过期的日志按天范围删除。日志id只在记录日志的节点内唯一，所以key中加上记录日志的节点id
+
+// 用户会话审计日志表
+var TableSessionLog = struct {
+	Id     [2]byte
+	Size   int
+	Column struct {
+		Uid        [2]byte // 用户uid
+		Event      [2]byte // 事件类型
+		NodeId     [2]byte // 连接所在的节点
+		DeviceFlag [2]byte // 设备标记
+		DeviceId   [2]byte // 设备id
+		Ip         [2]byte // 客户端ip
+		ReasonCode [2]byte // 原因码
+		Reason     [2]byte // 原因
+		CreatedAt  [2]byte // 事件时间
+	}
+}{
+	Id:   [2]byte{0x19, 0x01},
+	Size: 2 + 2 + 4 + 8 + 8 + 8 + 2, // tableId + dataType + day + uid hash + id + record node id + columnKey
+	Column: struct {
+		Uid        [2]byte
+		Event      [2]byte
+		NodeId     [2]byte
+		DeviceFlag [2]byte
+		DeviceId   [2]byte
+		Ip         [2]byte
+		ReasonCode [2]byte
+		Reason     [2]byte
+		CreatedAt  [2]byte
+	}{
+		Uid:        [2]byte{0x19, 0x01},
+		Event:      [2]byte{0x19, 0x02},
+		NodeId:     [2]byte{0x19, 0x03},
+		DeviceFlag: [2]byte{0x19, 0x04},
+		DeviceId:   [2]byte{0x19, 0x05},
+		Ip:         [2]byte{0x19, 0x06},
+		ReasonCode: [2]byte{0x19, 0x07},
+		Reason:     [2]byte{0x19, 0x08},
+		CreatedAt:  [2]byte{0x19, 0x09},
+	},
+}
+
+// ======================== WebhookOutbox ========================
+// ---------------------
+// | tableID  | dataType | id     |
+// | 2 byte   | 2 byte   | 8 字节  |
+// ---------------------
+// 本节点的webhook事件发件箱，按id顺序投递
+
+var TableWebhookOutbox = struct {
+	Id   [2]byte
+	Size int
+}{
+	Id:   [2]byte{0x1A, 0x01},
+	Size: 2 + 2 + 8, // tableId + dataType + id
+}
+
+// ======================== WebhookDeadLetter ========================
+// ---------------------
+// | tableID  | dataType | id     |
+// | 2 byte   | 2 byte   | 8 字节  |
+// ---------------------
+// 本节点投递失败超过最大重试次数的webhook事件，id为进入死信的纳秒时间
+
+var TableWebhookDeadLetter = struct {
+	Id   [2]byte
+	Size int
+}{
+	Id:   [2]byte{0x1B, 0x01},
+	Size: 2 + 2 + 8, // tableId + dataType + id
+}
+
+// ======================== WebhookEndpoint ========================
+// ---------------------
+// | tableID  | dataType | name hash |
+// | 2 byte   | 2 byte   | 8 字节     |
+// ---------------------
+// 运行时添加的webhook端点（存储在slot 0上）
+
+var TableWebhookEndpoint = struct {
+	Id   [2]byte
+	Size int
+}{
+	Id:   [2]byte{0x1C, 0x01},
+	Size: 2 + 2 + 8, // tableId + dataType + name hash
+}
+
+// ======================== EventSinkOffset ========================
+// ---------------------
+// | tableID  | dataType | name hash |
+// | 2 byte   | 2 byte   | 8 字节     |
+// ---------------------
+// 事件sink已写入的偏移量（本节点）
+
+var TableEventSinkOffset = struct {
+	Id   [2]byte
+	Size int
+}{
+	Id:   [2]byte{0x1D, 0x01},
+	Size: 2 + 2 + 8, // tableId + dataType + name hash
+}
+
+// ======================== ThreadReply ========================
+// ---------------------
+// | tableID  | dataType | rootMessageId |
+// | 2 byte   | 2 byte   | 8 字节         |
+// ---------------------
+// 子区根消息的回复统计（存储在父频道所在的槽上）
+
+var TableThreadReply = struct {
+	Id   [2]byte
+	Size int
+}{
+	Id:   [2]byte{0x1E, 0x01},
+	Size: 2 + 2 + 8, // tableId + dataType + rootMessageId
+}
+
+// ======================== ChannelWebhookStats ========================
+// ---------------------
+// | tableID  | dataType | channel hash |
+// | 2 byte   | 2 byte   | 8 字节        |
+// ---------------------
+// 频道webhook的推送统计（本节点）
+
+var TableChannelWebhookStats = struct {
+	Id   [2]byte
+	Size int
+}{
+	Id:   [2]byte{0x1F, 0x01},
+	Size: 2 + 2 + 8, // tableId + dataType + channel hash
+}
+
+// ======================== MQTTRetain ========================
+// ---------------------
+// | tableID  | dataType | channel hash |
+// | 2 byte   | 2 byte   | 8 字节        |
+// ---------------------
+// mqtt主题（频道）的保留消息（存储在频道所在的槽上）
+
+var TableMQTTRetain = struct {
+	Id   [2]byte
+	Size int
+}{
+	Id:   [2]byte{0x20, 0x01},
+	Size: 2 + 2 + 8, // tableId + dataType + channel hash
+}
@@ -87,6 +87,13 @@ type Device struct {
 	RecvMsgBytes uint64     `json:"recv_msg_bytes,omitempty"` // 接收消息字节数
 	CreatedAt    *time.Time `json:"created_at,omitempty"`     // 创建时间
 	UpdatedAt    *time.Time `json:"updated_at,omitempty"`     // 更新时间
+
+	LastLoginAt   *time.Time `json:"last_login_at,omitempty"`  // 最后登录时间
+	LastLoginIP   string     `json:"last_login_ip,omitempty"`  // 最后登录ip
+	ClientVersion string     `json:"client_version,omitempty"` // 客户端版本
+	UserAgent     string     `json:"user_agent,omitempty"`     // 客户端UserAgent
+
+	RevokedAt *time.Time `json:"revoked_at,omitempty"` // 吊销时间（在此之前签发的jwt token不能再连接）
 }
 
 var EmptyUser = User{}
@@ -124,6 +131,7 @@ type ChannelInfo struct {
 	LastMsgSeq      uint64     `json:"last_msg_seq,omitempty"`     // 最新消息序号
 	LastMsgTime     uint64     `json:"last_msg_time,omitempty"`    // 最后一次消息时间
 	Webhook         string     `json:"webhook,omitempty"`          // webhook地址
+	WebhookEvents   []string   `json:"webhook_events,omitempty"`   // webhook关注的事件，为空表示关注所有频道事件
 	CreatedAt       *time.Time `json:"created_at,omitempty"`       // 创建时间
 	UpdatedAt       *time.Time `json:"updated_at,omitempty"`       // 更新时间
 }
@@ -819,3 +827,568 @@ func (t *Tester) Unmarshal(data []byte) error {
 	}
 	return nil
 }
+
+var EmptyTmpChannel = TmpChannel{}
+
+// TmpChannel 临时频道
+type TmpChannel struct {
+	Id        uint64
+	ChannelId string     // 临时频道ID
+	Ttl       uint64     // 创建后的存活时间（秒），0表示不限制
+	IdleTtl   uint64     // 闲置（没有新消息）的存活时间（秒），0表示不限制
+	CreatedAt *time.Time // 创建时间
+	UpdatedAt *time.Time // 更新时间
+}
+
+// Expired 临时频道是否已过期，lastMsgTime为频道最后一条消息的时间（纳秒）
+func (t TmpChannel) Expired(now time.Time, lastMsgTime uint64) bool {
+	if t.CreatedAt == nil {
+		return false
+	}
+	if t.Ttl > 0 && now.Sub(*t.CreatedAt) >= time.Duration(t.Ttl)*time.Second {
+		return true
+	}
+	if t.IdleTtl > 0 {
+		lastActive := *t.CreatedAt
+		if lastMsgTime > 0 {
+			msgTime := time.Unix(0, int64(lastMsgTime))
+			if msgTime.After(lastActive) {
+				lastActive = msgTime
+			}
+		}
+		if now.Sub(lastActive) >= time.Duration(t.IdleTtl)*time.Second {
+			return true
+		}
+	}
+	return false
+}
+
+var EmptyChannelDisband = ChannelDisband{}
+
+// ChannelDisbandStatus 频道解散进度
+type ChannelDisbandStatus uint8
+
+const (
+	ChannelDisbandStatusNone     ChannelDisbandStatus = iota
+	ChannelDisbandStatusFrozen                        // 频道已冻结（不能再发送消息）
+	ChannelDisbandStatusNotified                      // 已通知频道成员
+	ChannelDisbandStatusPurged                        // 频道消息和成员已清除
+)
+
+func (c ChannelDisbandStatus) String() string {
+	switch c {
+	case ChannelDisbandStatusFrozen:
+		return "frozen"
+	case ChannelDisbandStatusNotified:
+		return "notified"
+	case ChannelDisbandStatusPurged:
+		return "purged"
+	}
+	return "none"
+}
+
+// ChannelDisband 频道解散记录
+type ChannelDisband struct {
+	Id          uint64
+	ChannelId   string               // 频道ID
+	ChannelType uint8                // 频道类型
+	Status      ChannelDisbandStatus // 解散进度
+	Reason      string               // 解散原因
+	Purge       bool                 // 是否在宽限期后清除频道消息和成员
+	PurgeAt     *time.Time           // 清除频道消息的时间
+	CreatedAt   *time.Time           // 创建时间
+	UpdatedAt   *time.Time           // 更新时间
+}
+
+// NeedPurge 是否到了清除频道消息的时间
+func (c ChannelDisband) NeedPurge(now time.Time) bool {
+	if !c.Purge || c.Status != ChannelDisbandStatusNotified || c.PurgeAt == nil {
+		return false
+	}
+	return !now.Before(*c.PurgeAt)
+}
+
+var EmptyUserPresence = UserPresence{}
+
+// UserStatus 用户设置的状态
+type UserStatus uint8
+
+const (
+	UserStatusAvailable UserStatus = iota // 在线
+	UserStatusAway                        // 离开
+	UserStatusBusy                        // 忙碌
+)
+
+func (u UserStatus) String() string {
+	switch u {
+	case UserStatusAvailable:
+		return "available"
+	case UserStatusAway:
+		return "away"
+	case UserStatusBusy:
+		return "busy"
+	}
+	return "unknown"
+}
+
+// UserPresence 用户在线状态
+type UserPresence struct {
+	Id         uint64
+	Uid        string     // 用户uid
+	LastSeen   *time.Time // 最后在线时间（最后一次断开连接的时间）
+	Status     UserStatus // 用户设置的状态
+	StatusText string     // 用户设置的状态文本
+	UpdatedAt  *time.Time // 状态更新时间
+}
+
+var EmptyUserBan = UserBan{}
+
+// UserBan 用户封禁
+type UserBan struct {
+	Id        uint64
+	Uid       string     // 用户uid
+	Reason    string     // 封禁原因
+	ExpireAt  *time.Time // 封禁到期时间，为空表示永久封禁
+	CreatedAt *time.Time // 封禁时间
+}
+
+// Expired 封禁是否已到期
+func (u UserBan) Expired(now time.Time) bool {
+	if u.ExpireAt == nil {
+		return false
+	}
+	return !now.Before(*u.ExpireAt)
+}
+
+// 会话审计事件类型
+const (
+	SessionEventConnect    = "connect"    // 连接认证成功
+	SessionEventAuthFail   = "auth_fail"  // 连接认证失败
+	SessionEventKick       = "kick"       // 连接被踢
+	SessionEventDisconnect = "disconnect" // 连接断开
+)
+
+var EmptySessionLog = SessionLog{}
+
+// SessionLog 用户会话审计日志
+type SessionLog struct {
+	Id           uint64     // 日志id，按时间递增（为0则取CreatedAt的纳秒时间），只在记录日志的节点内唯一
+	RecordNodeId uint64     // 记录日志的节点（与日志id一起唯一标识一条日志）
+	Uid          string     // 用户uid
+	Event        string     // 事件类型
+	NodeId       uint64     // 连接所在的节点
+	DeviceFlag   uint64     // 设备标记
+	DeviceId     string     // 设备id
+	Ip           string     // 客户端ip
+	ReasonCode   uint8      // 原因码（connack或disconnect的reason code）
+	Reason       string     // 原因
+	CreatedAt    *time.Time // 事件时间
+}
+
+var EmptyWebhookEvent = WebhookEvent{}
+
+// WebhookEvent webhook发件箱中待投递的事件
+type WebhookEvent struct {
+	Id          uint64 // 事件id，本节点递增，决定投递顺序
+	Endpoint    string // 投递的webhook端点名称，为空表示全局配置的webhook
+	Event       string // 事件类型
+	Data        []byte // 事件数据（json）
+	DeliveryId  string // 投递id，重试和重启后不变
+	OrderKey    string // 顺序key，同一个key的事件按id顺序串行投递，为空不保证顺序
+	RetryCount  uint32 // 已重试次数
+	NextRetryAt int64  // 下次重试时间（unix纳秒），0表示立即投递
+	LastError   string // 最后一次投递的错误
+	CreatedAt   int64  // 创建时间（unix纳秒）
+	SinkOffset  uint64 // 事件sink的偏移量，写入发件箱时分配，重试时不变（非sink事件为0）
+}
+
+func (w *WebhookEvent) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint64(w.Id)
+	enc.WriteString(w.Endpoint)
+	enc.WriteString(w.Event)
+	enc.WriteString(w.DeliveryId)
+	enc.WriteString(w.OrderKey)
+	enc.WriteUint32(w.RetryCount)
+	enc.WriteInt64(w.NextRetryAt)
+	enc.WriteString(w.LastError)
+	enc.WriteInt64(w.CreatedAt)
+	enc.WriteUint64(w.SinkOffset)
+	enc.WriteBytes(w.Data) // 数据可能超过WriteBinary的长度限制，放在最后
+	return enc.Bytes(), nil
+}
+
+func (w *WebhookEvent) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if w.Id, err = dec.Uint64(); err != nil {
+		return err
+	}
+	if w.Endpoint, err = dec.String(); err != nil {
+		return err
+	}
+	if w.Event, err = dec.String(); err != nil {
+		return err
+	}
+	if w.DeliveryId, err = dec.String(); err != nil {
+		return err
+	}
+	if w.OrderKey, err = dec.String(); err != nil {
+		return err
+	}
+	if w.RetryCount, err = dec.Uint32(); err != nil {
+		return err
+	}
+	if w.NextRetryAt, err = dec.Int64(); err != nil {
+		return err
+	}
+	if w.LastError, err = dec.String(); err != nil {
+		return err
+	}
+	if w.CreatedAt, err = dec.Int64(); err != nil {
+		return err
+	}
+	if w.SinkOffset, err = dec.Uint64(); err != nil {
+		return err
+	}
+	var body []byte
+	if body, err = dec.BinaryAll(); err != nil {
+		return err
+	}
+	w.Data = append([]byte(nil), body...) // 拷贝，数据可能来自迭代器的缓存
+	return nil
+}
+
+var EmptyWebhookDeadLetter = WebhookDeadLetter{}
+
+func IsEmptyWebhookDeadLetter(d WebhookDeadLetter) bool {
+	return d.Id == 0
+}
+
+// WebhookDeadLetter 投递失败超过最大重试次数的webhook事件（死信）
+type WebhookDeadLetter struct {
+	Id         uint64 // 死信id，为进入死信的纳秒时间（本节点递增）
+	Endpoint   string // 投递的webhook端点名称，为空表示全局配置的webhook
+	Event      string // 事件类型
+	Data       []byte // 事件数据（json）
+	DeliveryId string // 投递id（重放时不变）
+	OrderKey   string // 顺序key
+	Attempts   uint32 // 已投递的次数
+	LastError  string // 最后一次投递的错误
+	CreatedAt  int64  // 事件创建时间（unix纳秒）
+}
+
+func (d *WebhookDeadLetter) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint64(d.Id)
+	enc.WriteString(d.Endpoint)
+	enc.WriteString(d.Event)
+	enc.WriteString(d.DeliveryId)
+	enc.WriteString(d.OrderKey)
+	enc.WriteUint32(d.Attempts)
+	enc.WriteString(d.LastError)
+	enc.WriteInt64(d.CreatedAt)
+	enc.WriteBytes(d.Data) // 数据可能超过WriteBinary的长度限制，放在最后
+	return enc.Bytes(), nil
+}
+
+func (d *WebhookDeadLetter) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if d.Id, err = dec.Uint64(); err != nil {
+		return err
+	}
+	if d.Endpoint, err = dec.String(); err != nil {
+		return err
+	}
+	if d.Event, err = dec.String(); err != nil {
+		return err
+	}
+	if d.DeliveryId, err = dec.String(); err != nil {
+		return err
+	}
+	if d.OrderKey, err = dec.String(); err != nil {
+		return err
+	}
+	if d.Attempts, err = dec.Uint32(); err != nil {
+		return err
+	}
+	if d.LastError, err = dec.String(); err != nil {
+		return err
+	}
+	if d.CreatedAt, err = dec.Int64(); err != nil {
+		return err
+	}
+	var body []byte
+	if body, err = dec.BinaryAll(); err != nil {
+		return err
+	}
+	d.Data = append([]byte(nil), body...) // 拷贝，数据可能来自迭代器的缓存
+	return nil
+}
+
+var EmptyWebhookEndpoint = WebhookEndpoint{}
+
+func IsEmptyWebhookEndpoint(e WebhookEndpoint) bool {
+	return e.Name == ""
+}
+
+// WebhookEndpoint 运行时添加的webhook端点（集群共享）
+type WebhookEndpoint struct {
+	Name         string   // 端点名称（唯一）
+	HTTPAddr     string   // http地址
+	GRPCAddr     string   // grpc地址，不为空则使用grpc投递
+	Events       []string // 关注的事件，为空表示所有事件
+	ChannelTypes []uint8  // 关注的频道类型，为空表示所有频道类型（只对频道相关的事件生效）
+	BatchSize    uint32   // msg.notify每次推送的最大消息数量
+	Secret       string   // 签名密钥，为空不签名
+	CreatedAt    int64    // 创建时间（unix秒）
+	UpdatedAt    int64    // 更新时间（unix秒）
+}
+
+func (e *WebhookEndpoint) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteString(e.Name)
+	enc.WriteString(e.HTTPAddr)
+	enc.WriteString(e.GRPCAddr)
+	enc.WriteUint16(uint16(len(e.Events)))
+	for _, event := range e.Events {
+		enc.WriteString(event)
+	}
+	enc.WriteUint16(uint16(len(e.ChannelTypes)))
+	for _, channelType := range e.ChannelTypes {
+		enc.WriteUint8(channelType)
+	}
+	enc.WriteUint32(e.BatchSize)
+	enc.WriteString(e.Secret)
+	enc.WriteInt64(e.CreatedAt)
+	enc.WriteInt64(e.UpdatedAt)
+	return enc.Bytes(), nil
+}
+
+func (e *WebhookEndpoint) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if e.Name, err = dec.String(); err != nil {
+		return err
+	}
+	if e.HTTPAddr, err = dec.String(); err != nil {
+		return err
+	}
+	if e.GRPCAddr, err = dec.String(); err != nil {
+		return err
+	}
+	var count uint16
+	if count, err = dec.Uint16(); err != nil {
+		return err
+	}
+	e.Events = nil
+	for i := 0; i < int(count); i++ {
+		var event string
+		if event, err = dec.String(); err != nil {
+			return err
+		}
+		e.Events = append(e.Events, event)
+	}
+	if count, err = dec.Uint16(); err != nil {
+		return err
+	}
+	e.ChannelTypes = nil
+	for i := 0; i < int(count); i++ {
+		var channelType uint8
+		if channelType, err = dec.Uint8(); err != nil {
+			return err
+		}
+		e.ChannelTypes = append(e.ChannelTypes, channelType)
+	}
+	if e.BatchSize, err = dec.Uint32(); err != nil {
+		return err
+	}
+	if e.Secret, err = dec.String(); err != nil {
+		return err
+	}
+	if e.CreatedAt, err = dec.Int64(); err != nil {
+		return err
+	}
+	if e.UpdatedAt, err = dec.Int64(); err != nil {
+		return err
+	}
+	return nil
+}
+
+var EmptyThreadReply = ThreadReply{}
+
+// ThreadReply 子区根消息的回复统计
+type ThreadReply struct {
+	RootMessageId       int64  // 根消息ID
+	ChannelId           string // 父频道ID
+	ChannelType         uint8  // 父频道类型
+	ReplyCount          uint64 // 回复数量（子区频道的最大消息序号）
+	LastReplyMessageId  int64  // 最后一条回复的消息ID
+	LastReplyMessageSeq uint64 // 最后一条回复的消息序号
+	LastReplyFromUid    string // 最后一条回复的发送者
+	LastReplyAt         int64  // 最后一条回复的时间（unix秒）
+}
+
+func (t *ThreadReply) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteInt64(t.RootMessageId)
+	enc.WriteString(t.ChannelId)
+	enc.WriteUint8(t.ChannelType)
+	enc.WriteUint64(t.ReplyCount)
+	enc.WriteInt64(t.LastReplyMessageId)
+	enc.WriteUint64(t.LastReplyMessageSeq)
+	enc.WriteString(t.LastReplyFromUid)
+	enc.WriteInt64(t.LastReplyAt)
+	return enc.Bytes(), nil
+}
+
+func (t *ThreadReply) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if t.RootMessageId, err = dec.Int64(); err != nil {
+		return err
+	}
+	if t.ChannelId, err = dec.String(); err != nil {
+		return err
+	}
+	if t.ChannelType, err = dec.Uint8(); err != nil {
+		return err
+	}
+	if t.ReplyCount, err = dec.Uint64(); err != nil {
+		return err
+	}
+	if t.LastReplyMessageId, err = dec.Int64(); err != nil {
+		return err
+	}
+	if t.LastReplyMessageSeq, err = dec.Uint64(); err != nil {
+		return err
+	}
+	if t.LastReplyFromUid, err = dec.String(); err != nil {
+		return err
+	}
+	if t.LastReplyAt, err = dec.Int64(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ChannelWebhookStats 频道webhook的推送统计
+type ChannelWebhookStats struct {
+	ChannelId     string // 频道ID
+	ChannelType   uint8  // 频道类型
+	Webhook       string // 频道webhook地址
+	SuccessCount  uint64 // 推送成功次数
+	RetryCount    uint64 // 重试次数
+	FailCount     uint64 // 超过最大重试次数移入死信的次数
+	LastError     string // 最后一次错误
+	LastSuccessAt int64  // 最后一次成功时间（unix秒）
+	LastFailAt    int64  // 最后一次失败时间（unix秒）
+}
+
+func (c *ChannelWebhookStats) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteString(c.ChannelId)
+	enc.WriteUint8(c.ChannelType)
+	enc.WriteString(c.Webhook)
+	enc.WriteUint64(c.SuccessCount)
+	enc.WriteUint64(c.RetryCount)
+	enc.WriteUint64(c.FailCount)
+	enc.WriteString(c.LastError)
+	enc.WriteInt64(c.LastSuccessAt)
+	enc.WriteInt64(c.LastFailAt)
+	return enc.Bytes(), nil
+}
+
+func (c *ChannelWebhookStats) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if c.ChannelId, err = dec.String(); err != nil {
+		return err
+	}
+	if c.ChannelType, err = dec.Uint8(); err != nil {
+		return err
+	}
+	if c.Webhook, err = dec.String(); err != nil {
+		return err
+	}
+	if c.SuccessCount, err = dec.Uint64(); err != nil {
+		return err
+	}
+	if c.RetryCount, err = dec.Uint64(); err != nil {
+		return err
+	}
+	if c.FailCount, err = dec.Uint64(); err != nil {
+		return err
+	}
+	if c.LastError, err = dec.String(); err != nil {
+		return err
+	}
+	if c.LastSuccessAt, err = dec.Int64(); err != nil {
+		return err
+	}
+	if c.LastFailAt, err = dec.Int64(); err != nil {
+		return err
+	}
+	return nil
+}
+
+var EmptyMQTTRetain = MQTTRetain{}
+
+// MQTTRetain mqtt主题（频道）的保留消息
+type MQTTRetain struct {
+	ChannelId   string // 频道ID（个人频道为fake频道ID）
+	ChannelType uint8  // 频道类型
+	MessageId   int64  // 消息ID
+	MessageSeq  uint64 // 消息序号，只保留序号最大的消息
+	FromUid     string // 发送者
+	Timestamp   int32  // 消息时间（unix秒）
+	Payload     []byte // 消息内容，为空表示清除保留消息
+}
+
+func (m *MQTTRetain) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteString(m.ChannelId)
+	enc.WriteUint8(m.ChannelType)
+	enc.WriteInt64(m.MessageId)
+	enc.WriteUint64(m.MessageSeq)
+	enc.WriteString(m.FromUid)
+	enc.WriteInt32(m.Timestamp)
+	enc.WriteBytes(m.Payload) // 放在最后
+	return enc.Bytes(), nil
+}
+
+func (m *MQTTRetain) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if m.ChannelId, err = dec.String(); err != nil {
+		return err
+	}
+	if m.ChannelType, err = dec.Uint8(); err != nil {
+		return err
+	}
+	if m.MessageId, err = dec.Int64(); err != nil {
+		return err
+	}
+	if m.MessageSeq, err = dec.Uint64(); err != nil {
+		return err
+	}
+	if m.FromUid, err = dec.String(); err != nil {
+		return err
+	}
+	if m.Timestamp, err = dec.Int32(); err != nil {
+		return err
+	}
+	var payload []byte
+	if payload, err = dec.BinaryAll(); err != nil {
+		return err
+	}
+	m.Payload = append([]byte(nil), payload...)
+	return nil
+}
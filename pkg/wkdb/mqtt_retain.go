@@ -0,0 +1,57 @@
+package wkdb
+
+import (
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) SetMQTTRetain(retain MQTTRetain) error {
+	old, err := wk.getMQTTRetain(retain.ChannelId, retain.ChannelType)
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+	if err == nil && old.MessageSeq >= retain.MessageSeq { // 重复或乱序的更新忽略
+		return nil
+	}
+	data, err := retain.Marshal()
+	if err != nil {
+		return err
+	}
+	batch := wk.shardDB(retain.ChannelId).NewBatch()
+	defer batch.Close()
+	if err = batch.Set(key.NewMQTTRetainKey(retain.ChannelId, retain.ChannelType), data, wk.noSync); err != nil {
+		return err
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) GetMQTTRetain(channelId string, channelType uint8) (MQTTRetain, error) {
+	retain, err := wk.getMQTTRetain(channelId, channelType)
+	if err != nil {
+		return EmptyMQTTRetain, err
+	}
+	if len(retain.Payload) == 0 { // 已清除
+		return EmptyMQTTRetain, ErrNotFound
+	}
+	return retain, nil
+}
+
+// 获取保存的保留消息（包括已清除的记录）
+func (wk *wukongDB) getMQTTRetain(channelId string, channelType uint8) (MQTTRetain, error) {
+	value, closer, err := wk.shardDB(channelId).Get(key.NewMQTTRetainKey(channelId, channelType))
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return EmptyMQTTRetain, ErrNotFound
+		}
+		return EmptyMQTTRetain, err
+	}
+	defer closer.Close()
+	var retain MQTTRetain
+	if err = retain.Unmarshal(value); err != nil {
+		return EmptyMQTTRetain, err
+	}
+	if retain.ChannelId != channelId || retain.ChannelType != channelType { // hash冲突
+		return EmptyMQTTRetain, ErrNotFound
+	}
+	return retain, nil
+}
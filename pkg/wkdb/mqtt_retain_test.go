@@ -0,0 +1,46 @@
+package wkdb_test
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMQTTRetain(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	_, err = d.GetMQTTRetain("g1", 2)
+	assert.Equal(t, wkdb.ErrNotFound, err)
+
+	err = d.SetMQTTRetain(wkdb.MQTTRetain{ChannelId: "g1", ChannelType: 2, MessageId: 1002, MessageSeq: 2, FromUid: "u1", Timestamp: 100, Payload: []byte("on")})
+	assert.NoError(t, err)
+
+	// 序号更小的更新忽略
+	err = d.SetMQTTRetain(wkdb.MQTTRetain{ChannelId: "g1", ChannelType: 2, MessageId: 1001, MessageSeq: 1, FromUid: "u1", Payload: []byte("off")})
+	assert.NoError(t, err)
+
+	retain, err := d.GetMQTTRetain("g1", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1002), retain.MessageId)
+	assert.Equal(t, uint64(2), retain.MessageSeq)
+	assert.Equal(t, "u1", retain.FromUid)
+	assert.Equal(t, int32(100), retain.Timestamp)
+	assert.Equal(t, []byte("on"), retain.Payload)
+
+	_, err = d.GetMQTTRetain("g1", 1)
+	assert.Equal(t, wkdb.ErrNotFound, err)
+
+	// payload为空清除保留消息
+	err = d.SetMQTTRetain(wkdb.MQTTRetain{ChannelId: "g1", ChannelType: 2, MessageId: 1003, MessageSeq: 3, FromUid: "u1"})
+	assert.NoError(t, err)
+	_, err = d.GetMQTTRetain("g1", 2)
+	assert.Equal(t, wkdb.ErrNotFound, err)
+}
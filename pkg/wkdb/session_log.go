@@ -0,0 +1,244 @@
+package wkdb
+
+import (
+	"math"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) AddSessionLogs(logs []SessionLog) error {
+
+	batches := make(map[*pebble.DB]*pebble.Batch)
+	defer func() {
+		for _, batch := range batches {
+			_ = batch.Close()
+		}
+	}()
+
+	for _, log := range logs {
+		db := wk.shardDB(log.Uid)
+		batch := batches[db]
+		if batch == nil {
+			batch = db.NewBatch()
+			batches[db] = batch
+		}
+		if err := wk.writeSessionLog(batch, log); err != nil {
+			return err
+		}
+	}
+	for _, batch := range batches {
+		if err := batch.Commit(wk.sync); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (wk *wukongDB) GetSessionLogs(uid string, startTime, endTime int64, limit int) ([]SessionLog, error) {
+
+	db := wk.shardDB(uid)
+	uidHash := key.HashWithString(uid)
+
+	if endTime <= 0 {
+		endTime = time.Now().UnixNano()
+	}
+	endDay := sessionLogDay(endTime)
+
+	// 最早的分区
+	minDay, ok, err := wk.minSessionLogDay(db)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, nil
+	}
+	startDay := minDay
+	if startTime > 0 && sessionLogDay(startTime) > startDay {
+		startDay = sessionLogDay(startTime)
+	}
+
+	logs := make([]SessionLog, 0)
+	for day := int64(endDay); day >= int64(startDay); day-- {
+		iter := db.NewIter(&pebble.IterOptions{
+			LowerBound: key.NewSessionLogColumnKey(uint32(day), uidHash, 0, 0, key.MinColumnKey),
+			UpperBound: key.NewSessionLogColumnKey(uint32(day), uidHash, math.MaxUint64, math.MaxUint64, key.MaxColumnKey),
+		})
+		err := wk.iteratorSessionLogReverse(iter, func(log SessionLog) bool {
+			if log.Uid != uid { // uid hash冲突
+				return true
+			}
+			if log.Id > uint64(endTime) {
+				return true
+			}
+			if startTime > 0 && log.Id < uint64(startTime) {
+				return false
+			}
+			logs = append(logs, log)
+			return limit <= 0 || len(logs) < limit
+		})
+		_ = iter.Close()
+		if err != nil {
+			return nil, err
+		}
+		if limit > 0 && len(logs) >= limit {
+			break
+		}
+	}
+	return logs, nil
+}
+
+func (wk *wukongDB) RemoveSessionLogsBefore(t time.Time) error {
+
+	day := sessionLogDay(t.UnixNano())
+	for _, db := range wk.dbs {
+		batch := db.NewBatch()
+		err := batch.DeleteRange(key.NewSessionLogColumnKey(0, 0, 0, 0, key.MinColumnKey), key.NewSessionLogColumnKey(day, 0, 0, 0, key.MinColumnKey), wk.noSync)
+		if err != nil {
+			_ = batch.Close()
+			return err
+		}
+		err = batch.Commit(wk.sync)
+		_ = batch.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 最早的分区（天）
+func (wk *wukongDB) minSessionLogDay(db *pebble.DB) (uint32, bool, error) {
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewSessionLogColumnKey(0, 0, 0, 0, key.MinColumnKey),
+		UpperBound: key.NewSessionLogColumnKey(math.MaxUint32, math.MaxUint64, math.MaxUint64, math.MaxUint64, key.MaxColumnKey),
+	})
+	defer iter.Close()
+	if !iter.First() {
+		return 0, false, nil
+	}
+	day, _, _, _, _, err := key.ParseSessionLogColumnKey(iter.Key())
+	if err != nil {
+		return 0, false, err
+	}
+	return day, true, nil
+}
+
+func (wk *wukongDB) writeSessionLog(w pebble.Writer, log SessionLog) error {
+	if log.CreatedAt == nil {
+		now := time.Now()
+		log.CreatedAt = &now
+	}
+	if log.Id == 0 {
+		log.Id = uint64(log.CreatedAt.UnixNano())
+	}
+	day := sessionLogDay(int64(log.Id))
+	uidHash := key.HashWithString(log.Uid)
+
+	var err error
+	if err = w.Set(key.NewSessionLogColumnKey(day, uidHash, log.Id, log.RecordNodeId, key.TableSessionLog.Column.Uid), []byte(log.Uid), wk.noSync); err != nil {
+		return err
+	}
+	if err = w.Set(key.NewSessionLogColumnKey(day, uidHash, log.Id, log.RecordNodeId, key.TableSessionLog.Column.Event), []byte(log.Event), wk.noSync); err != nil {
+		return err
+	}
+	nodeIdBytes := make([]byte, 8)
+	wk.endian.PutUint64(nodeIdBytes, log.NodeId)
+	if err = w.Set(key.NewSessionLogColumnKey(day, uidHash, log.Id, log.RecordNodeId, key.TableSessionLog.Column.NodeId), nodeIdBytes, wk.noSync); err != nil {
+		return err
+	}
+	deviceFlagBytes := make([]byte, 8)
+	wk.endian.PutUint64(deviceFlagBytes, log.DeviceFlag)
+	if err = w.Set(key.NewSessionLogColumnKey(day, uidHash, log.Id, log.RecordNodeId, key.TableSessionLog.Column.DeviceFlag), deviceFlagBytes, wk.noSync); err != nil {
+		return err
+	}
+	if err = w.Set(key.NewSessionLogColumnKey(day, uidHash, log.Id, log.RecordNodeId, key.TableSessionLog.Column.DeviceId), []byte(log.DeviceId), wk.noSync); err != nil {
+		return err
+	}
+	if err = w.Set(key.NewSessionLogColumnKey(day, uidHash, log.Id, log.RecordNodeId, key.TableSessionLog.Column.Ip), []byte(log.Ip), wk.noSync); err != nil {
+		return err
+	}
+	if err = w.Set(key.NewSessionLogColumnKey(day, uidHash, log.Id, log.RecordNodeId, key.TableSessionLog.Column.ReasonCode), []byte{log.ReasonCode}, wk.noSync); err != nil {
+		return err
+	}
+	if err = w.Set(key.NewSessionLogColumnKey(day, uidHash, log.Id, log.RecordNodeId, key.TableSessionLog.Column.Reason), []byte(log.Reason), wk.noSync); err != nil {
+		return err
+	}
+	createdAtBytes := make([]byte, 8)
+	wk.endian.PutUint64(createdAtBytes, uint64(log.CreatedAt.UnixNano()))
+	if err = w.Set(key.NewSessionLogColumnKey(day, uidHash, log.Id, log.RecordNodeId, key.TableSessionLog.Column.CreatedAt), createdAtBytes, wk.noSync); err != nil {
+		return err
+	}
+	return nil
+}
+
+// 倒序遍历会话审计日志
+func (wk *wukongDB) iteratorSessionLogReverse(iter *pebble.Iterator, iterFnc func(log SessionLog) bool) error {
+
+	var (
+		preId          uint64
+		preNodeId      uint64
+		preLog         SessionLog
+		lastNeedAppend bool = true
+		hasData        bool = false
+	)
+
+	for iter.Last(); iter.Valid(); iter.Prev() {
+		_, _, id, recordNodeId, columnName, err := key.ParseSessionLogColumnKey(iter.Key())
+		if err != nil {
+			return err
+		}
+
+		if preId != id || preNodeId != recordNodeId {
+			if preId != 0 {
+				if !iterFnc(preLog) {
+					lastNeedAppend = false
+					break
+				}
+			}
+			preId = id
+			preNodeId = recordNodeId
+			preLog = SessionLog{Id: id, RecordNodeId: recordNodeId}
+		}
+
+		switch columnName {
+		case key.TableSessionLog.Column.Uid:
+			preLog.Uid = string(iter.Value())
+		case key.TableSessionLog.Column.Event:
+			preLog.Event = string(iter.Value())
+		case key.TableSessionLog.Column.NodeId:
+			preLog.NodeId = wk.endian.Uint64(iter.Value())
+		case key.TableSessionLog.Column.DeviceFlag:
+			preLog.DeviceFlag = wk.endian.Uint64(iter.Value())
+		case key.TableSessionLog.Column.DeviceId:
+			preLog.DeviceId = string(iter.Value())
+		case key.TableSessionLog.Column.Ip:
+			preLog.Ip = string(iter.Value())
+		case key.TableSessionLog.Column.ReasonCode:
+			if len(iter.Value()) > 0 {
+				preLog.ReasonCode = iter.Value()[0]
+			}
+		case key.TableSessionLog.Column.Reason:
+			preLog.Reason = string(iter.Value())
+		case key.TableSessionLog.Column.CreatedAt:
+			tm := int64(wk.endian.Uint64(iter.Value()))
+			if tm > 0 {
+				t := time.Unix(tm/1e9, tm%1e9)
+				preLog.CreatedAt = &t
+			}
+		}
+		lastNeedAppend = true
+		hasData = true
+	}
+
+	if lastNeedAppend && hasData {
+		_ = iterFnc(preLog)
+	}
+	return nil
+}
+
+// 日志所在的分区（天）
+func sessionLogDay(unixNano int64) uint32 {
+	return uint32(unixNano / int64(time.Hour*24))
+}
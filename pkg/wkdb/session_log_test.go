@@ -0,0 +1,88 @@
+package wkdb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionLog(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	nw := time.Now()
+	twoDaysAgo := nw.Add(-time.Hour * 48)
+	oneHourAgo := nw.Add(-time.Hour)
+	logs := []wkdb.SessionLog{
+		{Uid: "u1", Event: wkdb.SessionEventConnect, NodeId: 1, DeviceFlag: 1, DeviceId: "d1", Ip: "127.0.0.1", ReasonCode: 1, CreatedAt: &twoDaysAgo},
+		{Uid: "u1", Event: wkdb.SessionEventKick, NodeId: 1, DeviceFlag: 1, DeviceId: "d1", Ip: "127.0.0.1", Reason: "banned", CreatedAt: &oneHourAgo},
+		{Uid: "u1", Event: wkdb.SessionEventAuthFail, NodeId: 2, DeviceFlag: 0, DeviceId: "d2", Ip: "10.0.0.1", ReasonCode: 2, CreatedAt: &nw},
+		{Uid: "u2", Event: wkdb.SessionEventConnect, NodeId: 1, CreatedAt: &nw},
+	}
+
+	t.Run("AddSessionLogs", func(t *testing.T) {
+		err := d.AddSessionLogs(logs)
+		assert.NoError(t, err)
+	})
+
+	t.Run("GetSessionLogs", func(t *testing.T) {
+		result, err := d.GetSessionLogs("u1", 0, 0, 0)
+		assert.NoError(t, err)
+		assert.Len(t, result, 3)
+
+		// 按时间倒序
+		assert.Equal(t, wkdb.SessionEventAuthFail, result[0].Event)
+		assert.Equal(t, uint64(2), result[0].NodeId)
+		assert.Equal(t, "d2", result[0].DeviceId)
+		assert.Equal(t, "10.0.0.1", result[0].Ip)
+		assert.Equal(t, uint8(2), result[0].ReasonCode)
+		assert.Equal(t, nw.UnixNano(), result[0].CreatedAt.UnixNano())
+		assert.Equal(t, "banned", result[1].Reason)
+		assert.Equal(t, wkdb.SessionEventConnect, result[2].Event)
+
+		// limit
+		result, err = d.GetSessionLogs("u1", 0, 0, 2)
+		assert.NoError(t, err)
+		assert.Len(t, result, 2)
+
+		// 时间范围
+		result, err = d.GetSessionLogs("u1", nw.Add(-time.Hour*2).UnixNano(), nw.Add(-time.Minute).UnixNano(), 0)
+		assert.NoError(t, err)
+		assert.Len(t, result, 1)
+		assert.Equal(t, wkdb.SessionEventKick, result[0].Event)
+	})
+
+	t.Run("RemoveSessionLogsBefore", func(t *testing.T) {
+		err := d.RemoveSessionLogsBefore(nw.Add(-time.Hour * 24))
+		assert.NoError(t, err)
+
+		result, err := d.GetSessionLogs("u1", 0, 0, 0)
+		assert.NoError(t, err)
+		assert.Len(t, result, 2)
+	})
+
+	t.Run("SameIdOnDifferentNodes", func(t *testing.T) {
+		// 不同节点记录的日志id相同时不会相互覆盖
+		err := d.AddSessionLogs([]wkdb.SessionLog{
+			{Id: uint64(nw.UnixNano()), RecordNodeId: 1, Uid: "u3", Event: wkdb.SessionEventConnect, NodeId: 1, CreatedAt: &nw},
+			{Id: uint64(nw.UnixNano()), RecordNodeId: 2, Uid: "u3", Event: wkdb.SessionEventDisconnect, NodeId: 2, CreatedAt: &nw},
+		})
+		assert.NoError(t, err)
+
+		result, err := d.GetSessionLogs("u3", 0, 0, 0)
+		assert.NoError(t, err)
+		assert.Len(t, result, 2)
+		assert.Equal(t, uint64(2), result[0].RecordNodeId)
+		assert.Equal(t, wkdb.SessionEventDisconnect, result[0].Event)
+		assert.Equal(t, uint64(1), result[1].RecordNodeId)
+		assert.Equal(t, wkdb.SessionEventConnect, result[1].Event)
+	})
+}
@@ -0,0 +1,47 @@
+package wkdb
+
+import (
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) UpdateThreadReply(threadReply ThreadReply) error {
+	old, err := wk.GetThreadReply(threadReply.RootMessageId)
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+	if err == nil && old.ReplyCount >= threadReply.ReplyCount { // 统计只增不减（重复或乱序的更新忽略）
+		return nil
+	}
+	data, err := threadReply.Marshal()
+	if err != nil {
+		return err
+	}
+	batch := wk.shardDB(threadReply.ChannelId).NewBatch()
+	defer batch.Close()
+	if err = batch.Set(key.NewThreadReplyKey(threadReply.RootMessageId), data, wk.noSync); err != nil {
+		return err
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) GetThreadReply(rootMessageId int64) (ThreadReply, error) {
+	threadKey := key.NewThreadReplyKey(rootMessageId)
+	for _, db := range wk.dbs {
+		value, closer, err := db.Get(threadKey)
+		if err != nil {
+			if err == pebble.ErrNotFound {
+				continue
+			}
+			return EmptyThreadReply, err
+		}
+		var threadReply ThreadReply
+		err = threadReply.Unmarshal(value)
+		closer.Close()
+		if err != nil {
+			return EmptyThreadReply, err
+		}
+		return threadReply, nil
+	}
+	return EmptyThreadReply, ErrNotFound
+}
@@ -0,0 +1,48 @@
+package wkdb_test
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThreadReply(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	_, err = d.GetThreadReply(100)
+	assert.Equal(t, wkdb.ErrNotFound, err)
+
+	err = d.UpdateThreadReply(wkdb.ThreadReply{
+		RootMessageId:       100,
+		ChannelId:           "g1",
+		ChannelType:         2,
+		ReplyCount:          2,
+		LastReplyMessageId:  1002,
+		LastReplyMessageSeq: 2,
+		LastReplyFromUid:    "u2",
+		LastReplyAt:         1700000000,
+	})
+	assert.NoError(t, err)
+
+	// 回复数量没有增加的更新被忽略
+	err = d.UpdateThreadReply(wkdb.ThreadReply{RootMessageId: 100, ChannelId: "g1", ChannelType: 2, ReplyCount: 1, LastReplyMessageId: 1001})
+	assert.NoError(t, err)
+
+	threadReply, err := d.GetThreadReply(100)
+	assert.NoError(t, err)
+	assert.Equal(t, "g1", threadReply.ChannelId)
+	assert.Equal(t, uint8(2), threadReply.ChannelType)
+	assert.Equal(t, uint64(2), threadReply.ReplyCount)
+	assert.Equal(t, int64(1002), threadReply.LastReplyMessageId)
+	assert.Equal(t, uint64(2), threadReply.LastReplyMessageSeq)
+	assert.Equal(t, "u2", threadReply.LastReplyFromUid)
+	assert.Equal(t, int64(1700000000), threadReply.LastReplyAt)
+}
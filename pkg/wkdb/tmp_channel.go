@@ -0,0 +1,162 @@
+package wkdb
+
+import (
+	"math"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) AddOrUpdateTmpChannel(tmpChannel TmpChannel) error {
+
+	batch := wk.defaultShardBatchDB().NewBatch()
+
+	tmpChannel.Id = key.HashWithString(tmpChannel.ChannelId)
+
+	if err := wk.writeTmpChannel(batch, tmpChannel); err != nil {
+		return err
+	}
+
+	return batch.CommitWait()
+}
+
+func (wk *wukongDB) GetTmpChannel(channelId string) (TmpChannel, error) {
+
+	db := wk.defaultShardDB()
+
+	id := key.HashWithString(channelId)
+
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewTmpChannelColumnKey(id, key.MinColumnKey),
+		UpperBound: key.NewTmpChannelColumnKey(id, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	var tmpChannel TmpChannel
+	err := wk.iteratorTmpChannel(iter, func(t TmpChannel) bool {
+		tmpChannel = t
+		return false
+	})
+	if err != nil {
+		return EmptyTmpChannel, err
+	}
+	if tmpChannel.ChannelId == "" {
+		return EmptyTmpChannel, ErrNotFound
+	}
+	return tmpChannel, nil
+}
+
+func (wk *wukongDB) GetTmpChannels() ([]TmpChannel, error) {
+
+	db := wk.defaultShardDB()
+
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewTmpChannelColumnKey(0, key.MinColumnKey),
+		UpperBound: key.NewTmpChannelColumnKey(math.MaxUint64, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	var tmpChannels []TmpChannel
+	err := wk.iteratorTmpChannel(iter, func(t TmpChannel) bool {
+		tmpChannels = append(tmpChannels, t)
+		return true
+	})
+	return tmpChannels, err
+}
+
+func (wk *wukongDB) RemoveTmpChannel(channelId string) error {
+
+	batch := wk.defaultShardBatchDB().NewBatch()
+
+	id := key.HashWithString(channelId)
+
+	batch.DeleteRange(key.NewTmpChannelColumnKey(id, key.MinColumnKey), key.NewTmpChannelColumnKey(id, key.MaxColumnKey))
+
+	return batch.CommitWait()
+}
+
+func (wk *wukongDB) writeTmpChannel(w *Batch, tmpChannel TmpChannel) error {
+
+	w.Set(key.NewTmpChannelColumnKey(tmpChannel.Id, key.TableTmpChannel.Column.ChannelId), []byte(tmpChannel.ChannelId))
+
+	var ttlBytes = make([]byte, 8)
+	wk.endian.PutUint64(ttlBytes, tmpChannel.Ttl)
+	w.Set(key.NewTmpChannelColumnKey(tmpChannel.Id, key.TableTmpChannel.Column.Ttl), ttlBytes)
+
+	var idleTtlBytes = make([]byte, 8)
+	wk.endian.PutUint64(idleTtlBytes, tmpChannel.IdleTtl)
+	w.Set(key.NewTmpChannelColumnKey(tmpChannel.Id, key.TableTmpChannel.Column.IdleTtl), idleTtlBytes)
+
+	if tmpChannel.CreatedAt != nil {
+		ct := uint64(tmpChannel.CreatedAt.UnixNano())
+		var createdAtBytes = make([]byte, 8)
+		wk.endian.PutUint64(createdAtBytes, ct)
+		w.Set(key.NewTmpChannelColumnKey(tmpChannel.Id, key.TableTmpChannel.Column.CreatedAt), createdAtBytes)
+	}
+
+	if tmpChannel.UpdatedAt != nil {
+		up := uint64(tmpChannel.UpdatedAt.UnixNano())
+		var updatedAtBytes = make([]byte, 8)
+		wk.endian.PutUint64(updatedAtBytes, up)
+		w.Set(key.NewTmpChannelColumnKey(tmpChannel.Id, key.TableTmpChannel.Column.UpdatedAt), updatedAtBytes)
+	}
+
+	return nil
+}
+
+func (wk *wukongDB) iteratorTmpChannel(iter *pebble.Iterator, iterFnc func(tmpChannel TmpChannel) bool) error {
+
+	var (
+		preId          uint64
+		preTmpChannel  TmpChannel
+		lastNeedAppend bool = true
+		hasData        bool = false
+	)
+
+	for iter.First(); iter.Valid(); iter.Next() {
+		primaryKey, columnName, err := key.ParseTmpChannelColumnKey(iter.Key())
+		if err != nil {
+			return err
+		}
+
+		if preId != primaryKey {
+			if preId != 0 {
+				if !iterFnc(preTmpChannel) {
+					lastNeedAppend = false
+					break
+				}
+			}
+			preId = primaryKey
+			preTmpChannel = TmpChannel{Id: primaryKey}
+		}
+
+		switch columnName {
+		case key.TableTmpChannel.Column.ChannelId:
+			preTmpChannel.ChannelId = string(iter.Value())
+		case key.TableTmpChannel.Column.Ttl:
+			preTmpChannel.Ttl = wk.endian.Uint64(iter.Value())
+		case key.TableTmpChannel.Column.IdleTtl:
+			preTmpChannel.IdleTtl = wk.endian.Uint64(iter.Value())
+		case key.TableTmpChannel.Column.CreatedAt:
+			tm := int64(wk.endian.Uint64(iter.Value()))
+			if tm > 0 {
+				t := time.Unix(tm/1e9, tm%1e9)
+				preTmpChannel.CreatedAt = &t
+			}
+		case key.TableTmpChannel.Column.UpdatedAt:
+			tm := int64(wk.endian.Uint64(iter.Value()))
+			if tm > 0 {
+				t := time.Unix(tm/1e9, tm%1e9)
+				preTmpChannel.UpdatedAt = &t
+			}
+		}
+		lastNeedAppend = true
+		hasData = true
+	}
+
+	if lastNeedAppend && hasData {
+		_ = iterFnc(preTmpChannel)
+	}
+	return nil
+}
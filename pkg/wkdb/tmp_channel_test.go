@@ -0,0 +1,65 @@
+package wkdb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTmpChannel(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	nw := time.Now()
+	tmpChannel := wkdb.TmpChannel{
+		ChannelId: "test@tmp",
+		Ttl:       60,
+		IdleTtl:   30,
+		CreatedAt: &nw,
+		UpdatedAt: &nw,
+	}
+
+	t.Run("AddOrUpdateTmpChannel", func(t *testing.T) {
+		err := d.AddOrUpdateTmpChannel(tmpChannel)
+		assert.NoError(t, err)
+	})
+
+	t.Run("GetTmpChannels", func(t *testing.T) {
+		tt, err := d.GetTmpChannels()
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(tt))
+	})
+
+	t.Run("GetTmpChannel", func(t *testing.T) {
+		tt, err := d.GetTmpChannel(tmpChannel.ChannelId)
+		assert.NoError(t, err)
+		assert.Equal(t, tmpChannel.ChannelId, tt.ChannelId)
+		assert.Equal(t, tmpChannel.Ttl, tt.Ttl)
+		assert.Equal(t, tmpChannel.IdleTtl, tt.IdleTtl)
+		assert.Equal(t, tmpChannel.CreatedAt.Unix(), tt.CreatedAt.Unix())
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		assert.False(t, tmpChannel.Expired(nw.Add(time.Second*10), 0))
+		assert.True(t, tmpChannel.Expired(nw.Add(time.Second*31), 0))
+		lastMsgTime := uint64(nw.Add(time.Second * 20).UnixNano())
+		assert.False(t, tmpChannel.Expired(nw.Add(time.Second*31), lastMsgTime))
+		assert.True(t, tmpChannel.Expired(nw.Add(time.Second*60), lastMsgTime))
+	})
+
+	t.Run("RemoveTmpChannel", func(t *testing.T) {
+		err := d.RemoveTmpChannel(tmpChannel.ChannelId)
+		assert.NoError(t, err)
+
+		_, err = d.GetTmpChannel(tmpChannel.ChannelId)
+		assert.Equal(t, wkdb.ErrNotFound, err)
+	})
+}
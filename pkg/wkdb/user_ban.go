@@ -0,0 +1,155 @@
+package wkdb
+
+import (
+	"math"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) AddOrUpdateUserBan(userBan UserBan) error {
+
+	batch := wk.defaultShardBatchDB().NewBatch()
+
+	userBan.Id = key.HashWithString(userBan.Uid)
+
+	// 先删除旧的封禁（比如旧的封禁有到期时间，新的是永久封禁）
+	batch.DeleteRange(key.NewUserBanColumnKey(userBan.Id, key.MinColumnKey), key.NewUserBanColumnKey(userBan.Id, key.MaxColumnKey))
+
+	if err := wk.writeUserBan(batch, userBan); err != nil {
+		return err
+	}
+
+	return batch.CommitWait()
+}
+
+func (wk *wukongDB) GetUserBan(uid string) (UserBan, error) {
+
+	db := wk.defaultShardDB()
+
+	id := key.HashWithString(uid)
+
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewUserBanColumnKey(id, key.MinColumnKey),
+		UpperBound: key.NewUserBanColumnKey(id, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	var userBan UserBan
+	err := wk.iteratorUserBan(iter, func(u UserBan) bool {
+		userBan = u
+		return false
+	})
+	if err != nil {
+		return EmptyUserBan, err
+	}
+	if userBan.Uid == "" {
+		return EmptyUserBan, ErrNotFound
+	}
+	return userBan, nil
+}
+
+func (wk *wukongDB) GetUserBans() ([]UserBan, error) {
+
+	db := wk.defaultShardDB()
+
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewUserBanColumnKey(0, key.MinColumnKey),
+		UpperBound: key.NewUserBanColumnKey(math.MaxUint64, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	var userBans []UserBan
+	err := wk.iteratorUserBan(iter, func(u UserBan) bool {
+		userBans = append(userBans, u)
+		return true
+	})
+	return userBans, err
+}
+
+func (wk *wukongDB) RemoveUserBan(uid string) error {
+
+	batch := wk.defaultShardBatchDB().NewBatch()
+
+	id := key.HashWithString(uid)
+
+	batch.DeleteRange(key.NewUserBanColumnKey(id, key.MinColumnKey), key.NewUserBanColumnKey(id, key.MaxColumnKey))
+
+	return batch.CommitWait()
+}
+
+func (wk *wukongDB) writeUserBan(w *Batch, userBan UserBan) error {
+
+	w.Set(key.NewUserBanColumnKey(userBan.Id, key.TableUserBan.Column.Uid), []byte(userBan.Uid))
+
+	w.Set(key.NewUserBanColumnKey(userBan.Id, key.TableUserBan.Column.Reason), []byte(userBan.Reason))
+
+	if userBan.ExpireAt != nil {
+		var expireAtBytes = make([]byte, 8)
+		wk.endian.PutUint64(expireAtBytes, uint64(userBan.ExpireAt.UnixNano()))
+		w.Set(key.NewUserBanColumnKey(userBan.Id, key.TableUserBan.Column.ExpireAt), expireAtBytes)
+	}
+
+	if userBan.CreatedAt != nil {
+		var createdAtBytes = make([]byte, 8)
+		wk.endian.PutUint64(createdAtBytes, uint64(userBan.CreatedAt.UnixNano()))
+		w.Set(key.NewUserBanColumnKey(userBan.Id, key.TableUserBan.Column.CreatedAt), createdAtBytes)
+	}
+
+	return nil
+}
+
+func (wk *wukongDB) iteratorUserBan(iter *pebble.Iterator, iterFnc func(userBan UserBan) bool) error {
+
+	var (
+		preId          uint64
+		preUserBan     UserBan
+		lastNeedAppend bool = true
+		hasData        bool = false
+	)
+
+	for iter.First(); iter.Valid(); iter.Next() {
+		primaryKey, columnName, err := key.ParseUserBanColumnKey(iter.Key())
+		if err != nil {
+			return err
+		}
+
+		if preId != primaryKey {
+			if preId != 0 {
+				if !iterFnc(preUserBan) {
+					lastNeedAppend = false
+					break
+				}
+			}
+			preId = primaryKey
+			preUserBan = UserBan{Id: primaryKey}
+		}
+
+		switch columnName {
+		case key.TableUserBan.Column.Uid:
+			preUserBan.Uid = string(iter.Value())
+		case key.TableUserBan.Column.Reason:
+			preUserBan.Reason = string(iter.Value())
+		case key.TableUserBan.Column.ExpireAt:
+			tm := int64(wk.endian.Uint64(iter.Value()))
+			if tm > 0 {
+				t := time.Unix(tm/1e9, tm%1e9)
+				preUserBan.ExpireAt = &t
+			}
+		case key.TableUserBan.Column.CreatedAt:
+			tm := int64(wk.endian.Uint64(iter.Value()))
+			if tm > 0 {
+				t := time.Unix(tm/1e9, tm%1e9)
+				preUserBan.CreatedAt = &t
+			}
+		}
+		lastNeedAppend = true
+		hasData = true
+	}
+
+	if lastNeedAppend && hasData {
+		_ = iterFnc(preUserBan)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package wkdb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserBan(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	nw := time.Now()
+	expireAt := nw.Add(time.Hour)
+	userBan := wkdb.UserBan{
+		Uid:       "u1",
+		Reason:    "spam",
+		ExpireAt:  &expireAt,
+		CreatedAt: &nw,
+	}
+
+	t.Run("AddOrUpdateUserBan", func(t *testing.T) {
+		err := d.AddOrUpdateUserBan(userBan)
+		assert.NoError(t, err)
+
+		err = d.AddOrUpdateUserBan(wkdb.UserBan{Uid: "u2", CreatedAt: &nw})
+		assert.NoError(t, err)
+	})
+
+	t.Run("GetUserBan", func(t *testing.T) {
+		u, err := d.GetUserBan(userBan.Uid)
+		assert.NoError(t, err)
+		assert.Equal(t, userBan.Uid, u.Uid)
+		assert.Equal(t, userBan.Reason, u.Reason)
+		assert.Equal(t, expireAt.Unix(), u.ExpireAt.Unix())
+		assert.False(t, u.Expired(nw))
+		assert.True(t, u.Expired(expireAt))
+	})
+
+	t.Run("GetUserBans", func(t *testing.T) {
+		bans, err := d.GetUserBans()
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(bans))
+	})
+
+	t.Run("UpdateToPermanent", func(t *testing.T) {
+		err := d.AddOrUpdateUserBan(wkdb.UserBan{Uid: userBan.Uid, Reason: "again", CreatedAt: &nw})
+		assert.NoError(t, err)
+		u, err := d.GetUserBan(userBan.Uid)
+		assert.NoError(t, err)
+		assert.Nil(t, u.ExpireAt)
+		assert.False(t, u.Expired(expireAt))
+	})
+
+	t.Run("RemoveUserBan", func(t *testing.T) {
+		err := d.RemoveUserBan(userBan.Uid)
+		assert.NoError(t, err)
+		_, err = d.GetUserBan(userBan.Uid)
+		assert.Equal(t, wkdb.ErrNotFound, err)
+	})
+}
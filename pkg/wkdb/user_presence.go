@@ -0,0 +1,122 @@
+package wkdb
+
+import (
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) UpdateUserLastSeen(uid string, lastSeen time.Time) error {
+
+	id := key.HashWithString(uid)
+
+	batch := wk.sharedBatchDB(uid).NewBatch()
+
+	batch.Set(key.NewUserPresenceColumnKey(id, key.TableUserPresence.Column.Uid), []byte(uid))
+
+	var lastSeenBytes = make([]byte, 8)
+	wk.endian.PutUint64(lastSeenBytes, uint64(lastSeen.UnixNano()))
+	batch.Set(key.NewUserPresenceColumnKey(id, key.TableUserPresence.Column.LastSeen), lastSeenBytes)
+
+	return batch.CommitWait()
+}
+
+func (wk *wukongDB) UpdateUserStatus(uid string, status UserStatus, statusText string, updatedAt time.Time) error {
+
+	id := key.HashWithString(uid)
+
+	batch := wk.sharedBatchDB(uid).NewBatch()
+
+	batch.Set(key.NewUserPresenceColumnKey(id, key.TableUserPresence.Column.Uid), []byte(uid))
+
+	batch.Set(key.NewUserPresenceColumnKey(id, key.TableUserPresence.Column.Status), []byte{uint8(status)})
+
+	batch.Set(key.NewUserPresenceColumnKey(id, key.TableUserPresence.Column.StatusText), []byte(statusText))
+
+	var updatedAtBytes = make([]byte, 8)
+	wk.endian.PutUint64(updatedAtBytes, uint64(updatedAt.UnixNano()))
+	batch.Set(key.NewUserPresenceColumnKey(id, key.TableUserPresence.Column.UpdatedAt), updatedAtBytes)
+
+	return batch.CommitWait()
+}
+
+func (wk *wukongDB) GetUserPresence(uid string) (UserPresence, error) {
+
+	id := key.HashWithString(uid)
+
+	iter := wk.shardDB(uid).NewIter(&pebble.IterOptions{
+		LowerBound: key.NewUserPresenceColumnKey(id, key.MinColumnKey),
+		UpperBound: key.NewUserPresenceColumnKey(id, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	var userPresence UserPresence
+	err := wk.iteratorUserPresence(iter, func(u UserPresence) bool {
+		userPresence = u
+		return false
+	})
+	if err != nil {
+		return EmptyUserPresence, err
+	}
+	if userPresence.Uid == "" {
+		return EmptyUserPresence, ErrNotFound
+	}
+	return userPresence, nil
+}
+
+func (wk *wukongDB) iteratorUserPresence(iter *pebble.Iterator, iterFnc func(userPresence UserPresence) bool) error {
+
+	var (
+		preId           uint64
+		preUserPresence UserPresence
+		lastNeedAppend  bool = true
+		hasData         bool = false
+	)
+
+	for iter.First(); iter.Valid(); iter.Next() {
+		primaryKey, columnName, err := key.ParseUserPresenceColumnKey(iter.Key())
+		if err != nil {
+			return err
+		}
+
+		if preId != primaryKey {
+			if preId != 0 {
+				if !iterFnc(preUserPresence) {
+					lastNeedAppend = false
+					break
+				}
+			}
+			preId = primaryKey
+			preUserPresence = UserPresence{Id: primaryKey}
+		}
+
+		switch columnName {
+		case key.TableUserPresence.Column.Uid:
+			preUserPresence.Uid = string(iter.Value())
+		case key.TableUserPresence.Column.LastSeen:
+			tm := int64(wk.endian.Uint64(iter.Value()))
+			if tm > 0 {
+				t := time.Unix(tm/1e9, tm%1e9)
+				preUserPresence.LastSeen = &t
+			}
+		case key.TableUserPresence.Column.Status:
+			preUserPresence.Status = UserStatus(iter.Value()[0])
+		case key.TableUserPresence.Column.StatusText:
+			preUserPresence.StatusText = string(iter.Value())
+		case key.TableUserPresence.Column.UpdatedAt:
+			tm := int64(wk.endian.Uint64(iter.Value()))
+			if tm > 0 {
+				t := time.Unix(tm/1e9, tm%1e9)
+				preUserPresence.UpdatedAt = &t
+			}
+		}
+		lastNeedAppend = true
+		hasData = true
+	}
+
+	if lastNeedAppend && hasData {
+		_ = iterFnc(preUserPresence)
+	}
+	return nil
+}
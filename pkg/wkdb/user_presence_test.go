@@ -0,0 +1,47 @@
+package wkdb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserPresence(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	uid := "u1"
+
+	t.Run("NotFound", func(t *testing.T) {
+		_, err := d.GetUserPresence(uid)
+		assert.Equal(t, wkdb.ErrNotFound, err)
+	})
+
+	lastSeen := time.Now()
+	t.Run("UpdateUserLastSeen", func(t *testing.T) {
+		err := d.UpdateUserLastSeen(uid, lastSeen)
+		assert.NoError(t, err)
+	})
+
+	t.Run("UpdateUserStatus", func(t *testing.T) {
+		err := d.UpdateUserStatus(uid, wkdb.UserStatusBusy, "开会中", time.Now())
+		assert.NoError(t, err)
+	})
+
+	t.Run("GetUserPresence", func(t *testing.T) {
+		p, err := d.GetUserPresence(uid)
+		assert.NoError(t, err)
+		assert.Equal(t, uid, p.Uid)
+		assert.Equal(t, lastSeen.UnixNano(), p.LastSeen.UnixNano())
+		assert.Equal(t, wkdb.UserStatusBusy, p.Status)
+		assert.Equal(t, "开会中", p.StatusText)
+	})
+}
@@ -0,0 +1,102 @@
+package wkdb
+
+import (
+	"math"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) AddWebhookDeadLetters(letters []WebhookDeadLetter) error {
+	batch := wk.defaultShardDB().NewBatch()
+	defer batch.Close()
+	for _, letter := range letters {
+		data, err := letter.Marshal()
+		if err != nil {
+			return err
+		}
+		if err = batch.Set(key.NewWebhookDeadLetterKey(letter.Id), data, wk.noSync); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) GetWebhookDeadLetters(event string, offsetId uint64, limit int) ([]WebhookDeadLetter, error) {
+	upperId := uint64(math.MaxUint64)
+	if offsetId > 0 {
+		upperId = offsetId
+	}
+	iter := wk.defaultShardDB().NewIter(&pebble.IterOptions{
+		LowerBound: key.NewWebhookDeadLetterKey(0),
+		UpperBound: key.NewWebhookDeadLetterKey(upperId),
+	})
+	defer iter.Close()
+
+	letters := make([]WebhookDeadLetter, 0)
+	for iter.Last(); iter.Valid(); iter.Prev() {
+		var letter WebhookDeadLetter
+		if err := letter.Unmarshal(iter.Value()); err != nil {
+			return nil, err
+		}
+		if event != "" && letter.Event != event {
+			continue
+		}
+		letters = append(letters, letter)
+		if limit > 0 && len(letters) >= limit {
+			break
+		}
+	}
+	return letters, nil
+}
+
+func (wk *wukongDB) GetWebhookDeadLetter(id uint64) (WebhookDeadLetter, error) {
+	value, closer, err := wk.defaultShardDB().Get(key.NewWebhookDeadLetterKey(id))
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return EmptyWebhookDeadLetter, ErrNotFound
+		}
+		return EmptyWebhookDeadLetter, err
+	}
+	defer closer.Close()
+
+	var letter WebhookDeadLetter
+	if err := letter.Unmarshal(value); err != nil {
+		return EmptyWebhookDeadLetter, err
+	}
+	return letter, nil
+}
+
+func (wk *wukongDB) RemoveWebhookDeadLetters(ids []uint64) error {
+	batch := wk.defaultShardDB().NewBatch()
+	defer batch.Close()
+	for _, id := range ids {
+		if err := batch.Delete(key.NewWebhookDeadLetterKey(id), wk.noSync); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) RemoveWebhookDeadLettersBefore(id uint64) error {
+	batch := wk.defaultShardDB().NewBatch()
+	defer batch.Close()
+	if err := batch.DeleteRange(key.NewWebhookDeadLetterKey(0), key.NewWebhookDeadLetterKey(id), wk.noSync); err != nil {
+		return err
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) GetWebhookDeadLetterCount() (int, error) {
+	iter := wk.defaultShardDB().NewIter(&pebble.IterOptions{
+		LowerBound: key.NewWebhookDeadLetterKey(0),
+		UpperBound: key.NewWebhookDeadLetterKey(math.MaxUint64),
+	})
+	defer iter.Close()
+
+	count := 0
+	for iter.First(); iter.Valid(); iter.Next() {
+		count++
+	}
+	return count, nil
+}
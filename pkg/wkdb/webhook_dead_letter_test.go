@@ -0,0 +1,58 @@
+package wkdb_test
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookDeadLetter(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	letters := []wkdb.WebhookDeadLetter{
+		{Id: 1, Event: "msg.offline", Data: make([]byte, 100*1024), DeliveryId: "d1", OrderKey: "c1", Attempts: 21, LastError: "timeout", CreatedAt: 1},
+		{Id: 2, Event: "msg.notify", Data: []byte(`[]`), DeliveryId: "d2", Attempts: 5, CreatedAt: 2},
+		{Id: 3, Event: "msg.offline", Data: []byte(`{}`), DeliveryId: "d3", OrderKey: "c1", Attempts: 21, CreatedAt: 3},
+	}
+	err = d.AddWebhookDeadLetters(letters)
+	assert.NoError(t, err)
+
+	// 按id倒序
+	result, err := d.GetWebhookDeadLetters("", 0, 2)
+	assert.NoError(t, err)
+	assert.Len(t, result, 2)
+	assert.Equal(t, uint64(3), result[0].Id)
+	assert.Equal(t, uint64(2), result[1].Id)
+
+	// 分页和事件过滤
+	result, err = d.GetWebhookDeadLetters("msg.offline", 3, 0)
+	assert.NoError(t, err)
+	assert.Len(t, result, 1)
+	assert.Equal(t, "d1", result[0].DeliveryId)
+	assert.Len(t, result[0].Data, 100*1024)
+
+	letter, err := d.GetWebhookDeadLetter(1)
+	assert.NoError(t, err)
+	assert.Equal(t, "c1", letter.OrderKey)
+	assert.Equal(t, uint32(21), letter.Attempts)
+	assert.Equal(t, "timeout", letter.LastError)
+
+	err = d.RemoveWebhookDeadLetters([]uint64{1})
+	assert.NoError(t, err)
+	_, err = d.GetWebhookDeadLetter(1)
+	assert.Equal(t, wkdb.ErrNotFound, err)
+
+	err = d.RemoveWebhookDeadLettersBefore(3)
+	assert.NoError(t, err)
+	count, err := d.GetWebhookDeadLetterCount()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
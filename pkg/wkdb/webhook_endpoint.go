@@ -0,0 +1,52 @@
+package wkdb
+
+import (
+	"math"
+	"sort"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) SaveWebhookEndpoint(endpoint WebhookEndpoint) error {
+	data, err := endpoint.Marshal()
+	if err != nil {
+		return err
+	}
+	batch := wk.defaultShardDB().NewBatch()
+	defer batch.Close()
+	if err = batch.Set(key.NewWebhookEndpointKey(endpoint.Name), data, wk.noSync); err != nil {
+		return err
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) RemoveWebhookEndpoint(name string) error {
+	batch := wk.defaultShardDB().NewBatch()
+	defer batch.Close()
+	if err := batch.Delete(key.NewWebhookEndpointKey(name), wk.noSync); err != nil {
+		return err
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) GetWebhookEndpoints() ([]WebhookEndpoint, error) {
+	iter := wk.defaultShardDB().NewIter(&pebble.IterOptions{
+		LowerBound: key.NewWebhookEndpointHashKey(0),
+		UpperBound: key.NewWebhookEndpointHashKey(math.MaxUint64),
+	})
+	defer iter.Close()
+
+	endpoints := make([]WebhookEndpoint, 0)
+	for iter.First(); iter.Valid(); iter.Next() {
+		var endpoint WebhookEndpoint
+		if err := endpoint.Unmarshal(iter.Value()); err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Slice(endpoints, func(i, j int) bool {
+		return endpoints[i].Name < endpoints[j].Name
+	})
+	return endpoints, nil
+}
@@ -0,0 +1,47 @@
+package wkdb_test
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookEndpoint(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	err = d.SaveWebhookEndpoint(wkdb.WebhookEndpoint{Name: "b", GRPCAddr: "127.0.0.1:6979", BatchSize: 10})
+	assert.NoError(t, err)
+	err = d.SaveWebhookEndpoint(wkdb.WebhookEndpoint{Name: "a", HTTPAddr: "http://127.0.0.1", Events: []string{"msg.notify", "msg.offline"}, ChannelTypes: []uint8{1, 2}, Secret: "s"})
+	assert.NoError(t, err)
+
+	// 按名称排序
+	endpoints, err := d.GetWebhookEndpoints()
+	assert.NoError(t, err)
+	assert.Len(t, endpoints, 2)
+	assert.Equal(t, "a", endpoints[0].Name)
+	assert.Equal(t, []string{"msg.notify", "msg.offline"}, endpoints[0].Events)
+	assert.Equal(t, []uint8{1, 2}, endpoints[0].ChannelTypes)
+	assert.Equal(t, "s", endpoints[0].Secret)
+	assert.Equal(t, "127.0.0.1:6979", endpoints[1].GRPCAddr)
+	assert.Equal(t, uint32(10), endpoints[1].BatchSize)
+
+	// 更新
+	err = d.SaveWebhookEndpoint(wkdb.WebhookEndpoint{Name: "b", HTTPAddr: "http://127.0.0.2"})
+	assert.NoError(t, err)
+	err = d.RemoveWebhookEndpoint("a")
+	assert.NoError(t, err)
+
+	endpoints, err = d.GetWebhookEndpoints()
+	assert.NoError(t, err)
+	assert.Len(t, endpoints, 1)
+	assert.Equal(t, "http://127.0.0.2", endpoints[0].HTTPAddr)
+	assert.Equal(t, "", endpoints[0].GRPCAddr)
+}
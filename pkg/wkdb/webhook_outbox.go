@@ -0,0 +1,68 @@
+package wkdb
+
+import (
+	"math"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) AppendWebhookEvents(events []WebhookEvent) error {
+	db := wk.defaultShardDB()
+	batch := db.NewBatch()
+	defer batch.Close()
+	for _, event := range events {
+		data, err := event.Marshal()
+		if err != nil {
+			return err
+		}
+		if err = batch.Set(key.NewWebhookOutboxKey(event.Id), data, wk.noSync); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) GetWebhookEvents(startId uint64, limit int) ([]WebhookEvent, error) {
+	iter := wk.defaultShardDB().NewIter(&pebble.IterOptions{
+		LowerBound: key.NewWebhookOutboxKey(startId),
+		UpperBound: key.NewWebhookOutboxKey(math.MaxUint64),
+	})
+	defer iter.Close()
+
+	events := make([]WebhookEvent, 0)
+	for iter.First(); iter.Valid(); iter.Next() {
+		var event WebhookEvent
+		if err := event.Unmarshal(iter.Value()); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+		if limit > 0 && len(events) >= limit {
+			break
+		}
+	}
+	return events, nil
+}
+
+func (wk *wukongDB) RemoveWebhookEvents(ids []uint64) error {
+	batch := wk.defaultShardDB().NewBatch()
+	defer batch.Close()
+	for _, id := range ids {
+		if err := batch.Delete(key.NewWebhookOutboxKey(id), wk.noSync); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) GetWebhookOutboxMaxId() (uint64, error) {
+	iter := wk.defaultShardDB().NewIter(&pebble.IterOptions{
+		LowerBound: key.NewWebhookOutboxKey(0),
+		UpperBound: key.NewWebhookOutboxKey(math.MaxUint64),
+	})
+	defer iter.Close()
+	if !iter.Last() {
+		return 0, nil
+	}
+	return key.ParseWebhookOutboxKey(iter.Key())
+}
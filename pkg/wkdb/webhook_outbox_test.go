@@ -0,0 +1,66 @@
+package wkdb_test
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookOutbox(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	bigData := make([]byte, 100*1024)
+	events := []wkdb.WebhookEvent{
+		{Id: 2, Event: "msg.offline", Data: bigData, DeliveryId: "d2", OrderKey: "c1", CreatedAt: 2},
+		{Id: 1, Event: "user.onlinestatus", Data: []byte(`["u1-1-1-1-1-1"]`), DeliveryId: "d1", OrderKey: "user.onlinestatus", CreatedAt: 1},
+		{Id: 3, Endpoint: "sink:file", Event: "channel.tmp.expired", Data: []byte(`{}`), DeliveryId: "d3", CreatedAt: 3, SinkOffset: 7},
+	}
+	err = d.AppendWebhookEvents(events)
+	assert.NoError(t, err)
+
+	maxId, err := d.GetWebhookOutboxMaxId()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), maxId)
+
+	// 按id顺序
+	result, err := d.GetWebhookEvents(0, 2)
+	assert.NoError(t, err)
+	assert.Len(t, result, 2)
+	assert.Equal(t, uint64(1), result[0].Id)
+	assert.Equal(t, "user.onlinestatus", result[0].OrderKey)
+	assert.Equal(t, uint64(2), result[1].Id)
+	assert.Len(t, result[1].Data, len(bigData))
+
+	// 更新重试信息
+	result[0].RetryCount = 2
+	result[0].NextRetryAt = 100
+	result[0].LastError = "timeout"
+	err = d.AppendWebhookEvents(result[:1])
+	assert.NoError(t, err)
+
+	err = d.RemoveWebhookEvents([]uint64{2})
+	assert.NoError(t, err)
+
+	result, err = d.GetWebhookEvents(0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, result, 2)
+	assert.Equal(t, uint32(2), result[0].RetryCount)
+	assert.Equal(t, int64(100), result[0].NextRetryAt)
+	assert.Equal(t, "timeout", result[0].LastError)
+	assert.Equal(t, "d3", result[1].DeliveryId)
+	assert.Equal(t, uint64(7), result[1].SinkOffset)
+
+	// 从指定id开始
+	result, err = d.GetWebhookEvents(2, 0)
+	assert.NoError(t, err)
+	assert.Len(t, result, 1)
+	assert.Equal(t, uint64(3), result[0].Id)
+}
@@ -0,0 +1,97 @@
+package wkhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// webhook请求的签名
+// 签名内容为 "{timestamp}.{deliveryId}.{event}.{body}"，算法为HMAC-SHA256，
+// 签名头格式为 "v1={hex签名}"，密钥轮换期间同时配置两个密钥时为 "v1={新密钥签名},v1={旧密钥签名}"，接收方任意一个签名验证通过即可。
+// 接收方应校验时间戳在允许的时间范围内，并按投递id去重，防止重放。
+const (
+	HeaderDeliveryId = "X-WK-Delivery-Id" // 投递id，同一次投递的重试不变，接收方可用于幂等
+	HeaderTimestamp  = "X-WK-Timestamp"   // 签名时间（unix秒）
+	HeaderSignature  = "X-WK-Signature"   // 签名
+
+	// grpc的metadata（key为小写）
+	MetadataDeliveryId = "x-wk-delivery-id"
+	MetadataTimestamp  = "x-wk-timestamp"
+	MetadataSignature  = "x-wk-signature"
+
+	signatureVersion = "v1"
+)
+
+var (
+	ErrSignatureMissing  = errors.New("webhook signature missing")
+	ErrSignatureMismatch = errors.New("webhook signature mismatch")
+	ErrTimestampInvalid  = errors.New("webhook timestamp invalid")
+	ErrTimestampExpired  = errors.New("webhook timestamp out of tolerance")
+)
+
+// Sign 用密钥对webhook请求签名，返回hex格式的签名
+func Sign(secret string, timestamp int64, deliveryId, event string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write([]byte(deliveryId))
+	mac.Write([]byte("."))
+	mac.Write([]byte(event))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignatureHeader 生成签名头的值，每个密钥一个签名（空密钥忽略）
+func SignatureHeader(secrets []string, timestamp int64, deliveryId, event string, body []byte) string {
+	signatures := make([]string, 0, len(secrets))
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		signatures = append(signatures, signatureVersion+"="+Sign(secret, timestamp, deliveryId, event, body))
+	}
+	return strings.Join(signatures, ",")
+}
+
+// Verify 接收方校验webhook请求的签名
+// secrets为接收方当前有效的密钥，tolerance为允许的时间偏差（为0不校验时间）
+func Verify(secrets []string, signatureHeader string, timestampStr string, deliveryId, event string, body []byte, tolerance time.Duration) error {
+	if strings.TrimSpace(signatureHeader) == "" {
+		return ErrSignatureMissing
+	}
+	timestamp, err := strconv.ParseInt(timestampStr, 10, 64)
+	if err != nil {
+		return ErrTimestampInvalid
+	}
+	if tolerance > 0 {
+		diff := time.Since(time.Unix(timestamp, 0))
+		if diff < 0 {
+			diff = -diff
+		}
+		if diff > tolerance {
+			return ErrTimestampExpired
+		}
+	}
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		expected := []byte(Sign(secret, timestamp, deliveryId, event, body))
+		for _, part := range strings.Split(signatureHeader, ",") {
+			version, signature, ok := strings.Cut(strings.TrimSpace(part), "=")
+			if !ok || version != signatureVersion {
+				continue
+			}
+			if hmac.Equal(expected, []byte(signature)) {
+				return nil
+			}
+		}
+	}
+	return ErrSignatureMismatch
+}
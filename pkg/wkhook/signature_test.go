@@ -0,0 +1,34 @@
+package wkhook
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignature(t *testing.T) {
+	body := []byte(`{"uid":"u1"}`)
+	now := time.Now().Unix()
+	ts := strconv.FormatInt(now, 10)
+
+	// 密钥轮换期间同时使用新旧两个密钥签名
+	header := SignatureHeader([]string{"new", "old"}, now, "d1", "msg.notify", body)
+
+	assert.NoError(t, Verify([]string{"old"}, header, ts, "d1", "msg.notify", body, time.Minute))
+	assert.NoError(t, Verify([]string{"new"}, header, ts, "d1", "msg.notify", body, time.Minute))
+	assert.Equal(t, ErrSignatureMismatch, Verify([]string{"other"}, header, ts, "d1", "msg.notify", body, time.Minute))
+
+	// 篡改内容
+	assert.Equal(t, ErrSignatureMismatch, Verify([]string{"new"}, header, ts, "d2", "msg.notify", body, time.Minute))
+	assert.Equal(t, ErrSignatureMismatch, Verify([]string{"new"}, header, ts, "d1", "msg.offline", body, time.Minute))
+	assert.Equal(t, ErrSignatureMismatch, Verify([]string{"new"}, header, ts, "d1", "msg.notify", []byte(`{}`), time.Minute))
+
+	// 过期的时间戳
+	old := now - 600
+	oldHeader := SignatureHeader([]string{"new"}, old, "d1", "msg.notify", body)
+	assert.Equal(t, ErrTimestampExpired, Verify([]string{"new"}, oldHeader, strconv.FormatInt(old, 10), "d1", "msg.notify", body, time.Minute))
+
+	assert.Equal(t, ErrSignatureMissing, Verify([]string{"new"}, "", ts, "d1", "msg.notify", body, time.Minute))
+}
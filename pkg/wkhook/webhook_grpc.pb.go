@@ -24,6 +24,10 @@ const _ = grpc.SupportPackageIsVersion7
 type WebhookServiceClient interface {
 	// 发送webhook事件
 	SendWebhook(ctx context.Context, in *EventReq, opts ...grpc.CallOption) (*EventResp, error)
+	// 连接认证（委托第三方认证服务认证客户端连接）
+	Auth(ctx context.Context, in *AuthReq, opts ...grpc.CallOption) (*AuthResp, error)
+	// 消息发送前的拦截（例如内容审核，频道领导节点存储消息前批量调用）
+	BeforeSend(ctx context.Context, in *BeforeSendReq, opts ...grpc.CallOption) (*BeforeSendResp, error)
 }
 
 type webhookServiceClient struct {
@@ -43,12 +47,34 @@ func (c *webhookServiceClient) SendWebhook(ctx context.Context, in *EventReq, op
 	return out, nil
 }
 
+func (c *webhookServiceClient) Auth(ctx context.Context, in *AuthReq, opts ...grpc.CallOption) (*AuthResp, error) {
+	out := new(AuthResp)
+	err := c.cc.Invoke(ctx, "/wkhook.WebhookService/Auth", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *webhookServiceClient) BeforeSend(ctx context.Context, in *BeforeSendReq, opts ...grpc.CallOption) (*BeforeSendResp, error) {
+	out := new(BeforeSendResp)
+	err := c.cc.Invoke(ctx, "/wkhook.WebhookService/BeforeSend", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // WebhookServiceServer is the server API for WebhookService service.
 // All implementations must embed UnimplementedWebhookServiceServer
 // for forward compatibility
 type WebhookServiceServer interface {
 	// 发送webhook事件
 	SendWebhook(context.Context, *EventReq) (*EventResp, error)
+	// 连接认证（委托第三方认证服务认证客户端连接）
+	Auth(context.Context, *AuthReq) (*AuthResp, error)
+	// 消息发送前的拦截（例如内容审核，频道领导节点存储消息前批量调用）
+	BeforeSend(context.Context, *BeforeSendReq) (*BeforeSendResp, error)
 	mustEmbedUnimplementedWebhookServiceServer()
 }
 
@@ -59,6 +85,12 @@ type UnimplementedWebhookServiceServer struct {
 func (UnimplementedWebhookServiceServer) SendWebhook(context.Context, *EventReq) (*EventResp, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SendWebhook not implemented")
 }
+func (UnimplementedWebhookServiceServer) Auth(context.Context, *AuthReq) (*AuthResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Auth not implemented")
+}
+func (UnimplementedWebhookServiceServer) BeforeSend(context.Context, *BeforeSendReq) (*BeforeSendResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BeforeSend not implemented")
+}
 func (UnimplementedWebhookServiceServer) mustEmbedUnimplementedWebhookServiceServer() {}
 
 // UnsafeWebhookServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -90,6 +122,42 @@ func _WebhookService_SendWebhook_Handler(srv interface{}, ctx context.Context, d
 	return interceptor(ctx, in, info, handler)
 }
 
+func _WebhookService_Auth_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AuthReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WebhookServiceServer).Auth(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wkhook.WebhookService/Auth",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WebhookServiceServer).Auth(ctx, req.(*AuthReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WebhookService_BeforeSend_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BeforeSendReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WebhookServiceServer).BeforeSend(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wkhook.WebhookService/BeforeSend",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WebhookServiceServer).BeforeSend(ctx, req.(*BeforeSendReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // WebhookService_ServiceDesc is the grpc.ServiceDesc for WebhookService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -101,6 +169,14 @@ var WebhookService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "SendWebhook",
 			Handler:    _WebhookService_SendWebhook_Handler,
 		},
+		{
+			MethodName: "Auth",
+			Handler:    _WebhookService_Auth_Handler,
+		},
+		{
+			MethodName: "BeforeSend",
+			Handler:    _WebhookService_BeforeSend_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "pkg/wkhook/webhook.proto",
@@ -16,6 +16,8 @@ type Acceptor struct {
 	listen    *listener
 	listenWS  *listener // websocket
 	listenWSS *listener // websocket
+
+	listenMQTT *listener // mqtt
 }
 
 func NewAcceptor(eg *Engine) *Acceptor {
@@ -48,6 +50,12 @@ func (a *Acceptor) Stop() error {
 	if err != nil {
 		a.Warn("listenWSS.Close() failed", zap.Error(err))
 	}
+	if a.listenMQTT != nil {
+		err = a.listenMQTT.Close()
+		if err != nil {
+			a.Warn("listenMQTT.Close() failed", zap.Error(err))
+		}
+	}
 	for _, reactorSub := range a.reactorSubs {
 		reactorSub.Stop()
 	}
@@ -67,6 +75,10 @@ func (a *Acceptor) wssRealAddr() net.Addr {
 	return a.listenWSS.realAddr
 }
 
+func (a *Acceptor) mqttRealAddr() net.Addr {
+	return a.listenMQTT.realAddr
+}
+
 func (a *Acceptor) start() error {
 	for _, reactorSub := range a.reactorSubs {
 		reactorSub.Start()
@@ -79,6 +91,9 @@ func (a *Acceptor) start() error {
 	if strings.TrimSpace(a.eg.options.WssAddr) != "" {
 		wg.Add(1)
 	}
+	if strings.TrimSpace(a.eg.options.MQTTAddr) != "" {
+		wg.Add(1)
+	}
 	go func() {
 		err := a.initTCPListener(wg)
 		if err != nil {
@@ -102,6 +117,14 @@ func (a *Acceptor) start() error {
 			}
 		}()
 	}
+	if strings.TrimSpace(a.eg.options.MQTTAddr) != "" {
+		go func() {
+			err := a.initMQTTListener(wg)
+			if err != nil {
+				panic(err)
+			}
+		}()
+	}
 
 	wg.Wait()
 	return nil
@@ -116,7 +139,7 @@ func (a *Acceptor) initTCPListener(wg *sync.WaitGroup) error {
 	}
 	wg.Done()
 	a.listen.Polling(func(fd NetFd) error {
-		return a.acceptConn(fd, false, false)
+		return a.acceptConn(fd, connKindTCP)
 	})
 	return nil
 }
@@ -130,7 +153,7 @@ func (a *Acceptor) initWSListener(wg *sync.WaitGroup) error {
 	}
 	wg.Done()
 	a.listenWS.Polling(func(fd NetFd) error {
-		return a.acceptConn(fd, true, false)
+		return a.acceptConn(fd, connKindWS)
 	})
 	return nil
 }
@@ -144,12 +167,26 @@ func (a *Acceptor) initWSSListener(wg *sync.WaitGroup) error {
 	}
 	wg.Done()
 	a.listenWSS.Polling(func(fd NetFd) error {
-		return a.acceptConn(fd, false, true)
+		return a.acceptConn(fd, connKindWSS)
+	})
+	return nil
+}
+
+func (a *Acceptor) initMQTTListener(wg *sync.WaitGroup) error {
+	// mqtt
+	a.listenMQTT = newListener(a.eg.options.MQTTAddr, a.eg.options)
+	err := a.listenMQTT.init()
+	if err != nil {
+		return err
+	}
+	wg.Done()
+	a.listenMQTT.Polling(func(fd NetFd) error {
+		return a.acceptConn(fd, connKindMQTT)
 	})
 	return nil
 }
 
-func (a *Acceptor) acceptConn(connNetFd NetFd, ws bool, wss bool) error {
+func (a *Acceptor) acceptConn(connNetFd NetFd, kind connKind) error {
 	var (
 		conn Conn
 		err  error
@@ -159,15 +196,20 @@ func (a *Acceptor) acceptConn(connNetFd NetFd, ws bool, wss bool) error {
 	remoteAddr := connNetFd.conn.RemoteAddr()
 
 	subReactor := a.reactorSubByConnFd(connFd)
-	if wss {
+	switch kind {
+	case connKindWSS:
 		if conn, err = a.eg.eventHandler.OnNewWSSConn(a.eg.GenClientID(), connNetFd, a.wssRealAddr(), remoteAddr, a.eg, subReactor); err != nil {
 			return err
 		}
-	} else if ws {
+	case connKindWS:
 		if conn, err = a.eg.eventHandler.OnNewWSConn(a.eg.GenClientID(), connNetFd, a.wsRealAddr(), remoteAddr, a.eg, subReactor); err != nil {
 			return err
 		}
-	} else {
+	case connKindMQTT:
+		if conn, err = a.eg.eventHandler.OnNewMQTTConn(a.eg.GenClientID(), connNetFd, a.mqttRealAddr(), remoteAddr, a.eg, subReactor); err != nil {
+			return err
+		}
+	default:
 		if conn, err = a.eg.eventHandler.OnNewConn(a.eg.GenClientID(), connNetFd, a.tcpRealAddr(), remoteAddr, a.eg, subReactor); err != nil {
 			return err
 		}
@@ -2,7 +2,26 @@ package wknet
 
 import "errors"
 
+// 连接的监听类型
+type connKind int
+
+const (
+	connKindTCP  connKind = iota // tcp
+	connKindWS                   // websocket
+	connKindWSS                  // websocket tls
+	connKindMQTT                 // mqtt
+)
+
 var (
 	// ErrUnsupportedOp occurs when calling some methods that has not been implemented yet.
 	ErrUnsupportedOp = errors.New("unsupported operation")
 )
+
+const (
+	// ConnValueUserAgent websocket握手请求的User-Agent
+	ConnValueUserAgent = "userAgent"
+	// ConnValueClientVersion websocket握手请求带的客户端版本（请求头X-Client-Version或url参数client_version）
+	ConnValueClientVersion = "clientVersion"
+	// ConnValueWSSubprotocol websocket握手协商的子协议（Sec-WebSocket-Protocol），没有协商为空
+	ConnValueWSSubprotocol = "wsSubprotocol"
+)
@@ -21,6 +21,8 @@ type EventHandler struct {
 	// OnNewWSConn is called when a new websocket connection is established.
 	OnNewWSConn  OnNewConn
 	OnNewWSSConn OnNewConn
+	// OnNewMQTTConn is called when a new mqtt connection is established.
+	OnNewMQTTConn OnNewConn
 	// OnNewInboundConn is called when need create a new inbound buffer.
 	OnNewInboundConn OnNewInboundConn
 	// OnNewOutboundConn is called when need create a new outbound buffer.
@@ -41,6 +43,9 @@ func NewEventHandler() *EventHandler {
 		OnNewWSSConn: func(id int64, connFd NetFd, localAddr, remoteAddr net.Addr, eg *Engine, reactorSub *ReactorSub) (Conn, error) {
 			return CreateWSSConn(id, connFd, localAddr, remoteAddr, eg, reactorSub)
 		},
+		OnNewMQTTConn: func(id int64, connFd NetFd, localAddr, remoteAddr net.Addr, eg *Engine, reactorSub *ReactorSub) (Conn, error) {
+			return CreateMQTTConn(id, connFd, localAddr, remoteAddr, eg, reactorSub)
+		},
 		OnNewInboundConn:  func(conn Conn, eg *Engine) InboundBuffer { return NewDefaultBuffer() },
 		OnNewOutboundConn: func(conn Conn, eg *Engine) OutboundBuffer { return NewDefaultBuffer() },
 	}
@@ -38,7 +38,7 @@ func (l *listener) init() error {
 	l.customNetwork = network
 	l.customAddr = addr
 
-	if strings.HasPrefix(network, "tcp") || strings.HasPrefix(network, "ws") {
+	if strings.HasPrefix(network, "tcp") || strings.HasPrefix(network, "ws") || network == "mqtt" {
 		return l.initTCPListener(network, addr)
 	}
 	return fmt.Errorf("unsupported network: %s", network)
@@ -64,7 +64,7 @@ func (l *listener) initTCPListener(network, addr string) error {
 	)
 
 	switch network {
-	case "ws", "wss":
+	case "ws", "wss", "mqtt":
 		l.fd, _, err = socket.TCPSocket("tcp", addr, true, sockOpts...)
 	case "tcp", "tcp4", "tcp6":
 		l.fd, _, err = socket.TCPSocket(network, addr, true, sockOpts...)
@@ -53,7 +53,7 @@ func (l *listener) init() error {
 	l.customNetwork = network
 	l.customAddr = addr
 
-	if strings.HasPrefix(network, "tcp") || strings.HasPrefix(network, "ws") {
+	if strings.HasPrefix(network, "tcp") || strings.HasPrefix(network, "ws") || network == "mqtt" {
 		return l.initTCPListener(network, addr)
 	}
 	return fmt.Errorf("unsupported network: %s", network)
@@ -76,7 +76,7 @@ func (l *listener) initTCPListener(network, addr string) error {
 	}
 	var err error
 	switch network {
-	case "ws", "wss":
+	case "ws", "wss", "mqtt":
 		l.ln, err = lc.Listen(context.Background(), "tcp", addr)
 	case "tcp", "tcp4", "tcp6":
 		l.ln, err = lc.Listen(context.Background(), network, addr)
@@ -0,0 +1,19 @@
+package wknet
+
+import "net"
+
+func CreateMQTTConn(id int64, connFd NetFd, localAddr, remoteAddr net.Addr, eg *Engine, reactorSub *ReactorSub) (Conn, error) {
+	defaultConn := GetDefaultConn(id, connFd, localAddr, remoteAddr, eg, reactorSub)
+	return NewMQTTConn(defaultConn), nil
+}
+
+// MQTTConn mqtt连接，读写的数据为原始的mqtt报文，报文的编解码由上层处理
+type MQTTConn struct {
+	*DefaultConn
+}
+
+func NewMQTTConn(d *DefaultConn) *MQTTConn {
+	return &MQTTConn{
+		DefaultConn: d,
+	}
+}
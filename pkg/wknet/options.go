@@ -1,6 +1,7 @@
 package wknet
 
 import (
+	"compress/flate"
 	"runtime"
 	"time"
 
@@ -16,6 +17,8 @@ type Options struct {
 	// WsAddr is the listen addr  example: ws://127.0.0.1:5200或 wss://127.0.0.1:5200
 	WsAddr  string
 	WssAddr string // wss addr
+	// MQTTAddr is the mqtt listen addr  example: mqtt://127.0.0.1:1883
+	MQTTAddr string
 	// WSTlsConfig ws tls config
 	// MaxOpenFiles is the maximum number of open files that the server can
 	MaxOpenFiles int
@@ -34,15 +37,29 @@ type Options struct {
 	SocketSendBuffer int
 	// TCPKeepAlive sets up a duration for (SO_KEEPALIVE) socket option.
 	TCPKeepAlive time.Duration
+	// WSSubprotocols websocket支持的子协议，按客户端请求的顺序选择第一个支持的
+	WSSubprotocols []string
+	// WSSSubprotocols 只在wss连接上支持的子协议（明文传输不安全的协议）
+	WSSSubprotocols []string
+	// WSCompression websocket permessage-deflate 压缩
+	WSCompression struct {
+		On                      bool // 是否开启（客户端请求时才协商）
+		Level                   int  // 压缩级别 -2~9（compress/flate）
+		Threshold               int  // 小于此大小（字节）的消息不压缩
+		ServerNoContextTakeover bool // 服务端压缩不保留上下文（压缩器在连接间复用）。保留上下文压缩率更高，但每个连接独占一个flate.Writer直到连接关闭（级别1约470K，级别越高越大，最高约1M），连接多时内存占用很大（1万个连接约5~10G）
+		ClientNoContextTakeover bool // 要求客户端压缩不保留上下文（保留上下文时每个连接需要缓存32K的解压数据）
+	}
 
 	Event struct {
-		OnReadBytes  func(n int) // 读到的字节大小
-		OnWirteBytes func(n int) // 写出字节大小
+		OnReadBytes    func(n int)                 // 读到的字节大小
+		OnWirteBytes   func(n int)                 // 写出字节大小
+		OnWSCompress   func(rawN, compressedN int) // websocket消息压缩前后的字节大小
+		OnWSDecompress func(compressedN, rawN int) // websocket消息解压前后的字节大小
 	}
 }
 
 func NewOptions() *Options {
-	return &Options{
+	opts := &Options{
 		Addr:               "tcp://127.0.0.1:5100",
 		MaxOpenFiles:       GetMaxOpenFiles(),
 		SubReactorNum:      runtime.NumCPU(),
@@ -50,6 +67,10 @@ func NewOptions() *Options {
 		MaxWriteBufferSize: 1024 * 1024 * 50,
 		MaxReadBufferSize:  1024 * 1024 * 50,
 	}
+	opts.WSCompression.Level = flate.BestSpeed
+	opts.WSCompression.Threshold = 512
+	opts.WSCompression.ServerNoContextTakeover = true
+	return opts
 }
 
 type Option func(opts *Options)
@@ -73,6 +94,12 @@ func WithWSSAddr(v string) Option {
 	}
 }
 
+func WithMQTTAddr(v string) Option {
+	return func(opts *Options) {
+		opts.MQTTAddr = v
+	}
+}
+
 func WithTCPTLSConfig(v *tls.Config) Option {
 	return func(opts *Options) {
 		opts.TCPTLSConfig = v
@@ -120,6 +147,37 @@ func WithTCPKeepAlive(v time.Duration) Option {
 	}
 }
 
+// WithWSSubprotocols 设置websocket支持的子协议
+func WithWSSubprotocols(protocols ...string) Option {
+	return func(opts *Options) {
+		opts.WSSubprotocols = protocols
+	}
+}
+
+// WithWSSSubprotocols 设置只在wss连接上支持的子协议
+func WithWSSSubprotocols(protocols ...string) Option {
+	return func(opts *Options) {
+		opts.WSSSubprotocols = protocols
+	}
+}
+
+// WithWSCompression 开启websocket permessage-deflate 压缩
+func WithWSCompression(level int, threshold int) Option {
+	return func(opts *Options) {
+		opts.WSCompression.On = true
+		opts.WSCompression.Level = level
+		opts.WSCompression.Threshold = threshold
+	}
+}
+
+// WithWSCompressionNoContextTakeover 设置压缩是否不保留上下文（server为false时每个连接独占一个flate.Writer，注意内存占用）
+func WithWSCompressionNoContextTakeover(server, client bool) Option {
+	return func(opts *Options) {
+		opts.WSCompression.ServerNoContextTakeover = server
+		opts.WSCompression.ClientNoContextTakeover = client
+	}
+}
+
 func WithOnReadBytes(f func(n int)) Option {
 	return func(opts *Options) {
 		opts.Event.OnReadBytes = f
@@ -132,3 +190,15 @@ func WithOnWirteBytes(f func(n int)) Option {
 		opts.Event.OnWirteBytes = f
 	}
 }
+
+func WithOnWSCompress(f func(rawN, compressedN int)) Option {
+	return func(opts *Options) {
+		opts.Event.OnWSCompress = f
+	}
+}
+
+func WithOnWSDecompress(f func(compressedN, rawN int)) Option {
+	return func(opts *Options) {
+		opts.Event.OnWSDecompress = f
+	}
+}
@@ -34,6 +34,7 @@ type WSConn struct {
 	*DefaultConn
 	upgraded         bool
 	tmpInboundBuffer InboundBuffer // inboundBuffer InboundBuffer
+	codec            wsCodec       // websocket数据编解码（含压缩）
 }
 
 func NewWSConn(d *DefaultConn) *WSConn {
@@ -67,7 +68,13 @@ func (w *WSConn) ReadToInboundBuffer() (int, error) {
 func (w *WSConn) WriteServerBinary(data []byte) error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
-	return wsutil.WriteServerBinary(w.outboundBuffer, data)
+	return w.codec.writeServerFrame(w.outboundBuffer, ws.OpBinary, data)
+}
+
+func (w *WSConn) WriteServerText(data []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.codec.writeServerFrame(w.outboundBuffer, ws.OpText, data)
 }
 
 // 解包ws的数据
@@ -108,44 +115,14 @@ func (w *WSConn) decode() ([]wsutil.Message, error) {
 	if err != nil {
 		return nil, err
 	}
-	if len(buff) < ws.MinHeaderSize { // 数据不完整
-		w.Debug("数据不完整", zap.Int("len", len(buff)))
-		return nil, nil
-	}
-	tmpReader := bytes.NewReader(buff)
-	header, err := ws.ReadHeader(tmpReader)
+	messages, n, err := w.codec.decode(buff)
 	if err != nil {
-		if err == io.EOF || err == io.ErrUnexpectedEOF { //数据不完整
-			return nil, nil
-		}
 		w.Debug("发送错误，丢弃数据", zap.Error(err))
 		w.DiscardFromTemp(len(buff)) // 发送错误，丢弃数据
 		return nil, err
 	}
-	dataLen := header.Length
-	if dataLen > int64(tmpReader.Len()) { // 数据不完整
-		w.Debug("数据不完整", zap.Int64("dataLen", dataLen), zap.Int64("tmpReader.Len()", int64(tmpReader.Len())))
-		return nil, nil
-	}
-
-	if header.Fin { // 当前 frame 已经是最后一个frame
-		var messages []wsutil.Message
-		tmpReader.Reset(buff)
-		remLen := tmpReader.Len()
-		for tmpReader.Len() > 0 {
-			messages, err = wsutil.ReadClientMessage(tmpReader, messages)
-			if err != nil {
-				w.Warn("read client message error", zap.Error(err))
-				break
-			}
-		}
-		remLen = remLen - tmpReader.Len()
-		w.DiscardFromTemp(remLen)
-		return messages, nil
-	} else {
-		w.Debug("ws header not is fin", zap.Int("len", len(buff)))
-	}
-	return nil, nil
+	w.DiscardFromTemp(n)
+	return messages, nil
 }
 
 func (w *WSConn) upgrade() error {
@@ -155,10 +132,10 @@ func (w *WSConn) upgrade() error {
 	}
 	tmpReader := bytes.NewReader(buff)
 	tmpWriter := bytes.NewBuffer(nil)
-	_, err = ws.Upgrade(&readWrite{
+	hs, err := wsUpgrade(&readWrite{
 		Reader: tmpReader,
 		Writer: tmpWriter,
-	})
+	}, w.eg.options, false)
 	if err != nil {
 		if err == io.EOF || err == io.ErrUnexpectedEOF { //数据不完整
 			return nil
@@ -166,6 +143,8 @@ func (w *WSConn) upgrade() error {
 		w.DiscardFromTemp(len(buff)) // 发送错误，丢弃数据
 		return err
 	}
+	w.codec.deflate = hs.deflate
+	w.SetValue(ConnValueWSSubprotocol, hs.protocol)
 
 	// 解析http请求
 	req, err := w.parseHttpRequest(buff)
@@ -173,6 +152,8 @@ func (w *WSConn) upgrade() error {
 		return err
 	}
 
+	setWSHandshakeValues(w, req)
+
 	realIp := w.getRealIp(req) // 获取真实ip
 	realPortStr := req.Header.Get("X-Real-Port")
 	if strings.TrimSpace(realIp) != "" {
@@ -200,6 +181,16 @@ func (w *WSConn) upgrade() error {
 	return nil
 }
 
+// 保存握手请求里客户端的信息
+func setWSHandshakeValues(conn Conn, req *http.Request) {
+	conn.SetValue(ConnValueUserAgent, req.Header.Get("User-Agent"))
+	clientVersion := req.Header.Get("X-Client-Version")
+	if strings.TrimSpace(clientVersion) == "" && req.URL != nil {
+		clientVersion = req.URL.Query().Get("client_version")
+	}
+	conn.SetValue(ConnValueClientVersion, clientVersion)
+}
+
 func (w *WSConn) getRealIp(r *http.Request) string {
 	realIp := r.Header.Get("X-Forwarded-For")
 	if strings.TrimSpace(realIp) == "" {
@@ -245,6 +236,9 @@ func (w *WSConn) DiscardFromTemp(n int) {
 }
 
 func (w *WSConn) Close() error {
+	w.mu.Lock()
+	w.codec.close()
+	w.mu.Unlock()
 	_ = w.tmpInboundBuffer.Release()
 	return w.DefaultConn.Close()
 }
@@ -259,6 +253,7 @@ type WSSConn struct {
 	upgraded bool
 
 	wsTmpInboundBuffer InboundBuffer // inboundBuffer InboundBuffer
+	codec              wsCodec       // websocket数据编解码（含压缩）
 }
 
 func NewWSSConn(tlsConn *TLSConn) *WSSConn {
@@ -340,10 +335,10 @@ func (w *WSSConn) upgrade() error {
 
 	tmpReader := bytes.NewReader(buff)
 	tmpWriter := bytes.NewBuffer(nil)
-	_, err = ws.Upgrade(&readWrite{
+	hs, err := wsUpgrade(&readWrite{
 		Reader: tmpReader,
 		Writer: tmpWriter,
-	})
+	}, w.d.eg.options, true)
 	if err != nil {
 		if err == io.EOF || err == io.ErrUnexpectedEOF { //数据不完整
 			return nil
@@ -351,6 +346,16 @@ func (w *WSSConn) upgrade() error {
 		w.discardFromWSTemp(len(buff)) // 发送错误，丢弃数据
 		return err
 	}
+	w.codec.deflate = hs.deflate
+	w.SetValue(ConnValueWSSubprotocol, hs.protocol)
+
+	req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(buff)))
+	if err != nil {
+		w.d.Warn("parse handshake request failed", zap.Error(err))
+	} else {
+		setWSHandshakeValues(w, req)
+	}
+
 	_, err = w.TLSConn.Write(tmpWriter.Bytes())
 	if err != nil {
 		return err
@@ -396,6 +401,9 @@ func (w *WSSConn) unpacketWSData() error {
 }
 
 func (w *WSSConn) Close() error {
+	w.d.mu.Lock()
+	w.codec.close()
+	w.d.mu.Unlock()
 	w.upgraded = false
 	_ = w.wsTmpInboundBuffer.Release()
 	return w.TLSConn.Close()
@@ -404,7 +412,13 @@ func (w *WSSConn) Close() error {
 func (w *WSSConn) WriteServerBinary(data []byte) error {
 	w.d.mu.Lock()
 	defer w.d.mu.Unlock()
-	return wsutil.WriteServerBinary(w.TLSConn, data)
+	return w.codec.writeServerFrame(w.TLSConn, ws.OpBinary, data)
+}
+
+func (w *WSSConn) WriteServerText(data []byte) error {
+	w.d.mu.Lock()
+	defer w.d.mu.Unlock()
+	return w.codec.writeServerFrame(w.TLSConn, ws.OpText, data)
 }
 
 func (w *WSSConn) decode() ([]wsutil.Message, error) {
@@ -412,42 +426,12 @@ func (w *WSSConn) decode() ([]wsutil.Message, error) {
 	if err != nil {
 		return nil, err
 	}
-	if len(buff) < ws.MinHeaderSize { // 数据不完整
-		w.d.Debug("数据还没读完", zap.Int("len", len(buff)))
-		return nil, nil
-	}
-	tmpReader := bytes.NewReader(buff)
-	header, err := ws.ReadHeader(tmpReader)
+	messages, n, err := w.codec.decode(buff)
 	if err != nil {
-		if err == io.EOF || err == io.ErrUnexpectedEOF { //数据不完整
-			return nil, nil
-		}
 		w.d.Debug("wss: 发送错误，丢弃数据", zap.Error(err))
 		w.discardFromWSTemp(len(buff)) // 发送错误，丢弃数据
 		return nil, err
 	}
-	dataLen := header.Length
-	if dataLen > int64(tmpReader.Len()) { // 数据不完整
-		w.d.Debug("wss: 数据还没读完....", zap.Int("dataLen", int(dataLen)), zap.Int("tmpReader.Len()", int(tmpReader.Len())))
-		return nil, nil
-	}
-	if header.Fin { // 当前 frame 已经是最后一个frame
-
-		var messages []wsutil.Message
-		tmpReader.Reset(buff)
-		remLen := tmpReader.Len()
-		for tmpReader.Len() > 0 {
-			messages, err = wsutil.ReadClientMessage(tmpReader, messages)
-			if err != nil {
-				w.d.Warn("read client message error", zap.Error(err))
-				break
-			}
-		}
-		remLen = remLen - tmpReader.Len()
-		w.discardFromWSTemp(remLen)
-		return messages, nil
-	} else {
-		w.d.Debug("wss: ws header not is fin", zap.Int("len", len(buff)))
-	}
-	return nil, nil
+	w.discardFromWSTemp(n)
+	return messages, nil
 }
@@ -3,16 +3,20 @@ package wknet
 import (
 	"bufio"
 	"bytes"
+	"compress/flate"
 	"context"
 	"crypto/tls"
 	"io"
 	"net"
 	"net/url"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	stls "github.com/WuKongIM/crypto/tls"
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsflate"
 	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
 )
@@ -48,6 +52,185 @@ func TestWebsocket(t *testing.T) {
 
 }
 
+func TestWebsocketCompression(t *testing.T) {
+	var rawN, compressedN, inflateN atomic.Int64
+	e := NewEngine(
+		WithWSAddr("ws://0.0.0.0:0"),
+		WithWSCompression(flate.BestSpeed, 0),
+		WithOnWSCompress(func(raw, compressed int) {
+			rawN.Add(int64(raw))
+			compressedN.Add(int64(compressed))
+		}),
+		WithOnWSDecompress(func(compressed, raw int) {
+			inflateN.Add(int64(raw))
+		}),
+	)
+	e.Start()
+	defer e.Stop()
+
+	e.OnData(func(conn Conn) error { // 原样返回
+		data, err := conn.Peek(-1)
+		assert.NoError(t, err)
+		if len(data) == 0 {
+			return nil
+		}
+		_, _ = conn.Discard(len(data))
+		err = conn.(IWSConn).WriteServerBinary(data)
+		assert.NoError(t, err)
+		return conn.WakeWrite()
+	})
+
+	u := url.URL{Scheme: "ws", Host: e.WSRealListenAddr().String(), Path: "/"}
+	dialer := websocket.Dialer{EnableCompression: true}
+	c1, resp, err := dialer.Dial(u.String(), nil)
+	assert.NoError(t, err)
+	defer c1.Close()
+	assert.Contains(t, resp.Header.Get("Sec-WebSocket-Extensions"), "permessage-deflate")
+
+	msg := bytes.Repeat([]byte(`{"channel_id":"group1","payload":"hello"}`), 50)
+	for i := 0; i < 3; i++ {
+		err = c1.WriteMessage(websocket.BinaryMessage, msg)
+		assert.NoError(t, err)
+
+		_ = c1.SetReadDeadline(time.Now().Add(time.Second * 5))
+		_, data, err := c1.ReadMessage()
+		assert.NoError(t, err)
+		assert.Equal(t, msg, data)
+	}
+	assert.Equal(t, int64(len(msg)*3), rawN.Load())
+	assert.Less(t, compressedN.Load(), rawN.Load())
+	assert.Equal(t, int64(len(msg)*3), inflateN.Load())
+}
+
+func TestWebsocketSubprotocol(t *testing.T) {
+	e := NewEngine(WithWSAddr("ws://0.0.0.0:0"), WithWSSubprotocols("wukongim.json"))
+	e.Start()
+	defer e.Stop()
+
+	e.OnData(func(conn Conn) error { // 以文本消息原样返回
+		data, err := conn.Peek(-1)
+		assert.NoError(t, err)
+		if len(data) == 0 {
+			return nil
+		}
+		_, _ = conn.Discard(len(data))
+		assert.Equal(t, "wukongim.json", conn.Value(ConnValueWSSubprotocol))
+		err = conn.(IWSConn).WriteServerText(data)
+		assert.NoError(t, err)
+		return conn.WakeWrite()
+	})
+
+	u := url.URL{Scheme: "ws", Host: e.WSRealListenAddr().String(), Path: "/"}
+	dialer := websocket.Dialer{Subprotocols: []string{"mqtt", "wukongim.json"}}
+	c1, _, err := dialer.Dial(u.String(), nil)
+	assert.NoError(t, err)
+	defer c1.Close()
+	assert.Equal(t, "wukongim.json", c1.Subprotocol())
+
+	err = c1.WriteMessage(websocket.TextMessage, []byte(`{"type":"ping"}`))
+	assert.NoError(t, err)
+
+	_ = c1.SetReadDeadline(time.Now().Add(time.Second * 5))
+	msgType, data, err := c1.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, websocket.TextMessage, msgType)
+	assert.Equal(t, `{"type":"ping"}`, string(data))
+}
+
+func TestWSDeflateContextTakeover(t *testing.T) {
+	opts := NewOptions()
+	server := newWSDeflate(opts, wsflate.Parameters{})
+	client := newWSDeflate(opts, wsflate.Parameters{})
+
+	msg := bytes.Repeat([]byte("hello wukongim "), 20)
+	var sizes []int
+	for i := 0; i < 3; i++ {
+		compressed, err := server.compress(msg)
+		assert.NoError(t, err)
+		sizes = append(sizes, len(compressed))
+
+		data, err := client.decompress(append([]byte(nil), compressed...))
+		assert.NoError(t, err)
+		assert.Equal(t, msg, data)
+	}
+	assert.Less(t, sizes[1], sizes[0]) // 保留上下文后重复的内容压缩得更小
+}
+
+func TestWebsocketWSSSubprotocol(t *testing.T) {
+	cert, err := stls.X509KeyPair(rsaCertPEM, rsaKeyPEM)
+	assert.NoError(t, err)
+	e := NewEngine(WithWSAddr("ws://0.0.0.0:0"), WithWSSAddr("wss://0.0.0.0:0"), WithWSTLSConfig(&stls.Config{Certificates: []stls.Certificate{cert}}), WithWSSSubprotocols("wukongim.json"))
+	err = e.Start()
+	assert.NoError(t, err)
+	defer e.Stop()
+
+	// ws连接不协商只在wss上支持的子协议
+	dialer := websocket.Dialer{Subprotocols: []string{"wukongim.json"}}
+	u := url.URL{Scheme: "ws", Host: e.WSRealListenAddr().String(), Path: "/"}
+	c1, _, err := dialer.Dial(u.String(), nil)
+	assert.NoError(t, err)
+	defer c1.Close()
+	assert.Equal(t, "", c1.Subprotocol())
+
+	dialer.NetDialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return tls.Dial(network, addr, &tls.Config{InsecureSkipVerify: true})
+	}
+	u = url.URL{Scheme: "wss", Host: e.WSSRealListenAddr().String(), Path: "/"}
+	c2, _, err := dialer.Dial(u.String(), nil)
+	assert.NoError(t, err)
+	defer c2.Close()
+	assert.Equal(t, "wukongim.json", c2.Subprotocol())
+}
+
+func TestWSCodecFragments(t *testing.T) {
+	var buff bytes.Buffer
+	frames := []ws.Frame{
+		ws.NewFrame(ws.OpBinary, false, []byte("hel")),
+		ws.NewPingFrame([]byte("ping")),
+		ws.NewFrame(ws.OpContinuation, true, []byte("lo")),
+	}
+	for _, frame := range frames {
+		assert.NoError(t, ws.WriteFrame(&buff, ws.MaskFrame(frame)))
+	}
+	data := buff.Bytes()
+
+	codec := &wsCodec{}
+	// 数据不完整时不消费
+	messages, n, err := codec.decode(data[:len(data)-1])
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(messages))
+	assert.Equal(t, ws.OpPing, messages[0].OpCode)
+
+	messages, m, err := codec.decode(data[n:])
+	assert.NoError(t, err)
+	assert.Equal(t, len(data), n+m)
+	assert.Equal(t, 1, len(messages))
+	assert.Equal(t, ws.OpBinary, messages[0].OpCode)
+	assert.Equal(t, "hello", string(messages[0].Payload))
+}
+
+func TestWSCodecFragmentsTooLarge(t *testing.T) {
+	var buff bytes.Buffer
+	assert.NoError(t, ws.WriteFrame(&buff, ws.MaskFrame(ws.NewFrame(ws.OpContinuation, false, []byte("a")))))
+
+	// 已收到的分片达到上限
+	codec := &wsCodec{opCode: ws.OpBinary, fragments: make([]byte, maxInflateSize)}
+	_, _, err := codec.decode(buff.Bytes())
+	assert.Equal(t, errMessageTooLarge, err)
+}
+
+func TestWSDeflateClose(t *testing.T) {
+	d := newWSDeflate(NewOptions(), wsflate.Parameters{})
+	_, err := d.compress([]byte("hello"))
+	assert.NoError(t, err)
+	assert.NotNil(t, d.fw)
+
+	d.close()
+	assert.Nil(t, d.fw)
+	_, err = d.compress([]byte("hello"))
+	assert.Equal(t, errDeflateClosed, err)
+}
+
 func TestBatchWSConn(t *testing.T) {
 	e := NewEngine(WithWSAddr("ws://0.0.0.0:0"))
 	e.Start()
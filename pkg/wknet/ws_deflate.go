@@ -0,0 +1,368 @@
+package wknet
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/gobwas/httphead"
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsflate"
+	"github.com/gobwas/ws/wsutil"
+)
+
+// websocket permessage-deflate 压缩扩展（RFC 7692）
+// 压缩和解压都在连接读写数据时同步完成，不为连接开启额外的goroutine
+
+const (
+	deflateWindowSize = 32 * 1024        // 滑动窗口大小（window bits 15）
+	maxInflateSize    = 64 * 1024 * 1024 // 消息（合并分片后和解压后）的最大大小
+)
+
+var (
+	// 压缩数据同步刷新后末尾的空块，发送时去掉
+	deflateTail = []byte{0x00, 0x00, 0xff, 0xff}
+	// 解压时在数据末尾补上空块和一个结束块，使flate读取到EOF
+	inflateTail = []byte{0x00, 0x00, 0xff, 0xff, 0x01, 0x00, 0x00, 0xff, 0xff}
+
+	errInflateTooLarge = errors.New("ws: inflated message too large")
+	errMessageTooLarge = errors.New("ws: message too large")
+	errDeflateClosed   = errors.New("ws: deflate closed")
+)
+
+var (
+	flateWriterPools [flate.BestCompression - flate.HuffmanOnly + 1]sync.Pool // 按压缩级别缓存
+	flateReaderPool  sync.Pool
+)
+
+func getFlateWriter(level int, w io.Writer) *flate.Writer {
+	if fw, ok := flateWriterPools[level-flate.HuffmanOnly].Get().(*flate.Writer); ok {
+		fw.Reset(w)
+		return fw
+	}
+	fw, _ := flate.NewWriter(w, level) // 级别已校验，不会返回错误
+	return fw
+}
+
+func putFlateWriter(level int, fw *flate.Writer) {
+	flateWriterPools[level-flate.HuffmanOnly].Put(fw)
+}
+
+func getFlateReader(r io.Reader, dict []byte) io.ReadCloser {
+	if fr, ok := flateReaderPool.Get().(io.ReadCloser); ok {
+		_ = fr.(flate.Resetter).Reset(r, dict)
+		return fr
+	}
+	return flate.NewReaderDict(r, dict)
+}
+
+func putFlateReader(fr io.ReadCloser) {
+	flateReaderPool.Put(fr)
+}
+
+// 校正压缩级别
+func validFlateLevel(level int) int {
+	if level < flate.HuffmanOnly || level > flate.BestCompression {
+		return flate.DefaultCompression
+	}
+	return level
+}
+
+// 握手时协商permessage-deflate
+type wsDeflateNegotiator struct {
+	opts     *Options
+	accepted bool
+	params   wsflate.Parameters
+}
+
+func (n *wsDeflateNegotiator) negotiate(opt httphead.Option) (httphead.Option, error) {
+	if n.accepted || !bytes.Equal(opt.Name, wsflate.ExtensionNameBytes) {
+		return httphead.Option{}, nil
+	}
+	var offer wsflate.Parameters
+	if err := offer.Parse(opt); err != nil { // 参数不合法的offer不接受，继续协商下一个
+		return httphead.Option{}, nil
+	}
+	if offer.ServerMaxWindowBits.Defined() && offer.ServerMaxWindowBits.Bytes() < deflateWindowSize { // compress/flate 不支持更小的窗口
+		return httphead.Option{}, nil
+	}
+	cfg := n.opts.WSCompression
+	n.params = wsflate.Parameters{
+		ServerNoContextTakeover: cfg.ServerNoContextTakeover || offer.ServerNoContextTakeover,
+		ClientNoContextTakeover: cfg.ClientNoContextTakeover || offer.ClientNoContextTakeover, // 客户端声明不保留上下文时回应，无需缓存解压数据
+	}
+	n.accepted = true
+	return n.params.Option(), nil
+}
+
+// 连接的permessage-deflate状态
+// 压缩在连接的写锁内调用，解压在连接的读事件中调用
+type wsDeflate struct {
+	opts                    *Options
+	level                   int
+	threshold               int
+	serverNoContextTakeover bool
+	clientNoContextTakeover bool
+
+	fw     *flate.Writer // 保留上下文时连接独占的压缩器
+	outBuf bytes.Buffer
+	dict   []byte // 保留上下文时最近解压的数据（解压下一条消息的字典）
+	closed bool
+}
+
+func newWSDeflate(opts *Options, params wsflate.Parameters) *wsDeflate {
+	return &wsDeflate{
+		opts:                    opts,
+		level:                   validFlateLevel(opts.WSCompression.Level),
+		threshold:               opts.WSCompression.Threshold,
+		serverNoContextTakeover: params.ServerNoContextTakeover,
+		clientNoContextTakeover: params.ClientNoContextTakeover,
+	}
+}
+
+// 压缩消息，返回的数据在下一次调用前有效
+func (d *wsDeflate) compress(data []byte) ([]byte, error) {
+	if d.closed {
+		return nil, errDeflateClosed
+	}
+	d.outBuf.Reset()
+	fw := d.fw
+	if fw == nil {
+		if d.serverNoContextTakeover {
+			fw = getFlateWriter(d.level, &d.outBuf)
+			defer putFlateWriter(d.level, fw)
+		} else {
+			fw, _ = flate.NewWriter(&d.outBuf, d.level)
+			d.fw = fw
+		}
+	}
+	if _, err := fw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := fw.Flush(); err != nil {
+		return nil, err
+	}
+	out := d.outBuf.Bytes()
+	out = bytes.TrimSuffix(out, deflateTail)
+
+	if cb := d.opts.Event.OnWSCompress; cb != nil {
+		cb(len(data), len(out))
+	}
+	return out, nil
+}
+
+// 连接关闭时释放压缩器（在连接的写锁内调用）
+func (d *wsDeflate) close() {
+	d.closed = true
+	if d.fw != nil {
+		d.fw.Reset(io.Discard)
+		d.fw = nil
+	}
+	d.outBuf = bytes.Buffer{}
+}
+
+// 解压消息
+func (d *wsDeflate) decompress(data []byte) ([]byte, error) {
+	var dict []byte
+	if !d.clientNoContextTakeover {
+		dict = d.dict
+	}
+	fr := getFlateReader(io.MultiReader(bytes.NewReader(data), bytes.NewReader(inflateTail)), dict)
+	defer putFlateReader(fr)
+
+	var out bytes.Buffer
+	n, err := io.Copy(&out, io.LimitReader(fr, maxInflateSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if n > maxInflateSize {
+		return nil, errInflateTooLarge
+	}
+	result := out.Bytes()
+	if !d.clientNoContextTakeover {
+		d.keepDict(result)
+	}
+	if cb := d.opts.Event.OnWSDecompress; cb != nil {
+		cb(len(data), len(result))
+	}
+	return result, nil
+}
+
+// 保留最近窗口大小的解压数据
+func (d *wsDeflate) keepDict(data []byte) {
+	if len(data) >= deflateWindowSize {
+		d.dict = append(d.dict[:0], data[len(data)-deflateWindowSize:]...)
+		return
+	}
+	d.dict = append(d.dict, data...)
+	if over := len(d.dict) - deflateWindowSize; over > 0 {
+		copy(d.dict, d.dict[over:])
+		d.dict = d.dict[:deflateWindowSize]
+	}
+}
+
+// websocket握手的结果
+type wsHandshake struct {
+	protocol string     // 协商的子协议
+	deflate  *wsDeflate // 协商了压缩时不为nil
+}
+
+// websocket升级，协商子协议和permessage-deflate（开启压缩时）
+// secure为wss连接，只有wss连接才协商WSSSubprotocols中的子协议
+func wsUpgrade(rw io.ReadWriter, opts *Options, secure bool) (wsHandshake, error) {
+	var (
+		upgrader   ws.Upgrader
+		negotiator *wsDeflateNegotiator
+		hs         wsHandshake
+	)
+	protocols := opts.WSSubprotocols
+	if secure && len(opts.WSSSubprotocols) > 0 {
+		protocols = append(append([]string(nil), protocols...), opts.WSSSubprotocols...)
+	}
+	if len(protocols) > 0 {
+		upgrader.Protocol = func(protocol []byte) bool {
+			for _, p := range protocols {
+				if p == string(protocol) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	if opts.WSCompression.On {
+		negotiator = &wsDeflateNegotiator{opts: opts}
+		upgrader.Negotiate = negotiator.negotiate
+	}
+	handshake, err := upgrader.Upgrade(rw)
+	if err != nil {
+		return hs, err
+	}
+	hs.protocol = handshake.Protocol
+	if negotiator != nil && negotiator.accepted {
+		hs.deflate = newWSDeflate(opts, negotiator.params)
+	}
+	return hs, nil
+}
+
+// websocket数据的编解码（逐帧解析客户端的数据，支持分片和压缩）
+type wsCodec struct {
+	deflate *wsDeflate // 协商了压缩时不为nil
+
+	opCode     ws.OpCode // 当前消息的类型（分片消息）
+	compressed bool      // 当前消息是否压缩
+	fragments  []byte    // 已收到的分片数据
+}
+
+// 解析buff中完整的帧，返回完整的消息和已消费的字节数
+func (c *wsCodec) decode(buff []byte) ([]wsutil.Message, int, error) {
+	state := ws.StateServerSide
+	if c.deflate != nil {
+		state = state.Set(ws.StateExtended)
+	}
+	var (
+		messages []wsutil.Message
+		offset   int
+	)
+	for offset < len(buff) {
+		tmpReader := bytes.NewReader(buff[offset:])
+		header, err := ws.ReadHeader(tmpReader)
+		if err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF { // 数据不完整
+				break
+			}
+			return messages, offset, err
+		}
+		if header.Length > int64(tmpReader.Len()) { // 数据不完整
+			break
+		}
+		frameState := state
+		if c.opCode != 0 { // 分片消息接收中
+			frameState = frameState.Set(ws.StateFragmented)
+		}
+		if err = ws.CheckHeader(header, frameState); err != nil {
+			return messages, offset, err
+		}
+		start := offset + len(buff[offset:]) - tmpReader.Len()
+		payload := make([]byte, header.Length)
+		copy(payload, buff[start:start+int(header.Length)])
+		offset = start + int(header.Length)
+		if header.Masked {
+			ws.Cipher(payload, header.Mask, 0)
+		}
+
+		if header.OpCode.IsControl() {
+			if header.Rsv != 0 {
+				return messages, offset, ws.ErrProtocolNonZeroRsv
+			}
+			messages = append(messages, wsutil.Message{OpCode: header.OpCode, Payload: payload})
+			continue
+		}
+
+		compressed := false
+		if c.deflate != nil {
+			if header, compressed, err = wsflate.UnsetBit(header); err != nil {
+				return messages, offset, err
+			}
+		}
+		if header.OpCode == ws.OpContinuation {
+			if c.opCode == 0 {
+				return messages, offset, ws.ErrProtocolContinuationUnexpected
+			}
+			if compressed {
+				return messages, offset, wsflate.ErrUnexpectedCompressionBit
+			}
+		} else {
+			if c.opCode != 0 {
+				return messages, offset, ws.ErrProtocolContinuationExpected
+			}
+			c.opCode = header.OpCode
+			c.compressed = compressed
+		}
+
+		if len(c.fragments)+len(payload) > maxInflateSize {
+			return messages, offset, errMessageTooLarge
+		}
+		if !header.Fin { // 还有后续分片
+			c.fragments = append(c.fragments, payload...)
+			continue
+		}
+		if c.fragments != nil {
+			payload = append(c.fragments, payload...)
+			c.fragments = nil
+		}
+		if c.compressed {
+			if payload, err = c.deflate.decompress(payload); err != nil {
+				return messages, offset, err
+			}
+		}
+		messages = append(messages, wsutil.Message{OpCode: c.opCode, Payload: payload})
+		c.opCode = 0
+		c.compressed = false
+	}
+	return messages, offset, nil
+}
+
+// 连接关闭时释放压缩状态（在连接的写锁内调用）
+func (c *wsCodec) close() {
+	if c.deflate != nil {
+		c.deflate.close()
+	}
+}
+
+// 写服务端的消息（二进制或文本），协商了压缩且消息大小达到阈值时压缩
+func (c *wsCodec) writeServerFrame(w io.Writer, op ws.OpCode, data []byte) error {
+	if c.deflate == nil || len(data) < c.deflate.threshold {
+		return wsutil.WriteServerMessage(w, op, data)
+	}
+	compressed, err := c.deflate.compress(data)
+	if err != nil {
+		return err
+	}
+	frame := ws.NewFrame(op, true, compressed)
+	if frame.Header, err = wsflate.SetBit(frame.Header); err != nil {
+		return err
+	}
+	return ws.WriteFrame(w, frame)
+}